@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// checkAdHocDatabases fails an ad-hoc backup up front when one of its
+// databases does not exist, since no configuration vouches for them
+func checkAdHocDatabases(ctx context.Context, cfg *config.Config) error {
+	existing, err := listServerDatabases(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	found := make(map[string]bool, len(existing))
+	for _, db := range existing {
+		found[db] = true
+	}
+	for _, db := range cfg.Backup.Databases {
+		if !found[db] {
+			return fmt.Errorf("database %s does not exist on %s", db, cfg.Database.RecordedHost())
+		}
+	}
+	return nil
+}
+
+// listServerDatabases lists the databases on the server of the config,
+// MySQL or PostgreSQL
+func listServerDatabases(ctx context.Context, cfg *config.Config) ([]string, error) {
+	if cfg.Database.IsPostgreSQL() {
+		provider, err := database.NewConfiguredProvider(&cfg.Database)
+		if err != nil {
+			return nil, err
+		}
+		infos, err := provider.ListDatabases(ctx)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(infos))
+		for _, info := range infos {
+			names = append(names, info.Name)
+		}
+		return names, nil
+	}
+
+	dbClient, err := database.NewClient(&cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+	return dbClient.ListDatabases(ctx)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/bench"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+type benchFlags struct {
+	configFile   string
+	database     string
+	table        string
+	rows         int64
+	uploadSizeMB int
+	skipUpload   bool
+	output       string
+	tenant       string
+}
+
+func newBenchCommand() *cobra.Command {
+	var flags benchFlags
+
+	cmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure dump, compression and upload throughput",
+		Long: `Measure how fast this host dumps a sample table, how fast each gzip level
+compresses that dump, and the upload bandwidth to each configured destination,
+then recommend backup.concurrency and a compression level. The benchmark only
+reads from the database; its upload test file is removed afterwards.`,
+		Example: `  tenangdb bench
+  tenangdb bench --database app_db --table orders --rows 500000
+  tenangdb bench --skip-upload --output json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateOutputFormat(flags.output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := runBench(flags); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&flags.database, "database", "", "database to sample (default: first of backup.databases)")
+	cmd.Flags().StringVar(&flags.table, "table", "", "table to sample (default: the largest table)")
+	cmd.Flags().Int64Var(&flags.rows, "rows", 100000, "number of rows to dump")
+	cmd.Flags().IntVar(&flags.uploadSizeMB, "upload-size", 32, "size in MB of the file uploaded to each destination")
+	cmd.Flags().BoolVar(&flags.skipUpload, "skip-upload", false, "do not measure upload bandwidth")
+	cmd.Flags().StringVar(&flags.output, "output", outputText, "output format: text or json")
+	cmd.Flags().StringVar(&flags.tenant, "tenant", "", "only sample databases of the named tenant and upload below its prefix")
+
+	return cmd
+}
+
+// benchResult is the outcome of `tenangdb bench`
+type benchResult struct {
+	Database       string                    `json:"database"`
+	Table          string                    `json:"table"`
+	Rows           int64                     `json:"rows"`
+	DumpBytes      int64                     `json:"dump_bytes"`
+	DumpRowsPerSec float64                   `json:"dump_rows_per_second"`
+	DumpMBps       float64                   `json:"dump_mb_per_second"`
+	Compression    []bench.CompressionResult `json:"compression"`
+	Uploads        []benchUpload             `json:"uploads,omitempty"`
+	CPUs           int                       `json:"cpus"`
+	Recommendation bench.Recommendation      `json:"recommendation"`
+}
+
+type benchUpload struct {
+	Destination string  `json:"destination"`
+	MBps        float64 `json:"mb_per_second,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+func runBench(flags benchFlags) error {
+	if flags.rows < 1 {
+		return fmt.Errorf("--rows must be at least 1")
+	}
+
+	cfg, err := config.LoadConfig(flags.configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := requireMySQL(cfg, "bench"); err != nil {
+		return err
+	}
+	if cfg, err = cfg.ForTenant(flags.tenant); err != nil {
+		return err
+	}
+	if flags.database != "" {
+		if err := checkTenantDatabase(cfg, flags.tenant, flags.database); err != nil {
+			return err
+		}
+	}
+
+	log, err := logger.NewFileLoggerWithSeparateFormats(cfg.Logging.Level, cfg.Logging.FilePath, cfg.Logging.Format, cfg.Logging.FileFormat)
+	if err != nil {
+		log = logger.NewLogger(cfg.Logging.Level)
+		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
+	}
+	applyOutputMode(log, cfg)
+
+	dbName := flags.database
+	if dbName == "" {
+		if len(cfg.Backup.Databases) == 0 {
+			return fmt.Errorf("no database to sample, set --database")
+		}
+		dbName = cfg.Backup.Databases[0]
+	}
+
+	ctx := context.Background()
+	dbClient, err := database.NewClient(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+	dbClient.SetLogger(log)
+
+	table := flags.table
+	if table == "" {
+		if table, err = dbClient.LargestTable(ctx, dbName); err != nil {
+			return err
+		}
+	}
+
+	tempDir, err := os.MkdirTemp("", "tenangdb-bench-")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	result := benchResult{Database: dbName, Table: table, CPUs: runtime.NumCPU()}
+
+	// Dump throughput
+	log.WithField("table", table).WithField("rows", flags.rows).Info("⏱️ Measuring dump throughput")
+	dumpFile := filepath.Join(tempDir, "sample.sql")
+	start := time.Now()
+	result.Rows, err = dbClient.DumpSample(ctx, dbName, table, flags.rows, dumpFile)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	sample, err := os.ReadFile(dumpFile)
+	if err != nil {
+		return fmt.Errorf("failed to read sample dump: %w", err)
+	}
+	result.DumpBytes = int64(len(sample))
+	result.DumpMBps = bench.MBps(result.DumpBytes, elapsed)
+	result.DumpRowsPerSec = float64(result.Rows) / elapsed.Seconds()
+
+	// Compression throughput
+	log.Info("⏱️ Measuring compression throughput")
+	if result.Compression, err = bench.Compression(sample, bench.Levels); err != nil {
+		return fmt.Errorf("failed to measure compression: %w", err)
+	}
+
+	// Upload bandwidth
+	uploadMBps := 0.0
+	if !flags.skipUpload {
+		uploadCfg := cfg.UploadFor(dbName)
+		destinations := []string{uploadCfg.Destination, uploadCfg.FallbackDestination}
+		uploader := upload.NewService(uploadCfg, log)
+		uploadFile := filepath.Join(tempDir, fmt.Sprintf("upload-%d.bin", time.Now().Unix()))
+		if err := writeRandomFile(uploadFile, int64(flags.uploadSizeMB)*1024*1024); err != nil {
+			return err
+		}
+
+		for _, destination := range destinations {
+			if destination == "" {
+				continue
+			}
+			log.WithField("destination", destination).Info("⏱️ Measuring upload bandwidth")
+			item := benchUpload{Destination: destination}
+			if took, err := uploader.MeasureUpload(ctx, uploadFile, destination); err != nil {
+				item.Error = err.Error()
+			} else {
+				item.MBps = bench.MBps(int64(flags.uploadSizeMB)*1024*1024, took)
+				// Size concurrency for the primary destination
+				if uploadMBps == 0 {
+					uploadMBps = item.MBps
+				}
+			}
+			result.Uploads = append(result.Uploads, item)
+		}
+	}
+
+	result.Recommendation = bench.Recommend(result.DumpMBps, result.Compression, uploadMBps, result.CPUs)
+
+	if flags.output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+	printBenchResult(&result)
+	return nil
+}
+
+func writeRandomFile(path string, size int64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create upload test file: %w", err)
+	}
+	if _, err := io.CopyN(file, rand.Reader, size); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write upload test file: %w", err)
+	}
+	return file.Close()
+}
+
+func printBenchResult(result *benchResult) {
+	fmt.Printf("Dump of %s.%s: %d rows, %s\n", result.Database, result.Table, result.Rows, formatFileSize(result.DumpBytes))
+	fmt.Printf("  %.0f rows/s, %.1f MB/s\n\n", result.DumpRowsPerSec, result.DumpMBps)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "GZIP LEVEL\tMB/S\tRATIO")
+	for _, c := range result.Compression {
+		fmt.Fprintf(w, "%d\t%.1f\t%.1f%%\n", c.Level, c.MBps, c.Ratio*100)
+	}
+	w.Flush()
+
+	if len(result.Uploads) > 0 {
+		fmt.Println()
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DESTINATION\tMB/S")
+		for _, u := range result.Uploads {
+			if u.Error != "" {
+				fmt.Fprintf(w, "%s\tfailed: %s\n", u.Destination, u.Error)
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%.1f\n", u.Destination, u.MBps)
+		}
+		w.Flush()
+	}
+
+	fmt.Printf("\nRecommended for %d CPUs:\n", result.CPUs)
+	fmt.Printf("  backup.concurrency: %d\n", result.Recommendation.Concurrency)
+	if result.Recommendation.CompressionLevel > 0 {
+		fmt.Printf("  backup.compression.level: %d\n", result.Recommendation.CompressionLevel)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/abdullahainun/tenangdb/internal/bundle"
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+func newExportBundleCommand() *cobra.Command {
+	var configFile string
+	var out string
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "export-bundle <backup-id>",
+		Short: "Package a backup for legal hold or compliance handoff",
+		Long: `Package a backup into a single tar file with its manifest, the log entries of the
+run that created it, encryption metadata and SHA256SUMS over every file.
+Verify an extracted bundle with 'sha256sum -c SHA256SUMS' inside its directory.`,
+		Example: `  tenangdb export-bundle app_db-2025-07-05_10-30-15 --out bundle.tar`,
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runExportBundle(configFile, args[0], out, tenant); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&out, "out", "", "bundle file to write (default: <backup-id>.tar)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only allow backups of databases of the named tenant")
+
+	return cmd
+}
+
+func runExportBundle(configFile, id, out, tenant string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	entry, err := catalog.Find(cfg.Backup.Directory, id)
+	if err != nil {
+		return err
+	}
+	if err := checkTenantBackup(cfg, tenant, entry); err != nil {
+		return err
+	}
+
+	if out == "" {
+		out = id + ".tar"
+	}
+
+	exportedBy := version
+	if exportedBy == "" {
+		exportedBy = "unknown"
+	}
+
+	info, err := bundle.Export(entry, cfg.Logging.FilePath, exportedBy, out)
+	if err != nil {
+		return err
+	}
+
+	checksum, err := manifest.FileChecksum(out)
+	if err != nil {
+		return fmt.Errorf("failed to checksum bundle: %w", err)
+	}
+
+	fmt.Printf("📦 Exported backup %s to %s\n", id, out)
+	fmt.Printf("   Audit entries: %d\n", info.AuditEntries)
+	fmt.Printf("   SHA-256: %s\n", checksum)
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// checkRestoreCharset compares the backup's charset with the target before
+// loading it. A missing target database is created with the backup's
+// charset, since the server default (often latin1) would corrupt utf8mb4
+// text. A mismatching existing database is a warning, or an error with
+// restore.strict_charset.
+func checkRestoreCharset(ctx context.Context, dbClient *database.Client, restoreCfg *config.RestoreConfig, backupPath, targetDatabase string, log *logger.Logger) error {
+	backupCharset, ok := backupCharsetOf(backupPath)
+	if !ok {
+		log.Debug("Backup has no charset metadata, skipping charset check")
+		return nil
+	}
+
+	target, exists, err := dbClient.DatabaseCharset(ctx, targetDatabase)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		create := backupCharset
+		if create.Collation != "" {
+			known, err := dbClient.HasCollation(ctx, create.Collation)
+			if err != nil {
+				return err
+			}
+			if !known {
+				log.WithField("collation", create.Collation).
+					Warn("⚠️ Target server does not know the backup's collation, using the charset default")
+				create.Collation = ""
+			}
+		}
+
+		if err := dbClient.CreateDatabase(ctx, targetDatabase, create); err != nil {
+			return err
+		}
+		log.WithField("database", targetDatabase).WithField("charset", create.String()).
+			Info("🔤 Created target database with the backup's charset")
+		return nil
+	}
+
+	if strings.EqualFold(target.Name, backupCharset.Name) &&
+		(backupCharset.Collation == "" || strings.EqualFold(target.Collation, backupCharset.Collation)) {
+		return nil
+	}
+
+	msg := fmt.Sprintf("backup charset %s differs from target database charset %s", backupCharset, target)
+	if restoreCfg.StrictCharset {
+		return fmt.Errorf("%s (restore.strict_charset is enabled)", msg)
+	}
+	log.WithField("database", targetDatabase).
+		Warn("⚠️ " + msg + "; tables keep their own charset, but text in columns without one may be converted")
+	return nil
+}
+
+// backupCharsetOf reads the database charset recorded at backup time from
+// the manifest, or from the schema of an uncompressed mydumper backup
+func backupCharsetOf(backupPath string) (database.Charset, bool) {
+	if m, err := manifest.Read(manifest.PathFor(backupPath)); err == nil && m.Charset != "" {
+		return database.Charset{Name: m.Charset, Collation: m.Collation}, true
+	}
+	return database.SchemaCreateCharset(backupPath)
+}
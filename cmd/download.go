@@ -0,0 +1,120 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/encryption"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+	"github.com/sirupsen/logrus"
+)
+
+// downloadBackup fetches a backup given as an rclone remote path into a
+// temporary directory named after the remote, so an interrupted download
+// resumes when the restore is run again. Downloads of other remotes that
+// were abandoned for upload.PartialMaxAge are removed first. The returned
+// cleanup removes the download.
+func downloadBackup(ctx context.Context, cfg *config.Config, remote string, log *logger.Logger) (string, func(), error) {
+	sum := sha256.Sum256([]byte(remote))
+	dir := filepath.Join(restoreTempDir(cfg), "tenangdb-download-"+hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, fmt.Errorf("failed to create download directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+	expireDownloads(restoreTempDir(cfg), dir, log)
+
+	downloader := upload.NewService(&cfg.Upload, log)
+	localPath, err := downloader.Download(ctx, remote, dir, cfg.Restore.DownloadStreams)
+	if err != nil {
+		// Keep what was fetched for the next attempt
+		if parts, _ := filepath.Glob(filepath.Join(dir, "*"+upload.PartSuffix)); len(parts) > 0 {
+			log.WithField("directory", dir).Warn("⚠️ Download interrupted, run the restore again to resume it")
+		} else {
+			cleanup()
+		}
+		return "", nil, err
+	}
+
+	log.WithField("path", localPath).Info("✅ Backup downloaded")
+	return localPath, cleanup, nil
+}
+
+// expireDownloads removes the interrupted downloads in the tenangdb-download
+// directories of tempDir, other than keep, that no restore resumed for
+// upload.PartialMaxAge, and the directories they leave empty
+func expireDownloads(tempDir, keep string, log *logger.Logger) {
+	dirs, _ := filepath.Glob(filepath.Join(tempDir, "tenangdb-download-*"))
+	for _, dir := range dirs {
+		if dir == keep {
+			continue
+		}
+		removed, err := upload.ExpirePartial(dir, upload.PartialMaxAge, time.Now())
+		if err != nil {
+			log.WithError(err).WithField("directory", dir).Warn("⚠️ Failed to remove abandoned download")
+		}
+		for _, part := range removed {
+			log.WithField("path", part).Info("Removed abandoned download")
+		}
+		// Only succeeds once the directory is empty
+		os.Remove(dir)
+	}
+}
+
+// restoreTempDir returns where restores keep temporary files:
+// restore.temp_directory, or the system temporary directory
+func restoreTempDir(cfg *config.Config) string {
+	if cfg.Restore.TempDirectory != "" {
+		return cfg.Restore.TempDirectory
+	}
+	return os.TempDir()
+}
+
+// makeRestoreTempDir creates a new directory for temporary restore files
+func makeRestoreTempDir(cfg *config.Config, pattern string) (string, error) {
+	if err := os.MkdirAll(restoreTempDir(cfg), 0700); err != nil {
+		return "", err
+	}
+	return os.MkdirTemp(restoreTempDir(cfg), pattern)
+}
+
+// cleanupOnExit makes cleanup also run when the command exits through
+// log.Fatal or log.Exit, which skip deferred calls. The returned function
+// runs it at most once.
+func cleanupOnExit(cleanup func()) func() {
+	var once sync.Once
+	run := func() { once.Do(cleanup) }
+	logrus.RegisterExitHandler(run)
+	return run
+}
+
+// decryptBackup decrypts an encrypted backup file into a temporary
+// directory for restore. Other backups are returned unchanged. The returned
+// cleanup removes the decrypted copy.
+func decryptBackup(ctx context.Context, cfg *config.Config, backupPath string, log *logger.Logger) (string, func(), error) {
+	if !encryption.IsEncrypted(backupPath) {
+		return backupPath, func() {}, nil
+	}
+
+	dir, err := makeRestoreTempDir(cfg, "tenangdb-decrypt-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create decryption directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	localPath := filepath.Join(dir, strings.TrimSuffix(filepath.Base(backupPath), encryption.Suffix))
+	log.WithField("path", backupPath).Info("🔐 Decrypting backup")
+	if err := encryption.DecryptFile(ctx, &cfg.Backup.Encryption, backupPath, localPath); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return localPath, cleanup, nil
+}
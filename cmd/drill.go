@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/policy"
+	"github.com/abdullahainun/tenangdb/internal/resources"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+// drillDatabasePrefix names the scratch databases drills restore into
+const drillDatabasePrefix = "tenangdb_drill_"
+
+func newDrillCommand() *cobra.Command {
+	var configFile string
+	var dbName string
+	var keep bool
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "drill",
+		Short: "Restore the latest backup of a random database into a scratch instance",
+		Long: `Run a restore drill: pick a random database, restore its latest local backup
+into the scratch instance named by drill.target (a standby from the config) as
+tenangdb_drill_<database>, verify every table with CHECK TABLE, compare the
+checksums recorded with backup.checksums, and record how
+long the restore took. The scratch database is dropped afterwards. The command
+exits with an error when the drill fails, so a timer can alert on it.`,
+		Example: `  tenangdb drill
+  tenangdb drill --database app_db --keep`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runDrill(configFile, dbName, tenant, keep); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&dbName, "database", "", "database to drill instead of a random one")
+	cmd.Flags().BoolVar(&keep, "keep", false, "keep the scratch database for inspection")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only drill databases of the named tenant")
+
+	return cmd
+}
+
+func runDrill(configFile, dbName, tenant string, keep bool) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+	if cfg.Drill.Target == "" {
+		return fmt.Errorf("drill.target is not configured")
+	}
+	standby, err := cfg.Standby(cfg.Drill.Target)
+	if err != nil {
+		return err
+	}
+
+	if dbName == "" {
+		candidates := tenantDatabases(cfg, tenant, cfg.Drill.Databases)
+		if len(candidates) == 0 {
+			candidates = cfg.Backup.Databases
+		}
+		if len(candidates) == 0 {
+			return fmt.Errorf("no database to drill, set --database")
+		}
+		dbName = candidates[rand.Intn(len(candidates))]
+	} else if err := checkTenantDatabase(cfg, tenant, dbName); err != nil {
+		return err
+	}
+
+	log, err := logger.NewFileLoggerWithSeparateFormats(cfg.Logging.Level, cfg.Logging.FilePath, cfg.Logging.Format, cfg.Logging.FileFormat)
+	if err != nil {
+		log = logger.NewLogger(cfg.Logging.Level)
+		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
+	}
+	applyOutputMode(log, cfg)
+
+	// Stay within the configured CPU and memory envelope
+	if err := resources.Apply(&cfg.Resources, log); err != nil {
+		log.WithError(err).Fatal("Failed to apply resource limits")
+	}
+
+	ctx := context.Background()
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	var metricsStorage *metrics.MetricsStorage
+	if cfg.Metrics.Enabled {
+		metricsPath := cfg.Metrics.StoragePath
+		if metricsPath == "" {
+			metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
+		}
+		metricsStorage = metrics.NewMetricsStorage(metricsPath)
+	}
+
+	dbLog := log.WithDatabase(dbName).WithField("target", standby.Name)
+	dbLog.Info("🧯 Starting restore drill of " + dbName)
+
+	backupID, rto, tables, drillErr := drillDatabase(ctx, cfg, standby, entries, dbName, keep, log)
+
+	if metricsStorage != nil {
+		if err := metricsStorage.UpdateDrillMetrics(dbName, backupID, rto, drillErr == nil); err != nil {
+			dbLog.WithError(err).Warn("Failed to update drill metrics")
+		}
+		if drillErr != nil {
+			if err := metricsStorage.RecordFailure("drill", dbName, drillErr); err != nil {
+				dbLog.WithError(err).Warn("Failed to record drill failure")
+			}
+		}
+	}
+
+	if drillErr != nil {
+		dbLog.WithError(drillErr).Error("❌ Restore drill failed")
+		return fmt.Errorf("restore drill of %s failed: %w", dbName, drillErr)
+	}
+
+	dbLog.WithField("backup", backupID).WithField("tables", tables).WithField("rto", rto.Round(time.Second)).
+		Info("✅ Restore drill passed")
+	fmt.Printf("Drill of %s passed: backup %s restored and %d tables verified in %s\n", dbName, backupID, tables, rto.Round(time.Second))
+	return nil
+}
+
+// drillDatabase restores the latest backup of dbName into a scratch
+// database on the drill target and verifies it. It returns the backup used,
+// the time to restore and verify it, and the number of tables checked.
+func drillDatabase(ctx context.Context, cfg *config.Config, standby *config.StandbyConfig, entries []catalog.Entry, dbName string, keep bool, log *logger.Logger) (string, time.Duration, int, error) {
+	var entry *catalog.Entry
+	for i := range entries {
+		if entries[i].Manifest.Database == dbName {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		return "", 0, 0, fmt.Errorf("no backup of %s found", dbName)
+	}
+
+	// A backup that no longer matches its checksum is a failed drill, not a
+	// reason to try an older one
+	if entry.Manifest.SHA256 != "" {
+		sum, err := manifest.FileChecksum(entry.ArtifactPath)
+		if err != nil {
+			return entry.ID, 0, 0, fmt.Errorf("failed to checksum backup: %w", err)
+		}
+		if sum != entry.Manifest.SHA256 {
+			return entry.ID, 0, 0, fmt.Errorf("backup %s does not match its checksum", entry.ID)
+		}
+	}
+
+	scratch := drillDatabasePrefix + dbName
+	if err := policy.CheckRestore(&cfg.Policy, standby.Host, scratch); err != nil {
+		return entry.ID, 0, 0, err
+	}
+
+	// Everything below talks to the scratch instance, never to production
+	conn := standby.ConnectionConfig(cfg.Database)
+	dbClient, err := database.NewClient(&conn)
+	if err != nil {
+		return entry.ID, 0, 0, fmt.Errorf("failed to connect to %s: %w", standby.Name, err)
+	}
+	defer dbClient.Close()
+	dbClient.SetLogger(log)
+
+	// Start from an empty database, a kept drill may have left one behind
+	if err := dbClient.DropDatabase(ctx, scratch); err != nil {
+		return entry.ID, 0, 0, err
+	}
+	if !keep {
+		defer func() {
+			if err := dbClient.DropDatabase(ctx, scratch); err != nil {
+				log.WithError(err).Warn("Failed to drop drill database " + scratch)
+			}
+		}()
+	}
+
+	start := time.Now()
+	if err := checkRestoreCompat(ctx, dbClient, &cfg.Restore, entry.ArtifactPath, log); err != nil {
+		return entry.ID, 0, 0, err
+	}
+	if err := checkRestoreCharset(ctx, dbClient, &cfg.Restore, entry.ArtifactPath, scratch, log); err != nil {
+		return entry.ID, 0, 0, err
+	}
+	warnToolVersions(cfg, entry.ArtifactPath, log)
+	backupPath, cleanup, err := decryptBackup(ctx, cfg, entry.ArtifactPath, log)
+	if err != nil {
+		return entry.ID, 0, 0, err
+	}
+	defer cleanup()
+
+	log.WithField("backup", entry.ID).WithField("scratch_database", scratch).Info("🔄 Restoring backup for drill")
+	if err := dbClient.RestoreBackup(ctx, backupPath, scratch, &cfg.Restore); err != nil {
+		return entry.ID, 0, 0, err
+	}
+	tables, err := dbClient.VerifyRestore(ctx, scratch)
+	if err != nil {
+		return entry.ID, 0, 0, err
+	}
+	rto := time.Since(start)
+
+	// Checksums are taken before the dump, so writes in between also show
+	// up as differences; they point at the source rather than the backup
+	if len(entry.Manifest.TableChecksums) > 0 {
+		diffs, err := dbClient.CompareChecksums(ctx, scratch, entry.Manifest.TableChecksums)
+		if err != nil {
+			log.WithError(err).Warn("Failed to compare table checksums")
+		} else if len(diffs) > 0 {
+			log.WithField("backup", entry.ID).WithField("tables", diffs).
+				Warn("⚠️ Restored tables differ from the checksums taken before the dump, the source changed or was already corrupted")
+		} else {
+			log.WithField("tables", len(entry.Manifest.TableChecksums)).Info("✅ Restored tables match the checksums taken before the dump")
+		}
+	}
+
+	if cfg.Drill.MaxRTO > 0 && rto > cfg.Drill.MaxRTO {
+		return entry.ID, rto, tables, fmt.Errorf("restore took %s, longer than drill.max_rto %s", rto.Round(time.Second), cfg.Drill.MaxRTO)
+	}
+	return entry.ID, rto, tables, nil
+}
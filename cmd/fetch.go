@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/compression"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/policy"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+type fetchFlags struct {
+	configFile string
+	database   string
+	table      string
+	backupID   string
+	out        string
+	restoreTo  string
+	tenant     string
+	yes        bool
+}
+
+func newFetchCommand() *cobra.Command {
+	var flags fetchFlags
+
+	cmd := &cobra.Command{
+		Use:   "fetch",
+		Short: "Download or restore one table from a mydumper backup in the cloud",
+		Long: `Download only the schema and data files of one table from a mydumper backup
+that was uploaded as a directory tree (upload.archive_directories: false), or
+extract them from a seekable tar.zst archive (backup.compression.seekable),
+locally or in the cloud, decompressing only the parts holding them.
+The files are written to --out, or restored into --restore-to with myloader,
+which replaces that table and leaves the rest of the database alone.`,
+		Example: `  tenangdb fetch --database app_db --table users --backup app_db-2025-07-05_10-30-15
+  tenangdb fetch --database app_db --table users --backup app_db-2025-07-05_10-30-15 --restore-to app_db_restore`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runFetch(flags); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&flags.database, "database", "", "database the backup was taken of (required)")
+	cmd.Flags().StringVar(&flags.table, "table", "", "table to fetch (required)")
+	cmd.Flags().StringVar(&flags.backupID, "backup", "", "backup ID, as shown by 'tenangdb list' (required)")
+	cmd.Flags().StringVar(&flags.out, "out", ".", "directory to write the table's files to")
+	cmd.Flags().StringVar(&flags.restoreTo, "restore-to", "", "restore the table into this database instead of keeping the files")
+	cmd.Flags().StringVar(&flags.tenant, "tenant", "", "only allow databases of the named tenant")
+	cmd.Flags().BoolVarP(&flags.yes, "yes", "y", false, "skip confirmation prompts (for automated mode)")
+	for _, name := range []string{"database", "table", "backup"} {
+		if err := cmd.MarkFlagRequired(name); err != nil {
+			fmt.Printf("Error: Failed to mark %s flag as required: %v\n", name, err)
+			os.Exit(1)
+		}
+	}
+
+	return cmd
+}
+
+func runFetch(flags fetchFlags) error {
+	cfg, err := config.LoadConfig(flags.configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := requireMySQL(cfg, "fetch"); err != nil {
+		return err
+	}
+	if cfg, err = cfg.ForTenant(flags.tenant); err != nil {
+		return err
+	}
+	for _, db := range []string{flags.database, flags.restoreTo} {
+		if db == "" {
+			continue
+		}
+		if err := checkTenantDatabase(cfg, flags.tenant, db); err != nil {
+			return err
+		}
+	}
+	log, err := logger.NewFileLoggerWithSeparateFormats(cfg.Logging.Level, cfg.Logging.FilePath, cfg.Logging.Format, cfg.Logging.FileFormat)
+	if err != nil {
+		log = logger.NewLogger(cfg.Logging.Level)
+		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
+	}
+	applyOutputMode(log, cfg)
+
+	ctx := context.Background()
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	// The local manifest knows whether the backup was uploaded as a tree
+	// or as a seekable archive, and which destination holds it
+	destination := cfg.UploadFor(flags.database).Destination
+	entry, err := catalog.Find(cfg.Backup.Directory, flags.backupID)
+	seekable := err == nil && entry.Manifest.Seekable && entry.Manifest.Tool == "mydumper"
+	if err == nil && !seekable {
+		if entry.Manifest.Tool != "mydumper" || entry.Manifest.Compression != "" {
+			return fmt.Errorf("backup %s is neither an uncompressed mydumper directory nor a seekable archive, download it with 'tenangdb restore' instead", flags.backupID)
+		}
+	}
+	if err == nil && entry.Manifest.Destination != "" {
+		destination = entry.Manifest.Destination
+	}
+
+	localDir := filepath.Join(flags.out, flags.backupID)
+	if flags.restoreTo != "" {
+		tempDir, err := makeRestoreTempDir(cfg, "tenangdb-fetch-")
+		if err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+		localDir = filepath.Join(tempDir, flags.backupID)
+	}
+
+	uploader := upload.NewService(&cfg.Upload, log)
+	if seekable {
+		if err := fetchTableFromArchive(ctx, entry, destination, uploader, flags, localDir, log); err != nil {
+			return err
+		}
+	} else {
+		if destination == "" {
+			return fmt.Errorf("upload.destination is not configured")
+		}
+		remoteDir, err := upload.RemoteBackupDir(destination, flags.database, flags.backupID)
+		if err != nil {
+			return err
+		}
+		log.WithField("remote", remoteDir).WithField("table", flags.table).Info("☁️  Fetching table from cloud")
+		if err := uploader.FetchTable(ctx, remoteDir, flags.database, flags.table, localDir); err != nil {
+			return err
+		}
+	}
+
+	if flags.restoreTo == "" {
+		fmt.Printf("Fetched %s.%s to %s\n", flags.database, flags.table, localDir)
+		return nil
+	}
+	return restoreFetchedTable(ctx, cfg, localDir, flags, log)
+}
+
+// fetchTableFromArchive extracts the files of one table from a seekable
+// archive into localDir, decompressing only the frames that hold them. The
+// local archive is read when it is still there, the cloud copy otherwise.
+func fetchTableFromArchive(ctx context.Context, entry *catalog.Entry, destination string, uploader *upload.Service, flags fetchFlags, localDir string, log *logger.Logger) error {
+	var source compression.RangeReader
+	if file, err := os.Open(entry.ArtifactPath); err == nil {
+		defer file.Close()
+		source = compression.FileRange{File: file}
+		log.WithField("archive", entry.ArtifactPath).WithField("table", flags.table).Info("📦 Extracting table from local archive")
+	} else {
+		if destination == "" {
+			return fmt.Errorf("%s is not on this host and upload.destination is not configured", entry.Manifest.Artifact)
+		}
+		if entry.Manifest.CopyAt(destination) != nil {
+			return fmt.Errorf("%s is not on this host and %s holds a copy made for its destination policy, which is not seekable; download it with 'tenangdb restore' instead", entry.Manifest.Artifact, destination)
+		}
+		remote := upload.RemoteArtifactPath(destination, entry.ArtifactPath)
+		source = uploader.RangeReader(ctx, remote, entry.Manifest.Parts)
+		log.WithField("remote", remote).WithField("table", flags.table).Info("☁️  Fetching table from cloud archive")
+	}
+
+	archive, err := compression.OpenSeekable(source)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", entry.Manifest.Artifact, err)
+	}
+	defer archive.Close()
+
+	if err := os.MkdirAll(localDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", localDir, err)
+	}
+	found := false
+	for _, member := range archive.Members {
+		name := path.Base(member.Name)
+		if !isTableFile(name, flags.database, flags.table) {
+			continue
+		}
+		if err := extractArchiveMember(archive, member, filepath.Join(localDir, name)); err != nil {
+			os.RemoveAll(localDir)
+			return err
+		}
+		found = found || strings.HasPrefix(name, flags.database+"."+flags.table+"-schema.sql")
+	}
+	if !found {
+		os.RemoveAll(localDir)
+		return fmt.Errorf("table %s not found in %s", flags.table, entry.Manifest.Artifact)
+	}
+	return nil
+}
+
+// isTableFile reports whether a mydumper file is needed to restore table:
+// the backup metadata, the database and table schema, or the table's data.
+// These are the files FetchTable copies from a tree.
+func isTableFile(name, dbName, table string) bool {
+	return name == "metadata" ||
+		strings.HasPrefix(name, dbName+"-schema-create.sql") ||
+		strings.HasPrefix(name, dbName+"."+table+"-schema") ||
+		strings.HasPrefix(name, dbName+"."+table+".")
+}
+
+func extractArchiveMember(archive *compression.SeekableArchive, member compression.SeekableMember, target string) error {
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := archive.ExtractMember(member, file); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// restoreFetchedTable loads a fetched table with myloader, which drops and
+// recreates only the tables present in the directory
+func restoreFetchedTable(ctx context.Context, cfg *config.Config, localDir string, flags fetchFlags, log *logger.Logger) error {
+	mydumper := cfg.Database.Mydumper
+	if mydumper == nil || !mydumper.Enabled || mydumper.Myloader == nil || !mydumper.Myloader.Enabled {
+		return fmt.Errorf("restoring a single table needs database.mydumper.myloader enabled, use --out to keep the files instead")
+	}
+	if err := policy.CheckRestore(&cfg.Policy, cfg.Database.Host, flags.restoreTo); err != nil {
+		return err
+	}
+
+	if !flags.yes {
+		fmt.Printf("Table %s in database '%s' will be replaced with its copy from %s.\n", flags.table, flags.restoreTo, flags.backupID)
+		fmt.Print("Continue? [y/N]: ")
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			log.Info("Table restore cancelled by user")
+			return nil
+		}
+	}
+
+	closeTunnel := openDatabaseTunnel(ctx, cfg, log)
+	defer closeTunnel()
+
+	dbClient, err := database.NewClient(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbClient.Close()
+	dbClient.SetLogger(log)
+
+	if err := dbClient.RestoreBackup(ctx, localDir, flags.restoreTo, &cfg.Restore); err != nil {
+		return err
+	}
+	log.WithField("table", flags.table).WithField("target_database", flags.restoreTo).Info("✅ Table restored")
+	return nil
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/abdullahainun/tenangdb/internal/backup"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+// runIncremental archives the binary logs written since the last full or
+// incremental run, for point-in-time recovery on top of the full backups
+func runIncremental(ctx context.Context, cfg *config.Config, flags backupFlags, sigChan <-chan os.Signal, cancel context.CancelFunc, log *logger.Logger) {
+	closeTunnel := openDatabaseTunnel(ctx, cfg, log)
+	defer closeTunnel()
+
+	backupService, err := backup.NewService(cfg, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize backup service")
+	}
+	if flags.skipUpload && cfg.Upload.Enabled {
+		backupService.SkipUpload()
+		log.Info("Upload skipped, the binary logs are uploaded with the next incremental run")
+	}
+
+	type result struct {
+		archive *backup.BinlogArchive
+		err     error
+	}
+	done := make(chan result, 1)
+	go func() {
+		archive, err := backupService.ArchiveBinlogs(ctx)
+		done <- result{archive, err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+	case <-sigChan:
+		log.Info("Received shutdown signal, stopping binary log archiving...")
+		cancel()
+		r = <-done
+	}
+
+	if flags.output == outputJSON && r.archive != nil {
+		data, err := json.MarshalIndent(r.archive, "", "  ")
+		if err != nil {
+			log.WithError(err).Warn("Failed to marshal binary log archive")
+		} else {
+			fmt.Println(string(data))
+		}
+	}
+	if r.err != nil {
+		log.WithError(r.err).Error("❌ Incremental backup failed")
+		os.Exit(1)
+	}
+
+	entry := log.WithField("server", r.archive.Server).WithField("archive", r.archive.Dir)
+	if r.archive.File != "" {
+		entry = entry.WithField("files", len(r.archive.Files)).WithField("position", fmt.Sprintf("%s:%d", r.archive.File, r.archive.Position))
+	}
+	entry.Info("✅ Incremental backup completed")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// containerStateDirectory is the state directory in containers, declared as
+// a volume by the image
+const containerStateDirectory = "/var/lib/tenangdb"
+
+// containerMount is a volume the generated deployment mounts into the
+// container at a path chosen in the wizard
+type containerMount struct {
+	name     string // host directory in compose, subPath in Kubernetes
+	path     string
+	readOnly bool
+}
+
+// containerMounts returns the volumes for the chosen paths. Logs and
+// metrics kept inside the state or backup directory need no mount of their
+// own.
+func containerMounts(configPath, backupDir, stateDir, logPath, metricsPath string) []containerMount {
+	mounts := []containerMount{
+		{name: "config.yaml", path: configPath, readOnly: true},
+		{name: "backups", path: backupDir},
+		{name: "state", path: stateDir},
+	}
+	for _, extra := range []containerMount{
+		{name: "logs", path: filepath.Dir(logPath)},
+		{name: "metrics", path: filepath.Dir(metricsPath)},
+	} {
+		if extra.path == "." || isWithin(extra.path, stateDir) || isWithin(extra.path, backupDir) {
+			continue
+		}
+		mounts = append(mounts, extra)
+	}
+	return mounts
+}
+
+// isWithin reports whether path is dir or lies below it
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// writeContainerDeployment writes a docker-compose service or a Kubernetes
+// CronJob for runtime next to the config file and returns its path
+func writeContainerDeployment(runtime, configPath string, mounts []containerMount, metricsConfig config.MetricsConfig) (string, error) {
+	var name, content string
+	if runtime == config.ContainerKubernetes {
+		name = "tenangdb-cronjob.yaml"
+		content = generateCronJob(configPath, mounts)
+	} else {
+		name = "docker-compose.tenangdb.yml"
+		content = generateComposeService(configPath, mounts, metricsConfig)
+	}
+
+	path := filepath.Join(filepath.Dir(configPath), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return path, nil
+}
+
+func generateComposeService(configPath string, mounts []containerMount, metricsConfig config.MetricsConfig) string {
+	var b strings.Builder
+
+	b.WriteString("# TenangDB docker-compose service\n")
+	b.WriteString("# Generated by: tenangdb init\n")
+	b.WriteString("# Host directories are relative to this file; schedule runs with cron on the\n")
+	b.WriteString("# host, for example: 0 2 * * * docker compose -f docker-compose.tenangdb.yml run --rm tenangdb\n\n")
+	b.WriteString("services:\n")
+	b.WriteString("  tenangdb:\n")
+	b.WriteString("    image: ghcr.io/abdullahainun/tenangdb:latest\n")
+	b.WriteString("    volumes:\n")
+	for _, m := range mounts {
+		mode := ""
+		if m.readOnly {
+			mode = ":ro"
+		}
+		b.WriteString(fmt.Sprintf("      - ./%s:%s%s\n", m.name, m.path, mode))
+	}
+	b.WriteString(fmt.Sprintf("    command: [\"backup\", \"--force\", \"--config\", \"%s\"]\n", configPath))
+
+	if metricsConfig.Enabled {
+		b.WriteString("\n")
+		b.WriteString("  tenangdb-exporter:\n")
+		b.WriteString("    image: ghcr.io/abdullahainun/tenangdb:latest\n")
+		b.WriteString("    restart: unless-stopped\n")
+		b.WriteString("    ports:\n")
+		b.WriteString("      - \"9090:9090\"\n")
+		b.WriteString("    volumes:\n")
+		for _, m := range mounts {
+			if m.name == "backups" {
+				continue
+			}
+			mode := ""
+			if m.readOnly {
+				mode = ":ro"
+			}
+			b.WriteString(fmt.Sprintf("      - ./%s:%s%s\n", m.name, m.path, mode))
+		}
+		b.WriteString(fmt.Sprintf("    command: [\"tenangdb-exporter\", \"--config\", \"%s\"]\n", configPath))
+	}
+
+	return b.String()
+}
+
+func generateCronJob(configPath string, mounts []containerMount) string {
+	var b strings.Builder
+
+	b.WriteString("# TenangDB backup CronJob\n")
+	b.WriteString("# Generated by: tenangdb init\n")
+	b.WriteString("# Create the config first:\n")
+	b.WriteString(fmt.Sprintf("#   kubectl -n tenangdb create configmap tenangdb-config --from-file=config.yaml=%s\n", configPath))
+	b.WriteString("# and a PersistentVolumeClaim named tenangdb-data for backups and state.\n")
+	b.WriteString("apiVersion: batch/v1\n")
+	b.WriteString("kind: CronJob\n")
+	b.WriteString("metadata:\n")
+	b.WriteString("  name: tenangdb-backup\n")
+	b.WriteString("  namespace: tenangdb\n")
+	b.WriteString("spec:\n")
+	b.WriteString("  schedule: \"0 2 * * *\"\n")
+	b.WriteString("  concurrencyPolicy: Forbid\n")
+	b.WriteString("  jobTemplate:\n")
+	b.WriteString("    spec:\n")
+	b.WriteString("      template:\n")
+	b.WriteString("        spec:\n")
+	b.WriteString("          restartPolicy: OnFailure\n")
+	b.WriteString("          securityContext:\n")
+	b.WriteString("            runAsNonRoot: true\n")
+	b.WriteString("            runAsUser: 1001\n")
+	b.WriteString("            runAsGroup: 1001\n")
+	b.WriteString("            fsGroup: 1001\n")
+	b.WriteString("          containers:\n")
+	b.WriteString("          - name: tenangdb\n")
+	b.WriteString("            image: ghcr.io/abdullahainun/tenangdb:latest\n")
+	b.WriteString("            command: [\"/tenangdb\"]\n")
+	b.WriteString(fmt.Sprintf("            args: [\"backup\", \"--force\", \"--config\", \"%s\"]\n", configPath))
+	b.WriteString("            volumeMounts:\n")
+	for _, m := range mounts {
+		volume := "data"
+		if m.readOnly {
+			volume = "config"
+		}
+		b.WriteString(fmt.Sprintf("            - name: %s\n", volume))
+		b.WriteString(fmt.Sprintf("              mountPath: %s\n", m.path))
+		b.WriteString(fmt.Sprintf("              subPath: %s\n", m.name))
+		if m.readOnly {
+			b.WriteString("              readOnly: true\n")
+		}
+	}
+	b.WriteString("          volumes:\n")
+	b.WriteString("          - name: config\n")
+	b.WriteString("            configMap:\n")
+	b.WriteString("              name: tenangdb-config\n")
+	b.WriteString("          - name: data\n")
+	b.WriteString("            persistentVolumeClaim:\n")
+	b.WriteString("              claimName: tenangdb-data\n")
+
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+)
+
+// composeExporterPort is the port the exporter of the generated
+// docker-compose service listens on
+const composeExporterPort = "9090"
+
+// monitoringFiles are the files written by the monitoring step of init
+type monitoringFiles struct {
+	scrapeConfig string
+	alertRules   string
+	exporterUnit string // empty when the exporter runs some other way
+}
+
+// promptMonitoringSetup asks whether to generate the Prometheus side of the
+// metrics setup
+func promptMonitoringSetup() bool {
+	fmt.Printf("Prometheus needs a scrape config to collect the exporter metrics.\n")
+	fmt.Print("Generate a scrape config and alert rules? [Y/n]: ")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	if scanner.Scan() {
+		response := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		return response != "n" && response != "no"
+	}
+	return false
+}
+
+// writeMonitoringFiles writes a Prometheus scrape config and the recommended
+// alert rules next to the config file. withUnit also writes an exporter
+// unit for hosts where the systemd deployment did not install one.
+func writeMonitoringFiles(configPath, runtimeName string, metricsConfig config.MetricsConfig, withUnit bool, systemdUser string) (*monitoringFiles, error) {
+	dir := filepath.Dir(configPath)
+	files := &monitoringFiles{
+		scrapeConfig: filepath.Join(dir, "prometheus-tenangdb.yml"),
+		alertRules:   filepath.Join(dir, "tenangdb-alerts.yml"),
+	}
+
+	target := "localhost:" + metricsConfig.Port
+	if runtimeName != "" {
+		target = "tenangdb-exporter:" + composeExporterPort
+	}
+	if err := os.WriteFile(files.scrapeConfig, []byte(generateScrapeConfig(target)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write scrape config: %w", err)
+	}
+	if err := os.WriteFile(files.alertRules, []byte(metrics.AlertRules(metrics.DefaultMonitoringOptions)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write alert rules: %w", err)
+	}
+
+	if withUnit {
+		files.exporterUnit = filepath.Join(dir, "tenangdb-exporter.service")
+		unit := generateExporterService(systemdUser, exporterBinaryPath(), configPath, metricsConfig.Port)
+		if err := os.WriteFile(files.exporterUnit, []byte(unit), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write exporter unit: %w", err)
+		}
+	}
+	return files, nil
+}
+
+// exporterUnitApplies reports whether init should write an exporter unit:
+// on Linux hosts with systemd that did not get the full systemd deployment
+func exporterUnitApplies(runtimeName string, deployedSystemd bool) bool {
+	if runtimeName != "" || deployedSystemd || runtime.GOOS != "linux" {
+		return false
+	}
+	_, err := os.Stat("/run/systemd/system")
+	return err == nil
+}
+
+// exporterBinaryPath finds tenangdb-exporter next to this binary or on the
+// PATH, falling back to where the systemd deployment installs it
+func exporterBinaryPath() string {
+	if execPath, err := os.Executable(); err == nil {
+		path := filepath.Join(filepath.Dir(execPath), "tenangdb-exporter")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path, err := exec.LookPath("tenangdb-exporter"); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+	}
+	return "/opt/tenangdb/tenangdb-exporter"
+}
+
+func generateScrapeConfig(target string) string {
+	var b strings.Builder
+
+	b.WriteString("# TenangDB Prometheus scrape config\n")
+	b.WriteString("# Generated by: tenangdb init\n")
+	b.WriteString("# Merge into prometheus.yml and copy tenangdb-alerts.yml next to it. Replace\n")
+	b.WriteString("# the target with this host's address when Prometheus runs elsewhere; the\n")
+	b.WriteString("# exporter systemd unit only accepts connections from localhost until its\n")
+	b.WriteString("# IPAddressAllow lines are extended.\n\n")
+	b.WriteString("rule_files:\n")
+	b.WriteString("  - tenangdb-alerts.yml\n\n")
+	b.WriteString("scrape_configs:\n")
+	b.WriteString("  - job_name: tenangdb\n")
+	b.WriteString("    scrape_interval: 30s\n")
+	b.WriteString("    metrics_path: /metrics\n")
+	b.WriteString("    static_configs:\n")
+	b.WriteString(fmt.Sprintf("      - targets: [\"%s\"]\n", target))
+
+	return b.String()
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/abdullahainun/tenangdb/internal/backup"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/kubernetes"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newKubernetesCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "k8s",
+		Short: "Back up MySQL instances discovered in a Kubernetes cluster",
+		Long: `Discover MySQL instances from Services annotated with tenangdb.io/backup: "true"
+and back them up. Credentials come from the Secret named by tenangdb.io/secret.
+Meant to run in-cluster, for example from the tenangdb CronJob, with a service
+account allowed to list Services and read those Secrets.`,
+	}
+	cmd.AddCommand(newKubernetesDiscoverCommand())
+	cmd.AddCommand(newKubernetesBackupCommand())
+	return cmd
+}
+
+func newKubernetesDiscoverCommand() *cobra.Command {
+	var configFile string
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "List the MySQL instances annotated for backup",
+		Example: `  tenangdb k8s discover
+  tenangdb k8s discover --output json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateOutputFormat(output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := runKubernetesDiscover(configFile, output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&output, "output", outputText, "output format: text or json")
+
+	return cmd
+}
+
+func newKubernetesBackupCommand() *cobra.Command {
+	var configFile string
+
+	cmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Back up every discovered MySQL instance",
+		Long: `Back up each MySQL instance annotated for backup, one after the other, with the
+backup settings of the config. Backups of an instance go to
+{backup.directory}/{namespace}/{service} and are uploaded below
+{upload.destination}/{namespace}/{service}. The command exits with status 1 when
+any instance could not be backed up completely.`,
+		Example: `  tenangdb k8s backup --config /config.yaml`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runKubernetesBackup(configFile); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+
+	return cmd
+}
+
+// discoverInstances loads the config and lists the annotated instances
+func discoverInstances(ctx context.Context, configFile string) (*config.Config, []kubernetes.Instance, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	client, err := kubernetes.NewClient(&cfg.Kubernetes)
+	if err != nil {
+		return nil, nil, err
+	}
+	instances, err := client.Discover(ctx, cfg.Kubernetes.Namespaces)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to discover instances: %w", err)
+	}
+	return cfg, instances, nil
+}
+
+// k8sInstance is the JSON form of an instance in `tenangdb k8s discover`
+type k8sInstance struct {
+	Namespace string   `json:"namespace"`
+	Service   string   `json:"service"`
+	Host      string   `json:"host"`
+	Port      int      `json:"port"`
+	Username  string   `json:"username"`
+	Databases []string `json:"databases,omitempty"`
+}
+
+func runKubernetesDiscover(configFile, output string) error {
+	_, instances, err := discoverInstances(context.Background(), configFile)
+	if err != nil {
+		return err
+	}
+
+	if output == outputJSON {
+		result := make([]k8sInstance, 0, len(instances))
+		for _, instance := range instances {
+			result = append(result, k8sInstance{
+				Namespace: instance.Namespace,
+				Service:   instance.Service,
+				Host:      instance.Host,
+				Port:      instance.Port,
+				Username:  instance.Username,
+				Databases: instance.Databases,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	if len(instances) == 0 {
+		fmt.Printf("No services annotated with %s: \"true\"\n", kubernetes.AnnotationBackup)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INSTANCE\tADDRESS\tUSER\tDATABASES")
+	for _, instance := range instances {
+		databases := strings.Join(instance.Databases, ",")
+		if databases == "" {
+			databases = "(all)"
+		}
+		fmt.Fprintf(w, "%s\t%s:%d\t%s\t%s\n", instance.Name(), instance.Host, instance.Port, instance.Username, databases)
+	}
+	return w.Flush()
+}
+
+func runKubernetesBackup(configFile string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Stop starting databases on shutdown, like the backup command
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	cfg, instances, err := discoverInstances(ctx, configFile)
+	if err != nil {
+		return err
+	}
+
+	log, err := logger.NewFileLoggerWithSeparateFormats(cfg.Logging.Level, cfg.Logging.FilePath, cfg.Logging.Format, cfg.Logging.FileFormat)
+	if err != nil {
+		log = logger.NewLogger(cfg.Logging.Level)
+		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
+	}
+	applyOutputMode(log, cfg)
+
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	if len(instances) == 0 {
+		log.Warn("No services annotated with " + kubernetes.AnnotationBackup + ": \"true\"")
+		return nil
+	}
+	log.WithField("instances", len(instances)).Info("☸️ Discovered MySQL instances")
+
+	var failed []string
+	for i := range instances {
+		instance := &instances[i]
+		if ctx.Err() != nil {
+			failed = append(failed, instance.Name())
+			continue
+		}
+		if err := backupInstance(ctx, cfg, instance, log); err != nil {
+			log.WithError(err).WithField("instance", instance.Name()).Error("❌ Backup of " + instance.Name() + " failed")
+			failed = append(failed, instance.Name())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d instances were not backed up completely: %s", len(failed), len(instances), strings.Join(failed, ", "))
+	}
+	log.Info("✅ All discovered instances backed up")
+	return nil
+}
+
+// backupInstance runs a backup of one discovered instance with the backup
+// settings of cfg
+func backupInstance(ctx context.Context, cfg *config.Config, instance *kubernetes.Instance, log *logger.Logger) error {
+	instanceCfg := instanceConfig(cfg, instance)
+	databases := instance.Databases
+	if len(databases) == 0 {
+		dbClient, err := database.NewClient(&instanceCfg.Database)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		databases, err = dbClient.ListDatabases(ctx)
+		dbClient.Close()
+		if err != nil {
+			return err
+		}
+		if len(databases) == 0 {
+			log.WithField("instance", instance.Name()).Warn("No user databases to back up")
+			return nil
+		}
+	}
+	instanceCfg.Backup.Databases = databases
+
+	log.WithField("instance", instance.Name()).WithField("databases", databases).Info("🔄 Backing up " + instance.Name())
+	service, err := backup.NewService(&instanceCfg, log)
+	if err != nil {
+		return err
+	}
+	service.SetVersion(version)
+	if err := service.Run(ctx); err != nil {
+		return err
+	}
+	if stats := service.GetStatistics(); stats.FailedBackups > 0 {
+		return fmt.Errorf("%d of %d databases failed", stats.FailedBackups, stats.TotalDatabases)
+	}
+	return nil
+}
+
+// instanceConfig is cfg pointed at a Kubernetes instance, with its backups
+// kept under {backup.directory}/{namespace}/{service}
+func instanceConfig(cfg *config.Config, instance *kubernetes.Instance) config.Config {
+	instanceCfg := *cfg
+	instanceCfg.Database.Host = instance.Host
+	instanceCfg.Database.Port = instance.Port
+	instanceCfg.Database.Username = instance.Username
+	instanceCfg.Database.Password = instance.Password
+	instanceCfg.Database.Socket = ""
+	instanceCfg.Database.SSH = config.SSHConfig{}
+	instanceCfg.Database.Container = ""
+	instanceCfg.Backup.Directory = filepath.Join(cfg.Backup.Directory, instance.Namespace, instance.Service)
+	if cfg.Upload.Destination != "" {
+		instanceCfg.Upload.Destination = strings.TrimSuffix(cfg.Upload.Destination, "/") + "/" + instance.Namespace + "/" + instance.Service
+	}
+	return instanceCfg
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+
+	"github.com/spf13/cobra"
+)
+
+func newListCommand() *cobra.Command {
+	var configFile string
+	var database string
+	var labelPairs []string
+	var output string
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List local backups",
+		Long:  `List local backups from their manifests, newest first, optionally filtered by database or labels.`,
+		Example: `  tenangdb list
+  tenangdb list --database myapp
+  tenangdb list --label reason=pre-migration --output json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateOutputFormat(output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			labels, err := catalog.ParseLabels(labelPairs)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := runList(configFile, database, tenant, labels, output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&database, "database", "", "only list backups of this database")
+	cmd.Flags().StringArrayVar(&labelPairs, "label", nil, "only list backups with this key=value label (repeatable)")
+	cmd.Flags().StringVar(&output, "output", outputText, "output format: text or json")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only list backups of databases of the named tenant")
+
+	return cmd
+}
+
+// listedBackup is the JSON form of a backup in `tenangdb list`
+type listedBackup struct {
+	ID           string            `json:"id"`
+	Database     string            `json:"database"`
+	Path         string            `json:"path"`
+	CreatedAt    string            `json:"created_at"`
+	SizeBytes    int64             `json:"size_bytes"`
+	Tool         string            `json:"tool"`
+	ToolVersions map[string]string `json:"tool_versions,omitempty"`
+	Compression  string            `json:"compression,omitempty"`
+	Pinned       bool              `json:"pinned"`
+	AdHoc        bool              `json:"ad_hoc,omitempty"`
+	Uploaded     bool              `json:"uploaded"` // a verified copy is in cloud storage
+	Labels       map[string]string `json:"labels,omitempty"`
+}
+
+func runList(configFile, database, tenant string, labels map[string]string, output string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+	entries = tenantEntries(cfg, tenant, entries)
+
+	var matched []catalog.Entry
+	for _, entry := range entries {
+		if database != "" && entry.Manifest.Database != database {
+			continue
+		}
+		if !entry.HasLabels(labels) {
+			continue
+		}
+		matched = append(matched, entry)
+	}
+
+	if output == outputJSON {
+		backups := make([]listedBackup, 0, len(matched))
+		for _, entry := range matched {
+			m := entry.Manifest
+			backups = append(backups, listedBackup{
+				ID:           entry.ID,
+				Database:     m.Database,
+				Path:         entry.ArtifactPath,
+				CreatedAt:    m.CreatedAt.Format(time.RFC3339),
+				SizeBytes:    m.SizeBytes,
+				Tool:         m.Tool,
+				ToolVersions: m.ToolVersions,
+				Compression:  m.Compression,
+				Pinned:       m.Pinned,
+				AdHoc:        m.AdHoc,
+				Uploaded:     isUploaded(entry.ArtifactPath),
+				Labels:       m.Labels,
+			})
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(backups)
+	}
+
+	if len(matched) == 0 {
+		fmt.Println("No backups found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATABASE\tCREATED\tSIZE\tTOOL\tPINNED\tUPLOADED\tLABELS")
+	for _, entry := range matched {
+		m := entry.Manifest
+		pinned := ""
+		if m.Pinned {
+			pinned = "yes"
+		}
+		uploaded := "no"
+		if isUploaded(entry.ArtifactPath) {
+			uploaded = "yes"
+		}
+		database := m.Database
+		if m.AdHoc {
+			database += " (ad-hoc)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID,
+			database,
+			m.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			formatFileSize(m.SizeBytes),
+			strings.TrimSpace(m.Tool+" "+m.ToolVersions[m.Tool]),
+			pinned,
+			uploaded,
+			catalog.FormatLabels(m.Labels))
+	}
+	return w.Flush()
+}
+
+// isUploaded reports whether an artifact has an upload marker
+func isUploaded(artifactPath string) bool {
+	marker, _ := manifest.LoadUploaded(artifactPath)
+	return marker != nil
+}
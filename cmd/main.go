@@ -6,6 +6,7 @@ import (
 	"crypto/md5"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -16,9 +17,17 @@ import (
 	"time"
 
 	"github.com/abdullahainun/tenangdb/internal/backup"
+	"github.com/abdullahainun/tenangdb/internal/catalog"
 	"github.com/abdullahainun/tenangdb/internal/config"
 	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
 	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/policy"
+	"github.com/abdullahainun/tenangdb/internal/progress"
+	"github.com/abdullahainun/tenangdb/internal/resources"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/internal/schedule"
+	"github.com/abdullahainun/tenangdb/internal/upload"
 	"github.com/abdullahainun/tenangdb/pkg/database"
 
 	"github.com/spf13/cobra"
@@ -41,6 +50,9 @@ func main() {
 
 	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
+	rootCmd.PersistentFlags().BoolVarP(&quietOutput, "quiet", "q", false, "only print errors, as JSON lines")
+	rootCmd.PersistentFlags().BoolVarP(&verboseOutput, "verbose", "v", false, "print debug details including external commands (secrets redacted)")
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
 	
 	// Add version flag
 	var showVersionFlag bool
@@ -61,6 +73,55 @@ func main() {
 	// Add restore subcommand
 	rootCmd.AddCommand(newRestoreCommand())
 
+	// Add list command
+	rootCmd.AddCommand(newListCommand())
+
+	// Add pin and unpin commands
+	rootCmd.AddCommand(newPinCommand())
+	rootCmd.AddCommand(newUnpinCommand())
+
+	// Add export-bundle command
+	rootCmd.AddCommand(newExportBundleCommand())
+
+	// Add upload command
+	rootCmd.AddCommand(newUploadCommand())
+
+	// Add refresh-standby command
+	rootCmd.AddCommand(newRefreshStandbyCommand())
+
+	// Add sla command
+	rootCmd.AddCommand(newSLACommand())
+
+	// Add pause and resume commands
+	rootCmd.AddCommand(newPauseCommand())
+	rootCmd.AddCommand(newResumeCommand())
+
+	// Add report command
+	rootCmd.AddCommand(newReportCommand())
+
+	// Add export-monitoring command
+	rootCmd.AddCommand(newExportMonitoringCommand())
+
+	// Add bench command
+	rootCmd.AddCommand(newBenchCommand())
+
+	// Add fetch command
+	rootCmd.AddCommand(newFetchCommand())
+
+	// Add verify command
+	rootCmd.AddCommand(newVerifyCommand())
+
+	// Add rekey command
+	rootCmd.AddCommand(newRekeyCommand())
+
+	// Add drill command
+	rootCmd.AddCommand(newDrillCommand())
+
+	// Add k8s command
+	rootCmd.AddCommand(newKubernetesCommand())
+
+	// Add operator command
+	rootCmd.AddCommand(newOperatorCommand())
 
 	// Add version command
 	rootCmd.AddCommand(newVersionCommand())
@@ -83,13 +144,48 @@ func newBackupCommand() *cobra.Command {
 	var databases string
 	var force bool
 	var yes bool
+	var tenant string
+	var flags backupFlags
+	var labelPairs []string
 
 	cmd := &cobra.Command{
 		Use:   "backup",
 		Short: "Run database backup",
 		Long:  `Backup databases to local directory with optional cloud upload.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runBackup(configFile, logLevel, dryRun, databases, force, yes)
+			if err := validateOutputFormat(flags.output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if err := config.ValidateTargetCompat(flags.targetCompat); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			labels, err := catalog.ParseLabels(labelPairs)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			flags.labels = labels
+			for _, pattern := range splitDatabases(flags.excludeDatabases) {
+				if _, err := filepath.Match(pattern, ""); err != nil {
+					fmt.Printf("Error: invalid --exclude-databases pattern %q: %v\n", pattern, err)
+					os.Exit(1)
+				}
+			}
+			if err := flags.validateTuning(); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if flags.adHoc && strings.TrimSpace(databases) == "" {
+				fmt.Printf("Error: --ad-hoc needs --databases\n")
+				os.Exit(1)
+			}
+			if flags.incremental && (flags.adHoc || databases != "" || flags.excludeDatabases != "" || tenant != "") {
+				fmt.Printf("Error: --incremental archives the binary logs of the whole server, it can't be combined with --ad-hoc, --databases, --exclude-databases or --tenant\n")
+				os.Exit(1)
+			}
+			runBackup(configFile, logLevel, dryRun, databases, force, yes, tenant, flags)
 		},
 	}
 
@@ -97,13 +193,124 @@ func newBackupCommand() *cobra.Command {
 	cmd.Flags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be backed up without actually running backup")
 	cmd.Flags().StringVar(&databases, "databases", "", "comma-separated list of databases to backup (overrides config)")
+	cmd.Flags().StringVar(&flags.excludeDatabases, "exclude-databases", "", "comma-separated databases or globs to leave out of this run")
 	cmd.Flags().BoolVar(&force, "force", false, "skip backup frequency confirmation prompts")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip confirmation prompts (for automated mode)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only backup databases of the named tenant")
+	cmd.Flags().BoolVar(&flags.noProgress, "no-progress", false, "print plain logs instead of progress bars on a terminal")
+	cmd.Flags().StringVar(&flags.output, "output", outputText, "format of the --dry-run plan and the run result: text or json")
+	cmd.Flags().StringVar(&flags.reportPath, "report", "", "write the JSON run result to this file (overrides config)")
+	cmd.Flags().StringArrayVar(&labelPairs, "label", nil, "label the backups of this run as key=value (repeatable)")
+	cmd.Flags().StringVar(&flags.targetCompat, "target-compat", "", "make the dump restorable on an older server: 5.7 (overrides backup.target_compat)")
+	cmd.Flags().BoolVar(&flags.skipUpload, "skip-upload", false, "only create local backups; upload them later with 'tenangdb upload --run-id'")
+	cmd.Flags().IntVar(&flags.concurrency, "concurrency", 0, "databases backed up in parallel (overrides backup.concurrency)")
+	cmd.Flags().IntVar(&flags.batchSize, "batch-size", 0, "databases per batch (overrides backup.batch_size)")
+	cmd.Flags().IntVar(&flags.compressionLevel, "compression-level", 0, "compression level 1-9, turns off compression.auto (overrides backup.compression.level)")
+	cmd.Flags().BoolVar(&flags.ignorePause, "ignore-pause", false, "back up even while backups are paused with 'tenangdb pause'")
+	cmd.Flags().BoolVar(&flags.adHoc, "ad-hoc", false, "back up --databases even if they are not in the config, with default settings, and mark the backups ad-hoc")
+	cmd.Flags().BoolVar(&flags.incremental, "incremental", false, "archive the binary logs written since the last run instead of dumping databases, for point-in-time recovery")
 
 	return cmd
 }
 
-func runBackup(configFile, logLevel string, dryRun bool, databases string, force bool, yes bool) {
+// backupFlags holds backup options that only exist on the backup command
+type backupFlags struct {
+	noProgress   bool
+	output       string
+	labels       map[string]string
+	targetCompat string
+	skipUpload   bool
+	reportPath   string
+	adHoc        bool
+	ignorePause  bool
+	incremental  bool // archive binary logs instead of dumping
+
+	excludeDatabases string // comma-separated names or globs
+
+	// Tuning overrides, 0 keeps the configured value
+	concurrency      int
+	batchSize        int
+	compressionLevel int
+}
+
+// validateTuning checks the tuning overrides
+func (f *backupFlags) validateTuning() error {
+	if f.concurrency < 0 || f.batchSize < 0 {
+		return fmt.Errorf("--concurrency and --batch-size must be greater than 0")
+	}
+	if f.compressionLevel != 0 && (f.compressionLevel < 1 || f.compressionLevel > 9) {
+		return fmt.Errorf("--compression-level must be between 1 and 9")
+	}
+	return nil
+}
+
+// applyTuning overrides the configured tuning with the flags that were set
+// and reports whether any was
+func (f *backupFlags) applyTuning(cfg *config.Config) bool {
+	if f.concurrency > 0 {
+		cfg.Backup.Concurrency = f.concurrency
+	}
+	if f.batchSize > 0 {
+		cfg.Backup.BatchSize = f.batchSize
+	}
+	if f.compressionLevel > 0 {
+		cfg.Backup.Compression.Level = f.compressionLevel
+		cfg.Backup.Compression.Auto = false
+	}
+	return f.concurrency > 0 || f.batchSize > 0 || f.compressionLevel > 0
+}
+
+// splitDatabases splits a comma-separated --databases value
+func splitDatabases(databases string) []string {
+	if databases == "" {
+		return nil
+	}
+	// Commas inside backticks or double quotes are part of the name
+	var selected []string
+	var quote rune
+	start := 0
+	for i, r := range databases {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '`' || r == '"':
+			quote = r
+		case r == ',':
+			selected = append(selected, config.UnquoteDatabaseName(databases[start:i]))
+			start = i + 1
+		}
+	}
+	return append(selected, config.UnquoteDatabaseName(databases[start:]))
+}
+
+// excludeDatabases drops the databases matching any of patterns, names or
+// globs, and returns the patterns that matched none
+func excludeDatabases(databases, patterns []string) (kept, unmatched []string) {
+	matched := make(map[string]bool, len(patterns))
+	for _, db := range databases {
+		excluded := false
+		for _, pattern := range patterns {
+			if ok, _ := filepath.Match(pattern, db); ok {
+				matched[pattern] = true
+				excluded = true
+			}
+		}
+		if !excluded {
+			kept = append(kept, db)
+		}
+	}
+	for _, pattern := range patterns {
+		if !matched[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+	return kept, unmatched
+}
+
+func runBackup(configFile, logLevel string, dryRun bool, databases string, force bool, yes bool, tenant string, flags backupFlags) {
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -111,24 +318,69 @@ func runBackup(configFile, logLevel string, dryRun bool, databases string, force
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// Load configuration first to get log file path
-	cfg, err := config.LoadConfig(configFile)
+	selectedDatabases := splitDatabases(databases)
+
+	// Load configuration first to get log file path. Ad-hoc databases
+	// don't have to be in it.
+	var cfg *config.Config
+	var err error
+	if flags.adHoc {
+		cfg, err = config.LoadAdHocConfig(configFile, selectedDatabases)
+	} else {
+		cfg, err = config.LoadConfig(configFile)
+	}
 	if err != nil {
 		// Use basic logger if config fails
 		log := logger.NewLogger(logLevel)
 		log.WithError(err).Fatal("Failed to load configuration")
 	}
 
+	// Scope configuration to a single tenant if requested. Command-line
+	// overrides below change this copy, never the loaded config.
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		log := logger.NewLogger(logLevel)
+		log.WithError(err).Fatal("Failed to apply tenant")
+	}
+
+	if flags.targetCompat != "" {
+		cfg.Backup.TargetCompat = flags.targetCompat
+	}
+	if flags.applyTuning(cfg) {
+		log := logger.NewLogger(logLevel)
+		log.Infof("Tuning from command line: concurrency %d, batch size %d, compression level %d",
+			cfg.Backup.Concurrency, cfg.Backup.BatchSize, cfg.Backup.Compression.Level)
+	}
+
 	// Override databases from command line if specified
-	if databases != "" {
-		selectedDatabases := strings.Split(databases, ",")
-		for i, db := range selectedDatabases {
-			selectedDatabases[i] = strings.TrimSpace(db)
+	if flags.adHoc {
+		cfg = cfg.ForAdHoc(selectedDatabases)
+		log := logger.NewLogger(logLevel)
+		log.Infof("Ad-hoc backup of databases from command line: %v", selectedDatabases)
+	} else if databases != "" {
+		for _, db := range selectedDatabases {
+			if tenant != "" && !cfg.HasDatabase(db) {
+				log := logger.NewLogger(logLevel)
+				log.Fatalf("Database %s does not belong to tenant %s", db, tenant)
+			}
 		}
 		cfg.Backup.Databases = selectedDatabases
 		log := logger.NewLogger(logLevel)
 		log.Infof("Using databases from command line: %v", selectedDatabases)
 	}
+
+	// Leave databases out of this run only
+	if flags.excludeDatabases != "" {
+		log := logger.NewLogger(logLevel)
+		kept, unmatched := excludeDatabases(cfg.Backup.Databases, splitDatabases(flags.excludeDatabases))
+		for _, pattern := range unmatched {
+			log.Warnf("--exclude-databases %s matches no database of this run", pattern)
+		}
+		if len(kept) == 0 {
+			log.Fatal("--exclude-databases leaves no databases to back up")
+		}
+		cfg.Backup.Databases = kept
+		log.Infof("Excluding databases from this run, backing up: %v", kept)
+	}
 	
 	// Override skip confirmation if force or yes flag is used
 	if force || yes {
@@ -150,33 +402,89 @@ func runBackup(configFile, logLevel string, dryRun bool, databases string, force
 		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
 	}
 
+	applyOutputMode(log, cfg)
+
+	// Stay within the configured CPU and memory envelope
+	if err := resources.Apply(&cfg.Resources, log); err != nil {
+		log.WithError(err).Fatal("Failed to apply resource limits")
+	}
+
+	// Keep stdout for the JSON plan or run result
+	if flags.output == outputJSON {
+		log.SetOutput(os.Stderr)
+	}
+
+	// Tag this invocation so its logs, metrics and artifacts can be correlated
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
 	// Initialize Prometheus metrics if enabled (before any user interaction)
 	if cfg.Metrics.Enabled {
 		metrics.Init()
-		log.WithField("port", cfg.Metrics.Port).Debug("Initializing Prometheus metrics server")
-		go func() {
-			if err := metrics.StartMetricsServer(cfg.Metrics.Port); err != nil {
+		if cfg.Metrics.InlineServer {
+			metricsServer, err := metrics.StartServer(cfg.Metrics.Port)
+			if err != nil {
 				log.WithError(err).WithField("port", cfg.Metrics.Port).Warn("Metrics server failed to start (backup will continue)")
 			} else {
 				log.WithField("port", cfg.Metrics.Port).Debug("Metrics server started successfully")
+				defer func() {
+					if err := metricsServer.Shutdown(5 * time.Second); err != nil {
+						log.WithError(err).Warn("Failed to shut down metrics server")
+					}
+				}()
 			}
-		}()
-		// Give metrics server a moment to start and potentially fail
-		time.Sleep(200 * time.Millisecond)
+		}
+	}
+
+	if dryRun && flags.incremental {
+		log.WithField("archive", filepath.Join(cfg.Backup.Directory, backup.BinlogDirName)).
+			Info("DRY RUN MODE: Would archive the closed binary logs of the server")
+		return
+	}
+
+	if dryRun && flags.output == outputJSON {
+		if err := writeBackupPlan(ctx, cfg, flags.skipUpload, log); err != nil {
+			log.WithError(err).Fatal("Failed to build backup plan")
+		}
+		return
 	}
 
 	if dryRun {
 		log.Info("DRY RUN MODE: No actual backup will be performed")
 		log.WithField("databases", cfg.Backup.Databases).Info("Would backup these databases")
 		log.WithField("backup_directory", cfg.Backup.Directory).Info("Backup directory")
-		if cfg.Upload.Enabled {
+		if cfg.Upload.Enabled && !flags.skipUpload {
 			log.WithField("upload_destination", cfg.Upload.Destination).Info("Would upload to")
 		}
 		return
 	}
 
-	// Check backup frequency if enabled
-	if cfg.Backup.CheckLastBackupTime && !force && !checkBackupFrequency(cfg, log) {
+	// Stay out of maintenance windows set with tenangdb pause
+	if !flags.ignorePause {
+		paused, err := checkPaused(cfg)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to check for a maintenance pause")
+		}
+		if paused != nil {
+			entry := log.WithField("until", paused.Until.Format(time.RFC3339))
+			if paused.Reason != "" {
+				entry = entry.WithField("reason", paused.Reason)
+			}
+			entry.Info("⏸️ Backups are paused, skipping this run. Use --ignore-pause to back up anyway")
+			return
+		}
+	}
+
+	// Incremental runs only archive binary logs, they are no full backup
+	// for the frequency check and need no confirmation
+	if flags.incremental {
+		runIncremental(ctx, cfg, flags, sigChan, cancel, log)
+		return
+	}
+
+	// Check backup frequency if enabled; ad-hoc runs are not scheduled runs
+	if cfg.Backup.CheckLastBackupTime && !force && !flags.adHoc && !checkBackupFrequency(cfg, log) {
 		log.Info("Backup cancelled due to frequency check")
 		return
 	}
@@ -188,10 +496,37 @@ func runBackup(configFile, logLevel string, dryRun bool, databases string, force
 	}
 
 	// Initialize backup service
+	// Reach the database through the SSH bastion if configured
+	closeTunnel := openDatabaseTunnel(ctx, cfg, log)
+	defer closeTunnel()
+
+	if flags.adHoc {
+		if err := checkAdHocDatabases(ctx, cfg); err != nil {
+			log.WithError(err).Fatal("Cannot run ad-hoc backup")
+		}
+	}
+
 	backupService, err := backup.NewService(cfg, log)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to initialize backup service")
 	}
+	backupService.SetLabels(flags.labels)
+	backupService.SetVersion(version)
+	if flags.adHoc {
+		backupService.MarkAdHoc()
+	}
+	if flags.skipUpload && cfg.Upload.Enabled {
+		backupService.SkipUpload()
+		log.WithField("run_id", runID).Info("Upload skipped, run 'tenangdb upload --run-id " + runID + "' to upload this run's backups")
+	}
+
+	// Draw progress bars on interactive terminals; piped output keeps plain logs
+	var display *progress.Display
+	if !flags.noProgress && !quietOutput && flags.output != outputJSON && progress.IsTerminal(os.Stdout) {
+		display = progress.New(os.Stdout, len(cfg.Backup.Databases), backupService.Phases())
+		log.SetOutput(display)
+		backupService.SetProgress(display)
+	}
 
 	// Start backup process
 	done := make(chan error, 1)
@@ -202,14 +537,26 @@ func runBackup(configFile, logLevel string, dryRun bool, databases string, force
 	// Wait for backup completion or shutdown signal
 	select {
 	case err := <-done:
+		display.Close()
+
+		// Collect the errors of every database in one run result
+		if flags.reportPath == "" {
+			flags.reportPath = cfg.Backup.ReportPath
+		}
+		writeRunResult(backupService.Result(), flags.reportPath, flags.output, log)
+		writeTenantResults(cfg, backupService.Result(), log)
+
 		if err != nil {
 			log.WithError(err).Error("Backup process failed")
 			os.Exit(1)
 		}
 		
-		// Update last backup time tracking
-		if err := updateLastBackupTime(cfg.Backup.Directory); err != nil {
-			log.WithError(err).Warn("Failed to update backup timestamp")
+		// Update last backup time tracking, which the frequency check
+		// uses for the configured databases
+		if !flags.adHoc {
+			if err := updateLastBackupTime(cfg.StateDirectory, cfg.Backup.Directory); err != nil {
+				log.WithError(err).Warn("Failed to update backup timestamp")
+			}
 		}
 		
 		// Get backup statistics for accurate final message
@@ -227,6 +574,7 @@ func runBackup(configFile, logLevel string, dryRun bool, databases string, force
 			os.Exit(1)
 		}
 	case <-sigChan:
+		display.Close()
 		log.Info("Received shutdown signal, gracefully shutting down...")
 		cancel()
 		// Wait for backup to finish gracefully
@@ -235,6 +583,18 @@ func runBackup(configFile, logLevel string, dryRun bool, databases string, force
 		case <-time.After(30 * time.Second):
 			log.Warn("Backup process did not finish within 30 seconds, forcing exit")
 		}
+
+		// The run result lists the databases the shutdown kept from starting
+		if flags.reportPath == "" {
+			flags.reportPath = cfg.Backup.ReportPath
+		}
+		result := backupService.Result()
+		writeRunResult(result, flags.reportPath, flags.output, log)
+		writeTenantResults(cfg, result, log)
+		if len(result.Skipped) > 0 {
+			log.WithField("skipped_databases", result.Skipped).Warn(fmt.Sprintf("⏹️ Backup interrupted, %d databases were not backed up", len(result.Skipped)))
+		}
+		os.Exit(1)
 	}
 }
 
@@ -255,7 +615,7 @@ func run(cmd *cobra.Command, args []string) {
 	log.Debug("DEPRECATED: Running tenangdb without 'backup' subcommand is deprecated. Use 'tenangdb backup' instead.")
 	
 	// Call the new backup function for backward compatibility
-	runBackup(configFile, logLevel, dryRun, databases, false, false)
+	runBackup(configFile, logLevel, dryRun, databases, false, false, "", backupFlags{output: outputText})
 }
 
 func newCleanupCommand() *cobra.Command {
@@ -265,27 +625,39 @@ func newCleanupCommand() *cobra.Command {
 	var force bool
 	var databases string
 	var yes bool
+	var tenant string
+	var allowUnverified bool
+	var reportPath string
+	var output string
 
 	cmd := &cobra.Command{
 		Use:   "cleanup",
 		Short: "Cleanup uploaded backup files",
 		Long:  `Remove local backup files that have been successfully uploaded to cloud storage.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runCleanup(configFile, logLevel, dryRun, force, databases, yes)
+			if err := validateOutputFormat(output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			runCleanup(configFile, logLevel, dryRun, force, databases, yes, tenant, allowUnverified, reportPath, output)
 		},
 	}
 
 	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
 	cmd.Flags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be deleted without actually deleting")
-	cmd.Flags().BoolVar(&force, "force", false, "force cleanup regardless of day (bypass weekend-only restriction)")
+	cmd.Flags().BoolVar(&force, "force", false, "force cleanup regardless of cleanup.schedule")
 	cmd.Flags().StringVar(&databases, "databases", "", "comma-separated list of databases to cleanup (overrides config)")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip confirmation prompts (for automated mode)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only cleanup databases of the named tenant")
+	cmd.Flags().BoolVar(&allowUnverified, "allow-unverified", false, "allow deleting old backups that were never uploaded or are not verified in cloud storage")
+	cmd.Flags().StringVar(&reportPath, "report", "", "write a JSON cleanup report to this file (overrides config)")
+	cmd.Flags().StringVar(&output, "output", outputText, "format of the --dry-run plan: text or json")
 
 	return cmd
 }
 
-func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases string, yes bool) {
+func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases string, yes bool, tenant string, allowUnverified bool, reportPath string, output string) {
 	ctx := context.Background()
 
 	// Load configuration first to get log file path
@@ -296,6 +668,12 @@ func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases
 		log.WithError(err).Fatal("Failed to load configuration")
 	}
 
+	// Scope configuration to a single tenant if requested
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		log := logger.NewLogger(logLevel)
+		log.WithError(err).Fatal("Failed to apply tenant")
+	}
+
 	// Determine effective log level: CLI flag overrides config
 	effectiveLogLevel := logLevel
 	if logLevel == "info" && cfg.Logging.Level != "" {
@@ -311,11 +689,28 @@ func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases
 		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
 	}
 
-	// Check if today is weekend (Saturday or Sunday) unless force flag is used
+	applyOutputMode(log, cfg)
+
+	// Keep stdout for the JSON plan
+	if dryRun && output == outputJSON {
+		log.SetOutput(os.Stderr)
+	}
+
+	// Tag this invocation so its logs, metrics and artifacts can be correlated
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	// Check cleanup.schedule unless force flag is used
+	cleanupSchedule, err := schedule.Parse(cfg.Cleanup.EffectiveSchedule())
+	if err != nil {
+		log.WithError(err).Fatal("Invalid cleanup schedule")
+	}
 	if !force {
-		today := time.Now().Weekday()
-		if today != time.Saturday && today != time.Sunday {
-			log.Info("Cleanup only runs on weekends. Use --force to cleanup anytime. Skipping cleanup.")
+		now := time.Now()
+		if !cleanupSchedule.RunsOn(now) {
+			log.WithField("schedule", cleanupSchedule.String()).
+				Infof("Skipping cleanup: %s is not a scheduled cleanup day. Use --force to cleanup anytime.", now.Format("Monday 2006-01-02"))
 			return
 		}
 	}
@@ -323,7 +718,7 @@ func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases
 	if force {
 		log.Info("Starting forced cleanup process")
 	} else {
-		log.Info("Starting weekend cleanup process")
+		log.WithField("schedule", cleanupSchedule.String()).Info("Starting scheduled cleanup process")
 	}
 
 	// Parse databases from command line and merge with config
@@ -333,6 +728,9 @@ func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases
 		selectedDatabases = strings.Split(databases, ",")
 		for i, db := range selectedDatabases {
 			selectedDatabases[i] = strings.TrimSpace(db)
+			if tenant != "" && !cfg.HasDatabase(selectedDatabases[i]) {
+				log.Fatalf("Database %s does not belong to tenant %s", selectedDatabases[i], tenant)
+			}
 		}
 		log.Infof("Using databases from command line: %v", selectedDatabases)
 	} else if len(cfg.Cleanup.Databases) > 0 {
@@ -354,180 +752,153 @@ func runCleanup(configFile, logLevel string, dryRun bool, force bool, databases
 		metricsStorage = metrics.NewMetricsStorage(metricsPath)
 	}
 
+	// Reach the database through the SSH bastion if configured
+	closeTunnel := openDatabaseTunnel(ctx, cfg, log)
+	defer closeTunnel()
+
 	// Initialize backup service to access uploaded files tracking
 	backupService, err := backup.NewService(cfg, log)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to initialize backup service")
 	}
 
+	cleanupService := backup.NewCleanupService(&cfg.Cleanup, &cfg.Upload, log)
+	cleanupService.SetAllowUnverified(allowUnverified)
+	cleanupService.SetRequireVerification(cfg.Policy.RequireVerificationBeforeCleanup)
+	cleanupService.SetTenants(cfg.Tenants)
+	if allowUnverified {
+		log.Warn("⚠️ --allow-unverified: old backups missing from cloud storage may be deleted")
+	}
+
+	trash := backup.NewTrash(cfg.Backup.Directory, cfg.Cleanup.TrashRetentionHours, log)
+
+	// Enforce organizational guardrails before anything is deleted
+	if !dryRun {
+		if err := policy.CheckCleanup(&cfg.Policy, &cfg.Cleanup, &cfg.Upload, allowUnverified); err != nil {
+			log.WithError(err).Fatal("Cleanup denied by policy")
+		}
+	}
+
+	// Dry runs and real runs share one plan, so what a dry run shows is
+	// exactly what gets deleted
+	cleanupPlan, err := cleanupService.PlanCleanup(cfg.Backup.Directory, selectedDatabases, backupService.GetUploadedFiles(), trash, time.Now())
+	if err != nil {
+		log.WithError(err).Fatal("Failed to plan cleanup")
+	}
+
+	if dryRun && output == outputJSON {
+		if err := writeCleanupPlan(cleanupPlan, trash); err != nil {
+			log.WithError(err).Fatal("Failed to write cleanup plan")
+		}
+		return
+	}
+
 	if dryRun {
 		log.Info("DRY RUN MODE: No files will be actually deleted")
-		showFilesToCleanup(backupService, log)
-		
-		// Show age-based cleanup files if enabled
-		if cfg.Cleanup.AgeBasedCleanup {
-			cleanupService := backup.NewCleanupService(&cfg.Cleanup, &cfg.Upload, log)
-			showAgeBasedFilesToCleanup(cleanupService, cfg.Backup.Directory, selectedDatabases, log)
-		}
+		showCleanupPlan(cleanupPlan, log)
 		return
 	}
 
 	// Show confirmation prompt if not skipped
-	if !yes && !showCleanupConfirmation(backupService, &cfg.Cleanup, cfg.Backup.Directory, selectedDatabases, log) {
+	if !yes && !showCleanupConfirmation(cleanupPlan, &cfg.Cleanup, cfg.Backup.Directory, selectedDatabases, allowUnverified) {
 		log.Info("Cleanup cancelled by user")
 		return
 	}
 
 	// Record cleanup start
-	cleanupStartTime := time.Now()
-	var totalFilesRemoved int64
-	var totalBytesFreed int64
+	report := &backup.CleanupReport{StartedAt: time.Now()}
+	if reportPath == "" {
+		reportPath = cfg.Cleanup.ReportPath
+	}
+
+	// finishCleanup records metrics, the summary and the report for this run
+	finishCleanup := func(cleanupErr error) {
+		report.Finish(cleanupErr)
 
-	// Perform cleanup of uploaded files
-	if err := backupService.CleanupUploadedFiles(ctx); err != nil {
-		log.WithError(err).Error("Cleanup process failed")
-		cleanupDuration := time.Since(cleanupStartTime)
 		if cfg.Metrics.Enabled && metricsStorage != nil {
-			if err := metricsStorage.UpdateCleanupMetrics(cleanupDuration, false, totalFilesRemoved, totalBytesFreed); err != nil {
+			if err := metricsStorage.UpdateCleanupMetrics(time.Since(report.StartedAt), report.Success, report.FilesRemoved, report.BytesFreed); err != nil {
 				log.WithError(err).Warn("Failed to update cleanup metrics")
 			}
 		}
-		os.Exit(1)
-	}
 
-	// Perform age-based cleanup (always enabled for cleanup command)
-	maxAgeDays := cfg.Cleanup.MaxAgeDays
-	if maxAgeDays == 0 {
-		maxAgeDays = 7 // Safe default: 7 days
-	}
-	
-	if err := cleanupOldBackupFiles(cfg.Backup.Directory, selectedDatabases, maxAgeDays, log); err != nil {
-		log.WithError(err).Error("Age-based cleanup failed")
-		cleanupDuration := time.Since(cleanupStartTime)
-		if cfg.Metrics.Enabled && metricsStorage != nil {
-			if err := metricsStorage.UpdateCleanupMetrics(cleanupDuration, false, totalFilesRemoved, totalBytesFreed); err != nil {
-				log.WithError(err).Warn("Failed to update cleanup metrics")
+		log.WithField("files_removed", report.FilesRemoved).
+			WithField("bytes_freed", formatFileSize(report.BytesFreed)).
+			WithField("uploaded_files_removed", report.UploadedFiles.FilesRemoved).
+			WithField("age_based_removed", report.AgeBased.FilesRemoved).
+			WithField("duration", time.Since(report.StartedAt).Round(time.Second)).
+			Info("📊 Cleanup summary")
+
+		if reportPath != "" {
+			if err := report.WriteFile(reportPath); err != nil {
+				log.WithError(err).Warn("Failed to write cleanup report")
+			} else {
+				log.WithField("report", reportPath).Info("Cleanup report written")
 			}
 		}
+	}
+
+	err = cleanupService.ExecuteCleanup(cleanupPlan, trash, report)
+	removed := append([]string{}, report.UploadedFiles.Files...)
+	backupService.ForgetUploaded(append(removed, report.AgeBased.Files...))
+	if err != nil {
+		log.WithError(err).Error("Cleanup process failed")
+		finishCleanup(err)
 		os.Exit(1)
 	}
 
 	// Record successful cleanup
-	cleanupDuration := time.Since(cleanupStartTime)
-	if cfg.Metrics.Enabled && metricsStorage != nil {
-		if err := metricsStorage.UpdateCleanupMetrics(cleanupDuration, true, totalFilesRemoved, totalBytesFreed); err != nil {
-			log.WithError(err).Warn("Failed to update cleanup metrics")
-		}
-	}
+	finishCleanup(nil)
 
 	if force {
 		log.Info("Forced cleanup completed successfully")
 	} else {
-		log.Info("Weekend cleanup completed successfully")
+		log.Info("Scheduled cleanup completed successfully")
 	}
 }
 
-func showFilesToCleanup(service *backup.Service, log *logger.Logger) {
-	uploadedFiles := service.GetUploadedFiles()
-
-	if len(uploadedFiles) == 0 {
-		log.Info("No uploaded files to cleanup")
-		return
+// showCleanupPlan logs what a cleanup run would remove and which old files
+// it would keep
+func showCleanupPlan(cleanupPlan *backup.CleanupPlan, log *logger.Logger) {
+	if len(cleanupPlan.ExpiredTrash) > 0 {
+		log.WithField("batches", len(cleanupPlan.ExpiredTrash)).Info("Would purge expired trash")
 	}
 
-	var filesToClean []string
-	for filePath, uploadTime := range uploadedFiles {
-		if time.Since(uploadTime) >= time.Hour {
-			filesToClean = append(filesToClean, filePath)
+	if len(cleanupPlan.Uploaded) == 0 {
+		log.Info("No uploaded files to cleanup")
+	} else {
+		log.WithField("files_to_cleanup", len(cleanupPlan.Uploaded)).Info("Files that would be cleaned up:")
+		for _, candidate := range cleanupPlan.Uploaded {
+			log.WithField("file", candidate.Path).Info("Would delete")
 		}
 	}
 
-	log.WithField("files_to_cleanup", len(filesToClean)).Info("Files that would be cleaned up:")
-	for _, file := range filesToClean {
-		log.WithField("file", file).Info("Would delete")
-	}
-}
-
-func showAgeBasedFilesToCleanup(cleanupService *backup.CleanupService, backupDir string, selectedDatabases []string, log *logger.Logger) {
-	// Get old files based on age
-	oldFiles, err := cleanupService.GetOldFiles(backupDir, cleanupService.GetConfig().MaxAgeDays)
-	if err != nil {
-		log.WithError(err).Error("Failed to get old files for age-based cleanup")
-		return
-	}
-
-	// Filter by selected databases if specified
-	if len(selectedDatabases) > 0 {
-		filteredFiles := []string{}
-		for _, file := range oldFiles {
-			if shouldCleanupFile(file, selectedDatabases) {
-				filteredFiles = append(filteredFiles, file)
-			}
+	if len(cleanupPlan.AgeBased) == 0 {
+		log.WithField("max_age_days", cleanupPlan.MaxAgeDays).Info("No old files found for age-based cleanup")
+	} else {
+		log.WithField("old_files_count", len(cleanupPlan.AgeBased)).
+			WithField("max_age_days", cleanupPlan.MaxAgeDays).
+			Info("Age-based files that would be cleaned up:")
+		for _, candidate := range cleanupPlan.AgeBased {
+			log.WithField("file", candidate.Path).Info("Would delete (age-based)")
 		}
-		oldFiles = filteredFiles
 	}
 
-	if len(oldFiles) == 0 {
-		log.Info("No old files found for age-based cleanup")
-		return
+	for _, candidate := range cleanupPlan.Kept {
+		log.WithField("file", candidate.Path).WithField("reason", candidate.Reason).Info("Would keep")
 	}
 
-	log.WithField("old_files_count", len(oldFiles)).Info("Age-based files that would be cleaned up:")
-	for _, file := range oldFiles {
-		log.WithField("file", file).Info("Would delete (age-based)")
-	}
+	log.WithField("bytes_freed", formatFileSize(cleanupPlan.Bytes())).Info("📊 Cleanup plan")
 }
 
-// shouldCleanupFile checks if a file should be cleaned up based on database filter
-func shouldCleanupFile(filePath string, selectedDatabases []string) bool {
-	if len(selectedDatabases) == 0 {
-		return true // no filter, cleanup all
-	}
-
-	// Extract database name from file path
-	// Expected format: /path/to/backup/database_name/file.sql.gz
-	parts := strings.Split(filePath, "/")
-	if len(parts) < 2 {
-		return false
-	}
-
-	// Find database name in path
-	var dbName string
-	for _, part := range parts {
-		if part != "" && part != "." && part != ".." {
-			// Check if this part looks like a database name
-			// by checking if it matches any of the selected databases
-			for _, selectedDB := range selectedDatabases {
-				if strings.Contains(part, selectedDB) {
-					dbName = selectedDB
-					break
-				}
-			}
-			if dbName != "" {
-				break
-			}
-		}
-	}
-
-	// If no database found in path, check filename
-	if dbName == "" {
-		filename := parts[len(parts)-1]
-		for _, selectedDB := range selectedDatabases {
-			if strings.HasPrefix(filename, selectedDB) {
-				dbName = selectedDB
-				break
-			}
-		}
-	}
-
-	// Check if database should be cleaned up
-	for _, selectedDB := range selectedDatabases {
-		if dbName == selectedDB {
-			return true
-		}
-	}
-
-	return false
+// restoreFlags holds per-restore overrides of the restore config section
+type restoreFlags struct {
+	disableForeignKeyChecks bool
+	disableUniqueChecks     bool
+	triggers                string
+	definer                 string
+	resume                  bool
+	truncatePartial         bool
+	skipCompatCheck         bool
 }
 
 func newRestoreCommand() *cobra.Command {
@@ -536,21 +907,31 @@ func newRestoreCommand() *cobra.Command {
 	var backupPath string
 	var targetDatabase string
 	var yes bool
+	var tenant string
+	var flags restoreFlags
 
 	cmd := &cobra.Command{
 		Use:   "restore",
 		Short: "Restore database from backup",
 		Long:  `Restore a database from mydumper backup directory or SQL file.`,
 		Run: func(cmd *cobra.Command, args []string) {
-			runRestore(configFile, logLevel, backupPath, targetDatabase, yes)
+			runRestore(configFile, logLevel, backupPath, targetDatabase, yes, tenant, flags)
 		},
 	}
 
 	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
 	cmd.Flags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
-	cmd.Flags().StringVarP(&backupPath, "backup-path", "b", "", "path to backup directory or SQL file (required)")
+	cmd.Flags().StringVarP(&backupPath, "backup-path", "b", "", "path to backup directory or SQL file, or an rclone remote path such as s3:backups/... (required)")
 	cmd.Flags().StringVarP(&targetDatabase, "database", "d", "", "target database name (required)")
 	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "skip confirmation prompts (for automated mode)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only allow restoring into databases of the named tenant")
+	cmd.Flags().BoolVar(&flags.disableForeignKeyChecks, "disable-fk-checks", false, "disable foreign key checks while loading (overrides config)")
+	cmd.Flags().BoolVar(&flags.disableUniqueChecks, "disable-unique-checks", false, "disable unique checks while loading (overrides config)")
+	cmd.Flags().StringVar(&flags.triggers, "triggers", "", "trigger handling: restore, skip or defer until data is loaded (overrides config)")
+	cmd.Flags().StringVar(&flags.definer, "definer", "", "DEFINER clauses: keep, strip, or an account such as app@% to rewrite them to (overrides config)")
+	cmd.Flags().BoolVar(&flags.resume, "resume", false, "continue an interrupted myloader restore, skipping tables already loaded")
+	cmd.Flags().BoolVar(&flags.truncatePartial, "truncate-partial", false, "with --resume, truncate the table the interrupted restore was loading first")
+	cmd.Flags().BoolVar(&flags.skipCompatCheck, "skip-compat-check", false, "restore even when the target server looks unable to load the backup")
 
 	if err := cmd.MarkFlagRequired("backup-path"); err != nil {
 		fmt.Printf("Error: Failed to mark backup-path flag as required: %v\n", err)
@@ -564,7 +945,7 @@ func newRestoreCommand() *cobra.Command {
 	return cmd
 }
 
-func runRestore(configFile, logLevel, backupPath, targetDatabase string, yes bool) {
+func runRestore(configFile, logLevel, backupPath, targetDatabase string, yes bool, tenant string, flags restoreFlags) {
 	ctx := context.Background()
 
 	// Load configuration first to get log file path
@@ -575,6 +956,52 @@ func runRestore(configFile, logLevel, backupPath, targetDatabase string, yes boo
 		log.WithError(err).Fatal("Failed to load configuration")
 	}
 
+	// Tenants may only restore into their own databases. Restore flags
+	// below change this copy, never the loaded config.
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		log := logger.NewLogger(logLevel)
+		log.WithError(err).Fatal("Failed to apply tenant")
+	}
+	if tenant != "" {
+		if !cfg.HasDatabase(targetDatabase) {
+			log := logger.NewLogger(logLevel)
+			log.Fatalf("Database %s does not belong to tenant %s", targetDatabase, tenant)
+		}
+	}
+
+	// Apply per-restore overrides
+	if flags.disableForeignKeyChecks {
+		cfg.Restore.DisableForeignKeyChecks = true
+	}
+	if flags.disableUniqueChecks {
+		cfg.Restore.DisableUniqueChecks = true
+	}
+	if flags.triggers != "" {
+		if err := config.ValidateTriggersMode(flags.triggers); err != nil {
+			log := logger.NewLogger(logLevel)
+			log.WithError(err).Fatal("Invalid --triggers value")
+		}
+		cfg.Restore.Triggers = flags.triggers
+	}
+	if flags.definer != "" {
+		if err := config.ValidateDefiner(flags.definer); err != nil {
+			log := logger.NewLogger(logLevel)
+			log.WithError(err).Fatal("Invalid --definer value")
+		}
+		cfg.Restore.Definer = flags.definer
+	}
+	if flags.resume && !cfg.Restore.Resumable {
+		log := logger.NewLogger(logLevel)
+		log.Fatal("--resume needs restore.resumable enabled")
+	}
+	if flags.truncatePartial && !flags.resume {
+		log := logger.NewLogger(logLevel)
+		log.Fatal("--truncate-partial only applies with --resume")
+	}
+	if flags.skipCompatCheck {
+		cfg.Restore.CompatCheck = false
+	}
+
 	// Determine effective log level: CLI flag overrides config
 	effectiveLogLevel := logLevel
 	if logLevel == "info" && cfg.Logging.Level != "" {
@@ -590,22 +1017,51 @@ func runRestore(configFile, logLevel, backupPath, targetDatabase string, yes boo
 		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
 	}
 
+	applyOutputMode(log, cfg)
+
+	// Stay within the configured CPU and memory envelope
+	if err := resources.Apply(&cfg.Resources, log); err != nil {
+		log.WithError(err).Fatal("Failed to apply resource limits")
+	}
+
+	// Tag this invocation so its logs, metrics and artifacts can be correlated
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	// Enforce organizational guardrails before touching the target server
+	if err := policy.CheckRestore(&cfg.Policy, cfg.Database.Host, targetDatabase); err != nil {
+		log.WithError(err).Fatal("Restore denied by policy")
+	}
+
 	// Initialize database client
+	closeTunnel := openDatabaseTunnel(ctx, cfg, log)
+	defer closeTunnel()
+
+	if cfg.Database.IsPostgreSQL() {
+		runPostgreSQLRestore(ctx, cfg, backupPath, targetDatabase, yes, flags, log)
+		return
+	}
+
 	dbClient, err := database.NewClient(&cfg.Database)
 	if err != nil {
 		log.WithError(err).Fatal("Failed to initialize database client")
 	}
 	defer dbClient.Close()
+	dbClient.SetLogger(log)
+
+	// Track myloader progress per table so a failed restore can be resumed
+	if cfg.Restore.Resumable {
+		dbClient.SetRestoreTracking(&database.RestoreTracking{
+			StatePath:       filepath.Join(cfg.StateDirectory, "restore-"+targetDatabase+".json"),
+			Backup:          backupPath,
+			Resume:          flags.resume,
+			TruncatePartial: flags.truncatePartial,
+		})
+	}
 
 	// Initialize metrics storage only if metrics are enabled
-	var metricsStorage *metrics.MetricsStorage
-	if cfg.Metrics.Enabled {
-		metricsPath := cfg.Metrics.StoragePath
-		if metricsPath == "" {
-			metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
-		}
-		metricsStorage = metrics.NewMetricsStorage(metricsPath)
-	}
+	metricsStorage := newRestoreMetricsStorage(cfg)
 
 	log.WithField("backup_path", backupPath).WithField("target_database", targetDatabase).Info("Starting database restore")
 
@@ -615,40 +1071,81 @@ func runRestore(configFile, logLevel, backupPath, targetDatabase string, yes boo
 		return
 	}
 
+	// From here Ctrl-C stops the restore through ctx, so an interrupted
+	// download can resume and temporary files are removed
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// Record restore start
 	restoreStartTime := time.Now()
 	if cfg.Metrics.Enabled {
 		metrics.RecordRestoreStart(targetDatabase)
 	}
 
+	// Fetch backups given as a remote path, verified before decompression
+	if upload.IsRemotePath(backupPath) {
+		localPath, cleanup, err := downloadBackup(ctx, cfg, backupPath, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to download backup")
+		}
+		defer cleanupOnExit(cleanup)()
+		backupPath = localPath
+	}
+
+	// Fail now rather than far into the load when the target is too old
+	if err := checkRestoreCompat(ctx, dbClient, &cfg.Restore, backupPath, log); err != nil {
+		log.WithError(err).Fatal("Restore compatibility check failed")
+	}
+
+	// Guard against loading utf8mb4 data into a latin1 database
+	if err := checkRestoreCharset(ctx, dbClient, &cfg.Restore, backupPath, targetDatabase, log); err != nil {
+		log.WithError(err).Fatal("Charset check failed")
+	}
+	warnToolVersions(cfg, backupPath, log)
+
+	// Encrypted backups are decrypted with the key named in their header
+	backupPath, cleanupDecrypted, err := decryptBackup(ctx, cfg, backupPath, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to decrypt backup")
+	}
+	defer cleanupOnExit(cleanupDecrypted)()
+
 	// Perform restore
-	err = dbClient.RestoreBackup(ctx, backupPath, targetDatabase)
+	err = dbClient.RestoreBackup(ctx, backupPath, targetDatabase, &cfg.Restore)
 	restoreDuration := time.Since(restoreStartTime)
+	recordRestoreMetrics(cfg, metricsStorage, targetDatabase, restoreDuration, err == nil, log)
 
 	if err != nil {
 		log.WithError(err).Error("Database restore failed")
-		if cfg.Metrics.Enabled {
-			metrics.RecordRestoreEnd(targetDatabase, restoreDuration, false)
-			if metricsStorage != nil {
-				if err := metricsStorage.UpdateRestoreMetrics(targetDatabase, restoreDuration, false); err != nil {
-					log.WithError(err).Warn("Failed to update restore metrics")
-				}
-			}
-		}
-		os.Exit(1)
+		log.Exit(1)
 	}
 
-	// Record successful restore
-	if cfg.Metrics.Enabled {
-		metrics.RecordRestoreEnd(targetDatabase, restoreDuration, true)
-		if metricsStorage != nil {
-			if err := metricsStorage.UpdateRestoreMetrics(targetDatabase, restoreDuration, true); err != nil {
-				log.WithError(err).Warn("Failed to update restore metrics")
-			}
-		}
+	log.WithField("target_database", targetDatabase).Info("Database restore completed successfully")
+}
+
+// newRestoreMetricsStorage opens the metrics file when metrics are enabled
+func newRestoreMetricsStorage(cfg *config.Config) *metrics.MetricsStorage {
+	if !cfg.Metrics.Enabled {
+		return nil
+	}
+	metricsPath := cfg.Metrics.StoragePath
+	if metricsPath == "" {
+		metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
 	}
+	return metrics.NewMetricsStorage(metricsPath)
+}
 
-	log.WithField("target_database", targetDatabase).Info("Database restore completed successfully")
+// recordRestoreMetrics records the end of a restore
+func recordRestoreMetrics(cfg *config.Config, storage *metrics.MetricsStorage, targetDatabase string, duration time.Duration, success bool, log *logger.Logger) {
+	if !cfg.Metrics.Enabled {
+		return
+	}
+	metrics.RecordRestoreEnd(targetDatabase, duration, success)
+	if storage != nil {
+		if err := storage.UpdateRestoreMetrics(targetDatabase, duration, success); err != nil {
+			log.WithError(err).Warn("Failed to update restore metrics")
+		}
+	}
 }
 
 
@@ -787,6 +1284,9 @@ func showBackupConfirmation(cfg *config.Config, _ *logger.Logger) bool {
 	fmt.Printf("\n⚙️  Options:\n")
 	fmt.Printf("   Concurrency: %d\n", cfg.Backup.Concurrency)
 	fmt.Printf("   Batch size: %d\n", cfg.Backup.BatchSize)
+	if cfg.Backup.Order != "config" {
+		fmt.Printf("   Order: %s\n", cfg.Backup.Order)
+	}
 	
 	fmt.Printf("\n")
 	
@@ -802,39 +1302,22 @@ func showBackupConfirmation(cfg *config.Config, _ *logger.Logger) bool {
 	return false
 }
 
-// showCleanupConfirmation displays a confirmation prompt for cleanup operation
-func showCleanupConfirmation(_ *backup.Service, cleanupCfg *config.CleanupConfig, backupDir string, selectedDatabases []string, _ *logger.Logger) bool {
+// showCleanupConfirmation displays a confirmation prompt for the cleanup plan
+func showCleanupConfirmation(cleanupPlan *backup.CleanupPlan, cleanupCfg *config.CleanupConfig, backupDir string, selectedDatabases []string, allowUnverified bool) bool {
 	fmt.Printf("\n📋 Cleanup Summary\n")
 	fmt.Printf("=================\n\n")
 	
-	// Set safe defaults for cleanup command
-	maxAgeDays := cleanupCfg.MaxAgeDays
-	if maxAgeDays == 0 {
-		maxAgeDays = 7 // Safe default: 7 days
-	}
+	maxAgeDays := cleanupPlan.MaxAgeDays
 	
 	// Get all backup files in directory
 	allBackupFiles := getBackupFiles(backupDir, selectedDatabases)
 	
-	if len(allBackupFiles) == 0 {
+	if len(allBackupFiles) == 0 && cleanupPlan.Empty() {
 		fmt.Printf("✅ No backup files found in %s\n", backupDir)
 		return false
 	}
 	
-	// Categorize files by age
-	var filesToDelete []BackupFileInfo
-	var totalSizeToDelete int64
-	
-	for _, fileInfo := range allBackupFiles {
-		ageDays := int(time.Since(fileInfo.ModTime).Hours() / 24)
-		
-		if ageDays >= maxAgeDays {
-			filesToDelete = append(filesToDelete, fileInfo)
-			totalSizeToDelete += fileInfo.Size
-		}
-	}
-	
-	// Display all files with age info
+	// Display all files with what the plan does to them
 	fmt.Printf("📁 Backup files found:\n")
 	for i, fileInfo := range allBackupFiles {
 		if i >= 15 { // Show max 15 files
@@ -844,25 +1327,43 @@ func showCleanupConfirmation(_ *backup.Service, cleanupCfg *config.CleanupConfig
 		
 		ageDays := int(time.Since(fileInfo.ModTime).Hours() / 24)
 		status := "✅ Keep"
-		if ageDays >= maxAgeDays {
+		if cleanupPlan.Removes(fileInfo.Path) {
 			status = "⚠️  Will delete"
+		} else if reason := cleanupPlan.KeepReason(fileInfo.Path); reason != "" {
+			status = "🔒 Keep (" + reason + ")"
 		}
 		
 		fmt.Printf("  %d. %s (%d days old, %s) %s\n", 
 			i+1, fileInfo.Name, ageDays, formatFileSize(fileInfo.Size), status)
 	}
 	
-	fmt.Printf("\n📊 Files to delete: %d (%d+ days old)\n", len(filesToDelete), maxAgeDays)
-	fmt.Printf("📊 Total space to free: %s\n", formatFileSize(totalSizeToDelete))
+	if len(cleanupPlan.ExpiredTrash) > 0 {
+		fmt.Printf("\n🗑️  Expired trash batches to purge: %d\n", len(cleanupPlan.ExpiredTrash))
+	}
+	fmt.Printf("\n📊 Files to delete: %d uploaded, %d age-based (%d+ days old)\n", len(cleanupPlan.Uploaded), len(cleanupPlan.AgeBased), maxAgeDays)
+	fmt.Printf("📊 Total space to free: %s\n", formatFileSize(cleanupPlan.Bytes()))
 	fmt.Printf("⏰ Age threshold: %d days (configurable)\n", maxAgeDays)
+	if cleanupCfg.MinKeep > 0 {
+		fmt.Printf("🔒 Newest %d backups per database are always kept (min_keep)\n", cleanupCfg.MinKeep)
+	}
+	if cleanupCfg.VerifyCloudExists && !allowUnverified {
+		fmt.Printf("☁️  Files not found in cloud storage will be kept (use --allow-unverified to delete them)\n")
+	} else if !allowUnverified {
+		fmt.Printf("☁️  Files never uploaded to cloud storage will be kept (use --allow-unverified to delete them)\n")
+	}
 	
-	if len(filesToDelete) == 0 {
-		fmt.Printf("\n✅ No files old enough to cleanup (all files are < %d days old)\n", maxAgeDays)
+	if cleanupPlan.Empty() {
+		fmt.Printf("\n✅ Nothing to cleanup\n")
 		return false
 	}
 	
-	fmt.Printf("\n⚠️  WARNING: This action cannot be undone!\n")
-	fmt.Printf("⚠️  Deleted backup files cannot be recovered!\n\n")
+	if cleanupCfg.TrashRetentionHours > 0 {
+		fmt.Printf("\n🗑️  Files are moved to %s and can be recovered for %d hours\n\n",
+			filepath.Join(backupDir, backup.TrashDirName), cleanupCfg.TrashRetentionHours)
+	} else {
+		fmt.Printf("\n⚠️  WARNING: This action cannot be undone!\n")
+		fmt.Printf("⚠️  Deleted backup files cannot be recovered!\n\n")
+	}
 	
 	// Confirmation prompt
 	fmt.Print("Do you want to proceed with cleanup? [y/N]: ")
@@ -884,51 +1385,65 @@ type BackupFileInfo struct {
 	ModTime time.Time
 }
 
-// getBackupFiles scans backup directory and returns backup file information
+// getBackupFiles walks the backup directory, {database}/{YYYY-MM}/ as well
+// as artifacts of older releases at the top level, and returns every backup
+// artifact of the selected databases. Directories such as mydumper backups
+// are listed once with their total size; manifests and upload markers are
+// left out.
 func getBackupFiles(backupDir string, selectedDatabases []string) []BackupFileInfo {
 	var backupFiles []BackupFileInfo
 	
-	// Read backup directory
-	entries, err := os.ReadDir(backupDir)
-	if err != nil {
-		return backupFiles
-	}
-	
-	for _, entry := range entries {
-		// Skip non-directories and non-backup files
-		if !entry.IsDir() && !strings.HasSuffix(entry.Name(), ".tar.gz") && 
-		   !strings.HasSuffix(entry.Name(), ".tar.zst") && 
-		   !strings.HasSuffix(entry.Name(), ".tar.xz") {
-			continue
+	_ = filepath.WalkDir(backupDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // unreadable entries are skipped
+		}
+		if path == backupDir {
+			return nil
 		}
 		
-		// Check if file should be included based on database filter
-		if len(selectedDatabases) > 0 && !shouldCleanupFile(entry.Name(), selectedDatabases) {
-			continue
+		// Skip the trash and other hidden entries
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		
+		// Descend into database and month directories until an artifact
+		if _, _, ok := backup.ParseArtifactName(d.Name()); !ok || manifest.IsManifest(path) || manifest.IsUploadedMarker(path) {
+			return nil
+		}
+		if !backup.MatchesDatabases(backupDir, path, selectedDatabases) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
 		
-		// Get file info
-		fullPath := filepath.Join(backupDir, entry.Name())
-		info, err := os.Stat(fullPath)
+		info, err := d.Info()
 		if err != nil {
-			continue
+			return nil
 		}
 		
 		// Calculate size (for directories, get total size)
-		var size int64
-		if info.IsDir() {
-			size, _ = getDirSize(fullPath)
-		} else {
-			size = info.Size()
+		size := info.Size()
+		if d.IsDir() {
+			size, _ = getDirSize(path)
 		}
 		
+		name, _ := filepath.Rel(backupDir, path)
 		backupFiles = append(backupFiles, BackupFileInfo{
-			Name:    entry.Name(),
-			Path:    fullPath,
+			Name:    name,
+			Path:    path,
 			Size:    size,
 			ModTime: info.ModTime(),
 		})
-	}
+		
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
 	
 	return backupFiles
 }
@@ -936,7 +1451,7 @@ func getBackupFiles(backupDir string, selectedDatabases []string) []BackupFileIn
 // checkBackupFrequency checks if enough time has passed since last backup
 func checkBackupFrequency(cfg *config.Config, log *logger.Logger) bool {
 	// Get last backup time
-	lastBackupTime, err := getLastBackupTime(cfg.Backup.Directory)
+	lastBackupTime, err := getLastBackupTime(cfg.StateDirectory, cfg.Backup.Directory)
 	if err != nil {
 		// If no tracking file exists, allow backup
 		log.WithError(err).Debug("No previous backup timestamp found, allowing backup")
@@ -976,8 +1491,8 @@ func checkBackupFrequency(cfg *config.Config, log *logger.Logger) bool {
 }
 
 // getLastBackupTime reads the last backup timestamp from tracking file
-func getLastBackupTime(backupDir string) (time.Time, error) {
-	trackingFile := getTrackingFilePath(backupDir)
+func getLastBackupTime(stateDir, backupDir string) (time.Time, error) {
+	trackingFile := getTrackingFilePath(stateDir, backupDir)
 	
 	data, err := os.ReadFile(trackingFile)
 	if err != nil {
@@ -996,8 +1511,8 @@ func getLastBackupTime(backupDir string) (time.Time, error) {
 }
 
 // updateLastBackupTime updates the last backup timestamp in tracking file
-func updateLastBackupTime(backupDir string) error {
-	trackingFile := getTrackingFilePath(backupDir)
+func updateLastBackupTime(stateDir, backupDir string) error {
+	trackingFile := getTrackingFilePath(stateDir, backupDir)
 	
 	// Ensure the directory exists
 	if err := os.MkdirAll(filepath.Dir(trackingFile), 0755); err != nil {
@@ -1018,44 +1533,16 @@ func updateLastBackupTime(backupDir string) error {
 	return os.WriteFile(trackingFile, data, 0644)
 }
 
-// getTrackingFilePath returns the path for backup tracking file
-// Uses a more persistent location to survive container restarts
-func getTrackingFilePath(backupDir string) string {
-	// Try to use a more persistent location based on platform and user context
-	var trackingDir string
-	
-	// Check if running as systemd service (system user or specific directories exist)
-	if _, err := os.Stat("/var/lib/tenangdb"); err == nil {
-		// Systemd deployment detected - use system directory
-		trackingDir = "/var/lib/tenangdb"
-	} else if runtime.GOOS == "darwin" {
-		// macOS: Use Application Support directory
-		if homeDir, err := os.UserHomeDir(); err == nil {
-			trackingDir = filepath.Join(homeDir, "Library", "Application Support", "TenangDB")
-		}
-	} else {
-		// Linux: Use /tmp for Docker containers, XDG for regular usage
-		if _, err := os.Stat("/.dockerenv"); err == nil {
-			// Running in Docker container - use /tmp which is more likely to be persistent
-			trackingDir = "/tmp/tenangdb"
-		} else if homeDir, err := os.UserHomeDir(); err == nil {
-			// Regular Linux usage
-			trackingDir = filepath.Join(homeDir, ".local", "share", "tenangdb")
-		}
-	}
-	
-	// Fallback to backup directory if we can't determine a better location
-	if trackingDir == "" {
-		trackingDir = backupDir
-	}
-	
+// getTrackingFilePath returns the path of the backup tracking file in the
+// state directory
+func getTrackingFilePath(stateDir, backupDir string) string {
 	// Create a safe filename based on backup directory path
 	// This allows multiple backup configs to have separate tracking files
 	hash := md5.Sum([]byte(backupDir))
 	hasher := fmt.Sprintf("%x", hash)[:8]
 	
 	trackingFile := fmt.Sprintf(".tenangdb_backup_tracking_%s.json", hasher)
-	return filepath.Join(trackingDir, trackingFile)
+	return filepath.Join(stateDir, trackingFile)
 }
 
 // formatDuration formats duration in human readable format
@@ -1081,35 +1568,6 @@ func formatDuration(d time.Duration) string {
 	}
 }
 
-// cleanupOldBackupFiles removes backup files older than specified days
-func cleanupOldBackupFiles(backupDir string, selectedDatabases []string, maxAgeDays int, log *logger.Logger) error {
-	// Get all backup files
-	allBackupFiles := getBackupFiles(backupDir, selectedDatabases)
-	
-	var filesToDelete []BackupFileInfo
-	for _, fileInfo := range allBackupFiles {
-		ageDays := int(time.Since(fileInfo.ModTime).Hours() / 24)
-		if ageDays >= maxAgeDays {
-			filesToDelete = append(filesToDelete, fileInfo)
-		}
-	}
-	
-	// Delete old files
-	for _, fileInfo := range filesToDelete {
-		log.WithField("file", fileInfo.Name).
-			WithField("age_days", int(time.Since(fileInfo.ModTime).Hours()/24)).
-			Info("🗑️ Deleting old backup file")
-		
-		if err := os.RemoveAll(fileInfo.Path); err != nil {
-			log.WithError(err).WithField("file", fileInfo.Path).Error("Failed to delete backup file")
-			return fmt.Errorf("failed to delete %s: %w", fileInfo.Path, err)
-		}
-	}
-	
-	log.WithField("deleted_files", len(filesToDelete)).Info("✅ Age-based cleanup completed")
-	return nil
-}
-
 // formatFileSize formats file size in human readable format
 func formatFileSize(size int64) string {
 	const (
@@ -1159,6 +1617,12 @@ func showRestoreConfirmation(backupPath, targetDatabase string, dbClient *databa
 		databaseExists = false
 	}
 	
+	return confirmRestore(targetDatabase, databaseExists)
+}
+
+// confirmRestore warns about overwriting an existing database and asks
+// for confirmation
+func confirmRestore(targetDatabase string, databaseExists bool) bool {
 	fmt.Printf("\n")
 	
 	if databaseExists {
@@ -1254,6 +1718,16 @@ func runInit(configPath string, force bool, deploySystemd bool, systemdUser stri
 	fmt.Printf("========================\n\n")
 	fmt.Printf("This wizard will help you set up TenangDB with your MySQL database.\n\n")
 
+	// Containers have no systemd; a compose service or CronJob is generated instead
+	container := config.ContainerRuntime()
+	if container != "" {
+		fmt.Printf("🐳 Running in a %s container: a deployment file replaces the systemd setup\n\n", container)
+		if deploySystemd {
+			fmt.Printf("⚠️  --deploy-systemd is ignored inside containers\n\n")
+			deploySystemd = false
+		}
+	}
+
 	// Check if systemd deployment requires root privileges
 	if deploySystemd && os.Geteuid() != 0 {
 		fmt.Printf("❌ Error: --deploy-systemd requires root privileges\n")
@@ -1359,7 +1833,11 @@ func runInit(configPath string, force bool, deploySystemd bool, systemdUser stri
 
 	// Step 7: Generate and save config
 	fmt.Printf("\n💾 Step 7: Generating configuration...\n")
-	fullConfig := generateConfig(dbConfig, backupConfig, uploadConfig, loggingConfig, metricsConfig)
+	stateDirectory := ""
+	if container != "" {
+		stateDirectory = containerStateDirectory
+	}
+	fullConfig := generateConfig(dbConfig, backupConfig, uploadConfig, loggingConfig, metricsConfig, stateDirectory)
 	
 	if err := saveConfig(fullConfig, targetConfigPath); err != nil {
 		fmt.Printf("❌ Failed to save config: %v\n", err)
@@ -1370,8 +1848,23 @@ func runInit(configPath string, force bool, deploySystemd bool, systemdUser stri
 	fmt.Printf("\n📁 Step 8: Creating directories...\n")
 	createDirectories(backupConfig.Directory, loggingConfig.FilePath, metricsConfig.StoragePath)
 
-	// Step 9: Systemd deployment (optional)
-	if deploySystemd || (!deploySystemd && promptSystemdDeployment()) {
+	// Step 9: Container deployment, or systemd deployment (optional)
+	var deploymentPath string
+	deployedSystemd := false
+	if container != "" {
+		fmt.Printf("\n🐳 Step 9: Generating container deployment...\n")
+		mounts := containerMounts(targetConfigPath, backupConfig.Directory, stateDirectory, loggingConfig.FilePath, metricsConfig.StoragePath)
+		path, err := writeContainerDeployment(container, targetConfigPath, mounts, metricsConfig)
+		if err != nil {
+			fmt.Printf("❌ Failed to generate deployment: %v\n", err)
+		} else {
+			deploymentPath = path
+			fmt.Printf("✅ Deployment written: %s\n", deploymentPath)
+			for _, m := range mounts {
+				fmt.Printf("   📂 %s → %s\n", m.name, m.path)
+			}
+		}
+	} else if deploySystemd || (!deploySystemd && promptSystemdDeployment()) {
 		fmt.Printf("\n🚀 Step 9: Deploying as systemd service...\n")
 		if os.Geteuid() != 0 {
 			fmt.Printf("❌ Systemd deployment requires root privileges\n")
@@ -1381,11 +1874,33 @@ func runInit(configPath string, force bool, deploySystemd bool, systemdUser stri
 				fmt.Printf("❌ Failed to deploy systemd service: %v\n", err)
 				fmt.Printf("💡 You can deploy manually later using the script in scripts/install.sh\n")
 			} else {
+				deployedSystemd = true
 				fmt.Printf("✅ Systemd service deployed successfully!\n")
 			}
 		}
 	}
 
+	// Step 10: Prometheus scrape config, alert rules and exporter unit
+	// (optional). Kubernetes deployments run no exporter.
+	var monitoring *monitoringFiles
+	if metricsConfig.Enabled && container != config.ContainerKubernetes {
+		fmt.Printf("\n📈 Step 10: Monitoring (Optional)\n")
+		fmt.Printf("================================\n")
+		if promptMonitoringSetup() {
+			files, err := writeMonitoringFiles(targetConfigPath, container, metricsConfig, exporterUnitApplies(container, deployedSystemd), systemdUser)
+			if err != nil {
+				fmt.Printf("❌ Failed to generate monitoring config: %v\n", err)
+			} else {
+				monitoring = files
+				fmt.Printf("✅ Scrape config written: %s\n", files.scrapeConfig)
+				fmt.Printf("✅ Alert rules written: %s\n", files.alertRules)
+				if files.exporterUnit != "" {
+					fmt.Printf("✅ Exporter unit written: %s\n", files.exporterUnit)
+				}
+			}
+		}
+	}
+
 	// Summary
 	fmt.Printf("\n🎉 Setup Complete!\n")
 	fmt.Printf("==================\n\n")
@@ -1398,7 +1913,15 @@ func runInit(configPath string, force bool, deploySystemd bool, systemdUser stri
 	fmt.Printf("\n")
 	
 	fmt.Printf("🚀 Next steps:\n")
-	if deploySystemd {
+	if deploymentPath != "" && container == config.ContainerKubernetes {
+		fmt.Printf("  1. Review the CronJob: %s\n", deploymentPath)
+		fmt.Printf("  2. Store the config: kubectl -n tenangdb create configmap tenangdb-config --from-file=config.yaml=%s\n", targetConfigPath)
+		fmt.Printf("  3. Apply it: kubectl apply -f %s\n", filepath.Base(deploymentPath))
+	} else if deploymentPath != "" {
+		fmt.Printf("  1. Copy %s and the config to the host, next to ./backups and ./state\n", deploymentPath)
+		fmt.Printf("  2. Run a backup: docker compose -f %s run --rm tenangdb\n", filepath.Base(deploymentPath))
+		fmt.Printf("  3. Schedule that command with cron on the host\n")
+	} else if deploySystemd {
 		fmt.Printf("  1. Check service status: sudo systemctl status tenangdb.timer\n")
 		fmt.Printf("  2. View logs: sudo journalctl -u tenangdb.service -f\n")
 		fmt.Printf("  3. Manual backup: sudo systemctl start tenangdb.service\n")
@@ -1415,6 +1938,14 @@ func runInit(configPath string, force bool, deploySystemd bool, systemdUser stri
 		}
 		fmt.Printf("  4. Deploy as service: tenangdb init --deploy-systemd --force\n")
 	}
+	if monitoring != nil {
+		fmt.Printf("\n📈 Monitoring:\n")
+		fmt.Printf("  - Merge %s into prometheus.yml, with %s next to it\n", monitoring.scrapeConfig, filepath.Base(monitoring.alertRules))
+		if monitoring.exporterUnit != "" {
+			fmt.Printf("  - Start the exporter: sudo cp %s /etc/systemd/system/ && sudo systemctl enable --now tenangdb-exporter\n", monitoring.exporterUnit)
+		}
+		fmt.Printf("  - Dashboard: tenangdb export-monitoring --format grafana-json --out tenangdb-dashboard.json\n")
+	}
 	fmt.Printf("\n📚 Need help? Check: tenangdb --help\n\n")
 }
 
@@ -1657,7 +2188,9 @@ func setupBackupConfig(dbConfig config.DatabaseConfig) config.BackupConfig {
 
 	// Backup directory
 	var defaultDir string
-	if runtime.GOOS == "darwin" {
+	if config.ContainerRuntime() != "" {
+		defaultDir = "/backups"
+	} else if runtime.GOOS == "darwin" {
 		if os.Geteuid() == 0 {
 			defaultDir = "/usr/local/var/tenangdb/backups"
 		} else {
@@ -1770,7 +2303,11 @@ func setupLoggingAndMetrics() (config.LoggingConfig, config.MetricsConfig) {
 
 	// Default paths
 	var logPath, metricsPath string
-	if runtime.GOOS == "darwin" {
+	if config.ContainerRuntime() != "" {
+		// Inside the state directory, so one volume keeps logs and metrics
+		logPath = filepath.Join(containerStateDirectory, "logs", "tenangdb.log")
+		metricsPath = filepath.Join(containerStateDirectory, "metrics.json")
+	} else if runtime.GOOS == "darwin" {
 		if os.Geteuid() == 0 {
 			logPath = "/usr/local/var/log/tenangdb/tenangdb.log"
 			metricsPath = "/usr/local/var/tenangdb/metrics.json"
@@ -1802,12 +2339,16 @@ func setupLoggingAndMetrics() (config.LoggingConfig, config.MetricsConfig) {
 		}
 }
 
-func generateConfig(dbConfig config.DatabaseConfig, backupConfig config.BackupConfig, uploadConfig config.UploadConfig, loggingConfig config.LoggingConfig, metricsConfig config.MetricsConfig) string {
+func generateConfig(dbConfig config.DatabaseConfig, backupConfig config.BackupConfig, uploadConfig config.UploadConfig, loggingConfig config.LoggingConfig, metricsConfig config.MetricsConfig, stateDirectory string) string {
 	var configBuilder strings.Builder
 	
 	configBuilder.WriteString("# TenangDB Configuration\n")
 	configBuilder.WriteString("# Generated by: tenangdb init\n")
 	configBuilder.WriteString(fmt.Sprintf("# Created: %s\n\n", time.Now().Format("2006-01-02 15:04:05")))
+
+	if stateDirectory != "" {
+		configBuilder.WriteString(fmt.Sprintf("state_directory: %s\n\n", stateDirectory))
+	}
 	
 	// Database section
 	configBuilder.WriteString("database:\n")
@@ -1925,7 +2466,7 @@ func promptSystemdDeployment() bool {
 	fmt.Printf("=================================\n")
 	fmt.Printf("TenangDB can be deployed as a systemd service for:\n")
 	fmt.Printf("  ✅ Automated daily backups\n")
-	fmt.Printf("  ✅ Weekend cleanup\n")  
+	fmt.Printf("  ✅ Scheduled cleanup\n")  
 	fmt.Printf("  ✅ Always-on metrics server\n")
 	fmt.Printf("  ✅ Auto-restart on failures\n\n")
 	
@@ -1976,13 +2517,33 @@ func deployAsSystemdService(configPath, systemdUser, metricsPort string) error {
 		return fmt.Errorf("failed to install config: %w", err)
 	}
 	
+	// Backup and cleanup timers follow backup.schedule and cleanup.schedule
+	// from the deployed config, and restore drills get a timer when
+	// drill.enabled is set
+	backupCalendar := []string{"daily"}
+	cleanupCalendar := []string{"Sat,Sun " + schedule.DefaultTime}
+	var drillCalendar []string
+	if cfg, err := config.LoadConfig(configPath); err == nil {
+		if s, err := schedule.Parse(cfg.Backup.Schedule); err == nil {
+			backupCalendar = s.OnCalendar()
+		}
+		if s, err := schedule.Parse(cfg.Cleanup.EffectiveSchedule()); err == nil {
+			cleanupCalendar = s.OnCalendar()
+		}
+		if cfg.Drill.Enabled {
+			if s, err := schedule.Parse(cfg.Drill.Schedule); err == nil {
+				drillCalendar = s.OnCalendar()
+			}
+		}
+	}
+
 	// Generate and install systemd service files
-	if err := installSystemdServices(systemdUser, metricsPort); err != nil {
+	if err := installSystemdServices(systemdUser, metricsPort, backupCalendar, cleanupCalendar, drillCalendar); err != nil {
 		return fmt.Errorf("failed to install systemd services: %w", err)
 	}
 	
 	// Enable and start services
-	if err := enableSystemdServices(); err != nil {
+	if err := enableSystemdServices(len(drillCalendar) > 0); err != nil {
 		return fmt.Errorf("failed to enable systemd services: %w", err)
 	}
 	
@@ -2138,16 +2699,20 @@ func installConfig(configPath string) error {
 	return nil
 }
 
-func installSystemdServices(systemdUser, metricsPort string) error {
+func installSystemdServices(systemdUser, metricsPort string, backupCalendar, cleanupCalendar, drillCalendar []string) error {
 	fmt.Printf("Installing systemd service files...\n")
 	
 	// Generate service file content
 	services := map[string]string{
 		"tenangdb.service": generateTenangDBService(systemdUser),
-		"tenangdb.timer": generateTenangDBTimer(),
+		"tenangdb.timer": generateTenangDBTimer(backupCalendar),
 		"tenangdb-cleanup.service": generateCleanupService(systemdUser),
-		"tenangdb-cleanup.timer": generateCleanupTimer(),
-		"tenangdb-exporter.service": generateExporterService(systemdUser, metricsPort),
+		"tenangdb-cleanup.timer": generateCleanupTimer(cleanupCalendar),
+		"tenangdb-exporter.service": generateExporterService(systemdUser, "/opt/tenangdb/tenangdb-exporter", "/etc/tenangdb/config.yaml", metricsPort),
+	}
+	if len(drillCalendar) > 0 {
+		services["tenangdb-drill.service"] = generateDrillService(systemdUser)
+		services["tenangdb-drill.timer"] = generateDrillTimer(drillCalendar)
 	}
 	
 	for filename, content := range services {
@@ -2179,7 +2744,7 @@ func installSystemdServices(systemdUser, metricsPort string) error {
 	return nil
 }
 
-func enableSystemdServices() error {
+func enableSystemdServices(drills bool) error {
 	fmt.Printf("Enabling and starting systemd services...\n")
 	
 	services := []string{
@@ -2187,6 +2752,9 @@ func enableSystemdServices() error {
 		"tenangdb-cleanup.timer", 
 		"tenangdb-exporter.service",
 	}
+	if drills {
+		services = append(services, "tenangdb-drill.timer")
+	}
 	
 	for _, service := range services {
 		// Enable service
@@ -2242,14 +2810,18 @@ WantedBy=multi-user.target
 `, systemdUser, systemdUser)
 }
 
-func generateTenangDBTimer() string {
+func generateTenangDBTimer(calendar []string) string {
+	var onCalendar strings.Builder
+	for _, c := range calendar {
+		onCalendar.WriteString("OnCalendar=" + c + "\n")
+	}
+
 	return `[Unit]
 Description=TenangDB Backup Timer
 Requires=tenangdb.service
 
 [Timer]
-OnCalendar=daily
-Persistent=true
+` + onCalendar.String() + `Persistent=true
 RandomizedDelaySec=300
 
 [Install]
@@ -2288,14 +2860,18 @@ WantedBy=multi-user.target
 `, systemdUser, systemdUser)
 }
 
-func generateCleanupTimer() string {
+func generateCleanupTimer(calendar []string) string {
+	var onCalendar strings.Builder
+	for _, c := range calendar {
+		onCalendar.WriteString("OnCalendar=" + c + "\n")
+	}
+
 	return `[Unit]
 Description=TenangDB Cleanup Timer
 Requires=tenangdb-cleanup.service
 
 [Timer]
-OnCalendar=Sat,Sun 02:00
-Persistent=true
+` + onCalendar.String() + `Persistent=true
 RandomizedDelaySec=600
 
 [Install]
@@ -2303,7 +2879,56 @@ WantedBy=timers.target
 `
 }
 
-func generateExporterService(systemdUser, metricsPort string) string {
+func generateDrillService(systemdUser string) string {
+	return fmt.Sprintf(`[Unit]
+Description=TenangDB Restore Drill
+After=network.target
+
+[Service]
+Type=oneshot
+User=%s
+Group=%s
+WorkingDirectory=/opt/tenangdb
+ExecStart=/opt/tenangdb/tenangdb drill --config /etc/tenangdb/config.yaml
+StandardOutput=journal
+StandardError=journal
+TimeoutStartSec=14400
+TimeoutStopSec=300
+
+# Security settings
+NoNewPrivileges=true
+PrivateTmp=true
+ProtectSystem=strict
+ProtectHome=true
+ReadWritePaths=/var/backups/tenangdb /var/log/tenangdb /var/lib/tenangdb
+ProtectKernelTunables=true
+ProtectKernelModules=true
+ProtectControlGroups=true
+`, systemdUser, systemdUser)
+}
+
+func generateDrillTimer(calendar []string) string {
+	var onCalendar strings.Builder
+	for _, c := range calendar {
+		onCalendar.WriteString("OnCalendar=" + c + "\n")
+	}
+
+	return `[Unit]
+Description=TenangDB Restore Drill Timer
+Requires=tenangdb-drill.service
+
+[Timer]
+` + onCalendar.String() + `Persistent=true
+RandomizedDelaySec=1800
+
+[Install]
+WantedBy=timers.target
+`
+}
+
+// generateExporterService returns the exporter unit serving metrics on
+// metricsPort, run as systemdUser from exporterPath with configPath
+func generateExporterService(systemdUser, exporterPath, configPath, metricsPort string) string {
 	return fmt.Sprintf(`[Unit]
 Description=TenangDB Metrics Exporter
 Documentation=https://tenangdb.ainun.cloud
@@ -2314,8 +2939,8 @@ Wants=network.target
 Type=simple
 User=%s
 Group=%s
-WorkingDirectory=/opt/tenangdb
-ExecStart=/opt/tenangdb/tenangdb-exporter --config /etc/tenangdb/config.yaml --port %s
+WorkingDirectory=%s
+ExecStart=%s --config %s --port %s
 ExecReload=/bin/kill -HUP $MAINPID
 Restart=always
 RestartSec=5
@@ -2352,5 +2977,5 @@ IPAddressAllow=::1/128
 
 [Install]
 WantedBy=multi-user.target
-`, systemdUser, systemdUser, metricsPort)
+`, systemdUser, systemdUser, filepath.Dir(exporterPath), exporterPath, configPath, metricsPort)
 }
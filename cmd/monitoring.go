@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+
+	"github.com/spf13/cobra"
+)
+
+// export-monitoring formats
+const (
+	formatPrometheusRules = "prometheus-rules"
+	formatGrafanaJSON     = "grafana-json"
+)
+
+func newExportMonitoringCommand() *cobra.Command {
+	var configFile string
+	var format string
+	var out string
+	opts := metrics.DefaultMonitoringOptions
+	var maxBackupAge time.Duration
+	var maxPause time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "export-monitoring",
+		Short: "Generate Prometheus alert rules or a Grafana dashboard",
+		Long: `Generate recommended Prometheus alert rules or a Grafana dashboard for the
+metrics of tenangdb-exporter. The rules alert when a backup is too old, when
+backups keep failing, when the backup disk runs low, and on SLA breaches,
+missed runs, failed restore drills and maintenance pauses that were left in
+place. The dashboard shows the same metrics with the alert thresholds marked.
+
+Both are generated from the metric names the exporter serves, so regenerate
+them after upgrading instead of editing copies. Without --max-backup-age, the
+sla.max_backup_age of the config is used when set, otherwise 26h. Without
+--max-pause, backup.pause.alert_after is used.`,
+		Example: `  tenangdb export-monitoring --format prometheus-rules --out /etc/prometheus/tenangdb-alerts.yml
+  tenangdb export-monitoring --format grafana-json --out tenangdb-dashboard.json
+  tenangdb export-monitoring --format prometheus-rules --max-backup-age 8h --min-disk-free 20`,
+		Run: func(cmd *cobra.Command, args []string) {
+			// Thresholds not set with flags come from the config, when there is one
+			if cfg, err := config.LoadConfig(configFile); err == nil {
+				if cfg.SLA.MaxBackupAge > 0 {
+					opts.MaxBackupAge = cfg.SLA.MaxBackupAge
+				}
+				opts.MaxPause = cfg.Backup.Pause.AlertAfter
+			}
+			if maxBackupAge > 0 {
+				opts.MaxBackupAge = maxBackupAge
+			}
+			if maxPause > 0 {
+				opts.MaxPause = maxPause
+			}
+			if err := runExportMonitoring(format, out, opts); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&format, "format", formatPrometheusRules, "output format: prometheus-rules or grafana-json")
+	cmd.Flags().StringVar(&out, "out", "", "file to write to (default: stdout)")
+	cmd.Flags().DurationVar(&maxBackupAge, "max-backup-age", 0, "alert when the last backup of a database is older than this")
+	cmd.Flags().DurationVar(&maxPause, "max-pause", 0, "alert when backups have been paused for longer than this")
+	cmd.Flags().IntVar(&opts.FailureStreak, "failure-streak", opts.FailureStreak, "alert after this many backups of a database failed in a row")
+	cmd.Flags().Float64Var(&opts.MinDiskFree, "min-disk-free", opts.MinDiskFree, "alert when less than this percentage of the backup filesystem is free")
+
+	return cmd
+}
+
+func runExportMonitoring(format, out string, opts metrics.MonitoringOptions) error {
+	if opts.FailureStreak < 1 {
+		return fmt.Errorf("--failure-streak must be at least 1")
+	}
+	if opts.MinDiskFree <= 0 || opts.MinDiskFree >= 100 {
+		return fmt.Errorf("--min-disk-free must be between 0 and 100")
+	}
+
+	var content []byte
+	switch format {
+	case formatPrometheusRules:
+		content = []byte(metrics.AlertRules(opts))
+	case formatGrafanaJSON:
+		dashboard, err := metrics.Dashboard(opts)
+		if err != nil {
+			return fmt.Errorf("failed to generate dashboard: %w", err)
+		}
+		content = append(dashboard, '\n')
+	default:
+		return fmt.Errorf("invalid format %q, must be %s or %s", format, formatPrometheusRules, formatGrafanaJSON)
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+	if err := os.WriteFile(out, content, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", out, err)
+	}
+	fmt.Printf("✅ Written to %s\n", out)
+	return nil
+}
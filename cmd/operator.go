@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/kubernetes"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/policy"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/internal/schedule"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newOperatorCommand() *cobra.Command {
+	var configFile string
+	var interval time.Duration
+	var once bool
+
+	cmd := &cobra.Command{
+		Use:   "operator",
+		Short: "Reconcile BackupSchedule and RestoreRequest resources in Kubernetes",
+		Long: `Run as a Kubernetes operator: poll the BackupSchedule and RestoreRequest custom
+resources (k8s/crds.yaml) and reconcile them with the backup and restore engine.
+
+A BackupSchedule is backed up whenever its schedule is due, with the backup
+settings of the config, into {backup.directory}/{namespace}/{name}. Missed runs
+are caught up with a single backup. A RestoreRequest restores one of those
+backups into the schedule's MySQL server once, and records the outcome in its
+status. Run a single replica: work is done one resource at a time, and a
+request found Running at startup was interrupted and is marked Failed.`,
+		Example: `  tenangdb operator --config /config.yaml
+  tenangdb operator --interval 30s`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runOperator(configFile, interval, once); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().DurationVar(&interval, "interval", time.Minute, "how often to poll the custom resources")
+	cmd.Flags().BoolVar(&once, "once", false, "reconcile once and exit")
+
+	return cmd
+}
+
+// operator reconciles the custom resources of one cluster
+type operator struct {
+	cfg    *config.Config
+	client *kubernetes.Client
+	log    *logger.Logger
+}
+
+func runOperator(configFile string, interval time.Duration, once bool) error {
+	if interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sigChan
+		cancel()
+	}()
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	client, err := kubernetes.NewClient(&cfg.Kubernetes)
+	if err != nil {
+		return err
+	}
+
+	log, err := logger.NewFileLoggerWithSeparateFormats(cfg.Logging.Level, cfg.Logging.FilePath, cfg.Logging.Format, cfg.Logging.FileFormat)
+	if err != nil {
+		log = logger.NewLogger(cfg.Logging.Level)
+		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
+	}
+	applyOutputMode(log, cfg)
+
+	op := &operator{cfg: cfg, client: client, log: log}
+	op.failInterrupted(ctx)
+
+	log.WithField("interval", interval).Info("☸️ Operator started")
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		op.reconcile(ctx)
+		if once {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			log.Info("Operator stopped")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// reconcile runs due schedules and pending restore requests. Errors are
+// logged and recorded in resource status, the next pass tries again.
+func (op *operator) reconcile(ctx context.Context) {
+	schedules, err := op.client.BackupSchedules(ctx, op.cfg.Kubernetes.Namespaces)
+	if err != nil {
+		op.log.WithError(err).Error("Failed to list backup schedules")
+	}
+	// Due schedules wait for the end of a maintenance pause and run then
+	if paused, err := checkPaused(op.cfg); err != nil {
+		op.log.WithError(err).Warn("Failed to check for a maintenance pause")
+	} else if paused != nil {
+		op.log.WithField("until", paused.Until.Format(time.RFC3339)).Debug("Backups are paused, not running due schedules")
+		schedules = nil
+	}
+	for i := range schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		op.reconcileSchedule(ctx, &schedules[i], time.Now())
+	}
+
+	requests, err := op.client.RestoreRequests(ctx, op.cfg.Kubernetes.Namespaces)
+	if err != nil {
+		op.log.WithError(err).Error("Failed to list restore requests")
+	}
+	for i := range requests {
+		if ctx.Err() != nil {
+			return
+		}
+		if request := &requests[i]; !request.Done() {
+			op.reconcileRestore(ctx, request)
+		}
+	}
+}
+
+// reconcileSchedule backs up the schedule's databases when a run is due
+// since its last one, or since it was created
+func (op *operator) reconcileSchedule(ctx context.Context, bs *kubernetes.BackupSchedule, now time.Time) {
+	log := op.log.WithField("schedule", bs.Name())
+	if bs.Spec.Suspend {
+		return
+	}
+	sched, err := schedule.Parse(bs.Spec.Schedule)
+	if err != nil {
+		op.updateScheduleStatus(ctx, bs, now, err)
+		return
+	}
+	last := bs.Metadata.CreationTimestamp
+	if bs.Status.LastScheduleTime != nil {
+		last = *bs.Status.LastScheduleTime
+	}
+	if next := sched.Next(last.In(now.Location())); next.IsZero() || next.After(now) {
+		return
+	}
+
+	runID := runid.New()
+	op.log.SetRunID(runID)
+	runCtx := runid.WithContext(ctx, runID)
+
+	log.Info("🔄 Backup schedule " + bs.Name() + " is due")
+	instance, err := op.client.Instance(runCtx, bs)
+	if err == nil {
+		err = backupInstance(runCtx, op.cfg, instance, op.log)
+	}
+	if err != nil {
+		log.WithError(err).Error("❌ Scheduled backup of " + bs.Name() + " failed")
+	} else {
+		log.Info("✅ Scheduled backup of " + bs.Name() + " completed")
+	}
+	op.updateScheduleStatus(ctx, bs, now, err)
+}
+
+func (op *operator) updateScheduleStatus(ctx context.Context, bs *kubernetes.BackupSchedule, now time.Time, runErr error) {
+	bs.Status.LastScheduleTime = &now
+	bs.Status.LastResult, bs.Status.Message = kubernetes.PhaseSucceeded, ""
+	if runErr != nil {
+		bs.Status.LastResult, bs.Status.Message = kubernetes.PhaseFailed, runErr.Error()
+	} else {
+		finished := time.Now()
+		bs.Status.LastSuccessfulTime = &finished
+	}
+	if err := op.client.UpdateBackupScheduleStatus(context.WithoutCancel(ctx), bs); err != nil {
+		op.log.WithError(err).WithField("schedule", bs.Name()).Warn("Failed to update backup schedule status")
+	}
+}
+
+// failInterrupted marks restore requests left Running by a previous
+// operator process as failed, since nothing is restoring them anymore
+func (op *operator) failInterrupted(ctx context.Context) {
+	requests, err := op.client.RestoreRequests(ctx, op.cfg.Kubernetes.Namespaces)
+	if err != nil {
+		op.log.WithError(err).Warn("Failed to list restore requests")
+		return
+	}
+	for i := range requests {
+		if request := &requests[i]; request.Status.Phase == kubernetes.PhaseRunning {
+			op.updateRestoreStatus(ctx, request, fmt.Errorf("operator restarted during the restore"))
+		}
+	}
+}
+
+// reconcileRestore runs a pending restore request and records the outcome
+func (op *operator) reconcileRestore(ctx context.Context, request *kubernetes.RestoreRequest) {
+	log := op.log.WithField("restore_request", request.Name())
+
+	runID := runid.New()
+	op.log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	start := time.Now()
+	request.Status = kubernetes.RestoreRequestStatus{Phase: kubernetes.PhaseRunning, StartTime: &start}
+	if err := op.client.UpdateRestoreRequestStatus(ctx, request); err != nil {
+		// Without the Running phase a restart could not tell the request
+		// was started, so leave it for the next pass
+		log.WithError(err).Warn("Failed to update restore request status")
+		return
+	}
+
+	log.Info("🔄 Restoring for request " + request.Name())
+	err := op.restore(ctx, request)
+	if err != nil {
+		log.WithError(err).Error("❌ Restore for " + request.Name() + " failed")
+	} else {
+		log.Info("✅ Restore for " + request.Name() + " completed")
+	}
+	op.updateRestoreStatus(ctx, request, err)
+}
+
+func (op *operator) updateRestoreStatus(ctx context.Context, request *kubernetes.RestoreRequest, runErr error) {
+	finished := time.Now()
+	request.Status.CompletionTime = &finished
+	request.Status.Phase, request.Status.Message = kubernetes.PhaseSucceeded, ""
+	if runErr != nil {
+		request.Status.Phase, request.Status.Message = kubernetes.PhaseFailed, runErr.Error()
+	}
+	if err := op.client.UpdateRestoreRequestStatus(context.WithoutCancel(ctx), request); err != nil {
+		op.log.WithError(err).WithField("restore_request", request.Name()).Warn("Failed to update restore request status")
+	}
+}
+
+// restore loads a backup taken by the request's schedule into its target
+// database on the schedule's server
+func (op *operator) restore(ctx context.Context, request *kubernetes.RestoreRequest) error {
+	log := op.log
+	spec := request.Spec
+	if spec.BackupSchedule == "" || spec.Database == "" || spec.TargetDatabase == "" {
+		return fmt.Errorf("spec.backupSchedule, spec.database and spec.targetDatabase are required")
+	}
+	bs, err := op.client.BackupSchedule(ctx, request.Metadata.Namespace, spec.BackupSchedule)
+	if err != nil {
+		return err
+	}
+	instance, err := op.client.Instance(ctx, bs)
+	if err != nil {
+		return err
+	}
+	instanceCfg := instanceConfig(op.cfg, instance)
+
+	entries, err := catalog.Scan(instanceCfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+	var entry *catalog.Entry
+	for i := range entries {
+		if entries[i].Manifest.Database == spec.Database && (spec.Backup == "" || entries[i].ID == spec.Backup) {
+			entry = &entries[i]
+			break
+		}
+	}
+	if entry == nil {
+		if spec.Backup != "" {
+			return fmt.Errorf("backup %s of %s not found", spec.Backup, spec.Database)
+		}
+		return fmt.Errorf("no backup of %s found", spec.Database)
+	}
+	request.Status.Backup = entry.ID
+
+	if err := policy.CheckRestore(&op.cfg.Policy, instance.Host, spec.TargetDatabase); err != nil {
+		return err
+	}
+
+	dbClient, err := database.NewClient(&instanceCfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer dbClient.Close()
+	dbClient.SetLogger(log)
+
+	start := time.Now()
+	if err := checkRestoreCompat(ctx, dbClient, &op.cfg.Restore, entry.ArtifactPath, log); err != nil {
+		return err
+	}
+	if err := checkRestoreCharset(ctx, dbClient, &op.cfg.Restore, entry.ArtifactPath, spec.TargetDatabase, log); err != nil {
+		return err
+	}
+	backupPath, cleanup, err := decryptBackup(ctx, &instanceCfg, entry.ArtifactPath, log)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	log.WithField("backup", entry.ID).WithField("target_database", spec.TargetDatabase).Info("Starting database restore")
+	restoreErr := dbClient.RestoreBackup(ctx, backupPath, spec.TargetDatabase, &op.cfg.Restore)
+
+	if op.cfg.Metrics.Enabled {
+		metricsPath := op.cfg.Metrics.StoragePath
+		if metricsPath == "" {
+			metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
+		}
+		if err := metrics.NewMetricsStorage(metricsPath).UpdateRestoreMetrics(spec.TargetDatabase, time.Since(start), restoreErr == nil); err != nil {
+			log.WithError(err).Warn("Failed to update restore metrics")
+		}
+	}
+	return restoreErr
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	quietOutput   bool // --quiet: errors only, as JSON lines
+	verboseOutput bool // --verbose: debug level, external commands and per-table details
+)
+
+// applyOutputMode adjusts a command's logger for --quiet, --verbose and
+// logging.emoji
+func applyOutputMode(log *logger.Logger, cfg *config.Config) {
+	switch {
+	case quietOutput:
+		log.SetQuiet()
+		log.DisableEmoji()
+		return
+	case verboseOutput:
+		log.SetLevel(logrus.DebugLevel)
+	}
+
+	if !cfg.Logging.Emoji {
+		log.DisableEmoji()
+	}
+}
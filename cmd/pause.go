@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/pause"
+
+	"github.com/spf13/cobra"
+)
+
+// pauseTimeLayouts are the accepted --until formats, without a zone in
+// local time
+var pauseTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02 15:04", "2006-01-02"}
+
+func newPauseCommand() *cobra.Command {
+	var configFile string
+	var until string
+	var duration time.Duration
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "pause",
+		Short: "Pause scheduled backups for a maintenance window",
+		Long: `Pause backups until a point in time, for maintenance windows where backups
+must not run. While paused, 'tenangdb backup' logs that it is paused and exits
+successfully without backing up, and the operator skips due schedules. The
+pause ends by itself at --until, or earlier with 'tenangdb resume'.
+
+Missed runs within the pause are not reported by the watchdog. When a pause
+lasts longer than backup.pause.alert_after, tenangdb-exporter logs an error
+and reports it in /status and tenangdb_backup_paused_seconds.`,
+		Example: `  tenangdb pause --until 2025-09-01T06:00 --reason "storage migration"
+  tenangdb pause --for 4h`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runPause(configFile, until, duration, reason); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&until, "until", "", "end of the pause: 2025-09-01T06:00 in local time, or RFC 3339")
+	cmd.Flags().DurationVar(&duration, "for", 0, "length of the pause, instead of --until")
+	cmd.Flags().StringVar(&reason, "reason", "", "why backups are paused, shown by backup runs")
+
+	return cmd
+}
+
+func newResumeCommand() *cobra.Command {
+	var configFile string
+
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "End a maintenance pause",
+		Long:  `Remove the pause set with 'tenangdb pause' so scheduled backups run again.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runResume(configFile); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+
+	return cmd
+}
+
+// parsePauseUntil parses --until
+func parsePauseUntil(value string) (time.Time, error) {
+	for _, layout := range pauseTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --until %q, use 2025-09-01T06:00 or RFC 3339", value)
+}
+
+func runPause(configFile, until string, duration time.Duration, reason string) error {
+	if (until == "") == (duration == 0) {
+		return fmt.Errorf("set either --until or --for")
+	}
+
+	now := time.Now()
+	end := now.Add(duration)
+	if until != "" {
+		var err error
+		if end, err = parsePauseUntil(until); err != nil {
+			return err
+		}
+	}
+	if !end.After(now) {
+		return fmt.Errorf("the pause must end in the future, not at %s", end.Format(time.RFC3339))
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	marker := &pause.Marker{PausedAt: now, Until: end, Reason: reason}
+	if err := pause.Save(cfg.StateDirectory, marker); err != nil {
+		return err
+	}
+
+	fmt.Printf("⏸️  Backups paused until %s\n", end.Format("2006-01-02 15:04 MST"))
+	if end.Sub(now) > cfg.Backup.Pause.AlertAfter {
+		fmt.Printf("⚠️  The pause is longer than backup.pause.alert_after (%s), monitoring will report it before it ends\n",
+			formatDuration(cfg.Backup.Pause.AlertAfter))
+	}
+	fmt.Printf("💡 Run 'tenangdb resume' to end it earlier\n")
+	return nil
+}
+
+func runResume(configFile string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	marker, err := pause.Load(cfg.StateDirectory)
+	if err != nil {
+		return err
+	}
+	if _, err := pause.Remove(cfg.StateDirectory); err != nil {
+		return err
+	}
+
+	if !marker.Active(time.Now()) {
+		fmt.Printf("Backups are not paused\n")
+		return nil
+	}
+	fmt.Printf("▶️  Backups resumed, the pause was set until %s\n", marker.Until.Format("2006-01-02 15:04 MST"))
+	return nil
+}
+
+// checkPaused returns the pause stopping backups with cfg, or nil
+func checkPaused(cfg *config.Config) (*pause.Marker, error) {
+	marker, err := pause.Load(cfg.StateDirectory)
+	if err != nil || !marker.Active(time.Now()) {
+		return nil, err
+	}
+	return marker, nil
+}
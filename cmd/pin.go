@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+
+	"github.com/spf13/cobra"
+)
+
+func newPinCommand() *cobra.Command {
+	return newPinningCommand(true)
+}
+
+func newUnpinCommand() *cobra.Command {
+	return newPinningCommand(false)
+}
+
+// newPinningCommand builds `pin` or `unpin`, which differ only in the flag
+// they set
+func newPinningCommand(pinned bool) *cobra.Command {
+	var configFile string
+	var tenant string
+
+	use, short, long := "pin", "Protect a backup from cleanup",
+		`Pin a backup so cleanup never removes it until it is unpinned.
+Backup IDs are shown by 'tenangdb list'.`
+	if !pinned {
+		use, short, long = "unpin", "Allow cleanup to remove a pinned backup",
+			`Unpin a backup so the normal retention rules apply to it again.`
+	}
+
+	cmd := &cobra.Command{
+		Use:   use + " <backup-id>",
+		Short: short,
+		Long:  long,
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runPin(configFile, args[0], tenant, pinned); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only allow backups of databases of the named tenant")
+
+	return cmd
+}
+
+func runPin(configFile, id, tenant string, pinned bool) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	entry, err := catalog.Find(cfg.Backup.Directory, id)
+	if err != nil {
+		return err
+	}
+	if err := checkTenantBackup(cfg, tenant, entry); err != nil {
+		return err
+	}
+
+	action := "Pinned"
+	if !pinned {
+		action = "Unpinned"
+	}
+
+	if entry.Manifest.Pinned == pinned {
+		fmt.Printf("Backup %s is already %s\n", id, strings.ToLower(action))
+		return nil
+	}
+
+	entry.Manifest.Pinned = pinned
+	if _, err := entry.Manifest.Write(entry.ArtifactPath); err != nil {
+		return err
+	}
+
+	// Keep the cloud copy of the manifest in sync
+	if cfg.Upload.Enabled {
+		log := logger.NewLogger(logLevel)
+		uploader := upload.NewService(&cfg.Upload, log)
+		if err := uploader.UploadTo(context.Background(), manifest.PathFor(entry.ArtifactPath), entry.Manifest.Destination); err != nil {
+			return fmt.Errorf("%s locally, but failed to upload the manifest: %w", action, err)
+		}
+	}
+
+	fmt.Printf("📌 %s backup %s\n", action, id)
+	return nil
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/backup"
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/compression"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/encryption"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/plan"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// Output formats for --dry-run
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// validateOutputFormat checks the --output flag value
+func validateOutputFormat(output string) error {
+	switch output {
+	case outputText, outputJSON:
+		return nil
+	default:
+		return fmt.Errorf("invalid output format %q (use text or json)", output)
+	}
+}
+
+// writeBackupPlan prints what a backup would create and upload as JSON.
+// Sizes are estimated from each database's last backup, and so is whether
+// an archive is streamed or uploaded in parts.
+func writeBackupPlan(ctx context.Context, cfg *config.Config, skipUpload bool, log *logger.Logger) error {
+	p := plan.New("backup")
+	now := time.Now()
+
+	compressor := compression.NewCompressor(&cfg.Backup.Compression, log)
+
+	for _, dbName := range cfg.Backup.Databases {
+		// Backups of a tenant's databases go below its upload prefix
+		var uploader *upload.Service
+		if cfg.Upload.Enabled && !skipUpload {
+			uploader = upload.NewService(cfg.UploadFor(dbName), log)
+		}
+
+		var estimate int64
+		if history := backup.History(cfg.Backup.Directory, dbName); len(history) > 0 {
+			estimate = history[0].SizeBytes
+		}
+
+		backupPlan := database.PlanBackup(&cfg.Database, dbName, cfg.Backup.Directory, now)
+		if cfg.Database.IsPostgreSQL() {
+			backupPlan = database.PlanPostgreSQLBackup(&cfg.Database, dbName, cfg.Backup.Directory, now, !cfg.Backup.Compression.Enabled)
+		}
+		artifact := backupPlan.Artifact
+		isDir := backupPlan.Tool == "mydumper" || backupPlan.Tool == "pg_dump" && cfg.Database.PostgreSQL.Format == "directory"
+		p.Add(plan.Action{
+			Type:     plan.Create,
+			Database: dbName,
+			Path:     artifact,
+			Command:  backupPlan.Command,
+			Reason:   backupPlan.Tool + " backup",
+		})
+
+		if cfg.ArchivesBackup(backupPlan.Tool) {
+			archive, err := compressor.ArchivePath(artifact)
+			if err != nil {
+				return err
+			}
+			artifact, isDir = archive, false
+
+			// Like the backup, which goes by the size of the dump, judge
+			// by the last backup whether the archive is streamed
+			compressionCfg := cfg.Backup.Compression
+			if uploader != nil && compressionCfg.StreamUpload && !cfg.Backup.Encryption.Enabled && cfg.Upload.SplitSizeGB == 0 && estimate >= int64(compressionCfg.StreamMinSizeMB)<<20 {
+				p.Add(plan.Action{
+					Type:           plan.Upload,
+					Database:       dbName,
+					Path:           artifact,
+					Destination:    uploader.RemotePath(artifact, false),
+					Command:        uploader.StreamCommand(ctx, artifact, -1),
+					EstimatedBytes: estimate,
+					Reason:         "compressed and uploaded in one stream, without a local archive",
+				})
+				continue
+			}
+
+			p.Add(plan.Action{
+				Type:     plan.Create,
+				Database: dbName,
+				Path:     artifact,
+				Reason:   "compressed archive",
+			})
+		}
+
+		if encryptionCfg := cfg.Backup.Encryption; encryptionCfg.Enabled {
+			artifact += encryption.Suffix
+			p.Add(plan.Action{
+				Type:     plan.Create,
+				Database: dbName,
+				Path:     artifact,
+				Reason:   fmt.Sprintf("encrypted under %s key %s", encryptionCfg.Provider, encryptionCfg.KeyID),
+			})
+		}
+
+		// The final artifact carries the size estimate
+		p.Actions[len(p.Actions)-1].EstimatedBytes = estimate
+		p.EstimatedBytes += estimate
+
+		if uploader != nil {
+			addUploadActions(ctx, p, uploader, dbName, artifact, isDir, estimate)
+		}
+	}
+
+	return p.Write(os.Stdout)
+}
+
+// writeUploadPlan prints what an upload of entries would copy as JSON
+func writeUploadPlan(ctx context.Context, cfg *config.Config, entries []catalog.Entry, log *logger.Logger) error {
+	p := plan.New("upload")
+
+	for _, entry := range entries {
+		info, err := os.Stat(entry.ArtifactPath)
+		if err != nil {
+			return fmt.Errorf("backup %s: %w", entry.ID, err)
+		}
+		uploader := upload.NewService(cfg.UploadFor(entry.Manifest.Database), log)
+		addUploadActions(ctx, p, uploader, entry.Manifest.Database, entry.ArtifactPath, info.IsDir(), entry.Manifest.SizeBytes)
+	}
+
+	return p.Write(os.Stdout)
+}
+
+// addUploadActions adds the upload of artifact to p, one action per part
+// for files over upload.split_size_gb
+func addUploadActions(ctx context.Context, p *plan.Plan, uploader *upload.Service, dbName, artifact string, isDir bool, size int64) {
+	var parts []int64
+	if !isDir {
+		parts = uploader.PartSizes(size)
+	}
+	for i, partSize := range parts {
+		part := manifest.PartName(artifact, i)
+		p.Add(plan.Action{
+			Type:           plan.Upload,
+			Database:       dbName,
+			Path:           part,
+			Destination:    uploader.RemotePath(part, false),
+			Command:        uploader.StreamCommand(ctx, part, partSize),
+			EstimatedBytes: partSize,
+			Reason:         fmt.Sprintf("part %d of %d", i+1, len(parts)),
+		})
+	}
+	if parts == nil {
+		p.Add(plan.Action{
+			Type:           plan.Upload,
+			Database:       dbName,
+			Path:           artifact,
+			Destination:    uploader.RemotePath(artifact, isDir),
+			Command:        uploader.CopyCommand(ctx, artifact, isDir),
+			EstimatedBytes: size,
+		})
+	}
+}
+
+// writeCleanupPlan prints what a cleanup would delete as JSON
+func writeCleanupPlan(cleanupPlan *backup.CleanupPlan, trash *backup.Trash) error {
+	p := plan.New("cleanup")
+
+	for _, batch := range cleanupPlan.ExpiredTrash {
+		size, _ := getDirSize(batch)
+		p.Add(plan.Action{Type: plan.Delete, Path: batch, EstimatedBytes: size, Reason: "trash retention expired"})
+	}
+
+	// Files are moved to the trash instead of deleted when it is enabled
+	removal := plan.Delete
+	if trash.Enabled() {
+		removal = plan.Trash
+	}
+
+	for _, candidate := range cleanupPlan.Removals() {
+		p.Add(plan.Action{Type: removal, Database: candidate.Database, Path: candidate.Path, EstimatedBytes: candidate.Size, Reason: candidate.Reason})
+	}
+
+	return p.Write(os.Stdout)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/compression"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// requireMySQL fails commands that only work against MySQL when
+// database.type is postgresql
+func requireMySQL(cfg *config.Config, command string) error {
+	if cfg.Database.IsPostgreSQL() {
+		return fmt.Errorf("%s only works with MySQL, it is not available for database.type postgresql", command)
+	}
+	return nil
+}
+
+// runPostgreSQLRestore restores a pg_dump backup with pg_restore or psql.
+// Backups compressed by tenangdb are unpacked first; the MySQL checks and
+// resumable loads of runRestore do not apply.
+func runPostgreSQLRestore(ctx context.Context, cfg *config.Config, backupPath, targetDatabase string, yes bool, flags restoreFlags, log *logger.Logger) {
+	if flags.resume || flags.triggers != "" || flags.definer != "" {
+		log.Fatal("--resume, --triggers and --definer only work with MySQL")
+	}
+
+	provider, err := database.NewConfiguredProvider(&cfg.Database)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to initialize PostgreSQL provider")
+	}
+	defer provider.Close()
+
+	metricsStorage := newRestoreMetricsStorage(cfg)
+
+	log.WithField("backup_path", backupPath).WithField("target_database", targetDatabase).Info("Starting database restore")
+
+	if !yes {
+		exists, err := provider.DatabaseExists(ctx, targetDatabase)
+		if err != nil {
+			log.WithError(err).Warn("Failed to check if database exists")
+		}
+		if !confirmRestore(targetDatabase, exists) {
+			log.Info("Database restore cancelled by user")
+			return
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	restoreStartTime := time.Now()
+	if cfg.Metrics.Enabled {
+		metrics.RecordRestoreStart(targetDatabase)
+	}
+
+	if upload.IsRemotePath(backupPath) {
+		localPath, cleanup, err := downloadBackup(ctx, cfg, backupPath, log)
+		if err != nil {
+			log.WithError(err).Fatal("Failed to download backup")
+		}
+		defer cleanupOnExit(cleanup)()
+		backupPath = localPath
+	}
+
+	backupPath, cleanupDecrypted, err := decryptBackup(ctx, cfg, backupPath, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to decrypt backup")
+	}
+	defer cleanupOnExit(cleanupDecrypted)()
+
+	dumpPath, cleanupDump, err := unpackPostgreSQLBackup(cfg, backupPath, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to decompress backup")
+	}
+	defer cleanupOnExit(cleanupDump)()
+
+	err = provider.RestoreBackup(ctx, &database.RestoreOptions{
+		BackupPath:   dumpPath,
+		TargetDB:     targetDatabase,
+		DropIfExists: true,
+	})
+	restoreDuration := time.Since(restoreStartTime)
+	recordRestoreMetrics(cfg, metricsStorage, targetDatabase, restoreDuration, err == nil, log)
+	if err != nil {
+		log.WithError(err).Error("Database restore failed")
+		log.Exit(1)
+	}
+
+	log.WithField("target_database", targetDatabase).Info("Database restore completed successfully")
+}
+
+// unpackPostgreSQLBackup returns the pg_dump output inside a backup
+// compressed by tenangdb, and a function removing what was unpacked
+func unpackPostgreSQLBackup(cfg *config.Config, backupPath string, log *logger.Logger) (string, func(), error) {
+	if compression.DetectFormat(backupPath) == "" {
+		return backupPath, func() {}, nil
+	}
+
+	compressor := compression.NewCompressor(&config.CompressionConfig{Enabled: true, Format: "tar.gz"}, log)
+	compressor.SetMaxExtractSize(int64(cfg.Restore.MaxExtractSizeGB) << 30)
+	compressor.SetTempDirectory(cfg.Restore.TempDirectory, cfg.Restore.ExpansionFactor)
+	unpacked, err := compressor.DecompressBackup(backupPath)
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(unpacked) }
+
+	// A tar archive holds the single dump file or directory pg_dump wrote
+	dumpPath := unpacked
+	if info, err := os.Stat(unpacked); err == nil && info.IsDir() {
+		if _, err := os.Stat(filepath.Join(unpacked, "toc.dat")); err != nil {
+			entries, err := os.ReadDir(unpacked)
+			if err != nil {
+				cleanup()
+				return "", nil, err
+			}
+			if len(entries) != 1 {
+				cleanup()
+				return "", nil, fmt.Errorf("%s does not hold a single pg_dump backup", backupPath)
+			}
+			dumpPath = filepath.Join(unpacked, entries[0].Name())
+		}
+	}
+	return dumpPath, cleanup, nil
+}
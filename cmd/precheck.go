@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// checkRestoreCompat fails a restore before anything is loaded when the
+// target server can't take the backup, such as a MySQL 8.0 dump with 0900
+// collations onto 5.7, instead of erroring far into the load
+func checkRestoreCompat(ctx context.Context, dbClient *database.Client, restoreCfg *config.RestoreConfig, backupPath string, log *logger.Logger) error {
+	if !restoreCfg.CompatCheck {
+		return nil
+	}
+	m, err := manifest.Read(manifest.PathFor(backupPath))
+	if err != nil || m.ServerVersion == "" {
+		log.Debug("Backup has no server version, skipping compatibility check")
+		return nil
+	}
+
+	problems, err := dbClient.CheckRestoreCompat(ctx, database.RestoreSource{
+		ServerVersion: m.ServerVersion,
+		TargetCompat:  m.TargetCompat,
+		Collations:    m.Collations,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to check restore compatibility: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("the target server can't load this backup:\n  - %s\nset restore.compat_check: false or pass restore --skip-compat-check to try anyway",
+			strings.Join(problems, "\n  - "))
+	}
+
+	log.WithField("source_version", m.ServerVersion).Debug("Target server can load the backup")
+	return nil
+}
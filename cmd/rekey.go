@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/encryption"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+
+	"github.com/spf13/cobra"
+)
+
+func newRekeyCommand() *cobra.Command {
+	var configFile string
+	var report bool
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "rekey [backup-id]...",
+		Short: "Move encrypted backups to the configured encryption key",
+		Long: `Move encrypted backups from a retired key to the key configured in
+backup.encryption.
+
+Only the wrapped data key in each artifact's header is replaced: the data key is
+unwrapped with the key the header names and wrapped again with the configured
+one, and the encrypted data is copied as it is. Nothing is decrypted or
+restored. Backups that are only in the cloud are downloaded, rewritten and
+uploaded again; uploaded backups are replaced in the cloud along with their
+manifests.
+
+Without backup IDs, every backup under a key other than the configured one is
+rekeyed. With --report, those backups are listed and nothing is changed.`,
+		Example: `  tenangdb rekey --report
+  tenangdb rekey
+  tenangdb rekey app_db-2025-07-05_01-00-00`,
+		Run: func(cmd *cobra.Command, args []string) {
+			var err error
+			if report {
+				if len(args) > 0 {
+					err = fmt.Errorf("--report cannot be combined with backups")
+				} else {
+					err = runRekeyReport(configFile, tenant)
+				}
+			} else {
+				err = runRekey(configFile, args, tenant)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().BoolVar(&report, "report", false, "list backups still under retired keys without changing them")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only rekey or report backups of databases of the named tenant")
+
+	return cmd
+}
+
+// loadRekeyConfig loads the configuration and the tenant's backups
+func loadRekeyConfig(configFile, tenant string) (*config.Config, []catalog.Entry, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.Backup.Encryption.Provider == "" || cfg.Backup.Encryption.KeyID == "" {
+		return nil, nil, fmt.Errorf("backup.encryption.provider and backup.encryption.key_id must name the current key")
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return nil, nil, err
+	}
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+	return cfg, tenantEntries(cfg, tenant, entries), nil
+}
+
+// retiredKey reports whether entry is encrypted under a key other than the
+// configured one
+func retiredKey(cfg *config.Config, entry catalog.Entry) bool {
+	enc := entry.Manifest.Encryption
+	return enc != nil && (enc.Provider != cfg.Backup.Encryption.Provider || enc.KeyID != cfg.Backup.Encryption.KeyID)
+}
+
+func runRekeyReport(configFile, tenant string) error {
+	cfg, entries, err := loadRekeyConfig(configFile, tenant)
+	if err != nil {
+		return err
+	}
+
+	var retired []catalog.Entry
+	for _, entry := range entries {
+		if retiredKey(cfg, entry) {
+			retired = append(retired, entry)
+		}
+	}
+	if len(retired) == 0 {
+		fmt.Printf("No backups under retired keys, all encrypted backups use %s key %s\n", cfg.Backup.Encryption.Provider, cfg.Backup.Encryption.KeyID)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATABASE\tCREATED\tPROVIDER\tKEY ID\tCLOUD")
+	for _, entry := range retired {
+		m := entry.Manifest
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", entry.ID, m.Database, m.CreatedAt.Local().Format("2006-01-02 15:04"),
+			m.Encryption.Provider, m.Encryption.KeyID, m.Destination)
+	}
+	w.Flush()
+	fmt.Printf("\n%d backup(s) under retired keys, run 'tenangdb rekey' to move them to %s key %s\n", len(retired), cfg.Backup.Encryption.Provider, cfg.Backup.Encryption.KeyID)
+	return nil
+}
+
+func runRekey(configFile string, refs []string, tenant string) error {
+	cfg, entries, err := loadRekeyConfig(configFile, tenant)
+	if err != nil {
+		return err
+	}
+
+	var selected []catalog.Entry
+	if len(refs) > 0 {
+		if selected, err = selectBackups(entries, refs, ""); err != nil {
+			return err
+		}
+	} else {
+		for _, entry := range entries {
+			if retiredKey(cfg, entry) {
+				selected = append(selected, entry)
+			}
+		}
+	}
+
+	log := logger.NewLogger(logLevel)
+	ctx := context.Background()
+
+	rekeyed, failed := 0, 0
+	for i := range selected {
+		entry := &selected[i]
+		entryLog := log.WithField("backup", entry.ID)
+		if entry.Manifest.Encryption == nil {
+			entryLog.Warn("⚠️ Backup is not encrypted, skipping")
+			continue
+		}
+		if !retiredKey(cfg, *entry) {
+			entryLog.Info("Backup already uses the configured key, skipping")
+			continue
+		}
+
+		if err := rekeyBackup(ctx, cfg, entry, log); err != nil {
+			entryLog.WithError(err).Error("❌ Failed to rekey backup")
+			failed++
+			continue
+		}
+		entryLog.WithField("key_id", cfg.Backup.Encryption.KeyID).Info("🔐 Backup rekeyed")
+		rekeyed++
+	}
+
+	fmt.Printf("Rekeyed %d backup(s) to %s key %s\n", rekeyed, cfg.Backup.Encryption.Provider, cfg.Backup.Encryption.KeyID)
+	if failed > 0 {
+		return fmt.Errorf("%d backup(s) could not be rekeyed", failed)
+	}
+	return nil
+}
+
+// rekeyBackup rewraps the data key of a backup, downloading it first when
+// only the cloud copy is left, and replaces the cloud copy. The manifest is
+// updated last, so a backup whose upload failed is still reported and
+// rekeyed again on the next run.
+func rekeyBackup(ctx context.Context, cfg *config.Config, entry *catalog.Entry, log *logger.Logger) error {
+	m := entry.Manifest
+	if m.Destination != "" && !cfg.Upload.Enabled {
+		return fmt.Errorf("upload is not enabled, the cloud copy on %s cannot be replaced", m.Destination)
+	}
+
+	path := entry.ArtifactPath
+	if _, err := os.Stat(path); err != nil {
+		if m.Destination == "" {
+			return fmt.Errorf("%s does not exist and was never uploaded", path)
+		}
+		localPath, cleanup, err := downloadBackup(ctx, cfg, upload.RemoteArtifactPath(m.Destination, path), log)
+		if err != nil {
+			return fmt.Errorf("failed to download backup: %w", err)
+		}
+		defer cleanup()
+		path = localPath
+	}
+
+	header, err := encryption.Rewrap(ctx, &cfg.Backup.Encryption, path)
+	if err != nil {
+		return err
+	}
+
+	uploader := upload.NewService(cfg.UploadFor(m.Database), log)
+	if m.Destination != "" {
+		if err := uploader.UploadTo(ctx, path, m.Destination); err != nil {
+			return fmt.Errorf("rekeyed locally, but failed to replace the cloud copy: %w", err)
+		}
+		// A split upload listed its parts in the manifest next to path
+		if uploaded, err := manifest.Load(path); err == nil {
+			m.Parts = uploaded.Parts
+		}
+	}
+
+	m.Encryption.Provider, m.Encryption.KeyID = header.Provider, header.KeyID
+	if m.SHA256, err = manifest.FileChecksum(path); err != nil {
+		return fmt.Errorf("failed to checksum backup: %w", err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		m.SizeBytes = info.Size()
+	}
+	if _, err := m.Write(entry.ArtifactPath); err != nil {
+		return fmt.Errorf("failed to update manifest: %w", err)
+	}
+
+	if m.Destination != "" {
+		if err := uploader.UploadTo(ctx, manifest.PathFor(entry.ArtifactPath), m.Destination); err != nil {
+			return fmt.Errorf("failed to upload manifest: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/report"
+
+	"github.com/spf13/cobra"
+)
+
+func newReportCommand() *cobra.Command {
+	var configFile string
+	var days int
+	var format string
+	var out string
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate an operations summary of recent backups",
+		Long: `Summarise the backups of the last days: success rate, data growth per database,
+failures with their reasons, and an estimate of the storage cost. The report is
+written as Markdown or HTML, ready to be emailed or posted to a chat channel.`,
+		Example: `  tenangdb report
+  tenangdb report --days 30 --format html --out report.html`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runReport(configFile, days, format, out, tenant); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().IntVar(&days, "days", 7, "number of days to cover")
+	cmd.Flags().StringVar(&format, "format", report.FormatMarkdown, "report format: markdown or html")
+	cmd.Flags().StringVar(&out, "out", "", "file to write the report to (default: stdout)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only report on databases of the named tenant")
+
+	return cmd
+}
+
+func runReport(configFile string, days int, format, out, tenant string) error {
+	if days < 1 {
+		return fmt.Errorf("--days must be at least 1")
+	}
+	if format != report.FormatMarkdown && format != report.FormatHTML {
+		return fmt.Errorf("invalid format %q, must be markdown or html", format)
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+	entries = tenantEntries(cfg, tenant, entries)
+
+	// Failure reasons are only recorded with metrics enabled
+	var data *metrics.MetricsData
+	if cfg.Metrics.StoragePath != "" {
+		if _, statErr := os.Stat(cfg.Metrics.StoragePath); statErr == nil {
+			data, err = metrics.NewMetricsStorage(cfg.Metrics.StoragePath).LoadMetrics()
+			if err != nil {
+				return fmt.Errorf("failed to read metrics: %w", err)
+			}
+		}
+	}
+	if data != nil && tenant != "" {
+		var failures []metrics.FailureRecord
+		for _, failure := range data.Failures {
+			if cfg.HasDatabase(failure.Database) {
+				failures = append(failures, failure)
+			}
+		}
+		data.Failures = failures
+	}
+
+	to := time.Now()
+	summary := report.Build(cfg, entries, data, to.AddDate(0, 0, -days), to)
+
+	if out == "" {
+		return summary.Write(os.Stdout, format)
+	}
+
+	file, err := os.Create(out)
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	if err := summary.Write(file, format); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+	return file.Close()
+}
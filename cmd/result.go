@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/abdullahainun/tenangdb/internal/backup"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+// writeRunResult writes the result of a backup run to reportPath, if set,
+// and prints it as JSON with --output json
+func writeRunResult(result backup.RunResult, reportPath, output string, log *logger.Logger) {
+	if reportPath != "" {
+		if err := result.WriteFile(reportPath); err != nil {
+			log.WithError(err).Warn("Failed to write run result")
+		} else {
+			log.WithField("report", reportPath).Debug("Run result written")
+		}
+	}
+
+	if output == outputJSON {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.WithError(err).Warn("Failed to marshal run result")
+			return
+		}
+		fmt.Println(string(data))
+	}
+}
+
+// writeTenantResults writes the part of result that concerns the databases
+// of each tenant with a report_path, so each team's notification script
+// only sees its own databases. Tenants without databases in the run are
+// left alone.
+func writeTenantResults(cfg *config.Config, result backup.RunResult, log *logger.Logger) {
+	for _, tenant := range cfg.Tenants {
+		if tenant.ReportPath == "" {
+			continue
+		}
+		databases := tenantDatabases(cfg, tenant.Name, tenant.Databases)
+		if len(databases) == 0 {
+			continue
+		}
+		tenantResult := result.ForDatabases(databases)
+		if err := tenantResult.WriteFile(tenant.ReportPath); err != nil {
+			log.WithError(err).WithField("tenant", tenant.Name).Warn("Failed to write tenant run result")
+		} else {
+			log.WithField("tenant", tenant.Name).WithField("report", tenant.ReportPath).Debug("Tenant run result written")
+		}
+	}
+}
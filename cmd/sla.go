@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/sla"
+
+	"github.com/spf13/cobra"
+)
+
+func newSLACommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sla",
+		Short: "Check backup freshness SLAs",
+	}
+	cmd.AddCommand(newSLAStatusCommand())
+	cmd.AddCommand(newSLAReportCommand())
+	return cmd
+}
+
+func newSLAStatusCommand() *cobra.Command {
+	var configFile string
+	var output string
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Show whether each database's newest verified backup is within its SLA",
+		Long: `Compare the newest verified backup of every database with sla.max_backup_age.
+Exits with status 1 when any database breaches its SLA, so it can run from cron
+or a monitoring check.`,
+		Example: `  tenangdb sla status
+  tenangdb sla status --output json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateOutputFormat(output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			breached, err := runSLAStatus(configFile, output, tenant)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if breached {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&output, "output", outputText, "output format: text or json")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only show databases of the named tenant")
+
+	return cmd
+}
+
+// slaStatus is the JSON form of a database in `tenangdb sla status`
+type slaStatus struct {
+	Database      string  `json:"database"`
+	MaxAgeSeconds float64 `json:"max_age_seconds"`
+	LastBackupID  string  `json:"last_backup_id,omitempty"`
+	LastBackupAt  string  `json:"last_backup_at,omitempty"`
+	AgeSeconds    float64 `json:"age_seconds,omitempty"`
+	Breached      bool    `json:"breached"`
+}
+
+func runSLAStatus(configFile, output, tenant string) (bool, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return false, err
+	}
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return false, fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	statuses := sla.Evaluate(cfg, entries, time.Now())
+	breached := false
+	for _, status := range statuses {
+		breached = breached || status.Breached
+	}
+
+	if output == outputJSON {
+		result := make([]slaStatus, 0, len(statuses))
+		for _, status := range statuses {
+			item := slaStatus{
+				Database:      status.Database,
+				MaxAgeSeconds: status.MaxAge.Seconds(),
+				Breached:      status.Breached,
+			}
+			if status.LastBackup != nil {
+				item.LastBackupID = status.LastBackup.ID
+				item.LastBackupAt = status.LastBackup.Manifest.CreatedAt.Format(time.RFC3339)
+				item.AgeSeconds = status.Age.Seconds()
+			}
+			result = append(result, item)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return breached, encoder.Encode(result)
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No SLA configured, set sla.max_backup_age")
+		return false, nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATABASE\tSLA\tLAST VERIFIED BACKUP\tAGE\tSTATUS")
+	for _, status := range statuses {
+		last, age := "none", "-"
+		if status.LastBackup != nil {
+			last = status.LastBackup.ID
+			age = status.Age.Round(time.Minute).String()
+		}
+		state := "✅ ok"
+		if status.Breached {
+			state = "❌ breached"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", status.Database, status.MaxAge, last, age, state)
+	}
+	return breached, w.Flush()
+}
+
+func newSLAReportCommand() *cobra.Command {
+	var configFile string
+	var output string
+	var days int
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Report the RPO and RTO each database actually achieved",
+		Long: `Measure the recovery point and recovery time objectives each database achieved
+over the last days.
+
+RPO is the longest gap between verified backups in the period, or the age of
+the newest one if that is longer. RTO comes from measured restores: restore
+drills and real restores, which are counted under the database they restored
+into. Restore durations are only recorded when metrics are enabled.
+
+Targets are taken from sla.max_backup_age and drill.max_rto.`,
+		Example: `  tenangdb sla report
+  tenangdb sla report --days 90 --output json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateOutputFormat(output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if days <= 0 {
+				fmt.Printf("Error: --days must be positive\n")
+				os.Exit(1)
+			}
+			if err := runSLAReport(configFile, output, tenant, days); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&output, "output", outputText, "output format: text or json")
+	cmd.Flags().IntVar(&days, "days", 30, "number of days to measure over")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only measure databases of the named tenant")
+
+	return cmd
+}
+
+// slaMeasurement is the JSON form of a database in `tenangdb sla report`
+type slaMeasurement struct {
+	Database           string  `json:"database"`
+	Backups            int     `json:"backups"`
+	RPOSeconds         float64 `json:"rpo_seconds,omitempty"`
+	RPOTargetSeconds   float64 `json:"rpo_target_seconds,omitempty"`
+	MaxIntervalSeconds float64 `json:"max_backup_interval_seconds,omitempty"`
+	AvgIntervalSeconds float64 `json:"avg_backup_interval_seconds,omitempty"`
+	BackupAgeSeconds   float64 `json:"backup_age_seconds,omitempty"`
+	Restores           int     `json:"restores"`
+	RTOSeconds         float64 `json:"rto_seconds,omitempty"`
+	MaxRTOSeconds      float64 `json:"max_rto_seconds,omitempty"`
+	RTOTargetSeconds   float64 `json:"rto_target_seconds,omitempty"`
+	RTOSource          string  `json:"rto_source,omitempty"`
+}
+
+func runSLAReport(configFile, output, tenant string, days int) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	var restores []sla.Restore
+	if cfg.Metrics.Enabled {
+		metricsPath := cfg.Metrics.StoragePath
+		if metricsPath == "" {
+			metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
+		}
+		data, err := metrics.NewMetricsStorage(metricsPath).LoadMetrics()
+		if err != nil {
+			return fmt.Errorf("failed to read metrics: %w", err)
+		}
+		restores = data.RestoreSamples()
+	}
+
+	window := time.Duration(days) * 24 * time.Hour
+	measurements := sla.Measure(cfg, entries, restores, time.Now(), window)
+
+	if output == outputJSON {
+		result := make([]slaMeasurement, 0, len(measurements))
+		for _, m := range measurements {
+			item := slaMeasurement{
+				Database:         m.Database,
+				Backups:          m.Backups,
+				RPOTargetSeconds: cfg.SLA.MaxAgeFor(m.Database).Seconds(),
+				Restores:         m.Restores,
+				RTOTargetSeconds: cfg.Drill.MaxRTO.Seconds(),
+			}
+			if m.HasBackup {
+				item.RPOSeconds = m.RPO().Seconds()
+				item.MaxIntervalSeconds = m.MaxInterval.Seconds()
+				item.AvgIntervalSeconds = m.AvgInterval.Seconds()
+				item.BackupAgeSeconds = m.BackupAge.Seconds()
+			}
+			if m.Restores > 0 {
+				item.RTOSeconds = m.LastRTO.Seconds()
+				item.MaxRTOSeconds = m.MaxRTO.Seconds()
+				item.RTOSource = m.LastSource
+			}
+			result = append(result, item)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(result)
+	}
+
+	if len(measurements) == 0 {
+		fmt.Println("No databases configured, set backup.databases")
+		return nil
+	}
+
+	fmt.Printf("Achieved RPO and RTO over the last %d days\n\n", days)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATABASE\tBACKUPS\tAVG INTERVAL\tRPO\tRPO TARGET\tRESTORES\tLAST RTO\tMAX RTO\tRTO TARGET")
+	for _, m := range measurements {
+		avg, rpo := "-", "no backup"
+		if m.HasBackup {
+			rpo = formatSLADuration(m.RPO())
+			if m.AvgInterval > 0 {
+				avg = formatSLADuration(m.AvgInterval)
+			}
+		}
+		lastRTO, maxRTO := "not measured", "-"
+		if m.Restores > 0 {
+			lastRTO = formatSLADuration(m.LastRTO) + " (" + m.LastSource + ")"
+			maxRTO = formatSLADuration(m.MaxRTO)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n", m.Database, m.Backups, avg, rpo,
+			formatSLADuration(cfg.SLA.MaxAgeFor(m.Database)), m.Restores, lastRTO, maxRTO, formatSLADuration(cfg.Drill.MaxRTO))
+	}
+	return w.Flush()
+}
+
+// formatSLADuration rounds a duration for the report, "-" when unset
+func formatSLADuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return "-"
+	case d < time.Minute:
+		return d.Round(time.Second).String()
+	default:
+		return d.Round(time.Minute).String()
+	}
+}
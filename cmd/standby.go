@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/policy"
+	"github.com/abdullahainun/tenangdb/internal/resources"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newRefreshStandbyCommand() *cobra.Command {
+	var configFile string
+	var target string
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "refresh-standby",
+		Short: "Restore the latest verified backups into a standby server",
+		Long: `Restore the latest verified local backup of each database into a standby or
+staging server from the standbys section of the config, then apply its masking
+rules. Existing databases on the standby are overwritten without confirmation.
+Run it from a timer to keep the standby fresh.`,
+		Example: `  tenangdb refresh-standby --target staging`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := runRefreshStandby(configFile, target, tenant); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&target, "target", "", "name of the standby to refresh (required)")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only refresh databases of the named tenant")
+	if err := cmd.MarkFlagRequired("target"); err != nil {
+		fmt.Printf("Error: Failed to mark target flag as required: %v\n", err)
+		os.Exit(1)
+	}
+
+	return cmd
+}
+
+func runRefreshStandby(configFile, target, tenant string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	standby, err := cfg.Standby(target)
+	if err != nil {
+		return err
+	}
+	databases := standby.Databases
+	if len(databases) == 0 {
+		databases = cfg.Backup.Databases
+	}
+	databases = tenantDatabases(cfg, tenant, databases)
+
+	log, err := logger.NewFileLoggerWithSeparateFormats(cfg.Logging.Level, cfg.Logging.FilePath, cfg.Logging.Format, cfg.Logging.FileFormat)
+	if err != nil {
+		log = logger.NewLogger(cfg.Logging.Level)
+		log.WithError(err).Warn("Failed to initialize file logger, using stdout")
+	}
+	applyOutputMode(log, cfg)
+
+	// Stay within the configured CPU and memory envelope
+	if err := resources.Apply(&cfg.Resources, log); err != nil {
+		log.WithError(err).Fatal("Failed to apply resource limits")
+	}
+
+	ctx := context.Background()
+	runID := runid.New()
+	log.SetRunID(runID)
+	ctx = runid.WithContext(ctx, runID)
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	// Everything below talks to the standby, never to production
+	cfg.Database = standby.ConnectionConfig(cfg.Database)
+	dbClient, err := database.NewClient(&cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to standby %s: %w", standby.Name, err)
+	}
+	defer dbClient.Close()
+	dbClient.SetLogger(log)
+
+	var metricsStorage *metrics.MetricsStorage
+	if cfg.Metrics.Enabled {
+		metricsPath := cfg.Metrics.StoragePath
+		if metricsPath == "" {
+			metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
+		}
+		metricsStorage = metrics.NewMetricsStorage(metricsPath)
+	}
+
+	failed := 0
+	for _, dbName := range databases {
+		dbLog := log.WithDatabase(dbName).WithField("standby", standby.Name)
+
+		entry, refreshErr := refreshStandbyDatabase(ctx, cfg, standby, dbClient, entries, dbName, log)
+		if refreshErr != nil {
+			dbLog.WithError(refreshErr).Error("❌ Standby refresh failed")
+			failed++
+		} else {
+			dbLog.WithField("backup", entry.ID).Info("✅ Standby refreshed")
+		}
+
+		if metricsStorage != nil {
+			var createdAt time.Time
+			if entry != nil {
+				createdAt = entry.Manifest.CreatedAt
+			}
+			if err := metricsStorage.UpdateStandbyMetrics(standby.Name, dbName, createdAt, refreshErr == nil); err != nil {
+				dbLog.WithError(err).Warn("Failed to update standby metrics")
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d database(s) could not be refreshed on %s", failed, len(databases), standby.Name)
+	}
+	return nil
+}
+
+// refreshStandbyDatabase restores the latest verified backup of dbName on
+// the standby and masks it. If masking fails the database is dropped, so
+// unmasked data never stays on the standby.
+func refreshStandbyDatabase(ctx context.Context, cfg *config.Config, standby *config.StandbyConfig, dbClient *database.Client, entries []catalog.Entry, dbName string, log *logger.Logger) (*catalog.Entry, error) {
+	entry := latestVerifiedBackup(entries, dbName, log)
+	if entry == nil {
+		return nil, fmt.Errorf("no verified backup of %s found", dbName)
+	}
+
+	if err := policy.CheckRestore(&cfg.Policy, standby.Host, dbName); err != nil {
+		return nil, err
+	}
+	if err := checkRestoreCompat(ctx, dbClient, &cfg.Restore, entry.ArtifactPath, log); err != nil {
+		return nil, err
+	}
+	if err := checkRestoreCharset(ctx, dbClient, &cfg.Restore, entry.ArtifactPath, dbName, log); err != nil {
+		return nil, err
+	}
+	warnToolVersions(cfg, entry.ArtifactPath, log)
+
+	log.WithField("standby", standby.Name).WithField("backup", entry.ID).Info("🔄 Refreshing standby database " + dbName)
+	backupPath, cleanup, err := decryptBackup(ctx, cfg, entry.ArtifactPath, log)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	if err := dbClient.RestoreBackup(ctx, backupPath, dbName, &cfg.Restore); err != nil {
+		return nil, err
+	}
+
+	if len(standby.Masking) > 0 {
+		changed, skipped, err := dbClient.ApplyMasking(ctx, dbName, standby.Masking)
+		if len(skipped) > 0 {
+			log.WithDatabase(dbName).WithField("rules", strings.Join(skipped, ", ")).Warn("⚠️ Skipped masking rules for tables or columns the backup does not have")
+		}
+		if err != nil {
+			if dropErr := dbClient.DropDatabase(ctx, dbName); dropErr != nil {
+				log.WithError(dropErr).Error("❌ Failed to drop unmasked standby database " + dbName)
+			}
+			return nil, err
+		}
+		log.WithDatabase(dbName).WithField("rows", changed).Info("🎭 Applied masking rules")
+	}
+
+	return entry, nil
+}
+
+// latestVerifiedBackup returns the newest backup of dbName that still
+// matches the checksum in its manifest. Directory backups carry no checksum
+// and count as verified.
+func latestVerifiedBackup(entries []catalog.Entry, dbName string, log *logger.Logger) *catalog.Entry {
+	for i := range entries {
+		entry := &entries[i]
+		if entry.Manifest.Database != dbName {
+			continue
+		}
+		if _, err := os.Stat(entry.ArtifactPath); err != nil {
+			continue
+		}
+
+		if entry.Manifest.SHA256 != "" {
+			sum, err := manifest.FileChecksum(entry.ArtifactPath)
+			if err != nil || sum != entry.Manifest.SHA256 {
+				log.WithField("backup", entry.ID).Warn("⚠️ Backup does not match its checksum, trying an older one")
+				continue
+			}
+		}
+		return entry
+	}
+	return nil
+}
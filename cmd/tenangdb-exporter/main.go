@@ -34,12 +34,15 @@ func main() {
 	var port string
 	var metricsFile string
 	var showVersionFlag bool
+	var goMetrics, processMetrics bool
 
 	rootCmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
 	rootCmd.Flags().StringVar(&logLevel, "log-level", "info", "log level (debug, info, warn, error)")
 	rootCmd.Flags().StringVar(&port, "port", "9090", "HTTP server port for metrics")
 	rootCmd.Flags().StringVar(&metricsFile, "metrics-file", "", "path to metrics storage file (auto-discovery if not specified)")
 	rootCmd.Flags().BoolVar(&showVersionFlag, "version", false, "show version information")
+	rootCmd.Flags().BoolVar(&goMetrics, "go-metrics", true, "serve Go runtime metrics (overrides metrics.go_metrics)")
+	rootCmd.Flags().BoolVar(&processMetrics, "process-metrics", true, "serve process metrics (overrides metrics.process_metrics)")
 
 	// Add version command
 	rootCmd.AddCommand(newVersionCommand())
@@ -129,12 +132,25 @@ func runExporter(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Go and process metrics: flags override the config, both default to on
+	opts := metrics.ExporterOptions{GoMetrics: true, ProcessMetrics: true}
+	if cfg != nil {
+		opts.GoMetrics = cfg.Metrics.GoMetrics
+		opts.ProcessMetrics = cfg.Metrics.ProcessMetrics
+	}
+	if cmd.Flags().Changed("go-metrics") {
+		opts.GoMetrics, _ = cmd.Flags().GetBool("go-metrics")
+	}
+	if cmd.Flags().Changed("process-metrics") {
+		opts.ProcessMetrics, _ = cmd.Flags().GetBool("process-metrics")
+	}
+
 	log.WithField("port", port).WithField("metrics_file", metricsFile).Info("Starting tenangdb-exporter")
 
 	// Start metrics exporter
 	done := make(chan error, 1)
 	go func() {
-		done <- metrics.StartMetricsExporter(ctx, port, metricsFile, log)
+		done <- metrics.StartMetricsExporter(ctx, port, metricsFile, cfg, opts, log)
 	}()
 
 	// Wait for shutdown signal
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// tenantEntries keeps the backups of the databases of the tenant given
+// with --tenant, once Config.ForTenant narrowed cfg to them
+func tenantEntries(cfg *config.Config, tenant string, entries []catalog.Entry) []catalog.Entry {
+	if tenant == "" {
+		return entries
+	}
+	var kept []catalog.Entry
+	for _, entry := range entries {
+		if cfg.HasDatabase(entry.Manifest.Database) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// checkTenantBackup refuses a backup of a database outside the tenant
+// given with --tenant
+func checkTenantBackup(cfg *config.Config, tenant string, entry *catalog.Entry) error {
+	if tenant != "" && !cfg.HasDatabase(entry.Manifest.Database) {
+		return fmt.Errorf("backup %s is of database %s, which does not belong to tenant %s", entry.ID, entry.Manifest.Database, tenant)
+	}
+	return nil
+}
+
+// tenantDatabases keeps the databases of the tenant given with --tenant
+func tenantDatabases(cfg *config.Config, tenant string, databases []string) []string {
+	if tenant == "" {
+		return databases
+	}
+	var kept []string
+	for _, db := range databases {
+		if cfg.HasDatabase(db) {
+			kept = append(kept, db)
+		}
+	}
+	return kept
+}
+
+// checkTenantDatabase refuses a database outside the tenant given with
+// --tenant
+func checkTenantDatabase(cfg *config.Config, tenant, dbName string) error {
+	if tenant != "" && !cfg.HasDatabase(dbName) {
+		return fmt.Errorf("database %s does not belong to tenant %s", dbName, tenant)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/toolversion"
+)
+
+// warnToolVersions warns when the tools restoring a backup are a major
+// version away from the ones recorded in its manifest. Restores usually
+// still work, but dump formats and defaults change between majors.
+func warnToolVersions(cfg *config.Config, backupPath string, log *logger.Logger) {
+	m, err := manifest.Read(manifest.PathFor(backupPath))
+	if err != nil || len(m.ToolVersions) == 0 {
+		return
+	}
+
+	restoring := toolversion.Restore(cfg, m.Tool, version)
+	for _, mismatch := range toolversion.Compare(m.ToolVersions, restoring) {
+		log.WithField("backup_tool", mismatch.BackupTool+" "+mismatch.BackupVersion).
+			WithField("restore_tool", mismatch.Tool+" "+mismatch.Version).
+			Warn(fmt.Sprintf("⚠️ Backup was made with %s %s, restoring with %s %s", mismatch.BackupTool, mismatch.BackupVersion, mismatch.Tool, mismatch.Version))
+	}
+}
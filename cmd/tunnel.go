@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/docker"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/tunnel"
+)
+
+// openDatabaseTunnel connects through database.ssh when configured and points
+// the database config at the local end of the tunnel. The returned function
+// closes the tunnel. With database.container, the config is pointed at the
+// container instead.
+func openDatabaseTunnel(ctx context.Context, cfg *config.Config, log *logger.Logger) func() {
+	if cfg.Database.Container != "" {
+		if err := docker.Resolve(ctx, &cfg.Database); err != nil {
+			log.WithError(err).Fatal("Failed to find database container")
+		}
+		log.WithField("container", cfg.Database.Container).WithField("address", fmt.Sprintf("%s:%d", cfg.Database.Host, cfg.Database.Port)).
+			Debug("Using database container")
+		return func() {}
+	}
+
+	if !cfg.Database.SSH.Enabled() {
+		return func() {}
+	}
+
+	t, err := tunnel.Open(ctx, &cfg.Database.SSH, cfg.Database.Host, cfg.Database.Port, log)
+	if err != nil {
+		log.WithError(err).Fatal("Failed to open SSH tunnel")
+	}
+
+	// Manifests and logs keep naming the database host, not the tunnel
+	cfg.Database.TunneledHost = cfg.Database.Host
+	cfg.Database.Host = "127.0.0.1"
+	cfg.Database.Port = t.LocalPort
+	return t.Close
+}
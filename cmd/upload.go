@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/upload"
+
+	"github.com/spf13/cobra"
+)
+
+func newUploadCommand() *cobra.Command {
+	var configFile string
+	var reconcile bool
+	var runID string
+	var force bool
+	var tenant string
+	var dryRun bool
+	var output string
+
+	cmd := &cobra.Command{
+		Use:   "upload [backup-id|path]...",
+		Short: "Upload local backups to the cloud",
+		Long: `Upload local backups to the cloud.
+
+Backups are selected by ID or artifact path, or with --run-id all backups of one
+run, such as a 'tenangdb backup --skip-upload' run. This lets dumps and uploads be
+scheduled separately, e.g. dump at 01:00 and upload in an off-peak bandwidth
+window. Each backup is uploaded with its manifest, falling back to
+upload.fallback_destination like a backup run, and marked as uploaded.
+Backups already marked as uploaded are skipped unless --force is given.
+--dry-run shows what would be uploaded; with --output json it prints the plan
+of the same backups.
+
+With --reconcile, backups that were stored on upload.fallback_destination because
+the primary destination failed are copied from the fallback to the primary
+destination, and their manifests are updated. The fallback copies are left in
+place, and the local backups need not exist anymore.`,
+		Example: `  tenangdb upload --run-id 20250705T010000-3f9a2c
+  tenangdb upload app_db-2025-07-05_01-00-00
+  tenangdb upload /var/backups/app_db/2025-07/app_db-2025-07-05_01-00-00.sql.tar.gz
+  tenangdb upload --run-id 20250705T010000-3f9a2c --dry-run --output json
+  tenangdb upload --reconcile`,
+		Run: func(cmd *cobra.Command, args []string) {
+			err := validateOutputFormat(output)
+			switch {
+			case err != nil:
+			case reconcile && (runID != "" || len(args) > 0):
+				err = fmt.Errorf("--reconcile cannot be combined with --run-id or backups")
+			case reconcile && dryRun:
+				err = fmt.Errorf("--dry-run cannot be combined with --reconcile")
+			case reconcile:
+				err = runReconcile(configFile, tenant)
+			case runID == "" && len(args) == 0:
+				err = fmt.Errorf("nothing to do, give backups, --run-id or --reconcile")
+			default:
+				err = runUpload(configFile, args, runID, tenant, force, dryRun, output)
+			}
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().BoolVar(&reconcile, "reconcile", false, "copy backups held only by the fallback destination to the primary destination")
+	cmd.Flags().StringVar(&runID, "run-id", "", "upload every backup of this run")
+	cmd.Flags().BoolVar(&force, "force", false, "upload backups that are already marked as uploaded")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only upload or reconcile backups of databases of the named tenant")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "show what would be uploaded without uploading")
+	cmd.Flags().StringVar(&output, "output", outputText, "format of the --dry-run plan: text or json")
+
+	return cmd
+}
+
+// selectBackups returns the entries named by ID or artifact path, and those
+// of runID
+func selectBackups(entries []catalog.Entry, refs []string, runID string) ([]catalog.Entry, error) {
+	var selected []catalog.Entry
+	picked := make(map[string]bool)
+	pick := func(entry catalog.Entry) {
+		if !picked[entry.ArtifactPath] {
+			picked[entry.ArtifactPath] = true
+			selected = append(selected, entry)
+		}
+	}
+
+	for _, ref := range refs {
+		path, _ := filepath.Abs(strings.TrimSuffix(ref, manifest.Suffix))
+		found := false
+		for _, entry := range entries {
+			if entry.ID == ref || entry.ArtifactPath == filepath.Clean(path) {
+				pick(entry)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("backup %s not found", ref)
+		}
+	}
+
+	if runID != "" {
+		found := false
+		for _, entry := range entries {
+			if entry.Manifest.RunID == runID {
+				pick(entry)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no backups of run %s found", runID)
+		}
+	}
+	return selected, nil
+}
+
+// pendingUploads returns the selected backups that an upload uploads: all
+// of them with force, otherwise those not marked as uploaded yet. It also
+// returns how many were skipped.
+func pendingUploads(cfg *config.Config, refs []string, runID, tenant string, force bool, log *logger.Logger) ([]catalog.Entry, int, error) {
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read backups: %w", err)
+	}
+	selected, err := selectBackups(entries, refs, runID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var pending []catalog.Entry
+	skipped := 0
+	for i := range selected {
+		if err := checkTenantBackup(cfg, tenant, &selected[i]); err != nil {
+			return nil, 0, err
+		}
+		if marker, _ := manifest.LoadUploaded(selected[i].ArtifactPath); marker != nil && !force {
+			log.WithField("backup", selected[i].ID).WithField("destination", marker.Destination).Info("Backup already uploaded, skipping")
+			skipped++
+			continue
+		}
+		pending = append(pending, selected[i])
+	}
+	return pending, skipped, nil
+}
+
+func runUpload(configFile string, refs []string, runID, tenant string, force, dryRun bool, output string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !cfg.Upload.Enabled {
+		return fmt.Errorf("upload is not enabled")
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	log := logger.NewLogger(logLevel)
+	ctx := context.Background()
+
+	// Keep stdout for the JSON plan
+	if dryRun && output == outputJSON {
+		log.SetOutput(os.Stderr)
+	}
+
+	selected, skipped, err := pendingUploads(cfg, refs, runID, tenant, force, log)
+	if err != nil {
+		return err
+	}
+
+	if dryRun && output == outputJSON {
+		return writeUploadPlan(ctx, cfg, selected, log)
+	}
+	if dryRun {
+		for _, entry := range selected {
+			info, err := os.Stat(entry.ArtifactPath)
+			if err != nil {
+				return fmt.Errorf("backup %s: %w", entry.ID, err)
+			}
+			destination := upload.NewService(cfg.UploadFor(entry.Manifest.Database), log).RemotePath(entry.ArtifactPath, info.IsDir())
+			fmt.Printf("Would upload %s (%s) to %s\n", entry.ID, formatFileSize(entry.Manifest.SizeBytes), destination)
+		}
+		fmt.Printf("%d backup(s) would be uploaded, %d already uploaded\n", len(selected), skipped)
+		return nil
+	}
+
+	var metricsStorage *metrics.MetricsStorage
+	if cfg.Metrics.Enabled {
+		metricsPath := cfg.Metrics.StoragePath
+		if metricsPath == "" {
+			metricsPath = "/var/lib/tenangdb/metrics.json" // fallback
+		}
+		metricsStorage = metrics.NewMetricsStorage(metricsPath)
+	}
+
+	uploaded, failed := 0, 0
+	for _, entry := range selected {
+		entryLog := log.WithField("backup", entry.ID)
+
+		// Backups of a tenant's databases go below its upload prefix
+		uploadCfg := cfg.UploadFor(entry.Manifest.Database)
+		uploader := upload.NewService(uploadCfg, log)
+		start := time.Now()
+		destination, err := uploader.UploadWithFallback(ctx, entry.ArtifactPath)
+		if metricsStorage != nil {
+			if err := metricsStorage.UpdateUploadMetrics(entry.Manifest.Database, time.Since(start), err == nil, entry.Manifest.SizeBytes); err != nil {
+				entryLog.WithError(err).Warn("Failed to update upload metrics")
+			}
+		}
+		if err != nil {
+			entryLog.WithError(err).Error("❌ Failed to upload backup")
+			failed++
+			continue
+		}
+		if destination != uploadCfg.Destination {
+			entryLog.WithField("destination", destination).Warn("⚠️ Backup stored on fallback destination, run 'tenangdb upload --reconcile' once the primary is back")
+		}
+
+		// A split upload listed its parts in the manifest
+		if m, err := manifest.Load(entry.ArtifactPath); err == nil {
+			entry.Manifest = m
+		}
+		entry.Manifest.Destination = destination
+		entry.Manifest.UploadSeconds = time.Since(start).Seconds()
+		if _, err := entry.Manifest.Write(entry.ArtifactPath); err != nil {
+			entryLog.WithError(err).Warn("⚠️ Failed to update manifest")
+		} else if err := uploader.UploadTo(ctx, manifest.PathFor(entry.ArtifactPath), destination); err != nil {
+			entryLog.WithError(err).Warn("⚠️ Failed to upload manifest")
+		}
+		if err := manifest.MarkUploaded(entry.ArtifactPath, destination, time.Now()); err != nil {
+			entryLog.WithError(err).Warn("⚠️ Failed to mark backup as uploaded")
+		}
+
+		entryLog.Info("☁️  Backup uploaded")
+		uploaded++
+	}
+
+	fmt.Printf("Uploaded %d backup(s), %d already uploaded\n", uploaded, skipped)
+	if failed > 0 {
+		return fmt.Errorf("%d backup(s) could not be uploaded", failed)
+	}
+	return nil
+}
+
+func runReconcile(configFile, tenant string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if !cfg.Upload.Enabled {
+		return fmt.Errorf("upload is not enabled")
+	}
+	if cfg.Upload.FallbackDestination == "" {
+		return fmt.Errorf("upload.fallback_destination is not set, there is nothing to reconcile")
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return err
+	}
+
+	entries, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return fmt.Errorf("failed to read backups: %w", err)
+	}
+	entries = tenantEntries(cfg, tenant, entries)
+
+	log := logger.NewLogger(logLevel)
+	ctx := context.Background()
+
+	reconciled, failed := 0, 0
+	for _, entry := range entries {
+		uploadCfg := cfg.UploadFor(entry.Manifest.Database)
+		if entry.Manifest.Destination != uploadCfg.FallbackDestination {
+			continue
+		}
+
+		// Copy what the fallback holds, the local backup may be cleaned up.
+		// A copy made for the fallback's policy is not what the primary
+		// takes, so that is uploaded again from the local backup.
+		uploader := upload.NewService(uploadCfg, log)
+		entryLog := log.WithField("backup", entry.ID).WithField("from", entry.Manifest.Destination)
+		if entry.Manifest.CopyAt(uploadCfg.FallbackDestination) != nil {
+			if _, err := os.Stat(entry.ArtifactPath); err != nil {
+				entryLog.WithError(err).Error("❌ The fallback holds a copy made for its policy and the local backup is gone, cannot reconcile")
+				failed++
+				continue
+			}
+			if err := uploader.UploadTo(ctx, entry.ArtifactPath, uploadCfg.Destination); err != nil {
+				entryLog.WithError(err).Error("❌ Failed to upload backup to primary destination")
+				failed++
+				continue
+			}
+			// The upload recorded a copy for the primary's policy
+			if m, err := manifest.Load(entry.ArtifactPath); err == nil {
+				entry.Manifest = m
+			}
+		} else if err := uploader.CopyRemote(ctx, entry.ArtifactPath, uploadCfg.FallbackDestination, uploadCfg.Destination); err != nil {
+			entryLog.WithError(err).Error("❌ Failed to copy backup to primary destination")
+			failed++
+			continue
+		}
+
+		entry.Manifest.Destination = uploadCfg.Destination
+		if _, err := entry.Manifest.Write(entry.ArtifactPath); err != nil {
+			entryLog.WithError(err).Error("❌ Failed to update manifest")
+			failed++
+			continue
+		}
+		if err := uploader.Upload(ctx, manifest.PathFor(entry.ArtifactPath)); err != nil {
+			entryLog.WithError(err).Warn("⚠️ Failed to upload manifest")
+		}
+		if err := manifest.MarkUploaded(entry.ArtifactPath, uploadCfg.Destination, time.Now()); err != nil {
+			entryLog.WithError(err).Warn("⚠️ Failed to mark backup as uploaded")
+		}
+
+		entryLog.Info("☁️  Backup reconciled to primary destination")
+		reconciled++
+	}
+
+	fmt.Printf("Reconciled %d backup(s) to %s\n", reconciled, cfg.Upload.Destination)
+	if failed > 0 {
+		return fmt.Errorf("%d backup(s) could not be reconciled", failed)
+	}
+	return nil
+}
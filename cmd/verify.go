@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+
+	"github.com/spf13/cobra"
+)
+
+func newVerifyCommand() *cobra.Command {
+	var configFile string
+	var dbName string
+	var againstLive bool
+	var tolerance float64
+	var output string
+	var tenant string
+
+	cmd := &cobra.Command{
+		Use:   "verify [backup-id]",
+		Short: "Check backups against their manifest and the live database",
+		Long: `Verify a backup, or the latest local backup of every database: the artifact must
+exist and match the checksum in its manifest.
+
+With --against-live, the tables recorded in the manifest are also compared with
+the live database. A live table missing from the backup, or one whose
+approximate row count moved by more than --tolerance, fails the check; such
+tables were usually skipped or filtered by mistake. Tables in the backup that
+no longer exist live are only reported. Row counts are information_schema
+estimates, so keep the tolerance loose.
+
+Exits with status 1 when any backup fails verification.`,
+		Example: `  tenangdb verify
+  tenangdb verify --database app_db --against-live
+  tenangdb verify app_db-2025-07-05_02-00-12 --against-live --output json`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := validateOutputFormat(output); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if tolerance < 0 {
+				fmt.Printf("Error: --tolerance cannot be negative\n")
+				os.Exit(1)
+			}
+			id := ""
+			if len(args) > 0 {
+				id = args[0]
+			}
+			ok, err := runVerify(configFile, id, dbName, tenant, againstLive, tolerance, output)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			if !ok {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().StringVar(&configFile, "config", "", "config file path (auto-discovery if not specified)")
+	cmd.Flags().StringVar(&dbName, "database", "", "verify the latest backup of this database only")
+	cmd.Flags().BoolVar(&againstLive, "against-live", false, "compare the tables in the backup with the live database")
+	cmd.Flags().Float64Var(&tolerance, "tolerance", 0.5, "row count drift allowed with --against-live, as a fraction of the larger count")
+	cmd.Flags().StringVar(&output, "output", outputText, "output format: text or json")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "only verify backups of databases of the named tenant")
+
+	return cmd
+}
+
+// verifyResult is the outcome of verifying one backup
+type verifyResult struct {
+	Backup   string                    `json:"backup"`
+	Database string                    `json:"database"`
+	OK       bool                      `json:"ok"`
+	Problems []string                  `json:"problems,omitempty"`
+	Notes    []string                  `json:"notes,omitempty"`
+	Tables   *database.TableComparison `json:"tables,omitempty"`
+}
+
+func runVerify(configFile, id, dbName, tenant string, againstLive bool, tolerance float64, output string) (bool, error) {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg, err = cfg.ForTenant(tenant); err != nil {
+		return false, err
+	}
+
+	entries, err := verifyTargets(cfg, id, dbName)
+	if err != nil {
+		return false, err
+	}
+	for i := range entries {
+		if err := checkTenantBackup(cfg, tenant, &entries[i]); err != nil {
+			return false, err
+		}
+	}
+
+	var dbClient *database.Client
+	if againstLive {
+		if err := requireMySQL(cfg, "verify --against-live"); err != nil {
+			return false, err
+		}
+		log := logger.NewLogger(cfg.Logging.Level)
+		applyOutputMode(log, cfg)
+		if dbClient, err = database.NewClient(&cfg.Database); err != nil {
+			return false, fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer dbClient.Close()
+		dbClient.SetLogger(log)
+	}
+
+	ctx := context.Background()
+	allOK := true
+	results := make([]verifyResult, 0, len(entries))
+	for i := range entries {
+		result := verifyBackup(ctx, &entries[i], dbClient, tolerance)
+		allOK = allOK && result.OK
+		results = append(results, result)
+	}
+
+	if output == outputJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return allOK, encoder.Encode(results)
+	}
+
+	for _, result := range results {
+		state := "✅"
+		if !result.OK {
+			state = "❌"
+		}
+		fmt.Printf("%s %s\n", state, result.Backup)
+		for _, problem := range result.Problems {
+			fmt.Printf("   %s\n", problem)
+		}
+		for _, note := range result.Notes {
+			fmt.Printf("   note: %s\n", note)
+		}
+	}
+	return allOK, nil
+}
+
+// verifyTargets returns the backup named by id, or the latest backup of
+// dbName or of every configured database
+func verifyTargets(cfg *config.Config, id, dbName string) ([]catalog.Entry, error) {
+	if id != "" {
+		entry, err := catalog.Find(cfg.Backup.Directory, id)
+		if err != nil {
+			return nil, err
+		}
+		return []catalog.Entry{*entry}, nil
+	}
+
+	all, err := catalog.Scan(cfg.Backup.Directory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backups: %w", err)
+	}
+
+	databases := cfg.Backup.Databases
+	if dbName != "" {
+		databases = []string{dbName}
+	}
+
+	// Scan sorts newest first
+	var entries []catalog.Entry
+	for _, db := range databases {
+		for _, entry := range all {
+			if entry.Manifest.Database == db {
+				entries = append(entries, entry)
+				break
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no backups found to verify")
+	}
+	return entries, nil
+}
+
+// verifyBackup checks the artifact of entry against its manifest and, with
+// a client, its tables against the live database
+func verifyBackup(ctx context.Context, entry *catalog.Entry, dbClient *database.Client, tolerance float64) verifyResult {
+	result := verifyResult{Backup: entry.ID, Database: entry.Manifest.Database}
+
+	info, err := os.Stat(entry.ArtifactPath)
+	switch {
+	case err != nil && entry.Manifest.Destination != "":
+		result.Notes = append(result.Notes, "artifact is only in the cloud, checksum not checked")
+	case err != nil:
+		result.Problems = append(result.Problems, "artifact is missing locally")
+	case entry.Manifest.SHA256 != "" && !info.IsDir():
+		sum, err := manifest.FileChecksum(entry.ArtifactPath)
+		if err != nil {
+			result.Problems = append(result.Problems, fmt.Sprintf("failed to checksum artifact: %v", err))
+		} else if sum != entry.Manifest.SHA256 {
+			result.Problems = append(result.Problems, "artifact does not match its checksum")
+		}
+	}
+
+	if dbClient != nil {
+		if entry.Manifest.Tables == nil {
+			result.Notes = append(result.Notes, "manifest records no tables, the backup predates table recording")
+		} else {
+			live, err := dbClient.TableRowCounts(ctx, entry.Manifest.Database)
+			if err != nil {
+				result.Problems = append(result.Problems, err.Error())
+			} else {
+				comparison := database.CompareTables(entry.Manifest.Tables, live, tolerance)
+				result.Tables = &comparison
+				if len(comparison.Missing) > 0 {
+					result.Problems = append(result.Problems, "live tables missing from the backup: "+strings.Join(comparison.Missing, ", "))
+				}
+				for _, drift := range comparison.Drifted {
+					result.Problems = append(result.Problems, fmt.Sprintf("%s: about %d rows in the backup, %d live", drift.Table, drift.BackupRows, drift.LiveRows))
+				}
+				if len(comparison.Extra) > 0 {
+					result.Notes = append(result.Notes, "backed up tables no longer live: "+strings.Join(comparison.Extra, ", "))
+				}
+			}
+		}
+	}
+
+	result.OK = len(result.Problems) == 0
+	return result
+}
@@ -0,0 +1,301 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// BinlogDirName is the directory inside the backup directory that holds the
+// binary logs archived by tenangdb backup --incremental, one directory per
+// server
+const BinlogDirName = ".binlogs"
+
+// BinlogArchiveDir returns where the binary logs of server are archived
+func BinlogArchiveDir(backupDir, server string) string {
+	return filepath.Join(backupDir, BinlogDirName, server)
+}
+
+// BinlogArchive is what an incremental run archived
+type BinlogArchive struct {
+	Server   string   `json:"server"`
+	Dir      string   `json:"dir"`
+	Files    []string `json:"files"`              // binary logs archived by this run, oldest first
+	File     string   `json:"file,omitempty"`     // newest archived binary log
+	Position uint64   `json:"position,omitempty"` // its end, how far point-in-time recovery reaches
+	Gap      string   `json:"gap,omitempty"`      // why the archive misses logs before Files, if it does
+}
+
+// serverName returns the name of the server's binary log archive, or ""
+// when it can't be read
+func (s *Service) serverName(ctx context.Context) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.binlogServer == "" {
+		server, err := s.dbClient.BinlogServer(ctx)
+		if err != nil {
+			s.logger.WithError(err).Warn("⚠️ Failed to identify the server, manifests will not name its binary log archive")
+			return ""
+		}
+		s.binlogServer = server
+	}
+	return s.binlogServer
+}
+
+// ArchiveBinlogs copies the closed binary logs of the server that are not
+// archived yet to the backup directory and uploads the archive. The first
+// run starts at the oldest binary log position recorded by a full mydumper
+// backup of the server.
+func (s *Service) ArchiveBinlogs(ctx context.Context) (*BinlogArchive, error) {
+	if s.dbClient == nil {
+		return nil, fmt.Errorf("binary logs can only be archived from MySQL, not with database.type postgresql")
+	}
+	// Encrypted full backups would sit next to plain binary logs holding
+	// the same data
+	if s.config.Backup.Encryption.Enabled {
+		return nil, fmt.Errorf("binary logs can't be archived with backup.encryption enabled, they would be stored unencrypted")
+	}
+
+	server, err := s.dbClient.BinlogServer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	archive := &BinlogArchive{Server: server, Dir: BinlogArchiveDir(s.config.Backup.Directory, server)}
+	log := s.logger.WithField("server", server)
+
+	err = s.archiveBinlogs(ctx, archive)
+	if s.metricsStorage != nil {
+		if metricsErr := s.metricsStorage.UpdateBinlogMetrics(server, archive.File, archive.Position, len(archive.Files), err == nil); metricsErr != nil {
+			log.WithError(metricsErr).Warn("Failed to update binlog metrics")
+		}
+	}
+	return archive, err
+}
+
+func (s *Service) archiveBinlogs(ctx context.Context, archive *BinlogArchive) error {
+	log := s.logger.WithField("server", archive.Server)
+
+	if err := os.MkdirAll(archive.Dir, 0755); err != nil {
+		return fmt.Errorf("failed to create binary log archive: %w", err)
+	}
+
+	// Close the active log, so the archive reaches up to this run
+	if s.config.Backup.Binlog.Flush {
+		if err := s.dbClient.FlushBinaryLogs(ctx); err != nil {
+			log.WithError(err).Warn("⚠️ Failed to flush binary logs, the active one is archived by a later run")
+		}
+	}
+
+	logs, err := s.dbClient.BinaryLogs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(logs) == 0 {
+		return fmt.Errorf("the server has no binary logs, binary logging is off")
+	}
+
+	archived, err := archivedBinlogs(archive.Dir)
+	if err != nil {
+		return err
+	}
+	var starts []string
+	if len(archived) == 0 {
+		if starts, err = binlogStarts(s.config.Backup.Directory, archive.Server); err != nil {
+			return err
+		}
+	}
+
+	fetch, gap, err := binlogsToArchive(logs, archived, starts)
+	if err != nil {
+		return err
+	}
+	if gap != "" {
+		archive.Gap = gap
+		log.Warn("⚠️ " + gap)
+	}
+
+	if len(fetch) > 0 {
+		log.WithField("files", len(fetch)).Info("📜 Archiving binary logs " + fetch[0].Name + " to " + fetch[len(fetch)-1].Name)
+		if err := s.dbClient.FetchBinaryLogs(ctx, s.config.Backup.Binlog.MysqlbinlogPath, archive.Dir, fetch); err != nil {
+			return err
+		}
+		for _, binlog := range fetch {
+			archive.Files = append(archive.Files, binlog.Name)
+		}
+		last := fetch[len(fetch)-1]
+		archive.File, archive.Position = last.Name, uint64(last.Size)
+	} else {
+		log.Info("📜 No new binary logs to archive")
+	}
+
+	if s.uploader != nil {
+		if err := s.uploader.UploadBinlogs(ctx, archive.Dir, archive.Server); err != nil {
+			return fmt.Errorf("binary logs were archived locally but not uploaded: %w", err)
+		}
+	}
+	return nil
+}
+
+// archivedBinlogs lists the binary logs in an archive directory
+func archivedBinlogs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read binary log archive: %w", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// binlogStarts returns the binary log files full backups of server started
+// at, from their manifests. Backups taken before manifests named the
+// server count for every server.
+func binlogStarts(backupDir, server string) ([]string, error) {
+	entries, err := catalog.Scan(backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan backups: %w", err)
+	}
+	var starts []string
+	for _, entry := range entries {
+		binlog := entry.Manifest.Binlog
+		if binlog != nil && (binlog.Server == "" || binlog.Server == server) {
+			starts = append(starts, binlog.File)
+		}
+	}
+	return starts, nil
+}
+
+// binlogsToArchive picks the closed binary logs of the server, logs with
+// the active one last, that follow the newest archived log or, for an empty
+// archive, the oldest full backup position in starts. gap explains logs the
+// server purged before they could be archived.
+func binlogsToArchive(logs []database.BinaryLog, archived, starts []string) ([]database.BinaryLog, string, error) {
+	base, oldest, ok := database.BinlogSequence(logs[0].Name)
+	if !ok {
+		return nil, "", fmt.Errorf("unexpected binary log name %s", logs[0].Name)
+	}
+
+	var next uint64
+	var found bool
+	if len(archived) > 0 {
+		for _, name := range archived {
+			if b, seq, ok := database.BinlogSequence(name); ok && b == base && (!found || seq+1 > next) {
+				next, found = seq+1, true
+			}
+		}
+	} else {
+		for _, name := range starts {
+			if b, seq, ok := database.BinlogSequence(name); ok && b == base && (!found || seq < next) {
+				next, found = seq, true
+			}
+		}
+	}
+	if !found {
+		if len(archived) > 0 {
+			return nil, "", fmt.Errorf("no archived binary log is named like the server's %s.*, was log_bin renamed?", base)
+		}
+		return nil, "", fmt.Errorf("no full backup records a binary log position of this server, run a full mydumper backup first")
+	}
+
+	var gap string
+	if oldest > next {
+		gap = fmt.Sprintf("the server no longer holds the binary logs before %s, point-in-time recovery can't cross the gap", logs[0].Name)
+	}
+
+	var fetch []database.BinaryLog
+	for _, binlog := range logs[:len(logs)-1] {
+		if _, seq, ok := database.BinlogSequence(binlog.Name); ok && seq >= next {
+			fetch = append(fetch, binlog)
+		}
+	}
+	return fetch, gap, nil
+}
+
+// planBinlogs adds the archived binary logs no kept backup needs to the
+// plan. Point-in-time recovery replays from the position of a full backup,
+// so logs older than every backup the plan keeps are of no use. Archives of
+// servers without a kept backup position are left alone.
+func (c *CleanupService) planBinlogs(backupDir string, plan *CleanupPlan) error {
+	servers, err := os.ReadDir(filepath.Join(backupDir, BinlogDirName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	entries, err := catalog.Scan(backupDir)
+	if err != nil {
+		return err
+	}
+	startsByServer := make(map[string][]string)
+	for _, entry := range entries {
+		if binlog := entry.Manifest.Binlog; binlog != nil && !plan.Removes(entry.ArtifactPath) {
+			startsByServer[binlog.Server] = append(startsByServer[binlog.Server], binlog.File)
+		}
+	}
+
+	for _, server := range servers {
+		if !server.IsDir() {
+			continue
+		}
+		dir := filepath.Join(backupDir, BinlogDirName, server.Name())
+		archived, err := archivedBinlogs(dir)
+		if err != nil {
+			return err
+		}
+		starts := append(startsByServer[server.Name()], startsByServer[""]...)
+		for _, name := range staleBinlogs(archived, starts) {
+			path := filepath.Join(dir, name)
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			plan.AgeBased = append(plan.AgeBased, CleanupCandidate{
+				Path: path, Size: info.Size(), ModTime: info.ModTime(),
+				Reason: "binary log older than every kept backup",
+			})
+		}
+	}
+	return nil
+}
+
+// staleBinlogs returns the archived binary logs older than the oldest of
+// starts with the same base name, oldest first
+func staleBinlogs(archived, starts []string) []string {
+	oldest := make(map[string]uint64)
+	for _, name := range starts {
+		if base, seq, ok := database.BinlogSequence(name); ok {
+			if current, seen := oldest[base]; !seen || seq < current {
+				oldest[base] = seq
+			}
+		}
+	}
+
+	var stale []string
+	for _, name := range archived {
+		base, seq, ok := database.BinlogSequence(name)
+		if !ok {
+			continue
+		}
+		if start, seen := oldest[base]; seen && seq < start {
+			stale = append(stale, name)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool {
+		_, a, _ := database.BinlogSequence(stale[i])
+		_, b, _ := database.BinlogSequence(stale[j])
+		return a < b
+	})
+	return stale
+}
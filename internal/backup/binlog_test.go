@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+func TestBinlogsToArchive(t *testing.T) {
+	logs := []database.BinaryLog{
+		{Name: "binlog.000008", Size: 100},
+		{Name: "binlog.000009", Size: 200},
+		{Name: "binlog.000010", Size: 300},
+		{Name: "binlog.000011", Size: 50}, // active
+	}
+
+	tests := []struct {
+		name     string
+		archived []string
+		starts   []string
+		want     []string
+		gap      bool
+		err      bool
+	}{
+		{"continues the archive", []string{"binlog.000007", "binlog.000008"}, nil, []string{"binlog.000009", "binlog.000010"}, false, false},
+		{"first run starts at the oldest backup", nil, []string{"binlog.000010", "binlog.000009"}, []string{"binlog.000009", "binlog.000010"}, false, false},
+		{"nothing new", []string{"binlog.000010"}, nil, nil, false, false},
+		{"purged logs leave a gap", []string{"binlog.000005"}, nil, []string{"binlog.000008", "binlog.000009", "binlog.000010"}, true, false},
+		{"no full backup", nil, nil, nil, false, true},
+		{"renamed log_bin", []string{"mysql-bin.000003"}, nil, nil, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fetch, gap, err := binlogsToArchive(logs, tt.archived, tt.starts)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %v", err, tt.err)
+			}
+			var names []string
+			for _, binlog := range fetch {
+				names = append(names, binlog.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("fetch = %v, want %v", names, tt.want)
+			}
+			if (gap != "") != tt.gap {
+				t.Errorf("gap = %q, want gap %v", gap, tt.gap)
+			}
+		})
+	}
+}
+
+func TestPlanCleanupRotatesBinlogs(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.AddDate(0, 0, -30)
+
+	// The old backup expires, the new one needs binlog.000005 on
+	backups := []struct {
+		path    string
+		created time.Time
+		binlog  string
+	}{
+		{"app/2024-05/app-2024-05-01_02-00-00.sql", old, "binlog.000002"},
+		{"app/2024-06/app-2024-06-01_02-00-00.sql", now, "binlog.000005"},
+	}
+	for _, b := range backups {
+		path := filepath.Join(dir, b.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		m := &manifest.Manifest{Database: "app", Artifact: filepath.Base(path), CreatedAt: b.created,
+			Binlog: &manifest.BinlogPosition{Server: "db1-1", File: b.binlog, Position: 4}}
+		manifestPath, err := m.Write(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, p := range []string{path, manifestPath} {
+			if err := os.Chtimes(p, b.created, b.created); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	archives := map[string][]string{
+		"db1-1": {"binlog.000001", "binlog.000004", "binlog.000005", "binlog.000006"},
+		"db2-2": {"binlog.000001"}, // no backup records this server
+	}
+	for server, names := range archives {
+		archive := BinlogArchiveDir(dir, server)
+		if err := os.MkdirAll(archive, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range names {
+			path := filepath.Join(archive, name)
+			if err := os.WriteFile(path, []byte("binlog"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chtimes(path, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	log := logger.NewLogger("error")
+	cleanupService := NewCleanupService(&config.CleanupConfig{MaxAgeDays: 7}, &config.UploadConfig{}, log)
+	cleanupService.SetAllowUnverified(true)
+	plan, err := cleanupService.PlanCleanup(dir, nil, nil, NewTrash(dir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var removed []string
+	for _, candidate := range plan.AgeBased {
+		rel, _ := filepath.Rel(dir, candidate.Path)
+		removed = append(removed, rel)
+	}
+	want := []string{
+		"app/2024-05/app-2024-05-01_02-00-00.sql",
+		"app/2024-05/app-2024-05-01_02-00-00.sql" + manifest.Suffix,
+		filepath.Join(BinlogDirName, "db1-1", "binlog.000001"),
+		filepath.Join(BinlogDirName, "db1-1", "binlog.000004"),
+	}
+	slices.Sort(removed)
+	slices.Sort(want)
+	if !slices.Equal(removed, want) {
+		t.Errorf("AgeBased = %v, want %v", removed, want)
+	}
+}
@@ -0,0 +1,273 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// DefaultMaxAgeDays is the age-based cleanup threshold when
+// cleanup.max_age_days is not set
+const DefaultMaxAgeDays = 7
+
+// uploadedSafetyBuffer is how long an uploaded file stays before cleanup
+// removes it
+const uploadedSafetyBuffer = time.Hour
+
+// CleanupCandidate is a backup file that cleanup removes or, for old files
+// it keeps, the reason it is kept
+type CleanupCandidate struct {
+	Path     string
+	Database string
+	Size     int64
+	ModTime  time.Time
+	Reason   string
+}
+
+// CleanupPlan is everything one cleanup run removes. Dry runs print it and
+// real runs execute it, so what a dry run shows is what gets deleted.
+type CleanupPlan struct {
+	MaxAgeDays   int
+	ExpiredTrash []string           // trash batches past their retention
+	Uploaded     []CleanupCandidate // uploaded to cloud storage past the safety buffer
+	AgeBased     []CleanupCandidate // older than MaxAgeDays
+	Kept         []CleanupCandidate // older than MaxAgeDays but kept
+}
+
+// Empty reports whether the plan removes nothing
+func (p *CleanupPlan) Empty() bool {
+	return len(p.ExpiredTrash) == 0 && len(p.Uploaded) == 0 && len(p.AgeBased) == 0
+}
+
+// Removals returns the files the plan removes, uploaded files first
+func (p *CleanupPlan) Removals() []CleanupCandidate {
+	return append(append([]CleanupCandidate{}, p.Uploaded...), p.AgeBased...)
+}
+
+// Bytes is the space freed by removing the plan's files
+func (p *CleanupPlan) Bytes() int64 {
+	var total int64
+	for _, candidate := range p.Removals() {
+		total += candidate.Size
+	}
+	return total
+}
+
+// Removes reports whether the plan removes path, something inside it or
+// the directory it is in
+func (p *CleanupPlan) Removes(path string) bool {
+	path = filepath.Clean(path)
+	for _, candidate := range p.Removals() {
+		if candidate.Path == path || isWithin(candidate.Path, path) || isWithin(path, candidate.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// KeepReason returns why the plan keeps an old file at, inside or
+// containing path, or "" when it keeps none
+func (p *CleanupPlan) KeepReason(path string) string {
+	path = filepath.Clean(path)
+	for _, candidate := range p.Kept {
+		if candidate.Path == path || isWithin(candidate.Path, path) || isWithin(path, candidate.Path) {
+			return candidate.Reason
+		}
+	}
+	return ""
+}
+
+// removesUploaded reports whether path goes with an uploaded file the plan
+// removes, such as a file inside a mydumper directory
+func (p *CleanupPlan) removesUploaded(path string) bool {
+	for _, candidate := range p.Uploaded {
+		if path == candidate.Path || isWithin(path, candidate.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// PlanCleanup decides what a cleanup run removes from backupDir: expired
+// trash, unpinned files uploaded more than an hour ago and, when policy
+// requires verification, found in cloud storage, and files of the
+// selected databases older than their tenant's max_age_days or
+// cleanup.max_age_days, except for retained backups and, unless unverified
+// deletes are allowed, files never uploaded or, with verify_cloud_exists,
+// missing from cloud storage
+func (c *CleanupService) PlanCleanup(backupDir string, selectedDatabases []string, uploaded map[string]time.Time, trash *Trash, now time.Time) (*CleanupPlan, error) {
+	plan := &CleanupPlan{MaxAgeDays: c.config.MaxAgeDays}
+	if plan.MaxAgeDays <= 0 {
+		plan.MaxAgeDays = DefaultMaxAgeDays
+	}
+
+	expired, err := trash.Expired()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+	plan.ExpiredTrash = expired
+
+	retained, err := RetainedBackups(backupDir, c.config.MinKeep)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan backups for retention: %w", err)
+	}
+
+	for path, uploadTime := range uploaded {
+		if now.Sub(uploadTime) < uploadedSafetyBuffer {
+			continue
+		}
+		// Pinned backups stay local until unpinned, uploaded or not
+		if retained.Pins(path) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue // already gone
+		}
+		// Old files kept here are listed with their reason by the age walk
+		if c.requireVerified && !c.VerifyFileExistsInCloud(path, backupDir) {
+			c.logger.WithField("file", path).Debug("Uploaded file not found in cloud storage, keeping it as policy requires")
+			continue
+		}
+		size := info.Size()
+		if info.IsDir() {
+			size, _ = dirSize(path)
+		}
+		dbName, _, _ := ParseArtifactName(filepath.Base(path))
+		plan.Uploaded = append(plan.Uploaded, CleanupCandidate{
+			Path: filepath.Clean(path), Database: dbName, Size: size, ModTime: info.ModTime(),
+			Reason: "uploaded to cloud storage",
+		})
+	}
+	sort.Slice(plan.Uploaded, func(i, j int) bool { return plan.Uploaded[i].Path < plan.Uploaded[j].Path })
+
+	err = filepath.Walk(backupDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		// Skip directories and files already in the trash
+		if info.IsDir() {
+			// Archived binary logs go by the backups that need them
+			if info.Name() == TrashDirName || (info.Name() == BinlogDirName && filepath.Dir(path) == filepath.Clean(backupDir)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Hidden files, such as the dedup encryption key, are state, not backups
+		if strings.HasPrefix(info.Name(), ".") {
+			return nil
+		}
+		// Tenants may keep their backups for longer or shorter
+		owner, _ := DatabaseOf(backupDir, path)
+		maxAgeDays := c.maxAgeDays(owner, plan.MaxAgeDays)
+		if !info.ModTime().Before(now.AddDate(0, 0, -maxAgeDays)) {
+			return nil
+		}
+		if plan.removesUploaded(path) || !MatchesDatabases(backupDir, path, selectedDatabases) {
+			return nil
+		}
+
+		dbName, _, _ := ParseArtifactName(info.Name())
+		candidate := CleanupCandidate{Path: path, Database: dbName, Size: info.Size(), ModTime: info.ModTime(), Reason: fmt.Sprintf("older than %d days", maxAgeDays)}
+
+		// Never delete pinned backups or the newest min_keep of a database
+		if retained.Protects(path) {
+			candidate.Reason = "pinned"
+			if c.config.MinKeep > 0 {
+				candidate.Reason = fmt.Sprintf("pinned or among the newest %d backups of its database", c.config.MinKeep)
+			}
+			plan.Kept = append(plan.Kept, candidate)
+			return nil
+		}
+
+		// Refuse to delete what may be the only copy of a backup. Without
+		// verify_cloud_exists, the upload marker or ledger has to show a
+		// copy was uploaded.
+		if !c.allowUnverified {
+			artifact := ArtifactOf(backupDir, path)
+			marker, _ := manifest.LoadUploaded(artifact)
+			_, inLedger := uploaded[artifact]
+			verified := marker != nil || inLedger
+			if c.config.VerifyCloudExists {
+				verified = c.VerifyFileExistsInCloud(path, backupDir)
+			}
+			if !verified {
+				candidate.Reason = "only copy, never uploaded (use --allow-unverified to delete)"
+				if marker != nil {
+					candidate.Reason = "uploaded to " + marker.Destination + " but no longer found there (use --allow-unverified to delete)"
+				}
+				plan.Kept = append(plan.Kept, candidate)
+				return nil
+			}
+		}
+
+		plan.AgeBased = append(plan.AgeBased, candidate)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan backup directory: %w", err)
+	}
+
+	if err := c.planBinlogs(backupDir, plan); err != nil {
+		return nil, fmt.Errorf("failed to scan binary log archive: %w", err)
+	}
+
+	return plan, nil
+}
+
+// ExecuteCleanup removes exactly what plan lists and records it in report.
+// Uploaded files that cannot be removed are skipped; a failure to remove an
+// old file stops the run.
+func (c *CleanupService) ExecuteCleanup(plan *CleanupPlan, trash *Trash, report *CleanupReport) error {
+	// Purge what earlier runs moved to the trash once its retention is over
+	purged, err := trash.PurgeBatches(plan.ExpiredTrash)
+	report.TrashBatchesPurged = purged
+	if err != nil {
+		c.logger.WithError(err).Warn("Failed to purge expired trash")
+	}
+
+	for _, candidate := range plan.Uploaded {
+		if err := trash.Remove(candidate.Path); err != nil {
+			c.logger.WithError(err).WithField("file", candidate.Path).Error("Failed to remove uploaded file")
+			continue
+		}
+		report.UploadedFiles.Add(candidate.Path, candidate.Size)
+		c.logger.WithField("file", candidate.Path).Info("Removed uploaded backup file")
+	}
+
+	for _, candidate := range plan.Kept {
+		c.logger.WithField("file", candidate.Path).WithField("reason", candidate.Reason).Info("🔒 Keeping old backup file")
+	}
+	for _, candidate := range plan.AgeBased {
+		c.logger.WithField("file", candidate.Path).
+			WithField("age_days", int(time.Since(candidate.ModTime).Hours()/24)).
+			Info("🗑️ Deleting old backup file")
+		if err := trash.Remove(candidate.Path); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", candidate.Path, err)
+		}
+		report.AgeBased.Add(candidate.Path, candidate.Size)
+	}
+	return nil
+}
+
+// dirSize totals the size of the files below path
+func dirSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
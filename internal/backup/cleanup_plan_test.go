@@ -0,0 +1,246 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+func TestPlanCleanupMatchesExecution(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.AddDate(0, 0, -30)
+
+	files := map[string]time.Time{
+		"app/2024-05/app-2024-05-01_02-00-00.sql": old,
+		"app/2024-05/app-2024-05-02_02-00-00.sql": old,
+		"app/2024-06/app-2024-06-01_02-00-00.sql": now,
+		"crm/2024-05/crm-2024-05-01_02-00-00.sql": old,
+		"crm/2024-05/crm-2024-05-02_02-00-00.sql": old,
+		"app/.dedup.key": old,
+	}
+	for f, modTime := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only backups with a copy in the cloud are removed by age
+	if err := manifest.MarkUploaded(filepath.Join(dir, "app/2024-05/app-2024-05-01_02-00-00.sql"), "remote:backups", old); err != nil {
+		t.Fatal(err)
+	}
+
+	log := logger.NewLogger("error")
+	cleanupService := NewCleanupService(&config.CleanupConfig{MaxAgeDays: 7, MinKeep: 2}, &config.UploadConfig{}, log)
+	trash := NewTrash(dir, 0, log)
+
+	uploaded := map[string]time.Time{
+		filepath.Join(dir, "crm/2024-05/crm-2024-05-02_02-00-00.sql"): now.Add(-2 * time.Hour),
+	}
+	plan, err := cleanupService.PlanCleanup(dir, []string{"app", "crm"}, uploaded, trash, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(plan.Uploaded) != 1 || plan.Uploaded[0].Path != filepath.Join(dir, "crm/2024-05/crm-2024-05-02_02-00-00.sql") {
+		t.Errorf("Uploaded = %+v", plan.Uploaded)
+	}
+	// app keeps its newest two backups, crm has only two
+	if len(plan.AgeBased) != 1 || plan.AgeBased[0].Path != filepath.Join(dir, "app/2024-05/app-2024-05-01_02-00-00.sql") {
+		t.Errorf("AgeBased = %+v", plan.AgeBased)
+	}
+	if len(plan.Kept) != 2 {
+		t.Errorf("Kept = %+v", plan.Kept)
+	}
+
+	report := &CleanupReport{}
+	if err := cleanupService.ExecuteCleanup(plan, trash, report); err != nil {
+		t.Fatal(err)
+	}
+
+	for f := range files {
+		path := filepath.Join(dir, f)
+		_, err := os.Stat(path)
+		if removed := os.IsNotExist(err); removed != plan.Removes(path) {
+			t.Errorf("%s removed = %v, planned = %v", f, removed, plan.Removes(path))
+		}
+	}
+	if report.UploadedFiles.FilesRemoved != 1 || report.AgeBased.FilesRemoved != 1 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestPlanCleanupKeepsPinnedUploads(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	path := filepath.Join(dir, "app/2024-06/app-2024-06-01_02-00-00.sql")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &manifest.Manifest{Database: "app", Artifact: filepath.Base(path), CreatedAt: now, Pinned: true}
+	if _, err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	log := logger.NewLogger("error")
+	cleanupService := NewCleanupService(&config.CleanupConfig{MaxAgeDays: 7}, &config.UploadConfig{}, log)
+	uploaded := map[string]time.Time{path: now.Add(-2 * time.Hour)}
+	plan, err := cleanupService.PlanCleanup(dir, nil, uploaded, NewTrash(dir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Removes(path) {
+		t.Errorf("plan removes the pinned upload: %+v", plan.Removals())
+	}
+}
+
+func TestPlanCleanupTenantRetention(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	tenDaysAgo := now.AddDate(0, 0, -10)
+
+	paths := []string{
+		filepath.Join(dir, "app/2024-05/app-2024-05-01_02-00-00.sql"),
+		filepath.Join(dir, "payments/2024-05/payments-2024-05-01_02-00-00.sql"),
+	}
+	for _, path := range paths {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, tenDaysAgo, tenDaysAgo); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// payments keeps its backups for 14 days, the rest for 7
+	log := logger.NewLogger("error")
+	cleanupService := NewCleanupService(&config.CleanupConfig{MaxAgeDays: 7}, &config.UploadConfig{}, log)
+	cleanupService.SetTenants([]config.TenantConfig{{Name: "payments", Databases: []string{"payments"}, MaxAgeDays: 14}})
+	cleanupService.SetAllowUnverified(true)
+	plan, err := cleanupService.PlanCleanup(dir, nil, nil, NewTrash(dir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Removes(paths[0]) {
+		t.Errorf("plan keeps %s, older than cleanup.max_age_days", paths[0])
+	}
+	if plan.Removes(paths[1]) {
+		t.Errorf("plan removes %s, within its tenant's max_age_days", paths[1])
+	}
+}
+
+func TestPlanCleanupKeepsNeverUploaded(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.AddDate(0, 0, -30)
+
+	local := filepath.Join(dir, "app/2024-05/app-2024-05-01_02-00-00.sql")
+	uploaded := filepath.Join(dir, "app/2024-05/app-2024-05-02_02-00-00.sql")
+	for _, path := range []string{local, uploaded} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := manifest.MarkUploaded(uploaded, "remote:backups", old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without verify_cloud_exists the upload marker is the proof of a copy
+	log := logger.NewLogger("error")
+	cleanupService := NewCleanupService(&config.CleanupConfig{MaxAgeDays: 7}, &config.UploadConfig{}, log)
+	plan, err := cleanupService.PlanCleanup(dir, nil, nil, NewTrash(dir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Removes(local) || plan.KeepReason(local) != "only copy, never uploaded (use --allow-unverified to delete)" {
+		t.Errorf("local-only backup: removed = %v, kept for %q", plan.Removes(local), plan.KeepReason(local))
+	}
+	if !plan.Removes(uploaded) {
+		t.Error("plan keeps the uploaded backup")
+	}
+
+	cleanupService.SetAllowUnverified(true)
+	plan, err = cleanupService.PlanCleanup(dir, nil, nil, NewTrash(dir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Removes(local) {
+		t.Error("--allow-unverified keeps the local-only backup")
+	}
+}
+
+func TestPlanCleanupPolicyVerifiesUploads(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+
+	// The fake rclone lists every file but the missing one
+	rclone := filepath.Join(dir, "rclone")
+	script := "#!/bin/sh\ncase \"$2\" in\n*missing*) exit 3 ;;\n*) basename \"$2\" ;;\nesac\n"
+	if err := os.WriteFile(rclone, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	backupDir := filepath.Join(dir, "backups")
+	found := filepath.Join(backupDir, "app/2024-06/app-2024-06-01_02-00-00.sql")
+	missing := filepath.Join(backupDir, "missing/2024-06/missing-2024-06-01_02-00-00.sql")
+	uploaded := make(map[string]time.Time)
+	for _, path := range []string{found, missing} {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		uploaded[path] = now.Add(-2 * time.Hour)
+	}
+
+	log := logger.NewLogger("error")
+	cleanupService := NewCleanupService(&config.CleanupConfig{MaxAgeDays: 7, VerifyCloudExists: true},
+		&config.UploadConfig{Enabled: true, RclonePath: rclone, Destination: "remote:backups"}, log)
+	cleanupService.SetRequireVerification(true)
+	plan, err := cleanupService.PlanCleanup(backupDir, nil, uploaded, NewTrash(backupDir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Removes(found) {
+		t.Error("plan keeps the upload found in cloud storage")
+	}
+	if plan.Removes(missing) {
+		t.Error("plan removes the upload missing from cloud storage although policy requires verification")
+	}
+
+	// The upload ledger alone is enough without the policy
+	cleanupService.SetRequireVerification(false)
+	plan, err = cleanupService.PlanCleanup(backupDir, nil, uploaded, NewTrash(backupDir, 0, log), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Removes(missing) {
+		t.Error("plan keeps the upload in the ledger without the policy")
+	}
+}
@@ -0,0 +1,140 @@
+package backup
+
+import (
+	"sort"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// etaHistory is how many recent backups of a database are averaged
+const etaHistory = 3
+
+// HistoricalDurations returns the average time the most recent backups of
+// each database took, read from their manifests. Databases without recorded
+// durations are left out.
+func HistoricalDurations(backupDir string, databases []string) map[string]time.Duration {
+	durations := make(map[string]time.Duration)
+
+	for _, dbName := range databases {
+		var total time.Duration
+		count := 0
+		for _, m := range History(backupDir, dbName) {
+			if m.DurationSeconds <= 0 {
+				continue
+			}
+			total += time.Duration((m.DurationSeconds + m.UploadSeconds) * float64(time.Second))
+			count++
+			if count == etaHistory {
+				break
+			}
+		}
+		if count > 0 {
+			durations[dbName] = total / time.Duration(count)
+		}
+	}
+
+	return durations
+}
+
+// History returns the manifests of a database's local backups, newest first
+func History(backupDir, dbName string) []*manifest.Manifest {
+	paths, err := manifestsOf(backupDir, dbName)
+	if err != nil {
+		return nil
+	}
+	// Month directories and timestamped names sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	var manifests []*manifest.Manifest
+	for _, path := range paths {
+		m, err := manifest.Read(path)
+		if err != nil || m.Database != dbName {
+			continue
+		}
+		manifests = append(manifests, m)
+	}
+	return manifests
+}
+
+// runEstimate predicts when a backup run completes from historical durations.
+// Databases without history are assumed to take the average of those with.
+type runEstimate struct {
+	durations   map[string]time.Duration
+	fallback    time.Duration
+	parallelism int
+	pending     map[string]bool
+	running     map[string]time.Time
+}
+
+func newRunEstimate(durations map[string]time.Duration, databases []string, parallelism int) *runEstimate {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	e := &runEstimate{
+		durations:   durations,
+		parallelism: parallelism,
+		pending:     make(map[string]bool),
+		running:     make(map[string]time.Time),
+	}
+	for _, dbName := range databases {
+		e.pending[dbName] = true
+	}
+
+	if len(durations) > 0 {
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		e.fallback = total / time.Duration(len(durations))
+	}
+
+	return e
+}
+
+func (e *runEstimate) start(dbName string, at time.Time) {
+	delete(e.pending, dbName)
+	e.running[dbName] = at
+}
+
+func (e *runEstimate) finish(dbName string) {
+	delete(e.pending, dbName)
+	delete(e.running, dbName)
+}
+
+// remaining returns the number of databases left and the estimated
+// completion time; ok is false when there is no history to go on
+func (e *runEstimate) remaining(now time.Time) (left int, completion time.Time, ok bool) {
+	left = len(e.pending) + len(e.running)
+	if e.fallback == 0 {
+		return left, time.Time{}, false
+	}
+
+	var work time.Duration
+	for dbName := range e.pending {
+		work += e.duration(dbName)
+	}
+	for dbName, started := range e.running {
+		if rest := e.duration(dbName) - now.Sub(started); rest > 0 {
+			work += rest
+		}
+	}
+
+	parallelism := e.parallelism
+	if left < parallelism {
+		parallelism = left
+	}
+	if parallelism < 1 {
+		return left, now, true
+	}
+
+	return left, now.Add(work / time.Duration(parallelism)), true
+}
+
+func (e *runEstimate) duration(dbName string) time.Duration {
+	if d, ok := e.durations[dbName]; ok {
+		return d
+	}
+	return e.fallback
+}
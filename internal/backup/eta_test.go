@@ -0,0 +1,40 @@
+package backup
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunEstimate(t *testing.T) {
+	durations := map[string]time.Duration{
+		"app":   10 * time.Minute,
+		"users": 20 * time.Minute,
+	}
+	now := time.Date(2025, 7, 5, 2, 0, 0, 0, time.UTC)
+
+	e := newRunEstimate(durations, []string{"app", "users", "new_db"}, 2)
+
+	// new_db has no history and takes the 15 minute average
+	left, completion, ok := e.remaining(now)
+	if !ok || left != 3 {
+		t.Fatalf("remaining() = %d, %v, want 3 databases with an estimate", left, ok)
+	}
+	if want := now.Add(45 * time.Minute / 2); !completion.Equal(want) {
+		t.Errorf("completion = %v, want %v", completion, want)
+	}
+
+	// Time already spent on a running database is subtracted
+	e.start("app", now)
+	e.finish("users")
+	left, completion, _ = e.remaining(now.Add(4 * time.Minute))
+	if left != 2 {
+		t.Errorf("left = %d, want 2", left)
+	}
+	if want := now.Add(4*time.Minute + 21*time.Minute/2); !completion.Equal(want) {
+		t.Errorf("completion = %v, want %v", completion, want)
+	}
+
+	if _, _, ok := newRunEstimate(nil, []string{"app"}, 1).remaining(now); ok {
+		t.Error("expected no estimate without history")
+	}
+}
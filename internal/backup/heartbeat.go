@@ -0,0 +1,51 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// heartbeat is the row written to a database right before its dump
+type heartbeat struct {
+	id        string
+	writtenAt time.Time
+}
+
+// writeHeartbeat writes the heartbeat row of this run to dbName. It returns
+// nil when the row could not be written, e.g. on a read-only replica; the
+// dump then goes ahead unchecked.
+func (s *Service) writeHeartbeat(ctx context.Context, dbName string) *heartbeat {
+	id := runid.FromContext(ctx)
+	if id == "" {
+		id = runid.New()
+	}
+	writtenAt, err := s.dbClient.WriteHeartbeat(ctx, dbName, s.config.Backup.Heartbeat.Table, id)
+	if err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("⚠️ Failed to write heartbeat, the dump will not be checked for it")
+		return nil
+	}
+	return &heartbeat{id: id, writtenAt: writtenAt}
+}
+
+// checkHeartbeat fails when the dump of dbName at backupPath lacks the
+// heartbeat row, and removes the dump: it missed writes made before it
+// started and must not pass for a good backup
+func (s *Service) checkHeartbeat(dbName, backupPath string, hb *heartbeat) error {
+	found, err := database.DumpHasHeartbeat(backupPath, dbName, s.config.Backup.Heartbeat.Table, hb.id)
+	if err != nil {
+		return fmt.Errorf("failed to check dump for heartbeat: %w", err)
+	}
+	if !found {
+		if err := os.RemoveAll(backupPath); err != nil {
+			s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to remove dump without heartbeat")
+		}
+		return fmt.Errorf("dump lacks the heartbeat written to %s at %s, it missed writes made before it started", s.config.Backup.Heartbeat.Table, hb.writtenAt.Format(time.RFC3339))
+	}
+	s.logger.WithDatabase(dbName).Debug("Dump holds the heartbeat")
+	return nil
+}
@@ -0,0 +1,112 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// ImpactSummary is what a backup run cost the server, from samples taken
+// every backup.impact.interval while it lasted
+type ImpactSummary struct {
+	Samples                int     `json:"samples"`
+	ThreadsRunningBaseline int     `json:"threads_running_baseline"` // before the first dump
+	ThreadsRunningPeak     int     `json:"threads_running_peak"`     // including the dump's own threads
+	LockWaitersPeak        int     `json:"lock_waiters_peak"`        // transactions waiting for row locks at once
+	MaxLockWaitSeconds     float64 `json:"max_lock_wait_seconds"`
+	BlockedQueriesPeak     int     `json:"blocked_queries_peak"` // statements waiting for table or global read locks at once
+	MaxBlockedSeconds      float64 `json:"max_blocked_seconds"`
+	RowLockWaits           int64   `json:"row_lock_waits"` // row lock waits anywhere on the server during the run
+
+	rowLockWaitsStart int64
+}
+
+// add folds one sample into the summary; the first one is the baseline
+func (s *ImpactSummary) add(sample database.ImpactSample) {
+	if s.Samples == 0 {
+		s.ThreadsRunningBaseline = sample.ThreadsRunning
+		s.rowLockWaitsStart = sample.RowLockWaits
+	}
+	s.Samples++
+	s.ThreadsRunningPeak = max(s.ThreadsRunningPeak, sample.ThreadsRunning)
+	s.LockWaitersPeak = max(s.LockWaitersPeak, sample.LockWaiters)
+	s.MaxLockWaitSeconds = max(s.MaxLockWaitSeconds, sample.MaxLockWait.Seconds())
+	s.BlockedQueriesPeak = max(s.BlockedQueriesPeak, sample.BlockedQueries)
+	s.MaxBlockedSeconds = max(s.MaxBlockedSeconds, sample.MaxBlocked.Seconds())
+
+	// The counter starts over when the server restarts
+	if sample.RowLockWaits >= s.rowLockWaitsStart {
+		s.RowLockWaits = max(s.RowLockWaits, sample.RowLockWaits-s.rowLockWaitsStart)
+	}
+}
+
+// String is the one line summary logged at the end of a run
+func (s *ImpactSummary) String() string {
+	return fmt.Sprintf("threads_running peak %d (baseline %d), longest row lock wait %.0fs with up to %d waiting, up to %d queries blocked for %.0fs",
+		s.ThreadsRunningPeak, s.ThreadsRunningBaseline, s.MaxLockWaitSeconds, s.LockWaitersPeak, s.BlockedQueriesPeak, s.MaxBlockedSeconds)
+}
+
+// impactSampler samples the server in the background during a run
+type impactSampler struct {
+	mu      sync.Mutex
+	summary ImpactSummary
+}
+
+// sampleImpact takes a baseline sample and keeps sampling until the
+// returned function is called, which logs the summary. It samples nothing
+// when the baseline cannot be read.
+func (s *Service) sampleImpact(ctx context.Context) func() {
+	first, err := s.dbClient.SampleImpact(ctx)
+	if err != nil {
+		s.logger.WithError(err).Warn("⚠️ Failed to sample server load, the run result will have no impact summary")
+		return func() {}
+	}
+	sampler := &impactSampler{}
+	sampler.summary.add(first)
+
+	s.mu.Lock()
+	s.impact = sampler
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.config.Backup.Impact.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			sample, err := s.dbClient.SampleImpact(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.WithError(err).Debug("Failed to sample server load")
+				}
+				continue
+			}
+			sampler.mu.Lock()
+			sampler.summary.add(sample)
+			sampler.mu.Unlock()
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		summary := sampler.get()
+		s.logger.WithField("impact", summary).Info("📉 Backup impact: " + summary.String())
+	}
+}
+
+// get returns a copy of the summary so far
+func (i *impactSampler) get() ImpactSummary {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.summary
+}
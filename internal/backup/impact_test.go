@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+func TestImpactSummary(t *testing.T) {
+	var summary ImpactSummary
+	for _, sample := range []database.ImpactSample{
+		{ThreadsRunning: 3, RowLockWaits: 1000},
+		{ThreadsRunning: 9, RowLockWaits: 1004, LockWaiters: 2, MaxLockWait: 4 * time.Second},
+		{ThreadsRunning: 6, RowLockWaits: 1010, BlockedQueries: 5, MaxBlocked: 12 * time.Second},
+		{ThreadsRunning: 2, RowLockWaits: 3}, // the server restarted
+	} {
+		summary.add(sample)
+	}
+
+	want := ImpactSummary{
+		Samples:                4,
+		ThreadsRunningBaseline: 3,
+		ThreadsRunningPeak:     9,
+		LockWaitersPeak:        2,
+		MaxLockWaitSeconds:     4,
+		BlockedQueriesPeak:     5,
+		MaxBlockedSeconds:      12,
+		RowLockWaits:           10,
+		rowLockWaitsStart:      1000,
+	}
+	if summary != want {
+		t.Errorf("summary = %+v, want %+v", summary, want)
+	}
+}
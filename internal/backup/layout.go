@@ -0,0 +1,94 @@
+package backup
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// DatabaseDir returns the directory in backupDir that holds the backups of
+// dbName, with the name encoded as in the backups' file names
+func DatabaseDir(backupDir, dbName string) string {
+	return filepath.Join(backupDir, database.PathName(dbName))
+}
+
+// manifestsOf returns the manifests of dbName's backups in backupDir, in
+// name order. Glob characters in the names match only themselves.
+func manifestsOf(backupDir, dbName string) ([]string, error) {
+	return filepath.Glob(filepath.Join(escapeGlob(DatabaseDir(backupDir, dbName)), "*", "*"+manifest.Suffix))
+}
+
+// escapeGlob makes filepath.Glob match name literally
+func escapeGlob(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`\*?[`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// DatabaseOf returns the database a path in backupDir belongs to. Backups
+// are written to {backupDir}/{database}/{YYYY-MM}/{database}-{timestamp},
+// so the first path segment names the database; artifacts of older releases
+// lie directly in backupDir and are recognised by name.
+func DatabaseOf(backupDir, path string) (string, bool) {
+	rel, err := filepath.Rel(backupDir, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		// Outside the backup directory only the name tells
+		dbName, _, ok := ParseArtifactName(filepath.Base(path))
+		return dbName, ok
+	}
+
+	first := strings.Split(rel, string(filepath.Separator))[0]
+	if dbName, _, ok := ParseArtifactName(first); ok {
+		return dbName, true
+	}
+	if strings.HasPrefix(first, ".") {
+		return "", false // trash and state files
+	}
+	return database.DatabaseName(first), true
+}
+
+// MatchesDatabases reports whether a path in backupDir belongs to one of
+// databases, compared by exact name. Every path matches an empty list.
+func MatchesDatabases(backupDir, path string, databases []string) bool {
+	if len(databases) == 0 {
+		return true
+	}
+	dbName, ok := DatabaseOf(backupDir, path)
+	if !ok {
+		return false
+	}
+	for _, selected := range databases {
+		if dbName == selected {
+			return true
+		}
+	}
+	return false
+}
+
+// ArtifactOf returns the backup artifact a path in backupDir belongs to:
+// the path itself, the mydumper directory holding it, or the artifact a
+// manifest or upload marker describes. It returns "" for other paths.
+func ArtifactOf(backupDir, path string) string {
+	path = strings.TrimSuffix(path, manifest.Suffix)
+	path = strings.TrimSuffix(path, manifest.UploadedSuffix)
+
+	rel, err := filepath.Rel(backupDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	artifact := backupDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		artifact = filepath.Join(artifact, part)
+		if _, _, ok := ParseArtifactName(part); ok {
+			return artifact
+		}
+	}
+	return ""
+}
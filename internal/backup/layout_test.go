@@ -0,0 +1,107 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+func TestMatchesDatabases(t *testing.T) {
+	dir := filepath.Join("/var", "backups")
+	selected := []string{"app"}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"app/2024-05/app-2024-05-01_02-00-00.sql", true},
+		{"app/2024-05/app-2024-05-01_02-00-00/app.users.sql", true},
+		{"app/2024-05", true},
+		{"app-2024-05-01_02-00-00.tar.gz", true}, // flat layout of older releases
+		{"app_test/2024-05/app_test-2024-05-01_02-00-00.sql", false},
+		{"myapp/2024-05/myapp-2024-05-01_02-00-00.sql", false},
+		{"app-v2/2024-05/app-v2-2024-05-01_02-00-00.sql", false},
+		{"app-v2-2024-05-01_02-00-00.tar.gz", false},
+		{"myapp-2024-05-01_02-00-00.tar.gz", false},
+		{".trash/2024-05-01_02-00-00/app/2024-05/app-2024-05-01_02-00-00.sql", false},
+	}
+	for _, tt := range tests {
+		if got := MatchesDatabases(dir, filepath.Join(dir, tt.path), selected); got != tt.want {
+			t.Errorf("MatchesDatabases(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if !MatchesDatabases(dir, filepath.Join(dir, "myapp/2024-05/myapp-2024-05-01_02-00-00.sql"), nil) {
+		t.Error("an empty filter should match every database")
+	}
+	if MatchesDatabases(dir, "/elsewhere/myapp-2024-05-01_02-00-00.sql", selected) {
+		t.Error("a path outside the backup directory should match by artifact name")
+	}
+}
+
+func TestArtifactOf(t *testing.T) {
+	dir := filepath.Join("/var", "backups")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"app/2024-05/app-2024-05-01_02-00-00.sql.tar.gz", "app/2024-05/app-2024-05-01_02-00-00.sql.tar.gz"},
+		{"app/2024-05/app-2024-05-01_02-00-00/app.users.sql", "app/2024-05/app-2024-05-01_02-00-00"},
+		{"app/2024-05/app-2024-05-01_02-00-00.sql.tar.gz.manifest.json", "app/2024-05/app-2024-05-01_02-00-00.sql.tar.gz"},
+		{"app/2024-05/app-2024-05-01_02-00-00.uploaded", "app/2024-05/app-2024-05-01_02-00-00"},
+		{"app/2024-05", ""},
+	}
+	for _, tt := range tests {
+		want := ""
+		if tt.want != "" {
+			want = filepath.Join(dir, tt.want)
+		}
+		if got := ArtifactOf(dir, filepath.Join(dir, tt.path)); got != want {
+			t.Errorf("ArtifactOf(%s) = %q, want %q", tt.path, got, want)
+		}
+	}
+}
+
+func TestDatabaseOfExoticNames(t *testing.T) {
+	dir := filepath.Join("/var", "backups")
+
+	for _, name := range []string{"my-app", "my.app", "a/b", ".hidden", "user@example", "2024-05"} {
+		artifact := filepath.Join(DatabaseDir(dir, name), "2024-05", database.PathName(name)+"-2024-05-01_02-00-00.sql")
+		if got, ok := DatabaseOf(dir, artifact); !ok || got != name {
+			t.Errorf("DatabaseOf(%s) = %q, %v, want %q", artifact, got, ok, name)
+		}
+		if got, _, ok := ParseArtifactName(filepath.Base(artifact)); !ok || got != name {
+			t.Errorf("ParseArtifactName(%s) = %q, %v, want %q", filepath.Base(artifact), got, ok, name)
+		}
+		if !MatchesDatabases(dir, artifact, []string{name}) {
+			t.Errorf("%s should match database %q", artifact, name)
+		}
+	}
+}
+
+func TestManifestsOfGlobCharacters(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app[1]", "app1", "app*"} {
+		path := filepath.Join(DatabaseDir(dir, name), "2024-05", name+"-2024-05-01_02-00-00.sql"+manifest.Suffix)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{"app[1]", "app*"} {
+		paths, err := manifestsOf(dir, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(paths) != 1 || filepath.Base(filepath.Dir(filepath.Dir(paths[0]))) != name {
+			t.Errorf("manifestsOf(%q) = %v, want only its own manifest", name, paths)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// UploadLedger persists when local backups were uploaded, so that a cleanup
+// run in another process knows which files have a cloud copy. It lives in
+// the state directory, next to the backup frequency tracking file.
+type UploadLedger struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewUploadLedger returns the ledger kept in stateDir
+func NewUploadLedger(stateDir string) *UploadLedger {
+	return &UploadLedger{path: filepath.Join(stateDir, "uploaded.json")}
+}
+
+// Path returns the ledger file
+func (l *UploadLedger) Path() string {
+	return l.path
+}
+
+// Load returns the upload time of every recorded file. A missing ledger is
+// empty.
+func (l *UploadLedger) Load() (map[string]time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.load()
+}
+
+// Add records that path was uploaded at the given time. Entries of files
+// that no longer exist are dropped on the way.
+func (l *UploadLedger) Add(path string, at time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.load()
+	if err != nil {
+		return err
+	}
+	for file := range entries {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			delete(entries, file)
+		}
+	}
+	entries[path] = at
+	return l.write(entries)
+}
+
+// Remove forgets paths, typically after cleanup deleted them
+func (l *UploadLedger) Remove(paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entries, err := l.load()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		delete(entries, path)
+	}
+	return l.write(entries)
+}
+
+func (l *UploadLedger) load() (map[string]time.Time, error) {
+	entries := make(map[string]time.Time)
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("failed to read upload ledger: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse upload ledger %s: %w", l.path, err)
+	}
+	return entries, nil
+}
+
+func (l *UploadLedger) write(entries map[string]time.Time) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload ledger: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	tempPath := l.path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write upload ledger: %w", err)
+	}
+	if err := os.Rename(tempPath, l.path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write upload ledger: %w", err)
+	}
+	return nil
+}
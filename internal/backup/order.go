@@ -0,0 +1,176 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// unit is a set of databases started together: a single database or the
+// members of a consistency group
+type unit []string
+
+// batch is a set of units run with the configured concurrency
+type batch []unit
+
+// databases returns every database of the batch in order
+func (b batch) databases() []string {
+	var names []string
+	for _, u := range b {
+		names = append(names, u...)
+	}
+	return names
+}
+
+// planBatches splits databases into batches of at most batchSize databases.
+// Consistency groups stay together in one unit, and a database only runs in
+// a later batch than the databases it depends on. Without dependencies and
+// groups this is databases cut into batchSize slices.
+func planBatches(databases []string, cfg *config.BackupConfig) ([]batch, error) {
+	units, unitOf := groupUnits(databases, cfg.ConsistencyGroups)
+
+	deps := make([]map[int]bool, len(units))
+	for _, dep := range cfg.Dependencies {
+		for _, dbName := range databases {
+			if !matchDatabase(dep.Database, dbName) {
+				continue
+			}
+			for _, after := range dep.After {
+				for _, other := range databases {
+					if other == dbName || !matchDatabase(after, other) {
+						continue
+					}
+					u, d := unitOf[dbName], unitOf[other]
+					if u == d {
+						return nil, fmt.Errorf("%s cannot depend on %s, they are in the same consistency group", dbName, other)
+					}
+					if deps[u] == nil {
+						deps[u] = make(map[int]bool)
+					}
+					deps[u][d] = true
+				}
+			}
+		}
+	}
+
+	// A unit's stage is one past the latest stage of its dependencies
+	stages := make([]int, len(units))
+	state := make([]int, len(units)) // 0 unvisited, 1 visiting, 2 done
+	var visit func(u int, path []string) error
+	visit = func(u int, path []string) error {
+		switch state[u] {
+		case 1:
+			return fmt.Errorf("backup dependency cycle: %s", strings.Join(append(path, units[u][0]), " -> "))
+		case 2:
+			return nil
+		}
+		state[u] = 1
+		for d := range deps[u] {
+			if err := visit(d, append(path, units[u][0])); err != nil {
+				return err
+			}
+			if stages[d]+1 > stages[u] {
+				stages[u] = stages[d] + 1
+			}
+		}
+		state[u] = 2
+		return nil
+	}
+	maxStage := 0
+	for u := range units {
+		if err := visit(u, nil); err != nil {
+			return nil, err
+		}
+		if stages[u] > maxStage {
+			maxStage = stages[u]
+		}
+	}
+
+	var batches []batch
+	for stage := 0; stage <= maxStage; stage++ {
+		var current batch
+		size := 0
+		for u, members := range units {
+			if stages[u] != stage {
+				continue
+			}
+			if size > 0 && size+len(members) > cfg.BatchSize {
+				batches = append(batches, current)
+				current, size = nil, 0
+			}
+			current = append(current, members)
+			size += len(members)
+		}
+		if len(current) > 0 {
+			batches = append(batches, current)
+		}
+	}
+	return batches, nil
+}
+
+// orderBySize returns databases sorted by size for backup.order
+// largest_first or smallest_first, keeping the config order between
+// databases of the same size. Databases without a size sort as empty.
+func orderBySize(databases []string, sizes map[string]int64, order string) []string {
+	ordered := append([]string(nil), databases...)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if order == "smallest_first" {
+			return sizes[ordered[i]] < sizes[ordered[j]]
+		}
+		return sizes[ordered[i]] > sizes[ordered[j]]
+	})
+	return ordered
+}
+
+// groupUnits turns databases into units, placing each consistency group at
+// its first member. It returns the units and the unit of each database.
+func groupUnits(databases []string, groups []config.ConsistencyGroup) ([]unit, map[string]int) {
+	groupOf := make(map[string]int)
+	for i, group := range groups {
+		for _, dbName := range group.Databases {
+			groupOf[dbName] = i
+		}
+	}
+
+	var units []unit
+	unitOf := make(map[string]int)
+	groupUnit := make(map[int]int)
+	for _, dbName := range databases {
+		g, grouped := groupOf[dbName]
+		if !grouped {
+			unitOf[dbName] = len(units)
+			units = append(units, unit{dbName})
+			continue
+		}
+		u, started := groupUnit[g]
+		if !started {
+			u = len(units)
+			groupUnit[g] = u
+			units = append(units, nil)
+		}
+		units[u] = append(units[u], dbName)
+		unitOf[dbName] = u
+	}
+	return units, unitOf
+}
+
+// consistencyGroup returns the group the members of u belong to, nil for a
+// single database outside any group
+func consistencyGroup(u unit, groups []config.ConsistencyGroup) *config.ConsistencyGroup {
+	for i := range groups {
+		for _, dbName := range groups[i].Databases {
+			if dbName == u[0] {
+				return &groups[i]
+			}
+		}
+	}
+	return nil
+}
+
+func matchDatabase(pattern, dbName string) bool {
+	matched, _ := filepath.Match(pattern, dbName)
+	return matched
+}
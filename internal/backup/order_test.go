@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func formatBatches(batches []batch) string {
+	var parts []string
+	for _, b := range batches {
+		var units []string
+		for _, u := range b {
+			units = append(units, strings.Join(u, "+"))
+		}
+		parts = append(parts, "["+strings.Join(units, " ")+"]")
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestPlanBatches(t *testing.T) {
+	databases := []string{"tenant_a", "tenant_b", "config_db", "orders", "logs", "invoices"}
+
+	tests := []struct {
+		name string
+		cfg  config.BackupConfig
+		want string
+	}{
+		{
+			name: "plain batches",
+			cfg:  config.BackupConfig{BatchSize: 4},
+			want: "[tenant_a tenant_b config_db orders] [logs invoices]",
+		},
+		{
+			name: "dependency",
+			cfg: config.BackupConfig{BatchSize: 4, Dependencies: []config.BackupDependency{
+				{Database: "tenant_*", After: []string{"config_db"}},
+			}},
+			want: "[config_db orders logs invoices] [tenant_a tenant_b]",
+		},
+		{
+			name: "group kept together",
+			cfg: config.BackupConfig{BatchSize: 4, ConsistencyGroups: []config.ConsistencyGroup{
+				{Name: "billing", Databases: []string{"orders", "invoices"}},
+			}},
+			want: "[tenant_a tenant_b config_db] [orders+invoices logs]",
+		},
+		{
+			name: "group depending on a database",
+			cfg: config.BackupConfig{
+				BatchSize:         10,
+				Dependencies:      []config.BackupDependency{{Database: "invoices", After: []string{"logs"}}},
+				ConsistencyGroups: []config.ConsistencyGroup{{Name: "billing", Databases: []string{"orders", "invoices"}}},
+			},
+			want: "[tenant_a tenant_b config_db logs] [orders+invoices]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches, err := planBatches(databases, &tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := formatBatches(batches); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanBatchesErrors(t *testing.T) {
+	databases := []string{"a", "b", "c"}
+
+	cycle := config.BackupConfig{BatchSize: 5, Dependencies: []config.BackupDependency{
+		{Database: "a", After: []string{"b"}},
+		{Database: "b", After: []string{"c"}},
+		{Database: "c", After: []string{"a"}},
+	}}
+	if _, err := planBatches(databases, &cycle); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("cycle: err = %v", err)
+	}
+
+	inGroup := config.BackupConfig{
+		BatchSize:         5,
+		Dependencies:      []config.BackupDependency{{Database: "a", After: []string{"b"}}},
+		ConsistencyGroups: []config.ConsistencyGroup{{Name: "g", Databases: []string{"a", "b"}}},
+	}
+	_, err := planBatches(databases, &inGroup)
+	if err == nil {
+		t.Error("dependency inside a consistency group was accepted")
+	}
+}
+
+func TestOrderBySize(t *testing.T) {
+	databases := []string{"logs", "orders", "config_db", "users"}
+	sizes := map[string]int64{"logs": 50 << 30, "orders": 200 << 30, "users": 50 << 30}
+
+	if got := strings.Join(orderBySize(databases, sizes, "largest_first"), " "); got != "orders logs users config_db" {
+		t.Errorf("largest_first = %s", got)
+	}
+	if got := strings.Join(orderBySize(databases, sizes, "smallest_first"), " "); got != "config_db logs users orders" {
+		t.Errorf("smallest_first = %s", got)
+	}
+	if databases[0] != "logs" {
+		t.Error("orderBySize changed its input")
+	}
+}
@@ -0,0 +1,59 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// newPostgreSQLProvider connects to the PostgreSQL server of
+// database.type postgresql, checking its client tools first
+func newPostgreSQLProvider(ctx context.Context, s *Service) (database.Provider, error) {
+	provider, err := database.NewConfiguredProvider(&s.config.Database)
+	if err != nil {
+		return nil, err
+	}
+	if err := provider.ValidateTools(); err != nil {
+		return nil, err
+	}
+	if err := provider.TestConnection(ctx); err != nil {
+		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	}
+	return provider, nil
+}
+
+// createPostgreSQLBackup dumps dbName with pg_dump into the same
+// {database}/{YYYY-MM}/{database}-{timestamp} layout as MySQL backups.
+// pg_dump compresses custom and directory dumps itself unless tenangdb
+// compresses the backup afterwards.
+func (s *Service) createPostgreSQLBackup(ctx context.Context, dbName string) (string, error) {
+	now := time.Now()
+	dir := filepath.Join(DatabaseDir(s.config.Backup.Directory, dbName), now.Format("2006-01"))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create organized backup directory: %w", err)
+	}
+
+	results, err := s.provider.CreateBackup(ctx, &database.BackupOptions{
+		Databases:     []string{dbName},
+		Directory:     dir,
+		Timestamp:     now.Format("2006-01-02_15-04-05"),
+		Compression:   !s.config.Backup.Compression.Enabled,
+		IncludeData:   true,
+		IncludeSchema: true,
+		Timeout:       s.config.Backup.Timeout,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(results) != 1 {
+		return "", fmt.Errorf("pg_dump returned %d results for one database", len(results))
+	}
+	if results[0].Error != nil {
+		return "", results[0].Error
+	}
+	return results[0].BackupPath, nil
+}
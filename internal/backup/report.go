@@ -0,0 +1,68 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CleanupResult summarises what one cleanup pass removed
+type CleanupResult struct {
+	FilesRemoved int64    `json:"files_removed"`
+	BytesFreed   int64    `json:"bytes_freed"`
+	Files        []string `json:"files,omitempty"`
+}
+
+// Add records a removed backup file or directory
+func (r *CleanupResult) Add(path string, size int64) {
+	r.FilesRemoved++
+	r.BytesFreed += size
+	r.Files = append(r.Files, path)
+}
+
+// CleanupReport describes a complete cleanup run
+type CleanupReport struct {
+	StartedAt          time.Time     `json:"started_at"`
+	FinishedAt         time.Time     `json:"finished_at"`
+	DurationSeconds    float64       `json:"duration_seconds"`
+	Success            bool          `json:"success"`
+	Error              string        `json:"error,omitempty"`
+	FilesRemoved       int64         `json:"files_removed"`
+	BytesFreed         int64         `json:"bytes_freed"`
+	TrashBatchesPurged int           `json:"trash_batches_purged"`
+	UploadedFiles      CleanupResult `json:"uploaded_files"`
+	AgeBased           CleanupResult `json:"age_based"`
+}
+
+// Finish totals the report and records the outcome
+func (r *CleanupReport) Finish(err error) {
+	r.FinishedAt = time.Now()
+	r.DurationSeconds = r.FinishedAt.Sub(r.StartedAt).Seconds()
+	r.FilesRemoved = r.UploadedFiles.FilesRemoved + r.AgeBased.FilesRemoved
+	r.BytesFreed = r.UploadedFiles.BytesFreed + r.AgeBased.BytesFreed
+	r.Success = err == nil
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// WriteFile writes the report as JSON, replacing any previous report
+func (r *CleanupReport) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal cleanup report: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write cleanup report: %w", err)
+	}
+
+	return os.Rename(tempPath, path)
+}
@@ -0,0 +1,257 @@
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Categories of the errors collected in a run result
+const (
+	ErrorDump     = "dump"
+	ErrorCompress = "compress"
+	ErrorEncrypt  = "encrypt"
+	ErrorUpload   = "upload"
+)
+
+// DatabaseError is one failure of a database during a run
+type DatabaseError struct {
+	Database string    `json:"database"`
+	Category string    `json:"category"`
+	Message  string    `json:"message"`
+	Fatal    bool      `json:"fatal"` // the database got no backup in this pass
+	Pass     int       `json:"pass"`  // 1 for the main pass, higher for retry passes
+	At       time.Time `json:"at"`
+}
+
+// PassResult is the outcome of one pass over databases: the main pass, or
+// a retry pass over the databases that failed before
+type PassResult struct {
+	Pass      int      `json:"pass"`
+	Databases []string `json:"databases"`
+	Succeeded []string `json:"succeeded,omitempty"`
+	Failed    []string `json:"failed,omitempty"`
+}
+
+// RunResult is the outcome of a backup run, with every error of every
+// database collected in one place
+type RunResult struct {
+	RunID             string          `json:"run_id"`
+	StartedAt         time.Time       `json:"started_at"`
+	FinishedAt        time.Time       `json:"finished_at"`
+	DurationSeconds   float64         `json:"duration_seconds"`
+	Success           bool            `json:"success"` // no errors, other than ones a retry pass recovered from
+	TotalDatabases    int             `json:"total_databases"`
+	SuccessfulBackups int             `json:"successful_backups"`
+	FailedBackups     int             `json:"failed_backups"`
+	SuccessfulUploads int             `json:"successful_uploads"`
+	FailedUploads     int             `json:"failed_uploads"`
+	Skipped           []string        `json:"skipped,omitempty"`
+	Unchanged         []string        `json:"unchanged,omitempty"` // kept their last backup, counted as successful
+	Passes            []PassResult    `json:"passes,omitempty"`
+	ErrorCounts       map[string]int  `json:"error_counts,omitempty"` // category to number of errors
+	Errors            []DatabaseError `json:"errors,omitempty"`
+	Impact            *ImpactSummary  `json:"impact,omitempty"` // with backup.impact
+}
+
+// ErrorSummary lists the error counts by category, such as
+// "dump: 2, upload: 1", or returns "" without errors
+func (r *RunResult) ErrorSummary() string {
+	categories := make([]string, 0, len(r.ErrorCounts))
+	for category := range r.ErrorCounts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	parts := make([]string, 0, len(categories))
+	for _, category := range categories {
+		parts = append(parts, fmt.Sprintf("%s: %d", category, r.ErrorCounts[category]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteFile writes the result as JSON, replacing any previous one
+func (r *RunResult) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run result: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run result: %w", err)
+	}
+
+	return os.Rename(tempPath, path)
+}
+
+// recordError adds a failure of dbName to the run result
+func (s *Service) recordError(dbName, category string, err error, fatal bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errors = append(s.errors, DatabaseError{
+		Database: dbName,
+		Category: category,
+		Message:  err.Error(),
+		Fatal:    fatal,
+		Pass:     s.pass,
+		At:       time.Now(),
+	})
+}
+
+// Result returns the outcome of the run so far
+func (s *Service) Result() RunResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := RunResult{
+		RunID:             s.runID,
+		StartedAt:         s.stats.StartTime,
+		FinishedAt:        s.stats.EndTime,
+		TotalDatabases:    s.stats.TotalDatabases,
+		SuccessfulBackups: s.stats.SuccessfulBackups,
+		FailedBackups:     s.stats.FailedBackups,
+		SuccessfulUploads: s.stats.SuccessfulUploads,
+		FailedUploads:     s.stats.FailedUploads,
+		Skipped:           append([]string(nil), s.stats.SkippedDatabases...),
+		Unchanged:         append([]string(nil), s.stats.UnchangedDatabases...),
+		Passes:            append([]PassResult(nil), s.passes...),
+		Errors:            append([]DatabaseError(nil), s.errors...),
+	}
+	if result.FinishedAt.IsZero() {
+		result.FinishedAt = time.Now()
+	}
+	result.DurationSeconds = result.FinishedAt.Sub(result.StartedAt).Seconds()
+	if s.impact != nil {
+		impact := s.impact.get()
+		result.Impact = &impact
+	}
+
+	if len(result.Errors) > 0 {
+		result.ErrorCounts = make(map[string]int)
+		for _, e := range result.Errors {
+			result.ErrorCounts[e.Category]++
+		}
+	}
+	result.Success = len(result.Skipped) == 0
+	for _, e := range result.Errors {
+		if !result.recovered(e) {
+			result.Success = false
+		}
+	}
+	return result
+}
+
+// ForDatabases narrows the result to databases of the run, such as the
+// databases of one tenant. Backup and upload counts are recounted per
+// database from the errors; the impact of the run is left out, since it
+// is not known per database.
+func (r *RunResult) ForDatabases(databases []string) RunResult {
+	result := RunResult{
+		RunID:           r.RunID,
+		StartedAt:       r.StartedAt,
+		FinishedAt:      r.FinishedAt,
+		DurationSeconds: r.DurationSeconds,
+		TotalDatabases:  len(databases),
+	}
+	for _, dbName := range r.Skipped {
+		if contains(databases, dbName) {
+			result.Skipped = append(result.Skipped, dbName)
+		}
+	}
+	for _, dbName := range r.Unchanged {
+		if contains(databases, dbName) {
+			result.Unchanged = append(result.Unchanged, dbName)
+		}
+	}
+	for _, pass := range r.Passes {
+		kept := PassResult{Pass: pass.Pass}
+		for _, dbName := range pass.Databases {
+			if contains(databases, dbName) {
+				kept.Databases = append(kept.Databases, dbName)
+			}
+		}
+		if len(kept.Databases) == 0 {
+			continue
+		}
+		for _, dbName := range pass.Succeeded {
+			if contains(databases, dbName) {
+				kept.Succeeded = append(kept.Succeeded, dbName)
+			}
+		}
+		for _, dbName := range pass.Failed {
+			if contains(databases, dbName) {
+				kept.Failed = append(kept.Failed, dbName)
+			}
+		}
+		result.Passes = append(result.Passes, kept)
+	}
+
+	failedBackups := make(map[string]bool)
+	failedUploads := make(map[string]bool)
+	for _, e := range r.Errors {
+		if !contains(databases, e.Database) {
+			continue
+		}
+		result.Errors = append(result.Errors, e)
+		if result.ErrorCounts == nil {
+			result.ErrorCounts = make(map[string]int)
+		}
+		result.ErrorCounts[e.Category]++
+		if r.recovered(e) {
+			continue
+		}
+		if e.Fatal {
+			failedBackups[e.Database] = true
+		} else if e.Category == ErrorUpload {
+			failedUploads[e.Database] = true
+		}
+	}
+	result.FailedBackups = len(failedBackups)
+	result.SuccessfulBackups = result.TotalDatabases - result.FailedBackups - len(result.Skipped)
+	if r.SuccessfulUploads+r.FailedUploads > 0 {
+		result.FailedUploads = len(failedUploads)
+		result.SuccessfulUploads = result.SuccessfulBackups - result.FailedUploads
+	}
+	result.Success = len(result.Skipped) == 0
+	for _, e := range result.Errors {
+		if !r.recovered(e) {
+			result.Success = false
+		}
+	}
+	return result
+}
+
+// Recovered returns the databases a retry pass backed up
+func (r *RunResult) Recovered() []string {
+	var recovered []string
+	for _, pass := range r.Passes {
+		if pass.Pass > 1 {
+			recovered = append(recovered, pass.Succeeded...)
+		}
+	}
+	return recovered
+}
+
+// recovered reports whether a later pass backed up the database of e
+func (r *RunResult) recovered(e DatabaseError) bool {
+	for _, pass := range r.Passes {
+		if pass.Pass <= e.Pass {
+			continue
+		}
+		for _, dbName := range pass.Succeeded {
+			if dbName == e.Database {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package backup
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRunResultCollectsErrors(t *testing.T) {
+	s := &Service{stats: &Statistics{TotalDatabases: 3, SuccessfulBackups: 2, FailedBackups: 1}}
+	if result := s.Result(); !result.Success || result.ErrorSummary() != "" {
+		t.Errorf("result without errors = %+v", result)
+	}
+
+	s.recordError("logs_db", ErrorDump, errors.New("lock wait timeout"), true)
+	s.recordError("app_db", ErrorUpload, errors.New("connection reset"), false)
+	s.recordError("user_db", ErrorUpload, errors.New("connection reset"), false)
+
+	result := s.Result()
+	if result.Success {
+		t.Error("result with errors reports success")
+	}
+	if got, want := result.ErrorSummary(), "dump: 1, upload: 2"; got != want {
+		t.Errorf("ErrorSummary() = %q, want %q", got, want)
+	}
+	if len(result.Errors) != 3 || result.Errors[0].Database != "logs_db" || !result.Errors[0].Fatal {
+		t.Errorf("errors = %+v", result.Errors)
+	}
+}
+
+func TestRunResultRetryPasses(t *testing.T) {
+	s := &Service{stats: &Statistics{TotalDatabases: 3}, pass: 1}
+	s.recordError("logs_db", ErrorDump, errors.New("lock wait timeout"), true)
+	s.recordError("user_db", ErrorDump, errors.New("lock wait timeout"), true)
+	failed := s.failedInPass(1)
+	s.recordPass(1, []string{"app_db", "logs_db", "user_db"}, failed)
+
+	s.pass = 2
+	s.recordError("user_db", ErrorDump, errors.New("access denied"), true)
+	s.recordPass(2, failed, s.failedInPass(2))
+
+	result := s.Result()
+	if got := result.Recovered(); len(got) != 1 || got[0] != "logs_db" {
+		t.Errorf("Recovered() = %v, want [logs_db]", got)
+	}
+	if result.Success {
+		t.Error("user_db failed in every pass, want no success")
+	}
+	if first := result.Passes[0]; len(first.Succeeded) != 1 || len(first.Failed) != 2 {
+		t.Errorf("main pass = %+v", first)
+	}
+
+	// Once user_db is recovered too, the run succeeded
+	s.pass = 3
+	s.recordPass(3, []string{"user_db"}, nil)
+	if result := s.Result(); !result.Success {
+		t.Errorf("run with every failure recovered = %+v, want success", result)
+	}
+}
+
+func TestRunResultForDatabases(t *testing.T) {
+	s := &Service{stats: &Statistics{TotalDatabases: 4, SuccessfulBackups: 2, FailedBackups: 1, SuccessfulUploads: 1, FailedUploads: 1, SkippedDatabases: []string{"crm_db"}}, pass: 1}
+	s.recordError("logs_db", ErrorDump, errors.New("lock wait timeout"), true)
+	s.recordError("app_db", ErrorUpload, errors.New("connection reset"), false)
+	s.recordPass(1, []string{"app_db", "logs_db", "pay_db"}, s.failedInPass(1))
+	result := s.Result()
+
+	payments := result.ForDatabases([]string{"pay_db"})
+	if !payments.Success || payments.SuccessfulBackups != 1 || payments.SuccessfulUploads != 1 || len(payments.Errors) != 0 {
+		t.Errorf("result of pay_db = %+v, want a success", payments)
+	}
+
+	shop := result.ForDatabases([]string{"app_db", "logs_db", "crm_db"})
+	if shop.Success {
+		t.Error("result of shop reports success")
+	}
+	if shop.TotalDatabases != 3 || shop.SuccessfulBackups != 1 || shop.FailedBackups != 1 || shop.FailedUploads != 1 || shop.SuccessfulUploads != 0 {
+		t.Errorf("counts of shop = %+v", shop)
+	}
+	if len(shop.Skipped) != 1 || shop.ErrorSummary() != "dump: 1, upload: 1" {
+		t.Errorf("shop skipped %v with errors %q", shop.Skipped, shop.ErrorSummary())
+	}
+	if len(shop.Passes) != 1 || len(shop.Passes[0].Databases) != 2 {
+		t.Errorf("passes of shop = %+v", shop.Passes)
+	}
+}
@@ -0,0 +1,145 @@
+package backup
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// artifactNamePattern matches backup names created by the database client:
+// {database}-{YYYY-MM-DD_HH-MM-SS} followed by an optional extension
+var artifactNamePattern = regexp.MustCompile(`^(.+)-(\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})(\..*)?$`)
+
+// ParseArtifactName extracts the database and creation time from a backup
+// name, decoding the characters database.PathName encodes
+func ParseArtifactName(name string) (dbName string, createdAt time.Time, ok bool) {
+	m := artifactNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return "", time.Time{}, false
+	}
+
+	createdAt, err := time.ParseInLocation("2006-01-02_15-04-05", m[2], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	return database.DatabaseName(m[1]), createdAt, true
+}
+
+// RetentionSet holds backup artifacts that cleanup must never delete
+type RetentionSet struct {
+	paths  []string
+	pinned []string
+}
+
+// RetainedBackups walks backupDir and retains the newest minKeep backups of
+// every database, regardless of their age, and every pinned backup
+func RetainedBackups(backupDir string, minKeep int) (*RetentionSet, error) {
+	set := &RetentionSet{}
+
+	type backupRef struct {
+		createdAt time.Time
+		paths     []string
+		pinned    bool
+	}
+	byDatabase := make(map[string]map[time.Time]*backupRef)
+
+	err := filepath.WalkDir(backupDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == backupDir {
+			return nil
+		}
+		if d.IsDir() && d.Name() == TrashDirName {
+			return filepath.SkipDir
+		}
+
+		dbName, createdAt, ok := ParseArtifactName(d.Name())
+		if !ok {
+			return nil
+		}
+
+		if byDatabase[dbName] == nil {
+			byDatabase[dbName] = make(map[time.Time]*backupRef)
+		}
+		ref := byDatabase[dbName][createdAt]
+		if ref == nil {
+			ref = &backupRef{createdAt: createdAt}
+			byDatabase[dbName][createdAt] = ref
+		}
+		ref.paths = append(ref.paths, path)
+
+		// The manifest shares the artifact's name, so it lands in the same ref
+		if manifest.IsManifest(path) {
+			if m, err := manifest.Read(path); err == nil && m.Pinned {
+				ref.pinned = true
+			}
+		}
+
+		// mydumper backups are directories; their contents belong to the backup
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, backups := range byDatabase {
+		refs := make([]*backupRef, 0, len(backups))
+		for _, ref := range backups {
+			refs = append(refs, ref)
+		}
+		sort.Slice(refs, func(i, j int) bool {
+			return refs[i].createdAt.After(refs[j].createdAt)
+		})
+
+		for i, ref := range refs {
+			if i < minKeep || ref.pinned {
+				set.paths = append(set.paths, ref.paths...)
+			}
+			if ref.pinned {
+				set.pinned = append(set.pinned, ref.paths...)
+			}
+		}
+	}
+
+	return set, nil
+}
+
+// Protects reports whether deleting path would remove a retained backup,
+// either because path is one, lies inside one, or contains one
+func (r *RetentionSet) Protects(path string) bool {
+	return coversAny(r.paths, path)
+}
+
+// Pins reports whether deleting path would remove a pinned backup. Unlike
+// Protects it ignores min_keep, which only holds back age-based cleanup.
+func (r *RetentionSet) Pins(path string) bool {
+	return coversAny(r.pinned, path)
+}
+
+// coversAny reports whether path is one of paths, lies inside one or
+// contains one
+func coversAny(paths []string, path string) bool {
+	path = filepath.Clean(path)
+	for _, kept := range paths {
+		if path == kept || isWithin(path, kept) || isWithin(kept, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// isWithin reports whether path lies below dir
+func isWithin(path, dir string) bool {
+	return strings.HasPrefix(path, dir+string(filepath.Separator))
+}
@@ -0,0 +1,61 @@
+package backup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+func TestRetainedBackups(t *testing.T) {
+	dir := t.TempDir()
+
+	files := []string{
+		"app/2024-05/app-2024-05-01_02-00-00.sql",
+		"app/2024-05/app-2024-05-02_02-00-00.sql",
+		"app/2024-06/app-2024-06-01_02-00-00.sql.tar.gz",
+		"my-app/2024-05/my-app-2024-05-01_02-00-00/my-app.t1.sql",
+		"my-app/2024-05/my-app-2024-05-01_02-00-00/metadata",
+		"old/2024-01/old-2024-01-01_02-00-00.sql",
+		"old/2024-02/old-2024-02-01_02-00-00.sql",
+		"old/2024-03/old-2024-03-01_02-00-00.sql",
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pinned := &manifest.Manifest{Database: "old", Artifact: "old-2024-01-01_02-00-00.sql", Pinned: true}
+	if _, err := pinned.Write(filepath.Join(dir, "old/2024-01/old-2024-01-01_02-00-00.sql")); err != nil {
+		t.Fatal(err)
+	}
+
+	retained, err := RetainedBackups(dir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"app/2024-05/app-2024-05-01_02-00-00.sql", false},
+		{"app/2024-05/app-2024-05-02_02-00-00.sql", true},
+		{"app/2024-06/app-2024-06-01_02-00-00.sql.tar.gz", true},
+		{"app", true}, // contains retained backups
+		{"my-app/2024-05/my-app-2024-05-01_02-00-00/metadata", true},
+		{"old/2024-01/old-2024-01-01_02-00-00.sql", true}, // pinned
+		{"old/2024-01/old-2024-01-01_02-00-00.sql.manifest.json", true},
+	}
+	for _, tt := range tests {
+		if got := retained.Protects(filepath.Join(dir, tt.path)); got != tt.want {
+			t.Errorf("Protects(%s) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
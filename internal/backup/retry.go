@@ -0,0 +1,93 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+)
+
+// retryFailed records the main pass and then runs up to
+// backup.retry_failed.attempts passes over the databases still without a
+// backup, each after backup.retry_failed.delay
+func (s *Service) retryFailed(ctx context.Context, databases []string) {
+	failed := s.failedInPass(1)
+	s.recordPass(1, databases, failed)
+
+	retry := s.config.Backup.RetryFailed
+	for pass := 2; pass <= retry.Attempts+1 && len(failed) > 0; pass++ {
+		s.logger.WithField("databases", failed).WithField("pass", pass).
+			Info(fmt.Sprintf("🔁 Retrying %d failed databases in %s", len(failed), retry.Delay))
+		if !s.pause(ctx, retry.Delay) {
+			return
+		}
+
+		s.mu.Lock()
+		s.pass = pass
+		s.mu.Unlock()
+
+		units := make(batch, 0, len(failed))
+		for _, dbName := range failed {
+			units = append(units, unit{dbName})
+		}
+		if err := s.processBatch(ctx, units, s.config.Backup.Concurrency); err != nil {
+			s.logger.WithError(err).Error("Retry pass failed")
+		}
+
+		// Databases that ran again are counted by this pass instead
+		s.mu.Lock()
+		for _, dbName := range failed {
+			if !contains(s.stats.SkippedDatabases, dbName) {
+				s.stats.FailedBackups--
+			}
+		}
+		s.mu.Unlock()
+
+		stillFailed := s.failedInPass(pass)
+		result := s.recordPass(pass, failed, stillFailed)
+		if len(result.Succeeded) > 0 {
+			s.logger.WithField("pass", pass).WithField("databases", result.Succeeded).
+				Info(fmt.Sprintf("✅ %d of %d databases recovered on retry", len(result.Succeeded), len(failed)))
+		}
+		failed = stillFailed
+	}
+}
+
+// failedInPass returns the databases that got no backup in a pass
+func (s *Service) failedInPass(pass int) []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var failed []string
+	seen := make(map[string]bool)
+	for _, e := range s.errors {
+		if e.Pass == pass && e.Fatal && !seen[e.Database] {
+			seen[e.Database] = true
+			failed = append(failed, e.Database)
+		}
+	}
+	return failed
+}
+
+// recordPass adds the outcome of a pass over databases to the run result.
+// Databases skipped by a cancellation count neither way.
+func (s *Service) recordPass(pass int, databases, failed []string) PassResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := PassResult{Pass: pass, Databases: databases, Failed: failed}
+	for _, dbName := range databases {
+		if !contains(failed, dbName) && !contains(s.stats.SkippedDatabases, dbName) {
+			result.Succeeded = append(result.Succeeded, dbName)
+		}
+	}
+	s.passes = append(s.passes, result)
+	return result
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
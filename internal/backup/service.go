@@ -3,15 +3,24 @@ package backup
 import (
 	"context"
 	"fmt"
+	"math/rand"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/abdullahainun/tenangdb/internal/compression"
 	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/encryption"
+	"github.com/abdullahainun/tenangdb/internal/hooks"
 	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
 	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/progress"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+	"github.com/abdullahainun/tenangdb/internal/schemahistory"
+	"github.com/abdullahainun/tenangdb/internal/toolversion"
 	"github.com/abdullahainun/tenangdb/internal/upload"
 	"github.com/abdullahainun/tenangdb/pkg/database"
 )
@@ -19,13 +28,36 @@ import (
 type Service struct {
 	config         *config.Config
 	logger         *logger.Logger
-	dbClient       *database.Client
+	dbClient       *database.Client  // nil for PostgreSQL servers
+	provider       database.Provider // dumps PostgreSQL servers, nil for MySQL
 	uploader       *upload.Service
+	tenantUpload   map[string]*upload.Service // uploaders below the tenants' upload prefixes
+	tenantSlots    map[string]chan struct{}   // concurrency quotas of the tenants
 	compressor     *compression.Compressor
 	stats          *Statistics
 	uploadedFiles  map[string]time.Time // Track uploaded files with timestamp
+	ledger         *UploadLedger        // uploadedFiles of earlier runs
 	metricsStorage *metrics.MetricsStorage
+	trash          *Trash
+	progress       *progress.Display
+	eta            *runEstimate
+	labels         map[string]string
+	adHoc          bool
+	dumpStarts     map[string]time.Time // when each database's last dump attempt started
+	schemaHistory  *schemahistory.Repo
+	runID          string
+	pass           int             // 1 for the main pass, higher for retry passes
+	passes         []PassResult
+	errors         []DatabaseError // failures of this run, for the run result
+	version        string          // tenangdb version, recorded in manifests
+	toolVersions   toolversion.Versions
+	impact         *impactSampler // with backup.impact
+	binlogServer   string         // names the binary log archive of the server, read once
 	mu             sync.RWMutex
+
+	// Dedup mode encryption key, shared by the backups of a run
+	generationKey *encryption.DataKey
+	generationMu  sync.Mutex
 }
 
 type Statistics struct {
@@ -34,21 +66,48 @@ type Statistics struct {
 	FailedBackups     int
 	SuccessfulUploads int
 	FailedUploads     int
+	SkippedDatabases  []string // not started because the run was cancelled
+	UnchangedDatabases []string // kept their last backup, nothing was written to them
 	StartTime         time.Time
 	EndTime           time.Time
 }
 
 func NewService(cfg *config.Config, log *logger.Logger) (*Service, error) {
+	// PostgreSQL servers are dumped by their provider
+	if cfg.Database.IsPostgreSQL() {
+		return newService(cfg, log, nil)
+	}
+
 	// Initialize database client
 	dbClient, err := database.NewClient(&cfg.Database)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create database client: %w", err)
 	}
+	dbClient.SetLogger(log)
+	dbClient.SetLargeTableRules(cfg.Backup.LargeTableRules)
+	dbClient.SetNonTransactional(cfg.Backup.NonTransactional)
+	return newService(cfg, log, dbClient)
+}
+
+func newService(cfg *config.Config, log *logger.Logger, dbClient *database.Client) (*Service, error) {
+	var err error
 
-	// Initialize uploader if enabled
+	// Initialize uploader if enabled, with one per tenant upload prefix
 	var uploader *upload.Service
+	tenantUpload := make(map[string]*upload.Service)
 	if cfg.Upload.Enabled {
 		uploader = upload.NewService(&cfg.Upload, log)
+		for _, tenant := range cfg.Tenants {
+			if tenant.UploadPrefix != "" && len(tenant.Databases) > 0 {
+				tenantUpload[tenant.Name] = upload.NewService(cfg.UploadFor(tenant.Databases[0]), log)
+			}
+		}
+	}
+	tenantSlots := make(map[string]chan struct{})
+	for _, tenant := range cfg.Tenants {
+		if tenant.Concurrency > 0 {
+			tenantSlots[tenant.Name] = make(chan struct{}, tenant.Concurrency)
+		}
 	}
 
 	// Initialize compressor
@@ -64,36 +123,146 @@ func NewService(cfg *config.Config, log *logger.Logger) (*Service, error) {
 		metricsStorage = metrics.NewMetricsStorage(metricsPath)
 	}
 
+	// A broken schema history must not stop backups
+	var history *schemahistory.Repo
+	if cfg.Backup.SchemaHistory.Enabled {
+		history, err = schemahistory.Open(context.Background(), &cfg.Backup.SchemaHistory)
+		if err != nil {
+			log.WithError(err).Warn("⚠️ Schema history disabled")
+		}
+	}
 
-	return &Service{
+	s := &Service{
 		config:         cfg,
 		logger:         log,
 		dbClient:       dbClient,
 		compressor:     compressor,
 		uploader:       uploader,
+		tenantUpload:   tenantUpload,
+		tenantSlots:    tenantSlots,
 		uploadedFiles:  make(map[string]time.Time),
+		ledger:         NewUploadLedger(cfg.StateDirectory),
+		dumpStarts:     make(map[string]time.Time),
+		schemaHistory:  history,
 		metricsStorage: metricsStorage,
+		trash:          NewTrash(cfg.Backup.Directory, cfg.Cleanup.TrashRetentionHours, log),
 		stats: &Statistics{
 			TotalDatabases: len(cfg.Backup.Databases),
 		},
-	}, nil
+	}
+	if dbClient == nil {
+		if s.provider, err = newPostgreSQLProvider(context.Background(), s); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Phases returns the progress phases each database goes through
+func (s *Service) Phases() []progress.Phase {
+	phases := []progress.Phase{progress.PhaseDump}
+	if s.config.Backup.Compression.Enabled {
+		phases = append(phases, progress.PhaseCompress)
+	}
+	if s.uploader != nil {
+		phases = append(phases, progress.PhaseUpload)
+	}
+	return phases
+}
+
+// SetLabels attaches labels to the manifests and uploads of this run
+func (s *Service) SetLabels(labels map[string]string) {
+	s.labels = labels
+	if s.uploader != nil {
+		s.uploader.SetLabels(labels)
+	}
+	for _, uploader := range s.tenantUpload {
+		uploader.SetLabels(labels)
+	}
+}
+
+// uploaderFor returns the uploader for backups of dbName, which puts them
+// below its tenant's upload prefix, or nil when uploads are off
+func (s *Service) uploaderFor(dbName string) *upload.Service {
+	if s.uploader == nil {
+		return nil
+	}
+	if tenant := s.config.TenantOf(dbName); tenant != nil {
+		if uploader, ok := s.tenantUpload[tenant.Name]; ok {
+			return uploader
+		}
+	}
+	return s.uploader
+}
+
+// tenantSlot waits until the tenant of dbName runs fewer dumps than its
+// concurrency quota and returns the function freeing the slot. It returns
+// false when the run is cancelled while waiting.
+func (s *Service) tenantSlot(ctx context.Context, dbName string) (func(), bool) {
+	tenant := s.config.TenantOf(dbName)
+	if tenant == nil || s.tenantSlots[tenant.Name] == nil {
+		return func() {}, true
+	}
+	slots := s.tenantSlots[tenant.Name]
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// SkipUpload keeps this run's backups local. They are made exactly as for
+// an upload, so 'tenangdb upload' can send them later.
+func (s *Service) SkipUpload() {
+	s.uploader = nil
+}
+
+// MarkAdHoc records this run's backups as ad-hoc. The run does not count
+// as a scheduled run for the metrics and the watchdog.
+func (s *Service) MarkAdHoc() {
+	s.adHoc = true
+}
+
+// SetProgress shows per-database progress on an interactive terminal
+func (s *Service) SetProgress(display *progress.Display) {
+	s.progress = display
+}
+
+// SetVersion records the running tenangdb version in the manifests
+func (s *Service) SetVersion(version string) {
+	s.version = version
 }
 
 func (s *Service) Run(ctx context.Context) error {
+	runID := runid.FromContext(ctx)
+
+	// Manifests record the toolchain each backup was made with
+	s.toolVersions = toolversion.Backup(s.config, s.version)
+	s.logger.WithField("tools", s.toolVersions).Debug("Detected tool versions")
+
 	s.mu.Lock()
 	s.stats.StartTime = time.Now()
+	s.runID = runID
+	s.pass = 1
 	s.mu.Unlock()
 
 	// Initialize metrics only if enabled
 	if s.config.Metrics.Enabled {
 		metrics.SetTotalDatabases(s.stats.TotalDatabases)
 		metrics.RecordBackupStart("")
+		metrics.RecordRunStart(runID)
 
 		// Update metrics storage
-		if s.metricsStorage != nil {
+		if s.metricsStorage != nil && !s.adHoc {
+			if err := s.metricsStorage.SetRunID(runID); err != nil {
+				s.logger.WithError(err).Warn("Failed to record run ID metric")
+			}
 			if err := s.metricsStorage.SetTotalDatabases(s.stats.TotalDatabases); err != nil {
 				s.logger.WithError(err).Warn("Failed to set total databases metric")
 			}
+		}
+		if s.metricsStorage != nil {
 			if err := s.metricsStorage.SetBackupProcessActive(true); err != nil {
 				s.logger.WithError(err).Warn("Failed to set backup process active metric")
 			}
@@ -103,13 +272,23 @@ func (s *Service) Run(ctx context.Context) error {
 	s.logger.WithFields(map[string]interface{}{
 		"total_databases": s.stats.TotalDatabases,
 		"backup_directory": s.config.Backup.Directory,
-		"host": s.config.Database.Host,
+		"host": s.config.Database.RecordedHost(),
 		"port": s.config.Database.Port,
 		"batch_size": s.config.Backup.BatchSize,
 		"concurrency": s.config.Backup.Concurrency,
 		"databases": s.config.Backup.Databases,
 	}).Info("🚀 Starting database backup process")
 
+	// Estimate the end of the run from earlier backup durations
+	parallelism := s.config.Backup.Concurrency
+	if s.config.Backup.BatchSize > 0 && s.config.Backup.BatchSize < parallelism {
+		parallelism = s.config.Backup.BatchSize
+	}
+	s.mu.Lock()
+	s.eta = newRunEstimate(HistoricalDurations(s.config.Backup.Directory, s.config.Backup.Databases), s.config.Backup.Databases, parallelism)
+	s.mu.Unlock()
+	s.logEstimate()
+
 	// Create backup directory if it doesn't exist
 	if err := s.createBackupDirectory(); err != nil {
 		if s.config.Metrics.Enabled {
@@ -118,6 +297,11 @@ func (s *Service) Run(ctx context.Context) error {
 		return fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
+	// Sample what the run costs the server while it lasts
+	if s.config.Backup.Impact.Enabled {
+		defer s.sampleImpact(ctx)()
+	}
+
 	// Process databases in batches
 	if err := s.processDatabasesBatch(ctx); err != nil {
 		if s.config.Metrics.Enabled {
@@ -131,6 +315,9 @@ func (s *Service) Run(ctx context.Context) error {
 		return fmt.Errorf("batch processing failed: %w", err)
 	}
 
+	// Give failed databases another chance once the others are done
+	s.retryFailed(ctx, s.config.Backup.Databases)
+
 	s.mu.Lock()
 	s.stats.EndTime = time.Now()
 	s.mu.Unlock()
@@ -143,23 +330,39 @@ func (s *Service) Run(ctx context.Context) error {
 			}
 		}
 	}
+	if s.schemaHistory != nil {
+		if err := s.schemaHistory.Push(ctx); err != nil {
+			s.logger.WithError(err).Warn("⚠️ Failed to push schema history")
+		}
+	}
+
 	s.logFinalStatistics()
 	return nil
 }
 
 func (s *Service) processDatabasesBatch(ctx context.Context) error {
-	databases := s.config.Backup.Databases
-	batchSize := s.config.Backup.BatchSize
+	databases := s.orderDatabases(ctx, s.config.Backup.Databases)
 	concurrency := s.config.Backup.Concurrency
 
-	for i := 0; i < len(databases); i += batchSize {
-		end := i + batchSize
-		if end > len(databases) {
-			end = len(databases)
+	batches, err := planBatches(databases, &s.config.Backup)
+	if err != nil {
+		return err
+	}
+
+	done := 0
+	for i, batch := range batches {
+		batchDatabases := batch.databases()
+
+		// Don't start new batches once the run is cancelled
+		if ctx.Err() != nil {
+			for _, rest := range batches[i:] {
+				s.recordSkipped(rest.databases()...)
+			}
+			break
 		}
 
-		batch := databases[i:end]
-		s.logger.WithField("batch", fmt.Sprintf("%d-%d", i+1, end)).Debug("⚙️ Processing batch")
+		s.logger.WithField("batch", fmt.Sprintf("%d-%d", done+1, done+len(batchDatabases))).Debug("⚙️ Processing batch")
+		done += len(batchDatabases)
 
 		if err := s.processBatch(ctx, batch, concurrency); err != nil {
 			s.logger.WithError(err).Error("Batch processing failed")
@@ -167,46 +370,206 @@ func (s *Service) processDatabasesBatch(ctx context.Context) error {
 		}
 
 		// Add delay between batches to reduce system load
-		if end < len(databases) {
-			time.Sleep(time.Second * 5)
+		if i < len(batches)-1 {
+			s.pause(ctx, s.config.Backup.BatchDelay)
 		}
 	}
 
+	if skipped := s.GetStatistics().SkippedDatabases; len(skipped) > 0 {
+		s.logger.WithField("skipped_databases", skipped).
+			Warn("⏹️ Backup cancelled, " + fmt.Sprintf("%d databases were not backed up", len(skipped)))
+		return fmt.Errorf("backup cancelled with %d databases skipped: %w", len(skipped), ctx.Err())
+	}
+
 	return nil
 }
 
-func (s *Service) processBatch(ctx context.Context, databases []string, concurrency int) error {
+// orderDatabases applies backup.order. Starting the largest databases
+// first keeps one big dump from running alone at the end of the run; when
+// the sizes cannot be read the config order is kept.
+func (s *Service) orderDatabases(ctx context.Context, databases []string) []string {
+	if s.config.Backup.Order == "" || s.config.Backup.Order == "config" {
+		return databases
+	}
+
+	sizes, err := s.dbClient.DatabaseSizes(ctx, databases)
+	if err != nil {
+		s.logger.WithError(err).Warn("⚠️ Failed to read database sizes, backing up in config order")
+		return databases
+	}
+	ordered := orderBySize(databases, sizes, s.config.Backup.Order)
+	s.logger.WithField("order", s.config.Backup.Order).WithField("databases", ordered).Info("📏 Ordered databases by size")
+	return ordered
+}
+
+func (s *Service) processBatch(ctx context.Context, units batch, concurrency int) error {
 	semaphore := make(chan struct{}, concurrency)
 	var wg sync.WaitGroup
 
-	for _, dbName := range databases {
-		wg.Add(1)
-		go func(database string) {
-			defer wg.Done()
+	skipRest := func(i int) {
+		s.recordSkipped(units[i:].databases()...)
+		wg.Wait()
+	}
+
+	for i, u := range units {
+		// Give up waiting for worker slots if the run is cancelled. A
+		// consistency group takes a slot for each member so they start at once.
+		for acquired := 0; acquired < len(u); acquired++ {
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				for ; acquired > 0; acquired-- {
+					<-semaphore
+				}
+				skipRest(i)
+				return nil
+			}
+		}
+
+		// Space out starts within the batch
+		if i > 0 && !s.pause(ctx, s.config.Backup.Stagger) {
+			for range u {
+				<-semaphore
+			}
+			skipRest(i)
+			return nil
+		}
 
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
+		for _, dbName := range u {
+			wg.Add(1)
+			go func(database string) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
 
-			s.processDatabase(ctx, database)
-		}(dbName)
+				release, ok := s.tenantSlot(ctx, database)
+				if !ok {
+					s.recordSkipped(database)
+					return
+				}
+				defer release()
+				s.processDatabase(ctx, database)
+			}(dbName)
+		}
 	}
 
 	wg.Wait()
+	s.checkConsistencyWindows(units)
 	return nil
 }
 
+// checkConsistencyWindows warns about consistency groups whose dumps did not
+// all start within the group's window, e.g. because a dump was retried
+func (s *Service) checkConsistencyWindows(units batch) {
+	for _, u := range units {
+		if len(u) < 2 {
+			continue
+		}
+		group := consistencyGroup(u, s.config.Backup.ConsistencyGroups)
+		if group == nil || group.Window <= 0 {
+			continue
+		}
+
+		s.mu.RLock()
+		var first, last time.Time
+		for _, dbName := range u {
+			started := s.dumpStarts[dbName]
+			if first.IsZero() || started.Before(first) {
+				first = started
+			}
+			if started.After(last) {
+				last = started
+			}
+		}
+		s.mu.RUnlock()
+
+		if spread := last.Sub(first); spread > group.Window {
+			s.logger.WithField("consistency_group", group.Name).WithField("databases", []string(u)).
+				Warn(fmt.Sprintf("⚠️ Dumps of consistency group %s started %s apart, more than its window of %s", group.Name, spread.Round(time.Second), group.Window))
+		}
+	}
+}
+
+// pause waits for delay plus a random share of backup.jitter. It returns
+// false if the context was cancelled first.
+func (s *Service) pause(ctx context.Context, delay time.Duration) bool {
+	if jitter := s.config.Backup.Jitter; jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(jitter)))
+	}
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(delay):
+		return true
+	}
+}
+
+// recordSkipped notes databases that were never started due to cancellation
+func (s *Service) recordSkipped(databases ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.SkippedDatabases = append(s.stats.SkippedDatabases, databases...)
+}
+
 func (s *Service) processDatabase(ctx context.Context, dbName string) {
 	log := s.logger.WithDatabase(dbName)
 	log.WithFields(map[string]interface{}{
 		"database": dbName,
-		"host":     s.config.Database.Host,
+		"host":     s.config.Database.RecordedHost(),
 		"port":     s.config.Database.Port,
 	}).Info("🔄 Backing up " + dbName + " database")
 
 	backupStartTime := time.Now()
+	s.progress.Start(dbName)
+	s.startEstimate(dbName, backupStartTime)
+	defer s.finishEstimate(dbName)
+
+	// Keep the last backup of a database nothing was written to since
+	if manifestPath, last := s.unchangedBackup(ctx, dbName); last != nil {
+		s.keepUnchanged(ctx, dbName, manifestPath, last, time.Since(backupStartTime))
+		s.progress.Finish(dbName, true)
+		return
+	}
+
+	// Checksum a sample of tables right before the dump, so a restore can
+	// later be compared with the source
+	var checksums map[string]uint64
+	if s.config.Backup.Checksums.Enabled {
+		checksums = s.checksumTables(ctx, dbName)
+	}
+	var rowCounts map[string]int64
+	if s.dbClient != nil {
+		var err error
+		if rowCounts, err = s.dbClient.TableRowCounts(ctx, dbName); err != nil {
+			log.WithError(err).Debug("Failed to read table row counts")
+		}
+	}
+
+	// Find missing TRIGGER and EVENT privileges before the dump tool trips
+	// over them part way through
+	skip, err := s.checkDumpPrivileges(ctx, dbName)
+
+	// Create backup with retry logic, quiescing its applications around it
+	// A heartbeat written before the dump proves it captured writes up to
+	// its start
+	var hb *heartbeat
+	if err == nil && s.config.Backup.Heartbeat.Enabled {
+		hb = s.writeHeartbeat(ctx, dbName)
+	}
 
-	// Create backup with retry logic
-	backupPath, err := s.createBackupWithRetry(ctx, dbName)
+	var backupPath string
+	if err == nil {
+		backupPath, err = s.dumpDatabase(ctx, dbName, skip)
+	}
+	var heartbeatAt *time.Time
+	if err == nil && hb != nil {
+		if err = s.checkHeartbeat(dbName, backupPath, hb); err == nil {
+			heartbeatAt = &hb.writtenAt
+		}
+	}
 	backupDuration := time.Since(backupStartTime)
 
 	if err != nil {
@@ -216,35 +579,160 @@ func (s *Service) processDatabase(ctx context.Context, dbName string) {
 			"error":    err.Error(),
 		}).Error("❌ " + dbName + " backup failed")
 		s.incrementFailedBackups()
+		s.recordError(dbName, ErrorDump, err, true)
+		s.progress.Finish(dbName, false)
 		if s.config.Metrics.Enabled {
 			metrics.RecordBackupEnd(dbName, backupDuration, false, 0)
 			if s.metricsStorage != nil {
 				if err := s.metricsStorage.UpdateBackupMetrics(dbName, backupDuration, false, 0); err != nil {
 					s.logger.WithError(err).Warn("Failed to update backup metrics")
 				}
+				if recordErr := s.metricsStorage.RecordFailure("backup", dbName, err); recordErr != nil {
+					s.logger.WithError(recordErr).Warn("Failed to record backup failure")
+				}
 			}
 		}
 		return
 	}
 
+	// Time and data of each phase, to tell slow dumps from slow compression
+	// or uploads
+	dumpSize, _ := s.getBackupSize(backupPath)
+	phases := map[string]metrics.PhaseMetrics{
+		metrics.PhaseDump: {DurationSeconds: backupDuration.Seconds(), BytesOut: dumpSize},
+	}
+
+	// mydumper writes a directory, mysqldump a single file or chunks
+	backupTool := "mysqldump"
+	if s.provider != nil {
+		backupTool = "pg_dump"
+	} else if info, err := os.Stat(backupPath); err == nil && info.IsDir() && !database.IsChunkedDump(backupPath) {
+		backupTool = "mydumper"
+	}
+
+	// Record the tables the dump holds, so verify can spot ones it left out
+	var tables map[string]int64
+	if s.dbClient != nil {
+		tables = s.dumpedTables(dbName, backupPath, rowCounts)
+	}
+	var binlog *manifest.BinlogPosition
+	if backupTool == "mydumper" {
+		binlog = s.binlogPosition(ctx, dbName, backupPath)
+	}
+
+	// Rewrite 8.0-only syntax so the backup restores on an older server
+	targetCompat := s.config.Backup.TargetCompat
+	if targetCompat != "" {
+		changed, err := database.ApplyTargetCompat(backupPath, targetCompat)
+		if err != nil {
+			log.WithError(err).Error("❌ " + dbName + " backup could not be made compatible with MySQL " + targetCompat)
+			targetCompat = ""
+		} else {
+			log.WithField("database", dbName).WithField("changed_lines", changed).Info("🔁 Rewrote backup for MySQL " + targetCompat)
+		}
+	}
+
+	// Strip or rewrite DEFINER clauses for servers missing those accounts
+	if definer := s.config.Backup.Definer; definer != "" && definer != config.DefinerKeep {
+		if changed, err := database.ApplyDefiner(backupPath, definer); err != nil {
+			log.WithError(err).Warn("⚠️ Failed to rewrite DEFINER clauses")
+		} else {
+			log.WithField("database", dbName).WithField("changed_lines", changed).Debug("Rewrote DEFINER clauses")
+		}
+	}
+
+	// Add the MySQL 8 objects the dump tools leave out
+	var coverage *manifest.Coverage
+	if s.config.Backup.ServerObjects {
+		coverage = s.captureServerObjects(ctx, dbName, backupTool, backupPath)
+	}
+
+	// Write stored objects as separate files for review, before the dump
+	// is archived
+	if s.config.Backup.ObjectFiles {
+		s.writeObjectFiles(ctx, dbName, backupPath)
+	}
+	if s.schemaHistory != nil {
+		s.recordSchemaHistory(ctx, dbName, backupStartTime)
+	}
+
 	// Compress backup if enabled
 	finalBackupPath := backupPath
-	if s.config.Backup.Compression.Enabled {
+	compressionFormat := ""
+	var streamed *streamedArchive
+	if s.config.ArchivesBackup(backupTool) && s.streamsUpload(backupPath) {
+		log.WithField("database", dbName).Info("🗜️ Compressing and uploading backup in one stream")
+		s.progress.Phase(dbName, progress.PhaseUpload)
+		archive, err := s.streamArchive(ctx, dbName, backupPath)
+		if err != nil {
+			log.WithError(err).Warn("⚠️ Streaming upload failed, compressing locally instead")
+		} else {
+			streamed = archive
+			finalBackupPath = archive.path
+			compressionFormat = s.config.Backup.Compression.Format
+			phases[metrics.PhaseStream] = metrics.PhaseMetrics{DurationSeconds: archive.duration.Seconds(), BytesIn: dumpSize, BytesOut: archive.size}
+		}
+	}
+	if streamed == nil && s.config.ArchivesBackup(backupTool) {
 		log.WithField("database", dbName).Info("🗜️ Compressing backup")
+		s.progress.Phase(dbName, progress.PhaseCompress)
+		compressStart := time.Now()
 		compressedPath, compressionErr := s.compressor.CompressBackup(backupPath)
 		if compressionErr != nil {
 			log.WithError(compressionErr).Warn("⚠️ Backup compression failed, continuing with uncompressed backup")
+			s.recordError(dbName, ErrorCompress, compressionErr, false)
 		} else {
 			finalBackupPath = compressedPath
+			// compression.auto may have picked another format
+			compressionFormat = compression.ArchiveFormat(compressedPath)
+			compressedSize, _ := s.getBackupSize(compressedPath)
+			phases[metrics.PhaseCompress] = metrics.PhaseMetrics{DurationSeconds: time.Since(compressStart).Seconds(), BytesIn: dumpSize, BytesOut: compressedSize}
 			log.WithField("database", dbName).Info("✅ Backup compression completed")
 		}
 	}
 
+	// Encrypt the artifact; a backup that should be encrypted is never
+	// kept or uploaded in clear
+	var encryptionInfo *manifest.Encryption
+	if s.config.Backup.Encryption.Enabled {
+		encryptStart := time.Now()
+		plainSize, _ := s.getBackupSize(finalBackupPath)
+		encryptedPath, info, err := s.encryptBackup(ctx, dbName, finalBackupPath)
+		if err != nil {
+			log.WithError(err).Error("❌ " + dbName + " backup could not be encrypted")
+			s.incrementFailedBackups()
+			s.recordError(dbName, ErrorEncrypt, err, true)
+			s.progress.Finish(dbName, false)
+			if s.config.Metrics.Enabled && s.metricsStorage != nil {
+				if recordErr := s.metricsStorage.RecordFailure("backup", dbName, err); recordErr != nil {
+					s.logger.WithError(recordErr).Warn("Failed to record backup failure")
+				}
+			}
+			return
+		}
+		finalBackupPath, encryptionInfo = encryptedPath, info
+		encryptedSize, _ := s.getBackupSize(encryptedPath)
+		phases[metrics.PhaseEncrypt] = metrics.PhaseMetrics{DurationSeconds: time.Since(encryptStart).Seconds(), BytesIn: plainSize, BytesOut: encryptedSize}
+	}
+
 	// Get backup size (of final path)
-	backupSize, sizeErr := s.getBackupSize(finalBackupPath)
-	if sizeErr != nil {
-		log.WithError(sizeErr).Warn("Failed to get backup size")
-		backupSize = 0
+	var backupSize int64
+	var sha256 string
+	if streamed != nil {
+		backupSize, sha256 = streamed.size, streamed.sha256
+	} else {
+		var sizeErr error
+		backupSize, sizeErr = s.getBackupSize(finalBackupPath)
+		if sizeErr != nil {
+			log.WithError(sizeErr).Warn("Failed to get backup size")
+			backupSize = 0
+		}
+	}
+
+	// Describe the artifact in a manifest next to it
+	manifestPath, manifestErr := s.writeManifest(ctx, dbName, backupTool, finalBackupPath, compressionFormat, encryptionInfo, backupStartTime, backupSize, coverage, targetCompat, checksums, tables, binlog, heartbeatAt, sha256, skip)
+	if manifestErr != nil {
+		log.WithError(manifestErr).Warn("Failed to write backup manifest")
 	}
 
 	// Format backup size
@@ -272,21 +760,37 @@ func (s *Service) processDatabase(ctx context.Context, dbName string) {
 
 	// Upload to cloud if enabled
 	if s.uploader != nil {
+		s.progress.Phase(dbName, progress.PhaseUpload)
 		uploadStartTime := time.Now()
-		if err := s.uploadBackup(ctx, finalBackupPath); err != nil {
+		var destination string
+		var err error
+		if streamed != nil {
+			// Uploaded while it was compressed
+			destination, uploadStartTime = streamed.destination, uploadStartTime.Add(-streamed.duration)
+		} else {
+			destination, err = s.uploadBackup(ctx, dbName, finalBackupPath)
+		}
+		if err != nil {
 			log.Error("❌ " + dbName + " upload failed: " + err.Error())
 			s.incrementFailedUploads()
+			s.recordError(dbName, ErrorUpload, err, false)
 			if s.config.Metrics.Enabled {
 				metrics.RecordUploadEnd(dbName, "rclone", time.Since(uploadStartTime), false, 0)
 				if s.metricsStorage != nil {
 					if err := s.metricsStorage.UpdateUploadMetrics(dbName, time.Since(uploadStartTime), false, 0); err != nil {
 						s.logger.WithError(err).Warn("Failed to update upload metrics")
 					}
+					if recordErr := s.metricsStorage.RecordFailure("upload", dbName, err); recordErr != nil {
+						s.logger.WithError(recordErr).Warn("Failed to record upload failure")
+					}
 				}
 			}
 		} else {
 			log.Info("☁️  " + dbName + " upload completed")
 			s.incrementSuccessfulUploads()
+			if streamed == nil {
+				phases[metrics.PhaseUpload] = metrics.PhaseMetrics{DurationSeconds: time.Since(uploadStartTime).Seconds(), BytesIn: backupSize, BytesOut: backupSize}
+			}
 			if s.config.Metrics.Enabled {
 				metrics.RecordUploadEnd(dbName, "rclone", time.Since(uploadStartTime), true, backupSize)
 				if s.metricsStorage != nil {
@@ -296,13 +800,89 @@ func (s *Service) processDatabase(ctx context.Context, dbName string) {
 				}
 			}
 
-			// Mark backup as uploaded for potential cleanup
-			s.markFileAsUploaded(finalBackupPath)
+			// Mark backup as uploaded for potential cleanup; of a streamed
+			// archive, only the original can be left locally
+			if streamed != nil {
+				if _, err := os.Stat(backupPath); err == nil {
+					s.markFileAsUploaded(backupPath)
+				}
+			} else {
+				s.markFileAsUploaded(finalBackupPath)
+			}
+			if err := manifest.MarkUploaded(finalBackupPath, destination, time.Now()); err != nil {
+				log.WithError(err).Warn("Failed to mark backup as uploaded")
+			}
+
+			// Keep the manifest next to the artifact in the cloud
+			if manifestErr == nil {
+				s.recordUpload(manifestPath, time.Since(uploadStartTime), destination)
+				if err := s.uploader.UploadTo(ctx, manifestPath, destination); err != nil {
+					log.WithError(err).Warn("Failed to upload backup manifest")
+				}
+			}
+		}
+	}
+
+	s.recordPhases(dbName, phases)
+	s.progress.Finish(dbName, true)
+}
+
+// recordPhases stores the time and data of each phase of the backup of
+// dbName, replacing those of its previous backup
+func (s *Service) recordPhases(dbName string, phases map[string]metrics.PhaseMetrics) {
+	if !s.config.Metrics.Enabled {
+		return
+	}
+	metrics.RecordBackupPhases(dbName, phases)
+	if s.metricsStorage != nil {
+		if err := s.metricsStorage.UpdatePhaseMetrics(dbName, phases); err != nil {
+			s.logger.WithError(err).Warn("Failed to update backup phase metrics")
+		}
+	}
+}
+
+// dumpDatabase backs up dbName while the applications with app hooks on it
+// are quiesced
+func (s *Service) dumpDatabase(ctx context.Context, dbName string, skip []string) (string, error) {
+	log := s.logger.WithDatabase(dbName)
+
+	var quiesced []*hooks.Hook
+	defer func() {
+		// Bring applications back, last quiesced first, even when the dump
+		// failed or the run was cancelled
+		resumeCtx := context.WithoutCancel(ctx)
+		for i := len(quiesced) - 1; i >= 0; i-- {
+			hook := quiesced[i]
+			if err := hook.Resume(resumeCtx); err != nil {
+				log.WithError(err).WithField("app", hook.App).Error("❌ Failed to bring " + hook.App + " back after the backup")
+				if s.metricsStorage != nil {
+					if recordErr := s.metricsStorage.RecordFailure("hook", dbName, err); recordErr != nil {
+						s.logger.WithError(recordErr).Warn("Failed to record hook failure")
+					}
+				}
+				continue
+			}
+			log.WithField("app", hook.App).Info("▶️ Resumed " + hook.App)
+		}
+	}()
+
+	for _, hook := range hooks.ForDatabase(s.config.Backup.AppHooks, dbName) {
+		log.WithField("app", hook.App).Info("⏸️ Quiescing " + hook.App + " for the backup")
+		err := hook.Quiesce(ctx)
+		// A failed command may have changed the app's state anyway
+		quiesced = append(quiesced, hook)
+		if err != nil {
+			if hook.Aborts() {
+				return "", fmt.Errorf("app hook failed, database not backed up: %w", err)
+			}
+			log.WithError(err).WithField("app", hook.App).Warn("⚠️ App hook failed, backing up anyway")
 		}
 	}
+
+	return s.createBackupWithRetry(ctx, dbName, skip)
 }
 
-func (s *Service) createBackupWithRetry(ctx context.Context, dbName string) (string, error) {
+func (s *Service) createBackupWithRetry(ctx context.Context, dbName string, skip []string) (string, error) {
 	var lastErr error
 	retryCount := s.config.Backup.RetryCount
 	retryDelay := s.config.Backup.RetryDelay
@@ -310,10 +890,24 @@ func (s *Service) createBackupWithRetry(ctx context.Context, dbName string) (str
 	for attempt := 1; attempt <= retryCount; attempt++ {
 		if attempt > 1 {
 			s.logger.WithDatabase(dbName).WithField("attempt", attempt).Info("Retrying backup")
-			time.Sleep(retryDelay)
+			select {
+			case <-ctx.Done():
+				return "", fmt.Errorf("backup cancelled before attempt %d: %w", attempt, ctx.Err())
+			case <-time.After(retryDelay):
+			}
 		}
 
-		backupPath, err := s.dbClient.CreateBackup(ctx, dbName, s.config.Backup.Directory)
+		s.mu.Lock()
+		s.dumpStarts[dbName] = time.Now()
+		s.mu.Unlock()
+
+		var backupPath string
+		var err error
+		if s.provider != nil {
+			backupPath, err = s.createPostgreSQLBackup(ctx, dbName)
+		} else {
+			backupPath, err = s.dbClient.CreateBackup(ctx, dbName, s.config.Backup.Directory, skip...)
+		}
 		if err == nil {
 			return backupPath, nil
 		}
@@ -325,13 +919,345 @@ func (s *Service) createBackupWithRetry(ctx context.Context, dbName string) (str
 	return "", fmt.Errorf("backup failed after %d attempts: %w", retryCount, lastErr)
 }
 
-func (s *Service) uploadBackup(ctx context.Context, backupPath string) error {
+// checkDumpPrivileges returns the privileges the dump of dbName has to do
+// without, following backup.missing_privileges, or an error when the
+// backup must not go ahead without them
+func (s *Service) checkDumpPrivileges(ctx context.Context, dbName string) ([]string, error) {
+	if s.dbClient == nil {
+		return nil, nil
+	}
+	log := s.logger.WithDatabase(dbName)
+	missing, err := s.dbClient.MissingDumpPrivileges(ctx, dbName)
+	if err != nil {
+		log.WithError(err).Debug("Failed to check dump privileges")
+		return nil, nil
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	if s.config.Backup.MissingPrivileges == config.MissingPrivilegesAbort {
+		return nil, fmt.Errorf("backup user lacks the %s privilege on %s; grant it, or set backup.missing_privileges: skip to back up without %s",
+			strings.Join(missing, " and "), dbName, strings.Join(omittedObjects(missing), " and "))
+	}
+	log.WithField("missing_privileges", missing).
+		Warn("⚠️ Backup user lacks the " + strings.Join(missing, " and ") + " privilege, " + dbName + " is backed up WITHOUT " + strings.Join(omittedObjects(missing), " and "))
+	return missing, nil
+}
+
+// omittedObjects names the objects a dump leaves out without privileges
+func omittedObjects(privileges []string) []string {
+	var objects []string
+	for _, privilege := range privileges {
+		switch privilege {
+		case database.PrivilegeTrigger:
+			objects = append(objects, "triggers")
+		case database.PrivilegeEvent:
+			objects = append(objects, "events")
+		}
+	}
+	return objects
+}
+
+func (s *Service) uploadBackup(ctx context.Context, dbName, backupPath string) (string, error) {
 	// Upload backup (directory or file) - upload service will handle the logic
-	return s.uploader.Upload(ctx, backupPath)
+	destination, err := s.uploaderFor(dbName).UploadWithFallback(ctx, backupPath)
+	if err == nil && destination != s.config.UploadFor(dbName).Destination {
+		s.logger.WithField("destination", destination).Warn("⚠️ Backup stored on fallback destination, run 'tenangdb upload --reconcile' once the primary is back")
+	}
+	return destination, err
+}
+
+// writeManifest records the run and artifact details of a finished backup
+func (s *Service) writeManifest(ctx context.Context, dbName, tool, finalBackupPath, compressionFormat string, encryptionInfo *manifest.Encryption, startTime time.Time, size int64, coverage *manifest.Coverage, targetCompat string, checksums map[string]uint64, tables map[string]int64, binlog *manifest.BinlogPosition, heartbeat *time.Time, sha256 string, skip []string) (string, error) {
+	m := &manifest.Manifest{
+		RunID:       runid.FromContext(ctx),
+		Database:    dbName,
+		Artifact:    filepath.Base(finalBackupPath),
+		CreatedAt:   startTime,
+		SizeBytes:   size,
+		Tool:        tool,
+		Compression: compressionFormat,
+		Host:        s.config.Database.RecordedHost(),
+		Labels:      s.labels,
+		AdHoc:       s.adHoc,
+		Coverage:    coverage,
+		Encryption:  encryptionInfo,
+
+		ToolVersions:    s.toolVersions.For(tool),
+		TableChecksums:  checksums,
+		Tables:          tables,
+		Binlog:          binlog,
+		Heartbeat:       heartbeat,
+		TargetCompat:    targetCompat,
+		DurationSeconds: time.Since(startTime).Seconds(),
+		SHA256:          sha256,
+		Seekable:        compressionFormat != "" && encryptionInfo == nil && s.config.Backup.Compression.Seekable,
+		Omitted:         omittedObjects(skip),
+	}
+
+	// Restores from a remote check downloads against the checksum; streamed
+	// archives were hashed while they were uploaded
+	if info, err := os.Stat(finalBackupPath); err == nil && !info.IsDir() && m.SHA256 == "" {
+		if m.SHA256, err = manifest.FileChecksum(finalBackupPath); err != nil {
+			s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to checksum backup")
+		}
+	}
+
+	if s.dbClient == nil {
+		return m.Write(finalBackupPath)
+	}
+
+	// Restore checks the target server can load the backup before starting
+	if version, err := s.dbClient.ServerVersion(ctx); err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to read server version")
+	} else {
+		m.ServerVersion = version
+	}
+	if collations, err := s.dbClient.UsedCollations(ctx, dbName); err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to read collations")
+	} else {
+		m.Collations = collations
+	}
+
+	// Restore uses the charset to create the target database correctly
+	if charset, exists, err := s.dbClient.DatabaseCharset(ctx, dbName); err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to read database charset")
+	} else if exists {
+		m.Charset, m.Collation = charset.Name, charset.Collation
+	}
+
+	return m.Write(finalBackupPath)
+}
+
+// encryptBackup encrypts a backup file under a new data key and removes the
+// plaintext. It returns the encrypted path.
+func (s *Service) encryptBackup(ctx context.Context, dbName, backupPath string) (string, *manifest.Encryption, error) {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return "", nil, err
+	}
+	if info.IsDir() {
+		return "", nil, fmt.Errorf("%s is a directory, only archived backups can be encrypted", backupPath)
+	}
+
+	cfg := &s.config.Backup.Encryption
+	key, err := s.dataKey(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.logger.WithDatabase(dbName).WithField("provider", cfg.Provider).WithField("mode", cfg.Mode).Info("🔐 Encrypting backup")
+	encryptedPath := backupPath + encryption.Suffix
+	if err := key.EncryptFile(backupPath, encryptedPath); err != nil {
+		return "", nil, err
+	}
+	if err := os.Remove(backupPath); err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to remove unencrypted backup")
+	}
+
+	return encryptedPath, &manifest.Encryption{Provider: cfg.Provider, KeyID: cfg.KeyID, Algorithm: encryption.Algorithm, Mode: cfg.Mode}, nil
+}
+
+// dataKey returns the key to encrypt a backup with: a new one for every
+// backup, or in dedup mode the generation key, unwrapped once per run
+func (s *Service) dataKey(ctx context.Context) (*encryption.DataKey, error) {
+	cfg := &s.config.Backup.Encryption
+	if cfg.Mode != config.EncryptionModeDedup {
+		return encryption.NewKey(ctx, cfg)
+	}
+
+	s.generationMu.Lock()
+	defer s.generationMu.Unlock()
+	if s.generationKey == nil {
+		key, err := encryption.LoadGeneration(ctx, cfg, s.config.Backup.Directory, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		s.generationKey = key
+	}
+	return s.generationKey, nil
+}
+
+// checksumTables runs CHECKSUM TABLE on a sample of the tables of dbName.
+// A failure only loses the checksums, never the backup.
+func (s *Service) checksumTables(ctx context.Context, dbName string) map[string]uint64 {
+	log := s.logger.WithDatabase(dbName)
+	start := time.Now()
+	checksums, err := s.dbClient.ChecksumTables(ctx, dbName, s.config.Backup.Checksums.SampleSize)
+	if err != nil {
+		log.WithError(err).Warn("⚠️ Failed to checksum tables")
+		return nil
+	}
+	log.WithField("tables", len(checksums)).WithField("duration", time.Since(start).Round(time.Millisecond)).
+		Debug("Checksummed tables before dump")
+	return checksums
+}
+
+// dumpedTables maps the tables in an uncompressed backup to their row
+// counts read before the dump. A failure only loses the table list.
+func (s *Service) dumpedTables(dbName, backupPath string, rowCounts map[string]int64) map[string]int64 {
+	names, err := database.DumpedTables(backupPath)
+	if err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("⚠️ Failed to list the tables in the backup")
+		return nil
+	}
+	tables := make(map[string]int64, len(names))
+	for _, name := range names {
+		tables[name] = rowCounts[name]
+	}
+	return tables
+}
+
+// binlogPosition reads the binary log coordinates from the metadata of a
+// mydumper backup, in either metadata format. It returns nil when binary
+// logging is off or the metadata can't be read.
+func (s *Service) binlogPosition(ctx context.Context, dbName, backupPath string) *manifest.BinlogPosition {
+	metadata, err := database.ParseMydumperMetadata(filepath.Join(backupPath, "metadata"))
+	if err != nil {
+		s.logger.WithDatabase(dbName).WithError(err).Warn("⚠️ Failed to read binlog coordinates from mydumper metadata")
+		return nil
+	}
+	if metadata.BinlogFile == "" {
+		return nil
+	}
+	return &manifest.BinlogPosition{Server: s.serverName(ctx), File: metadata.BinlogFile, Position: metadata.BinlogPosition, GTIDSet: metadata.GTIDSet}
+}
+
+// captureServerObjects stores roles, resource groups and histograms in the
+// backup and reports what it holds. Failures leave the backup usable, so
+// they are only logged.
+func (s *Service) captureServerObjects(ctx context.Context, dbName, tool, backupPath string) *manifest.Coverage {
+	log := s.logger.WithDatabase(dbName)
+
+	objects, err := s.dbClient.DumpServerObjects(ctx, dbName, tool)
+	if err != nil {
+		log.WithError(err).Warn("⚠️ Failed to capture server objects")
+		return nil
+	}
+	if err := database.WriteServerObjects(backupPath, objects); err != nil {
+		log.WithError(err).Warn("⚠️ Failed to store server objects")
+		return nil
+	}
+
+	log.WithField("roles", objects.Captured["roles"]).
+		WithField("resource_groups", objects.Captured["resource_groups"]).
+		WithField("histograms", objects.Captured["histograms"]).
+		Info("🧩 Captured server objects")
+	for _, missing := range objects.NotCaptured {
+		log.WithField("object", missing).Warn("⚠️ Not captured in backup")
+	}
+
+	return &manifest.Coverage{Captured: objects.Captured, NotCaptured: objects.NotCaptured}
+}
+
+// writeObjectFiles stores the stored objects of dbName as one file each in
+// a directory next to the backup. They are a copy for review, so failures
+// are only logged.
+func (s *Service) writeObjectFiles(ctx context.Context, dbName, backupPath string) {
+	log := s.logger.WithDatabase(dbName)
+
+	dir := strings.TrimSuffix(backupPath, ".sql") + database.ObjectsDirSuffix
+	counts, err := s.dbClient.DumpObjectFiles(ctx, dbName, dir)
+	if err != nil {
+		log.WithError(err).Warn("⚠️ Failed to write object files")
+		return
+	}
+
+	log.WithField("procedures", counts["procedures"]).
+		WithField("functions", counts["functions"]).
+		WithField("views", counts["views"]).
+		WithField("triggers", counts["triggers"]).
+		WithField("events", counts["events"]).
+		WithField("directory", dir).
+		Info("📄 Wrote object files")
+}
+
+// recordSchemaHistory commits the current schema of dbName to the schema
+// history, dated at the backup start. Failures leave the backup usable, so
+// they are only logged.
+func (s *Service) recordSchemaHistory(ctx context.Context, dbName string, backupTime time.Time) {
+	log := s.logger.WithDatabase(dbName)
+
+	message := fmt.Sprintf("%s: schema at %s\n\nRun: %s\nHost: %s", dbName, backupTime.Format("2006-01-02 15:04:05"), runid.FromContext(ctx), s.config.Database.RecordedHost())
+	changed, err := s.schemaHistory.Update(ctx, dbName, backupTime, message, func(dir string) error {
+		if err := s.dbClient.DumpSchema(ctx, dbName, filepath.Join(dir, "schema.sql")); err != nil {
+			return err
+		}
+		_, err := s.dbClient.DumpObjectFiles(ctx, dbName, filepath.Join(dir, "objects"))
+		return err
+	})
+	if err != nil {
+		log.WithError(err).Warn("⚠️ Failed to record schema history")
+		return
+	}
+	if changed {
+		log.Info("📜 Schema changed, committed to schema history")
+	} else {
+		log.Debug("Schema unchanged since the last backup")
+	}
+}
+
+// recordUpload adds the upload time and destination to an artifact's
+// manifest; the time lets later runs estimate their duration
+func (s *Service) recordUpload(manifestPath string, duration time.Duration, destination string) {
+	m, err := manifest.Read(manifestPath)
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to read backup manifest")
+		return
+	}
+
+	m.UploadSeconds = duration.Seconds()
+	m.Destination = destination
+	artifactPath := strings.TrimSuffix(manifestPath, manifest.Suffix)
+	if _, err := m.Write(artifactPath); err != nil {
+		s.logger.WithError(err).Warn("Failed to update backup manifest")
+	}
+}
+
+func (s *Service) startEstimate(dbName string, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.eta != nil {
+		s.eta.start(dbName, at)
+	}
+}
+
+func (s *Service) finishEstimate(dbName string) {
+	s.mu.Lock()
+	if s.eta != nil {
+		s.eta.finish(dbName)
+	}
+	s.mu.Unlock()
+
+	s.logEstimate()
+}
+
+// logEstimate reports the estimated completion time of the run
+func (s *Service) logEstimate() {
+	s.mu.RLock()
+	if s.eta == nil {
+		s.mu.RUnlock()
+		return
+	}
+	now := time.Now()
+	left, completion, ok := s.eta.remaining(now)
+	s.mu.RUnlock()
+
+	if !ok || left == 0 {
+		return
+	}
+
+	s.progress.SetETA(completion)
+	s.logger.WithFields(map[string]interface{}{
+		"databases_left": left,
+		"eta":            completion.Format(time.RFC3339),
+	}).Info(fmt.Sprintf("⏱️ Estimated completion at %s (%d databases left, ~%s)",
+		completion.Format("15:04"), left, completion.Sub(now).Round(time.Minute)))
 }
 
 func (s *Service) createBackupDirectory() error {
-	return s.dbClient.CreateDirectory(s.config.Backup.Directory)
+	return os.MkdirAll(s.config.Backup.Directory, 0755)
 }
 
 func (s *Service) incrementSuccessfulBackups() {
@@ -359,12 +1285,25 @@ func (s *Service) incrementFailedUploads() {
 }
 
 func (s *Service) logFinalStatistics() {
+	result := s.Result()
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	duration := s.stats.EndTime.Sub(s.stats.StartTime)
 
-	s.logger.WithField("statistics", map[string]interface{}{
+	message := fmt.Sprintf("%d databases backed up in %v", s.stats.SuccessfulBackups, duration.Round(time.Millisecond*100))
+	if len(result.Errors) > 0 {
+		message += fmt.Sprintf(", %d errors (%s)", len(result.Errors), result.ErrorSummary())
+	}
+	if recovered := result.Recovered(); len(recovered) > 0 {
+		message += fmt.Sprintf(", %d recovered on retry", len(recovered))
+	}
+	if len(result.Unchanged) > 0 {
+		message += fmt.Sprintf(", %d unchanged and kept", len(result.Unchanged))
+	}
+
+	s.logger.WithField("errors", result.ErrorCounts).WithField("statistics", map[string]interface{}{
 		"total_databases":    s.stats.TotalDatabases,
 		"successful_backups": s.stats.SuccessfulBackups,
 		"failed_backups":     s.stats.FailedBackups,
@@ -375,36 +1314,64 @@ func (s *Service) logFinalStatistics() {
 		"end_time":           s.stats.EndTime.Format(time.RFC3339),
 		"backup_directory":   s.config.Backup.Directory,
 		"success_rate":       fmt.Sprintf("%.1f%%", float64(s.stats.SuccessfulBackups)/float64(s.stats.TotalDatabases)*100),
-	}).Info("🗂️ " + fmt.Sprintf("%d databases backed up in %v", s.stats.SuccessfulBackups, duration.Round(time.Millisecond*100)))
+	}).Info("🗂️ " + message)
 }
 
 func (s *Service) GetStatistics() Statistics {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return *s.stats
+	stats := *s.stats
+	stats.SkippedDatabases = append([]string(nil), s.stats.SkippedDatabases...)
+	stats.UnchangedDatabases = append([]string(nil), s.stats.UnchangedDatabases...)
+	return stats
 }
 
 // markFileAsUploaded marks a file as successfully uploaded
 func (s *Service) markFileAsUploaded(filePath string) {
+	now := time.Now()
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	s.uploadedFiles[filePath] = time.Now()
+	s.uploadedFiles[filePath] = now
+	s.mu.Unlock()
+
+	if err := s.ledger.Add(filePath, now); err != nil {
+		s.logger.WithError(err).Warn("Failed to record upload in ledger")
+	}
 }
 
-// GetUploadedFiles returns list of files that were successfully uploaded
+// GetUploadedFiles returns list of files that were successfully uploaded,
+// by this service or by earlier runs recorded in the upload ledger
 func (s *Service) GetUploadedFiles() map[string]time.Time {
+	result, err := s.ledger.Load()
+	if err != nil {
+		s.logger.WithError(err).Warn("Failed to read upload ledger")
+		result = make(map[string]time.Time)
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-
-	result := make(map[string]time.Time)
 	for k, v := range s.uploadedFiles {
 		result[k] = v
 	}
 	return result
 }
 
+// ForgetUploaded drops removed files from the uploaded files and the ledger
+func (s *Service) ForgetUploaded(paths []string) {
+	s.mu.Lock()
+	for _, path := range paths {
+		delete(s.uploadedFiles, path)
+	}
+	s.mu.Unlock()
+
+	if err := s.ledger.Remove(paths); err != nil {
+		s.logger.WithError(err).Warn("Failed to update upload ledger")
+	}
+}
+
 // CleanupUploadedFiles removes local files that have been successfully uploaded
-func (s *Service) CleanupUploadedFiles(ctx context.Context) error {
+func (s *Service) CleanupUploadedFiles(ctx context.Context) (CleanupResult, error) {
+	var result CleanupResult
+
 	s.mu.RLock()
 	uploadedFiles := make(map[string]time.Time)
 	for k, v := range s.uploadedFiles {
@@ -414,49 +1381,43 @@ func (s *Service) CleanupUploadedFiles(ctx context.Context) error {
 
 	if len(uploadedFiles) == 0 {
 		s.logger.Info("No uploaded files to cleanup")
-		return nil
+		return result, nil
 	}
 
 	s.logger.WithField("files_to_cleanup", len(uploadedFiles)).Info("Starting cleanup of uploaded files")
 
-	var cleanedFiles []string
-	var totalSize int64
-
 	for filePath, uploadTime := range uploadedFiles {
 		// Only cleanup files that were uploaded more than 1 hour ago (safety buffer)
 		if time.Since(uploadTime) < time.Hour {
 			continue
 		}
 
-		if err := s.removeBackupFile(filePath); err != nil {
+		size, err := s.removeBackupFile(filePath)
+		if err != nil {
 			s.logger.WithError(err).WithField("file", filePath).Error("Failed to remove uploaded file")
 			continue
 		}
 
-		cleanedFiles = append(cleanedFiles, filePath)
+		result.Add(filePath, size)
 		s.logger.WithField("file", filePath).Info("Removed uploaded backup file")
 	}
 
 	// Remove cleaned files from tracking
-	s.mu.Lock()
-	for _, filePath := range cleanedFiles {
-		delete(s.uploadedFiles, filePath)
-	}
-	s.mu.Unlock()
+	s.ForgetUploaded(result.Files)
 
 	s.logger.WithField("cleanup_stats", map[string]interface{}{
-		"files_cleaned": len(cleanedFiles),
-		"total_size_mb": totalSize / (1024 * 1024),
+		"files_cleaned": result.FilesRemoved,
+		"total_size_mb": result.BytesFreed / (1024 * 1024),
 	}).Info("Cleanup of uploaded files completed")
 
-	return nil
+	return result, nil
 }
 
-// removeBackupFile safely removes a backup file with size calculation
-func (s *Service) removeBackupFile(backupPath string) error {
+// removeBackupFile safely removes a backup file and returns its size
+func (s *Service) removeBackupFile(backupPath string) (int64, error) {
 	info, err := os.Stat(backupPath)
 	if err != nil {
-		return fmt.Errorf("failed to stat backup path: %w", err)
+		return 0, fmt.Errorf("failed to stat backup path: %w", err)
 	}
 
 	var totalSize int64
@@ -468,19 +1429,19 @@ func (s *Service) removeBackupFile(backupPath string) error {
 			totalSize = 0
 		}
 
-		if err := os.RemoveAll(backupPath); err != nil {
-			return fmt.Errorf("failed to remove directory: %w", err)
+		if err := s.trash.Remove(backupPath); err != nil {
+			return 0, fmt.Errorf("failed to remove directory: %w", err)
 		}
 	} else {
 		// For mysqldump files, remove single file
 		totalSize = info.Size()
-		if err := os.Remove(backupPath); err != nil {
-			return fmt.Errorf("failed to remove file: %w", err)
+		if err := s.trash.Remove(backupPath); err != nil {
+			return 0, fmt.Errorf("failed to remove file: %w", err)
 		}
 	}
 
 	s.logger.WithField("backup_size_mb", totalSize/(1024*1024)).Debug("Backup removed successfully")
-	return nil
+	return totalSize, nil
 }
 
 func (s *Service) calculateDirectorySize(dirPath string) (int64, error) {
@@ -0,0 +1,81 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"time"
+)
+
+// streamedArchive is an archive that was uploaded while it was written, and
+// never stored as a local file
+type streamedArchive struct {
+	path        string // where CompressBackup would have put it, names the remote copy
+	destination string
+	size        int64
+	sha256      string
+	duration    time.Duration
+}
+
+// streamsUpload reports whether backupPath is archived straight into the
+// upload. Encrypted backups, those below stream_min_size_mb and all of them
+// with upload.split_size_gb, whose parts need a local file to cut from,
+// take the local archive path.
+func (s *Service) streamsUpload(backupPath string) bool {
+	compression := s.config.Backup.Compression
+	if !compression.StreamUpload || s.uploader == nil || s.config.Backup.Encryption.Enabled || s.config.Upload.SplitSizeGB > 0 || s.config.Upload.HasPolicies() {
+		return false
+	}
+	size, err := s.getBackupSize(backupPath)
+	return err == nil && size >= int64(compression.StreamMinSizeMB)*1024*1024
+}
+
+// streamArchive pipes the archive of backupPath into rclone rcat, so
+// compression and upload run at the same time. The original is removed
+// afterwards unless keep_original is set.
+func (s *Service) streamArchive(ctx context.Context, dbName, backupPath string) (*streamedArchive, error) {
+	if err := s.compressor.CanStream(); err != nil {
+		return nil, err
+	}
+	archivePath, err := s.compressor.ArchivePath(backupPath)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	archive := &streamedArchive{path: archivePath}
+	archive.destination, err = s.uploaderFor(dbName).StreamWithFallback(ctx, archivePath, func(w io.Writer) error {
+		// Each attempt starts over, so count and hash per attempt
+		hash := sha256.New()
+		counter := &countingWriter{}
+		err := s.compressor.WriteArchive(ctx, backupPath, io.MultiWriter(w, hash, counter))
+		archive.size, archive.sha256 = counter.n, hex.EncodeToString(hash.Sum(nil))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	archive.duration = time.Since(start)
+	if archive.destination != s.config.Upload.Destination {
+		s.logger.WithField("destination", archive.destination).Warn("⚠️ Backup stored on fallback destination, run 'tenangdb upload --reconcile' once the primary is back")
+	}
+
+	if !s.config.Backup.Compression.KeepOriginal {
+		if err := os.RemoveAll(backupPath); err != nil {
+			s.logger.WithDatabase(dbName).WithError(err).Warn("Failed to remove original backup directory")
+		}
+	}
+	return archive, nil
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+// TrashDirName is the directory inside the backup directory that holds
+// backups removed by cleanup until trash_retention_hours have passed
+const TrashDirName = ".trash"
+
+const trashBatchFormat = "2006-01-02_15-04-05"
+
+// Trash implements two-phase deletion of backups. Each cleanup run moves its
+// files into .trash/{run time}/ keeping their path relative to the backup
+// directory, so a mistaken cleanup can be undone by moving them back.
+type Trash struct {
+	backupDir string
+	retention time.Duration
+	batch     string
+	logger    *logger.Logger
+}
+
+// NewTrash returns a trash for backupDir; retentionHours <= 0 disables it
+// and files are deleted immediately
+func NewTrash(backupDir string, retentionHours int, log *logger.Logger) *Trash {
+	return &Trash{
+		backupDir: backupDir,
+		retention: time.Duration(retentionHours) * time.Hour,
+		batch:     time.Now().Format(trashBatchFormat),
+		logger:    log,
+	}
+}
+
+// Enabled reports whether removed files are kept in the trash
+func (t *Trash) Enabled() bool {
+	return t.retention > 0
+}
+
+// Dir returns the trash directory
+func (t *Trash) Dir() string {
+	return filepath.Join(t.backupDir, TrashDirName)
+}
+
+// Remove moves path into the trash, or deletes it when the trash is disabled
+func (t *Trash) Remove(path string) error {
+	if !t.Enabled() {
+		return os.RemoveAll(path)
+	}
+
+	relPath, err := filepath.Rel(t.backupDir, path)
+	if err != nil || strings.HasPrefix(relPath, "..") {
+		relPath = filepath.Base(path)
+	}
+
+	target := filepath.Join(t.Dir(), t.batch, relPath)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("failed to move %s to trash: %w", path, err)
+	}
+
+	t.logger.WithField("file", path).WithField("trash", target).Debug("Moved backup to trash")
+	return nil
+}
+
+// Expired returns trash batches older than the retention period
+func (t *Trash) Expired() ([]string, error) {
+	entries, err := os.ReadDir(t.Dir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var expired []string
+	for _, entry := range entries {
+		removedAt, err := time.ParseInLocation(trashBatchFormat, entry.Name(), time.Local)
+		if err != nil {
+			continue // not ours
+		}
+		if time.Since(removedAt) >= t.retention {
+			expired = append(expired, filepath.Join(t.Dir(), entry.Name()))
+		}
+	}
+
+	return expired, nil
+}
+
+// Purge permanently deletes trash batches older than the retention period.
+// With the trash disabled, anything left over from earlier runs is purged.
+func (t *Trash) Purge() (int, error) {
+	expired, err := t.Expired()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+	return t.PurgeBatches(expired)
+}
+
+// PurgeBatches permanently deletes the given trash batches, as returned by
+// Expired
+func (t *Trash) PurgeBatches(expired []string) (int, error) {
+	purged := 0
+	for _, batch := range expired {
+		if err := os.RemoveAll(batch); err != nil {
+			return purged, fmt.Errorf("failed to purge %s: %w", batch, err)
+		}
+		purged++
+		t.logger.WithField("batch", filepath.Base(batch)).Info("🗑️ Purged expired trash")
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+	"github.com/abdullahainun/tenangdb/internal/runid"
+)
+
+// lastBackup returns the manifest path and manifest of the newest scheduled
+// backup of dbName, or "" when there is none
+func lastBackup(backupDir, dbName string) (string, *manifest.Manifest) {
+	paths, err := manifestsOf(backupDir, dbName)
+	if err != nil {
+		return "", nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(paths)))
+
+	for _, path := range paths {
+		m, err := manifest.Read(path)
+		if err != nil || m.Database != dbName || m.AdHoc {
+			continue
+		}
+		return path, m
+	}
+	return "", nil
+}
+
+// unchangedBackup returns the manifest path of the last backup of dbName
+// when backup.skip_unchanged is on and nothing was written to the database
+// since that backup started. Anything it cannot confirm counts as a change.
+func (s *Service) unchangedBackup(ctx context.Context, dbName string) (string, *manifest.Manifest) {
+	cfg := s.config.Backup.SkipUnchanged
+	if !cfg.Enabled || s.adHoc {
+		return "", nil
+	}
+	log := s.logger.WithDatabase(dbName)
+
+	path, m := lastBackup(s.config.Backup.Directory, dbName)
+	switch {
+	case m == nil:
+		return "", nil
+	case time.Since(m.CreatedAt) > cfg.MaxAge:
+		log.Debug("Last backup is older than backup.skip_unchanged.max_age, taking a new one")
+		return "", nil
+	case s.uploader != nil && m.Destination == "":
+		log.Debug("Last backup was not uploaded, taking a new one")
+		return "", nil
+	case m.Tables == nil:
+		log.Debug("Last backup has no table list, taking a new one")
+		return "", nil
+	}
+
+	// Nothing was written anywhere on the server while the binary log
+	// stayed at the position of the last dump
+	if m.Binlog != nil {
+		file, position, err := s.dbClient.BinlogCoordinates(ctx)
+		if err == nil && file == m.Binlog.File && position == m.Binlog.Position {
+			return path, m
+		}
+	}
+
+	tables := make([]string, 0, len(m.Tables))
+	for table := range m.Tables {
+		tables = append(tables, table)
+	}
+	// The heartbeat of the last run would always count as a write
+	var ignore []string
+	if s.config.Backup.Heartbeat.Enabled {
+		ignore = append(ignore, s.config.Backup.Heartbeat.Table)
+	}
+	reason, err := s.dbClient.WrittenSince(ctx, dbName, m.CreatedAt, tables, ignore...)
+	if err != nil {
+		log.WithError(err).Warn("⚠️ Failed to check for changes, taking a new backup")
+		return "", nil
+	}
+	if reason != "" {
+		log.WithField("reason", reason).Debug("Database changed since its last backup")
+		return "", nil
+	}
+	return path, m
+}
+
+// keepUnchanged records in the manifest of the last backup of dbName that
+// this run found the database unchanged and kept that backup, and counts it
+// as backed up
+func (s *Service) keepUnchanged(ctx context.Context, dbName, manifestPath string, m *manifest.Manifest, checkDuration time.Duration) {
+	log := s.logger.WithDatabase(dbName)
+	log.WithField("artifact", m.Artifact).
+		Info("⏭️ " + dbName + " unchanged since its backup of " + m.CreatedAt.Format("2006-01-02 15:04") + ", keeping it")
+
+	m.Unchanged = append(m.Unchanged, manifest.UnchangedCheck{RunID: runid.FromContext(ctx), CheckedAt: time.Now()})
+	if _, err := m.Write(strings.TrimSuffix(manifestPath, manifest.Suffix)); err != nil {
+		log.WithError(err).Warn("Failed to update backup manifest")
+	} else if s.uploader != nil {
+		if err := s.uploader.UploadTo(ctx, manifestPath, m.Destination); err != nil {
+			log.WithError(err).Warn("Failed to upload backup manifest")
+		}
+	}
+
+	s.mu.Lock()
+	s.stats.SuccessfulBackups++
+	s.stats.UnchangedDatabases = append(s.stats.UnchangedDatabases, dbName)
+	s.mu.Unlock()
+
+	// The database is as protected as after a new backup, so freshness
+	// alerts stay quiet
+	if s.config.Metrics.Enabled {
+		metrics.RecordBackupEnd(dbName, checkDuration, true, m.SizeBytes)
+		if s.metricsStorage != nil {
+			if err := s.metricsStorage.UpdateBackupMetrics(dbName, checkDuration, true, m.SizeBytes); err != nil {
+				s.logger.WithError(err).Warn("Failed to update backup metrics")
+			}
+		}
+	}
+}
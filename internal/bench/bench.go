@@ -0,0 +1,126 @@
+package bench
+
+import (
+	"compress/gzip"
+	"math"
+	"time"
+)
+
+// minCompressInput is how much data each level compresses at least, so
+// small samples still give a stable throughput
+const minCompressInput = 64 * 1024 * 1024
+
+// Levels are the gzip levels compared by Compression
+var Levels = []int{1, 3, 6, 9}
+
+// CompressionResult is the gzip throughput of one level
+type CompressionResult struct {
+	Level int     `json:"level"`
+	MBps  float64 `json:"mb_per_second"` // uncompressed input per second
+	Ratio float64 `json:"ratio"`         // compressed size / original size
+}
+
+// Compression compresses sample with each gzip level, as the backup
+// compressor does, and measures speed and ratio
+func Compression(sample []byte, levels []int) ([]CompressionResult, error) {
+	if len(sample) == 0 {
+		return nil, nil
+	}
+
+	results := make([]CompressionResult, 0, len(levels))
+	for _, level := range levels {
+		var out countingWriter
+		w, err := gzip.NewWriterLevel(&out, level)
+		if err != nil {
+			return nil, err
+		}
+
+		var input int64
+		start := time.Now()
+		for input < minCompressInput {
+			if _, err := w.Write(sample); err != nil {
+				return nil, err
+			}
+			input += int64(len(sample))
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		results = append(results, CompressionResult{
+			Level: level,
+			MBps:  MBps(input, time.Since(start)),
+			Ratio: float64(out.n) / float64(input),
+		})
+	}
+	return results, nil
+}
+
+// Recommendation is a suggested backup configuration for this host
+type Recommendation struct {
+	Concurrency      int `json:"concurrency"`
+	CompressionLevel int `json:"compression_level"`
+}
+
+// Recommend picks the best compressing gzip level that still keeps up with
+// one dump stream, and enough parallel dumps to fill the upload bandwidth
+// with compressed data, without using more streams than CPUs. uploadMBps
+// is 0 when uploads were not measured.
+func Recommend(dumpMBps float64, compression []CompressionResult, uploadMBps float64, cpus int) Recommendation {
+	rec := Recommendation{Concurrency: 1}
+
+	ratio := 1.0
+	if len(compression) > 0 {
+		best := -1
+		fastest := 0
+		for i, result := range compression {
+			if result.MBps > compression[fastest].MBps {
+				fastest = i
+			}
+			if result.MBps >= dumpMBps && (best < 0 || result.Ratio < compression[best].Ratio) {
+				best = i
+			}
+		}
+		if best < 0 {
+			best = fastest
+		}
+		rec.CompressionLevel = compression[best].Level
+		ratio = compression[best].Ratio
+	}
+
+	maxStreams := cpus / 2
+	if maxStreams < 1 {
+		maxStreams = 1
+	}
+	switch {
+	case dumpMBps <= 0:
+	case uploadMBps > 0:
+		rec.Concurrency = int(math.Ceil(uploadMBps / (dumpMBps * ratio)))
+	default:
+		rec.Concurrency = maxStreams
+	}
+	if rec.Concurrency > maxStreams {
+		rec.Concurrency = maxStreams
+	}
+	if rec.Concurrency < 1 {
+		rec.Concurrency = 1
+	}
+	return rec
+}
+
+// MBps returns a throughput in megabytes per second
+func MBps(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) / (1024 * 1024) / elapsed.Seconds()
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,34 @@
+package bench
+
+import "testing"
+
+func TestRecommend(t *testing.T) {
+	compression := []CompressionResult{
+		{Level: 1, MBps: 120, Ratio: 0.30},
+		{Level: 6, MBps: 40, Ratio: 0.20},
+		{Level: 9, MBps: 10, Ratio: 0.19},
+	}
+
+	tests := []struct {
+		name        string
+		dumpMBps    float64
+		uploadMBps  float64
+		cpus        int
+		concurrency int
+		level       int
+	}{
+		{"fast dump skips slow levels", 50, 0, 8, 4, 1},
+		{"slow dump affords best ratio", 8, 0, 8, 4, 9},
+		{"upload bound", 25, 10, 16, 2, 6},
+		{"capped by cpus", 25, 1000, 4, 2, 6},
+		{"single cpu", 25, 0, 1, 1, 6},
+		{"dump faster than every level", 500, 0, 8, 4, 1},
+	}
+
+	for _, tt := range tests {
+		rec := Recommend(tt.dumpMBps, compression, tt.uploadMBps, tt.cpus)
+		if rec.Concurrency != tt.concurrency || rec.CompressionLevel != tt.level {
+			t.Errorf("%s: got concurrency %d, level %d; want %d, %d", tt.name, rec.Concurrency, rec.CompressionLevel, tt.concurrency, tt.level)
+		}
+	}
+}
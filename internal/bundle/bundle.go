@@ -0,0 +1,236 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// Names of the generated files inside a bundle
+const (
+	ChecksumsFile = "SHA256SUMS"
+	AuditFile     = "audit.log"
+	InfoFile      = "bundle.json"
+)
+
+// Info describes a bundle in its bundle.json
+type Info struct {
+	BackupID     string     `json:"backup_id"`
+	Database     string     `json:"database"`
+	RunID        string     `json:"run_id"`
+	ExportedAt   time.Time  `json:"exported_at"`
+	ExportedBy   string     `json:"exported_by"` // tenangdb version
+	AuditEntries int        `json:"audit_entries"`
+	Encryption   Encryption `json:"encryption"`
+}
+
+// Encryption records how the artifact is protected at rest, and the key
+// needed to decrypt it
+type Encryption struct {
+	Encrypted bool   `json:"encrypted"`
+	Provider  string `json:"provider,omitempty"`
+	KeyID     string `json:"key_id,omitempty"`
+}
+
+// Export writes a tar bundle of a backup to out: the artifact, its manifest,
+// the log lines of the run that created it, bundle.json and SHA256SUMS over
+// all of them. Everything lives below a {backup id}/ directory, so
+// `sha256sum -c SHA256SUMS` verifies an extracted bundle.
+func Export(entry *catalog.Entry, logFile, version, out string) (*Info, error) {
+	info := &Info{
+		BackupID:   entry.ID,
+		Database:   entry.Manifest.Database,
+		RunID:      entry.Manifest.RunID,
+		ExportedAt: time.Now().UTC(),
+		ExportedBy: version,
+	}
+	if enc := entry.Manifest.Encryption; enc != nil {
+		info.Encryption = Encryption{Encrypted: true, Provider: enc.Provider, KeyID: enc.KeyID}
+	}
+
+	audit, entries, err := auditEntries(logFile, entry.Manifest.RunID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit entries: %w", err)
+	}
+	info.AuditEntries = entries
+
+	tempPath := out + ".tmp"
+	file, err := os.Create(tempPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer os.Remove(tempPath)
+
+	w := &writer{tw: tar.NewWriter(file), root: entry.ID, modTime: info.ExportedAt, sums: make(map[string]string)}
+
+	if err := w.addPath(entry.ArtifactPath); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to add artifact: %w", err)
+	}
+	if err := w.addPath(manifest.PathFor(entry.ArtifactPath)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to add manifest: %w", err)
+	}
+	if err := w.addBytes(AuditFile, audit); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	infoData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := w.addBytes(InfoFile, append(infoData, '\n')); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := w.addBytes(ChecksumsFile, w.checksums()); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	if err := w.tw.Close(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if err := os.Rename(tempPath, out); err != nil {
+		return nil, fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	return info, nil
+}
+
+// auditEntries collects the log lines tagged with runID
+func auditEntries(logFile, runID string) ([]byte, int, error) {
+	if logFile == "" || runID == "" {
+		return nil, 0, nil
+	}
+
+	file, err := os.Open(logFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var buf bytes.Buffer
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), runID) {
+			buf.Write(scanner.Bytes())
+			buf.WriteByte('\n')
+			count++
+		}
+	}
+	return buf.Bytes(), count, scanner.Err()
+}
+
+// writer adds files below root and remembers their checksums
+type writer struct {
+	tw      *tar.Writer
+	root    string
+	modTime time.Time // of generated files
+	sums    map[string]string
+}
+
+// addPath adds a file or directory tree under its base name
+func (w *writer) addPath(path string) error {
+	base := filepath.Dir(path)
+	return filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() && !fi.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(base, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		header, err := tar.FileInfoHeader(fi, "")
+		if err != nil {
+			return err
+		}
+		header.Name = w.root + "/" + rel
+		if fi.IsDir() {
+			header.Name += "/"
+			return w.tw.WriteHeader(header)
+		}
+		if err := w.tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		file, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		hash := sha256.New()
+		if _, err := io.Copy(io.MultiWriter(w.tw, hash), file); err != nil {
+			return err
+		}
+		w.sums[rel] = hex.EncodeToString(hash.Sum(nil))
+		return nil
+	})
+}
+
+// addBytes adds a generated file
+func (w *writer) addBytes(name string, data []byte) error {
+	header := &tar.Header{
+		Name:    w.root + "/" + name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: w.modTime,
+	}
+	if err := w.tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.tw.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+
+	sum := sha256.Sum256(data)
+	w.sums[name] = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// checksums renders the collected checksums in sha256sum format
+func (w *writer) checksums() []byte {
+	names := make([]string, 0, len(w.sums))
+	for name := range w.sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buf, "%s  %s\n", w.sums[name], name)
+	}
+	return buf.Bytes()
+}
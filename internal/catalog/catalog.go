@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// trashDirName mirrors backup.TrashDirName; removed backups are not listed
+const trashDirName = ".trash"
+
+// idPattern extracts {database}-{YYYY-MM-DD_HH-MM-SS} from an artifact name
+var idPattern = regexp.MustCompile(`^(.+-\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2})`)
+
+var labelKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// Entry is a local backup described by its manifest
+type Entry struct {
+	ID           string // {database}-{timestamp}, stable across compression formats
+	ArtifactPath string
+	Manifest     *manifest.Manifest
+}
+
+// IDFor returns the backup ID of an artifact name
+func IDFor(artifactName string) string {
+	if m := idPattern.FindStringSubmatch(artifactName); m != nil {
+		return m[1]
+	}
+	return artifactName
+}
+
+// Scan reads every manifest below backupDir, newest first
+func Scan(backupDir string) ([]Entry, error) {
+	var entries []Entry
+
+	err := filepath.WalkDir(backupDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == trashDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !manifest.IsManifest(path) {
+			return nil
+		}
+
+		m, err := manifest.Read(path)
+		if err != nil {
+			return nil // not ours or corrupt; skip it
+		}
+		entries = append(entries, Entry{
+			ID:           IDFor(m.Artifact),
+			ArtifactPath: strings.TrimSuffix(path, manifest.Suffix),
+			Manifest:     m,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Manifest.CreatedAt.After(entries[j].Manifest.CreatedAt)
+	})
+	return entries, nil
+}
+
+// Find returns the backup with the given ID
+func Find(backupDir, id string) (*Entry, error) {
+	entries, err := Scan(backupDir)
+	if err != nil {
+		return nil, err
+	}
+	for i := range entries {
+		if entries[i].ID == id {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("backup %s not found in %s", id, backupDir)
+}
+
+// HasLabels reports whether the backup carries all of the given labels
+func (e Entry) HasLabels(labels map[string]string) bool {
+	for key, value := range labels {
+		if e.Manifest.Labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabels parses key=value pairs given on the command line
+func ParseLabels(pairs []string) (map[string]string, error) {
+	labels := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || !labelKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid label %q: expected key=value with a key of letters, digits, '.', '_' or '-'", pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// FormatLabels renders labels as sorted key=value pairs
+func FormatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
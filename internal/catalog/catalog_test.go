@@ -0,0 +1,79 @@
+package catalog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+func TestIDFor(t *testing.T) {
+	tests := map[string]string{
+		"app-2025-07-05_10-30-15.sql":     "app-2025-07-05_10-30-15",
+		"app-2025-07-05_10-30-15.tar.zst": "app-2025-07-05_10-30-15",
+		"my-app-2025-07-05_10-30-15":      "my-app-2025-07-05_10-30-15",
+		"not-a-backup.sql":                "not-a-backup.sql",
+	}
+	for name, want := range tests {
+		if got := IDFor(name); got != want {
+			t.Errorf("IDFor(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	labels, err := ParseLabels([]string{"ticket=OPS-123", "reason=pre-migration", "note="})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels["ticket"] != "OPS-123" || labels["reason"] != "pre-migration" || labels["note"] != "" {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+
+	for _, bad := range []string{"ticket", "=value", "bad key=x"} {
+		if _, err := ParseLabels([]string{bad}); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestScanSkipsTrash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(rel string, labels map[string]string) {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		m := &manifest.Manifest{Database: "app", Artifact: filepath.Base(path), CreatedAt: time.Now(), Labels: labels}
+		if _, err := m.Write(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("app/2025-07/app-2025-07-05_10-30-15.sql", map[string]string{"reason": "pre-migration"})
+	write("app/2025-07/app-2025-07-06_10-30-15.sql", nil)
+	write(".trash/2025-07-07_00-00-00/app/2025-07/app-2025-07-01_10-30-15.sql", nil)
+
+	entries, err := Scan(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 backups, got %d", len(entries))
+	}
+
+	filter := map[string]string{"reason": "pre-migration"}
+	matched := 0
+	for _, e := range entries {
+		if e.HasLabels(filter) {
+			matched++
+			if e.ID != "app-2025-07-05_10-30-15" {
+				t.Errorf("unexpected match %s", e.ID)
+			}
+		}
+	}
+	if matched != 1 {
+		t.Errorf("expected 1 labelled backup, got %d", matched)
+	}
+}
@@ -0,0 +1,156 @@
+package compression
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+// autoSampleSize is how much of a backup is read to pick a format and level
+const autoSampleSize = 8 * 1024 * 1024
+
+// autoLevels are the levels compared by compression.auto, in each format's
+// own scale: gzip 1-9, zstd 1-19
+var autoLevels = map[string][]int{
+	formatTarGz:  {1, 3, 6, 9},
+	formatTarZst: {1, 3, 9, 19},
+}
+
+// levelTrial is the result of compressing the sample with one format and
+// level
+type levelTrial struct {
+	format  string
+	level   int
+	size    int64
+	elapsed time.Duration
+}
+
+// autoSetting compresses a sample of backupPath with each level of formats
+// and picks a format and level for the goal. It returns false when nothing
+// could be sampled.
+func (c *Compressor) autoSetting(backupPath string, formats []string) (levelTrial, bool) {
+	sample, err := readSample(backupPath, autoSampleSize)
+	if err != nil || len(sample) == 0 {
+		if err != nil {
+			c.logger.WithError(err).Warn("Failed to sample backup for compression level selection")
+		}
+		return levelTrial{}, false
+	}
+
+	var trials []levelTrial
+	for _, format := range formats {
+		for _, level := range autoLevels[format] {
+			trial, err := tryLevel(sample, format, level)
+			if err != nil {
+				c.logger.WithError(err).Warn("Failed to sample backup for compression level selection")
+				return levelTrial{}, false
+			}
+			trials = append(trials, trial)
+		}
+	}
+	if len(trials) == 0 {
+		return levelTrial{}, false
+	}
+
+	best := pickLevel(trials, c.config.Goal)
+	c.logger.WithField("format", best.format).WithField("level", best.level).WithField("goal", c.config.Goal).
+		Debug("Selected compression format and level")
+	return best, true
+}
+
+func tryLevel(sample []byte, format string, level int) (levelTrial, error) {
+	var out countingWriter
+	var w io.WriteCloser
+	var err error
+	if format == formatTarZst {
+		w, err = zstd.NewWriter(&out, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	} else {
+		w, err = gzip.NewWriterLevel(&out, level)
+	}
+	if err != nil {
+		return levelTrial{}, err
+	}
+	start := time.Now()
+	if _, err := w.Write(sample); err != nil {
+		return levelTrial{}, err
+	}
+	if err := w.Close(); err != nil {
+		return levelTrial{}, err
+	}
+	return levelTrial{format: format, level: level, size: out.n, elapsed: time.Since(start)}, nil
+}
+
+// pickLevel chooses the smallest output for "size", the fastest trial for
+// "speed", and for "balanced" the smallest output among the trials taking
+// at most twice as long as the fastest one
+func pickLevel(trials []levelTrial, goal string) levelTrial {
+	fastest, smallest := trials[0], trials[0]
+	for _, t := range trials[1:] {
+		if t.elapsed < fastest.elapsed {
+			fastest = t
+		}
+		if t.size < smallest.size {
+			smallest = t
+		}
+	}
+
+	switch goal {
+	case config.CompressionGoalSize:
+		return smallest
+	case config.CompressionGoalSpeed:
+		return fastest
+	}
+
+	best := fastest
+	for _, t := range trials {
+		if t.elapsed <= 2*fastest.elapsed && t.size < best.size {
+			best = t
+		}
+	}
+	return best
+}
+
+// readSample reads up to limit bytes from a backup file, or from the
+// files of a backup directory in walk order
+func readSample(backupPath string, limit int64) ([]byte, error) {
+	var sample []byte
+	err := filepath.Walk(backupPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Already compressed files are stored as they are
+		if !info.Mode().IsRegular() || isCompressedContent(path) {
+			return nil
+		}
+		if int64(len(sample)) >= limit {
+			return filepath.SkipAll
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(io.LimitReader(file, limit-int64(len(sample))))
+		if err != nil {
+			return err
+		}
+		sample = append(sample, data...)
+		return nil
+	})
+	return sample, err
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,87 @@
+package compression
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+func TestPickLevel(t *testing.T) {
+	trials := []levelTrial{
+		{format: "tar.gz", level: 1, size: 300, elapsed: 10 * time.Millisecond},
+		{format: "tar.gz", level: 6, size: 220, elapsed: 20 * time.Millisecond},
+		{format: "tar.gz", level: 9, size: 215, elapsed: 80 * time.Millisecond},
+		{format: "tar.zst", level: 1, size: 240, elapsed: 8 * time.Millisecond},
+		{format: "tar.zst", level: 3, size: 210, elapsed: 12 * time.Millisecond},
+		{format: "tar.zst", level: 19, size: 180, elapsed: 200 * time.Millisecond},
+	}
+
+	tests := map[string]string{
+		"size":     "tar.zst 19",
+		"speed":    "tar.zst 1",
+		"balanced": "tar.zst 3",
+	}
+	for goal, want := range tests {
+		best := pickLevel(trials, goal)
+		if got := fmt.Sprintf("%s %d", best.format, best.level); got != want {
+			t.Errorf("pickLevel(%s) = %s, want %s", goal, got, want)
+		}
+	}
+
+	// Among gzip levels alone, balanced keeps the gzip choice
+	if best := pickLevel(trials[:3], "balanced"); best.format != "tar.gz" || best.level != 6 {
+		t.Errorf("pickLevel(balanced) of gzip = %s %d, want tar.gz 6", best.format, best.level)
+	}
+}
+
+func TestCompressBackupAutoPicksZstd(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "app-2025-07-05_02-00-00")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	var dump bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&dump, "INSERT INTO `orders` VALUES (%d,'customer-%d','2025-07-%02d',%d.%02d);\n", i, i%977, i%28+1, i*7%1000, i%100)
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, "app.orders.sql"), dump.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// zstd's top level beats gzip 9 on SQL text, so the smallest archive
+	// is a tar.zst although tar.gz is configured
+	cfg := &config.CompressionConfig{Enabled: true, Format: "tar.gz", Level: 6, Auto: true, Goal: config.CompressionGoalSize, KeepOriginal: true}
+	compressor := NewCompressor(cfg, logger.NewLogger("error"))
+	archive, err := compressor.CompressBackup(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if archive != backupDir+".tar.zst" || ArchiveFormat(archive) != "tar.zst" {
+		t.Fatalf("archive = %s, want a tar.zst", archive)
+	}
+	if DetectFormat(archive) != "zstd" {
+		t.Errorf("archive content is %q, want zstd", DetectFormat(archive))
+	}
+
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Skip("zstd not installed")
+	}
+	if err := os.RemoveAll(backupDir); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := compressor.DecompressBackup(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join(restored, filepath.Base(backupDir), "app.orders.sql"))
+	if err != nil || !bytes.Equal(got, dump.Bytes()) {
+		t.Errorf("restored dump differs (err %v)", err)
+	}
+}
@@ -2,7 +2,9 @@ package compression
 
 import (
 	"archive/tar"
+	"bufio"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -12,12 +14,23 @@ import (
 
 	"github.com/abdullahainun/tenangdb/internal/config"
 	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/klauspost/compress/zstd"
 )
 
+// ErrExtractLimit is returned when decompressing a backup would write more
+// than the extraction size limit
+var ErrExtractLimit = errors.New("backup exceeds the extraction size limit")
+
 // Compressor handles backup compression operations
 type Compressor struct {
-	config *config.CompressionConfig
-	logger *logger.Logger
+	config         *config.CompressionConfig
+	logger         *logger.Logger
+	maxExtractSize int64 // bytes DecompressBackup may write, 0 for no limit
+
+	// Where DecompressBackup writes, "" for next to the archive, and the
+	// free space it needs there as a multiple of the archive size
+	tempDir         string
+	expansionFactor float64
 }
 
 // NewCompressor creates a new compressor instance
@@ -28,6 +41,20 @@ func NewCompressor(cfg *config.CompressionConfig, log *logger.Logger) *Compresso
 	}
 }
 
+// SetMaxExtractSize limits the bytes DecompressBackup writes; 0 removes the
+// limit
+func (c *Compressor) SetMaxExtractSize(size int64) {
+	c.maxExtractSize = size
+}
+
+// SetTempDirectory makes DecompressBackup write into dir instead of next to
+// the archive, after checking it has expansionFactor times the archive size
+// free. A factor of 0 skips the check.
+func (c *Compressor) SetTempDirectory(dir string, expansionFactor float64) {
+	c.tempDir = dir
+	c.expansionFactor = expansionFactor
+}
+
 // CompressBackup compresses a backup directory
 func (c *Compressor) CompressBackup(backupDir string) (string, error) {
 	if !c.config.Enabled {
@@ -38,20 +65,27 @@ func (c *Compressor) CompressBackup(backupDir string) (string, error) {
 	startTime := time.Now()
 
 	// Determine output file name
-	var outputFile string
-	switch strings.ToLower(c.config.Format) {
-	case "tar.gz", "tgz":
-		outputFile = backupDir + ".tar.gz"
-	case "tar.zst":
-		outputFile = backupDir + ".tar.zst"
-	case "tar.xz":
-		outputFile = backupDir + ".tar.xz"
-	default:
-		return "", fmt.Errorf("unsupported compression format: %s", c.config.Format)
+	outputFile, err := c.ArchivePath(backupDir)
+	if err != nil {
+		return "", err
+	}
+
+	// compression.auto may pick another format than the configured one,
+	// which names the archive
+	format, level := c.settings(backupDir, c.autoFormats())
+	if format != c.format() {
+		outputFile = archiveName(backupDir, format)
 	}
 
 	// Create compressed archive
-	err := c.createTarGz(backupDir, outputFile)
+	switch {
+	case c.seekable():
+		err = c.createTarSeekable(backupDir, outputFile, level)
+	case format == formatTarZst:
+		err = c.createTarZst(backupDir, outputFile, level)
+	default:
+		err = c.createTarGz(backupDir, outputFile, level)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to compress backup: %w", err)
 	}
@@ -64,6 +98,8 @@ func (c *Compressor) CompressBackup(backupDir string) (string, error) {
 	c.logger.WithField("original_size", c.formatSize(originalSize)).
 		WithField("compressed_size", c.formatSize(compressedSize)).
 		WithField("compression_ratio", fmt.Sprintf("%.1f%%", ratio)).
+		WithField("format", format).
+		WithField("level", level).
 		WithField("duration", time.Since(startTime)).
 		Info("Backup compression completed")
 
@@ -79,34 +115,151 @@ func (c *Compressor) CompressBackup(backupDir string) (string, error) {
 	return outputFile, nil
 }
 
-// DecompressBackup decompresses a backup archive for restore
+// Archive formats, as named in compression.format
+const (
+	formatTarGz  = "tar.gz"
+	formatTarZst = "tar.zst"
+	formatTarXz  = "tar.xz"
+)
+
+// format returns the configured archive format, "tgz" named "tar.gz"
+func (c *Compressor) format() string {
+	format := strings.ToLower(c.config.Format)
+	if format == "tgz" {
+		return formatTarGz
+	}
+	return format
+}
+
+// ArchivePath returns the name of the archive CompressBackup creates in the
+// configured format. With compression.auto, CompressBackup may pick the
+// other of tar.gz and tar.zst; ArchiveFormat tells by the name it returns.
+func (c *Compressor) ArchivePath(backupPath string) (string, error) {
+	switch c.format() {
+	case formatTarGz, formatTarZst, formatTarXz:
+		return archiveName(backupPath, c.format()), nil
+	default:
+		return "", fmt.Errorf("unsupported compression format: %s", c.config.Format)
+	}
+}
+
+func archiveName(backupPath, format string) string {
+	return backupPath + "." + format
+}
+
+// ArchiveFormat returns the format of an archive CompressBackup created,
+// by its name, or "" for other paths
+func ArchiveFormat(archivePath string) string {
+	for _, format := range []string{formatTarGz, formatTarZst, formatTarXz} {
+		if strings.HasSuffix(archivePath, "."+format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// seekable reports whether archives are written as seekable tar.zst
+func (c *Compressor) seekable() bool {
+	return c.config.Seekable && c.format() == formatTarZst
+}
+
+// autoFormats are the formats compression.auto picks from for an archive
+// file: seekable archives stay tar.zst, others may be tar.gz or tar.zst
+func (c *Compressor) autoFormats() []string {
+	if c.seekable() {
+		return []string{formatTarZst}
+	}
+	return []string{formatTarGz, formatTarZst}
+}
+
+// settings returns the format and level to compress backupPath with: the
+// configured ones, or with compression.auto the best of formats for a
+// sample of it. Levels are in the scale of the format returned.
+func (c *Compressor) settings(backupPath string, formats []string) (string, int) {
+	if c.config.Auto {
+		if trial, ok := c.autoSetting(backupPath, formats); ok {
+			return trial.format, trial.level
+		}
+	}
+	return c.format(), c.config.Level
+}
+
+// DecompressBackup decompresses a backup for restore. The format is told
+// by the file's magic bytes rather than its name: a tar archive is
+// extracted into a directory, a single compressed dump is written out as
+// a .sql file.
 func (c *Compressor) DecompressBackup(archiveFile string) (string, error) {
 	if !c.isCompressedFile(archiveFile) {
 		return archiveFile, nil
 	}
 
-	c.logger.WithField("archive", archiveFile).Info("Starting backup decompression")
+	c.logger.WithField("archive", archiveFile).WithField("format", DetectFormat(archiveFile)).Info("Starting backup decompression")
 	startTime := time.Now()
 
-	// Determine output directory
-	outputDir := strings.TrimSuffix(archiveFile, filepath.Ext(archiveFile))
-	outputDir = strings.TrimSuffix(outputDir, ".tar")
+	file, err := os.Open(archiveFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	stream, closeStream, err := decompressStream(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to decompress backup: %w", err)
+	}
+	defer closeStream()
+
+	content := bufio.NewReader(stream)
+	isTar := isTarStream(content)
+	suffix := ".sql"
+	if isTar {
+		suffix = ".restore"
+	}
+	outputPath, err := c.outputPath(archiveFile, suffix)
+	if err != nil {
+		return "", err
+	}
+	if err := c.checkFreeSpace(archiveFile, filepath.Dir(outputPath)); err != nil {
+		return "", err
+	}
 
-	// Extract archive
-	err := c.extractTarGz(archiveFile, outputDir)
+	if isTar {
+		err = c.extractTar(content, outputPath)
+	} else {
+		err = writeDecompressedFile(content, outputPath, &extractBudget{limit: c.maxExtractSize})
+	}
 	if err != nil {
+		// Leave nothing of a rejected or partial extraction behind
+		os.RemoveAll(outputPath)
+		if errors.Is(err, ErrExtractLimit) {
+			return "", fmt.Errorf("failed to decompress backup: %w (limit %s, see restore.max_extract_size_gb)", err, c.formatSize(c.maxExtractSize))
+		}
 		return "", fmt.Errorf("failed to decompress backup: %w", err)
 	}
 
-	c.logger.WithField("output_dir", outputDir).
+	c.logger.WithField("output_dir", outputPath).
 		WithField("duration", time.Since(startTime)).
 		Info("Backup decompression completed")
 
-	return outputDir, nil
+	return outputPath, nil
+}
+
+// writeDecompressedFile writes a single decompressed dump to path
+func writeDecompressedFile(r io.Reader, path string, budget *extractBudget) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := budget.copy(file, r); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
 }
 
 // createTarGz creates a tar.gz archive from a directory
-func (c *Compressor) createTarGz(sourceDir, targetFile string) error {
+func (c *Compressor) createTarGz(sourceDir, targetFile string, level int) error {
 	// Create output file
 	file, err := os.Create(targetFile)
 	if err != nil {
@@ -114,25 +267,130 @@ func (c *Compressor) createTarGz(sourceDir, targetFile string) error {
 	}
 	defer file.Close()
 
-	// Create gzip writer
-	gzipWriter := gzip.NewWriter(file)
-	defer gzipWriter.Close()
+	if err := writeTarGz(sourceDir, file, level); err != nil {
+		return err
+	}
+	return file.Close()
+}
 
-	// Set compression level
-	if c.config.Level >= 1 && c.config.Level <= 9 {
-		gzipWriter.Close()
-		gzipWriter, err = gzip.NewWriterLevel(file, c.config.Level)
-		if err != nil {
-			return err
+// createTarZst creates a tar.zst archive from a directory, compressed
+// in-process
+func (c *Compressor) createTarZst(sourceDir, targetFile string, level int) error {
+	file, err := os.Create(targetFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if level < 1 {
+		level = 3
+	}
+	encoder, err := zstd.NewWriter(file, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(encoder)
+	if err := writeTarEntries(sourceDir, tarWriter, nil, nil); err != nil {
+		encoder.Close()
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		encoder.Close()
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// Recompress writes the archive src to dst compressed as format, tar.gz or
+// tar.zst, at the format's default level. The tar stream is copied as it
+// is.
+func Recompress(src, dst, format string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	stream, closeStream, err := decompressStream(in)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(src), err)
+	}
+	defer closeStream()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	var w io.WriteCloser
+	switch format {
+	case formatTarGz:
+		w = gzip.NewWriter(out)
+	case formatTarZst:
+		w, err = zstd.NewWriter(out)
+	default:
+		err = fmt.Errorf("unsupported compression format: %s", format)
+	}
+	if err == nil {
+		if _, err = io.Copy(w, stream); err == nil {
+			err = w.Close()
 		}
-		defer gzipWriter.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to recompress %s: %w", filepath.Base(src), err)
+	}
+	return nil
+}
+
+// writeTarGz writes a directory as a tar.gz stream. Files that are already
+// compressed, such as mydumper's .gz data files, are stored in their own
+// uncompressed gzip member instead of being compressed again.
+func writeTarGz(sourceDir string, w io.Writer, level int) error {
+	if level < 1 || level > 9 {
+		level = gzip.DefaultCompression
+	}
+	members := &gzipMembers{out: w}
+	if err := members.setLevel(level); err != nil {
+		return err
 	}
 
 	// Create tar writer
-	tarWriter := tar.NewWriter(gzipWriter)
-	defer tarWriter.Close()
+	tarWriter := tar.NewWriter(members)
+
+	err := writeTarEntries(sourceDir, tarWriter, func(path string, info os.FileInfo) error {
+		// Pad the previous entry before switching gzip members
+		if err := tarWriter.Flush(); err != nil {
+			return err
+		}
+		memberLevel := level
+		if info.Mode().IsRegular() && isCompressedContent(path) {
+			memberLevel = gzip.NoCompression
+		}
+		return members.setLevel(memberLevel)
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := members.setLevel(level); err != nil {
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return members.Close()
+}
 
-	// Walk through source directory
+// writeTarEntries adds every file below sourceDir to tarWriter, named
+// relative to the parent of sourceDir. beforeEntry, if set, runs before
+// each header is written, afterHeader right after.
+func writeTarEntries(sourceDir string, tarWriter *tar.Writer, beforeEntry func(path string, info os.FileInfo) error, afterHeader func(header *tar.Header)) error {
 	return filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -151,10 +409,19 @@ func (c *Compressor) createTarGz(sourceDir, targetFile string) error {
 		}
 		header.Name = relPath
 
+		if beforeEntry != nil {
+			if err := beforeEntry(path, info); err != nil {
+				return err
+			}
+		}
+
 		// Write header
 		if err := tarWriter.WriteHeader(header); err != nil {
 			return err
 		}
+		if afterHeader != nil {
+			afterHeader(header)
+		}
 
 		// Write file content if it's a regular file
 		if info.Mode().IsRegular() {
@@ -174,26 +441,33 @@ func (c *Compressor) createTarGz(sourceDir, targetFile string) error {
 	})
 }
 
-// extractTarGz extracts a tar.gz archive to a directory
+// extractTarGz extracts a compressed tar archive to a directory
 func (c *Compressor) extractTarGz(archiveFile, outputDir string) error {
-	// Open archive file
 	file, err := os.Open(archiveFile)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	// Create gzip reader
-	gzipReader, err := gzip.NewReader(file)
+	stream, closeStream, err := decompressStream(file)
 	if err != nil {
 		return err
 	}
-	defer gzipReader.Close()
+	defer closeStream()
+
+	return c.extractTar(stream, outputDir)
+}
 
-	// Create tar reader
-	tarReader := tar.NewReader(gzipReader)
+// extractTar extracts a tar stream to a directory. Archives may come from
+// shared buckets, so entries must stay inside outputDir: names with .. or
+// absolute paths are rejected, and so are symlinks and hard links, which
+// tenangdb never writes and whose targets can't be checked without
+// following links already on disk. The total size is capped by the
+// extraction limit.
+func (c *Compressor) extractTar(stream io.Reader, outputDir string) error {
+	tarReader := tar.NewReader(stream)
+	budget := &extractBudget{limit: c.maxExtractSize}
 
-	// Extract files
 	for {
 		header, err := tarReader.Next()
 		if err == io.EOF {
@@ -203,8 +477,10 @@ func (c *Compressor) extractTarGz(archiveFile, outputDir string) error {
 			return err
 		}
 
-		// Determine file path
-		filePath := filepath.Join(outputDir, header.Name)
+		filePath, err := extractPath(outputDir, header.Name)
+		if err != nil {
+			return err
+		}
 
 		// Create directory if needed
 		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
@@ -218,28 +494,95 @@ func (c *Compressor) extractTarGz(archiveFile, outputDir string) error {
 				return err
 			}
 		case tar.TypeReg:
-			outFile, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDWR, os.FileMode(header.Mode))
-			if err != nil {
+			if err := budget.reserve(header.Name, header.Size); err != nil {
 				return err
 			}
-			defer outFile.Close()
-
-			if _, err := io.Copy(outFile, tarReader); err != nil {
+			if err := extractFile(tarReader, filePath, os.FileMode(header.Mode), budget); err != nil {
 				return err
 			}
+		case tar.TypeSymlink, tar.TypeLink:
+			return fmt.Errorf("unsafe archive entry %q: symlinks and hard links are not allowed in backup archives", header.Name)
 		}
 	}
 
 	return nil
 }
 
-// isCompressedFile checks if a file is a compressed archive
+// extractPath returns where an archive entry is extracted to, or an error
+// when its name would place it outside outputDir
+func extractPath(outputDir, name string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(name)) {
+		return "", fmt.Errorf("unsafe archive entry %q: path leaves the extraction directory", name)
+	}
+	filePath := filepath.Join(outputDir, name)
+
+	// A symlink already on disk must not redirect this entry
+	rel, _ := filepath.Rel(outputDir, filePath)
+	dir := outputDir
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == "." {
+			continue
+		}
+		dir = filepath.Join(dir, part)
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("unsafe archive entry %q: path goes through symlink %s", name, filepath.Base(dir))
+		}
+	}
+	return filePath, nil
+}
+
+// extractFile writes one archive member to path
+func extractFile(r io.Reader, path string, mode os.FileMode, budget *extractBudget) error {
+	// Never write to a file an earlier entry or anything else created
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := budget.copy(file, r); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// extractBudget caps the bytes a restore writes while decompressing, so a
+// corrupt or hostile archive cannot fill the disk. A limit of 0 means no
+// limit.
+type extractBudget struct {
+	limit int64
+	used  int64
+}
+
+// reserve fails when an entry of size would take the total over the limit
+func (b *extractBudget) reserve(name string, size int64) error {
+	if b.limit > 0 && b.used+size > b.limit {
+		return fmt.Errorf("%w at %s", ErrExtractLimit, name)
+	}
+	return nil
+}
+
+// copy copies r to w, failing once the total goes over the limit
+func (b *extractBudget) copy(w io.Writer, r io.Reader) error {
+	if b.limit <= 0 {
+		n, err := io.Copy(w, r)
+		b.used += n
+		return err
+	}
+	n, err := io.CopyN(w, r, b.limit-b.used+1)
+	b.used += n
+	if b.used > b.limit {
+		return ErrExtractLimit
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// isCompressedFile checks if a file is compressed, by its content
 func (c *Compressor) isCompressedFile(filename string) bool {
-	ext := strings.ToLower(filepath.Ext(filename))
-	return ext == ".gz" || ext == ".zst" || ext == ".xz" || 
-		   strings.HasSuffix(strings.ToLower(filename), ".tar.gz") ||
-		   strings.HasSuffix(strings.ToLower(filename), ".tar.zst") ||
-		   strings.HasSuffix(strings.ToLower(filename), ".tar.xz")
+	return DetectFormat(filename) != ""
 }
 
 // getDirSize calculates the total size of a directory
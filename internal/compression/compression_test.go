@@ -0,0 +1,133 @@
+package compression
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+// tarOf builds a tar stream from headers, giving regular files content of
+// their Size
+func tarOf(t *testing.T, headers ...*tar.Header) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	w := tar.NewWriter(&buf)
+	for _, header := range headers {
+		if header.Mode == 0 {
+			header.Mode = 0644
+		}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := w.Write(bytes.Repeat([]byte("x"), int(header.Size))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTarRejectsUnsafeEntries(t *testing.T) {
+	file := func(name string, size int64) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeReg, Size: size}
+	}
+	link := func(name, target string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeSymlink, Linkname: target, Mode: 0777}
+	}
+	hardlink := func(name, target string) *tar.Header {
+		return &tar.Header{Name: name, Typeflag: tar.TypeLink, Linkname: target}
+	}
+
+	tests := []struct {
+		name    string
+		entries []*tar.Header
+		want    string
+	}{
+		{"parent path", []*tar.Header{file("../evil.sql", 1)}, "path leaves"},
+		{"nested parent path", []*tar.Header{file("db/../../evil.sql", 1)}, "path leaves"},
+		{"absolute path", []*tar.Header{file("/tmp/evil.sql", 1)}, "path leaves"},
+		{"symlink out", []*tar.Header{link("db/out", "../../outside")}, "symlinks"},
+		{"absolute symlink", []*tar.Header{link("out", "/etc")}, "symlinks"},
+		{"symlink within", []*tar.Header{file("app.sql", 1), link("latest.sql", "app.sql")}, "symlinks"},
+		{"write through symlink", []*tar.Header{link("in", "."), file("in/evil.sql", 1)}, "symlinks"},
+		{"symlink chain", []*tar.Header{link("y", "."), link("x", "y/y/../evil.sql"), file("x", 1)}, "symlinks"},
+		{"hard link", []*tar.Header{hardlink("x", "../evil.sql")}, "hard links"},
+		{"duplicate file", []*tar.Header{file("a.sql", 1), file("a.sql", 1)}, "exists"},
+		{"size limit", []*tar.Header{file("a.sql", 600), file("b.sql", 600)}, "size limit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			outputDir := filepath.Join(dir, "restore")
+			c := NewCompressor(&config.CompressionConfig{}, logger.NewLogger("error"))
+			c.SetMaxExtractSize(1000)
+
+			err := c.extractTar(tarOf(t, tt.entries...), outputDir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("extractTar() = %v, want error containing %q", err, tt.want)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "evil.sql")); err == nil {
+				t.Error("entry was written outside the extraction directory")
+			}
+		})
+	}
+
+	// A symlink already in the extraction directory isn't written through
+	dir := t.TempDir()
+	outputDir := filepath.Join(dir, "restore")
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "evil.sql"), filepath.Join(outputDir, "x")); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCompressor(&config.CompressionConfig{}, logger.NewLogger("error"))
+	if err := c.extractTar(tarOf(t, file("x", 1)), outputDir); err == nil {
+		t.Error("extractTar() wrote through an existing symlink")
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "evil.sql")); err == nil {
+		t.Error("entry was written outside the extraction directory")
+	}
+
+	// Safe entries extract
+	dir = t.TempDir()
+	c.SetMaxExtractSize(1000)
+	stream := tarOf(t,
+		&tar.Header{Name: "app/", Typeflag: tar.TypeDir, Mode: 0755},
+		file("app/app.users.sql", 400),
+		file("./app/metadata", 400),
+	)
+	if err := c.extractTar(stream, dir); err != nil {
+		t.Fatalf("extractTar() = %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(dir, "app", "app.users.sql")); err != nil || len(content) != 400 {
+		t.Errorf("reading extracted file: %d bytes, %v", len(content), err)
+	}
+}
+
+func TestWriteDecompressedFileLimit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.sql")
+	err := writeDecompressedFile(bytes.NewReader(make([]byte, 2000)), path, &extractBudget{limit: 1000})
+	if !errors.Is(err, ErrExtractLimit) {
+		t.Fatalf("writeDecompressedFile() = %v, want ErrExtractLimit", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("partial file was left behind")
+	}
+
+	if err := writeDecompressedFile(bytes.NewReader(make([]byte, 1000)), path, &extractBudget{limit: 1000}); err != nil {
+		t.Errorf("writeDecompressedFile() at the limit = %v", err)
+	}
+}
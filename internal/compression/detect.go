@@ -0,0 +1,74 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Compression formats DetectFormat recognises
+const (
+	FormatGzip = "gzip"
+	FormatZstd = "zstd"
+	FormatXz   = "xz"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// archiveExtensions are stripped from an archive's name to name what it
+// decompresses to, longest first
+var archiveExtensions = []string{".tar.gz", ".tar.zst", ".tar.xz", ".tgz", ".gz", ".zst", ".xz"}
+
+// formatOf returns the compression format data starts with, or ""
+func formatOf(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		return FormatGzip
+	case bytes.HasPrefix(head, zstdMagic):
+		return FormatZstd
+	case bytes.HasPrefix(head, xzMagic):
+		return FormatXz
+	}
+	return ""
+}
+
+// DetectFormat returns the compression format of the file at path from its
+// magic bytes, whatever its name. It returns "" for directories and
+// uncompressed or unreadable files.
+func DetectFormat(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	head := make([]byte, len(xzMagic))
+	n, _ := io.ReadFull(file, head)
+	return formatOf(head[:n])
+}
+
+// isTarStream reports whether r holds a tar archive, from the ustar magic
+// of its first header
+func isTarStream(r *bufio.Reader) bool {
+	header, err := r.Peek(263)
+	return err == nil && bytes.HasPrefix(header[257:], []byte("ustar"))
+}
+
+// decompressedPath names what archiveFile decompresses to: its name without
+// the compression extension. Renamed or extension-less archives get suffix
+// instead.
+func decompressedPath(archiveFile, suffix string) string {
+	lower := strings.ToLower(filepath.Base(archiveFile))
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) && len(lower) > len(ext) {
+			return archiveFile[:len(archiveFile)-len(ext)]
+		}
+	}
+	return archiveFile + suffix
+}
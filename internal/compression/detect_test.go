@@ -0,0 +1,68 @@
+package compression
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+func TestDecompressBackupByContent(t *testing.T) {
+	dir := t.TempDir()
+	c := NewCompressor(&config.CompressionConfig{Enabled: true, Format: "tar.gz", Level: 1, KeepOriginal: true}, logger.NewLogger("error"))
+
+	// A tar.gz archive renamed without its extension
+	backupDir := filepath.Join(dir, "app-2024-06-01_02-00-00")
+	if err := os.Mkdir(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(backupDir, "app.t-schema.sql"), []byte("CREATE TABLE t (id INT);\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := c.CompressBackup(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renamed := filepath.Join(dir, "app-archive")
+	if err := os.Rename(archive, renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := DetectFormat(renamed); got != FormatGzip {
+		t.Fatalf("DetectFormat() = %q, want %q", got, FormatGzip)
+	}
+	out, err := c.DecompressBackup(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(out, "app-2024-06-01_02-00-00", "app.t-schema.sql")); err != nil {
+		t.Errorf("archive not extracted into %s: %v", out, err)
+	}
+
+	// A single gzipped dump is written out as a file
+	dump := filepath.Join(dir, "app-dump.sql.gz")
+	file, err := os.Create(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	gz.Write([]byte("INSERT INTO t VALUES (1);\n"))
+	gz.Close()
+	file.Close()
+
+	out, err = c.DecompressBackup(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content, err := os.ReadFile(out); err != nil || string(content) != "INSERT INTO t VALUES (1);\n" || filepath.Base(out) != "app-dump.sql" {
+		t.Errorf("DecompressBackup() = %s with %q, %v", out, content, err)
+	}
+
+	// Uncompressed files are left alone
+	if DetectFormat(out) != "" {
+		t.Errorf("DetectFormat(%s) = %q, want none", out, DetectFormat(out))
+	}
+}
@@ -0,0 +1,77 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+)
+
+// compressedMagic are the leading bytes of formats that don't shrink when
+// compressed again: gzip, zstd, lz4, xz and bzip2
+var compressedMagic = [][]byte{
+	{0x1f, 0x8b},
+	{0x28, 0xb5, 0x2f, 0xfd},
+	{0x04, 0x22, 0x4d, 0x18},
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},
+	{'B', 'Z', 'h'},
+}
+
+// isCompressedContent reports whether a file starts with the magic bytes
+// of a compression format. The content decides, not the file extension.
+func isCompressedContent(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, 6)
+	n, _ := io.ReadFull(file, head)
+	head = head[:n]
+	for _, magic := range compressedMagic {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipMembers writes a gzip stream as consecutive members, so the level can
+// change between tar entries. gzip readers, including gunzip and Go's
+// gzip.Reader, read the members as one stream.
+type gzipMembers struct {
+	out   io.Writer
+	gz    *gzip.Writer
+	level int
+}
+
+// setLevel starts a new member if level differs from the current one
+func (m *gzipMembers) setLevel(level int) error {
+	if m.gz != nil && m.level == level {
+		return nil
+	}
+	if err := m.Close(); err != nil {
+		return err
+	}
+	gz, err := gzip.NewWriterLevel(m.out, level)
+	if err != nil {
+		return err
+	}
+	m.gz, m.level = gz, level
+	return nil
+}
+
+func (m *gzipMembers) Write(p []byte) (int, error) {
+	return m.gz.Write(p)
+}
+
+// Close ends the current member
+func (m *gzipMembers) Close() error {
+	if m.gz == nil {
+		return nil
+	}
+	err := m.gz.Close()
+	m.gz = nil
+	return err
+}
@@ -0,0 +1,69 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+func TestCreateTarGzStoresCompressedMembers(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "app-2024-06-01_02-00-00")
+	if err := os.Mkdir(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Random data compressed by gzip does not shrink again
+	random := make([]byte, 1<<20)
+	if _, err := rand.Read(random); err != nil {
+		t.Fatal(err)
+	}
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(random)
+	w.Close()
+
+	schema := bytes.Repeat([]byte("CREATE TABLE t (id INT);\n"), 10000)
+	files := map[string][]byte{
+		"app.t.00000.sql.gz": gz.Bytes(),
+		"app.t-schema.sql":   schema,
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(backupDir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewCompressor(&config.CompressionConfig{Enabled: true, Format: "tar.gz", Level: 6}, logger.NewLogger("error"))
+	archive := backupDir + ".tar.gz"
+	if err := c.createTarGz(backupDir, archive, 6); err != nil {
+		t.Fatal(err)
+	}
+	if !isCompressedContent(archive) {
+		t.Error("archive is not gzip")
+	}
+
+	outDir := filepath.Join(dir, "out")
+	if err := c.extractTarGz(archive, outDir); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(outDir, filepath.Base(backupDir), name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s differs after extraction", name)
+		}
+	}
+
+	if isCompressedContent(filepath.Join(backupDir, "app.t-schema.sql")) {
+		t.Error("plain SQL detected as compressed")
+	}
+}
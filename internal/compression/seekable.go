@@ -0,0 +1,326 @@
+package compression
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Seekable tar.zst archives are split into independent zstd frames and end
+// with the seek table of the zstd seekable format, which maps each frame to
+// its place in the tar stream. An index of the tar members, in a skippable
+// frame before the seek table, tells which frames hold a file, so one table
+// can be read without decompressing the whole archive. zstd -d and tar
+// read them like any other tar.zst.
+const (
+	seekableFrameSize   = 4 << 20    // tar stream bytes per frame
+	seekTableMagic      = 0x184D2A5E // skippable frame holding the seek table
+	seekableFooterMagic = 0x8F92EAB1
+	seekableFooterSize  = 9
+	memberIndexMagic    = 0x184D2A5D // skippable frame holding the member index
+	seekChecksumFlag    = 0x80       // seek table entries carry a checksum
+	maxRangeFrames      = 16         // frames read with one ReadRange while extracting
+)
+
+// ErrNotSeekable is returned for archives without a seek table and index
+var ErrNotSeekable = errors.New("archive is not a seekable tar.zst")
+
+// SeekableMember is a file in a seekable archive, at Offset in the tar
+// stream
+type SeekableMember struct {
+	Name   string `json:"name"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+type seekableFrame struct {
+	compressedOffset int64
+	compressedSize   int64
+	offset           int64 // in the tar stream
+	size             int64
+}
+
+// seekableWriter compresses everything written to it in frames of
+// seekableFrameSize
+type seekableWriter struct {
+	out     io.Writer
+	encoder *zstd.Encoder
+	buf     []byte
+	frames  []seekableFrame
+	written int64 // tar stream bytes, including those in buf
+}
+
+func newSeekableWriter(out io.Writer, level int) (*seekableWriter, error) {
+	if level < 1 {
+		level = 3
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+	if err != nil {
+		return nil, err
+	}
+	return &seekableWriter{out: out, encoder: encoder, buf: make([]byte, 0, seekableFrameSize)}, nil
+}
+
+func (w *seekableWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		chunk := min(len(p), seekableFrameSize-len(w.buf))
+		w.buf = append(w.buf, p[:chunk]...)
+		p = p[chunk:]
+		if len(w.buf) == seekableFrameSize {
+			if err := w.flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	w.written += int64(n)
+	return n, nil
+}
+
+// flush writes the buffered data as one frame
+func (w *seekableWriter) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	frame := seekableFrame{size: int64(len(w.buf))}
+	if len(w.frames) > 0 {
+		last := w.frames[len(w.frames)-1]
+		frame.compressedOffset = last.compressedOffset + last.compressedSize
+		frame.offset = last.offset + last.size
+	}
+	compressed := w.encoder.EncodeAll(w.buf, nil)
+	if _, err := w.out.Write(compressed); err != nil {
+		return err
+	}
+	frame.compressedSize = int64(len(compressed))
+	w.frames = append(w.frames, frame)
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Close writes the last frame, the member index and the seek table
+func (w *seekableWriter) Close(members []SeekableMember) error {
+	if err := w.flush(); err != nil {
+		return err
+	}
+	w.encoder.Close()
+
+	index, err := json.Marshal(struct {
+		Members []SeekableMember `json:"members"`
+	}{members})
+	if err != nil {
+		return err
+	}
+	if err := writeSkippableFrame(w.out, memberIndexMagic, index); err != nil {
+		return err
+	}
+
+	table := make([]byte, 0, len(w.frames)*8+seekableFooterSize)
+	for _, frame := range w.frames {
+		table = binary.LittleEndian.AppendUint32(table, uint32(frame.compressedSize))
+		table = binary.LittleEndian.AppendUint32(table, uint32(frame.size))
+	}
+	table = binary.LittleEndian.AppendUint32(table, uint32(len(w.frames)))
+	table = append(table, 0) // no checksums, the frames carry their own
+	table = binary.LittleEndian.AppendUint32(table, seekableFooterMagic)
+	return writeSkippableFrame(w.out, seekTableMagic, table)
+}
+
+func writeSkippableFrame(w io.Writer, magic uint32, payload []byte) error {
+	header := binary.LittleEndian.AppendUint32(nil, magic)
+	header = binary.LittleEndian.AppendUint32(header, uint32(len(payload)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(payload)
+	return err
+}
+
+// writeTarSeekable writes a directory as a seekable tar.zst stream
+func writeTarSeekable(sourceDir string, w io.Writer, level int) error {
+	frames, err := newSeekableWriter(w, level)
+	if err != nil {
+		return err
+	}
+	tarWriter := tar.NewWriter(frames)
+
+	var members []SeekableMember
+	err = writeTarEntries(sourceDir, tarWriter, nil, func(header *tar.Header) {
+		if header.Typeflag == tar.TypeReg {
+			members = append(members, SeekableMember{Name: header.Name, Offset: frames.written, Size: header.Size})
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return frames.Close(members)
+}
+
+// createTarSeekable creates a seekable tar.zst archive from a directory
+func (c *Compressor) createTarSeekable(sourceDir, targetFile string, level int) error {
+	file, err := os.Create(targetFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if err := writeTarSeekable(sourceDir, file, level); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// RangeReader reads length bytes of an archive at offset, counted from its
+// end when negative
+type RangeReader interface {
+	ReadRange(offset, length int64) ([]byte, error)
+}
+
+// FileRange reads ranges of a local archive
+type FileRange struct {
+	File *os.File
+}
+
+// ReadRange implements RangeReader
+func (f FileRange) ReadRange(offset, length int64) ([]byte, error) {
+	if offset < 0 {
+		info, err := f.File.Stat()
+		if err != nil {
+			return nil, err
+		}
+		offset += info.Size()
+		if offset < 0 {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+	buf := make([]byte, length)
+	if _, err := f.File.ReadAt(buf, offset); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// SeekableArchive reads single members of a seekable tar.zst archive
+type SeekableArchive struct {
+	r       RangeReader
+	frames  []seekableFrame
+	decoder *zstd.Decoder
+	Members []SeekableMember
+}
+
+// OpenSeekable reads the seek table and member index of an archive. It
+// returns ErrNotSeekable for archives written without them.
+func OpenSeekable(r RangeReader) (*SeekableArchive, error) {
+	footer, err := r.ReadRange(-seekableFooterSize, seekableFooterSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive footer: %w", err)
+	}
+	if binary.LittleEndian.Uint32(footer[5:]) != seekableFooterMagic {
+		return nil, ErrNotSeekable
+	}
+	count := int64(binary.LittleEndian.Uint32(footer))
+	entrySize := int64(8)
+	if footer[4]&seekChecksumFlag != 0 {
+		entrySize = 12
+	}
+
+	tableSize := 8 + count*entrySize + seekableFooterSize
+	table, err := r.ReadRange(-tableSize, tableSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read seek table: %w", err)
+	}
+	if binary.LittleEndian.Uint32(table) != seekTableMagic {
+		return nil, ErrNotSeekable
+	}
+
+	archive := &SeekableArchive{r: r, frames: make([]seekableFrame, count)}
+	var compressedOffset, offset int64
+	for i := range archive.frames {
+		entry := table[8+int64(i)*entrySize:]
+		frame := seekableFrame{
+			compressedOffset: compressedOffset,
+			compressedSize:   int64(binary.LittleEndian.Uint32(entry)),
+			offset:           offset,
+			size:             int64(binary.LittleEndian.Uint32(entry[4:])),
+		}
+		archive.frames[i] = frame
+		compressedOffset += frame.compressedSize
+		offset += frame.size
+	}
+
+	// The member index follows the last frame
+	header, err := r.ReadRange(compressedOffset, 8)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read member index: %w", err)
+	}
+	if binary.LittleEndian.Uint32(header) != memberIndexMagic {
+		return nil, ErrNotSeekable
+	}
+	index, err := r.ReadRange(compressedOffset+8, int64(binary.LittleEndian.Uint32(header[4:])))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read member index: %w", err)
+	}
+	var parsed struct {
+		Members []SeekableMember `json:"members"`
+	}
+	if err := json.Unmarshal(index, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to parse member index: %w", err)
+	}
+	archive.Members = parsed.Members
+
+	if archive.decoder, err = zstd.NewReader(nil); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// Close releases the decoder
+func (a *SeekableArchive) Close() {
+	a.decoder.Close()
+}
+
+// ExtractMember writes the content of member to w, decompressing only the
+// frames that hold it
+func (a *SeekableArchive) ExtractMember(member SeekableMember, w io.Writer) error {
+	start, end := member.Offset, member.Offset+member.Size
+	first := 0
+	for first < len(a.frames) && a.frames[first].offset+a.frames[first].size <= start {
+		first++
+	}
+
+	for i := first; i < len(a.frames) && a.frames[i].offset < end; {
+		// Read runs of frames in one range, remote reads are slow to start
+		last := i
+		for last+1 < len(a.frames) && last+1-i < maxRangeFrames && a.frames[last+1].offset < end {
+			last++
+		}
+		base := a.frames[i].compressedOffset
+		compressed, err := a.r.ReadRange(base, a.frames[last].compressedOffset+a.frames[last].compressedSize-base)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", member.Name, err)
+		}
+
+		for ; i <= last; i++ {
+			frame := a.frames[i]
+			at := frame.compressedOffset - base
+			data, err := a.decoder.DecodeAll(compressed[at:at+frame.compressedSize], nil)
+			if err != nil {
+				return fmt.Errorf("failed to decompress %s: %w", member.Name, err)
+			}
+			from, to := max(start-frame.offset, 0), min(end-frame.offset, frame.size)
+			if _, err := w.Write(data[from:to]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
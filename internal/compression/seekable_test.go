@@ -0,0 +1,123 @@
+package compression
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/klauspost/compress/zstd"
+)
+
+// countingRange counts the bytes read through it
+type countingRange struct {
+	FileRange
+	read int64
+}
+
+func (c *countingRange) ReadRange(offset, length int64) ([]byte, error) {
+	c.read += length
+	return c.FileRange.ReadRange(offset, length)
+}
+
+func TestSeekableArchive(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "app-2024-06-01_02-00-00")
+	if err := os.Mkdir(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// A data file spanning several frames, between small schema files
+	files := map[string][]byte{
+		"app.orders-schema.sql": []byte("CREATE TABLE orders (id INT);\n"),
+		"app.users-schema.sql":  []byte("CREATE TABLE users (id INT);\n"),
+	}
+	var data bytes.Buffer
+	for i := 0; data.Len() < 3*seekableFrameSize; i++ {
+		fmt.Fprintf(&data, "INSERT INTO orders VALUES (%d);\n", i)
+	}
+	files["app.orders.00000.sql"] = data.Bytes()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(backupDir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c := NewCompressor(&config.CompressionConfig{Enabled: true, Format: "tar.zst", Level: 3, Seekable: true, KeepOriginal: true}, logger.NewLogger("error"))
+	archivePath, err := c.CompressBackup(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	// Any zstd reader sees a plain tar stream
+	decoder, err := zstd.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(decoder)
+	entries := 0
+	for {
+		if _, err := tarReader.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("reading as tar.zst: %v", err)
+		}
+		entries++
+	}
+	decoder.Close()
+	if entries != len(files)+1 {
+		t.Errorf("tar has %d entries, want %d", entries, len(files)+1)
+	}
+
+	r := &countingRange{FileRange: FileRange{File: file}}
+	archive, err := OpenSeekable(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+	if len(archive.Members) != len(files) {
+		t.Fatalf("index has %d members, want %d", len(archive.Members), len(files))
+	}
+	for _, member := range archive.Members {
+		var got bytes.Buffer
+		before := r.read
+		if err := archive.ExtractMember(member, &got); err != nil {
+			t.Fatal(err)
+		}
+		want := files[filepath.Base(member.Name)]
+		if !bytes.Equal(got.Bytes(), want) {
+			t.Errorf("%s: extracted %d bytes, want %d", member.Name, got.Len(), len(want))
+		}
+
+		// A schema file only needs the frame holding it
+		info, _ := file.Stat()
+		if len(want) < 100 && r.read-before > info.Size()/2 {
+			t.Errorf("%s: read %d of %d archive bytes", member.Name, r.read-before, info.Size())
+		}
+	}
+
+	// Archives without a seek table are reported as such
+	plain := NewCompressor(&config.CompressionConfig{Enabled: true, Format: "tar.gz", Level: 1, KeepOriginal: true}, logger.NewLogger("error"))
+	plainPath, err := plain.CompressBackup(backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plainFile, err := os.Open(plainPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plainFile.Close()
+	if _, err := OpenSeekable(FileRange{File: plainFile}); err != ErrNotSeekable {
+		t.Errorf("OpenSeekable(tar.gz) = %v, want ErrNotSeekable", err)
+	}
+}
@@ -0,0 +1,69 @@
+package compression
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/disk"
+)
+
+// outputPath returns where DecompressBackup writes archiveFile: next to it
+// or in the temp directory. It never names an existing file or directory,
+// such as the original backup directory kept beside its archive, since the
+// output is removed after the restore.
+func (c *Compressor) outputPath(archiveFile, suffix string) (string, error) {
+	path := decompressedPath(archiveFile, suffix)
+	if c.tempDir != "" {
+		if err := os.MkdirAll(c.tempDir, 0700); err != nil {
+			return "", fmt.Errorf("failed to create restore.temp_directory: %w", err)
+		}
+		path = filepath.Join(c.tempDir, filepath.Base(path))
+	}
+	return unusedPath(path), nil
+}
+
+// unusedPath returns path, or path with a number before its extension when
+// something already exists there
+func unusedPath(path string) string {
+	if _, err := os.Lstat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	if ext != ".sql" {
+		ext = ""
+	}
+	base := strings.TrimSuffix(path, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Lstat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// checkFreeSpace fails before decompressing when dir has less free space
+// than the archive is expected to expand to. Where free space cannot be
+// read, the check is skipped.
+func (c *Compressor) checkFreeSpace(archiveFile, dir string) error {
+	if c.expansionFactor <= 0 {
+		return nil
+	}
+	info, err := os.Stat(archiveFile)
+	if err != nil {
+		return err
+	}
+	free, _, err := disk.Space(dir)
+	if err != nil {
+		c.logger.WithError(err).Debug("Cannot read free space, skipping the check")
+		return nil
+	}
+
+	needed := float64(info.Size()) * c.expansionFactor
+	if float64(free) < needed {
+		return fmt.Errorf("not enough space in %s to decompress %s: %s free, about %s needed (%.4g× the archive, restore.expansion_factor); set restore.temp_directory to a larger filesystem",
+			dir, filepath.Base(archiveFile), c.formatSize(int64(free)), c.formatSize(int64(needed)), c.expansionFactor)
+	}
+	return nil
+}
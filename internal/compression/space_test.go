@@ -0,0 +1,69 @@
+package compression
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+func TestOutputPathAvoidsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "app-2024-06-01_02-00-00.tar.gz")
+	c := NewCompressor(&config.CompressionConfig{}, logger.NewLogger("error"))
+
+	// The original directory kept beside its archive is not reused
+	if err := os.Mkdir(filepath.Join(dir, "app-2024-06-01_02-00-00"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	path, err := c.outputPath(archive, ".restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "app-2024-06-01_02-00-00-1"); path != want {
+		t.Errorf("outputPath() = %s, want %s", path, want)
+	}
+
+	tempDir := filepath.Join(dir, "tmp")
+	c.SetTempDirectory(tempDir, 0)
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tempDir, "app.sql"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	path, err = c.outputPath(filepath.Join(dir, "app.sql.zst"), ".sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(tempDir, "app-1.sql"); path != want {
+		t.Errorf("outputPath() = %s, want %s", path, want)
+	}
+}
+
+func TestCheckFreeSpace(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("free space is only read on Linux")
+	}
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "app.sql.gz")
+	if err := os.WriteFile(archive, make([]byte, 1024), 0600); err != nil {
+		t.Fatal(err)
+	}
+	c := NewCompressor(&config.CompressionConfig{}, logger.NewLogger("error"))
+
+	c.SetTempDirectory(dir, 5)
+	if err := c.checkFreeSpace(archive, dir); err != nil {
+		t.Errorf("checkFreeSpace() = %v", err)
+	}
+
+	// 1 KiB expanding to an exbibyte fits nowhere
+	c.SetTempDirectory(dir, 1<<50)
+	if err := c.checkFreeSpace(archive, dir); err == nil || !strings.Contains(err.Error(), "not enough space") {
+		t.Errorf("checkFreeSpace() = %v, want not enough space", err)
+	}
+}
@@ -0,0 +1,150 @@
+package compression
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// zstdMagic starts every zstd frame
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// CanStream reports whether the configured format can be written as a
+// stream by WriteArchive
+func (c *Compressor) CanStream() error {
+	switch c.format() {
+	case formatTarGz:
+		return nil
+	case formatTarZst:
+		if c.seekable() {
+			return nil // compressed in-process
+		}
+		if _, err := exec.LookPath("zstd"); err != nil {
+			return fmt.Errorf("zstd not found in PATH: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("compression format %s cannot be streamed", c.config.Format)
+	}
+}
+
+// WriteArchive writes backupDir to w as an archive in the configured format,
+// without an intermediate file. tar.zst is compressed by the zstd command
+// using all cores, seekable tar.zst in-process; a slow w stalls the whole
+// pipeline rather than buffering. The archive is named before it is
+// written, so compression.auto only picks the level of the configured
+// format.
+func (c *Compressor) WriteArchive(ctx context.Context, backupDir string, w io.Writer) error {
+	_, level := c.settings(backupDir, []string{c.format()})
+
+	switch c.format() {
+	case formatTarGz:
+		return writeTarGz(backupDir, w, level)
+	case formatTarZst:
+		if c.seekable() {
+			return writeTarSeekable(backupDir, w, level)
+		}
+		return writeTarZstd(ctx, backupDir, w, level)
+	default:
+		return fmt.Errorf("compression format %s cannot be streamed", c.config.Format)
+	}
+}
+
+// writeTarZstd pipes a tar stream of sourceDir through zstd into w
+func writeTarZstd(ctx context.Context, sourceDir string, w io.Writer, level int) error {
+	if level < 1 {
+		level = 3
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "zstd", "-q", "-c", "-T0", fmt.Sprintf("-%d", level))
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start zstd: %w", err)
+	}
+
+	tarWriter := tar.NewWriter(stdin)
+	writeErr := writeTarEntries(sourceDir, tarWriter, nil, nil)
+	if writeErr == nil {
+		writeErr = tarWriter.Close()
+	}
+	stdin.Close()
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("zstd failed: %w (output: %s)", err, strings.TrimSpace(stderr.String()))
+	}
+	return writeErr
+}
+
+// decompressStream returns the decompressed content of a file, by the
+// format its magic bytes name: gzip in process, zstd and xz through their
+// command line tools
+func decompressStream(file *os.File) (io.Reader, func(), error) {
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(len(xzMagic))
+
+	switch formatOf(head) {
+	case FormatGzip:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		return gzipReader, func() { gzipReader.Close() }, nil
+	case FormatZstd:
+		return commandStream(reader, "zstd", "-q", "-d", "-c")
+	case FormatXz:
+		return commandStream(reader, "xz", "-q", "-d", "-c")
+	default:
+		return nil, nil, fmt.Errorf("%s is not gzip, zstd or xz compressed", file.Name())
+	}
+}
+
+// commandStream returns the output of a decompression command reading r
+func commandStream(r io.Reader, name string, args ...string) (io.Reader, func(), error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = r
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start %s: %w", name, err)
+	}
+	return stdout, func() {
+		stdout.Close()
+		cmd.Wait()
+	}, nil
+}
+
+// OpenDecompressed returns the content of a file, decompressed when its
+// magic bytes name gzip, zstd or xz
+func OpenDecompressed(path string) (io.Reader, func(), error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if DetectFormat(path) == "" {
+		return file, func() { file.Close() }, nil
+	}
+	stream, closeStream, err := decompressStream(file)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return stream, func() {
+		closeStream()
+		file.Close()
+	}, nil
+}
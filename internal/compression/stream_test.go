@@ -0,0 +1,63 @@
+package compression
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+func TestWriteArchiveRoundTrip(t *testing.T) {
+	for _, format := range []string{"tar.gz", "tar.zst"} {
+		t.Run(format, func(t *testing.T) {
+			if format == "tar.zst" {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd not installed")
+				}
+			}
+
+			dir := t.TempDir()
+			backupDir := filepath.Join(dir, "app-2024-06-01_02-00-00")
+			if err := os.Mkdir(backupDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			schema := bytes.Repeat([]byte("CREATE TABLE t (id INT);\n"), 10000)
+			if err := os.WriteFile(filepath.Join(backupDir, "app.t-schema.sql"), schema, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			c := NewCompressor(&config.CompressionConfig{Enabled: true, Format: format, Level: 3}, logger.NewLogger("error"))
+			if err := c.CanStream(); err != nil {
+				t.Fatal(err)
+			}
+			var archive bytes.Buffer
+			if err := c.WriteArchive(context.Background(), backupDir, &archive); err != nil {
+				t.Fatal(err)
+			}
+			if archive.Len() == 0 || archive.Len() >= len(schema) {
+				t.Fatalf("archive is %d bytes for %d bytes of input", archive.Len(), len(schema))
+			}
+
+			archivePath := backupDir + "." + format
+			if err := os.WriteFile(archivePath, archive.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			outDir := filepath.Join(dir, "out")
+			if err := c.extractTarGz(archivePath, outDir); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(outDir, filepath.Base(backupDir), "app.t-schema.sql"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, schema) {
+				t.Error("extracted file differs")
+			}
+		})
+	}
+}
@@ -2,34 +2,130 @@ package config
 
 import (
 	"fmt"
+	"math"
+	"net/url"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
+	"regexp"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/abdullahainun/tenangdb/internal/schedule"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	Database DatabaseConfig `mapstructure:"database"`
-	Backup   BackupConfig   `mapstructure:"backup"`
-	Upload   UploadConfig   `mapstructure:"upload"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Cleanup  CleanupConfig  `mapstructure:"cleanup"`
-	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Database DatabaseConfig  `mapstructure:"database"`
+	Backup   BackupConfig    `mapstructure:"backup"`
+	Upload   UploadConfig    `mapstructure:"upload"`
+	Restore  RestoreConfig   `mapstructure:"restore"`
+	Logging  LoggingConfig   `mapstructure:"logging"`
+	Cleanup  CleanupConfig   `mapstructure:"cleanup"`
+	Metrics  MetricsConfig   `mapstructure:"metrics"`
+	Tenants  []TenantConfig  `mapstructure:"tenants"`
+	Policy   PolicyConfig    `mapstructure:"policy"`
+	Standbys []StandbyConfig `mapstructure:"standbys"`
+	SLA      SLAConfig       `mapstructure:"sla"`
+	Report   ReportConfig    `mapstructure:"report"`
+	Drill    DrillConfig     `mapstructure:"drill"`
+	Kubernetes KubernetesConfig `mapstructure:"kubernetes"`
+	Resources  ResourcesConfig  `mapstructure:"resources"`
+
+	// StateDirectory holds the files TenangDB keeps between runs: backup
+	// frequency tracking, the upload ledger, metrics and schema history
+	StateDirectory string `mapstructure:"state_directory"`
+}
+
+// PolicyConfig holds organizational guardrails enforced by restore and cleanup
+type PolicyConfig struct {
+	DenyRestoreTo                    []string `mapstructure:"deny_restore_to"` // host globs, or "host/database"
+	RequireVerificationBeforeCleanup bool     `mapstructure:"require_verification_before_cleanup"`
 }
 
 type DatabaseConfig struct {
+	Type          string          `mapstructure:"type"` // "mysql" or "postgresql"
 	Host          string          `mapstructure:"host"`
 	Port          int             `mapstructure:"port"`
+	Socket        string          `mapstructure:"socket"` // unix socket path, used instead of host and port
 	Username      string          `mapstructure:"username"`
 	Password      string          `mapstructure:"password"`
 	Timeout       int             `mapstructure:"timeout"`
 	MysqldumpPath string          `mapstructure:"mysqldump_path"`
 	MysqlPath     string          `mapstructure:"mysql_path"`
 	Mydumper      *MydumperConfig `mapstructure:"mydumper"`
+	SSH           SSHConfig       `mapstructure:"ssh"`
+	Container     string          `mapstructure:"container"`   // Docker container running MySQL, dumps run inside it
+	DockerPath    string          `mapstructure:"docker_path"` // docker binary, found in PATH by default
+
+	// Connection tuning; zero timeouts fall back to Timeout or wait forever
+	DialTimeout     time.Duration `mapstructure:"dial_timeout"`      // defaults to timeout
+	ReadTimeout     time.Duration `mapstructure:"read_timeout"`      // 0 disables
+	WriteTimeout    time.Duration `mapstructure:"write_timeout"`     // 0 disables
+	MaxOpenConns    int           `mapstructure:"max_open_conns"`    // defaults to 10
+	MaxIdleConns    int           `mapstructure:"max_idle_conns"`    // defaults to 5
+	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime"` // defaults to timeout
+	Charset         string        `mapstructure:"charset"`           // also passed to mysqldump and mysql
+	Collation       string        `mapstructure:"collation"`
+
+	SSLMode    string           `mapstructure:"ssl_mode"`   // PostgreSQL only, passed as PGSSLMODE
+	PostgreSQL PostgreSQLConfig `mapstructure:"postgresql"` // used with type postgresql
+
+	// Host as configured, set when Host is pointed at the local end of an
+	// SSH tunnel
+	TunneledHost string `mapstructure:"-"`
+}
+
+// Database types of database.type
+const (
+	DatabaseMySQL      = "mysql"
+	DatabasePostgreSQL = "postgresql"
+)
+
+// PostgreSQLConfig sets up pg_dump, pg_restore and psql for servers with
+// database.type postgresql
+type PostgreSQLConfig struct {
+	Format        string `mapstructure:"format"`          // plain, custom, directory or tar
+	Jobs          int    `mapstructure:"jobs"`            // parallel jobs of directory dumps and restores, defaults to the CPU count
+	PgDumpPath    string `mapstructure:"pg_dump_path"`    // auto-discovered if empty
+	PgRestorePath string `mapstructure:"pg_restore_path"` // auto-discovered if empty
+	PsqlPath      string `mapstructure:"psql_path"`       // auto-discovered if empty
+}
+
+// IsPostgreSQL reports whether the database is a PostgreSQL server
+func (d *DatabaseConfig) IsPostgreSQL() bool {
+	return d.Type == DatabasePostgreSQL
+}
+
+// RecordedHost returns the host to record in manifests, logs and schema
+// history: the configured database.host, also while connected through an
+// SSH tunnel
+func (d *DatabaseConfig) RecordedHost() string {
+	if d.TunneledHost != "" {
+		return d.TunneledHost
+	}
+	return d.Host
+}
+
+// SSHConfig reaches a database through an SSH bastion. When Host is set, a
+// tunnel from a free local port to database.host:port is opened before
+// connecting and closed when the command finishes.
+type SSHConfig struct {
+	Host           string `mapstructure:"host"`
+	Port           int    `mapstructure:"port"`
+	User           string `mapstructure:"user"`
+	KeyFile        string `mapstructure:"key_file"`
+	KnownHostsFile string `mapstructure:"known_hosts_file"`
+	BinaryPath     string `mapstructure:"binary_path"`
+}
+
+// Enabled reports whether connections go through an SSH tunnel
+func (s SSHConfig) Enabled() bool {
+	return s.Host != ""
 }
 
 type BackupConfig struct {
@@ -40,10 +136,132 @@ type BackupConfig struct {
 	Timeout               time.Duration    `mapstructure:"timeout"`
 	RetryCount            int              `mapstructure:"retry_count"`
 	RetryDelay            time.Duration    `mapstructure:"retry_delay"`
+	RetryFailed           RetryFailedConfig `mapstructure:"retry_failed"` // retry passes over failed databases after the main pass
 	CheckLastBackupTime   bool             `mapstructure:"check_last_backup_time"`
 	MinBackupInterval     time.Duration    `mapstructure:"min_backup_interval"`
 	SkipConfirmation      bool             `mapstructure:"skip_confirmation"`
+	BatchDelay            time.Duration    `mapstructure:"batch_delay"` // pause between batches
+	Stagger               time.Duration    `mapstructure:"stagger"`     // pause between database starts within a batch
+	Jitter                time.Duration    `mapstructure:"jitter"`      // random extra of up to this much on each pause
+	Order                 string           `mapstructure:"order"`       // "config", "largest_first" or "smallest_first"
 	Compression           CompressionConfig `mapstructure:"compression"`
+	Encryption            EncryptionConfig `mapstructure:"encryption"`
+	ServerObjects         bool             `mapstructure:"server_objects"` // also back up MySQL 8 roles, resource groups and histograms
+	ObjectFiles           bool             `mapstructure:"object_files"`   // also write routines, views, triggers and events as one .sql file each
+	TargetCompat          string           `mapstructure:"target_compat"`  // rewrite dumps for an older server, e.g. "5.7"
+	Definer               string           `mapstructure:"definer"`        // "keep", "strip" or an account to rewrite DEFINER clauses to
+	NonTransactional      string           `mapstructure:"non_transactional"` // "warn", "lock" or "exclude" MEMORY, FEDERATED, CSV and MyISAM tables
+	MissingPrivileges     string           `mapstructure:"missing_privileges"` // "skip" or "abort" when the backup user lacks TRIGGER or EVENT
+	LargeTableRules       []LargeTableRule `mapstructure:"large_table_rules"`
+	Dependencies          []BackupDependency `mapstructure:"dependencies"`       // databases to back up before others
+	ConsistencyGroups     []ConsistencyGroup `mapstructure:"consistency_groups"` // databases to back up together
+	AppHooks              []AppHookConfig    `mapstructure:"app_hooks"`          // quiesce applications during their dump
+	SchemaHistory         SchemaHistoryConfig `mapstructure:"schema_history"`
+	Checksums             ChecksumConfig      `mapstructure:"checksums"`
+	ReportPath            string              `mapstructure:"report_path"` // optional JSON run result written after each backup run
+	Schedule              string              `mapstructure:"schedule"`    // when the backup timer fires: weekday list or cron expression
+	Watchdog              WatchdogConfig      `mapstructure:"watchdog"`
+	Pause                 PauseConfig         `mapstructure:"pause"`
+	SkipUnchanged         SkipUnchangedConfig `mapstructure:"skip_unchanged"`
+	Heartbeat             HeartbeatConfig     `mapstructure:"heartbeat"`
+	Impact                ImpactConfig        `mapstructure:"impact"`
+	Binlog                BinlogConfig        `mapstructure:"binlog"`
+}
+
+// WatchdogConfig makes tenangdb-exporter raise an alert when a scheduled
+// backup run did not start, such as when the host was asleep or the
+// scheduler is wedged
+type WatchdogConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	Grace   time.Duration `mapstructure:"grace"` // how late a run may start before it counts as missed
+}
+
+// PauseConfig sets how long a maintenance pause (tenangdb pause) may last
+// before tenangdb-exporter treats it as forgotten
+type PauseConfig struct {
+	AlertAfter time.Duration `mapstructure:"alert_after"`
+}
+
+// SkipUnchangedConfig keeps the last backup of a database nothing was
+// written to since, instead of dumping it again
+type SkipUnchangedConfig struct {
+	Enabled bool          `mapstructure:"enabled"`
+	MaxAge  time.Duration `mapstructure:"max_age"` // take a new backup anyway once the kept one is this old
+}
+
+// HeartbeatConfig writes a row with the run ID to a table of each database
+// right before its dump and checks the dump holds it, proving the dump saw
+// writes up to its start
+type HeartbeatConfig struct {
+	Enabled bool   `mapstructure:"enabled"`
+	Table   string `mapstructure:"table"` // created in each backed up database
+}
+
+// ImpactConfig samples lock waits and running threads on the server while
+// a run lasts, and summarises what the backup cost production in the run
+// result
+type ImpactConfig struct {
+	Enabled  bool          `mapstructure:"enabled"`
+	Interval time.Duration `mapstructure:"interval"` // time between samples
+}
+
+// BinlogConfig archives the binary logs of the server with
+// tenangdb backup --incremental, for point-in-time recovery on top of the
+// full mydumper backups
+type BinlogConfig struct {
+	MysqlbinlogPath string `mapstructure:"mysqlbinlog_path"`
+	Flush           bool   `mapstructure:"flush"` // close the active binary log first, so the archive reaches up to the run
+}
+
+// RetryFailedConfig retries the databases that failed once all others are
+// done, for lock waits and network blips that clear up after a while
+type RetryFailedConfig struct {
+	Attempts int           `mapstructure:"attempts"` // retry passes, 0 disables
+	Delay    time.Duration `mapstructure:"delay"`    // wait before each retry pass
+}
+
+// ChecksumConfig runs CHECKSUM TABLE on a sample of tables right before each
+// dump and records the results in the manifest, so drills can tell a source
+// that was already corrupted or diverged from a broken backup
+type ChecksumConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	SampleSize int  `mapstructure:"sample_size"` // tables per database, 0 checksums all of them
+}
+
+// SchemaHistoryConfig commits the schema of every backed up database to a
+// git repository, giving a history of schema changes tied to backup runs
+type SchemaHistoryConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Directory   string `mapstructure:"directory"`    // git repository, created if missing
+	Remote      string `mapstructure:"remote"`       // URL pushed to after each run, empty to keep the history local
+	Branch      string `mapstructure:"branch"`       // default "main"
+	GitPath     string `mapstructure:"git_path"`     // git binary, found in PATH by default
+	AuthorName  string `mapstructure:"author_name"`  // default "TenangDB"
+	AuthorEmail string `mapstructure:"author_email"` // default "tenangdb@localhost"
+}
+
+// BackupDependency starts the backup of databases matching Database only
+// after the databases matching After have finished
+type BackupDependency struct {
+	Database string   `mapstructure:"database"` // name or glob
+	After    []string `mapstructure:"after"`    // names or globs
+}
+
+// ConsistencyGroup is a set of databases whose dumps start together, so
+// their snapshots are taken within the same consistency window
+type ConsistencyGroup struct {
+	Name      string        `mapstructure:"name"`
+	Databases []string      `mapstructure:"databases"`
+	Window    time.Duration `mapstructure:"window"` // maximum spread of the dump starts, 0 to not check
+}
+
+// LargeTableRule splits the dump of matching tables into chunks. The first
+// rule matching a table applies.
+type LargeTableRule struct {
+	Table     string `mapstructure:"table"`       // table name or glob, empty matches all tables
+	MinSizeMB int64  `mapstructure:"min_size_mb"` // only tables with at least this much data
+	Rows      int64  `mapstructure:"rows"`        // rows per chunk
+	ChunkBy   string `mapstructure:"chunk_by"`    // integer column for mysqldump ranges, default the primary key
 }
 
 // CompressionConfig controls backup compression settings
@@ -53,8 +271,90 @@ type CompressionConfig struct {
 	Level         int    `mapstructure:"level"`          // 1-9 compression level
 	KeepOriginal  bool   `mapstructure:"keep_original"`  // Keep uncompressed backup locally
 	CompressUpload bool  `mapstructure:"compress_upload"` // Only compress for upload
+	Auto          bool   `mapstructure:"auto"`           // pick the format and level per backup from a sample
+	Goal          string `mapstructure:"goal"`           // what auto optimizes for: "size", "speed" or "balanced"
+	StreamUpload    bool `mapstructure:"stream_upload"`      // pipe the archive straight into rclone rcat, without a local archive file
+	StreamMinSizeMB int  `mapstructure:"stream_min_size_mb"` // smaller backups are compressed to a file first
+
+	// Write tar.zst archives as independent frames with a seek table, so
+	// single files can be extracted without decompressing the archive
+	Seekable bool `mapstructure:"seekable"`
+}
+
+// ArchivesBackup reports whether a backup made by tool is compressed into
+// an archive. With upload.archive_directories off, mydumper directories are
+// uploaded as a tree so single tables can be fetched from the remote.
+func (c *Config) ArchivesBackup(tool string) bool {
+	if !c.Backup.Compression.Enabled {
+		return false
+	}
+	return c.Upload.ArchiveDirectories || !c.Upload.Enabled || tool != "mydumper"
+}
+
+// Goals of automatic compression level selection
+const (
+	CompressionGoalSize     = "size"
+	CompressionGoalSpeed    = "speed"
+	CompressionGoalBalanced = "balanced"
+)
+
+// EncryptionConfig enables envelope encryption of backup artifacts. Every
+// backup gets its own data key, which is wrapped by a key management service
+// and stored, wrapped, in the artifact's header.
+type EncryptionConfig struct {
+	Enabled    bool          `mapstructure:"enabled"`
+	Provider   string        `mapstructure:"provider"`    // "aws-kms", "gcp-kms" or "vault-transit"
+	KeyID      string        `mapstructure:"key_id"`      // KMS key ARN or alias, GCP key resource name, or transit key name
+	CLIPath    string        `mapstructure:"cli_path"`    // aws, gcloud or vault binary, found in PATH by default
+	VaultMount string        `mapstructure:"vault_mount"` // mount path of the transit secrets engine
+	Mode       string        `mapstructure:"mode"`        // "stream", or "dedup" for dedup-friendly output
+	Generation time.Duration `mapstructure:"generation"`  // how long dedup mode keeps using one data key
+}
+
+// Key management services that can wrap data keys
+const (
+	EncryptionProviderAWSKMS       = "aws-kms"
+	EncryptionProviderGCPKMS       = "gcp-kms"
+	EncryptionProviderVaultTransit = "vault-transit"
+)
+
+// Encryption modes. Stream mode uses a new data key for every backup; dedup
+// mode reuses one key per generation and encrypts deterministically.
+const (
+	EncryptionModeStream = "stream"
+	EncryptionModeDedup  = "dedup"
+)
+
+// AppHookConfig puts an application into a quiet state while its database
+// is dumped and verifies that it took effect. The app templates fill in the
+// commands; set them to override the template or for a custom app.
+type AppHookConfig struct {
+	Database     string        `mapstructure:"database"`
+	App          string        `mapstructure:"app"`           // "wordpress", "magento", "laravel" or "custom"
+	Path         string        `mapstructure:"path"`          // application root, commands run there
+	User         string        `mapstructure:"user"`          // run the commands as this user through sudo
+	Before       string        `mapstructure:"before"`        // shell command run before the dump
+	After        string        `mapstructure:"after"`         // shell command run after the dump, even if it failed
+	VerifyBefore string        `mapstructure:"verify_before"` // succeeds once before took effect
+	VerifyAfter  string        `mapstructure:"verify_after"`  // succeeds once after took effect
+	Timeout      time.Duration `mapstructure:"timeout"`       // per command, default 2m
+	OnFailure    string        `mapstructure:"on_failure"`    // "abort" skips the dump, "continue" dumps anyway
 }
 
+// Applications with hook templates
+const (
+	AppWordPress = "wordpress"
+	AppMagento   = "magento"
+	AppLaravel   = "laravel"
+	AppCustom    = "custom"
+)
+
+// What to do when an app hook fails or does not take effect
+const (
+	HookFailureAbort    = "abort"
+	HookFailureContinue = "continue"
+)
+
 // MydumperConfig supports cross-platform mydumper versions with automatic parameter detection
 // Tested and supported versions:
 //   - v0.9.1+ (Ubuntu 18.04, older Linux distributions)
@@ -83,13 +383,311 @@ type MyloaderConfig struct {
 	Threads      int    `mapstructure:"threads"`
 }
 
+// ResourcesConfig bounds the CPU and memory of tenangdb and the dump tools
+// it runs, for shared database hosts
+type ResourcesConfig struct {
+	MaxProcs    int    `mapstructure:"max_procs"`    // GOMAXPROCS, 0 keeps Go's default
+	MemoryLimit string `mapstructure:"memory_limit"` // GOMEMLIMIT such as 512MiB or 2GiB, empty for none
+	CPUAffinity string `mapstructure:"cpu_affinity"` // CPUs such as 0-3,6 for tenangdb and its child processes (Linux)
+}
+
+// ParseMemoryLimit converts a GOMEMLIMIT style size, a number with an
+// optional B, KiB, MiB, GiB or TiB suffix, to bytes
+func ParseMemoryLimit(limit string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TiB", 1 << 40}, {"GiB", 1 << 30}, {"MiB", 1 << 20}, {"KiB", 1 << 10}, {"B", 1},
+	}
+
+	number, multiplier := strings.TrimSpace(limit), int64(1)
+	for _, unit := range units {
+		if strings.HasSuffix(number, unit.suffix) {
+			number, multiplier = strings.TrimSuffix(number, unit.suffix), unit.size
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n <= 0 || n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid memory limit %q, use a size such as 512MiB or 2GiB", limit)
+	}
+	return n * multiplier, nil
+}
+
+// ParseCPUList converts a CPU list such as 0-3,6 to CPU numbers
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(list, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU list %q, use CPUs and ranges such as 0-3,6", list)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.Atoi(last); err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU list %q, use CPUs and ranges such as 0-3,6", list)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			if !seen[cpu] {
+				seen[cpu] = true
+				cpus = append(cpus, cpu)
+			}
+		}
+	}
+	return cpus, nil
+}
+
+// validateResources checks the resources section
+func validateResources(r ResourcesConfig) error {
+	if r.MaxProcs < 0 {
+		return fmt.Errorf("resources.max_procs cannot be negative")
+	}
+	if r.MemoryLimit != "" {
+		if _, err := ParseMemoryLimit(r.MemoryLimit); err != nil {
+			return fmt.Errorf("resources.memory_limit: %w", err)
+		}
+	}
+	if r.CPUAffinity != "" {
+		if _, err := ParseCPUList(r.CPUAffinity); err != nil {
+			return fmt.Errorf("resources.cpu_affinity: %w", err)
+		}
+	}
+	return nil
+}
+
+// Compat57 makes backups restorable on MySQL 5.7
+const Compat57 = "5.7"
+
+// ValidateTargetCompat checks a backup compatibility target
+func ValidateTargetCompat(target string) error {
+	if target == "" || target == Compat57 {
+		return nil
+	}
+	return fmt.Errorf("backup target compat must be empty or '%s'", Compat57)
+}
+
+// DEFINER clause handling; any other value is an account such as app@%
+// that replaces the original definer
+const (
+	DefinerKeep  = "keep"
+	DefinerStrip = "strip"
+)
+
+// ValidateDefiner checks a DEFINER handling mode
+func ValidateDefiner(mode string) error {
+	switch mode {
+	case "", DefinerKeep, DefinerStrip, "CURRENT_USER":
+		return nil
+	}
+	at := strings.LastIndex(mode, "@")
+	if at <= 0 || at == len(mode)-1 || strings.ContainsAny(mode, " \t\n") {
+		return fmt.Errorf("definer must be '%s', '%s' or an account like app@%%, got %q", DefinerKeep, DefinerStrip, mode)
+	}
+	return nil
+}
+
+// Handling of tables on engines --single-transaction does not protect
+const (
+	NonTransactionalWarn    = "warn"    // dump them as usual and log a warning
+	NonTransactionalLock    = "lock"    // lock tables for the whole dump instead of using a transaction
+	NonTransactionalExclude = "exclude" // leave them out of the backup
+)
+
+// Handling of a backup user without the TRIGGER or EVENT privilege
+const (
+	MissingPrivilegesSkip  = "skip"  // back up without the objects it cannot read, with a warning
+	MissingPrivilegesAbort = "abort" // fail the database before dumping it
+)
+
+// Trigger handling modes for restore
+const (
+	TriggersRestore = "restore" // create triggers where they appear in the dump
+	TriggersSkip    = "skip"    // don't create triggers at all
+	TriggersDefer   = "defer"   // create triggers after all data is loaded
+)
+
+// RestoreConfig controls session settings applied while loading a backup
+
+type RestoreConfig struct {
+	SkipBinlog              bool    `mapstructure:"skip_binlog"` // SET sql_log_bin=0 so restores on a primary don't replicate
+	DisableForeignKeyChecks bool    `mapstructure:"disable_foreign_key_checks"`
+	DisableUniqueChecks     bool    `mapstructure:"disable_unique_checks"`
+	Triggers                string  `mapstructure:"triggers"`            // "restore", "skip" or "defer"
+	StrictCharset           bool    `mapstructure:"strict_charset"`      // abort when the target database charset differs from the backup
+	Definer                 string  `mapstructure:"definer"`             // "keep", "strip" or an account to rewrite DEFINER clauses to
+	DownloadStreams         int     `mapstructure:"download_streams"`    // parallel ranged streams per file when restoring from a remote
+	Resumable               bool    `mapstructure:"resumable"`           // load mydumper backups table by table, so restore --resume can continue
+	CompatCheck             bool    `mapstructure:"compat_check"`        // check the target server version and collations before loading
+	MaxExtractSizeGB        int     `mapstructure:"max_extract_size_gb"` // most a compressed backup may decompress to, 0 for no limit
+	TempDirectory           string  `mapstructure:"temp_directory"`      // where downloads, decrypted copies and decompressed backups go, "" for next to the backup
+	ExpansionFactor         float64 `mapstructure:"expansion_factor"`    // free space needed to decompress, as a multiple of the archive size, 0 skips the check
+}
+
 type UploadConfig struct {
-	Enabled          bool   `mapstructure:"enabled"`
-	RclonePath       string `mapstructure:"rclone_path"`
-	RcloneConfigPath string `mapstructure:"rclone_config_path"`
-	Destination      string `mapstructure:"destination"`
-	Timeout          int    `mapstructure:"timeout"`
-	RetryCount       int    `mapstructure:"retry_count"`
+	Enabled             bool         `mapstructure:"enabled"`
+	RclonePath          string       `mapstructure:"rclone_path"`
+	RcloneConfigPath    string       `mapstructure:"rclone_config_path"`
+	Destination         string       `mapstructure:"destination"`
+	FallbackDestination string       `mapstructure:"fallback_destination"` // used when destination fails after all retries
+	Timeout             int          `mapstructure:"timeout"`
+	RetryCount          int          `mapstructure:"retry_count"`
+	Metadata            bool         `mapstructure:"metadata"` // tag uploaded objects with the run ID (rclone 1.59+)
+	Proxy               ProxyConfig  `mapstructure:"proxy"`
+	Tuning              UploadTuning `mapstructure:"tuning"`
+	ArchiveDirectories  bool         `mapstructure:"archive_directories"` // false uploads mydumper directories as a tree instead of a tar archive
+	SplitSizeGB         int          `mapstructure:"split_size_gb"`       // upload larger files in parts of this size, 0 uploads them whole
+
+	// How the copies uploaded to each destination are compressed and
+	// encrypted, when they differ from the local backup
+	DestinationPolicy DestinationPolicy `mapstructure:"destination_policy"`
+	FallbackPolicy    DestinationPolicy `mapstructure:"fallback_policy"`
+}
+
+// DestinationPolicy rewrites the copy of an archived backup uploaded to one
+// destination. The zero value uploads the backup as it is.
+type DestinationPolicy struct {
+	Compression string           `mapstructure:"compression"` // "tar.gz" or "tar.zst" to recompress the archive, empty keeps it
+	Encryption  EncryptionConfig `mapstructure:"encryption"`  // encrypts the copy; stream mode only
+}
+
+// Changes reports whether the policy uploads something else than the
+// local backup
+func (p DestinationPolicy) Changes() bool {
+	return p.Compression != "" || p.Encryption.Enabled
+}
+
+// HasPolicies reports whether either destination has a policy
+func (u *UploadConfig) HasPolicies() bool {
+	return u.DestinationPolicy.Changes() || u.FallbackPolicy.Changes()
+}
+
+// UploadTuning passes rclone transfer options; zero values keep rclone's
+// defaults. Sizes use rclone's suffixes, e.g. "64M".
+type UploadTuning struct {
+	Transfers           int    `mapstructure:"transfers"`             // files uploaded in parallel
+	Checkers            int    `mapstructure:"checkers"`              // parallel equality checks
+	S3ChunkSize         string `mapstructure:"s3_chunk_size"`         // multipart part size for S3 compatible remotes
+	S3UploadConcurrency int    `mapstructure:"s3_upload_concurrency"` // parts of one file uploaded in parallel
+	DriveChunkSize      string `mapstructure:"drive_chunk_size"`      // Google Drive upload chunk, a power of 2 of at least 256k
+}
+
+// rcloneSizePattern matches rclone size values such as 512k, 64M or 1.5G
+var rcloneSizePattern = regexp.MustCompile(`^\d+(\.\d+)?[bBkKMGTP]?$`)
+
+// validateTuning checks the rclone transfer options
+func validateTuning(t UploadTuning) error {
+	if t.Transfers < 0 || t.Checkers < 0 || t.S3UploadConcurrency < 0 {
+		return fmt.Errorf("upload.tuning: transfers, checkers and s3_upload_concurrency cannot be negative")
+	}
+	if t.S3ChunkSize != "" && !rcloneSizePattern.MatchString(t.S3ChunkSize) {
+		return fmt.Errorf("upload.tuning.s3_chunk_size: invalid size %q (use e.g. 64M)", t.S3ChunkSize)
+	}
+	if t.DriveChunkSize != "" && !rcloneSizePattern.MatchString(t.DriveChunkSize) {
+		return fmt.Errorf("upload.tuning.drive_chunk_size: invalid size %q (use e.g. 64M)", t.DriveChunkSize)
+	}
+	return nil
+}
+
+// ProxyConfig routes upload traffic through an HTTP(S) or SOCKS5 proxy
+type ProxyConfig struct {
+	URL     string `mapstructure:"url"`      // http://, https:// or socks5:// address, may include user:password
+	NoProxy string `mapstructure:"no_proxy"` // comma separated hosts that bypass the proxy
+}
+
+// Env returns the proxy environment variables for upload commands. rclone
+// reads the standard variables and accepts socks5:// URLs in them.
+func (p ProxyConfig) Env() []string {
+	if p.URL == "" {
+		return nil
+	}
+
+	env := []string{
+		"HTTP_PROXY=" + p.URL, "http_proxy=" + p.URL,
+		"HTTPS_PROXY=" + p.URL, "https_proxy=" + p.URL,
+		"ALL_PROXY=" + p.URL, "all_proxy=" + p.URL,
+	}
+	if p.NoProxy != "" {
+		env = append(env, "NO_PROXY="+p.NoProxy, "no_proxy="+p.NoProxy)
+	}
+	return env
+}
+
+// validateProxy checks that the proxy URL has a supported scheme and a host
+func validateProxy(p ProxyConfig) error {
+	if p.URL == "" {
+		return nil
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return fmt.Errorf("upload.proxy.url: %w", err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return fmt.Errorf("upload.proxy.url: unsupported scheme %q (use http, https or socks5)", u.Scheme)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("upload.proxy.url: missing host")
+	}
+	return nil
+}
+
+// validatePostgreSQL checks database.type and, for PostgreSQL servers,
+// that no MySQL-only feature is turned on, rather than ignoring it
+func validatePostgreSQL(config *Config) error {
+	switch config.Database.Type {
+	case DatabaseMySQL:
+		return nil
+	case DatabasePostgreSQL:
+	default:
+		return fmt.Errorf("database.type must be mysql or postgresql, got %q", config.Database.Type)
+	}
+
+	switch config.Database.PostgreSQL.Format {
+	case "plain", "custom", "directory", "tar":
+	default:
+		return fmt.Errorf("database.postgresql.format must be plain, custom, directory or tar, got %q", config.Database.PostgreSQL.Format)
+	}
+	if config.Database.PostgreSQL.Jobs < 0 {
+		return fmt.Errorf("database.postgresql.jobs cannot be negative")
+	}
+
+	backup := config.Backup
+	mysqlOnly := map[string]bool{
+		"database.mydumper":        config.Database.Mydumper != nil && config.Database.Mydumper.Enabled,
+		"database.container":       config.Database.Container != "",
+		"database.socket":          config.Database.Socket != "",
+		"backup.checksums":         backup.Checksums.Enabled,
+		"backup.heartbeat":         backup.Heartbeat.Enabled,
+		"backup.skip_unchanged":    backup.SkipUnchanged.Enabled,
+		"backup.impact":            backup.Impact.Enabled,
+		"backup.schema_history":    backup.SchemaHistory.Enabled,
+		"backup.server_objects":    backup.ServerObjects,
+		"backup.object_files":      backup.ObjectFiles,
+		"backup.target_compat":     backup.TargetCompat != "",
+		"backup.definer":           backup.Definer != "" && backup.Definer != DefinerKeep,
+		"backup.large_table_rules": len(backup.LargeTableRules) > 0,
+		"backup.order":             backup.Order != "config",
+		"standbys":                 len(config.Standbys) > 0,
+		"kubernetes.discover":      config.Kubernetes.Discover,
+	}
+	var set []string
+	for name, on := range mysqlOnly {
+		if on {
+			set = append(set, name)
+		}
+	}
+	if len(set) > 0 {
+		sort.Strings(set)
+		return fmt.Errorf("%s only work with MySQL, turn them off for database.type postgresql", strings.Join(set, ", "))
+	}
+	return nil
 }
 
 type LoggingConfig struct {
@@ -97,56 +695,202 @@ type LoggingConfig struct {
 	Format     string `mapstructure:"format"`
 	FileFormat string `mapstructure:"file_format"`
 	FilePath   string `mapstructure:"file_path"`
+	Emoji      bool   `mapstructure:"emoji"` // decorate messages with emoji and status symbols
 }
 
 type CleanupConfig struct {
 	Enabled              bool     `mapstructure:"enabled"`
 	CleanupUploadedFiles bool     `mapstructure:"cleanup_uploaded_files"`
 	RemoteRetention      int      `mapstructure:"remote_retention_days"`
-	WeekendOnly          bool     `mapstructure:"weekend_only"`
+	WeekendOnly          bool     `mapstructure:"weekend_only"` // used when schedule is empty
+	Schedule             string   `mapstructure:"schedule"`     // weekday list ("Sat,Sun") or cron expression
 	AgeBasedCleanup      bool     `mapstructure:"age_based_cleanup"`
 	MaxAgeDays           int      `mapstructure:"max_age_days"`
+	MinKeep              int      `mapstructure:"min_keep"` // newest backups per database that age-based cleanup never deletes
+	TrashRetentionHours  int      `mapstructure:"trash_retention_hours"` // keep removed backups in .trash for this long, 0 deletes immediately
+	ReportPath           string   `mapstructure:"report_path"`           // optional JSON report written after each cleanup
 	VerifyCloudExists    bool     `mapstructure:"verify_cloud_exists"`
 	Databases            []string `mapstructure:"databases"`
 }
 
+// TenantConfig groups databases owned by one team. Every run applies the
+// tenant's upload prefix, retention and concurrency quota to its
+// databases, writes their part of the run result for the team's
+// notifications, and --tenant narrows a command to them.
+type TenantConfig struct {
+	Name         string   `mapstructure:"name"`
+	Databases    []string `mapstructure:"databases"`
+	UploadPrefix string   `mapstructure:"upload_prefix"` // appended to upload.destination
+	MaxAgeDays   int      `mapstructure:"max_age_days"`  // overrides cleanup.max_age_days
+	Concurrency  int      `mapstructure:"concurrency"`   // upper bound for backup.concurrency
+	ReportPath   string   `mapstructure:"report_path"`   // JSON run result of the tenant's databases, written after each backup run
+}
+
+// StandbyConfig is a standby or staging server that refresh-standby
+// overwrites with the latest verified backups
+type StandbyConfig struct {
+	Name      string        `mapstructure:"name"`
+	Host      string        `mapstructure:"host"`
+	Port      int           `mapstructure:"port"`      // defaults to database.port
+	Username  string        `mapstructure:"username"`  // defaults to database.username
+	Password  string        `mapstructure:"password"`  // defaults to database.password
+	Databases []string      `mapstructure:"databases"` // defaults to backup.databases
+	Masking   []MaskingRule `mapstructure:"masking"`
+}
+
+// MaskingRule overwrites a column after a standby refresh, e.g. customer
+// emails on a staging server
+type MaskingRule struct {
+	Database string `mapstructure:"database"` // empty applies to every refreshed database
+	Table    string `mapstructure:"table"`
+	Column   string `mapstructure:"column"`
+	Value    string `mapstructure:"value"` // SQL expression, e.g. CONCAT('user', id, '@example.com')
+}
+
+// ConnectionConfig returns the database connection to the standby. Tool
+// paths and tuning come from base, the production connection; SSH, socket
+// and container settings do not apply.
+func (s *StandbyConfig) ConnectionConfig(base DatabaseConfig) DatabaseConfig {
+	conn := base
+	conn.Host = s.Host
+	conn.Socket = ""
+	conn.SSH = SSHConfig{}
+	conn.Container = ""
+	if s.Port != 0 {
+		conn.Port = s.Port
+	}
+	if s.Username != "" {
+		conn.Username = s.Username
+		conn.Password = s.Password
+	}
+	return conn
+}
+
+// SLAConfig sets how old the newest verified backup of a database may get
+// before `tenangdb sla status` and the exporter report a breach
+type SLAConfig struct {
+	MaxBackupAge time.Duration `mapstructure:"max_backup_age"` // default for every database, 0 disables
+	Databases    []DatabaseSLA `mapstructure:"databases"`      // per-database overrides
+}
+
+// DatabaseSLA overrides the SLA of one database
+type DatabaseSLA struct {
+	Name         string        `mapstructure:"name"`
+	MaxBackupAge time.Duration `mapstructure:"max_backup_age"`
+}
+
+// MaxAgeFor returns the SLA of dbName, 0 if it has none
+func (s *SLAConfig) MaxAgeFor(dbName string) time.Duration {
+	for _, db := range s.Databases {
+		if db.Name == dbName {
+			return db.MaxBackupAge
+		}
+	}
+	return s.MaxBackupAge
+}
+
+// ReportConfig holds settings for 'tenangdb report'
+type ReportConfig struct {
+	StorageCostPerGB float64 `mapstructure:"storage_cost_per_gb"` // monthly price of cloud storage, 0 hides the estimate
+	Currency         string  `mapstructure:"currency"`
+}
+
+// DrillConfig schedules restore drills: the latest backup of a randomly
+// picked database is restored into a scratch instance and verified
+type DrillConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Target    string        `mapstructure:"target"`    // standby used as the scratch instance
+	Schedule  string        `mapstructure:"schedule"`  // weekday list ("Sun") or cron expression
+	Databases []string      `mapstructure:"databases"` // candidates, defaults to backup.databases
+	MaxRTO    time.Duration `mapstructure:"max_rto"`   // fail drills whose restore takes longer, 0 for no limit
+}
+
+// KubernetesConfig discovers MySQL instances from annotated Services for
+// `tenangdb k8s backup`. The API server, token and CA default to the
+// in-cluster service account.
+type KubernetesConfig struct {
+	Discover   bool     `mapstructure:"discover"`
+	Namespaces []string `mapstructure:"namespaces"` // empty searches every namespace
+	APIServer  string   `mapstructure:"api_server"`
+	TokenFile  string   `mapstructure:"token_file"`
+	CAFile     string   `mapstructure:"ca_file"`
+}
+
 type MetricsConfig struct {
 	Enabled     bool   `mapstructure:"enabled"`
 	Port        string `mapstructure:"port"`
 	StoragePath string `mapstructure:"storage_path"`
+	// Serve /metrics on Port while a backup runs; turn off to rely on
+	// tenangdb-exporter alone
+	InlineServer bool `mapstructure:"inline_server"`
+	// Go runtime and process metrics served by tenangdb-exporter
+	GoMetrics      bool `mapstructure:"go_metrics"`
+	ProcessMetrics bool `mapstructure:"process_metrics"`
 }
 
+// LoadConfig reads and validates a config file, or the first one found in
+// the platform config paths when configPath is empty, with the fragments
+// listed under include: merged over it. Every call uses its
+// own viper instance and returns a Config that shares nothing with other
+// loads, so configs can be reloaded or loaded concurrently. Commands derive
+// changed configs with Clone, ForTenant and ForAdHoc instead of changing
+// a loaded one.
 func LoadConfig(configPath string) (*Config, error) {
+	return loadConfig(configPath, nil)
+}
+
+// LoadAdHocConfig loads a config for an ad-hoc backup of databases, which
+// need not be in backup.databases. They are added to it before validation,
+// so a config listing no databases loads as well.
+func LoadAdHocConfig(configPath string, databases []string) (*Config, error) {
+	return loadConfig(configPath, databases)
+}
+
+func loadConfig(configPath string, adHocDatabases []string) (*Config, error) {
+	v := viper.New()
+
 	// Set default values first
-	setDefaults()
+	setDefaults(v)
 
 	// If specific config path is provided, use it directly
-	if configPath != "" {
-		viper.SetConfigFile(configPath)
-		viper.SetConfigType("yaml")
-
-		if err := viper.ReadInConfig(); err != nil {
-			return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
-		}
-	} else {
+	if configPath == "" {
 		// Auto-discover config file using multi-platform paths
 		foundPath, err := findConfigFile()
 		if err != nil {
 			return nil, fmt.Errorf("failed to find config file: %w", err)
 		}
+		configPath = foundPath
+	}
+
+	v.SetConfigFile(configPath)
+	v.SetConfigType("yaml")
 
-		viper.SetConfigFile(foundPath)
-		viper.SetConfigType("yaml")
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
 
-		if err := viper.ReadInConfig(); err != nil {
-			return nil, fmt.Errorf("failed to read config file %s: %w", foundPath, err)
+	seen := map[string]bool{filepath.Clean(configPath): true}
+	for _, include := range v.GetStringSlice("include") {
+		if err := mergeInclude(v, includePath(configPath, include), seen); err != nil {
+			return nil, err
 		}
 	}
 
 	var config Config
-	if err := viper.Unmarshal(&config); err != nil {
+	if err := v.Unmarshal(&config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if config.Database.IsPostgreSQL() && !v.InConfig("database.port") {
+		config.Database.Port = 5432
+	}
+	applyStateDirectory(&config)
+	unquoteDatabaseNames(&config)
+	for _, db := range adHocDatabases {
+		db = UnquoteDatabaseName(db)
+		if !config.HasDatabase(db) {
+			config.Backup.Databases = append(config.Backup.Databases, db)
+		}
+	}
 
 	if err := validateConfig(&config); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -155,6 +899,43 @@ func LoadConfig(configPath string) (*Config, error) {
 	return &config, nil
 }
 
+// mergeInclude merges a config fragment, and then the fragments it includes
+// in turn, over the settings read so far. Later files win: maps are merged
+// key by key, lists and scalars are replaced.
+func mergeInclude(v *viper.Viper, path string, seen map[string]bool) error {
+	if seen[path] {
+		return fmt.Errorf("config file %s is included more than once", path)
+	}
+	seen[path] = true
+
+	fragment := viper.New()
+	fragment.SetConfigFile(path)
+	fragment.SetConfigType("yaml")
+	if err := fragment.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read included config file %s: %w", path, err)
+	}
+
+	includes := fragment.GetStringSlice("include")
+	if err := v.MergeConfigMap(fragment.AllSettings()); err != nil {
+		return fmt.Errorf("failed to merge included config file %s: %w", path, err)
+	}
+	for _, include := range includes {
+		if err := mergeInclude(v, includePath(path, include), seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// includePath resolves an include relative to the file that includes it
+func includePath(from, include string) string {
+	include = expandHomeDir(include)
+	if !filepath.IsAbs(include) {
+		include = filepath.Join(filepath.Dir(from), include)
+	}
+	return filepath.Clean(include)
+}
+
 // findConfigFile searches for config file in platform-specific locations
 func findConfigFile() (string, error) {
 	configPaths := getConfigPaths()
@@ -407,120 +1188,286 @@ func FindRclonePath() string {
 	return findRclonePath()
 }
 
-func setDefaults() {
-	viper.SetDefault("database.host", "localhost")
-	viper.SetDefault("database.port", 3306)
-	viper.SetDefault("database.timeout", 30)
-	viper.SetDefault("database.mysqldump_path", findMysqldumpPath())
-	viper.SetDefault("database.mysql_path", findMysqlPath())
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("database.type", DatabaseMySQL)
+	v.SetDefault("database.host", "localhost")
+	v.SetDefault("database.port", 3306)
+	v.SetDefault("database.timeout", 30)
+	v.SetDefault("database.mysqldump_path", findMysqldumpPath())
+	v.SetDefault("database.mysql_path", findMysqlPath())
+	v.SetDefault("database.docker_path", "docker")
+	v.SetDefault("kubernetes.token_file", "/var/run/secrets/kubernetes.io/serviceaccount/token")
+	v.SetDefault("kubernetes.ca_file", "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt")
+	v.SetDefault("database.max_open_conns", 10)
+	v.SetDefault("database.max_idle_conns", 5)
+	v.SetDefault("database.postgresql.format", "custom")
+	v.SetDefault("database.ssh.port", 22)
+	v.SetDefault("database.ssh.binary_path", "ssh")
 
 	// Platform-specific backup directories
 	if runtime.GOOS == "darwin" {
 		if isRunningAsRoot() {
-			viper.SetDefault("backup.directory", "/usr/local/var/tenangdb/backups")
+			v.SetDefault("backup.directory", "/usr/local/var/tenangdb/backups")
 		} else {
-			viper.SetDefault("backup.directory", expandHomeDir("~/Library/Application Support/TenangDB/backups"))
+			v.SetDefault("backup.directory", expandHomeDir("~/Library/Application Support/TenangDB/backups"))
 		}
 	} else {
 		if isRunningAsRoot() {
-			viper.SetDefault("backup.directory", "/var/backups/tenangdb")
+			v.SetDefault("backup.directory", "/var/backups/tenangdb")
 		} else {
-			viper.SetDefault("backup.directory", expandHomeDir("~/.local/share/tenangdb/backups"))
+			v.SetDefault("backup.directory", expandHomeDir("~/.local/share/tenangdb/backups"))
 		}
 	}
-	viper.SetDefault("backup.batch_size", 5)
-	viper.SetDefault("backup.concurrency", 3)
-	viper.SetDefault("backup.timeout", "30m")
-	viper.SetDefault("backup.retry_count", 3)
-	viper.SetDefault("backup.retry_delay", "10s")
-	viper.SetDefault("backup.check_last_backup_time", true)
-	viper.SetDefault("backup.min_backup_interval", "1h")
-	viper.SetDefault("backup.skip_confirmation", false)
+	v.SetDefault("backup.batch_size", 5)
+	v.SetDefault("backup.concurrency", 3)
+	v.SetDefault("backup.timeout", "30m")
+	v.SetDefault("backup.retry_count", 3)
+	v.SetDefault("backup.retry_delay", "10s")
+	v.SetDefault("backup.retry_failed.attempts", 0)
+	v.SetDefault("backup.retry_failed.delay", "20m")
+	v.SetDefault("backup.skip_unchanged.enabled", false)
+	v.SetDefault("backup.skip_unchanged.max_age", "168h")
+	v.SetDefault("backup.heartbeat.enabled", false)
+	v.SetDefault("backup.heartbeat.table", "tenangdb_heartbeat")
+	v.SetDefault("backup.impact.enabled", false)
+	v.SetDefault("backup.impact.interval", "10s")
+	v.SetDefault("backup.binlog.mysqlbinlog_path", "mysqlbinlog")
+	v.SetDefault("backup.binlog.flush", true)
+	v.SetDefault("backup.check_last_backup_time", true)
+	v.SetDefault("backup.min_backup_interval", "1h")
+	v.SetDefault("backup.skip_confirmation", false)
+	v.SetDefault("backup.batch_delay", "5s")
+	v.SetDefault("backup.order", "config")
+	v.SetDefault("backup.stagger", "0s")
+	v.SetDefault("backup.jitter", "0s")
 	
 	// Compression defaults
-	viper.SetDefault("backup.compression.enabled", false)
-	viper.SetDefault("backup.compression.format", "tar.gz")
-	viper.SetDefault("backup.compression.level", 6)
-	viper.SetDefault("backup.compression.keep_original", true)
-	viper.SetDefault("backup.compression.compress_upload", true)
+	v.SetDefault("backup.compression.enabled", false)
+	v.SetDefault("backup.compression.format", "tar.gz")
+	v.SetDefault("backup.compression.level", 6)
+	v.SetDefault("backup.compression.keep_original", true)
+	v.SetDefault("backup.compression.compress_upload", true)
+	v.SetDefault("backup.compression.auto", false)
+	v.SetDefault("backup.compression.goal", CompressionGoalBalanced)
+	v.SetDefault("backup.compression.stream_upload", false)
+	v.SetDefault("backup.compression.stream_min_size_mb", 1024)
+	v.SetDefault("backup.compression.seekable", false)
+	v.SetDefault("backup.encryption.enabled", false)
+	v.SetDefault("backup.encryption.vault_mount", "transit")
+	v.SetDefault("backup.encryption.mode", EncryptionModeStream)
+	v.SetDefault("backup.encryption.generation", "720h")
+	v.SetDefault("backup.non_transactional", NonTransactionalWarn)
+	v.SetDefault("backup.missing_privileges", MissingPrivilegesSkip)
+	v.SetDefault("backup.checksums.enabled", false)
+	v.SetDefault("backup.checksums.sample_size", 10)
+	v.SetDefault("backup.schema_history.enabled", false)
+	v.SetDefault("backup.schema_history.branch", "main")
+	v.SetDefault("backup.schema_history.git_path", "git")
+	v.SetDefault("backup.schema_history.author_name", "TenangDB")
+	v.SetDefault("backup.schema_history.author_email", "tenangdb@localhost")
+	v.SetDefault("backup.report_path", "")
+	v.SetDefault("backup.schedule", "0 0 * * *")
+	v.SetDefault("backup.watchdog.enabled", false)
+	v.SetDefault("backup.watchdog.grace", "2h")
+	v.SetDefault("backup.pause.alert_after", "24h")
 
 	// Platform-specific binary paths and directories
 	if runtime.GOOS == "darwin" {
 		// macOS defaults (Homebrew)
-		viper.SetDefault("database.mydumper.binary_path", findMydumperPath())
-		viper.SetDefault("database.mydumper.myloader.binary_path", findMyloaderPath())
-		viper.SetDefault("upload.rclone_path", findRclonePath())
-		viper.SetDefault("upload.rclone_config_path", expandHomeDir("~/.config/rclone/rclone.conf"))
+		v.SetDefault("database.mydumper.binary_path", findMydumperPath())
+		v.SetDefault("database.mydumper.myloader.binary_path", findMyloaderPath())
+		v.SetDefault("upload.rclone_path", findRclonePath())
+		v.SetDefault("upload.rclone_config_path", expandHomeDir("~/.config/rclone/rclone.conf"))
 		
 		if isRunningAsRoot() {
-			viper.SetDefault("logging.file_path", "/usr/local/var/log/tenangdb/tenangdb.log")
+			v.SetDefault("logging.file_path", "/usr/local/var/log/tenangdb/tenangdb.log")
 		} else {
-			viper.SetDefault("logging.file_path", expandHomeDir("~/Library/Logs/TenangDB/tenangdb.log"))
+			v.SetDefault("logging.file_path", expandHomeDir("~/Library/Logs/TenangDB/tenangdb.log"))
 		}
 	} else {
 		// Linux/Unix defaults
-		viper.SetDefault("database.mydumper.binary_path", findMydumperPath())
-		viper.SetDefault("database.mydumper.myloader.binary_path", findMyloaderPath())
-		viper.SetDefault("upload.rclone_path", findRclonePath())
-		viper.SetDefault("upload.rclone_config_path", expandHomeDir("~/.config/rclone/rclone.conf"))
+		v.SetDefault("database.mydumper.binary_path", findMydumperPath())
+		v.SetDefault("database.mydumper.myloader.binary_path", findMyloaderPath())
+		v.SetDefault("upload.rclone_path", findRclonePath())
+		v.SetDefault("upload.rclone_config_path", expandHomeDir("~/.config/rclone/rclone.conf"))
 		
 		if isRunningAsRoot() {
-			viper.SetDefault("logging.file_path", "/var/log/tenangdb/tenangdb.log")
+			v.SetDefault("logging.file_path", "/var/log/tenangdb/tenangdb.log")
 		} else {
-			viper.SetDefault("logging.file_path", expandHomeDir("~/.local/share/tenangdb/logs/tenangdb.log"))
+			v.SetDefault("logging.file_path", expandHomeDir("~/.local/share/tenangdb/logs/tenangdb.log"))
 		}
 	}
 
 	// Mydumper defaults
-	viper.SetDefault("database.mydumper.enabled", false)
-	viper.SetDefault("database.mydumper.threads", 4)
-	viper.SetDefault("database.mydumper.chunk_filesize", 100)
-	viper.SetDefault("database.mydumper.compress_method", "gzip")
-	viper.SetDefault("database.mydumper.build_empty_files", false)
-	viper.SetDefault("database.mydumper.use_defer", true)
-	viper.SetDefault("database.mydumper.single_table", false)
-	viper.SetDefault("database.mydumper.no_schemas", false)
-	viper.SetDefault("database.mydumper.no_data", false)
+	v.SetDefault("database.mydumper.enabled", false)
+	v.SetDefault("database.mydumper.threads", 4)
+	v.SetDefault("database.mydumper.chunk_filesize", 100)
+	v.SetDefault("database.mydumper.compress_method", "gzip")
+	v.SetDefault("database.mydumper.build_empty_files", false)
+	v.SetDefault("database.mydumper.use_defer", true)
+	v.SetDefault("database.mydumper.single_table", false)
+	v.SetDefault("database.mydumper.no_schemas", false)
+	v.SetDefault("database.mydumper.no_data", false)
 
 	// Myloader defaults
-	viper.SetDefault("database.mydumper.myloader.enabled", false)
-	viper.SetDefault("database.mydumper.myloader.threads", 4)
-
-	viper.SetDefault("upload.enabled", false)
-	viper.SetDefault("upload.timeout", 300)
-	viper.SetDefault("upload.retry_count", 3)
-
-	viper.SetDefault("logging.level", "info")
-	viper.SetDefault("logging.format", "clean")
-	viper.SetDefault("logging.file_format", "text")
-
-	viper.SetDefault("cleanup.enabled", false)
-	viper.SetDefault("cleanup.cleanup_uploaded_files", true)
-	viper.SetDefault("cleanup.remote_retention_days", 30)
-	viper.SetDefault("cleanup.weekend_only", true)
-	viper.SetDefault("cleanup.age_based_cleanup", false)
-	viper.SetDefault("cleanup.max_age_days", 7)
-	viper.SetDefault("cleanup.verify_cloud_exists", true)
-
-	viper.SetDefault("metrics.enabled", false)
-	viper.SetDefault("metrics.port", "8080")
+	v.SetDefault("database.mydumper.myloader.enabled", false)
+	v.SetDefault("database.mydumper.myloader.threads", 4)
+
+	v.SetDefault("upload.enabled", false)
+	v.SetDefault("upload.timeout", 300)
+	v.SetDefault("upload.retry_count", 3)
+	v.SetDefault("upload.metadata", false)
+	v.SetDefault("upload.archive_directories", true)
+	v.SetDefault("upload.split_size_gb", 0)
+	for _, policy := range []string{"destination_policy", "fallback_policy"} {
+		v.SetDefault("upload."+policy+".encryption.vault_mount", "transit")
+		v.SetDefault("upload."+policy+".encryption.mode", EncryptionModeStream)
+	}
+
+	v.SetDefault("restore.skip_binlog", false)
+	v.SetDefault("restore.disable_foreign_key_checks", false)
+	v.SetDefault("restore.disable_unique_checks", false)
+	v.SetDefault("restore.triggers", TriggersRestore)
+	v.SetDefault("restore.strict_charset", false)
+	v.SetDefault("restore.download_streams", 4)
+	v.SetDefault("restore.resumable", true)
+	v.SetDefault("restore.compat_check", true)
+	v.SetDefault("restore.max_extract_size_gb", 1024)
+	v.SetDefault("restore.expansion_factor", 5)
+
+	v.SetDefault("logging.level", "info")
+	v.SetDefault("logging.format", "clean")
+	v.SetDefault("logging.file_format", "text")
+	v.SetDefault("logging.emoji", true)
+
+	v.SetDefault("cleanup.enabled", false)
+	v.SetDefault("cleanup.cleanup_uploaded_files", true)
+	v.SetDefault("cleanup.remote_retention_days", 30)
+	v.SetDefault("cleanup.weekend_only", true)
+	v.SetDefault("cleanup.schedule", "")
+	v.SetDefault("cleanup.age_based_cleanup", false)
+	v.SetDefault("cleanup.max_age_days", 7)
+	v.SetDefault("cleanup.min_keep", 2)
+	v.SetDefault("cleanup.trash_retention_hours", 0)
+	v.SetDefault("cleanup.report_path", "")
+	v.SetDefault("cleanup.verify_cloud_exists", true)
+
+	v.SetDefault("report.storage_cost_per_gb", 0)
+	v.SetDefault("report.currency", "USD")
+	v.SetDefault("drill.enabled", false)
+	v.SetDefault("drill.schedule", "Sun")
+
+	v.SetDefault("metrics.enabled", false)
+	v.SetDefault("metrics.port", "8080")
+	v.SetDefault("metrics.inline_server", true)
+	v.SetDefault("metrics.go_metrics", true)
+	v.SetDefault("metrics.process_metrics", true)
 	
-	// Platform-specific metrics storage paths
+	// metrics.storage_path and backup.schema_history.directory default to
+	// state_directory, see applyStateDirectory
+	v.SetDefault("state_directory", defaultStateDirectory())
+}
+
+// defaultStateDirectory returns where state lives when state_directory is
+// not configured. Containers use /var/lib/tenangdb, which the image declares
+// as a volume so that state survives restarts; elsewhere XDG_STATE_HOME is
+// honored, except by root and on macOS.
+func defaultStateDirectory() string {
+	if isRunningInContainer() {
+		return "/var/lib/tenangdb"
+	}
 	if runtime.GOOS == "darwin" {
 		if isRunningAsRoot() {
-			viper.SetDefault("metrics.storage_path", "/usr/local/var/tenangdb/metrics.json")
-		} else {
-			viper.SetDefault("metrics.storage_path", expandHomeDir("~/Library/Application Support/TenangDB/metrics.json"))
+			return "/usr/local/var/tenangdb"
 		}
-	} else {
-		if isRunningAsRoot() {
-			viper.SetDefault("metrics.storage_path", "/var/lib/tenangdb/metrics.json")
-		} else {
-			viper.SetDefault("metrics.storage_path", expandHomeDir("~/.local/share/tenangdb/metrics.json"))
+		return expandHomeDir("~/Library/Application Support/TenangDB")
+	}
+	if isRunningAsRoot() {
+		return "/var/lib/tenangdb"
+	}
+	if stateHome := os.Getenv("XDG_STATE_HOME"); stateHome != "" {
+		return filepath.Join(stateHome, "tenangdb")
+	}
+	// Installs of older releases keep their metrics and schema history
+	legacy := expandHomeDir("~/.local/share/tenangdb")
+	if _, err := os.Stat(filepath.Join(legacy, "metrics.json")); err == nil {
+		return legacy
+	}
+	return expandHomeDir("~/.local/state/tenangdb")
+}
+
+// Container runtimes reported by ContainerRuntime
+const (
+	ContainerDocker     = "docker"
+	ContainerKubernetes = "kubernetes"
+)
+
+// ContainerRuntime returns ContainerKubernetes inside a Kubernetes pod,
+// ContainerDocker inside another Docker container and "" outside containers
+func ContainerRuntime() string {
+	if os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return ContainerKubernetes
+	}
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return ContainerDocker
+	}
+	return ""
+}
+
+// isRunningInContainer reports whether the process runs in a Docker or
+// Kubernetes container
+func isRunningInContainer() bool {
+	return ContainerRuntime() != ""
+}
+
+// UnquoteDatabaseName removes the backticks or ANSI double quotes a
+// database name may be written in, so `my-app` and "my-app" both name the
+// database my-app. Doubled quote characters inside stand for one.
+func UnquoteDatabaseName(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) < 2 {
+		return name
+	}
+	for _, quote := range []string{"`", `"`} {
+		if strings.HasPrefix(name, quote) && strings.HasSuffix(name, quote) {
+			return strings.ReplaceAll(name[1:len(name)-1], quote+quote, quote)
+		}
+	}
+	return name
+}
+
+// unquoteDatabaseNames applies UnquoteDatabaseName to every list of
+// database names in the config
+func unquoteDatabaseNames(config *Config) {
+	lists := []*[]string{&config.Backup.Databases, &config.Cleanup.Databases, &config.Drill.Databases}
+	for i := range config.Tenants {
+		lists = append(lists, &config.Tenants[i].Databases)
+	}
+	for i := range config.Standbys {
+		lists = append(lists, &config.Standbys[i].Databases)
+	}
+	for i := range config.Backup.ConsistencyGroups {
+		lists = append(lists, &config.Backup.ConsistencyGroups[i].Databases)
+	}
+	for _, list := range lists {
+		for i, name := range *list {
+			(*list)[i] = UnquoteDatabaseName(name)
 		}
 	}
 }
 
+// applyStateDirectory expands state_directory and places the state files
+// that were not configured on their own inside it
+func applyStateDirectory(config *Config) {
+	config.StateDirectory = expandHomeDir(config.StateDirectory)
+	if config.Metrics.StoragePath == "" {
+		config.Metrics.StoragePath = filepath.Join(config.StateDirectory, "metrics.json")
+	}
+	if config.Backup.SchemaHistory.Directory == "" {
+		config.Backup.SchemaHistory.Directory = filepath.Join(config.StateDirectory, "schema-history")
+	}
+}
+
 // GetConfigPaths returns the config paths for the current platform (for CLI help)
 func GetConfigPaths() []string {
 	return getConfigPaths()
@@ -537,11 +1484,22 @@ func isRunningAsRoot() bool {
 }
 
 func validateConfig(config *Config) error {
-	if config.Database.Username == "" {
+	// Container credentials are read from its environment when connecting,
+	// discovered instances bring their own credentials and databases
+	discovered := config.Kubernetes.Discover
+	if config.Database.Username == "" && config.Database.Container == "" && !discovered {
 		return fmt.Errorf("database username is required")
 	}
 
-	if len(config.Backup.Databases) == 0 {
+	if config.Database.Container != "" && (config.Database.SSH.Enabled() || config.Database.Socket != "") {
+		return fmt.Errorf("database.container cannot be combined with database.ssh or database.socket")
+	}
+
+	if err := validatePostgreSQL(config); err != nil {
+		return err
+	}
+
+	if len(config.Backup.Databases) == 0 && !discovered {
 		return fmt.Errorf("at least one database must be specified")
 	}
 
@@ -553,10 +1511,230 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("concurrency must be greater than 0")
 	}
 
+	if config.Backup.BatchDelay < 0 || config.Backup.Stagger < 0 || config.Backup.Jitter < 0 {
+		return fmt.Errorf("batch_delay, stagger and jitter cannot be negative")
+	}
+
+	switch config.Backup.Order {
+	case "config", "largest_first", "smallest_first":
+	default:
+		return fmt.Errorf("backup.order must be config, largest_first or smallest_first, got %q", config.Backup.Order)
+	}
+
 	if config.Upload.Enabled && config.Upload.Destination == "" {
 		return fmt.Errorf("upload destination is required when upload is enabled")
 	}
 
+	if config.Upload.FallbackDestination != "" && config.Upload.FallbackDestination == config.Upload.Destination {
+		return fmt.Errorf("upload.fallback_destination must differ from upload.destination")
+	}
+
+	if err := validateProxy(config.Upload.Proxy); err != nil {
+		return err
+	}
+
+	if config.Upload.SplitSizeGB < 0 {
+		return fmt.Errorf("upload.split_size_gb cannot be negative")
+	}
+
+	if err := validateTuning(config.Upload.Tuning); err != nil {
+		return err
+	}
+
+	if err := validateResources(config.Resources); err != nil {
+		return err
+	}
+
+	db := config.Database
+	if db.DialTimeout < 0 || db.ReadTimeout < 0 || db.WriteTimeout < 0 || db.ConnMaxLifetime < 0 {
+		return fmt.Errorf("database timeouts cannot be negative")
+	}
+	if db.MaxOpenConns < 0 || db.MaxIdleConns < 0 {
+		return fmt.Errorf("database.max_open_conns and max_idle_conns cannot be negative")
+	}
+
+	for i, rule := range config.Backup.LargeTableRules {
+		if rule.Rows <= 0 {
+			return fmt.Errorf("backup.large_table_rules[%d]: rows must be greater than 0", i)
+		}
+		if rule.MinSizeMB < 0 {
+			return fmt.Errorf("backup.large_table_rules[%d]: min_size_mb cannot be negative", i)
+		}
+		if _, err := filepath.Match(rule.Table, ""); err != nil {
+			return fmt.Errorf("backup.large_table_rules[%d]: invalid table pattern %q: %w", i, rule.Table, err)
+		}
+	}
+
+	if err := validateOrdering(&config.Backup); err != nil {
+		return err
+	}
+
+	if err := validateAppHooks(config); err != nil {
+		return err
+	}
+
+	if history := config.Backup.SchemaHistory; history.Enabled {
+		if history.Directory == "" {
+			return fmt.Errorf("backup.schema_history.directory is required when schema history is enabled")
+		}
+		// Cleanup walks the backup directory and would delete the repository
+		if rel, err := filepath.Rel(config.Backup.Directory, history.Directory); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("backup.schema_history.directory must be outside backup.directory")
+		}
+	}
+
+	if config.Backup.Checksums.SampleSize < 0 {
+		return fmt.Errorf("backup.checksums.sample_size cannot be negative")
+	}
+
+	for _, pattern := range config.Policy.DenyRestoreTo {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("policy.deny_restore_to: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	if config.Database.SSH.Enabled() {
+		if config.Database.SSH.User == "" {
+			return fmt.Errorf("database.ssh.user is required when database.ssh.host is set")
+		}
+		if config.Database.Socket != "" {
+			return fmt.Errorf("database.socket cannot be combined with database.ssh")
+		}
+	}
+
+	switch config.Backup.Compression.Goal {
+	case CompressionGoalSize, CompressionGoalSpeed, CompressionGoalBalanced:
+	default:
+		return fmt.Errorf("invalid backup.compression.goal %q, must be size, speed or balanced", config.Backup.Compression.Goal)
+	}
+	if config.Backup.RetryFailed.Attempts < 0 || config.Backup.RetryFailed.Delay < 0 {
+		return fmt.Errorf("backup.retry_failed: attempts and delay cannot be negative")
+	}
+
+	if config.Backup.SkipUnchanged.Enabled && config.Backup.SkipUnchanged.MaxAge <= 0 {
+		return fmt.Errorf("backup.skip_unchanged.max_age must be positive")
+	}
+
+	if config.Backup.Heartbeat.Enabled && config.Backup.Heartbeat.Table == "" {
+		return fmt.Errorf("backup.heartbeat.table cannot be empty")
+	}
+	if config.Backup.Impact.Enabled && config.Backup.Impact.Interval < time.Second {
+		return fmt.Errorf("backup.impact.interval must be at least 1s")
+	}
+	if config.Backup.Binlog.MysqlbinlogPath == "" {
+		return fmt.Errorf("backup.binlog.mysqlbinlog_path cannot be empty")
+	}
+	if _, err := schedule.Parse(config.Backup.Schedule); err != nil {
+		return fmt.Errorf("backup.schedule: %w", err)
+	}
+	if config.Backup.Watchdog.Grace < 0 {
+		return fmt.Errorf("backup.watchdog.grace cannot be negative")
+	}
+	if config.Backup.Pause.AlertAfter <= 0 {
+		return fmt.Errorf("backup.pause.alert_after must be positive")
+	}
+
+	if config.Backup.Compression.Seekable && strings.ToLower(config.Backup.Compression.Format) != "tar.zst" {
+		return fmt.Errorf("backup.compression.seekable needs format tar.zst, not %s", config.Backup.Compression.Format)
+	}
+	if config.Backup.Compression.StreamUpload {
+		if format := strings.ToLower(config.Backup.Compression.Format); format != "tar.gz" && format != "tgz" && format != "tar.zst" {
+			return fmt.Errorf("backup.compression.stream_upload needs format tar.gz or tar.zst, not %s", config.Backup.Compression.Format)
+		}
+		if config.Backup.Compression.StreamMinSizeMB < 0 {
+			return fmt.Errorf("backup.compression.stream_min_size_mb cannot be negative")
+		}
+	}
+
+	if err := validateEncryption(config); err != nil {
+		return err
+	}
+	if err := validateDestinationPolicies(config); err != nil {
+		return err
+	}
+
+	if config.SLA.MaxBackupAge < 0 {
+		return fmt.Errorf("sla.max_backup_age cannot be negative")
+	}
+	for _, db := range config.SLA.Databases {
+		if !config.HasDatabase(db.Name) {
+			return fmt.Errorf("sla references database %s which is not in backup.databases", db.Name)
+		}
+		if db.MaxBackupAge < 0 {
+			return fmt.Errorf("sla max_backup_age of %s cannot be negative", db.Name)
+		}
+	}
+
+	if config.Report.StorageCostPerGB < 0 {
+		return fmt.Errorf("report.storage_cost_per_gb cannot be negative")
+	}
+
+	if err := validateStandbys(config); err != nil {
+		return err
+	}
+
+	if err := validateDrill(config); err != nil {
+		return err
+	}
+
+	if err := validateTenants(config); err != nil {
+		return err
+	}
+
+	if err := ValidateTriggersMode(config.Restore.Triggers); err != nil {
+		return err
+	}
+
+	if err := ValidateTargetCompat(config.Backup.TargetCompat); err != nil {
+		return err
+	}
+
+	switch config.Backup.NonTransactional {
+	case "", NonTransactionalWarn, NonTransactionalLock, NonTransactionalExclude:
+	default:
+		return fmt.Errorf("backup.non_transactional must be '%s', '%s' or '%s', got %q",
+			NonTransactionalWarn, NonTransactionalLock, NonTransactionalExclude, config.Backup.NonTransactional)
+	}
+
+	switch config.Backup.MissingPrivileges {
+	case "", MissingPrivilegesSkip, MissingPrivilegesAbort:
+	default:
+		return fmt.Errorf("backup.missing_privileges must be '%s' or '%s', got %q",
+			MissingPrivilegesSkip, MissingPrivilegesAbort, config.Backup.MissingPrivileges)
+	}
+
+	if err := ValidateDefiner(config.Backup.Definer); err != nil {
+		return fmt.Errorf("backup %w", err)
+	}
+
+	if config.Restore.DownloadStreams < 0 {
+		return fmt.Errorf("restore.download_streams cannot be negative")
+	}
+
+	if config.Restore.MaxExtractSizeGB < 0 {
+		return fmt.Errorf("restore.max_extract_size_gb cannot be negative")
+	}
+
+	if config.Restore.ExpansionFactor < 0 {
+		return fmt.Errorf("restore.expansion_factor cannot be negative")
+	}
+
+	if err := ValidateDefiner(config.Restore.Definer); err != nil {
+		return fmt.Errorf("restore %w", err)
+	}
+
+	if config.Cleanup.MinKeep < 0 {
+		return fmt.Errorf("cleanup.min_keep cannot be negative")
+	}
+
+	if config.Cleanup.TrashRetentionHours < 0 {
+		return fmt.Errorf("cleanup.trash_retention_hours cannot be negative")
+	}
+
+	if _, err := schedule.Parse(config.Cleanup.EffectiveSchedule()); err != nil {
+		return fmt.Errorf("cleanup.schedule: %w", err)
+	}
+
 	// Mydumper validation
 	if config.Database.Mydumper != nil && config.Database.Mydumper.Enabled {
 		if config.Database.Mydumper.Threads <= 0 {
@@ -581,3 +1759,438 @@ func validateConfig(config *Config) error {
 
 	return nil
 }
+
+
+func validateTenants(config *Config) error {
+	configured := make(map[string]bool, len(config.Backup.Databases))
+	for _, db := range config.Backup.Databases {
+		configured[db] = true
+	}
+
+	seen := make(map[string]bool, len(config.Tenants))
+	owners := make(map[string]string)
+	reports := map[string]string{config.Backup.ReportPath: "backup.report_path"}
+	for _, tenant := range config.Tenants {
+		if tenant.Name == "" {
+			return fmt.Errorf("tenant name is required")
+		}
+		if seen[tenant.Name] {
+			return fmt.Errorf("duplicate tenant name: %s", tenant.Name)
+		}
+		seen[tenant.Name] = true
+
+		if len(tenant.Databases) == 0 {
+			return fmt.Errorf("tenant %s must specify at least one database", tenant.Name)
+		}
+		for _, db := range tenant.Databases {
+			if !configured[db] {
+				return fmt.Errorf("tenant %s references database %s which is not in backup.databases", tenant.Name, db)
+			}
+			if owner, ok := owners[db]; ok && owner != tenant.Name {
+				return fmt.Errorf("database %s belongs to both tenant %s and tenant %s", db, owner, tenant.Name)
+			}
+			owners[db] = tenant.Name
+		}
+
+		if tenant.MaxAgeDays < 0 {
+			return fmt.Errorf("tenant %s max_age_days must not be negative", tenant.Name)
+		}
+		if tenant.Concurrency < 0 {
+			return fmt.Errorf("tenant %s concurrency must not be negative", tenant.Name)
+		}
+		if tenant.ReportPath != "" {
+			if other, ok := reports[tenant.ReportPath]; ok {
+				return fmt.Errorf("tenant %s report_path %s is also used by %s", tenant.Name, tenant.ReportPath, other)
+			}
+			reports[tenant.ReportPath] = "tenant " + tenant.Name
+		}
+	}
+
+	return nil
+}
+
+// validateEncryption checks that encrypted backups are single files, which
+// are the only artifacts encryption handles
+func validateEncryption(config *Config) error {
+	encryption := config.Backup.Encryption
+	if !encryption.Enabled {
+		return nil
+	}
+
+	if err := validateEncryptionKey("backup.encryption", encryption); err != nil {
+		return err
+	}
+	switch encryption.Mode {
+	case EncryptionModeStream:
+	case EncryptionModeDedup:
+		if encryption.Generation <= 0 {
+			return fmt.Errorf("backup.encryption.generation must be greater than 0 in dedup mode")
+		}
+	default:
+		return fmt.Errorf("invalid backup.encryption.mode %q, must be stream or dedup", encryption.Mode)
+	}
+
+	// mydumper and chunked mysqldump backups are directories until archived
+	usesMydumper := config.Database.Mydumper != nil && config.Database.Mydumper.Enabled
+	if usesMydumper && !config.ArchivesBackup("mydumper") {
+		return fmt.Errorf("backup.encryption with mydumper needs backup.compression enabled and upload.archive_directories on")
+	}
+	if len(config.Backup.LargeTableRules) > 0 && !config.Backup.Compression.Enabled {
+		return fmt.Errorf("backup.encryption with backup.large_table_rules needs backup.compression enabled")
+	}
+	return nil
+}
+
+// validateEncryptionKey checks the key management settings of the
+// encryption settings at name
+func validateEncryptionKey(name string, encryption EncryptionConfig) error {
+	switch encryption.Provider {
+	case EncryptionProviderAWSKMS, EncryptionProviderGCPKMS, EncryptionProviderVaultTransit:
+	default:
+		return fmt.Errorf("invalid %s.provider %q, must be aws-kms, gcp-kms or vault-transit", name, encryption.Provider)
+	}
+	if encryption.KeyID == "" {
+		return fmt.Errorf("%s.key_id is required when encryption is enabled", name)
+	}
+	return nil
+}
+
+// validateDestinationPolicies checks the upload destination policies. They
+// rewrite archived backups, which must not be encrypted already since the
+// upload cannot decrypt them.
+func validateDestinationPolicies(config *Config) error {
+	policies := []struct {
+		name   string
+		policy DestinationPolicy
+	}{
+		{"upload.destination_policy", config.Upload.DestinationPolicy},
+		{"upload.fallback_policy", config.Upload.FallbackPolicy},
+	}
+	usesMydumper := config.Database.Mydumper != nil && config.Database.Mydumper.Enabled
+	for _, p := range policies {
+		if !p.policy.Changes() {
+			continue
+		}
+		switch p.policy.Compression {
+		case "", "tar.gz", "tar.zst":
+		default:
+			return fmt.Errorf("invalid %s.compression %q, must be tar.gz or tar.zst", p.name, p.policy.Compression)
+		}
+		if p.policy.Encryption.Enabled {
+			if err := validateEncryptionKey(p.name+".encryption", p.policy.Encryption); err != nil {
+				return err
+			}
+			if p.policy.Encryption.Mode != "" && p.policy.Encryption.Mode != EncryptionModeStream {
+				return fmt.Errorf("%s.encryption.mode must be stream", p.name)
+			}
+		}
+		if config.Backup.Encryption.Enabled {
+			return fmt.Errorf("%s cannot be used with backup.encryption, encrypted backups are uploaded as they are", p.name)
+		}
+		if !config.Backup.Compression.Enabled || (usesMydumper && !config.ArchivesBackup("mydumper")) {
+			return fmt.Errorf("%s needs archived backups: backup.compression enabled, and upload.archive_directories on with mydumper", p.name)
+		}
+	}
+	if config.Upload.FallbackPolicy.Changes() && config.Upload.FallbackDestination == "" {
+		return fmt.Errorf("upload.fallback_policy needs upload.fallback_destination")
+	}
+	return nil
+}
+
+// validateDrill checks that drills restore into a configured standby and
+// only pick databases that are backed up
+func validateDrill(config *Config) error {
+	drill := config.Drill
+	if !drill.Enabled {
+		return nil
+	}
+
+	if drill.Target == "" {
+		return fmt.Errorf("drill.target is required when drills are enabled")
+	}
+	if _, err := config.Standby(drill.Target); err != nil {
+		return fmt.Errorf("drill.target: %w", err)
+	}
+	if _, err := schedule.Parse(drill.Schedule); err != nil {
+		return fmt.Errorf("drill.schedule: %w", err)
+	}
+	for _, db := range drill.Databases {
+		if !config.HasDatabase(db) {
+			return fmt.Errorf("drill references database %s which is not in backup.databases", db)
+		}
+	}
+	if drill.MaxRTO < 0 {
+		return fmt.Errorf("drill.max_rto cannot be negative")
+	}
+	return nil
+}
+
+// validateOrdering checks backup dependencies and consistency groups.
+// Dependency cycles are reported when the backup order is planned.
+func validateOrdering(backup *BackupConfig) error {
+	for i, dep := range backup.Dependencies {
+		if dep.Database == "" || len(dep.After) == 0 {
+			return fmt.Errorf("backup.dependencies[%d]: database and after are required", i)
+		}
+		for _, pattern := range append([]string{dep.Database}, dep.After...) {
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				return fmt.Errorf("backup.dependencies[%d]: invalid pattern %q: %w", i, pattern, err)
+			}
+		}
+	}
+
+	groupOf := make(map[string]string)
+	for _, group := range backup.ConsistencyGroups {
+		if group.Name == "" {
+			return fmt.Errorf("consistency group name is required")
+		}
+		if len(group.Databases) < 2 {
+			return fmt.Errorf("consistency group %s needs at least two databases", group.Name)
+		}
+		// Members start at once, so each needs a worker
+		if len(group.Databases) > backup.Concurrency {
+			return fmt.Errorf("consistency group %s has %d databases, more than backup.concurrency %d", group.Name, len(group.Databases), backup.Concurrency)
+		}
+		if group.Window < 0 {
+			return fmt.Errorf("consistency group %s: window cannot be negative", group.Name)
+		}
+		for _, db := range group.Databases {
+			if other, ok := groupOf[db]; ok {
+				return fmt.Errorf("database %s is in consistency groups %s and %s", db, other, group.Name)
+			}
+			groupOf[db] = group.Name
+		}
+	}
+	return nil
+}
+
+// validateAppHooks checks that app hooks name a known app and a backed up
+// database, and that custom apps say what to run
+func validateAppHooks(config *Config) error {
+	for i, hook := range config.Backup.AppHooks {
+		if hook.Database == "" {
+			return fmt.Errorf("backup.app_hooks[%d]: database is required", i)
+		}
+		if !config.HasDatabase(hook.Database) {
+			return fmt.Errorf("backup.app_hooks[%d]: database %s is not in backup.databases", i, hook.Database)
+		}
+		switch hook.App {
+		case AppWordPress, AppMagento, AppLaravel:
+			if hook.Path == "" {
+				return fmt.Errorf("backup.app_hooks[%d]: path to the %s installation is required", i, hook.App)
+			}
+		case AppCustom:
+			if hook.Before == "" || hook.After == "" {
+				return fmt.Errorf("backup.app_hooks[%d]: custom apps need before and after commands", i)
+			}
+		default:
+			return fmt.Errorf("backup.app_hooks[%d]: invalid app %q, must be wordpress, magento, laravel or custom", i, hook.App)
+		}
+		switch hook.OnFailure {
+		case "", HookFailureAbort, HookFailureContinue:
+		default:
+			return fmt.Errorf("backup.app_hooks[%d]: invalid on_failure %q, must be abort or continue", i, hook.OnFailure)
+		}
+		if hook.Timeout < 0 {
+			return fmt.Errorf("backup.app_hooks[%d]: timeout cannot be negative", i)
+		}
+	}
+	return nil
+}
+
+// validateStandbys checks that standby names are unique and that standbys
+// only receive backed up databases
+func validateStandbys(config *Config) error {
+	seen := make(map[string]bool, len(config.Standbys))
+	for _, standby := range config.Standbys {
+		if standby.Name == "" {
+			return fmt.Errorf("standby name is required")
+		}
+		if seen[standby.Name] {
+			return fmt.Errorf("duplicate standby name: %s", standby.Name)
+		}
+		seen[standby.Name] = true
+
+		if standby.Host == "" {
+			return fmt.Errorf("standby %s must specify a host", standby.Name)
+		}
+		// Container credentials are those of production, not the standby
+		if standby.Username == "" && config.Database.Username == "" {
+			return fmt.Errorf("standby %s must specify a username", standby.Name)
+		}
+		for _, db := range standby.Databases {
+			if !config.HasDatabase(db) {
+				return fmt.Errorf("standby %s references database %s which is not in backup.databases", standby.Name, db)
+			}
+		}
+		for _, rule := range standby.Masking {
+			if rule.Table == "" || rule.Column == "" || rule.Value == "" {
+				return fmt.Errorf("standby %s masking rules need table, column and value", standby.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// Standby returns the standby with the given name
+func (c *Config) Standby(name string) (*StandbyConfig, error) {
+	for i := range c.Standbys {
+		if c.Standbys[i].Name == name {
+			return &c.Standbys[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown standby: %s", name)
+}
+
+// ForTenant returns a copy of the configuration narrowed to a single
+// tenant: only the tenant's databases are backed up and cleaned. Its upload
+// prefix, retention and concurrency quota apply on every run, see TenantOf.
+// An empty name returns a plain copy.
+func (c *Config) ForTenant(name string) (*Config, error) {
+	scoped := c.Clone()
+	if name == "" {
+		return scoped, nil
+	}
+
+	var tenant *TenantConfig
+	for i := range scoped.Tenants {
+		if scoped.Tenants[i].Name == name {
+			tenant = &scoped.Tenants[i]
+			break
+		}
+	}
+	if tenant == nil {
+		return nil, fmt.Errorf("unknown tenant: %s", name)
+	}
+
+	scoped.Backup.Databases = append([]string(nil), tenant.Databases...)
+	scoped.Cleanup.Databases = append([]string(nil), tenant.Databases...)
+	return scoped, nil
+}
+
+// TenantOf returns the tenant dbName belongs to, or nil
+func (c *Config) TenantOf(dbName string) *TenantConfig {
+	for i := range c.Tenants {
+		for _, db := range c.Tenants[i].Databases {
+			if db == dbName {
+				return &c.Tenants[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Destination returns destination below the tenant's upload prefix. A nil
+// tenant, one without a prefix or an empty destination leave it as it is.
+func (t *TenantConfig) Destination(destination string) string {
+	if t == nil || t.UploadPrefix == "" || destination == "" {
+		return destination
+	}
+	return strings.TrimSuffix(destination, "/") + "/" + strings.Trim(t.UploadPrefix, "/")
+}
+
+// UploadFor returns the upload settings for backups of dbName: the primary
+// and fallback destinations are below its tenant's upload prefix
+func (c *Config) UploadFor(dbName string) *UploadConfig {
+	tenant := c.TenantOf(dbName)
+	if tenant == nil || tenant.UploadPrefix == "" {
+		return &c.Upload
+	}
+	upload := c.Upload
+	upload.Destination = tenant.Destination(upload.Destination)
+	upload.FallbackDestination = tenant.Destination(upload.FallbackDestination)
+	return &upload
+}
+
+// ForAdHoc returns a copy of the configuration scoped to an ad-hoc backup
+// of databases with default settings: ordering, consistency groups and app
+// hooks set up for the configured databases don't apply
+func (c *Config) ForAdHoc(databases []string) *Config {
+	scoped := c.Clone()
+	scoped.Backup.Databases = append([]string(nil), databases...)
+	scoped.Backup.Dependencies = nil
+	scoped.Backup.ConsistencyGroups = nil
+	scoped.Backup.AppHooks = nil
+	return scoped
+}
+
+// Clone returns a deep copy of the configuration. Commands apply tenants,
+// ad-hoc databases and command-line flags to a copy, so a loaded Config is
+// never changed under anyone else holding it.
+func (c *Config) Clone() *Config {
+	return deepCopy(reflect.ValueOf(c)).Interface().(*Config)
+}
+
+// deepCopy copies v along with the pointers, slices and maps it holds
+func deepCopy(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.New(v.Elem().Type())
+		copied.Elem().Set(deepCopy(v.Elem()))
+		return copied
+	case reflect.Struct:
+		copied := reflect.New(v.Type()).Elem()
+		copied.Set(v) // unexported fields, such as those of time.Time, as they are
+		for i := 0; i < v.NumField(); i++ {
+			if copied.Field(i).CanSet() {
+				copied.Field(i).Set(deepCopy(v.Field(i)))
+			}
+		}
+		return copied
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			copied.Index(i).Set(deepCopy(v.Index(i)))
+		}
+		return copied
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			copied.SetMapIndex(iter.Key(), deepCopy(iter.Value()))
+		}
+		return copied
+	}
+	return v
+}
+
+// HasDatabase reports whether dbName is one of the configured backup databases
+func (c *Config) HasDatabase(dbName string) bool {
+	for _, db := range c.Backup.Databases {
+		if db == dbName {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateTriggersMode checks a restore trigger handling mode
+func ValidateTriggersMode(mode string) error {
+	switch mode {
+	case "", TriggersRestore, TriggersSkip, TriggersDefer:
+		return nil
+	default:
+		return fmt.Errorf("restore triggers must be '%s', '%s' or '%s'", TriggersRestore, TriggersSkip, TriggersDefer)
+	}
+}
+
+// EffectiveSchedule returns cleanup.schedule, falling back to the legacy
+// weekend_only switch when no schedule is configured
+func (c *CleanupConfig) EffectiveSchedule() string {
+	if c.Schedule != "" {
+		return c.Schedule
+	}
+	if c.WeekendOnly {
+		return "Sat,Sun"
+	}
+	return "daily"
+}
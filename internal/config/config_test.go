@@ -0,0 +1,404 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigIsolated(t *testing.T) {
+	withPort := writeConfig(t, "a.yaml", `
+database:
+  username: a
+  port: 3307
+backup:
+  directory: /tmp/a
+  databases: [app]
+`)
+	withDefaults := writeConfig(t, "b.yaml", `
+database:
+  username: b
+backup:
+  directory: /tmp/b
+  databases: [crm, erp]
+`)
+
+	// Settings of one file must not leak into loads of another, concurrent
+	// or later
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			cfg, err := LoadConfig(withPort)
+			if err == nil && (cfg.Database.Port != 3307 || len(cfg.Backup.Databases) != 1) {
+				err = fmt.Errorf("a.yaml loaded as port %d, databases %v", cfg.Database.Port, cfg.Backup.Databases)
+			}
+			errs <- err
+		}()
+		go func() {
+			defer wg.Done()
+			cfg, err := LoadConfig(withDefaults)
+			if err == nil && (cfg.Database.Port != 3306 || len(cfg.Backup.Databases) != 2) {
+				err = fmt.Errorf("b.yaml loaded as port %d, databases %v", cfg.Database.Port, cfg.Backup.Databases)
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestLoadConfigInclude(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.yaml": `
+include:
+  - env/production.yaml
+  - secrets.yaml
+database:
+  host: db.internal
+  username: base
+backup:
+  directory: /tmp/backups
+  databases: [app, crm]
+`,
+		"env/production.yaml": `
+include: [../extra.yaml]
+database:
+  port: 3307
+backup:
+  databases: [app]
+`,
+		"extra.yaml": `
+database:
+  host: replica.internal
+`,
+		"secrets.yaml": `
+database:
+  username: backup
+  password: s3cret
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := cfg.Database
+	if db.Host != "replica.internal" || db.Port != 3307 || db.Username != "backup" || db.Password != "s3cret" {
+		t.Errorf("database = %s:%d %s/%s", db.Host, db.Port, db.Username, db.Password)
+	}
+	if len(cfg.Backup.Databases) != 1 || cfg.Backup.Databases[0] != "app" {
+		t.Errorf("databases = %v, want the list to be replaced", cfg.Backup.Databases)
+	}
+	if cfg.Backup.Directory != "/tmp/backups" {
+		t.Errorf("directory = %s, want the base setting kept", cfg.Backup.Directory)
+	}
+
+	loop := writeConfig(t, "loop.yaml", "include: [loop.yaml]\n")
+	if _, err := LoadConfig(loop); err == nil {
+		t.Error("a file including itself should fail to load")
+	}
+}
+
+func TestLoadConfigStateDirectory(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+state_directory: /srv/tenangdb
+database:
+  username: backup
+backup:
+  directory: /tmp/backups
+  databases: [app]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Metrics.StoragePath != "/srv/tenangdb/metrics.json" {
+		t.Errorf("metrics.storage_path = %s, want it in the state directory", cfg.Metrics.StoragePath)
+	}
+	if cfg.Backup.SchemaHistory.Directory != "/srv/tenangdb/schema-history" {
+		t.Errorf("schema_history.directory = %s, want it in the state directory", cfg.Backup.SchemaHistory.Directory)
+	}
+
+	explicit := writeConfig(t, "explicit.yaml", `
+state_directory: /srv/tenangdb
+database:
+  username: backup
+backup:
+  directory: /tmp/backups
+  databases: [app]
+metrics:
+  storage_path: /data/metrics.json
+`)
+	cfg, err = LoadConfig(explicit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Metrics.StoragePath != "/data/metrics.json" {
+		t.Errorf("metrics.storage_path = %s, want the configured path kept", cfg.Metrics.StoragePath)
+	}
+}
+
+func TestLoadAdHocConfig(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+database:
+  username: backup
+backup:
+  directory: /tmp/backups
+`)
+	if _, err := LoadConfig(path); err == nil {
+		t.Fatal("LoadConfig() accepted a config without databases")
+	}
+
+	cfg, err := LoadAdHocConfig(path, []string{"legacy_crm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = cfg.ForAdHoc([]string{"legacy_crm"})
+	if !reflect.DeepEqual(cfg.Backup.Databases, []string{"legacy_crm"}) {
+		t.Errorf("databases = %v, want [legacy_crm]", cfg.Backup.Databases)
+	}
+}
+
+func TestUnquoteDatabaseNames(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+database:
+  username: backup
+backup:
+  directory: /tmp/backups
+  databases:
+    - app
+    - "`+"`my-app`"+`"
+    - '"select"'
+    - '"say ""hi"""'
+    - "`+"`a``b`"+`"
+cleanup:
+  databases: ['"my.app"']
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"app", "my-app", "select", `say "hi"`, "a`b"}; !reflect.DeepEqual(cfg.Backup.Databases, want) {
+		t.Errorf("backup databases = %q, want %q", cfg.Backup.Databases, want)
+	}
+	if want := []string{"my.app"}; !reflect.DeepEqual(cfg.Cleanup.Databases, want) {
+		t.Errorf("cleanup databases = %q, want %q", cfg.Cleanup.Databases, want)
+	}
+}
+
+func TestParseResourceLimits(t *testing.T) {
+	for input, want := range map[string]int64{"1024": 1024, "512MiB": 512 << 20, "2GiB": 2 << 30, "64KiB": 64 << 10} {
+		got, err := ParseMemoryLimit(input)
+		if err != nil || got != want {
+			t.Errorf("ParseMemoryLimit(%q) = %d, %v; want %d", input, got, err, want)
+		}
+	}
+	for _, input := range []string{"", "2G", "-1MiB", "0"} {
+		if _, err := ParseMemoryLimit(input); err == nil {
+			t.Errorf("ParseMemoryLimit(%q) succeeded, want an error", input)
+		}
+	}
+
+	cpus, err := ParseCPUList("0-3, 6,2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{0, 1, 2, 3, 6}; !reflect.DeepEqual(cpus, want) {
+		t.Errorf("ParseCPUList = %v, want %v", cpus, want)
+	}
+	for _, input := range []string{"", "3-1", "a", "1-"} {
+		if _, err := ParseCPUList(input); err == nil {
+			t.Errorf("ParseCPUList(%q) succeeded, want an error", input)
+		}
+	}
+}
+
+func TestLoadPostgreSQLConfig(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+database:
+  type: postgresql
+  username: app
+backup:
+  directory: /tmp/pg
+  databases: [app]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Database.IsPostgreSQL() || cfg.Database.Port != 5432 || cfg.Database.PostgreSQL.Format != "custom" {
+		t.Errorf("loaded type %q port %d format %q, want postgresql on 5432 in custom format",
+			cfg.Database.Type, cfg.Database.Port, cfg.Database.PostgreSQL.Format)
+	}
+
+	// MySQL-only features are refused rather than silently skipped
+	mysqlOnly := writeConfig(t, "mysql-only.yaml", `
+database:
+  type: postgresql
+  username: app
+backup:
+  directory: /tmp/pg
+  databases: [app]
+  checksums:
+    enabled: true
+`)
+	if _, err := LoadConfig(mysqlOnly); err == nil || !strings.Contains(err.Error(), "backup.checksums") {
+		t.Errorf("got %v, want backup.checksums refused", err)
+	}
+
+	unknown := writeConfig(t, "unknown.yaml", `
+database:
+  type: oracle
+  username: app
+backup:
+  directory: /tmp/pg
+  databases: [app]
+`)
+	if _, err := LoadConfig(unknown); err == nil {
+		t.Error("database.type oracle was accepted")
+	}
+}
+
+func TestTenantResolution(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+database:
+  username: app
+backup:
+  directory: /tmp/tenants
+  databases: [app, payments, ledger]
+upload:
+  enabled: true
+  destination: remote:backups/
+  fallback_destination: other:backups
+tenants:
+  - name: finance
+    databases: [payments, ledger]
+    upload_prefix: /finance/
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every run resolves the tenant of each database, without --tenant
+	if tenant := cfg.TenantOf("ledger"); tenant == nil || tenant.Name != "finance" {
+		t.Errorf("ledger resolved to tenant %+v, want finance", tenant)
+	}
+	if cfg.TenantOf("app") != nil {
+		t.Error("app resolved to a tenant")
+	}
+	upload := cfg.UploadFor("payments")
+	if upload.Destination != "remote:backups/finance" || upload.FallbackDestination != "other:backups/finance" {
+		t.Errorf("payments uploads to %s and %s", upload.Destination, upload.FallbackDestination)
+	}
+	if upload := cfg.UploadFor("app"); upload.Destination != "remote:backups/" {
+		t.Errorf("app uploads to %s", upload.Destination)
+	}
+
+	overlap := writeConfig(t, "overlap.yaml", `
+database:
+  username: app
+backup:
+  directory: /tmp/tenants
+  databases: [app, payments]
+tenants:
+  - name: finance
+    databases: [payments]
+  - name: shop
+    databases: [app, payments]
+`)
+	if _, err := LoadConfig(overlap); err == nil || !strings.Contains(err.Error(), "both tenant finance and tenant shop") {
+		t.Errorf("got %v, want payments refused in two tenants", err)
+	}
+
+	sharedReport := writeConfig(t, "report.yaml", `
+database:
+  username: app
+backup:
+  directory: /tmp/tenants
+  databases: [app, payments]
+  report_path: /tmp/tenants/run.json
+tenants:
+  - name: finance
+    databases: [payments]
+    report_path: /tmp/tenants/run.json
+`)
+	if _, err := LoadConfig(sharedReport); err == nil || !strings.Contains(err.Error(), "also used by backup.report_path") {
+		t.Errorf("got %v, want the tenant report_path refused", err)
+	}
+}
+
+func TestConfigCopiesShareNothing(t *testing.T) {
+	path := writeConfig(t, "config.yaml", `
+database:
+  username: app
+backup:
+  directory: /tmp/tenants
+  databases: [app, payments]
+tenants:
+  - name: finance
+    databases: [payments]
+`)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A tenant's copy and the overrides of one command leave the loaded
+	// config as it was
+	scoped, err := cfg.ForTenant("finance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped.Backup.Concurrency = 99
+	scoped.Tenants[0].Databases[0] = "changed"
+	if !reflect.DeepEqual(cfg.Backup.Databases, []string{"app", "payments"}) || cfg.Backup.Concurrency == 99 {
+		t.Errorf("loaded config changed: databases %v, concurrency %d", cfg.Backup.Databases, cfg.Backup.Concurrency)
+	}
+	if cfg.Tenants[0].Databases[0] != "payments" {
+		t.Errorf("loaded tenant changed to %v", cfg.Tenants[0].Databases)
+	}
+
+	adHoc := cfg.ForAdHoc([]string{"legacy_crm"})
+	if !reflect.DeepEqual(adHoc.Backup.Databases, []string{"legacy_crm"}) || len(cfg.Backup.Databases) != 2 {
+		t.Errorf("ad-hoc databases %v, loaded databases %v", adHoc.Backup.Databases, cfg.Backup.Databases)
+	}
+
+	if _, err := cfg.ForTenant("shop"); err == nil {
+		t.Error("unknown tenant accepted")
+	}
+	if clone := cfg.Clone(); !reflect.DeepEqual(clone, cfg) {
+		t.Error("clone differs from the loaded config")
+	}
+}
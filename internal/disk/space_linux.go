@@ -0,0 +1,13 @@
+// Package disk reports filesystem space.
+package disk
+
+import "golang.org/x/sys/unix"
+
+// Space returns the free and total bytes of the filesystem holding path
+func Space(path string) (free, size uint64, err error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return 0, 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), stat.Blocks * uint64(stat.Bsize), nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+
+package disk
+
+import "fmt"
+
+// Space is only supported on Linux
+func Space(path string) (free, size uint64, err error) {
+	return 0, 0, fmt.Errorf("disk space is only reported on Linux")
+}
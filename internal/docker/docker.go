@@ -0,0 +1,183 @@
+// Package docker finds MySQL servers running in Docker containers and the
+// credentials their images were started with
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// mysqlPort is the port MySQL and MariaDB images listen on inside the container
+const mysqlPort = 3306
+
+// Container is what tenangdb needs to know about a database container
+type Container struct {
+	Name string
+	Host string // published address, or the container's own IP
+	Port int
+	Env  map[string]string
+}
+
+// inspectResult is the part of `docker inspect` output used here
+type inspectResult struct {
+	Name  string `json:"Name"`
+	State struct {
+		Running bool `json:"Running"`
+	} `json:"State"`
+	Config struct {
+		Env []string `json:"Env"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Ports map[string][]struct {
+			HostIP   string `json:"HostIp"`
+			HostPort string `json:"HostPort"`
+		} `json:"Ports"`
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+// Inspect looks up a running container. MySQL is reached through its
+// published port when 3306 is published, otherwise on the container's IP,
+// which works from the Docker host on Linux.
+func Inspect(ctx context.Context, dockerPath, name string) (*Container, error) {
+	out, err := run(ctx, dockerPath, "inspect", "--type", "container", name)
+	if err != nil {
+		return nil, err
+	}
+	var results []inspectResult
+	if err := json.Unmarshal(out, &results); err != nil || len(results) == 0 {
+		return nil, fmt.Errorf("unexpected docker inspect output for %s", name)
+	}
+	result := results[0]
+	if !result.State.Running {
+		return nil, fmt.Errorf("container %s is not running", name)
+	}
+
+	c := &Container{Name: name, Env: make(map[string]string)}
+	for _, kv := range result.Config.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			c.Env[k] = v
+		}
+	}
+
+	for _, binding := range result.NetworkSettings.Ports[fmt.Sprintf("%d/tcp", mysqlPort)] {
+		port, err := strconv.Atoi(binding.HostPort)
+		if err != nil {
+			continue
+		}
+		c.Host, c.Port = "127.0.0.1", port
+		if binding.HostIP != "" && binding.HostIP != "0.0.0.0" && binding.HostIP != "::" {
+			c.Host = binding.HostIP
+		}
+		return c, nil
+	}
+	for _, network := range result.NetworkSettings.Networks {
+		if network.IPAddress != "" {
+			c.Host, c.Port = network.IPAddress, mysqlPort
+			return c, nil
+		}
+	}
+	return nil, fmt.Errorf("container %s publishes no MySQL port and has no IP address", name)
+}
+
+// Credentials returns the account the container's image created from its
+// environment: root when its password is known, otherwise MYSQL_USER.
+// Passwords given as *_FILE secrets are read inside the container.
+func (c *Container) Credentials(ctx context.Context, dockerPath string) (string, string, error) {
+	if password, ok, err := c.secret(ctx, dockerPath, "ROOT_PASSWORD"); err != nil || ok {
+		return "root", password, err
+	}
+	if c.env("ALLOW_EMPTY_PASSWORD") != "" {
+		return "root", "", nil
+	}
+	if user := c.env("USER"); user != "" {
+		password, _, err := c.secret(ctx, dockerPath, "PASSWORD")
+		return user, password, err
+	}
+	return "", "", fmt.Errorf("container %s has no MYSQL_ROOT_PASSWORD or MYSQL_USER, set database.username and password", c.Name)
+}
+
+// env returns MYSQL_<name>, or MARIADB_<name> for MariaDB images
+func (c *Container) env(name string) string {
+	if value := c.Env["MARIADB_"+name]; value != "" {
+		return value
+	}
+	return c.Env["MYSQL_"+name]
+}
+
+// secret returns a password variable, reading the file a *_FILE variant
+// points to
+func (c *Container) secret(ctx context.Context, dockerPath, name string) (string, bool, error) {
+	if value := c.env(name); value != "" {
+		return value, true, nil
+	}
+	path := c.env(name + "_FILE")
+	if path == "" {
+		return "", false, nil
+	}
+	out, err := run(ctx, dockerPath, "exec", c.Name, "cat", path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return strings.TrimSpace(string(out)), true, nil
+}
+
+// Resolve points cfg at database.container: host and port become the
+// container's address, and an empty username or password is filled in
+// from the container's environment
+func Resolve(ctx context.Context, cfg *config.DatabaseConfig) error {
+	c, err := Inspect(ctx, cfg.DockerPath, cfg.Container)
+	if err != nil {
+		return err
+	}
+	cfg.Host, cfg.Port = c.Host, c.Port
+
+	if cfg.Username != "" && cfg.Password != "" {
+		return nil
+	}
+	user, password, err := c.Credentials(ctx, cfg.DockerPath)
+	if err != nil {
+		if cfg.Username != "" {
+			return nil // a configured user without a password
+		}
+		return err
+	}
+	if cfg.Username == "" {
+		cfg.Username, cfg.Password = user, password
+	} else if cfg.Username == user && cfg.Password == "" {
+		cfg.Password = password
+	}
+	return nil
+}
+
+// ExecArgs returns the docker command line running a MySQL client tool in
+// the container. The first of tools found in the container is used, so
+// MariaDB images without the mysql names work too.
+func ExecArgs(cfg *config.DatabaseConfig, tools []string, args ...string) []string {
+	var lookup []string
+	for _, tool := range tools {
+		lookup = append(lookup, "command -v "+tool)
+	}
+	script := `exec "$(` + strings.Join(lookup, " || ") + `)" "$@"`
+	return append([]string{cfg.DockerPath, "exec", "-i", cfg.Container, "sh", "-c", script, "sh"}, args...)
+}
+
+func run(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w (output: %s)", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
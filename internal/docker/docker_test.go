@@ -0,0 +1,79 @@
+package docker
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// fakeDocker writes a docker script that answers inspect with inspect and
+// cat of the secret file with secret
+func fakeDocker(t *testing.T, inspect, secret string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "inspect.json"), []byte(inspect), 0644); err != nil {
+		t.Fatal(err)
+	}
+	script := "#!/bin/sh\ncase \"$1\" in\ninspect) cat " + filepath.Join(dir, "inspect.json") + ";;\nexec) echo '" + secret + "';;\nesac\n"
+	path := filepath.Join(dir, "docker")
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestResolve(t *testing.T) {
+	published := `[{"State":{"Running":true},
+		"Config":{"Env":["MYSQL_ROOT_PASSWORD_FILE=/run/secrets/root","MYSQL_USER=app","MYSQL_PASSWORD=apppw"]},
+		"NetworkSettings":{"Ports":{"3306/tcp":[{"HostIp":"0.0.0.0","HostPort":"13306"}]},
+			"Networks":{"compose_default":{"IPAddress":"172.18.0.2"}}}}]`
+	cfg := config.DatabaseConfig{Container: "db", DockerPath: fakeDocker(t, published, "rootpw")}
+	if err := Resolve(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "127.0.0.1" || cfg.Port != 13306 || cfg.Username != "root" || cfg.Password != "rootpw" {
+		t.Errorf("resolved %s:%d as %s/%s", cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	}
+
+	// Unpublished MariaDB container with only an application user
+	internal := `[{"State":{"Running":true},
+		"Config":{"Env":["MARIADB_USER=app","MARIADB_PASSWORD=apppw"]},
+		"NetworkSettings":{"Ports":{"3306/tcp":null},"Networks":{"bridge":{"IPAddress":"172.17.0.3"}}}}]`
+	cfg = config.DatabaseConfig{Container: "db", DockerPath: fakeDocker(t, internal, "")}
+	if err := Resolve(context.Background(), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Host != "172.17.0.3" || cfg.Port != 3306 || cfg.Username != "app" || cfg.Password != "apppw" {
+		t.Errorf("resolved %s:%d as %s/%s", cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+	}
+
+	stopped := `[{"State":{"Running":false}}]`
+	cfg = config.DatabaseConfig{Container: "db", DockerPath: fakeDocker(t, stopped, "")}
+	if err := Resolve(context.Background(), &cfg); err == nil {
+		t.Error("resolved a stopped container")
+	}
+}
+
+func TestExecArgs(t *testing.T) {
+	cfg := &config.DatabaseConfig{Container: "db", DockerPath: "docker"}
+	args := ExecArgs(cfg, []string{"mariadb-dump", "mysqldump"}, "--user=root", "app")
+
+	// Run the script part with a PATH holding only mysqldump
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "mysqldump"), []byte("#!/bin/sh\necho mysqldump \"$@\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("/bin/sh", args[5:]...)
+	cmd.Env = []string{"PATH=" + bin}
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "mysqldump --user=root app\n" {
+		t.Errorf("ran %q", out)
+	}
+}
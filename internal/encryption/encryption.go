@@ -0,0 +1,405 @@
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// Suffix is appended to the name of encrypted artifacts
+const Suffix = ".enc"
+
+// Algorithm names the cipher of the data stream, recorded in manifests
+const Algorithm = "AES-256-GCM"
+
+// magic starts every encrypted file
+var magic = []byte("TDBENC1\n")
+
+const (
+	keySize     = 32
+	nonceSize   = 12
+	tagSize     = 16
+	chunkSize   = 64 * 1024
+	prefixSize  = 8
+	finalFlag   = 1 << 31
+	maxChunks   = 1<<32 - 1
+	maxHeaderSz = 64 * 1024
+
+	// Dedup mode pads the header and seals chunks to whole blocks, so equal
+	// data lines up with the blocks of dedup-aware storage
+	dedupHeaderSize = 4096
+	dedupBlockSize  = 1024 * 1024
+	dedupChunkSize  = dedupBlockSize - 4 - nonceSize - tagSize
+)
+
+// ErrTruncated is returned when an encrypted file ends before its final chunk
+var ErrTruncated = errors.New("encrypted backup is truncated")
+
+// Header is stored in clear at the start of an encrypted file, so the file
+// can be decrypted without its manifest by anyone allowed to unwrap the key
+type Header struct {
+	Provider   string `json:"provider"`
+	KeyID      string `json:"key_id"`
+	WrappedKey string `json:"wrapped_key"`
+	Mode       string `json:"mode,omitempty"` // empty for stream mode
+}
+
+// In stream mode the data after the header is a sequence of chunks of up to
+// 64KB, each sealed with AES-256-GCM under the data key. A chunk is written
+// as its 4-byte ciphertext length, whose top bit marks the last chunk,
+// followed by the ciphertext. The length is authenticated along with the
+// chunk and the nonce is a random per-file prefix followed by the chunk
+// counter, so chunks cannot be reordered, dropped or cut off without
+// decryption failing.
+//
+// In dedup mode chunks are sealed into 1MB blocks: the 4-byte plaintext
+// length with the same final bit, a 12-byte nonce and the ciphertext. The
+// nonce is an HMAC of the chunk's index and plaintext, so within one key
+// generation the same data at the same offset always encrypts to the same
+// block. The index is authenticated, and the nonce checked after
+// decryption, so chunks still cannot be moved or altered.
+
+// Writer encrypts a stream of data
+type Writer struct {
+	w        io.Writer
+	aead     cipher.AEAD
+	dedup    bool
+	prefix   [prefixSize]byte // stream mode nonce prefix
+	nonceKey []byte           // dedup mode nonce key
+	chunk    int
+	counter  uint32
+	buf      []byte
+	closed   bool
+}
+
+// NewWriter writes the header to w and returns a writer encrypting to it
+// with key, in the mode the header names. Close must be called to write
+// the final chunk.
+func NewWriter(w io.Writer, key []byte, header Header) (*Writer, error) {
+	out, err := encodeHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	ew := &Writer{w: w, chunk: chunkSize}
+	if header.Mode == config.EncryptionModeDedup {
+		ew.dedup, ew.chunk = true, dedupChunkSize
+	}
+	if ew.aead, ew.nonceKey, err = newCipher(key, ew.dedup); err != nil {
+		return nil, err
+	}
+	ew.buf = make([]byte, 0, ew.chunk)
+
+	if !ew.dedup {
+		if _, err := rand.Read(ew.prefix[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
+		out = append(out, ew.prefix[:]...)
+	}
+	if _, err := w.Write(out); err != nil {
+		return nil, err
+	}
+	return ew, nil
+}
+
+// encodeHeader returns the magic and header that start an encrypted file.
+// In dedup mode the header is padded to dedupHeaderSize.
+func encodeHeader(header Header) ([]byte, error) {
+	encoded, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Mode == config.EncryptionModeDedup {
+		padding := dedupHeaderSize - len(magic) - 4 - len(encoded)
+		if padding < 0 {
+			return nil, errors.New("encryption header is too large")
+		}
+		// JSON allows trailing whitespace
+		encoded = append(encoded, bytes.Repeat([]byte(" "), padding)...)
+	}
+
+	out := make([]byte, 0, len(magic)+4+len(encoded)+prefixSize)
+	out = append(out, magic...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(encoded)))
+	return append(out, encoded...), nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encryption writer")
+	}
+
+	n := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, so the last
+		// chunk is always the one Close seals
+		if len(w.buf) == w.chunk {
+			if err := w.seal(false); err != nil {
+				return n - len(p), err
+			}
+		}
+		k := copy(w.buf[len(w.buf):w.chunk], p)
+		w.buf = w.buf[:len(w.buf)+k]
+		p = p[k:]
+	}
+	return n, nil
+}
+
+// Close seals the final chunk. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *Writer) seal(final bool) error {
+	if w.counter == maxChunks {
+		return errors.New("encrypted stream is too large")
+	}
+
+	var sealed []byte
+	if w.dedup {
+		field := lengthField(uint32(len(w.buf)), final)
+		nonce := dedupNonce(w.nonceKey, w.counter, w.buf)
+		sealed = append(field[:], nonce...)
+		sealed = w.aead.Seal(sealed, nonce, w.buf, dedupAAD(w.counter, field))
+	} else {
+		field := lengthField(uint32(len(w.buf)+tagSize), final)
+		sealed = w.aead.Seal(append([]byte(nil), field[:]...), chunkNonce(w.prefix, w.counter), w.buf, field[:])
+	}
+
+	w.counter++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// Reader decrypts a stream written by Writer
+type Reader struct {
+	r        *bufio.Reader
+	aead     cipher.AEAD
+	dedup    bool
+	prefix   [prefixSize]byte
+	nonceKey []byte
+	counter  uint32
+	plain    []byte
+	done     bool
+}
+
+// ReadHeader reads the header of an encrypted stream. The returned reader
+// continues after the header and is passed to NewReader once the data key
+// is unwrapped.
+func ReadHeader(r io.Reader) (*Header, *bufio.Reader, error) {
+	br := bufio.NewReaderSize(r, chunkSize+64)
+
+	start := make([]byte, len(magic)+4)
+	if _, err := io.ReadFull(br, start); err != nil || string(start[:len(magic)]) != string(magic) {
+		return nil, nil, errors.New("not an encrypted backup")
+	}
+
+	size := binary.BigEndian.Uint32(start[len(magic):])
+	if size > maxHeaderSz {
+		return nil, nil, errors.New("encrypted backup header is too large")
+	}
+	encoded := make([]byte, size)
+	if _, err := io.ReadFull(br, encoded); err != nil {
+		return nil, nil, ErrTruncated
+	}
+
+	var header Header
+	if err := json.Unmarshal(encoded, &header); err != nil {
+		return nil, nil, fmt.Errorf("invalid encrypted backup header: %w", err)
+	}
+	return &header, br, nil
+}
+
+// NewReader returns a reader decrypting r, positioned after header, with
+// the data key
+func NewReader(r *bufio.Reader, header *Header, key []byte) (*Reader, error) {
+	er := &Reader{r: r, dedup: header.Mode == config.EncryptionModeDedup}
+
+	var err error
+	if er.aead, er.nonceKey, err = newCipher(key, er.dedup); err != nil {
+		return nil, err
+	}
+	if !er.dedup {
+		if _, err := io.ReadFull(r, er.prefix[:]); err != nil {
+			return nil, ErrTruncated
+		}
+	}
+	return er, nil
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *Reader) open() error {
+	var field [4]byte
+	if _, err := io.ReadFull(r.r, field[:]); err != nil {
+		return ErrTruncated
+	}
+
+	length := binary.BigEndian.Uint32(field[:])
+	final := length&finalFlag != 0
+	length &^= finalFlag
+
+	var plain []byte
+	var err error
+	if r.dedup {
+		plain, err = r.openDedup(field, length)
+	} else {
+		plain, err = r.openStream(field, length)
+	}
+	if err != nil {
+		return err
+	}
+	r.counter++
+	r.plain = plain
+
+	if final {
+		r.done = true
+		if _, err := r.r.ReadByte(); err != io.EOF {
+			return errors.New("encrypted backup has data after its final chunk")
+		}
+	}
+	return nil
+}
+
+func (r *Reader) openStream(field [4]byte, length uint32) ([]byte, error) {
+	if length < tagSize || length > chunkSize+tagSize {
+		return nil, errors.New("encrypted backup is corrupt")
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		return nil, ErrTruncated
+	}
+
+	plain, err := r.aead.Open(sealed[:0], chunkNonce(r.prefix, r.counter), sealed, field[:])
+	if err != nil {
+		return nil, errors.New("failed to decrypt backup: wrong key or corrupt data")
+	}
+	return plain, nil
+}
+
+func (r *Reader) openDedup(field [4]byte, length uint32) ([]byte, error) {
+	if length > dedupChunkSize {
+		return nil, errors.New("encrypted backup is corrupt")
+	}
+
+	sealed := make([]byte, nonceSize+length+tagSize)
+	if _, err := io.ReadFull(r.r, sealed); err != nil {
+		return nil, ErrTruncated
+	}
+
+	nonce := sealed[:nonceSize]
+	plain, err := r.aead.Open(nil, nonce, sealed[nonceSize:], dedupAAD(r.counter, field))
+	if err != nil || !hmac.Equal(nonce, dedupNonce(r.nonceKey, r.counter, plain)) {
+		return nil, errors.New("failed to decrypt backup: wrong key or corrupt data")
+	}
+	return plain, nil
+}
+
+// newCipher returns the AEAD for key. Dedup mode derives separate
+// encryption and nonce keys from it.
+func newCipher(key []byte, dedup bool) (cipher.AEAD, []byte, error) {
+	if len(key) != keySize {
+		return nil, nil, fmt.Errorf("data key must be %d bytes", keySize)
+	}
+
+	var nonceKey []byte
+	if dedup {
+		nonceKey = derive(key, "tenangdb dedup nonce")
+		key = derive(key, "tenangdb dedup encryption")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	return aead, nonceKey, err
+}
+
+func derive(key []byte, label string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+func lengthField(length uint32, final bool) [4]byte {
+	if final {
+		length |= finalFlag
+	}
+	var field [4]byte
+	binary.BigEndian.PutUint32(field[:], length)
+	return field
+}
+
+func chunkNonce(prefix [prefixSize]byte, counter uint32) []byte {
+	nonce := make([]byte, prefixSize+4)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[prefixSize:], counter)
+	return nonce
+}
+
+func dedupNonce(nonceKey []byte, counter uint32, plain []byte) []byte {
+	mac := hmac.New(sha256.New, nonceKey)
+	mac.Write(binary.BigEndian.AppendUint32(nil, counter))
+	mac.Write(plain)
+	return mac.Sum(nil)[:nonceSize]
+}
+
+func dedupAAD(counter uint32, field [4]byte) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, counter), field[:]...)
+}
+
+// NewDataKey generates a random data key for one backup
+func NewDataKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether path is an encrypted backup
+func IsEncrypted(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	start := make([]byte, len(magic))
+	if _, err := io.ReadFull(file, start); err != nil {
+		return false
+	}
+	return string(start) == string(magic)
+}
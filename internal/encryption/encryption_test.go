@@ -0,0 +1,124 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func encrypt(t *testing.T, key, plain []byte) []byte {
+	t.Helper()
+	return encryptMode(t, key, plain, "")
+}
+
+func encryptMode(t *testing.T, key, plain []byte, mode string) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := NewWriter(&out, key, Header{Provider: "vault-transit", KeyID: "backups", WrappedKey: "vault:v1:abc", Mode: mode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func decrypt(key, sealed []byte) (*Header, []byte, error) {
+	header, r, err := ReadHeader(bytes.NewReader(sealed))
+	if err != nil {
+		return nil, nil, err
+	}
+	dr, err := NewReader(r, header, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	plain, err := io.ReadAll(dr)
+	return header, plain, err
+}
+
+func TestRoundTrip(t *testing.T) {
+	key, _ := NewDataKey()
+	for _, size := range []int{0, 1, chunkSize, chunkSize + 1, 3*chunkSize + 17} {
+		plain := bytes.Repeat([]byte("tenangdb"), size/8+1)[:size]
+		header, got, err := decrypt(key, encrypt(t, key, plain))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Errorf("size %d: decrypted data differs", size)
+		}
+		if header.KeyID != "backups" || header.WrappedKey != "vault:v1:abc" {
+			t.Errorf("header = %+v", header)
+		}
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	key, _ := NewDataKey()
+	sealed := encrypt(t, key, bytes.Repeat([]byte("x"), 2*chunkSize+100))
+
+	// Cutting the file at a chunk boundary must not pass as a shorter backup
+	headerSize := len(sealed) - (2*(4+chunkSize+16) + 4 + 100 + 16)
+	if _, _, err := decrypt(key, sealed[:headerSize+4+chunkSize+16]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("truncated: err = %v, want ErrTruncated", err)
+	}
+
+	flipped := append([]byte(nil), sealed...)
+	flipped[len(flipped)-1] ^= 1
+	if _, _, err := decrypt(key, flipped); err == nil {
+		t.Error("modified ciphertext decrypted without error")
+	}
+
+	otherKey, _ := NewDataKey()
+	if _, _, err := decrypt(otherKey, sealed); err == nil {
+		t.Error("decrypted with the wrong key")
+	}
+}
+
+func TestDedupMode(t *testing.T) {
+	key, _ := NewDataKey()
+	plain := make([]byte, 2*dedupChunkSize+500)
+	for i := range plain {
+		plain[i] = byte(i / 7)
+	}
+
+	first := encryptMode(t, key, plain, config.EncryptionModeDedup)
+	if _, got, err := decrypt(key, first); err != nil || !bytes.Equal(got, plain) {
+		t.Fatalf("round trip failed: %v", err)
+	}
+
+	// Same data, same key: every block is identical and block aligned
+	if !bytes.Equal(first, encryptMode(t, key, plain, config.EncryptionModeDedup)) {
+		t.Error("dedup mode output is not deterministic")
+	}
+	if len(first) != dedupHeaderSize+2*dedupBlockSize+4+nonceSize+500+tagSize {
+		t.Errorf("output size %d is not block aligned", len(first))
+	}
+
+	// A change in the last chunk leaves the earlier blocks untouched
+	changed := append([]byte(nil), plain...)
+	changed[len(changed)-1] ^= 1
+	second := encryptMode(t, key, changed, config.EncryptionModeDedup)
+	prefix := dedupHeaderSize + 2*dedupBlockSize
+	if !bytes.Equal(first[:prefix], second[:prefix]) || bytes.Equal(first[prefix:], second[prefix:]) {
+		t.Error("only the changed block should differ")
+	}
+
+	// Blocks cannot be swapped
+	swapped := append([]byte(nil), first...)
+	a := swapped[dedupHeaderSize : dedupHeaderSize+dedupBlockSize]
+	b := swapped[dedupHeaderSize+dedupBlockSize : dedupHeaderSize+2*dedupBlockSize]
+	tmp := append([]byte(nil), a...)
+	copy(a, b)
+	copy(b, tmp)
+	if _, _, err := decrypt(key, swapped); err == nil {
+		t.Error("swapped blocks decrypted without error")
+	}
+}
@@ -0,0 +1,186 @@
+package encryption
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// DataKey is an unwrapped data key with the header naming how it is wrapped
+type DataKey struct {
+	key    []byte
+	Header Header
+}
+
+// NewKey generates a data key and wraps it with the configured key. The
+// plaintext key only lives in memory, so once it is dropped only the key
+// management service can decrypt what it encrypted.
+func NewKey(ctx context.Context, cfg *config.EncryptionConfig) (*DataKey, error) {
+	wrapper, err := NewKeyWrapper(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := NewDataKey()
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapper.Wrap(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	header := Header{Provider: cfg.Provider, KeyID: cfg.KeyID, WrappedKey: wrapped}
+	if cfg.Mode == config.EncryptionModeDedup {
+		header.Mode = config.EncryptionModeDedup
+	}
+	return &DataKey{key: key, Header: header}, nil
+}
+
+// EncryptFile encrypts src to dst
+func (k *DataKey) EncryptFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create encrypted file: %w", err)
+	}
+
+	w, err := NewWriter(out, k.key, k.Header)
+	if err == nil {
+		if _, err = io.Copy(w, in); err == nil {
+			err = w.Close()
+		}
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	return nil
+}
+
+// DecryptFile decrypts src to dst. The data key is unwrapped with the
+// provider and key named in the file's header; cfg supplies the CLI path
+// and Vault mount, so backups stay readable after the configured key changes.
+func DecryptFile(ctx context.Context, cfg *config.EncryptionConfig, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	header, r, err := ReadHeader(in)
+	if err != nil {
+		return fmt.Errorf("%s: %w", src, err)
+	}
+
+	key, err := unwrapKey(ctx, cfg, header)
+	if err != nil {
+		return err
+	}
+
+	decrypter, err := NewReader(r, header, key)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create decrypted file: %w", err)
+	}
+	_, err = io.Copy(out, decrypter)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+		return fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	return nil
+}
+
+// Rewrap wraps the data key of the encrypted file at path with the
+// configured key instead of the one named in its header, and returns the
+// new header. Only the header is rewritten: the data stays sealed under
+// the same data key and is copied as it is, so nothing is decrypted.
+func Rewrap(ctx context.Context, cfg *config.EncryptionConfig, path string) (*Header, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	header, r, err := ReadHeader(in)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key, err := unwrapKey(ctx, cfg, header)
+	if err != nil {
+		return nil, err
+	}
+	wrapper, err := NewKeyWrapper(cfg)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapper.Wrap(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	// The mode stays, dedup mode pads the header to the same size
+	rewrapped := Header{Provider: cfg.Provider, KeyID: cfg.KeyID, WrappedKey: wrapped, Mode: header.Mode}
+	encoded, err := encodeHeader(rewrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	tempPath := path + ".rekey"
+	out, err := os.OpenFile(tempPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rewrapped file: %w", err)
+	}
+	_, err = out.Write(encoded)
+	if err == nil {
+		_, err = io.Copy(out, r)
+	}
+	if err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return nil, fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	return &rewrapped, nil
+}
+
+// unwrapKey unwraps the data key of header with the provider and key it
+// names; cfg supplies the CLI path and Vault mount
+func unwrapKey(ctx context.Context, cfg *config.EncryptionConfig, header *Header) ([]byte, error) {
+	wrapperCfg := *cfg
+	wrapperCfg.Provider, wrapperCfg.KeyID = header.Provider, header.KeyID
+	wrapper, err := NewKeyWrapper(&wrapperCfg)
+	if err != nil {
+		return nil, err
+	}
+	key, err := wrapper.Unwrap(ctx, header.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with %s key %s: %w", header.Provider, header.KeyID, err)
+	}
+	return key, nil
+}
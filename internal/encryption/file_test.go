@@ -0,0 +1,84 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// fakeVault stands in for the vault CLI: the wrapped key is the key name
+// and the base64 plaintext, and only that key unwraps it
+const fakeVault = `#!/bin/sh
+path="$3"
+key="${path##*/}"
+in=$(cat)
+case "$path" in
+*/encrypt/*) echo "vault:$key:$in" ;;
+*/decrypt/*)
+	case "$in" in
+	"vault:$key:"*) echo "${in#vault:$key:}" ;;
+	*) echo "wrong key" >&2; exit 1 ;;
+	esac ;;
+esac
+`
+
+func TestRewrap(t *testing.T) {
+	dir := t.TempDir()
+	cli := filepath.Join(dir, "vault")
+	if err := os.WriteFile(cli, []byte(fakeVault), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plain := bytes.Repeat([]byte("tenangdb"), 3*chunkSize/8+5)
+	src := filepath.Join(dir, "app.sql.gz")
+	if err := os.WriteFile(src, plain, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []string{config.EncryptionModeStream, config.EncryptionModeDedup} {
+		retired := &config.EncryptionConfig{Provider: config.EncryptionProviderVaultTransit, KeyID: "retired", CLIPath: cli, Mode: mode}
+		current := *retired
+		current.KeyID = "current"
+
+		key, err := NewKey(context.Background(), retired)
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, mode+".enc")
+		if err := key.EncryptFile(src, path); err != nil {
+			t.Fatal(err)
+		}
+		before, _ := os.ReadFile(path)
+
+		header, err := Rewrap(context.Background(), &current, path)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if header.KeyID != "current" || header.Mode != key.Header.Mode {
+			t.Errorf("%s: header = %+v", mode, header)
+		}
+
+		// The sealed data is copied as it is
+		after, _ := os.ReadFile(path)
+		beforeHeader, _ := encodeHeader(key.Header)
+		afterHeader, _ := encodeHeader(*header)
+		if !bytes.Equal(before[len(beforeHeader):], after[len(afterHeader):]) {
+			t.Errorf("%s: data after the header changed", mode)
+		}
+		if mode == config.EncryptionModeDedup && len(after) != len(before) {
+			t.Errorf("dedup: size changed from %d to %d", len(before), len(after))
+		}
+
+		out := filepath.Join(dir, mode+".out")
+		if err := DecryptFile(context.Background(), &current, path, out); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		if got, _ := os.ReadFile(out); !bytes.Equal(got, plain) {
+			t.Errorf("%s: decrypted data differs", mode)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// generationFile holds the wrapped data key of the current dedup generation
+const generationFile = ".encryption-generation.json"
+
+type generationState struct {
+	Header
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoadGeneration returns the data key of the current dedup generation,
+// kept wrapped in dir. A new generation starts once the current one is
+// older than cfg.Generation or was wrapped with a different key. Backups of
+// one generation share their key, which is what lets equal data encrypt to
+// equal blocks.
+func LoadGeneration(ctx context.Context, cfg *config.EncryptionConfig, dir string, now time.Time) (*DataKey, error) {
+	path := filepath.Join(dir, generationFile)
+
+	var state generationState
+	if data, err := os.ReadFile(path); err == nil && json.Unmarshal(data, &state) == nil &&
+		state.Provider == cfg.Provider && state.KeyID == cfg.KeyID && now.Sub(state.CreatedAt) < cfg.Generation {
+		wrapper, err := NewKeyWrapper(cfg)
+		if err != nil {
+			return nil, err
+		}
+		key, err := wrapper.Unwrap(ctx, state.WrappedKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap generation key: %w", err)
+		}
+		return &DataKey{key: key, Header: state.Header}, nil
+	}
+
+	key, err := NewKey(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(generationState{Header: key.Header, CreatedAt: now}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return nil, fmt.Errorf("failed to save generation key: %w", err)
+	}
+	return key, nil
+}
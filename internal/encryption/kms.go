@@ -0,0 +1,137 @@
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// KeyWrapper wraps data keys with a key held by a key management service.
+// Data keys only ever reach the service's CLI on stdin, never its arguments.
+type KeyWrapper interface {
+	Wrap(ctx context.Context, key []byte) (string, error)
+	Unwrap(ctx context.Context, wrapped string) ([]byte, error)
+}
+
+// NewKeyWrapper returns the wrapper for cfg.Provider using cfg.KeyID
+func NewKeyWrapper(cfg *config.EncryptionConfig) (KeyWrapper, error) {
+	switch cfg.Provider {
+	case config.EncryptionProviderAWSKMS:
+		return &awsKMS{cli: cliPath(cfg, "aws"), keyID: cfg.KeyID}, nil
+	case config.EncryptionProviderGCPKMS:
+		return &gcpKMS{cli: cliPath(cfg, "gcloud"), keyID: cfg.KeyID}, nil
+	case config.EncryptionProviderVaultTransit:
+		mount := strings.Trim(cfg.VaultMount, "/")
+		if mount == "" {
+			mount = "transit"
+		}
+		return &vaultTransit{cli: cliPath(cfg, "vault"), mount: mount, keyID: cfg.KeyID}, nil
+	default:
+		return nil, fmt.Errorf("unsupported encryption provider: %s", cfg.Provider)
+	}
+}
+
+func cliPath(cfg *config.EncryptionConfig, name string) string {
+	if cfg.CLIPath != "" {
+		return cfg.CLIPath
+	}
+	return name
+}
+
+// run executes a CLI with stdin and returns its trimmed stdout
+func run(ctx context.Context, stdin []byte, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w (output: %s)", name, strings.Join(args[:2], " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return bytes.TrimSpace(stdout.Bytes()), nil
+}
+
+// awsKMS wraps keys with `aws kms encrypt`; the wrapped key is the base64
+// ciphertext blob
+type awsKMS struct {
+	cli   string
+	keyID string
+}
+
+func (k *awsKMS) Wrap(ctx context.Context, key []byte) (string, error) {
+	out, err := run(ctx, key, k.cli, "kms", "encrypt", "--key-id", k.keyID,
+		"--plaintext", "fileb:///dev/stdin", "--output", "text", "--query", "CiphertextBlob")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (k *awsKMS) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	out, err := run(ctx, blob, k.cli, "kms", "decrypt", "--key-id", k.keyID,
+		"--ciphertext-blob", "fileb:///dev/stdin", "--output", "text", "--query", "Plaintext")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(string(out))
+}
+
+// gcpKMS wraps keys with `gcloud kms encrypt`, which writes raw ciphertext
+type gcpKMS struct {
+	cli   string
+	keyID string
+}
+
+func (k *gcpKMS) Wrap(ctx context.Context, key []byte) (string, error) {
+	out, err := run(ctx, key, k.cli, "kms", "encrypt", "--key", k.keyID,
+		"--plaintext-file", "-", "--ciphertext-file", "-")
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+func (k *gcpKMS) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	blob, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key: %w", err)
+	}
+	return run(ctx, blob, k.cli, "kms", "decrypt", "--key", k.keyID,
+		"--ciphertext-file", "-", "--plaintext-file", "-")
+}
+
+// vaultTransit wraps keys with the Vault transit engine; the wrapped key is
+// the "vault:v1:..." ciphertext
+type vaultTransit struct {
+	cli   string
+	mount string
+	keyID string
+}
+
+func (k *vaultTransit) Wrap(ctx context.Context, key []byte) (string, error) {
+	// A value of "-" makes vault read it from stdin
+	out, err := run(ctx, []byte(base64.StdEncoding.EncodeToString(key)), k.cli, "write", "-field=ciphertext",
+		k.mount+"/encrypt/"+k.keyID, "plaintext=-")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (k *vaultTransit) Unwrap(ctx context.Context, wrapped string) ([]byte, error) {
+	out, err := run(ctx, []byte(wrapped), k.cli, "write", "-field=plaintext",
+		k.mount+"/decrypt/"+k.keyID, "ciphertext=-")
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(string(out))
+}
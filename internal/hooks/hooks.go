@@ -0,0 +1,137 @@
+// Package hooks quiesces applications while their database is dumped, so
+// backups do not capture a half-finished request or cron run
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// DefaultTimeout bounds each hook command
+const DefaultTimeout = 2 * time.Minute
+
+// templates are the commands for apps with first-class support, run in the
+// application root. Verification checks the app state rather than trusting
+// the exit status of the command that changed it.
+var templates = map[string]config.AppHookConfig{
+	// wp-cli maintenance mode shows visitors a maintenance page
+	config.AppWordPress: {
+		Before:       "wp maintenance-mode activate",
+		After:        "wp maintenance-mode deactivate",
+		VerifyBefore: "wp maintenance-mode is-active",
+		VerifyAfter:  "! wp maintenance-mode is-active",
+	},
+	// Removing the Magento crontab stops indexers and queue consumers
+	// started from cron from writing during the dump
+	config.AppMagento: {
+		Before:       "php bin/magento cron:remove",
+		After:        "php bin/magento cron:install",
+		VerifyBefore: "! crontab -l 2>/dev/null | grep -q 'MAGENTO START'",
+		VerifyAfter:  "crontab -l 2>/dev/null | grep -q 'MAGENTO START'",
+	},
+	// artisan down serves 503s until artisan up
+	config.AppLaravel: {
+		Before:       "php artisan down",
+		After:        "php artisan up",
+		VerifyBefore: "test -f storage/framework/down",
+		VerifyAfter:  "test ! -f storage/framework/down",
+	},
+}
+
+// Hook is an app hook with its template applied
+type Hook struct {
+	config.AppHookConfig
+}
+
+// New resolves the commands of cfg, filling what it leaves empty from the
+// template of its app
+func New(cfg config.AppHookConfig) *Hook {
+	hook := &Hook{AppHookConfig: cfg}
+	if template, ok := templates[cfg.App]; ok {
+		if hook.Before == "" {
+			hook.Before = template.Before
+		}
+		if hook.After == "" {
+			hook.After = template.After
+		}
+		if hook.VerifyBefore == "" {
+			hook.VerifyBefore = template.VerifyBefore
+		}
+		if hook.VerifyAfter == "" {
+			hook.VerifyAfter = template.VerifyAfter
+		}
+	}
+	if hook.Timeout <= 0 {
+		hook.Timeout = DefaultTimeout
+	}
+	return hook
+}
+
+// ForDatabase returns the hooks of dbName in config order
+func ForDatabase(cfgs []config.AppHookConfig, dbName string) []*Hook {
+	var hooks []*Hook
+	for _, cfg := range cfgs {
+		if cfg.Database == dbName {
+			hooks = append(hooks, New(cfg))
+		}
+	}
+	return hooks
+}
+
+// Aborts reports whether a failure of the hook should skip the dump
+func (h *Hook) Aborts() bool {
+	return h.OnFailure != config.HookFailureContinue
+}
+
+// Quiesce runs the before command and checks that it took effect
+func (h *Hook) Quiesce(ctx context.Context) error {
+	if err := h.run(ctx, h.Before); err != nil {
+		return err
+	}
+	if h.VerifyBefore != "" {
+		if err := h.run(ctx, h.VerifyBefore); err != nil {
+			return fmt.Errorf("%s did not take effect: %w", h.Before, err)
+		}
+	}
+	return nil
+}
+
+// Resume runs the after command and checks that it took effect
+func (h *Hook) Resume(ctx context.Context) error {
+	if err := h.run(ctx, h.After); err != nil {
+		return err
+	}
+	if h.VerifyAfter != "" {
+		if err := h.run(ctx, h.VerifyAfter); err != nil {
+			return fmt.Errorf("%s did not take effect: %w", h.After, err)
+		}
+	}
+	return nil
+}
+
+// run executes command with sh in the application root, as the hook's
+// user if one is set
+func (h *Hook) run(ctx context.Context, command string) error {
+	ctx, cancel := context.WithTimeout(ctx, h.Timeout)
+	defer cancel()
+
+	name, args := "sh", []string{"-c", command}
+	if h.User != "" {
+		name, args = "sudo", append([]string{"-n", "-u", h.User, "--", "sh"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = h.Path
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook %q failed: %w (output: %s)", h.App, command, err, strings.TrimSpace(output.String()))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package hooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestLaravelTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "storage", "framework"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A fake php whose artisan down did nothing must fail verification
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "php"), []byte("#!/bin/sh\nexit 0\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	hook := New(config.AppHookConfig{Database: "app", App: config.AppLaravel, Path: dir})
+	if err := hook.Quiesce(context.Background()); err == nil {
+		t.Fatal("quiesce passed although the app is not down")
+	}
+
+	// One that creates the marker passes, and resume checks it is gone
+	script := "#!/bin/sh\ncase \"$2\" in down) touch storage/framework/down;; up) rm storage/framework/down;; esac\n"
+	if err := os.WriteFile(filepath.Join(bin, "php"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Quiesce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := hook.Resume(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "storage", "framework", "down")); !os.IsNotExist(err) {
+		t.Error("app is still down after resume")
+	}
+}
+
+func TestCustomOverridesTemplate(t *testing.T) {
+	hook := New(config.AppHookConfig{App: config.AppWordPress, Before: "wp maintenance-mode activate --allow-root"})
+	if hook.Before != "wp maintenance-mode activate --allow-root" || hook.After != "wp maintenance-mode deactivate" {
+		t.Errorf("before = %q, after = %q", hook.Before, hook.After)
+	}
+	if !hook.Aborts() || hook.Timeout != DefaultTimeout {
+		t.Errorf("aborts = %v, timeout = %v", hook.Aborts(), hook.Timeout)
+	}
+}
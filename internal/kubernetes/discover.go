@@ -0,0 +1,273 @@
+// Package kubernetes discovers MySQL instances to back up from annotated
+// Services, using the Kubernetes API with the pod's service account
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// Annotations read from Services
+const (
+	AnnotationBackup      = "tenangdb.io/backup"       // "true" to back up the instance
+	AnnotationDatabases   = "tenangdb.io/databases"    // comma separated, all user databases when unset
+	AnnotationSecret      = "tenangdb.io/secret"       // Secret in the same namespace holding the credentials
+	AnnotationUsernameKey = "tenangdb.io/username-key" // key of the username, default "username"
+	AnnotationPasswordKey = "tenangdb.io/password-key" // key of the password, default "password"
+	AnnotationUsername    = "tenangdb.io/username"     // literal username when the Secret holds only a password
+	AnnotationPort        = "tenangdb.io/port"         // Service port, default the one named mysql or the first
+)
+
+// Instance is a discovered MySQL server
+type Instance struct {
+	Namespace string
+	Service   string
+	Host      string // cluster DNS name of the Service
+	Port      int
+	Username  string
+	Password  string
+	Databases []string // empty to back up every user database
+}
+
+// Name identifies the instance as namespace/service
+func (i *Instance) Name() string {
+	return i.Namespace + "/" + i.Service
+}
+
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type service struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Ports []struct {
+			Name string `json:"name"`
+			Port int    `json:"port"`
+		} `json:"ports"`
+	} `json:"spec"`
+}
+
+type secret struct {
+	Data map[string][]byte `json:"data"` // base64 in JSON, decoded by encoding/json
+}
+
+// Client talks to the Kubernetes API server
+type Client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+// NewClient connects with the configured or in-cluster API server,
+// service account token and CA
+func NewClient(cfg *config.KubernetesConfig) (*Client, error) {
+	server := cfg.APIServer
+	if server == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, fmt.Errorf("not running in a Kubernetes cluster, set kubernetes.api_server")
+		}
+		server = "https://" + net.JoinHostPort(host, port)
+	}
+
+	token, err := os.ReadFile(cfg.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.CAFile != "" {
+		if ca, err := os.ReadFile(cfg.CAFile); err == nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("no certificates in %s", cfg.CAFile)
+			}
+			transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+		} else if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to read cluster CA: %w", err)
+		}
+	}
+
+	return &Client{
+		server: strings.TrimSuffix(server, "/"),
+		token:  strings.TrimSpace(string(token)),
+		http:   &http.Client{Transport: transport, Timeout: 30 * time.Second},
+	}, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, "", nil, out)
+}
+
+// do sends a request with an optional JSON body and decodes the response
+// into out when it is not nil
+func (c *Client) do(ctx context.Context, method, path, contentType string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("kubernetes API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("kubernetes API %s returned %s: %s", path, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Discover returns the instances of every Service annotated for backup in
+// namespaces, or in all namespaces when none are given, sorted by name
+func (c *Client) Discover(ctx context.Context, namespaces []string) ([]Instance, error) {
+	paths := []string{"/api/v1/services"}
+	if len(namespaces) > 0 {
+		paths = paths[:0]
+		for _, ns := range namespaces {
+			paths = append(paths, "/api/v1/namespaces/"+url.PathEscape(ns)+"/services")
+		}
+	}
+
+	var instances []Instance
+	for _, path := range paths {
+		var list struct {
+			Items []service `json:"items"`
+		}
+		if err := c.get(ctx, path, &list); err != nil {
+			return nil, err
+		}
+		for _, svc := range list.Items {
+			if svc.Metadata.Annotations[AnnotationBackup] != "true" {
+				continue
+			}
+			instance, err := c.instance(ctx, &svc)
+			if err != nil {
+				return nil, fmt.Errorf("service %s/%s: %w", svc.Metadata.Namespace, svc.Metadata.Name, err)
+			}
+			instances = append(instances, *instance)
+		}
+	}
+
+	sort.Slice(instances, func(i, j int) bool { return instances[i].Name() < instances[j].Name() })
+	return instances, nil
+}
+
+// instance reads the connection and credentials of an annotated Service
+func (c *Client) instance(ctx context.Context, svc *service) (*Instance, error) {
+	meta := svc.Metadata
+	instance := &Instance{
+		Namespace: meta.Namespace,
+		Service:   meta.Name,
+		Host:      fmt.Sprintf("%s.%s.svc", meta.Name, meta.Namespace),
+	}
+
+	port, err := servicePort(svc)
+	if err != nil {
+		return nil, err
+	}
+	instance.Port = port
+
+	for _, db := range strings.Split(meta.Annotations[AnnotationDatabases], ",") {
+		if db = strings.TrimSpace(db); db != "" {
+			instance.Databases = append(instance.Databases, db)
+		}
+	}
+
+	if meta.Annotations[AnnotationSecret] == "" {
+		return nil, fmt.Errorf("annotation %s is required", AnnotationSecret)
+	}
+	username, password, err := c.credentials(ctx, meta.Namespace, meta.Annotations[AnnotationSecret],
+		meta.Annotations[AnnotationUsernameKey], meta.Annotations[AnnotationPasswordKey], meta.Annotations[AnnotationUsername])
+	if err != nil {
+		return nil, err
+	}
+	instance.Username, instance.Password = username, password
+	return instance, nil
+}
+
+// credentials reads a username and password from a Secret. The keys default
+// to username and password; username is used when the Secret has no
+// username key.
+func (c *Client) credentials(ctx context.Context, namespace, secretName, usernameKey, passwordKey, username string) (string, string, error) {
+	var sec secret
+	if err := c.get(ctx, "/api/v1/namespaces/"+url.PathEscape(namespace)+"/secrets/"+url.PathEscape(secretName), &sec); err != nil {
+		return "", "", err
+	}
+
+	usernameKey = orDefault(usernameKey, "username")
+	passwordKey = orDefault(passwordKey, "password")
+	if value, ok := sec.Data[usernameKey]; ok {
+		username = string(value)
+	}
+	password, ok := sec.Data[passwordKey]
+	if !ok {
+		return "", "", fmt.Errorf("secret %s has no key %s", secretName, passwordKey)
+	}
+	if username == "" {
+		return "", "", fmt.Errorf("secret %s has no key %s and no username is set", secretName, usernameKey)
+	}
+	return username, string(password), nil
+}
+
+// servicePort picks the annotated port, the one named mysql, or the first
+func servicePort(svc *service) (int, error) {
+	if value := svc.Metadata.Annotations[AnnotationPort]; value != "" {
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", AnnotationPort, value)
+		}
+		return port, nil
+	}
+	for _, port := range svc.Spec.Ports {
+		if port.Name == "mysql" {
+			return port.Port, nil
+		}
+	}
+	if len(svc.Spec.Ports) == 0 {
+		return 0, fmt.Errorf("service has no ports")
+	}
+	return svc.Spec.Ports[0].Port, nil
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package kubernetes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestDiscover(t *testing.T) {
+	responses := map[string]string{
+		"/api/v1/namespaces/shop/services": `{"items":[
+			{"metadata":{"name":"mysql","namespace":"shop","annotations":{
+				"tenangdb.io/backup":"true","tenangdb.io/secret":"mysql-auth","tenangdb.io/databases":"orders, invoices"}},
+			 "spec":{"ports":[{"name":"metrics","port":9104},{"name":"mysql","port":3306}]}},
+			{"metadata":{"name":"web","namespace":"shop"},"spec":{"ports":[{"port":80}]}},
+			{"metadata":{"name":"legacy","namespace":"shop","annotations":{
+				"tenangdb.io/backup":"true","tenangdb.io/secret":"legacy-root","tenangdb.io/username":"root",
+				"tenangdb.io/password-key":"mysql-root-password","tenangdb.io/port":"3307"}},
+			 "spec":{"ports":[{"port":3307}]}}]}`,
+		"/api/v1/namespaces/shop/secrets/mysql-auth":  `{"data":{"username":"YmFja3Vw","password":"czNjcjN0"}}`,
+		"/api/v1/namespaces/shop/secrets/legacy-root": `{"data":{"mysql-root-password":"cm9vdHB3"}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewClient(&config.KubernetesConfig{APIServer: server.URL, TokenFile: tokenFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances, err := client.Discover(context.Background(), []string{"shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(instances) != 2 {
+		t.Fatalf("got %d instances, want 2", len(instances))
+	}
+
+	legacy, mysql := instances[0], instances[1]
+	if legacy.Host != "legacy.shop.svc" || legacy.Port != 3307 || legacy.Username != "root" || legacy.Password != "rootpw" || len(legacy.Databases) != 0 {
+		t.Errorf("legacy = %+v", legacy)
+	}
+	if mysql.Port != 3306 || mysql.Username != "backup" || mysql.Password != "s3cr3t" ||
+		len(mysql.Databases) != 2 || mysql.Databases[1] != "invoices" {
+		t.Errorf("mysql = %+v", mysql)
+	}
+}
@@ -0,0 +1,205 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Custom resources reconciled by `tenangdb operator`, defined in
+// k8s/crds.yaml
+const (
+	Group   = "tenangdb.io"
+	Version = "v1alpha1"
+)
+
+// Phases of a RestoreRequest
+const (
+	PhaseRunning   = "Running"
+	PhaseSucceeded = "Succeeded"
+	PhaseFailed    = "Failed"
+)
+
+// resourceMeta is the metadata of a custom resource
+type resourceMeta struct {
+	Name              string    `json:"name"`
+	Namespace         string    `json:"namespace"`
+	CreationTimestamp time.Time `json:"creationTimestamp"`
+}
+
+// BackupSchedule backs up databases of a MySQL Service on a cron schedule
+type BackupSchedule struct {
+	Metadata resourceMeta         `json:"metadata"`
+	Spec     BackupScheduleSpec   `json:"spec"`
+	Status   BackupScheduleStatus `json:"status"`
+}
+
+// BackupScheduleSpec is the desired state of a BackupSchedule
+type BackupScheduleSpec struct {
+	Service     string   `json:"service"`        // Service of the MySQL server, in the same namespace
+	Port        int      `json:"port,omitempty"` // default 3306
+	Secret      string   `json:"secret"`         // Secret holding the credentials
+	UsernameKey string   `json:"usernameKey,omitempty"`
+	PasswordKey string   `json:"passwordKey,omitempty"`
+	Username    string   `json:"username,omitempty"` // literal username when the Secret holds only a password
+	Databases   []string `json:"databases,omitempty"`
+	Schedule    string   `json:"schedule"` // cron expression or weekday list
+	Suspend     bool     `json:"suspend,omitempty"`
+}
+
+// BackupScheduleStatus is the observed state of a BackupSchedule
+type BackupScheduleStatus struct {
+	LastScheduleTime   *time.Time `json:"lastScheduleTime,omitempty"`
+	LastSuccessfulTime *time.Time `json:"lastSuccessfulTime,omitempty"`
+	LastResult         string     `json:"lastResult,omitempty"` // Succeeded or Failed
+	Message            string     `json:"message"`
+}
+
+// Name identifies the schedule as namespace/name
+func (s *BackupSchedule) Name() string {
+	return s.Metadata.Namespace + "/" + s.Metadata.Name
+}
+
+// RestoreRequest restores one backup taken by a BackupSchedule into the
+// schedule's MySQL server
+type RestoreRequest struct {
+	Metadata resourceMeta         `json:"metadata"`
+	Spec     RestoreRequestSpec   `json:"spec"`
+	Status   RestoreRequestStatus `json:"status"`
+}
+
+// RestoreRequestSpec is the desired state of a RestoreRequest
+type RestoreRequestSpec struct {
+	BackupSchedule string `json:"backupSchedule"` // in the same namespace
+	Database       string `json:"database"`
+	Backup         string `json:"backup,omitempty"` // backup ID, the latest backup of database when empty
+	TargetDatabase string `json:"targetDatabase"`
+}
+
+// RestoreRequestStatus is the observed state of a RestoreRequest
+type RestoreRequestStatus struct {
+	Phase          string     `json:"phase,omitempty"`
+	Backup         string     `json:"backup,omitempty"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	Message        string     `json:"message"`
+}
+
+// Name identifies the request as namespace/name
+func (r *RestoreRequest) Name() string {
+	return r.Metadata.Namespace + "/" + r.Metadata.Name
+}
+
+// Done reports whether the request has finished, successfully or not
+func (r *RestoreRequest) Done() bool {
+	return r.Status.Phase == PhaseSucceeded || r.Status.Phase == PhaseFailed
+}
+
+// resourcePaths returns the collection paths of a resource in namespaces,
+// or across all namespaces when none are given
+func resourcePaths(plural string, namespaces []string) []string {
+	base := "/apis/" + Group + "/" + Version
+	if len(namespaces) == 0 {
+		return []string{base + "/" + plural}
+	}
+	var paths []string
+	for _, ns := range namespaces {
+		paths = append(paths, base+"/namespaces/"+url.PathEscape(ns)+"/"+plural)
+	}
+	return paths
+}
+
+// statusPath is the status subresource of a named custom resource
+func statusPath(plural, namespace, name string) string {
+	return "/apis/" + Group + "/" + Version + "/namespaces/" + url.PathEscape(namespace) + "/" + plural + "/" + url.PathEscape(name) + "/status"
+}
+
+// BackupSchedules lists the BackupSchedules in namespaces, sorted by name
+func (c *Client) BackupSchedules(ctx context.Context, namespaces []string) ([]BackupSchedule, error) {
+	var schedules []BackupSchedule
+	for _, path := range resourcePaths("backupschedules", namespaces) {
+		var list struct {
+			Items []BackupSchedule `json:"items"`
+		}
+		if err := c.get(ctx, path, &list); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, list.Items...)
+	}
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].Name() < schedules[j].Name() })
+	return schedules, nil
+}
+
+// RestoreRequests lists the RestoreRequests in namespaces, oldest first
+func (c *Client) RestoreRequests(ctx context.Context, namespaces []string) ([]RestoreRequest, error) {
+	var requests []RestoreRequest
+	for _, path := range resourcePaths("restorerequests", namespaces) {
+		var list struct {
+			Items []RestoreRequest `json:"items"`
+		}
+		if err := c.get(ctx, path, &list); err != nil {
+			return nil, err
+		}
+		requests = append(requests, list.Items...)
+	}
+	sort.SliceStable(requests, func(i, j int) bool {
+		return requests[i].Metadata.CreationTimestamp.Before(requests[j].Metadata.CreationTimestamp)
+	})
+	return requests, nil
+}
+
+// BackupSchedule returns a BackupSchedule by name
+func (c *Client) BackupSchedule(ctx context.Context, namespace, name string) (*BackupSchedule, error) {
+	var schedule BackupSchedule
+	path := "/apis/" + Group + "/" + Version + "/namespaces/" + url.PathEscape(namespace) + "/backupschedules/" + url.PathEscape(name)
+	if err := c.get(ctx, path, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// UpdateBackupScheduleStatus replaces the status of a BackupSchedule
+func (c *Client) UpdateBackupScheduleStatus(ctx context.Context, schedule *BackupSchedule) error {
+	return c.patchStatus(ctx, statusPath("backupschedules", schedule.Metadata.Namespace, schedule.Metadata.Name), schedule.Status)
+}
+
+// UpdateRestoreRequestStatus replaces the status of a RestoreRequest
+func (c *Client) UpdateRestoreRequestStatus(ctx context.Context, request *RestoreRequest) error {
+	return c.patchStatus(ctx, statusPath("restorerequests", request.Metadata.Namespace, request.Metadata.Name), request.Status)
+}
+
+func (c *Client) patchStatus(ctx context.Context, path string, status interface{}) error {
+	body := map[string]interface{}{"status": status}
+	return c.do(ctx, http.MethodPatch, path, "application/merge-patch+json", body, nil)
+}
+
+// Instance resolves the MySQL server and credentials of a BackupSchedule.
+// Service is the schedule name, so its backups are kept apart from those of
+// other schedules of the same server.
+func (c *Client) Instance(ctx context.Context, schedule *BackupSchedule) (*Instance, error) {
+	spec := schedule.Spec
+	if spec.Service == "" || spec.Secret == "" {
+		return nil, fmt.Errorf("spec.service and spec.secret are required")
+	}
+	ns := schedule.Metadata.Namespace
+	instance := &Instance{
+		Namespace: ns,
+		Service:   schedule.Metadata.Name,
+		Host:      fmt.Sprintf("%s.%s.svc", spec.Service, ns),
+		Port:      spec.Port,
+		Databases: spec.Databases,
+	}
+	if instance.Port == 0 {
+		instance.Port = 3306
+	}
+
+	username, password, err := c.credentials(ctx, ns, spec.Secret, spec.UsernameKey, spec.PasswordKey, spec.Username)
+	if err != nil {
+		return nil, err
+	}
+	instance.Username, instance.Password = username, password
+	return instance, nil
+}
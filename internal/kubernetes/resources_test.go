@@ -0,0 +1,69 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestRestoreRequests(t *testing.T) {
+	var patched map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/apis/tenangdb.io/v1alpha1/namespaces/shop/restorerequests":
+			w.Write([]byte(`{"items":[
+				{"metadata":{"name":"second","namespace":"shop","creationTimestamp":"2025-07-02T10:00:00Z"},
+				 "spec":{"backupSchedule":"nightly","database":"orders","targetDatabase":"orders_restored"}},
+				{"metadata":{"name":"first","namespace":"shop","creationTimestamp":"2025-07-01T10:00:00Z"},
+				 "spec":{"backupSchedule":"nightly","database":"orders","targetDatabase":"orders_old"},
+				 "status":{"phase":"Succeeded"}}]}`))
+		case r.Method == http.MethodPatch && r.URL.Path == "/apis/tenangdb.io/v1alpha1/namespaces/shop/restorerequests/second/status":
+			if r.Header.Get("Content-Type") != "application/merge-patch+json" {
+				http.Error(w, "unsupported patch", http.StatusUnsupportedMediaType)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			json.Unmarshal(body, &patched)
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(&config.KubernetesConfig{APIServer: server.URL, TokenFile: tokenFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests, err := client.RestoreRequests(context.Background(), []string{"shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 || requests[0].Metadata.Name != "first" || !requests[0].Done() || requests[1].Done() {
+		t.Fatalf("requests = %+v", requests)
+	}
+
+	request := &requests[1]
+	start := time.Date(2025, time.July, 2, 10, 0, 5, 0, time.UTC)
+	request.Status = RestoreRequestStatus{Phase: PhaseRunning, StartTime: &start}
+	if err := client.UpdateRestoreRequestStatus(context.Background(), request); err != nil {
+		t.Fatal(err)
+	}
+	status, _ := patched["status"].(map[string]interface{})
+	if status["phase"] != PhaseRunning || status["startTime"] != "2025-07-02T10:00:05Z" || status["message"] != "" {
+		t.Errorf("patched status = %v", patched)
+	}
+}
@@ -134,3 +134,106 @@ func (l *Logger) WithError(err error) *logrus.Entry {
 func (l *Logger) WithBackupFile(fileName string) *logrus.Entry {
 	return l.WithField("backup_file", fileName)
 }
+
+// fieldsHook adds fixed fields to every entry
+type fieldsHook struct {
+	fields logrus.Fields
+}
+
+func (hook *fieldsHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *fieldsHook) Fire(entry *logrus.Entry) error {
+	for k, v := range hook.fields {
+		if _, exists := entry.Data[k]; !exists {
+			entry.Data[k] = v
+		}
+	}
+	return nil
+}
+
+// SetRunID tags every following log line with the run ID
+func (l *Logger) SetRunID(runID string) {
+	l.prependHook(&fieldsHook{fields: logrus.Fields{"run_id": runID}})
+}
+
+// prependHook adds a hook that fires before existing ones, so entries it
+// modifies reach the file hook modified
+func (l *Logger) prependHook(hook logrus.Hook) {
+	hooks := make(logrus.LevelHooks)
+	hooks.Add(hook)
+	for level, levelHooks := range l.ReplaceHooks(make(logrus.LevelHooks)) {
+		hooks[level] = append(hooks[level], levelHooks...)
+	}
+	l.ReplaceHooks(hooks)
+}
+
+// emojiHook removes emoji and other status symbols from messages
+type emojiHook struct{}
+
+func (hook *emojiHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (hook *emojiHook) Fire(entry *logrus.Entry) error {
+	entry.Message = StripEmoji(entry.Message)
+	return nil
+}
+
+// DisableEmoji strips emoji decorations from every following log line
+func (l *Logger) DisableEmoji() {
+	l.prependHook(&emojiHook{})
+}
+
+// StripEmoji removes emoji and pictographic symbols from s
+func StripEmoji(s string) string {
+	stripped := strings.Map(func(r rune) rune {
+		if isDecoration(r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(stripped), " ")
+}
+
+func isDecoration(r rune) bool {
+	switch {
+	case r == 0x200D, r == 0x20E3, r == 0xFE0F: // joiner, keycap, emoji presentation
+		return true
+	case r >= 0x2190 && r <= 0x21FF: // arrows
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // technical symbols such as ⏰
+		return true
+	case r >= 0x2500 && r <= 0x27BF: // shapes, misc symbols and dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r >= 0x1F000 && r <= 0x1FAFF: // emoji blocks
+		return true
+	}
+	return false
+}
+
+// levelFilterFormatter drops entries less severe than min from one output
+// while the logger level still lets them through to hooks
+type levelFilterFormatter struct {
+	min   logrus.Level
+	inner logrus.Formatter
+}
+
+func (f *levelFilterFormatter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Level > f.min {
+		return nil, nil
+	}
+	return f.inner.Format(entry)
+}
+
+// SetQuiet limits terminal output to errors as JSON lines; the log file
+// keeps its configured level and format
+func (l *Logger) SetQuiet() {
+	l.SetFormatter(&levelFilterFormatter{
+		min:   logrus.ErrorLevel,
+		inner: &logrus.JSONFormatter{TimestampFormat: "2006-01-02T15:04:05Z07:00"},
+	})
+}
@@ -0,0 +1,267 @@
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Suffix is appended to a backup artifact's path to name its manifest
+const Suffix = ".manifest.json"
+
+// CurrentVersion is the manifest format written by this build
+const CurrentVersion = 1
+
+// Manifest describes one backup artifact. It is stored next to the artifact
+// as {artifact}.manifest.json and uploaded along with it.
+type Manifest struct {
+	Version     int       `json:"version"`
+	RunID       string    `json:"run_id"`
+	Database    string    `json:"database"`
+	Artifact    string    `json:"artifact"` // file or directory name
+	CreatedAt   time.Time `json:"created_at"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Tool        string    `json:"tool"`                  // mydumper or mysqldump
+	Compression string    `json:"compression,omitempty"` // archive format, empty if uncompressed
+	Host        string    `json:"host"`
+	SHA256      string    `json:"sha256,omitempty"` // of file artifacts, checked after downloads
+
+	// Seekable tar.zst archives can be read one file at a time
+	Seekable bool `json:"seekable,omitempty"`
+
+	// Pieces the artifact was uploaded in with upload.split_size_gb, in
+	// order; the remote copy is their concatenation
+	Parts []Part `json:"parts,omitempty"`
+
+	// Versions of tenangdb, the dump tool and rclone that made the backup,
+	// compared with the restoring toolchain on restore
+	ToolVersions map[string]string `json:"tool_versions,omitempty"`
+
+	// Default charset and collation of the database at backup time
+	Charset   string `json:"charset,omitempty"`
+	Collation string `json:"collation,omitempty"`
+
+	// Oldest server version the dump was rewritten for, e.g. "5.7"
+	TargetCompat string `json:"target_compat,omitempty"`
+
+	// VERSION() of the source server and the collations its tables and
+	// columns use, checked against the target before a restore
+	ServerVersion string   `json:"server_version,omitempty"`
+	Collations    []string `json:"collations,omitempty"`
+
+	// Labels given with --label, e.g. ticket=OPS-123
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Pinned backups are kept by cleanup until unpinned
+	Pinned bool `json:"pinned,omitempty"`
+
+	// Ad-hoc backups are of databases taken with backup --ad-hoc, usually
+	// outside backup.databases
+	AdHoc bool `json:"ad_hoc,omitempty"`
+
+	// Server objects captured with backup.server_objects
+	Coverage *Coverage `json:"coverage,omitempty"`
+
+	// Objects left out because the backup user lacked the privilege to
+	// dump them, with backup.missing_privileges: skip
+	Omitted []string `json:"omitted,omitempty"`
+
+	// CHECKSUM TABLE of a sample of tables, taken right before the dump
+	// with backup.checksums
+	TableChecksums map[string]uint64 `json:"table_checksums,omitempty"`
+
+	// Tables the backup holds, with their approximate row count right
+	// before the dump, for `tenangdb verify --against-live`
+	Tables map[string]int64 `json:"tables,omitempty"`
+
+	// Binary log coordinates of the source when the dump started, read
+	// from mydumper's metadata
+	Binlog *BinlogPosition `json:"binlog,omitempty"`
+
+	// When the heartbeat row the dump was checked to hold was written,
+	// with backup.heartbeat
+	Heartbeat *time.Time `json:"heartbeat,omitempty"`
+
+	// Later runs that found the database unchanged and kept this backup
+	// instead of taking a new one, with backup.skip_unchanged
+	Unchanged []UnchangedCheck `json:"unchanged,omitempty"`
+
+	// Key that wrapped the data key, for encrypted artifacts
+	Encryption *Encryption `json:"encryption,omitempty"`
+
+	// Upload destination holding the cloud copy, the fallback if the
+	// primary destination failed
+	Destination string `json:"destination,omitempty"`
+
+	// Copies an upload destination policy made of the artifact, for the
+	// destinations that hold one instead of the artifact itself
+	Copies []Copy `json:"copies,omitempty"`
+
+	// Time spent dumping and compressing, and uploading if enabled
+	DurationSeconds float64 `json:"duration_seconds,omitempty"`
+	UploadSeconds   float64 `json:"upload_seconds,omitempty"`
+}
+
+// Coverage reports which server objects a backup holds beyond its tables
+type Coverage struct {
+	Captured    map[string]int `json:"captured"`               // object kind to count
+	NotCaptured []string       `json:"not_captured,omitempty"` // objects left out, and why
+}
+
+// BinlogPosition is a point in the binary log of the source
+type BinlogPosition struct {
+	Server   string `json:"server,omitempty"` // names the binary log archive of tenangdb backup --incremental
+	File     string `json:"file"`
+	Position uint64 `json:"position"`
+	GTIDSet  string `json:"gtid_set,omitempty"`
+}
+
+// UnchangedCheck is a run that found nothing written to the database since
+// the backup and kept it
+type UnchangedCheck struct {
+	RunID     string    `json:"run_id"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Part is one piece of an artifact uploaded in parts, stored next to where
+// the artifact would be as {artifact}.001, {artifact}.002 and so on
+type Part struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Copy is an artifact recompressed or encrypted for one upload
+// destination. The destination also holds a manifest of its own for it.
+type Copy struct {
+	Destination string      `json:"destination"`
+	Artifact    string      `json:"artifact"`
+	Compression string      `json:"compression,omitempty"`
+	Encryption  *Encryption `json:"encryption,omitempty"`
+	SizeBytes   int64       `json:"size_bytes"`
+	SHA256      string      `json:"sha256"`
+}
+
+// CopyAt returns the copy uploaded to destination, or nil if it holds the
+// artifact itself
+func (m *Manifest) CopyAt(destination string) *Copy {
+	for i := range m.Copies {
+		if m.Copies[i].Destination == destination {
+			return &m.Copies[i]
+		}
+	}
+	return nil
+}
+
+// SetCopy records c, replacing an earlier copy uploaded to its destination
+func (m *Manifest) SetCopy(c Copy) {
+	if existing := m.CopyAt(c.Destination); existing != nil {
+		*existing = c
+		return
+	}
+	m.Copies = append(m.Copies, c)
+}
+
+// ForCopy returns the manifest describing c, for its destination
+func (m *Manifest) ForCopy(c *Copy) *Manifest {
+	copyManifest := *m
+	copyManifest.Artifact = c.Artifact
+	copyManifest.Compression = c.Compression
+	copyManifest.Encryption = c.Encryption
+	copyManifest.SizeBytes = c.SizeBytes
+	copyManifest.SHA256 = c.SHA256
+	copyManifest.Destination = c.Destination
+	copyManifest.Seekable = false
+	copyManifest.Parts = nil
+	copyManifest.Copies = nil
+	return &copyManifest
+}
+
+// PartName returns the name of part index, counted from 0, of artifact
+func PartName(artifact string, index int) string {
+	return fmt.Sprintf("%s.%03d", artifact, index+1)
+}
+
+// Encryption describes how an artifact was encrypted. The wrapped data key
+// itself is kept in the artifact's header.
+type Encryption struct {
+	Provider  string `json:"provider"`
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	Mode      string `json:"mode,omitempty"` // "stream" or "dedup"
+}
+
+// PathFor returns the manifest path of an artifact
+func PathFor(artifactPath string) string {
+	return strings.TrimSuffix(artifactPath, string(filepath.Separator)) + Suffix
+}
+
+// IsManifest reports whether path names a manifest file
+func IsManifest(path string) bool {
+	return strings.HasSuffix(path, Suffix)
+}
+
+// Write stores the manifest next to artifactPath and returns its path
+func (m *Manifest) Write(artifactPath string) (string, error) {
+	if m.Version == 0 {
+		m.Version = CurrentVersion
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	path := PathFor(artifactPath)
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return path, nil
+}
+
+// Load reads the manifest of an artifact
+func Load(artifactPath string) (*Manifest, error) {
+	return Read(PathFor(artifactPath))
+}
+
+// Read reads a manifest file
+func Read(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// FileChecksum returns the hex SHA-256 of a file
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
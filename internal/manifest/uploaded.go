@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UploadedSuffix is appended to a backup artifact's path to name its upload
+// marker. The marker exists only once a checksum-verified copy of the
+// artifact is in cloud storage, so an artifact without one may be the only
+// copy of the backup.
+const UploadedSuffix = ".uploaded"
+
+// Uploaded is the content of an upload marker
+type Uploaded struct {
+	Destination string    `json:"destination"`
+	UploadedAt  time.Time `json:"uploaded_at"`
+}
+
+// UploadedPathFor returns the upload marker path of an artifact
+func UploadedPathFor(artifactPath string) string {
+	return strings.TrimSuffix(artifactPath, string(filepath.Separator)) + UploadedSuffix
+}
+
+// IsUploadedMarker reports whether path names an upload marker
+func IsUploadedMarker(path string) bool {
+	return strings.HasSuffix(path, UploadedSuffix)
+}
+
+// MarkUploaded records that artifactPath was uploaded to destination
+func MarkUploaded(artifactPath, destination string, at time.Time) error {
+	data, err := json.MarshalIndent(&Uploaded{Destination: destination, UploadedAt: at}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload marker: %w", err)
+	}
+	if err := os.WriteFile(UploadedPathFor(artifactPath), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write upload marker: %w", err)
+	}
+	return nil
+}
+
+// LoadUploaded reads the upload marker of an artifact. It returns nil
+// without an error when the artifact was never uploaded.
+func LoadUploaded(artifactPath string) (*Uploaded, error) {
+	data, err := os.ReadFile(UploadedPathFor(artifactPath))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var u Uploaded
+	if err := json.Unmarshal(data, &u); err != nil {
+		return nil, fmt.Errorf("failed to parse upload marker of %s: %w", artifactPath, err)
+	}
+	return &u, nil
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// backupAgeCollector serves tenangdb_backup_age_seconds. The age is computed
+// from the stored last backup timestamps on every scrape, so it does not lag
+// behind by the update interval and alert rules need no time() arithmetic.
+type backupAgeCollector struct {
+	desc *prometheus.Desc
+	now  func() time.Time
+
+	mu          sync.RWMutex
+	lastBackups map[string]time.Time
+}
+
+func newBackupAgeCollector() *backupAgeCollector {
+	return &backupAgeCollector{
+		desc: prometheus.NewDesc(
+			"tenangdb_backup_age_seconds",
+			"Time since the last backup of the database finished, successful or not",
+			[]string{"database"}, nil,
+		),
+		now: time.Now,
+	}
+}
+
+// set replaces the last backup timestamps with those of backups
+func (c *backupAgeCollector) set(backups map[string]BackupMetrics) {
+	lastBackups := make(map[string]time.Time, len(backups))
+	for _, backup := range backups {
+		if !backup.LastBackup.IsZero() {
+			lastBackups[backup.Database] = backup.LastBackup
+		}
+	}
+
+	c.mu.Lock()
+	c.lastBackups = lastBackups
+	c.mu.Unlock()
+}
+
+func (c *backupAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *backupAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := c.now()
+	for database, lastBackup := range c.lastBackups {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, now.Sub(lastBackup).Seconds(), database)
+	}
+}
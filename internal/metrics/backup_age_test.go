@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestBackupAgeCollector(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 12, 0, 0, 0, time.UTC)
+	c := newBackupAgeCollector()
+	c.now = func() time.Time { return now }
+	c.set(map[string]BackupMetrics{
+		"orders": {Database: "orders", LastBackup: now.Add(-90 * time.Minute)},
+		"users":  {Database: "users"}, // never backed up
+	})
+
+	ch := make(chan prometheus.Metric, 4)
+	c.Collect(ch)
+	close(ch)
+
+	ages := map[string]float64{}
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatal(err)
+		}
+		ages[m.GetLabel()[0].GetValue()] = m.GetGauge().GetValue()
+	}
+	if len(ages) != 1 || ages["orders"] != 5400 {
+		t.Errorf("ages = %v, want orders at 5400s only", ages)
+	}
+}
@@ -2,13 +2,19 @@ package metrics
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
 	"time"
 
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/disk"
 	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/sla"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -34,6 +40,28 @@ type ExporterMetrics struct {
 	restoreFailed     *prometheus.GaugeVec  // Changed to Gauge to allow setting exact values
 	restoreTimestamp  *prometheus.GaugeVec
 	
+	// Standby refresh metrics
+	standbyRefreshTimestamp *prometheus.GaugeVec
+	standbyDataTimestamp    *prometheus.GaugeVec
+	standbyFreshness        *prometheus.GaugeVec
+	standbyFailed           *prometheus.GaugeVec
+	
+	// Restore drill metrics
+	drillTimestamp   *prometheus.GaugeVec
+	drillLastSuccess *prometheus.GaugeVec
+	drillRTO         *prometheus.GaugeVec
+	drillFailed      *prometheus.GaugeVec
+
+	// Backup freshness SLA metrics, computed from the backup catalog
+	slaMaxAge     *prometheus.GaugeVec
+	slaLastBackup *prometheus.GaugeVec
+	slaBreached   *prometheus.GaugeVec
+
+	// Achieved RPO and RTO over the last 30 days
+	rpoSeconds    *prometheus.GaugeVec
+	rtoSeconds    *prometheus.GaugeVec
+	rtoMaxSeconds *prometheus.GaugeVec
+	
 	// Cleanup metrics
 	cleanupDuration   prometheus.Gauge
 	cleanupSuccess    prometheus.Gauge      // Changed to Gauge to allow setting exact values
@@ -47,8 +75,32 @@ type ExporterMetrics struct {
 	processActive     prometheus.Gauge
 	systemHealth      prometheus.Gauge
 	lastProcessTime   prometheus.Gauge
+	lastRunInfo       *prometheus.GaugeVec
+
+	// Scheduled run watchdog
+	expectedRun prometheus.Gauge
+	runMissed   prometheus.Gauge
+	missedSlot  time.Time // last missed slot logged
+
+	// Maintenance pause
+	backupPaused  prometheus.Gauge
+	pauseUntil    prometheus.Gauge
+	pausedSeconds prometheus.Gauge
+	overduePause  time.Time // start of the overdue pause logged
+
+	// Failure streaks and space left where backups are written
+	backupFailureStreak *prometheus.GaugeVec
+	diskFree            prometheus.Gauge
+	diskSize            prometheus.Gauge
+
+	backupAge *backupAgeCollector
+	phases    phaseGauges
+
+	self selfMetrics
 	
 	storage *MetricsStorage
+	config  *config.Config // nil when the exporter runs without a config
+	log     *logger.Logger
 }
 
 // NewExporterMetrics creates a new ExporterMetrics instance
@@ -152,6 +204,104 @@ func NewExporterMetrics(storage *MetricsStorage) *ExporterMetrics {
 			},
 			[]string{"database"},
 		),
+		standbyRefreshTimestamp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_standby_last_refresh_timestamp",
+				Help: "Timestamp of the last successful or failed standby refresh",
+			},
+			[]string{"standby", "database"},
+		),
+		standbyDataTimestamp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_standby_data_timestamp",
+				Help: "Creation time of the backup last restored on the standby",
+			},
+			[]string{"standby", "database"},
+		),
+		standbyFreshness: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_standby_freshness_seconds",
+				Help: "Age of the data on the standby, from the creation of the backup it was refreshed with",
+			},
+			[]string{"standby", "database"},
+		),
+		standbyFailed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_standby_refresh_failed_total",
+				Help: "Total number of failed standby refreshes",
+			},
+			[]string{"standby", "database"},
+		),
+		drillTimestamp: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_drill_last_timestamp",
+				Help: "Timestamp of the last restore drill",
+			},
+			[]string{"database"},
+		),
+		drillLastSuccess: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_drill_last_success_timestamp",
+				Help: "Timestamp of the last successful restore drill",
+			},
+			[]string{"database"},
+		),
+		drillRTO: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_drill_rto_seconds",
+				Help: "Time the last successful restore drill took to restore and verify the backup",
+			},
+			[]string{"database"},
+		),
+		drillFailed: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_drill_failed",
+				Help: "Whether the last restore drill failed (1 = failed)",
+			},
+			[]string{"database"},
+		),
+		slaMaxAge: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_sla_max_backup_age_seconds",
+				Help: "Maximum age of the newest verified backup allowed by the SLA",
+			},
+			[]string{"database"},
+		),
+		slaLastBackup: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_sla_last_verified_backup_timestamp",
+				Help: "Creation time of the newest verified backup",
+			},
+			[]string{"database"},
+		),
+		slaBreached: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_sla_breached",
+				Help: "Whether the newest verified backup is older than the SLA allows (1 = breached)",
+			},
+			[]string{"database"},
+		),
+		rpoSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_rpo_seconds",
+				Help: "Achieved RPO over the last 30 days: the longest gap between verified backups, or the age of the newest if longer",
+			},
+			[]string{"database"},
+		),
+		rtoSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_rto_seconds",
+				Help: "Duration of the newest measured restore (drill or real restore) in the last 30 days",
+			},
+			[]string{"database"},
+		),
+		rtoMaxSeconds: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_rto_max_seconds",
+				Help: "Duration of the slowest measured restore in the last 30 days",
+			},
+			[]string{"database"},
+		),
 		cleanupDuration: prometheus.NewGauge(
 			prometheus.GaugeOpts{
 				Name: "tenangdb_cleanup_duration_seconds",
@@ -212,13 +362,77 @@ func NewExporterMetrics(storage *MetricsStorage) *ExporterMetrics {
 				Help: "Timestamp of the last backup process",
 			},
 		),
-		storage: storage,
+		lastRunInfo: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_run_info",
+				Help: "Start timestamp of the last backup run, labelled by run ID",
+			},
+			[]string{"run_id"},
+		),
+		expectedRun: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_expected_run_timestamp",
+				Help: "Time the latest scheduled backup run was due, per backup.schedule",
+			},
+		),
+		runMissed: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_run_missed",
+				Help: "Whether the latest scheduled backup run did not start within backup.watchdog.grace (1 = missed)",
+			},
+		),
+		backupPaused: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_paused",
+				Help: "Whether backups are paused for maintenance with tenangdb pause (1 = paused)",
+			},
+		),
+		pauseUntil: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_pause_until_timestamp",
+				Help: "Time the maintenance pause ends, 0 when backups are not paused",
+			},
+		),
+		pausedSeconds: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_paused_seconds",
+				Help: "How long backups have been paused for maintenance, 0 when they are not",
+			},
+		),
+		backupFailureStreak: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_consecutive_failures",
+				Help: "Number of backups of the database that failed in a row since the last success",
+			},
+			[]string{"database"},
+		),
+		diskFree: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_disk_free_bytes",
+				Help: "Free space on the filesystem of the backup directory",
+			},
+		),
+		diskSize: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_backup_disk_size_bytes",
+				Help: "Size of the filesystem of the backup directory",
+			},
+		),
+		backupAge: newBackupAgeCollector(),
+		phases:    newPhaseGauges(),
+		self:      newSelfMetrics(storage),
+		storage:   storage,
 	}
 }
 
-// Register registers all metrics with Prometheus
-func (e *ExporterMetrics) Register() {
-	prometheus.MustRegister(
+// Register registers all metrics with registry
+func (e *ExporterMetrics) Register(registry prometheus.Registerer) {
+	registry.MustRegister(e.collectors()...)
+}
+
+// collectors returns every metric the exporter serves
+func (e *ExporterMetrics) collectors() []prometheus.Collector {
+	return append([]prometheus.Collector{
 		e.backupDuration,
 		e.backupSuccess,
 		e.backupFailed,
@@ -233,6 +447,20 @@ func (e *ExporterMetrics) Register() {
 		e.restoreSuccess,
 		e.restoreFailed,
 		e.restoreTimestamp,
+		e.standbyRefreshTimestamp,
+		e.standbyDataTimestamp,
+		e.standbyFreshness,
+		e.standbyFailed,
+		e.drillTimestamp,
+		e.drillLastSuccess,
+		e.drillRTO,
+		e.drillFailed,
+		e.slaMaxAge,
+		e.slaLastBackup,
+		e.slaBreached,
+		e.rpoSeconds,
+		e.rtoSeconds,
+		e.rtoMaxSeconds,
 		e.cleanupDuration,
 		e.cleanupSuccess,
 		e.cleanupFailed,
@@ -243,15 +471,35 @@ func (e *ExporterMetrics) Register() {
 		e.processActive,
 		e.systemHealth,
 		e.lastProcessTime,
-	)
+		e.lastRunInfo,
+		e.expectedRun,
+		e.runMissed,
+		e.backupPaused,
+		e.pauseUntil,
+		e.pausedSeconds,
+		e.backupFailureStreak,
+		e.diskFree,
+		e.diskSize,
+		e.backupAge,
+		e.self.loadFailures,
+		e.self.reloadDuration,
+		e.self.lastReload,
+		e.self.storageAge,
+	}, e.phases.collectors()...)
 }
 
 // UpdateMetrics updates all metrics from storage
 func (e *ExporterMetrics) UpdateMetrics() error {
+	start := time.Now()
 	data, err := e.storage.LoadMetrics()
 	if err != nil {
+		e.self.loadFailures.Inc()
 		return fmt.Errorf("failed to load metrics: %w", err)
 	}
+	defer func() {
+		e.self.reloadDuration.Set(time.Since(start).Seconds())
+		e.self.lastReload.SetToCurrentTime()
+	}()
 	
 	// Update system metrics
 	e.totalDatabases.Set(float64(data.System.TotalDatabases))
@@ -268,6 +516,10 @@ func (e *ExporterMetrics) UpdateMetrics() error {
 	if !data.System.LastBackupProcess.IsZero() {
 		e.lastProcessTime.Set(float64(data.System.LastBackupProcess.Unix()))
 	}
+	e.lastRunInfo.Reset()
+	if data.System.LastRunID != "" {
+		e.lastRunInfo.WithLabelValues(data.System.LastRunID).Set(float64(data.System.LastRunStarted.Unix()))
+	}
 	
 	// Update backup metrics
 	for _, backup := range data.Backups {
@@ -275,11 +527,15 @@ func (e *ExporterMetrics) UpdateMetrics() error {
 		e.backupSuccess.WithLabelValues(backup.Database).Set(float64(backup.SuccessCount))
 		e.backupFailed.WithLabelValues(backup.Database).Set(float64(backup.FailureCount))
 		e.backupSize.WithLabelValues(backup.Database).Set(float64(backup.SizeBytes))
+		e.backupFailureStreak.WithLabelValues(backup.Database).Set(float64(backup.ConsecutiveFailures))
+		e.phases.set(backup.Database, backup.Phases)
 		if !backup.LastBackup.IsZero() {
 			e.backupTimestamp.WithLabelValues(backup.Database).Set(float64(backup.LastBackup.Unix()))
 		}
 	}
 	
+	e.backupAge.set(data.Backups)
+	
 	// Update upload metrics
 	for _, upload := range data.Uploads {
 		e.uploadDuration.WithLabelValues(upload.Database).Set(upload.DurationSeconds)
@@ -301,6 +557,34 @@ func (e *ExporterMetrics) UpdateMetrics() error {
 		}
 	}
 	
+	// Update standby refresh metrics
+	for _, refresh := range data.Standbys {
+		e.standbyRefreshTimestamp.WithLabelValues(refresh.Standby, refresh.Database).Set(float64(refresh.LastRefresh.Unix()))
+		e.standbyFailed.WithLabelValues(refresh.Standby, refresh.Database).Set(float64(refresh.FailureCount))
+		if !refresh.BackupCreatedAt.IsZero() {
+			e.standbyDataTimestamp.WithLabelValues(refresh.Standby, refresh.Database).Set(float64(refresh.BackupCreatedAt.Unix()))
+			e.standbyFreshness.WithLabelValues(refresh.Standby, refresh.Database).Set(time.Since(refresh.BackupCreatedAt).Seconds())
+		}
+	}
+	
+	// Update restore drill metrics
+	for _, drill := range data.Drills {
+		e.drillTimestamp.WithLabelValues(drill.Database).Set(float64(drill.LastDrill.Unix()))
+		failed := 0.0
+		if drill.Status == "failed" {
+			failed = 1
+		}
+		e.drillFailed.WithLabelValues(drill.Database).Set(failed)
+		if !drill.LastSuccess.IsZero() {
+			e.drillLastSuccess.WithLabelValues(drill.Database).Set(float64(drill.LastSuccess.Unix()))
+			e.drillRTO.WithLabelValues(drill.Database).Set(drill.RTOSeconds)
+		}
+	}
+	
+	e.updateSLAMetrics(data)
+	e.updateWatchdogMetrics(data, e.updatePauseMetrics())
+	e.updateDiskMetrics()
+	
 	// Update cleanup metrics
 	e.cleanupDuration.Set(data.Cleanup.DurationSeconds)
 	e.cleanupSuccess.Set(float64(data.Cleanup.SuccessCount))
@@ -314,23 +598,89 @@ func (e *ExporterMetrics) UpdateMetrics() error {
 	return nil
 }
 
+// updateSLAMetrics evaluates the backup freshness SLAs and the achieved
+// RPO and RTO against the local backup catalog
+func (e *ExporterMetrics) updateSLAMetrics(data *MetricsData) {
+	if e.config == nil {
+		return
+	}
+	entries, err := catalog.Scan(e.config.Backup.Directory)
+	if err != nil {
+		return
+	}
+
+	e.slaMaxAge.Reset()
+	e.slaLastBackup.Reset()
+	e.slaBreached.Reset()
+	for _, status := range sla.Evaluate(e.config, entries, time.Now()) {
+		e.slaMaxAge.WithLabelValues(status.Database).Set(status.MaxAge.Seconds())
+		if status.LastBackup != nil {
+			e.slaLastBackup.WithLabelValues(status.Database).Set(float64(status.LastBackup.Manifest.CreatedAt.Unix()))
+		}
+		breached := 0.0
+		if status.Breached {
+			breached = 1
+		}
+		e.slaBreached.WithLabelValues(status.Database).Set(breached)
+	}
+
+	e.rpoSeconds.Reset()
+	e.rtoSeconds.Reset()
+	e.rtoMaxSeconds.Reset()
+	for _, m := range sla.Measure(e.config, entries, data.RestoreSamples(), time.Now(), sla.DefaultWindow) {
+		if m.HasBackup {
+			e.rpoSeconds.WithLabelValues(m.Database).Set(m.RPO().Seconds())
+		}
+		if m.Restores > 0 {
+			e.rtoSeconds.WithLabelValues(m.Database).Set(m.LastRTO.Seconds())
+			e.rtoMaxSeconds.WithLabelValues(m.Database).Set(m.MaxRTO.Seconds())
+		}
+	}
+}
+
+// updateDiskMetrics reports the space left on the filesystem backups are
+// written to
+func (e *ExporterMetrics) updateDiskMetrics() {
+	if e.config == nil {
+		return
+	}
+	free, size, err := disk.Space(e.config.Backup.Directory)
+	if err != nil {
+		return
+	}
+	e.diskFree.Set(float64(free))
+	e.diskSize.Set(float64(size))
+}
+
 // getCurrentVersion returns version information for display
 func getCurrentVersion() string {
 	return "v1.1.3 (" + runtime.Version() + ")"
 }
 
-// StartMetricsExporter starts the metrics exporter HTTP server
-func StartMetricsExporter(ctx context.Context, port, metricsFile string, log *logger.Logger) error {
+// StartMetricsExporter starts the metrics exporter HTTP server. cfg may be
+// nil; with a config, backup freshness SLAs are exported too.
+func StartMetricsExporter(ctx context.Context, port, metricsFile string, cfg *config.Config, opts ExporterOptions, log *logger.Logger) error {
 	// Create metrics storage
 	storage := NewMetricsStorage(metricsFile)
 	
 	// Create exporter metrics
 	exporterMetrics := NewExporterMetrics(storage)
-	exporterMetrics.Register()
+	exporterMetrics.config = cfg
+	exporterMetrics.log = log
+	
+	// A registry of its own, so Go and process metrics can be turned off
+	registry := prometheus.NewRegistry()
+	exporterMetrics.Register(registry)
+	if opts.GoMetrics {
+		registry.MustRegister(collectors.NewGoCollector())
+	}
+	if opts.ProcessMetrics {
+		registry.MustRegister(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
 	
 	// Create HTTP server
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(registry, promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})))
 	
 	// Add health check endpoint
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -347,6 +697,28 @@ func StartMetricsExporter(ctx context.Context, port, metricsFile string, log *lo
 		_, _ = w.Write([]byte(`{"status":"healthy","service":"tenangdb-exporter"}`))
 	})
 	
+	// Add status endpoint for monitoring checks without Prometheus
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		data, err := storage.LoadMetrics()
+		if err != nil {
+			http.Error(w, "Cannot load metrics", http.StatusServiceUnavailable)
+			return
+		}
+		var entries []catalog.Entry
+		if cfg != nil {
+			entries, _ = catalog.Scan(cfg.Backup.Directory)
+		}
+		status := BuildStatus(data, cfg, entries, time.Now())
+		
+		w.Header().Set("Content-Type", "application/json")
+		if status.Status == StatusCritical {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(status)
+	})
+	
 	// Add readiness check endpoint
 	mux.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -386,6 +758,7 @@ func StartMetricsExporter(ctx context.Context, port, metricsFile string, log *lo
     <ul>
         <li><a href="/metrics">Metrics</a></li>
         <li><a href="/health">Health</a></li>
+        <li><a href="/status">Status</a></li>
         <li><a href="/ready">Ready</a></li>
     </ul>
     
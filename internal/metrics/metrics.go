@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"time"
 
@@ -91,6 +93,15 @@ var (
 		},
 	)
 
+	// Current backup run, labelled by run ID so a run can be found in logs
+	BackupRunInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "tenangdb_backup_run_info",
+			Help: "Start timestamp of the current or last backup run, labelled by run ID",
+		},
+		[]string{"run_id"},
+	)
+
 	// Total databases configured
 	TotalDatabases = prometheus.NewGauge(
 		prometheus.GaugeOpts{
@@ -214,6 +225,7 @@ func Init() {
 		BackupSizeBytes,
 		LastBackupTimestamp,
 		BackupProcessRunning,
+		BackupRunInfo,
 		
 		// Upload metrics
 		UploadDurationSeconds,
@@ -236,6 +248,7 @@ func Init() {
 		DiskUsageBytes,
 		ActiveOperations,
 	)
+	prometheus.MustRegister(BackupPhases.collectors()...)
 }
 
 // RecordBackupStart records the start of a backup operation
@@ -243,6 +256,12 @@ func RecordBackupStart(database string) {
 	BackupProcessRunning.Set(1)
 }
 
+// RecordRunStart exposes the run ID of a new backup run, replacing the last one
+func RecordRunStart(runID string) {
+	BackupRunInfo.Reset()
+	BackupRunInfo.WithLabelValues(runID).Set(float64(time.Now().Unix()))
+}
+
 // RecordBackupEnd records the end of a backup operation
 func RecordBackupEnd(database string, duration time.Duration, success bool, sizeBytes int64) {
 	status := "success"
@@ -356,8 +375,32 @@ func SetActiveOperations(operationType string, count int) {
 	ActiveOperations.WithLabelValues(operationType).Set(float64(count))
 }
 
-// StartMetricsServer starts HTTP server for Prometheus metrics
-func StartMetricsServer(port string) error {
-	http.Handle("/metrics", promhttp.Handler())
-	return http.ListenAndServe(":"+port, nil)
-}
\ No newline at end of file
+// Server serves the Prometheus metrics of a running command
+type Server struct {
+	server *http.Server
+}
+
+// StartServer serves /metrics on port with its own mux, so it neither
+// shares handlers with other servers in the process nor starts half-way when
+// the port is taken, for example by the exporter or another command
+func StartServer(port string) (*Server, error) {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	s := &Server{server: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}}
+	go func() {
+		_ = s.server.Serve(listener) // returns http.ErrServerClosed after Shutdown
+	}()
+	return s, nil
+}
+
+// Shutdown stops the server, letting scrapes in flight finish within timeout
+func (s *Server) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.server.Shutdown(ctx)
+}
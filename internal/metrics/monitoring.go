@@ -0,0 +1,350 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MonitoringOptions sets the thresholds of the generated alert rules and
+// dashboard
+type MonitoringOptions struct {
+	MaxBackupAge  time.Duration // age of the last backup of a database that alerts
+	FailureStreak int           // backups failed in a row that alert
+	MinDiskFree   float64       // free space of the backup filesystem, in percent, that alerts
+	MaxPause      time.Duration // how long a maintenance pause lasts before it alerts
+}
+
+// DefaultMonitoringOptions alerts for a daily schedule with some slack
+var DefaultMonitoringOptions = MonitoringOptions{
+	MaxBackupAge:  26 * time.Hour,
+	FailureStreak: 3,
+	MinDiskFree:   10,
+	MaxPause:      24 * time.Hour,
+}
+
+// alertRule is a Prometheus alerting rule
+type alertRule struct {
+	name        string
+	expr        string
+	forDuration string
+	severity    string
+	summary     string
+	description string
+}
+
+// alertRules returns the recommended rules. Their expressions only use
+// metrics the exporter serves, which TestMonitoringUsesExportedMetrics
+// enforces.
+func alertRules(opts MonitoringOptions) []alertRule {
+	return []alertRule{
+		{
+			name:        "TenangDBBackupTooOld",
+			expr:        fmt.Sprintf("tenangdb_backup_age_seconds > %d", int64(opts.MaxBackupAge.Seconds())),
+			forDuration: "10m",
+			severity:    "critical",
+			summary:     fmt.Sprintf("Last backup of {{ $labels.database }} is older than %s", promDuration(opts.MaxBackupAge)),
+			description: "No backup of {{ $labels.database }} finished recently. Check the backup log with `journalctl -u tenangdb` and the timer with `systemctl list-timers tenangdb.timer`.",
+		},
+		{
+			name:        "TenangDBBackupFailing",
+			expr:        fmt.Sprintf("tenangdb_backup_consecutive_failures >= %d", opts.FailureStreak),
+			severity:    "critical",
+			summary:     "Backups of {{ $labels.database }} are failing",
+			description: "The last {{ $value }} backups of {{ $labels.database }} failed. Check the backup log with `journalctl -u tenangdb`.",
+		},
+		{
+			name:        "TenangDBBackupDiskLow",
+			expr:        fmt.Sprintf("tenangdb_backup_disk_free_bytes / tenangdb_backup_disk_size_bytes * 100 < %s", strconv.FormatFloat(opts.MinDiskFree, 'f', -1, 64)),
+			forDuration: "15m",
+			severity:    "warning",
+			summary:     "Backup disk is running out of space",
+			description: "Only {{ $value | printf \"%.0f\" }}% of the backup filesystem is free. Run `tenangdb cleanup` or lower retention before backups start failing.",
+		},
+		{
+			name:        "TenangDBBackupSLABreached",
+			expr:        "tenangdb_sla_breached == 1",
+			forDuration: "10m",
+			severity:    "critical",
+			summary:     "Backup SLA breached for {{ $labels.database }}",
+			description: "The newest verified backup of {{ $labels.database }} is older than its configured max_backup_age. Run `tenangdb sla status` for details.",
+		},
+		{
+			name:        "TenangDBBackupRunMissed",
+			expr:        "tenangdb_backup_run_missed == 1",
+			severity:    "critical",
+			summary:     "Scheduled backup run did not start",
+			description: "No backup run started within backup.watchdog.grace of its scheduled time. Check that the host was up and tenangdb.timer is enabled with `systemctl list-timers tenangdb.timer`.",
+		},
+		{
+			name:        "TenangDBBackupsPausedTooLong",
+			expr:        fmt.Sprintf("tenangdb_backup_paused_seconds > %d", int64(opts.MaxPause.Seconds())),
+			severity:    "warning",
+			summary:     fmt.Sprintf("Backups have been paused for longer than %s", promDuration(opts.MaxPause)),
+			description: "A maintenance pause set with `tenangdb pause` is still in effect and no backups run until it ends. Run `tenangdb resume` if the maintenance is over.",
+		},
+		{
+			name:        "TenangDBExporterLoadFailing",
+			expr:        "increase(tenangdb_exporter_load_failures_total[15m]) > 0",
+			severity:    "warning",
+			summary:     "tenangdb-exporter cannot read the metrics file",
+			description: "The exporter failed to read or parse metrics.json, so the backup metrics it serves are stale. Check the exporter log with `journalctl -u tenangdb-exporter`.",
+		},
+		{
+			name:        "TenangDBRestoreDrillFailed",
+			expr:        "tenangdb_drill_failed == 1",
+			severity:    "critical",
+			summary:     "Restore drill failed for {{ $labels.database }}",
+			description: "The last restore drill of {{ $labels.database }} failed. Check the drill log with `journalctl -u tenangdb-drill`.",
+		},
+		{
+			name:        "TenangDBRestoreDrillStale",
+			expr:        "time() - max(tenangdb_drill_last_success_timestamp) > 15 * 86400",
+			severity:    "warning",
+			summary:     "No successful restore drill in 15 days",
+			description: "Restore drills have not passed for over two weeks. Check that tenangdb-drill.timer is enabled.",
+		},
+	}
+}
+
+// AlertRules renders the recommended alert rules as a Prometheus rule file
+func AlertRules(opts MonitoringOptions) string {
+	var b strings.Builder
+
+	b.WriteString("# TenangDB alert rules\n")
+	b.WriteString("# Generated by: tenangdb export-monitoring --format prometheus-rules\n")
+	b.WriteString("groups:\n")
+	b.WriteString("  - name: tenangdb\n")
+	b.WriteString("    rules:\n")
+	for i, rule := range alertRules(opts) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(fmt.Sprintf("      - alert: %s\n", rule.name))
+		b.WriteString(fmt.Sprintf("        expr: %s\n", rule.expr))
+		if rule.forDuration != "" {
+			b.WriteString(fmt.Sprintf("        for: %s\n", rule.forDuration))
+		}
+		b.WriteString("        labels:\n")
+		b.WriteString(fmt.Sprintf("          severity: %s\n", rule.severity))
+		b.WriteString("        annotations:\n")
+		b.WriteString(fmt.Sprintf("          summary: %s\n", strconv.Quote(rule.summary)))
+		b.WriteString(fmt.Sprintf("          description: %s\n", strconv.Quote(rule.description)))
+	}
+	return b.String()
+}
+
+// promDuration formats d the way Prometheus writes durations, "26h" or "90m"
+func promDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", int64(d.Seconds()))
+}
+
+// Grafana dashboard model, only the fields the generated dashboard sets
+type (
+	grafanaDashboard struct {
+		UID           string            `json:"uid"`
+		Title         string            `json:"title"`
+		Description   string            `json:"description"`
+		Tags          []string          `json:"tags"`
+		Editable      bool              `json:"editable"`
+		Refresh       string            `json:"refresh"`
+		SchemaVersion int               `json:"schemaVersion"`
+		Time          grafanaTimeRange  `json:"time"`
+		Templating    grafanaTemplating `json:"templating"`
+		Panels        []grafanaPanel    `json:"panels"`
+	}
+	grafanaTimeRange struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+	}
+	grafanaTemplating struct {
+		List []grafanaVariable `json:"list"`
+	}
+	grafanaVariable struct {
+		Name  string `json:"name"`
+		Label string `json:"label"`
+		Type  string `json:"type"`
+		Query string `json:"query"`
+	}
+	grafanaDatasource struct {
+		Type string `json:"type"`
+		UID  string `json:"uid"`
+	}
+	grafanaPanel struct {
+		ID          int                 `json:"id"`
+		Type        string              `json:"type"`
+		Title       string              `json:"title"`
+		GridPos     grafanaGridPos      `json:"gridPos"`
+		Datasource  *grafanaDatasource  `json:"datasource,omitempty"`
+		Targets     []grafanaTarget     `json:"targets,omitempty"`
+		FieldConfig *grafanaFieldConfig `json:"fieldConfig,omitempty"`
+		Collapsed   *bool               `json:"collapsed,omitempty"` // rows only
+	}
+	grafanaGridPos struct {
+		H int `json:"h"`
+		W int `json:"w"`
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	grafanaTarget struct {
+		RefID        string `json:"refId"`
+		Expr         string `json:"expr"`
+		LegendFormat string `json:"legendFormat,omitempty"`
+	}
+	grafanaFieldConfig struct {
+		Defaults  grafanaFieldDefaults `json:"defaults"`
+		Overrides []any                `json:"overrides"`
+	}
+	grafanaFieldDefaults struct {
+		Unit       string             `json:"unit,omitempty"`
+		Thresholds *grafanaThresholds `json:"thresholds,omitempty"`
+	}
+	grafanaThresholds struct {
+		Mode  string                 `json:"mode"`
+		Steps []grafanaThresholdStep `json:"steps"`
+	}
+	grafanaThresholdStep struct {
+		Color string   `json:"color"`
+		Value *float64 `json:"value"` // nil is the base step
+	}
+)
+
+// dashboardBuilder lays panels out left to right in rows 24 units wide
+type dashboardBuilder struct {
+	panels []grafanaPanel
+	x, y   int
+	height int // of the tallest panel in the current line
+}
+
+func (b *dashboardBuilder) row(title string) {
+	b.newLine()
+	collapsed := false
+	b.panels = append(b.panels, grafanaPanel{
+		ID:        len(b.panels) + 1,
+		Type:      "row",
+		Title:     title,
+		GridPos:   grafanaGridPos{H: 1, W: 24, X: 0, Y: b.y},
+		Collapsed: &collapsed,
+	})
+	b.y++
+}
+
+func (b *dashboardBuilder) panel(kind, title, unit string, width int, thresholds *grafanaThresholds, targets ...grafanaTarget) {
+	if b.x+width > 24 {
+		b.newLine()
+	}
+	for i := range targets {
+		targets[i].RefID = string(rune('A' + i))
+	}
+	b.panels = append(b.panels, grafanaPanel{
+		ID:         len(b.panels) + 1,
+		Type:       kind,
+		Title:      title,
+		GridPos:    grafanaGridPos{H: 8, W: width, X: b.x, Y: b.y},
+		Datasource: &grafanaDatasource{Type: "prometheus", UID: "${datasource}"},
+		Targets:    targets,
+		FieldConfig: &grafanaFieldConfig{
+			Defaults:  grafanaFieldDefaults{Unit: unit, Thresholds: thresholds},
+			Overrides: []any{},
+		},
+	})
+	b.x += width
+	b.height = 8
+}
+
+func (b *dashboardBuilder) newLine() {
+	b.y += b.height
+	b.x, b.height = 0, 0
+}
+
+// above turns red at limit and over, below turns red under it
+func above(limit float64) *grafanaThresholds {
+	return &grafanaThresholds{Mode: "absolute", Steps: []grafanaThresholdStep{
+		{Color: "green"}, {Color: "red", Value: &limit},
+	}}
+}
+
+func below(limit float64) *grafanaThresholds {
+	return &grafanaThresholds{Mode: "absolute", Steps: []grafanaThresholdStep{
+		{Color: "red"}, {Color: "green", Value: &limit},
+	}}
+}
+
+func perDatabase(expr string) grafanaTarget {
+	return grafanaTarget{Expr: expr, LegendFormat: "{{database}}"}
+}
+
+// Dashboard renders a Grafana dashboard of the exporter metrics, with the
+// alert thresholds of opts marked on its panels
+func Dashboard(opts MonitoringOptions) ([]byte, error) {
+	b := &dashboardBuilder{}
+
+	b.row("Backup Health")
+	b.panel("stat", "Backup Age", "s", 6, above(opts.MaxBackupAge.Seconds()),
+		perDatabase("tenangdb_backup_age_seconds"))
+	b.panel("stat", "Consecutive Failures", "none", 6, above(float64(opts.FailureStreak)),
+		perDatabase("tenangdb_backup_consecutive_failures"))
+	b.panel("gauge", "Backup Disk Free", "percent", 6, below(opts.MinDiskFree),
+		grafanaTarget{Expr: "tenangdb_backup_disk_free_bytes / tenangdb_backup_disk_size_bytes * 100", LegendFormat: "free"})
+	b.panel("stat", "SLA Breached", "none", 6, above(1),
+		perDatabase("tenangdb_sla_breached"),
+		grafanaTarget{Expr: "tenangdb_backup_run_missed", LegendFormat: "run missed"})
+
+	b.row("Backups")
+	b.panel("timeseries", "Backups per Hour", "none", 6, nil,
+		grafanaTarget{Expr: "sum(increase(tenangdb_backup_success_total[1h]))", LegendFormat: "success"},
+		grafanaTarget{Expr: "sum(increase(tenangdb_backup_failed_total[1h]))", LegendFormat: "failed"})
+	b.panel("timeseries", "Backup Duration", "s", 6, nil, perDatabase("tenangdb_backup_duration_seconds"))
+	b.panel("timeseries", "Backup Size", "bytes", 6, nil, perDatabase("tenangdb_backup_size_bytes"))
+	b.panel("stat", "Paused For", "s", 6, above(opts.MaxPause.Seconds()),
+		grafanaTarget{Expr: "tenangdb_backup_paused_seconds", LegendFormat: "paused"})
+
+	b.row("Backup Phases")
+	b.panel("timeseries", "Phase Duration", "s", 12, nil,
+		grafanaTarget{Expr: "tenangdb_backup_phase_duration_seconds", LegendFormat: "{{database}} {{phase}}"})
+	b.panel("timeseries", "Phase Throughput", "Bps", 12, nil,
+		grafanaTarget{Expr: "tenangdb_backup_phase_bytes_out / tenangdb_backup_phase_duration_seconds", LegendFormat: "{{database}} {{phase}}"})
+
+	b.row("Uploads")
+	b.panel("timeseries", "Upload Duration", "s", 12, nil, perDatabase("tenangdb_upload_duration_seconds"))
+	b.panel("timeseries", "Upload Failures", "none", 12, nil, perDatabase("tenangdb_upload_failed_total"))
+
+	b.row("Recovery")
+	b.panel("stat", "RPO", "s", 6, nil, perDatabase("tenangdb_rpo_seconds"))
+	b.panel("stat", "RTO", "s", 6, nil, perDatabase("tenangdb_rto_seconds"))
+	b.panel("stat", "Since Last Successful Drill", "s", 6, above(15*86400),
+		perDatabase("time() - tenangdb_drill_last_success_timestamp"))
+	b.panel("stat", "Drill Failed", "none", 6, above(1), perDatabase("tenangdb_drill_failed"))
+
+	b.row("Exporter")
+	b.panel("stat", "Metrics File Age", "s", 8, nil,
+		grafanaTarget{Expr: "tenangdb_exporter_storage_age_seconds", LegendFormat: "age"})
+	b.panel("stat", "Load Failures (1h)", "none", 8, above(1),
+		grafanaTarget{Expr: "increase(tenangdb_exporter_load_failures_total[1h])", LegendFormat: "failures"})
+	b.panel("stat", "Reload Duration", "s", 8, nil,
+		grafanaTarget{Expr: "tenangdb_exporter_reload_duration_seconds", LegendFormat: "reload"})
+
+	dashboard := grafanaDashboard{
+		UID:           "tenangdb-generated",
+		Title:         "TenangDB",
+		Description:   "Generated by: tenangdb export-monitoring --format grafana-json",
+		Tags:          []string{"tenangdb", "backup"},
+		Editable:      true,
+		Refresh:       "1m",
+		SchemaVersion: 39,
+		Time:          grafanaTimeRange{From: "now-7d", To: "now"},
+		Templating: grafanaTemplating{List: []grafanaVariable{
+			{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"},
+		}},
+		Panels: b.panels,
+	}
+	return json.MarshalIndent(dashboard, "", "  ")
+}
@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestMonitoringUsesExportedMetrics keeps the generated rules and dashboard
+// in sync with the exporter: renaming or dropping a metric fails here
+func TestMonitoringUsesExportedMetrics(t *testing.T) {
+	exported := map[string]bool{}
+	descs := make(chan *prometheus.Desc, 16)
+	go func() {
+		for _, c := range NewExporterMetrics(nil).collectors() {
+			c.Describe(descs)
+		}
+		close(descs)
+	}()
+	fqName := regexp.MustCompile(`fqName: "([^"]+)"`)
+	for desc := range descs {
+		if m := fqName.FindStringSubmatch(desc.String()); m != nil {
+			exported[m[1]] = true
+		}
+	}
+
+	dashboard, err := Dashboard(DefaultMonitoringOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(dashboard) {
+		t.Fatal("Dashboard() is not valid JSON")
+	}
+
+	used := regexp.MustCompile(`tenangdb_[a-z_]+`)
+	for _, name := range used.FindAllString(AlertRules(DefaultMonitoringOptions)+string(dashboard), -1) {
+		if !exported[name] {
+			t.Errorf("%s is not served by the exporter", name)
+		}
+	}
+}
+
+func TestAlertRulesThresholds(t *testing.T) {
+	rules := AlertRules(DefaultMonitoringOptions)
+	for _, want := range []string{
+		"tenangdb_backup_age_seconds > 93600",
+		"is older than 26h",
+		"tenangdb_backup_consecutive_failures >= 3",
+		"* 100 < 10\n",
+	} {
+		if !strings.Contains(rules, want) {
+			t.Errorf("AlertRules() is missing %q", want)
+		}
+	}
+}
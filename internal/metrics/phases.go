@@ -0,0 +1,95 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Phases of a database backup, the phase label of the phase metrics
+const (
+	PhaseDump     = "dump"
+	PhaseCompress = "compress"
+	PhaseEncrypt  = "encrypt"
+	PhaseUpload   = "upload"
+	PhaseStream   = "stream" // compressed and uploaded in one stream
+)
+
+// PhaseMetrics is the time one phase of a backup took and the data that
+// went through it. A dump only has output, its input is read from MySQL.
+type PhaseMetrics struct {
+	DurationSeconds float64 `json:"duration_seconds"`
+	BytesIn         int64   `json:"bytes_in,omitempty"`
+	BytesOut        int64   `json:"bytes_out"`
+}
+
+// phaseGauges serve the phase metrics of the last successful backup of each
+// database, from the inline server or the exporter
+type phaseGauges struct {
+	duration *prometheus.GaugeVec
+	bytesIn  *prometheus.GaugeVec
+	bytesOut *prometheus.GaugeVec
+}
+
+func newPhaseGauges() phaseGauges {
+	labels := []string{"database", "phase"}
+	return phaseGauges{
+		duration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tenangdb_backup_phase_duration_seconds",
+			Help: "Duration of each phase of the last successful backup: dump, compress, encrypt, upload, or stream when compressed and uploaded together",
+		}, labels),
+		bytesIn: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tenangdb_backup_phase_bytes_in",
+			Help: "Bytes read by each phase of the last successful backup, absent for the dump",
+		}, labels),
+		bytesOut: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "tenangdb_backup_phase_bytes_out",
+			Help: "Bytes written by each phase of the last successful backup",
+		}, labels),
+	}
+}
+
+func (g phaseGauges) collectors() []prometheus.Collector {
+	return []prometheus.Collector{g.duration, g.bytesIn, g.bytesOut}
+}
+
+// set replaces the phases of database, dropping phases its last backup
+// did not go through
+func (g phaseGauges) set(database string, phases map[string]PhaseMetrics) {
+	for _, vec := range []*prometheus.GaugeVec{g.duration, g.bytesIn, g.bytesOut} {
+		vec.DeletePartialMatch(prometheus.Labels{"database": database})
+	}
+	for phase, m := range phases {
+		g.duration.WithLabelValues(database, phase).Set(m.DurationSeconds)
+		if m.BytesIn > 0 {
+			g.bytesIn.WithLabelValues(database, phase).Set(float64(m.BytesIn))
+		}
+		g.bytesOut.WithLabelValues(database, phase).Set(float64(m.BytesOut))
+	}
+}
+
+// BackupPhases serves the phase metrics on the inline metrics server
+var BackupPhases = newPhaseGauges()
+
+// RecordBackupPhases records the phases of a successful backup of database
+func RecordBackupPhases(database string, phases map[string]PhaseMetrics) {
+	BackupPhases.set(database, phases)
+}
+
+// UpdatePhaseMetrics stores the phases of a successful backup of database,
+// replacing those of its previous backup
+func (s *MetricsStorage) UpdatePhaseMetrics(database string, phases map[string]PhaseMetrics) error {
+	data, err := s.LoadMetrics()
+	if err != nil {
+		return err
+	}
+
+	backup, exists := data.Backups[database]
+	if !exists {
+		backup = BackupMetrics{
+			Database: database,
+		}
+	}
+	backup.Phases = phases
+	data.Backups[database] = backup
+
+	return s.SaveMetrics(data)
+}
@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestPhaseGaugesReplacePhases(t *testing.T) {
+	g := newPhaseGauges()
+
+	g.set("orders", map[string]PhaseMetrics{
+		PhaseDump:     {DurationSeconds: 120, BytesOut: 4 << 30},
+		PhaseCompress: {DurationSeconds: 300, BytesIn: 4 << 30, BytesOut: 1 << 30},
+	})
+	g.set("users", map[string]PhaseMetrics{
+		PhaseDump: {DurationSeconds: 10, BytesOut: 1 << 20},
+	})
+	if got := testutil.ToFloat64(g.duration.WithLabelValues("orders", PhaseCompress)); got != 300 {
+		t.Errorf("compress duration = %v, want 300", got)
+	}
+	// The dump reads from MySQL, it has no bytes in
+	if got := testutil.CollectAndCount(g.bytesIn); got != 1 {
+		t.Errorf("bytes in series = %d, want 1", got)
+	}
+
+	// A backup that streamed instead of compressing locally drops the
+	// compress phase
+	g.set("orders", map[string]PhaseMetrics{
+		PhaseDump:   {DurationSeconds: 120, BytesOut: 4 << 30},
+		PhaseStream: {DurationSeconds: 400, BytesIn: 4 << 30, BytesOut: 1 << 30},
+	})
+	if got := testutil.CollectAndCount(g.duration); got != 3 {
+		t.Errorf("duration series = %d, want 3", got)
+	}
+	if got := testutil.ToFloat64(g.duration.WithLabelValues("users", PhaseDump)); got != 10 {
+		t.Errorf("users dump duration = %v, want 10", got)
+	}
+}
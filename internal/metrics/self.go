@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"os"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ExporterOptions sets what tenangdb-exporter serves besides the backup
+// metrics
+type ExporterOptions struct {
+	GoMetrics      bool // Go runtime metrics, go_*
+	ProcessMetrics bool // CPU, memory and file descriptors, process_*
+}
+
+// selfMetrics let the exporter itself be monitored: a broken metrics file or
+// a stuck reload otherwise looks like healthy, unchanging backup metrics
+type selfMetrics struct {
+	loadFailures   prometheus.Counter
+	reloadDuration prometheus.Gauge
+	lastReload     prometheus.Gauge
+	storageAge     *storageAgeCollector
+}
+
+func newSelfMetrics(storage *MetricsStorage) selfMetrics {
+	return selfMetrics{
+		loadFailures: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Name: "tenangdb_exporter_load_failures_total",
+				Help: "Number of times the metrics file could not be read or parsed",
+			},
+		),
+		reloadDuration: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_exporter_reload_duration_seconds",
+				Help: "Duration of the last reload of the metrics file",
+			},
+		),
+		lastReload: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "tenangdb_exporter_last_reload_timestamp",
+				Help: "Timestamp of the last successful reload of the metrics file",
+			},
+		),
+		storageAge: &storageAgeCollector{
+			desc: prometheus.NewDesc(
+				"tenangdb_exporter_storage_age_seconds",
+				"Time since the metrics file was last written, absent when it does not exist",
+				nil, nil,
+			),
+			storage: storage,
+		},
+	}
+}
+
+// storageAgeCollector reports the age of the metrics file on every scrape
+type storageAgeCollector struct {
+	desc    *prometheus.Desc
+	storage *MetricsStorage
+}
+
+func (c *storageAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *storageAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	if c.storage == nil {
+		return
+	}
+	info, err := os.Stat(c.storage.filePath)
+	if err != nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, time.Since(info.ModTime()).Seconds())
+}
@@ -0,0 +1,182 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/pause"
+	"github.com/abdullahainun/tenangdb/internal/schedule"
+	"github.com/abdullahainun/tenangdb/internal/sla"
+)
+
+// Overall states of a Status, in the order of Nagios exit codes
+const (
+	StatusOK       = "ok"
+	StatusWarning  = "warning"
+	StatusCritical = "critical"
+)
+
+// Status is the state of the backups served as JSON at /status, for
+// monitoring checks and dashboards that don't scrape Prometheus
+type Status struct {
+	Status      string           `json:"status"`             // ok, warning or critical
+	Problems    []string         `json:"problems,omitempty"` // why the status is not ok
+	Service     string           `json:"service"`
+	Version     string           `json:"version"`
+	GeneratedAt time.Time        `json:"generated_at"`
+	Run         RunStatus        `json:"run"`
+	Databases   []DatabaseStatus `json:"databases"`
+}
+
+// RunStatus describes the latest backup run
+type RunStatus struct {
+	ID           string     `json:"id,omitempty"`
+	StartedAt    *time.Time `json:"started_at,omitempty"`
+	Active       bool       `json:"active"`
+	Healthy      bool       `json:"healthy"`                  // the last run finished without failures
+	ExpectedAt   *time.Time `json:"expected_at,omitempty"`    // latest slot of backup.schedule, with the watchdog
+	Missed       bool       `json:"missed"`                   // no run started for that slot within the grace
+	MissedSlotAt *time.Time `json:"missed_slot_at,omitempty"` // the missed slot
+	PausedUntil  *time.Time `json:"paused_until,omitempty"`   // end of the maintenance pause in effect
+}
+
+// DatabaseStatus describes the backups of a database
+type DatabaseStatus struct {
+	Database       string     `json:"database"`
+	LastBackupAt   *time.Time `json:"last_backup_at,omitempty"`
+	BackupStatus   string     `json:"backup_status,omitempty"` // success or failed
+	SizeBytes      int64      `json:"size_bytes,omitempty"`
+	LastUploadAt   *time.Time `json:"last_upload_at,omitempty"`
+	UploadStatus   string     `json:"upload_status,omitempty"`
+	SLAMaxAgeSecs  float64    `json:"sla_max_age_seconds,omitempty"`
+	LastVerifiedAt *time.Time `json:"last_verified_backup_at,omitempty"`
+	SLABreached    bool       `json:"sla_breached"`
+	DrillStatus    string     `json:"drill_status,omitempty"`
+}
+
+// BuildStatus summarises data and, with a config, the backup freshness
+// SLAs, the maintenance pause and the scheduled run watchdog. entries are
+// the local backups as returned by catalog.Scan. A breached SLA or missed
+// run is critical, a failed backup, upload or drill or a pause lasting
+// longer than backup.pause.alert_after a warning.
+func BuildStatus(data *MetricsData, cfg *config.Config, entries []catalog.Entry, now time.Time) *Status {
+	status := &Status{
+		Status:      StatusOK,
+		Service:     "tenangdb-exporter",
+		Version:     getCurrentVersion(),
+		GeneratedAt: now,
+		Run: RunStatus{
+			ID:      data.System.LastRunID,
+			Active:  data.System.BackupProcessActive,
+			Healthy: data.System.SystemHealthy,
+		},
+	}
+	warn := func(format string, args ...interface{}) {
+		status.Problems = append(status.Problems, fmt.Sprintf(format, args...))
+		if status.Status == StatusOK {
+			status.Status = StatusWarning
+		}
+	}
+	crit := func(format string, args ...interface{}) {
+		status.Problems = append(status.Problems, fmt.Sprintf(format, args...))
+		status.Status = StatusCritical
+	}
+
+	if !data.System.LastRunStarted.IsZero() {
+		status.Run.StartedAt = timePtr(data.System.LastRunStarted)
+	}
+
+	databases := make(map[string]*DatabaseStatus)
+	get := func(dbName string) *DatabaseStatus {
+		if databases[dbName] == nil {
+			databases[dbName] = &DatabaseStatus{Database: dbName}
+		}
+		return databases[dbName]
+	}
+
+	for dbName, backup := range data.Backups {
+		db := get(dbName)
+		db.BackupStatus = backup.Status
+		db.SizeBytes = backup.SizeBytes
+		if !backup.LastBackup.IsZero() {
+			db.LastBackupAt = timePtr(backup.LastBackup)
+		}
+	}
+	for dbName, upload := range data.Uploads {
+		db := get(dbName)
+		db.UploadStatus = upload.Status
+		if !upload.LastUpload.IsZero() {
+			db.LastUploadAt = timePtr(upload.LastUpload)
+		}
+	}
+	for dbName, drill := range data.Drills {
+		get(dbName).DrillStatus = drill.Status
+	}
+
+	if cfg != nil {
+		for _, s := range sla.Evaluate(cfg, entries, now) {
+			db := get(s.Database)
+			db.SLAMaxAgeSecs = s.MaxAge.Seconds()
+			db.SLABreached = s.Breached
+			if s.LastBackup != nil {
+				db.LastVerifiedAt = timePtr(s.LastBackup.Manifest.CreatedAt)
+			}
+		}
+
+		paused, err := pause.Load(cfg.StateDirectory)
+		if err != nil {
+			warn("%v", err)
+		}
+		if paused.Active(now) {
+			status.Run.PausedUntil = timePtr(paused.Until)
+			if elapsed := paused.Elapsed(now); elapsed > cfg.Backup.Pause.AlertAfter {
+				warn("backups have been paused for %s, run tenangdb resume if the maintenance is over", elapsed.Round(time.Minute))
+			}
+		}
+
+		if cfg.Backup.Watchdog.Enabled {
+			if s, err := schedule.Parse(cfg.Backup.Schedule); err == nil {
+				if expected := s.Prev(now); !expected.IsZero() {
+					status.Run.ExpectedAt = timePtr(expected)
+				}
+				if slot := missedRun(s, data.System, paused, cfg.Backup.Watchdog.Grace, now); !slot.IsZero() {
+					status.Run.Missed = true
+					status.Run.MissedSlotAt = timePtr(slot)
+					crit("scheduled backup run at %s did not start", slot.Format(time.RFC3339))
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(databases))
+	for dbName := range databases {
+		names = append(names, dbName)
+	}
+	sort.Strings(names)
+
+	status.Databases = make([]DatabaseStatus, 0, len(names))
+	for _, dbName := range names {
+		db := databases[dbName]
+		if db.SLABreached {
+			crit("%s: backup SLA breached", dbName)
+		}
+		if db.BackupStatus == "failed" {
+			warn("%s: last backup failed", dbName)
+		}
+		if db.UploadStatus == "failed" {
+			warn("%s: last upload failed", dbName)
+		}
+		if db.DrillStatus == "failed" {
+			warn("%s: last restore drill failed", dbName)
+		}
+		status.Databases = append(status.Databases, *db)
+	}
+	return status
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBuildStatus(t *testing.T) {
+	now := time.Date(2024, time.June, 3, 3, 0, 0, 0, time.UTC)
+	data := &MetricsData{
+		Backups: map[string]BackupMetrics{
+			"orders": {Database: "orders", Status: "success", LastBackup: now.Add(-time.Hour)},
+			"users":  {Database: "users", Status: "success", LastBackup: now.Add(-time.Hour)},
+		},
+		Uploads: map[string]UploadMetrics{},
+	}
+
+	status := BuildStatus(data, nil, nil, now)
+	if status.Status != StatusOK || len(status.Problems) != 0 {
+		t.Fatalf("BuildStatus() = %s %v, want ok", status.Status, status.Problems)
+	}
+	if len(status.Databases) != 2 || status.Databases[0].Database != "orders" {
+		t.Fatalf("BuildStatus() databases = %+v, want orders and users", status.Databases)
+	}
+
+	data.Uploads["users"] = UploadMetrics{Database: "users", Status: "failed"}
+	status = BuildStatus(data, nil, nil, now)
+	if status.Status != StatusWarning || len(status.Problems) != 1 {
+		t.Errorf("BuildStatus() with a failed upload = %s %v, want one warning", status.Status, status.Problems)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/sla"
 )
 
 // MetricsStorage handles persistent storage of metrics data
@@ -25,6 +27,11 @@ type BackupMetrics struct {
 	Status          string    `json:"status"`
 	SuccessCount    int64     `json:"success_count"`
 	FailureCount    int64     `json:"failure_count"`
+	RunID           string    `json:"run_id,omitempty"` // run that produced the last backup
+
+	ConsecutiveFailures int64 `json:"consecutive_failures,omitempty"` // failures since the last success
+
+	Phases map[string]PhaseMetrics `json:"phases,omitempty"` // of the last successful backup
 }
 
 // UploadMetrics represents metrics for upload operations
@@ -48,6 +55,43 @@ type RestoreMetrics struct {
 	FailureCount    int64     `json:"failure_count"`
 }
 
+// StandbyMetrics represents the last refresh of a database on a standby
+type StandbyMetrics struct {
+	Standby         string    `json:"standby"`
+	Database        string    `json:"database"`
+	LastRefresh     time.Time `json:"last_refresh"`
+	BackupCreatedAt time.Time `json:"backup_created_at"` // age of the data on the standby
+	Status          string    `json:"status"`
+	SuccessCount    int64     `json:"success_count"`
+	FailureCount    int64     `json:"failure_count"`
+}
+
+// DrillMetrics represents the last restore drill of a database
+type DrillMetrics struct {
+	Database     string    `json:"database"`
+	Backup       string    `json:"backup"`
+	LastDrill    time.Time `json:"last_drill"`
+	LastSuccess  time.Time `json:"last_success,omitempty"`
+	RTOSeconds   float64   `json:"rto_seconds"` // time to restore in the last successful drill
+	Status       string    `json:"status"`
+	SuccessCount int64     `json:"success_count"`
+	FailureCount int64     `json:"failure_count"`
+}
+
+// BinlogMetrics represents the binary log archive of a server, kept by
+// tenangdb backup --incremental
+type BinlogMetrics struct {
+	Server        string    `json:"server"`
+	LastArchive   time.Time `json:"last_archive"`
+	LastSuccess   time.Time `json:"last_success,omitempty"`
+	File          string    `json:"file,omitempty"`     // newest archived binary log
+	Position      uint64    `json:"position,omitempty"` // end of that log, where point-in-time recovery can reach
+	ArchivedFiles int64     `json:"archived_files"`
+	Status        string    `json:"status"`
+	SuccessCount  int64     `json:"success_count"`
+	FailureCount  int64     `json:"failure_count"`
+}
+
 // CleanupMetrics represents metrics for cleanup operations
 type CleanupMetrics struct {
 	LastCleanup     time.Time `json:"last_cleanup"`
@@ -59,12 +103,42 @@ type CleanupMetrics struct {
 	FailureCount    int64     `json:"failure_count"`
 }
 
+// FailureRecord is one failed operation, kept for reports
+type FailureRecord struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"` // backup, upload, drill or hook
+	Database  string    `json:"database"`
+	Error     string    `json:"error"`
+}
+
+// Failure history limits; older records are dropped when a new one is added
+const (
+	failureHistoryAge  = 35 * 24 * time.Hour
+	failureHistorySize = 500
+)
+
+// RestoreRecord is one successful restore, kept to measure achieved RTO
+type RestoreRecord struct {
+	Time            time.Time `json:"time"`
+	Source          string    `json:"source"` // restore or drill
+	Database        string    `json:"database"`
+	DurationSeconds float64   `json:"duration_seconds"`
+}
+
+// Restore history limits, long enough for a quarterly SLA report
+const (
+	restoreHistoryAge  = 95 * 24 * time.Hour
+	restoreHistorySize = 1000
+)
+
 // SystemMetrics represents system-level metrics
 type SystemMetrics struct {
 	TotalDatabases      int       `json:"total_databases"`
 	LastBackupProcess   time.Time `json:"last_backup_process"`
 	BackupProcessActive bool      `json:"backup_process_active"`
 	SystemHealthy       bool      `json:"system_healthy"`
+	LastRunID           string    `json:"last_run_id,omitempty"`
+	LastRunStarted      time.Time `json:"last_run_started,omitempty"`
 }
 
 // MetricsData represents the complete metrics data structure
@@ -74,6 +148,11 @@ type MetricsData struct {
 	Uploads  map[string]UploadMetrics  `json:"uploads"`
 	Restores map[string]RestoreMetrics `json:"restores"`
 	Cleanup  CleanupMetrics            `json:"cleanup"`
+	Standbys map[string]StandbyMetrics `json:"standbys,omitempty"` // keyed by standby/database
+	Drills   map[string]DrillMetrics   `json:"drills,omitempty"`
+	Binlogs  map[string]BinlogMetrics  `json:"binlogs,omitempty"` // keyed by server
+	Failures []FailureRecord           `json:"failures,omitempty"` // oldest first
+	Restored []RestoreRecord           `json:"restored,omitempty"` // oldest first
 }
 
 // NewMetricsStorage creates a new metrics storage instance
@@ -104,6 +183,7 @@ func (s *MetricsStorage) LoadMetrics() (*MetricsData, error) {
 		Uploads:  make(map[string]UploadMetrics),
 		Restores: make(map[string]RestoreMetrics),
 		Cleanup:  CleanupMetrics{},
+		Standbys: make(map[string]StandbyMetrics),
 	}
 	
 	// Check if file exists
@@ -169,13 +249,16 @@ func (s *MetricsStorage) UpdateBackupMetrics(database string, duration time.Dura
 	backup.LastBackup = time.Now()
 	backup.DurationSeconds = duration.Seconds()
 	backup.SizeBytes = sizeBytes
+	backup.RunID = data.System.LastRunID
 	
 	if success {
 		backup.Status = "success"
 		backup.SuccessCount++
+		backup.ConsecutiveFailures = 0
 	} else {
 		backup.Status = "failed"
 		backup.FailureCount++
+		backup.ConsecutiveFailures++
 	}
 	
 	data.Backups[database] = backup
@@ -217,6 +300,19 @@ func (s *MetricsStorage) UpdateUploadMetrics(database string, duration time.Dura
 	return s.SaveMetrics(data)
 }
 
+// SetRunID records the ID of the backup run that is starting
+func (s *MetricsStorage) SetRunID(runID string) error {
+	data, err := s.LoadMetrics()
+	if err != nil {
+		return err
+	}
+
+	data.System.LastRunID = runID
+	data.System.LastRunStarted = time.Now()
+
+	return s.SaveMetrics(data)
+}
+
 // SetBackupProcessActive sets the backup process status
 func (s *MetricsStorage) SetBackupProcessActive(active bool) error {
 	data, err := s.LoadMetrics()
@@ -254,6 +350,7 @@ func (s *MetricsStorage) UpdateRestoreMetrics(database string, duration time.Dur
 	if success {
 		restore.Status = "success"
 		restore.SuccessCount++
+		recordRestore(data, "restore", database, duration, restore.LastRestore)
 	} else {
 		restore.Status = "failed"
 		restore.FailureCount++
@@ -264,6 +361,171 @@ func (s *MetricsStorage) UpdateRestoreMetrics(database string, duration time.Dur
 	return s.SaveMetrics(data)
 }
 
+// UpdateDrillMetrics records a restore drill of database from backup,
+// which took rto to restore if it succeeded
+func (s *MetricsStorage) UpdateDrillMetrics(database, backup string, rto time.Duration, success bool) error {
+	data, err := s.LoadMetrics()
+	if err != nil {
+		return err
+	}
+	if data.Drills == nil {
+		data.Drills = make(map[string]DrillMetrics)
+	}
+
+	drill, exists := data.Drills[database]
+	if !exists {
+		drill = DrillMetrics{Database: database}
+	}
+
+	drill.Backup = backup
+	drill.LastDrill = time.Now()
+	if success {
+		drill.Status = "success"
+		drill.LastSuccess = drill.LastDrill
+		drill.RTOSeconds = rto.Seconds()
+		drill.SuccessCount++
+		recordRestore(data, "drill", database, rto, drill.LastDrill)
+	} else {
+		drill.Status = "failed"
+		drill.FailureCount++
+	}
+
+	data.Drills[database] = drill
+
+	return s.SaveMetrics(data)
+}
+
+// UpdateBinlogMetrics records an incremental run of server that archived
+// files binary logs, up to position in file if it succeeded
+func (s *MetricsStorage) UpdateBinlogMetrics(server, file string, position uint64, files int, success bool) error {
+	data, err := s.LoadMetrics()
+	if err != nil {
+		return err
+	}
+	if data.Binlogs == nil {
+		data.Binlogs = make(map[string]BinlogMetrics)
+	}
+
+	binlog, exists := data.Binlogs[server]
+	if !exists {
+		binlog = BinlogMetrics{Server: server}
+	}
+
+	binlog.LastArchive = time.Now()
+	binlog.ArchivedFiles += int64(files)
+	if file != "" {
+		binlog.File = file
+		binlog.Position = position
+	}
+	if success {
+		binlog.Status = "success"
+		binlog.LastSuccess = binlog.LastArchive
+		binlog.SuccessCount++
+	} else {
+		binlog.Status = "failed"
+		binlog.FailureCount++
+	}
+
+	data.Binlogs[server] = binlog
+
+	return s.SaveMetrics(data)
+}
+
+// recordRestore adds a successful restore to the restore history
+func recordRestore(data *MetricsData, source, database string, duration time.Duration, now time.Time) {
+	data.Restored = append(data.Restored, RestoreRecord{
+		Time:            now,
+		Source:          source,
+		Database:        database,
+		DurationSeconds: duration.Seconds(),
+	})
+
+	keep := 0
+	for keep < len(data.Restored) && now.Sub(data.Restored[keep].Time) > restoreHistoryAge {
+		keep++
+	}
+	if len(data.Restored)-keep > restoreHistorySize {
+		keep = len(data.Restored) - restoreHistorySize
+	}
+	data.Restored = data.Restored[keep:]
+}
+
+// RestoreSamples returns the restore history for measuring achieved RTO
+func (d *MetricsData) RestoreSamples() []sla.Restore {
+	samples := make([]sla.Restore, 0, len(d.Restored))
+	for _, record := range d.Restored {
+		samples = append(samples, sla.Restore{
+			Database: record.Database,
+			Source:   record.Source,
+			Time:     record.Time,
+			Duration: time.Duration(record.DurationSeconds * float64(time.Second)),
+		})
+	}
+	return samples
+}
+
+// UpdateStandbyMetrics records a refresh of database on standby with a
+// backup taken at backupCreatedAt
+func (s *MetricsStorage) UpdateStandbyMetrics(standby, database string, backupCreatedAt time.Time, success bool) error {
+	data, err := s.LoadMetrics()
+	if err != nil {
+		return err
+	}
+	if data.Standbys == nil {
+		data.Standbys = make(map[string]StandbyMetrics)
+	}
+
+	key := standby + "/" + database
+	refresh, exists := data.Standbys[key]
+	if !exists {
+		refresh = StandbyMetrics{
+			Standby:  standby,
+			Database: database,
+		}
+	}
+
+	refresh.LastRefresh = time.Now()
+	if success {
+		refresh.Status = "success"
+		refresh.BackupCreatedAt = backupCreatedAt
+		refresh.SuccessCount++
+	} else {
+		refresh.Status = "failed"
+		refresh.FailureCount++
+	}
+
+	data.Standbys[key] = refresh
+
+	return s.SaveMetrics(data)
+}
+
+// RecordFailure adds a failed operation to the failure history
+func (s *MetricsStorage) RecordFailure(operation, database string, failure error) error {
+	data, err := s.LoadMetrics()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	data.Failures = append(data.Failures, FailureRecord{
+		Time:      now,
+		Operation: operation,
+		Database:  database,
+		Error:     failure.Error(),
+	})
+
+	keep := 0
+	for keep < len(data.Failures) && now.Sub(data.Failures[keep].Time) > failureHistoryAge {
+		keep++
+	}
+	if len(data.Failures)-keep > failureHistorySize {
+		keep = len(data.Failures) - failureHistorySize
+	}
+	data.Failures = data.Failures[keep:]
+
+	return s.SaveMetrics(data)
+}
+
 // UpdateCleanupMetrics updates cleanup metrics
 func (s *MetricsStorage) UpdateCleanupMetrics(duration time.Duration, success bool, filesRemoved int64, bytesFreed int64) error {
 	data, err := s.LoadMetrics()
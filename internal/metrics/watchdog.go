@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/pause"
+	"github.com/abdullahainun/tenangdb/internal/schedule"
+)
+
+// missedRun returns the latest backup slot of s that passed more than grace
+// ago without a run starting after it, or the zero time. Nothing counts as
+// missed before the first recorded run, while a run is still active, or
+// within a maintenance pause.
+func missedRun(s *schedule.Schedule, system SystemMetrics, paused *pause.Marker, grace time.Duration, now time.Time) time.Time {
+	if system.LastRunStarted.IsZero() || system.BackupProcessActive {
+		return time.Time{}
+	}
+
+	slot := s.Prev(now.Add(-grace))
+	if slot.IsZero() || !system.LastRunStarted.Before(slot) || paused.Covers(slot) {
+		return time.Time{}
+	}
+	return slot
+}
+
+// updateWatchdogMetrics flags a scheduled backup run that did not start,
+// logging it once per missed slot
+func (e *ExporterMetrics) updateWatchdogMetrics(data *MetricsData, paused *pause.Marker) {
+	if e.config == nil || !e.config.Backup.Watchdog.Enabled {
+		return
+	}
+	s, err := schedule.Parse(e.config.Backup.Schedule)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	if expected := s.Prev(now); !expected.IsZero() {
+		e.expectedRun.Set(float64(expected.Unix()))
+	}
+
+	slot := missedRun(s, data.System, paused, e.config.Backup.Watchdog.Grace, now)
+	if slot.IsZero() {
+		e.runMissed.Set(0)
+		return
+	}
+
+	e.runMissed.Set(1)
+	if !slot.Equal(e.missedSlot) && e.log != nil {
+		e.log.WithField("scheduled", slot.Format(time.RFC3339)).
+			WithField("last_run_started", data.System.LastRunStarted.Format(time.RFC3339)).
+			Error("⏰ Scheduled backup run did not start")
+	}
+	e.missedSlot = slot
+}
+
+// updatePauseMetrics exposes the maintenance pause and returns it, logging
+// once when it lasted longer than backup.pause.alert_after
+func (e *ExporterMetrics) updatePauseMetrics() *pause.Marker {
+	if e.config == nil {
+		return nil
+	}
+	marker, err := pause.Load(e.config.StateDirectory)
+	if err != nil {
+		if e.log != nil {
+			e.log.WithError(err).Warn("Failed to read pause marker")
+		}
+		return nil
+	}
+
+	now := time.Now()
+	if !marker.Active(now) {
+		e.backupPaused.Set(0)
+		e.pauseUntil.Set(0)
+		e.pausedSeconds.Set(0)
+		return marker
+	}
+
+	elapsed := marker.Elapsed(now)
+	e.backupPaused.Set(1)
+	e.pauseUntil.Set(float64(marker.Until.Unix()))
+	e.pausedSeconds.Set(elapsed.Seconds())
+	if elapsed > e.config.Backup.Pause.AlertAfter && !marker.PausedAt.Equal(e.overduePause) && e.log != nil {
+		e.log.WithField("paused_at", marker.PausedAt.Format(time.RFC3339)).
+			WithField("until", marker.Until.Format(time.RFC3339)).
+			Error("⏸️ Backups have been paused for longer than backup.pause.alert_after, run tenangdb resume if the maintenance is over")
+		e.overduePause = marker.PausedAt
+	}
+	return marker
+}
@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/pause"
+	"github.com/abdullahainun/tenangdb/internal/schedule"
+)
+
+func TestMissedRun(t *testing.T) {
+	s, err := schedule.Parse("0 0 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, time.June, 3, 3, 0, 0, 0, time.UTC)
+	grace := 2 * time.Hour
+	june3 := time.Date(2024, time.June, 3, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		system SystemMetrics
+		want   time.Time
+	}{
+		{"never ran", SystemMetrics{}, time.Time{}},
+		{"ran on time", SystemMetrics{LastRunStarted: june3.Add(4 * time.Minute)}, time.Time{}},
+		{"missed", SystemMetrics{LastRunStarted: june3.AddDate(0, 0, -1)}, june3},
+		{"still running", SystemMetrics{LastRunStarted: june3.AddDate(0, 0, -1), BackupProcessActive: true}, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := missedRun(s, tt.system, nil, grace, now); !got.Equal(tt.want) {
+			t.Errorf("%s: missedRun() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+
+	// Within the grace period the run is not missed yet
+	if got := missedRun(s, SystemMetrics{LastRunStarted: june3.AddDate(0, 0, -1)}, nil, grace, june3.Add(time.Hour)); !got.IsZero() {
+		t.Errorf("missedRun() within grace = %s, want zero", got)
+	}
+
+	// Slots within a maintenance pause are not missed
+	paused := &pause.Marker{PausedAt: june3.Add(-time.Hour), Until: june3.Add(time.Hour)}
+	if got := missedRun(s, SystemMetrics{LastRunStarted: june3.AddDate(0, 0, -1)}, paused, grace, now); !got.IsZero() {
+		t.Errorf("missedRun() within a pause = %s, want zero", got)
+	}
+}
@@ -0,0 +1,92 @@
+// Package pause keeps the maintenance pause marker. While a pause is in
+// effect, scheduled backups are skipped instead of running into a
+// maintenance window.
+package pause
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// fileName is the marker file in the state directory
+const fileName = "pause.json"
+
+// Marker pauses backups until a point in time
+type Marker struct {
+	PausedAt time.Time `json:"paused_at"`
+	Until    time.Time `json:"until"`
+	Reason   string    `json:"reason,omitempty"`
+}
+
+// Path returns the marker file of stateDir
+func Path(stateDir string) string {
+	return filepath.Join(stateDir, fileName)
+}
+
+// Load reads the marker of stateDir, nil when backups are not paused
+func Load(stateDir string) (*Marker, error) {
+	data, err := os.ReadFile(Path(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pause marker: %w", err)
+	}
+
+	var marker Marker
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return nil, fmt.Errorf("failed to parse pause marker %s: %w", Path(stateDir), err)
+	}
+	return &marker, nil
+}
+
+// Save writes marker to stateDir, replacing an earlier pause
+func Save(stateDir string, marker *Marker) error {
+	if err := os.MkdirAll(stateDir, 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	data, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(Path(stateDir), data, 0644); err != nil {
+		return fmt.Errorf("failed to write pause marker: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes the marker of stateDir and reports whether there was one
+func Remove(stateDir string) (bool, error) {
+	err := os.Remove(Path(stateDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to remove pause marker: %w", err)
+	}
+	return true, nil
+}
+
+// Active reports whether the pause is in effect at now. An expired marker
+// is left in place until resume, it no longer stops anything.
+func (m *Marker) Active(now time.Time) bool {
+	return m != nil && now.Before(m.Until)
+}
+
+// Covers reports whether t falls within the pause
+func (m *Marker) Covers(t time.Time) bool {
+	return m != nil && !t.Before(m.PausedAt) && t.Before(m.Until)
+}
+
+// Elapsed returns how long the pause has been in effect at now, 0 once it
+// expired
+func (m *Marker) Elapsed(now time.Time) time.Duration {
+	if !m.Active(now) {
+		return 0
+	}
+	return now.Sub(m.PausedAt)
+}
@@ -0,0 +1,63 @@
+package pause
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarker(t *testing.T) {
+	pausedAt := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	m := &Marker{PausedAt: pausedAt, Until: pausedAt.Add(6 * time.Hour)}
+
+	if !m.Active(pausedAt.Add(time.Hour)) {
+		t.Error("Active() within the pause = false")
+	}
+	if m.Active(m.Until) {
+		t.Error("Active() at Until = true")
+	}
+	if got := m.Elapsed(pausedAt.Add(2 * time.Hour)); got != 2*time.Hour {
+		t.Errorf("Elapsed() = %s, want 2h", got)
+	}
+	if got := m.Elapsed(m.Until.Add(time.Hour)); got != 0 {
+		t.Errorf("Elapsed() after expiry = %s, want 0", got)
+	}
+	if m.Covers(pausedAt.Add(-time.Minute)) || !m.Covers(pausedAt) || m.Covers(m.Until) {
+		t.Error("Covers() does not match [PausedAt, Until)")
+	}
+
+	var none *Marker
+	if none.Active(pausedAt) || none.Covers(pausedAt) {
+		t.Error("nil marker is active")
+	}
+}
+
+func TestSaveLoadRemove(t *testing.T) {
+	dir := t.TempDir()
+
+	if m, err := Load(dir); m != nil || err != nil {
+		t.Fatalf("Load() without marker = %v, %v", m, err)
+	}
+
+	want := &Marker{
+		PausedAt: time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2025, 9, 1, 6, 0, 0, 0, time.UTC),
+		Reason:   "storage migration",
+	}
+	if err := Save(dir, want); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Until.Equal(want.Until) || !got.PausedAt.Equal(want.PausedAt) || got.Reason != want.Reason {
+		t.Errorf("Load() = %+v, want %+v", got, want)
+	}
+
+	if removed, err := Remove(dir); !removed || err != nil {
+		t.Errorf("Remove() = %v, %v", removed, err)
+	}
+	if removed, err := Remove(dir); removed || err != nil {
+		t.Errorf("Remove() twice = %v, %v", removed, err)
+	}
+}
@@ -0,0 +1,57 @@
+package plan
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// Action types
+const (
+	Create = "create"
+	Upload = "upload"
+	Delete = "delete"
+	Trash  = "trash" // moved to the trash directory instead of deleted
+)
+
+// Plan is the machine-readable form of a --dry-run, listing everything a
+// command would do so it can be reviewed before running it for real
+type Plan struct {
+	Command        string    `json:"command"`
+	GeneratedAt    time.Time `json:"generated_at"`
+	Actions        []Action  `json:"actions"`
+	EstimatedBytes int64     `json:"estimated_bytes"`
+}
+
+// Action is a single file operation of a plan
+type Action struct {
+	Type           string   `json:"type"`
+	Database       string   `json:"database,omitempty"`
+	Path           string   `json:"path"`
+	Destination    string   `json:"destination,omitempty"`
+	Command        []string `json:"command,omitempty"` // secrets redacted
+	EstimatedBytes int64    `json:"estimated_bytes,omitempty"`
+	Reason         string   `json:"reason,omitempty"`
+}
+
+// New starts an empty plan for command
+func New(command string) *Plan {
+	return &Plan{
+		Command:     command,
+		GeneratedAt: time.Now(),
+		Actions:     []Action{},
+	}
+}
+
+// Add appends an action and counts its size towards the plan total
+func (p *Plan) Add(action Action) {
+	p.Actions = append(p.Actions, action)
+	p.EstimatedBytes += action.EstimatedBytes
+}
+
+// Write prints the plan as indented JSON
+func (p *Plan) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
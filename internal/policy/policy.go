@@ -0,0 +1,44 @@
+package policy
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// CheckRestore rejects restores into hosts or databases listed in
+// policy.deny_restore_to. Patterns are globs matched against the database
+// host, or against "host/database" when they contain a slash.
+func CheckRestore(p *config.PolicyConfig, host, database string) error {
+	for _, pattern := range p.DenyRestoreTo {
+		subject := host
+		if strings.Contains(pattern, "/") {
+			subject = host + "/" + database
+		}
+
+		if matched, _ := filepath.Match(pattern, subject); matched {
+			return fmt.Errorf("policy denies restoring to %s (deny_restore_to: %q)", subject, pattern)
+		}
+	}
+	return nil
+}
+
+// CheckCleanup enforces policy.require_verification_before_cleanup: old
+// backups may only be deleted after they are found in cloud storage
+func CheckCleanup(p *config.PolicyConfig, cleanup *config.CleanupConfig, upload *config.UploadConfig, allowUnverified bool) error {
+	if !p.RequireVerificationBeforeCleanup {
+		return nil
+	}
+
+	switch {
+	case allowUnverified:
+		return fmt.Errorf("policy requires cloud verification before cleanup; --allow-unverified is not permitted")
+	case !cleanup.VerifyCloudExists:
+		return fmt.Errorf("policy requires cloud verification before cleanup; set cleanup.verify_cloud_exists: true")
+	case !upload.Enabled:
+		return fmt.Errorf("policy requires cloud verification before cleanup, but upload is disabled")
+	}
+	return nil
+}
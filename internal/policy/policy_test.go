@@ -0,0 +1,29 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestCheckRestore(t *testing.T) {
+	p := &config.PolicyConfig{DenyRestoreTo: []string{"prod-db-*", "staging.internal/billing"}}
+
+	tests := []struct {
+		host, database string
+		denied         bool
+	}{
+		{"prod-db-01", "app", true},
+		{"prod-replica", "app", false},
+		{"staging.internal", "billing", true},
+		{"staging.internal", "billing_copy", false},
+		{"localhost", "billing", false},
+	}
+
+	for _, tt := range tests {
+		err := CheckRestore(p, tt.host, tt.database)
+		if denied := err != nil; denied != tt.denied {
+			t.Errorf("CheckRestore(%q, %q) denied = %v, want %v", tt.host, tt.database, denied, tt.denied)
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Phase is a step of a database backup shown in its progress bar
+type Phase string
+
+const (
+	PhaseDump     Phase = "dump"
+	PhaseCompress Phase = "compress"
+	PhaseUpload   Phase = "upload"
+)
+
+const (
+	barWidth        = 24
+	refreshInterval = 500 * time.Millisecond
+)
+
+// IsTerminal reports whether f is an interactive terminal rather than a
+// pipe or file
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+type row struct {
+	database string
+	phase    int
+	started  time.Time
+}
+
+// Display draws a bar for every running database below the log output, plus
+// an overall bar for the run. Log lines written through it scroll above the
+// bars. A nil *Display ignores all calls, so callers need no TTY checks.
+type Display struct {
+	mu       sync.Mutex
+	out      io.Writer
+	phases   []Phase
+	total    int
+	finished int
+	failed   int
+	rows     []*row
+	drawn    int
+	started  time.Time
+	eta      time.Time
+	stop     chan struct{}
+	closed   bool
+}
+
+// New starts a display for total databases going through phases
+func New(out io.Writer, total int, phases []Phase) *Display {
+	d := &Display{
+		out:     out,
+		phases:  phases,
+		total:   total,
+		started: time.Now(),
+		stop:    make(chan struct{}),
+	}
+
+	// Keep elapsed times moving while external tools run
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.mu.Lock()
+				d.redraw()
+				d.mu.Unlock()
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+
+	return d
+}
+
+// Start adds a bar for database in its first phase
+func (d *Display) Start(database string) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.rows = append(d.rows, &row{database: database, started: time.Now()})
+	d.redraw()
+}
+
+// Phase moves the bar of database to phase
+func (d *Display) Phase(database string, phase Phase) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for _, r := range d.rows {
+		if r.database != database {
+			continue
+		}
+		for i, p := range d.phases {
+			if p == phase {
+				r.phase = i
+			}
+		}
+	}
+	d.redraw()
+}
+
+// Finish removes the bar of database and counts it towards the run
+func (d *Display) Finish(database string, ok bool) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, r := range d.rows {
+		if r.database == database {
+			d.rows = append(d.rows[:i], d.rows[i+1:]...)
+			break
+		}
+	}
+	d.finished++
+	if !ok {
+		d.failed++
+	}
+	d.redraw()
+}
+
+// SetETA shows the estimated completion time of the run
+func (d *Display) SetETA(eta time.Time) {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.eta = eta
+	d.redraw()
+}
+
+// Write prints log output above the bars
+func (d *Display) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return d.out.Write(p)
+	}
+
+	d.clear()
+	n, err := d.out.Write(p)
+	d.draw()
+	return n, err
+}
+
+// Close stops refreshing and removes the bars from the terminal
+func (d *Display) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return
+	}
+	d.closed = true
+	close(d.stop)
+	d.clear()
+}
+
+func (d *Display) redraw() {
+	if d.closed {
+		return
+	}
+	d.clear()
+	d.draw()
+}
+
+// clear moves the cursor up over the previous bars and erases them
+func (d *Display) clear() {
+	if d.drawn > 0 {
+		fmt.Fprintf(d.out, "\x1b[%dA\x1b[J", d.drawn)
+		d.drawn = 0
+	}
+}
+
+func (d *Display) draw() {
+	var b strings.Builder
+
+	nameWidth := 0
+	for _, r := range d.rows {
+		if len(r.database) > nameWidth {
+			nameWidth = len(r.database)
+		}
+	}
+
+	for _, r := range d.rows {
+		fmt.Fprintf(&b, "  %-*s %s %-8s %s\n",
+			nameWidth, r.database,
+			bar(r.phase, len(d.phases)),
+			d.phases[r.phase],
+			formatElapsed(time.Since(r.started)))
+	}
+
+	summary := fmt.Sprintf("%d/%d databases", d.finished, d.total)
+	if d.failed > 0 {
+		summary += fmt.Sprintf(", %d failed", d.failed)
+	}
+	if !d.eta.IsZero() {
+		summary += ", ETA " + d.eta.Format("15:04")
+	}
+	fmt.Fprintf(&b, "  Total %s %s, elapsed %s\n", bar(d.finished, d.total), summary, formatElapsed(time.Since(d.started)))
+
+	io.WriteString(d.out, b.String())
+	d.drawn = len(d.rows) + 1
+}
+
+// bar renders done out of total steps as [====>    ]
+func bar(done, total int) string {
+	if total <= 0 {
+		total = 1
+	}
+	filled := done * barWidth / total
+	if filled > barWidth {
+		filled = barWidth
+	}
+
+	head := ""
+	if filled < barWidth {
+		head = ">"
+	}
+	rest := barWidth - filled - len(head)
+	return "[" + strings.Repeat("=", filled) + head + strings.Repeat(" ", rest) + "]"
+}
+
+func formatElapsed(d time.Duration) string {
+	d = d.Round(time.Second)
+	return fmt.Sprintf("%02d:%02d", int(d.Minutes()), int(d.Seconds())%60)
+}
@@ -0,0 +1,220 @@
+package report
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+)
+
+// Output formats of a summary
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// DatabaseSummary is the activity of one database during the period
+type DatabaseSummary struct {
+	Database    string
+	Backups     int
+	Failures    int
+	SuccessRate float64 // percent, 0 without any attempt
+	LatestSize  int64   // size of the newest backup
+	Growth      int64   // LatestSize minus the size of the backup before the period
+}
+
+// Summary is an operations report over a period, assembled from the
+// backup catalog and the failure history in the metrics file
+type Summary struct {
+	From           time.Time
+	To             time.Time
+	Databases      []DatabaseSummary
+	Backups        int
+	BackupFailures int
+	UploadFailures int
+	SuccessRate    float64
+	StoredBytes    int64   // backups counted in the storage estimate
+	StorageCost    float64 // monthly, 0 when no price is configured
+	Currency       string
+	Failures       []metrics.FailureRecord // newest first
+}
+
+// Build summarises the period [from, to). entries must be sorted newest
+// first, as returned by catalog.Scan; data may be nil without metrics.
+func Build(cfg *config.Config, entries []catalog.Entry, data *metrics.MetricsData, from, to time.Time) *Summary {
+	s := &Summary{From: from, To: to, Currency: cfg.Report.Currency}
+	inPeriod := func(t time.Time) bool { return !t.Before(from) && t.Before(to) }
+
+	failures := make(map[string]int)
+	if data != nil {
+		for i := len(data.Failures) - 1; i >= 0; i-- {
+			failure := data.Failures[i]
+			if !inPeriod(failure.Time) {
+				continue
+			}
+			s.Failures = append(s.Failures, failure)
+			switch failure.Operation {
+			case "upload":
+				s.UploadFailures++
+			case "backup":
+				s.BackupFailures++
+				failures[failure.Database]++
+			}
+		}
+	}
+
+	for _, dbName := range cfg.Backup.Databases {
+		db := DatabaseSummary{Database: dbName, Failures: failures[dbName]}
+		var baseline int64 = -1
+		for _, entry := range entries {
+			if entry.Manifest.Database != dbName || !entry.Manifest.CreatedAt.Before(to) {
+				continue
+			}
+			if inPeriod(entry.Manifest.CreatedAt) {
+				if db.Backups == 0 {
+					db.LatestSize = entry.Manifest.SizeBytes
+				}
+				db.Backups++
+				baseline = entry.Manifest.SizeBytes
+				continue
+			}
+			// Newest backup before the period
+			if db.Backups > 0 {
+				baseline = entry.Manifest.SizeBytes
+			}
+			break
+		}
+		if baseline >= 0 {
+			db.Growth = db.LatestSize - baseline
+		}
+		db.SuccessRate = successRate(db.Backups, db.Failures)
+
+		s.Backups += db.Backups
+		s.Databases = append(s.Databases, db)
+	}
+	s.SuccessRate = successRate(s.Backups, s.BackupFailures)
+
+	for _, entry := range entries {
+		if cfg.Upload.Enabled && entry.Manifest.Destination == "" {
+			continue
+		}
+		s.StoredBytes += entry.Manifest.SizeBytes
+	}
+	s.StorageCost = float64(s.StoredBytes) / (1024 * 1024 * 1024) * cfg.Report.StorageCostPerGB
+
+	sort.Slice(s.Databases, func(i, j int) bool { return s.Databases[i].Database < s.Databases[j].Database })
+	return s
+}
+
+func successRate(succeeded, failed int) float64 {
+	if succeeded+failed == 0 {
+		return 0
+	}
+	return float64(succeeded) / float64(succeeded+failed) * 100
+}
+
+// Write renders the summary as Markdown or HTML
+func (s *Summary) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return markdownTemplate.Execute(w, s)
+	case FormatHTML:
+		return htmlTemplate.Execute(w, s)
+	default:
+		return fmt.Errorf("invalid format %q, must be markdown or html", format)
+	}
+}
+
+var templateFuncs = map[string]interface{}{
+	"cell":   markdownCell,
+	"size":   formatSize,
+	"growth": formatGrowth,
+	"date":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"time":   func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}
+
+var markdownTemplate = template.Must(template.New("markdown").Funcs(templateFuncs).Parse(`# TenangDB Backup Report
+
+{{date .From}} to {{date .To}}
+
+- **Backups:** {{.Backups}} succeeded, {{.BackupFailures}} failed ({{printf "%.1f" .SuccessRate}}% success)
+- **Upload failures:** {{.UploadFailures}}
+- **Stored:** {{size .StoredBytes}}{{if .StorageCost}} (about {{printf "%.2f" .StorageCost}} {{.Currency}}/month){{end}}
+
+## Databases
+
+| Database | Backups | Failures | Success | Latest size | Growth |
+|----------|---------|----------|---------|-------------|--------|
+{{range .Databases}}| {{.Database}} | {{.Backups}} | {{.Failures}} | {{printf "%.1f" .SuccessRate}}% | {{size .LatestSize}} | {{growth .Growth}} |
+{{end}}
+## Failures
+{{if .Failures}}
+| Time | Operation | Database | Error |
+|------|-----------|----------|-------|
+{{range .Failures}}| {{time .Time}} | {{.Operation}} | {{.Database}} | {{cell .Error}} |
+{{end}}{{else}}
+No failures.
+{{end}}`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Funcs(templateFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>TenangDB Backup Report</title></head>
+<body>
+<h1>TenangDB Backup Report</h1>
+<p>{{date .From}} to {{date .To}}</p>
+<ul>
+<li><strong>Backups:</strong> {{.Backups}} succeeded, {{.BackupFailures}} failed ({{printf "%.1f" .SuccessRate}}% success)</li>
+<li><strong>Upload failures:</strong> {{.UploadFailures}}</li>
+<li><strong>Stored:</strong> {{size .StoredBytes}}{{if .StorageCost}} (about {{printf "%.2f" .StorageCost}} {{.Currency}}/month){{end}}</li>
+</ul>
+<h2>Databases</h2>
+<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Database</th><th>Backups</th><th>Failures</th><th>Success</th><th>Latest size</th><th>Growth</th></tr>
+{{range .Databases}}<tr><td>{{.Database}}</td><td>{{.Backups}}</td><td>{{.Failures}}</td><td>{{printf "%.1f" .SuccessRate}}%</td><td>{{size .LatestSize}}</td><td>{{growth .Growth}}</td></tr>
+{{end}}</table>
+<h2>Failures</h2>
+{{if .Failures}}<table border="1" cellpadding="4" cellspacing="0">
+<tr><th>Time</th><th>Operation</th><th>Database</th><th>Error</th></tr>
+{{range .Failures}}<tr><td>{{time .Time}}</td><td>{{.Operation}}</td><td>{{.Database}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>{{else}}<p>No failures.</p>{{end}}
+</body>
+</html>
+`))
+
+func formatSize(size int64) string {
+	const (
+		KB = 1024
+		MB = KB * 1024
+		GB = MB * 1024
+	)
+
+	if size >= GB {
+		return fmt.Sprintf("%.1f GB", float64(size)/GB)
+	} else if size >= MB {
+		return fmt.Sprintf("%.1f MB", float64(size)/MB)
+	} else if size >= KB {
+		return fmt.Sprintf("%.1f KB", float64(size)/KB)
+	}
+
+	return fmt.Sprintf("%d bytes", size)
+}
+
+// markdownCell keeps multi-line tool output from breaking a table row
+func markdownCell(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return strings.ReplaceAll(text, "|", "\\|")
+}
+
+func formatGrowth(bytes int64) string {
+	if bytes < 0 {
+		return "-" + formatSize(-bytes)
+	}
+	return "+" + formatSize(bytes)
+}
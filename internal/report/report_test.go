@@ -0,0 +1,68 @@
+package report
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/metrics"
+)
+
+func TestBuild(t *testing.T) {
+	to := time.Date(2024, 6, 8, 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(0, 0, -7)
+
+	entry := func(db string, daysAgo int, size int64) catalog.Entry {
+		return catalog.Entry{Manifest: &manifest.Manifest{
+			Database:    db,
+			CreatedAt:   to.AddDate(0, 0, -daysAgo).Add(time.Hour),
+			SizeBytes:   size,
+			Destination: "s3:backups",
+		}}
+	}
+
+	cfg := &config.Config{}
+	cfg.Backup.Databases = []string{"app", "logs"}
+	cfg.Upload.Enabled = true
+	cfg.Report.StorageCostPerGB = 1
+
+	// Newest first, as catalog.Scan returns them
+	entries := []catalog.Entry{
+		entry("app", 1, 1300),
+		entry("app", 3, 1200),
+		entry("logs", 4, 50),
+		entry("app", 6, 1100),
+		entry("app", 9, 1000),
+		entry("logs", 10, 40),
+	}
+	data := &metrics.MetricsData{Failures: []metrics.FailureRecord{
+		{Time: from.Add(-time.Hour), Operation: "backup", Database: "app", Error: "before the period"},
+		{Time: from.Add(time.Hour), Operation: "backup", Database: "app", Error: "timeout"},
+		{Time: from.Add(2 * time.Hour), Operation: "upload", Database: "logs", Error: "network"},
+	}}
+
+	s := Build(cfg, entries, data, from, to)
+
+	if s.Backups != 4 || s.BackupFailures != 1 || s.UploadFailures != 1 {
+		t.Errorf("backups %d, backup failures %d, upload failures %d; want 4, 1, 1", s.Backups, s.BackupFailures, s.UploadFailures)
+	}
+	if s.SuccessRate != 80 {
+		t.Errorf("success rate = %v, want 80", s.SuccessRate)
+	}
+	if len(s.Failures) != 2 || s.Failures[0].Error != "network" {
+		t.Errorf("failures = %+v, want the two in the period, newest first", s.Failures)
+	}
+
+	app, logs := s.Databases[0], s.Databases[1]
+	if app.Backups != 3 || app.Failures != 1 || app.LatestSize != 1300 || app.Growth != 300 {
+		t.Errorf("app = %+v, want 3 backups, 1 failure, latest 1300, growth 300", app)
+	}
+	if logs.Backups != 1 || logs.Growth != 10 {
+		t.Errorf("logs = %+v, want 1 backup, growth 10", logs)
+	}
+	if s.StoredBytes != 4690 {
+		t.Errorf("stored = %d, want 4690", s.StoredBytes)
+	}
+}
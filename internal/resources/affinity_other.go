@@ -0,0 +1,10 @@
+//go:build !linux
+
+package resources
+
+import "fmt"
+
+// setAffinity is only supported on Linux
+func setAffinity(cpus []int) error {
+	return fmt.Errorf("resources.cpu_affinity is only supported on Linux")
+}
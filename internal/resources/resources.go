@@ -0,0 +1,49 @@
+// Package resources applies the resources config section: GOMAXPROCS,
+// GOMEMLIMIT and the CPUs tenangdb and its child processes may run on.
+package resources
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+// Apply sets the limits of cfg for the running process. CPU affinity is
+// set first: children such as mysqldump, mydumper, zstd and rclone inherit
+// it, and GOMAXPROCS defaults to the number of CPUs allowed.
+func Apply(cfg *config.ResourcesConfig, log *logger.Logger) error {
+	maxProcs := cfg.MaxProcs
+
+	if cfg.CPUAffinity != "" {
+		cpus, err := config.ParseCPUList(cfg.CPUAffinity)
+		if err != nil {
+			return err
+		}
+		if err := setAffinity(cpus); err != nil {
+			return fmt.Errorf("failed to set CPU affinity: %w", err)
+		}
+		// Go sizes GOMAXPROCS from the affinity at startup only
+		if maxProcs == 0 {
+			maxProcs = len(cpus)
+		}
+		log.WithField("cpus", cfg.CPUAffinity).Debug("Set CPU affinity")
+	}
+
+	if maxProcs > 0 {
+		runtime.GOMAXPROCS(maxProcs)
+		log.WithField("gomaxprocs", maxProcs).Debug("Set GOMAXPROCS")
+	}
+
+	if cfg.MemoryLimit != "" {
+		limit, err := config.ParseMemoryLimit(cfg.MemoryLimit)
+		if err != nil {
+			return err
+		}
+		debug.SetMemoryLimit(limit)
+		log.WithField("memory_limit", cfg.MemoryLimit).Debug("Set GOMEMLIMIT")
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package runid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+type contextKey struct{}
+
+// New returns a unique, sortable ID for one tenangdb invocation,
+// e.g. 20240601T020000-3f9a2c
+func New() string {
+	suffix := make([]byte, 3)
+	if _, err := rand.Read(suffix); err != nil {
+		// Time alone still distinguishes runs started a second apart
+		return time.Now().Format("20060102T150405")
+	}
+	return time.Now().Format("20060102T150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// WithContext attaches a run ID to ctx
+func WithContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the run ID attached to ctx, or "" if there is none
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,333 @@
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a calendar rule in one of two forms: a weekday list such as
+// "Sat,Sun" or "Mon-Fri", or a five-field cron expression. An empty
+// expression, "*" or "daily" matches every day.
+type Schedule struct {
+	expr    string
+	cron    bool
+	minutes []int
+	hours   []int
+	days    []int // day of month
+	months  []int
+	weekday []int // 0 = Sunday
+	anyDay  bool  // day of month field was "*"
+	anyWday bool  // weekday field was "*"
+}
+
+// DefaultTime is used for systemd timers when the schedule has no time of day
+const DefaultTime = "02:00"
+
+var weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+var monthNames = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+
+type field struct {
+	name     string
+	min, max int
+	names    []string
+	nameBase int
+}
+
+var (
+	minuteField  = field{name: "minute", min: 0, max: 59}
+	hourField    = field{name: "hour", min: 0, max: 23}
+	dayField     = field{name: "day of month", min: 1, max: 31}
+	monthField   = field{name: "month", min: 1, max: 12, names: monthNames, nameBase: 1}
+	weekdayField = field{name: "day of week", min: 0, max: 7, names: weekdayNames, nameBase: 0}
+)
+
+// Parse parses a weekday list or cron expression
+func Parse(expr string) (*Schedule, error) {
+	s := &Schedule{expr: strings.TrimSpace(expr)}
+
+	switch strings.ToLower(s.expr) {
+	case "", "*", "daily":
+		s.anyDay, s.anyWday = true, true
+		return s, nil
+	}
+
+	fields := strings.Fields(s.expr)
+	if len(fields) == 1 {
+		weekdays, err := parseField(fields[0], weekdayField)
+		if err != nil {
+			return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+		}
+		s.weekday = weekdays
+		s.anyDay = true
+		return s, nil
+	}
+
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid schedule %q: expected a weekday list or 5 cron fields", expr)
+	}
+
+	s.cron = true
+	var err error
+	if s.minutes, err = parseField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	if s.hours, err = parseField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	if s.days, err = parseField(fields[2], dayField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	if s.months, err = parseField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	if s.weekday, err = parseField(fields[4], weekdayField); err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", expr, err)
+	}
+	s.anyDay = fields[2] == "*"
+	s.anyWday = fields[4] == "*"
+
+	return s, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	if s.expr == "" {
+		return "daily"
+	}
+	return s.expr
+}
+
+// RunsOn reports whether the schedule allows a run on the day of t. The time
+// of day in cron expressions is left to the scheduler that invokes us.
+func (s *Schedule) RunsOn(t time.Time) bool {
+	if s.cron && !contains(s.months, int(t.Month())) {
+		return false
+	}
+
+	dayMatch := s.anyDay || contains(s.days, t.Day())
+	wdayMatch := s.anyWday || contains(s.weekday, int(t.Weekday()))
+
+	// Cron runs when either day field matches if both are restricted
+	if s.cron && !s.anyDay && !s.anyWday {
+		return dayMatch || wdayMatch
+	}
+	return dayMatch && wdayMatch
+}
+
+// Next returns the first minute after the given time the schedule fires
+// at, in its location. Weekday lists fire at DefaultTime. It returns the
+// zero time when nothing matches within five years, such as on 31 February.
+func (s *Schedule) Next(after time.Time) time.Time {
+	hours, minutes := s.hours, s.minutes
+	if !s.cron {
+		var hour, minute int
+		fmt.Sscanf(DefaultTime, "%d:%d", &hour, &minute)
+		hours, minutes = []int{hour}, []int{minute}
+	}
+
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		if !s.RunsOn(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !contains(hours, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if contains(minutes, t.Minute()) {
+			return t
+		}
+		t = t.Add(time.Minute)
+	}
+	return time.Time{}
+}
+
+// Prev returns the last minute at or before the given time the schedule
+// fired at, looking back up to five years. It returns the zero time when
+// the schedule never fires.
+func (s *Schedule) Prev(before time.Time) time.Time {
+	day := time.Date(before.Year(), before.Month(), before.Day(), 0, 0, 0, 0, before.Location())
+	for limit := day.AddDate(-5, 0, 0); !day.Before(limit); day = day.AddDate(0, 0, -1) {
+		t := s.Next(day.Add(-time.Minute))
+		if t.IsZero() {
+			return t
+		}
+		if t.After(before) {
+			continue
+		}
+
+		// Walk forward through the fire times of the day
+		for {
+			next := s.Next(t)
+			if next.IsZero() || next.After(before) {
+				return t
+			}
+			t = next
+		}
+	}
+	return time.Time{}
+}
+
+// OnCalendar renders the schedule as systemd OnCalendar= values. A cron
+// expression restricting both day of month and day of week needs two
+// entries, since systemd ANDs them where cron ORs them.
+func (s *Schedule) OnCalendar() []string {
+	timeOfDay := DefaultTime
+	if s.cron {
+		timeOfDay = formatList(s.hours, hourField) + ":" + formatList(s.minutes, minuteField)
+	}
+
+	months := "*"
+	if s.cron {
+		months = formatList(s.months, monthField)
+	}
+
+	days := "*"
+	if !s.anyDay {
+		days = formatList(s.days, dayField)
+	}
+
+	var weekdays string
+	if !s.anyWday {
+		// systemd weeks start on Monday
+		var names []string
+		for i := 1; i <= 7; i++ {
+			if d := i % 7; contains(s.weekday, d) {
+				names = append(names, strings.ToUpper(weekdayNames[d][:1])+weekdayNames[d][1:])
+			}
+		}
+		weekdays = strings.Join(names, ",")
+	}
+
+	if s.cron && !s.anyDay && !s.anyWday {
+		return []string{
+			fmt.Sprintf("%s *-%s-* %s", weekdays, months, timeOfDay),
+			fmt.Sprintf("*-%s-%s %s", months, days, timeOfDay),
+		}
+	}
+
+	calendar := fmt.Sprintf("*-%s-%s %s", months, days, timeOfDay)
+	if weekdays != "" {
+		if !s.cron {
+			// Keep the short form for plain weekday lists
+			return []string{weekdays + " " + timeOfDay}
+		}
+		calendar = weekdays + " " + calendar
+	}
+	return []string{calendar}
+}
+
+// parseField expands a comma separated list of values, ranges and steps
+func parseField(value string, f field) ([]int, error) {
+	set := make(map[int]bool)
+
+	for _, part := range strings.Split(value, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in %s field: %q", f.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		switch {
+		case part == "*":
+			if f.max == 7 {
+				hi = 6 // 7 is only an alias for Sunday
+			}
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = f.value(bounds[0]); err != nil {
+				return nil, err
+			}
+			if hi, err = f.value(bounds[1]); err != nil {
+				return nil, err
+			}
+			if lo > hi {
+				return nil, fmt.Errorf("invalid range in %s field: %q", f.name, part)
+			}
+		default:
+			v, err := f.value(part)
+			if err != nil {
+				return nil, err
+			}
+			lo, hi = v, v
+			if step > 1 {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			if f.max == 7 && v == 7 {
+				set[0] = true // 7 is Sunday too
+				continue
+			}
+			set[v] = true
+		}
+	}
+
+	values := make([]int, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values, nil
+}
+
+// value parses a single number or name within the field bounds
+func (f field) value(s string) (int, error) {
+	lower := strings.ToLower(s)
+	for i, name := range f.names {
+		if len(lower) >= 3 && lower[:3] == name && strings.HasPrefix(fullName(name), lower) {
+			return i + f.nameBase, nil
+		}
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s: %q", f.name, s)
+	}
+	return v, nil
+}
+
+// fullName expands a three letter weekday or month abbreviation
+func fullName(abbr string) string {
+	full := map[string]string{
+		"sun": "sunday", "mon": "monday", "tue": "tuesday", "wed": "wednesday",
+		"thu": "thursday", "fri": "friday", "sat": "saturday",
+		"jan": "january", "feb": "february", "mar": "march", "apr": "april",
+		"may": "may", "jun": "june", "jul": "july", "aug": "august",
+		"sep": "september", "oct": "october", "nov": "november", "dec": "december",
+	}
+	return full[abbr]
+}
+
+// formatList renders values for systemd, collapsing a full range to "*"
+func formatList(values []int, f field) string {
+	if len(values) == f.max-f.min+1 {
+		return "*"
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%02d", v)
+	}
+	return strings.Join(parts, ",")
+}
+
+func contains(values []int, v int) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRunsOn(t *testing.T) {
+	saturday := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+	wednesday := time.Date(2024, time.June, 5, 12, 0, 0, 0, time.UTC)
+	fifteenth := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC) // Saturday
+
+	tests := []struct {
+		expr string
+		day  time.Time
+		want bool
+	}{
+		{"", wednesday, true},
+		{"daily", wednesday, true},
+		{"Sat,Sun", saturday, true},
+		{"Sat,Sun", wednesday, false},
+		{"Mon-Fri", wednesday, true},
+		{"saturday", saturday, true},
+		{"0 2 * * 6,0", saturday, true},
+		{"0 2 * * 6,7", wednesday, false},
+		{"0 2 1 * *", saturday, true},
+		{"0 2 1 * *", wednesday, false},
+		{"0 2 5 * 6", wednesday, true}, // day of month OR day of week
+		{"0 2 * 7 *", saturday, false},
+		{"0 2 */7 * *", fifteenth, true},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.RunsOn(tt.day); got != tt.want {
+			t.Errorf("Parse(%q).RunsOn(%s) = %v, want %v", tt.expr, tt.day.Weekday(), got, tt.want)
+		}
+	}
+}
+
+func TestOnCalendar(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"", []string{"*-*-* 02:00"}},
+		{"Sat,Sun", []string{"Sat,Sun 02:00"}},
+		{"30 3 * * 1-5", []string{"Mon,Tue,Wed,Thu,Fri *-*-* 03:30"}},
+		{"0 4 1,15 * *", []string{"*-*-01,15 04:00"}},
+		{"0 4 1 * 0", []string{"Sun *-*-* 04:00", "*-*-01 04:00"}},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.OnCalendar(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q).OnCalendar() = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"Funday", "Sat-Mon", "0 25 * * *", "0 2 * *", "*/0 * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	saturday := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"daily", saturday, time.Date(2024, time.June, 2, 2, 0, 0, 0, time.UTC)},
+		{"Mon-Fri", saturday, time.Date(2024, time.June, 3, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", saturday, time.Date(2024, time.June, 1, 12, 15, 0, 0, time.UTC)},
+		{"30 1,13 * * *", saturday, time.Date(2024, time.June, 1, 13, 30, 0, 0, time.UTC)},
+		{"0 3 1 * *", saturday, time.Date(2024, time.July, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 2 31 2 *", saturday, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.Next(tt.after); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.expr, tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestPrev(t *testing.T) {
+	saturday := time.Date(2024, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr   string
+		before time.Time
+		want   time.Time
+	}{
+		{"daily", saturday, time.Date(2024, time.June, 1, 2, 0, 0, 0, time.UTC)},
+		{"Mon-Fri", saturday, time.Date(2024, time.May, 31, 2, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", saturday, saturday},
+		{"30 1,13 * * *", saturday, time.Date(2024, time.June, 1, 1, 30, 0, 0, time.UTC)},
+		{"0 3 1 * *", saturday, time.Date(2024, time.June, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 3 2 * *", saturday, time.Date(2024, time.May, 2, 3, 0, 0, 0, time.UTC)},
+		{"0 2 31 2 *", saturday, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+		}
+		if got := s.Prev(tt.before); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Prev(%s) = %s, want %s", tt.expr, tt.before, got, tt.want)
+		}
+	}
+}
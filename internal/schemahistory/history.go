@@ -0,0 +1,143 @@
+// Package schemahistory keeps the schema of every backed up database in a
+// git repository, with one commit per backup that changed it
+package schemahistory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// remoteName is the git remote backup runs push to
+const remoteName = "origin"
+
+// Repo is the schema history repository. Databases are written to their own
+// directory, {repository}/{database}, and committed one at a time.
+type Repo struct {
+	cfg *config.SchemaHistoryConfig
+	mu  sync.Mutex
+}
+
+// Open returns the repository at cfg.Directory, creating it on the
+// configured branch if it does not exist yet, and points its remote at
+// cfg.Remote
+func Open(ctx context.Context, cfg *config.SchemaHistoryConfig) (*Repo, error) {
+	r := &Repo{cfg: cfg}
+	if err := os.MkdirAll(cfg.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create schema history directory: %w", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(cfg.Directory, ".git")); os.IsNotExist(err) {
+		if _, err := r.git(ctx, nil, "init", "--quiet"); err != nil {
+			return nil, err
+		}
+		if _, err := r.git(ctx, nil, "symbolic-ref", "HEAD", "refs/heads/"+cfg.Branch); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Remote != "" {
+		current, err := r.git(ctx, nil, "remote")
+		if err != nil {
+			return nil, err
+		}
+		verb := "add"
+		for _, name := range strings.Fields(current) {
+			if name == remoteName {
+				verb = "set-url"
+			}
+		}
+		if _, err := r.git(ctx, nil, "remote", verb, remoteName, cfg.Remote); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Dir returns the directory holding the schema of dbName, named like its
+// backups
+func (r *Repo) Dir(dbName string) string {
+	return filepath.Join(r.cfg.Directory, database.PathName(dbName))
+}
+
+// Update replaces the schema of dbName with what write puts into its
+// directory and commits the change, dated at the backup time. It reports
+// whether the schema changed since the last commit.
+func (r *Repo) Update(ctx context.Context, dbName string, at time.Time, message string, write func(dir string) error) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Start from an empty directory so dropped objects disappear
+	dir := r.Dir(dbName)
+	if err := os.RemoveAll(dir); err != nil {
+		return false, err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	if err := write(dir); err != nil {
+		return false, err
+	}
+
+	path := database.PathName(dbName)
+	if _, err := r.git(ctx, nil, "add", "--all", "--", path); err != nil {
+		return false, err
+	}
+	// diff --quiet exits 1 when something is staged
+	if _, err := r.git(ctx, nil, "diff", "--cached", "--quiet", "--", path); err == nil {
+		return false, nil
+	}
+
+	date := at.Format(time.RFC3339)
+	env := []string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}
+	if _, err := r.git(ctx, env, "commit", "--quiet", "-m", message, "--", path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Push sends the branch to the remote, if one is configured
+func (r *Repo) Push(ctx context.Context) error {
+	if r.cfg.Remote == "" {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Nothing to push before the first commit
+	if _, err := r.git(ctx, nil, "rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil
+	}
+	_, err := r.git(ctx, nil, "push", "--quiet", remoteName, "HEAD:refs/heads/"+r.cfg.Branch)
+	return err
+}
+
+// git runs a git command in the repository as the configured author.
+// Database names are used as paths, so pathspecs are taken literally.
+func (r *Repo) git(ctx context.Context, env []string, args ...string) (string, error) {
+	command := args[0]
+	args = append([]string{
+		"--literal-pathspecs",
+		"-c", "user.name=" + r.cfg.AuthorName,
+		"-c", "user.email=" + r.cfg.AuthorEmail,
+	}, args...)
+	cmd := exec.CommandContext(ctx, r.cfg.GitPath, args...)
+	cmd.Dir = r.cfg.Directory
+	cmd.Env = append(os.Environ(), env...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w (output: %s)", command, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
@@ -0,0 +1,65 @@
+package schemahistory
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestUpdate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	ctx := context.Background()
+	cfg := &config.SchemaHistoryConfig{
+		Directory:   filepath.Join(t.TempDir(), "history"),
+		Branch:      "main",
+		GitPath:     "git",
+		AuthorName:  "TenangDB",
+		AuthorEmail: "tenangdb@localhost",
+	}
+	repo, err := Open(ctx, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema := func(content string) func(string) error {
+		return func(dir string) error {
+			return os.WriteFile(filepath.Join(dir, "schema.sql"), []byte(content), 0644)
+		}
+	}
+	first := time.Date(2025, time.July, 1, 2, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		content string
+		changed bool
+	}{
+		{"CREATE TABLE a (id int);\n", true},
+		{"CREATE TABLE a (id int);\n", false},
+		{"CREATE TABLE a (id bigint);\n", true},
+	}
+	for i, step := range steps {
+		changed, err := repo.Update(ctx, "app_db", first.Add(time.Duration(i)*24*time.Hour), "app_db schema", schema(step.content))
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if changed != step.changed {
+			t.Errorf("step %d: changed = %v, want %v", i, changed, step.changed)
+		}
+	}
+
+	log, err := repo.git(ctx, nil, "log", "--format=%aI %s", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2025-07-03T02:00:00+00:00 app_db schema\n2025-07-01T02:00:00+00:00 app_db schema"
+	if got := strings.TrimSpace(log); got != want {
+		t.Errorf("log = %q, want %q", got, want)
+	}
+}
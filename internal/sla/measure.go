@@ -0,0 +1,111 @@
+package sla
+
+import (
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// DefaultWindow is the period RPO and RTO are measured over
+const DefaultWindow = 30 * 24 * time.Hour
+
+// Restore is one measured restore of a database, from a drill or a real
+// restore
+type Restore struct {
+	Database string
+	Source   string
+	Time     time.Time
+	Duration time.Duration
+}
+
+// Measurement is the recovery point and recovery time a database actually
+// achieved over a window
+type Measurement struct {
+	Database string
+
+	// Backups is the number of verified backups taken in the window,
+	// MaxInterval the longest gap between two of them and AvgInterval the
+	// typical one. BackupAge is the age of the newest verified backup.
+	Backups     int
+	MaxInterval time.Duration
+	AvgInterval time.Duration
+	BackupAge   time.Duration
+	HasBackup   bool
+
+	// Restores is the number of measured restores in the window, LastRTO
+	// the duration of the newest and MaxRTO of the slowest
+	Restores   int
+	LastRTO    time.Duration
+	MaxRTO     time.Duration
+	LastSource string
+}
+
+// RPO is the most data the database could have lost at any point in the
+// window: the longest gap between backups, or the time since the last one
+// if that is longer
+func (m *Measurement) RPO() time.Duration {
+	if m.BackupAge > m.MaxInterval {
+		return m.BackupAge
+	}
+	return m.MaxInterval
+}
+
+// Measure computes the achieved RPO and RTO of every backed up database
+// over the window ending at now. entries must be sorted newest first, as
+// returned by catalog.Scan, and restores oldest first.
+func Measure(cfg *config.Config, entries []catalog.Entry, restores []Restore, now time.Time, window time.Duration) []Measurement {
+	since := now.Add(-window)
+
+	var measurements []Measurement
+	for _, dbName := range cfg.Backup.Databases {
+		m := Measurement{Database: dbName}
+
+		// Walk backups newest first. The first backup before the window
+		// still closes the gap to the oldest backup inside it.
+		var newer time.Time
+		var total time.Duration
+		gaps := 0
+		for i := range entries {
+			entry := &entries[i]
+			if entry.Manifest.Database != dbName || !Verified(entry, cfg.Upload.Enabled) {
+				continue
+			}
+			created := entry.Manifest.CreatedAt
+			if !m.HasBackup {
+				m.HasBackup = true
+				m.BackupAge = now.Sub(created)
+			} else {
+				gap := newer.Sub(created)
+				total += gap
+				gaps++
+				if gap > m.MaxInterval {
+					m.MaxInterval = gap
+				}
+			}
+			if created.Before(since) {
+				break
+			}
+			m.Backups++
+			newer = created
+		}
+		if gaps > 0 {
+			m.AvgInterval = total / time.Duration(gaps)
+		}
+
+		for _, restore := range restores {
+			if restore.Database != dbName || restore.Time.Before(since) {
+				continue
+			}
+			m.Restores++
+			m.LastRTO = restore.Duration
+			m.LastSource = restore.Source
+			if restore.Duration > m.MaxRTO {
+				m.MaxRTO = restore.Duration
+			}
+		}
+
+		measurements = append(measurements, m)
+	}
+	return measurements
+}
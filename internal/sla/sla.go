@@ -0,0 +1,58 @@
+package sla
+
+import (
+	"os"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// Status is the backup freshness of one database against its SLA
+type Status struct {
+	Database   string
+	MaxAge     time.Duration
+	LastBackup *catalog.Entry // newest verified backup, nil if there is none
+	Age        time.Duration  // age of LastBackup
+	Breached   bool
+}
+
+// Evaluate checks every backed up database that has an SLA against its
+// newest verified backup. entries must be sorted newest first, as returned
+// by catalog.Scan.
+func Evaluate(cfg *config.Config, entries []catalog.Entry, now time.Time) []Status {
+	var statuses []Status
+	for _, dbName := range cfg.Backup.Databases {
+		maxAge := cfg.SLA.MaxAgeFor(dbName)
+		if maxAge <= 0 {
+			continue
+		}
+
+		status := Status{Database: dbName, MaxAge: maxAge, Breached: true}
+		for i := range entries {
+			entry := &entries[i]
+			if entry.Manifest.Database != dbName || !Verified(entry, cfg.Upload.Enabled) {
+				continue
+			}
+			status.LastBackup = entry
+			status.Age = now.Sub(entry.Manifest.CreatedAt)
+			status.Breached = status.Age > maxAge
+			break
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Verified reports whether a backup counts towards the SLA: it finished
+// with a manifest, is still on disk, and reached the cloud when uploads
+// are enabled
+func Verified(entry *catalog.Entry, uploadEnabled bool) bool {
+	if _, err := os.Stat(entry.ArtifactPath); err != nil {
+		return false
+	}
+	if uploadEnabled && entry.Manifest.Destination == "" {
+		return false
+	}
+	return true
+}
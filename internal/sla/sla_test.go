@@ -0,0 +1,117 @@
+package sla
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/catalog"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+func TestEvaluate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC)
+
+	entry := func(db string, age time.Duration, destination string) catalog.Entry {
+		path := filepath.Join(dir, db+"-"+age.String()+".sql")
+		if err := os.WriteFile(path, []byte("--"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return catalog.Entry{
+			ID:           db,
+			ArtifactPath: path,
+			Manifest:     &manifest.Manifest{Database: db, CreatedAt: now.Add(-age), Destination: destination},
+		}
+	}
+
+	cfg := &config.Config{}
+	cfg.Backup.Databases = []string{"app", "billing", "logs", "empty"}
+	cfg.Upload.Enabled = true
+	cfg.SLA.MaxBackupAge = 26 * time.Hour
+	cfg.SLA.Databases = []config.DatabaseSLA{
+		{Name: "billing", MaxBackupAge: 2 * time.Hour},
+		{Name: "logs", MaxBackupAge: 0},
+	}
+
+	entries := []catalog.Entry{
+		entry("app", time.Hour, ""), // never uploaded, not verified
+		entry("billing", 3*time.Hour, "s3:backups"),
+		entry("app", 20*time.Hour, "s3:backups"),
+		entry("logs", 90*24*time.Hour, "s3:backups"),
+	}
+
+	statuses := Evaluate(cfg, entries, now)
+	want := map[string]bool{"app": false, "billing": true, "empty": true}
+	if len(statuses) != len(want) {
+		t.Fatalf("got %d statuses, want %d", len(statuses), len(want))
+	}
+	for _, status := range statuses {
+		if breached, ok := want[status.Database]; !ok || status.Breached != breached {
+			t.Errorf("%s: breached = %v, want %v", status.Database, status.Breached, breached)
+		}
+	}
+	if age := statuses[0].Age; age != 20*time.Hour {
+		t.Errorf("app: age = %v, want 20h (newest verified backup)", age)
+	}
+}
+
+func TestMeasure(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 6, 2, 12, 0, 0, 0, time.UTC)
+
+	entry := func(age time.Duration) catalog.Entry {
+		path := filepath.Join(dir, "app-"+age.String()+".sql")
+		if err := os.WriteFile(path, []byte("--"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return catalog.Entry{
+			ID:           "app",
+			ArtifactPath: path,
+			Manifest:     &manifest.Manifest{Database: "app", CreatedAt: now.Add(-age)},
+		}
+	}
+
+	cfg := &config.Config{}
+	cfg.Backup.Databases = []string{"app", "empty"}
+
+	window := 7 * 24 * time.Hour
+	entries := []catalog.Entry{
+		entry(2 * time.Hour),
+		entry(26 * time.Hour),
+		entry(74 * time.Hour),
+		entry(7*24*time.Hour - time.Hour), // 93h gap, the worst
+		entry(8 * 24 * time.Hour),         // before the window, closes the last gap
+		entry(20 * 24 * time.Hour),        // ignored
+	}
+	restores := []Restore{
+		{Database: "app", Source: "restore", Time: now.Add(-10 * 24 * time.Hour), Duration: time.Hour},
+		{Database: "app", Source: "drill", Time: now.Add(-3 * 24 * time.Hour), Duration: 20 * time.Minute},
+		{Database: "app", Source: "drill", Time: now.Add(-24 * time.Hour), Duration: 10 * time.Minute},
+	}
+
+	measurements := Measure(cfg, entries, restores, now, window)
+	if len(measurements) != 2 {
+		t.Fatalf("got %d measurements, want 2", len(measurements))
+	}
+
+	app := measurements[0]
+	if app.Backups != 4 || app.BackupAge != 2*time.Hour {
+		t.Errorf("app: backups = %d, age = %v", app.Backups, app.BackupAge)
+	}
+	if app.MaxInterval != 93*time.Hour || app.RPO() != 93*time.Hour {
+		t.Errorf("app: max interval = %v, rpo = %v, want 93h", app.MaxInterval, app.RPO())
+	}
+	if app.AvgInterval != (8*24*time.Hour-2*time.Hour)/4 {
+		t.Errorf("app: avg interval = %v", app.AvgInterval)
+	}
+	if app.Restores != 2 || app.LastRTO != 10*time.Minute || app.MaxRTO != 20*time.Minute || app.LastSource != "drill" {
+		t.Errorf("app: restores = %d, last rto = %v, max rto = %v", app.Restores, app.LastRTO, app.MaxRTO)
+	}
+
+	if empty := measurements[1]; empty.HasBackup || empty.Restores != 0 {
+		t.Errorf("empty: %+v", empty)
+	}
+}
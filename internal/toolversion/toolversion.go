@@ -0,0 +1,178 @@
+// Package toolversion detects the versions of the tools a backup is made
+// and restored with, so manifests can record them and restores can warn
+// when the toolchain changed.
+package toolversion
+
+import (
+	"context"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// Tool names used as keys of the versions recorded in manifests
+const (
+	TenangDB  = "tenangdb"
+	Mysqldump = "mysqldump"
+	Mysql     = "mysql"
+	Mydumper  = "mydumper"
+	Myloader  = "myloader"
+	Rclone    = "rclone"
+)
+
+// Versions maps tool names to their version, such as "mysqldump": "8.0.36"
+type Versions map[string]string
+
+// detectTimeout bounds a --version call, some wrappers prompt or hang
+const detectTimeout = 10 * time.Second
+
+var (
+	versionPattern = regexp.MustCompile(`\d+\.\d+(\.\d+)?`)
+	// mysqldump 5.7 and MariaDB print their client protocol version
+	// first, "Ver 10.19 Distrib 10.11.6-MariaDB"
+	distribPattern = regexp.MustCompile(`Distrib (\d+\.\d+(\.\d+)?)`)
+)
+
+// Detect runs binary --version and returns the version it prints, or ""
+// when the binary is missing or prints none
+func Detect(binary string) string {
+	if binary == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), detectTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, binary, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return Parse(string(output))
+}
+
+// Parse extracts the version from --version output
+func Parse(output string) string {
+	if m := distribPattern.FindStringSubmatch(output); m != nil {
+		return m[1]
+	}
+	return versionPattern.FindString(output)
+}
+
+// Backup detects the versions of the tools a backup with cfg runs. Dump
+// tools inside a container are left out, their versions are not visible
+// from the host.
+func Backup(cfg *config.Config, tenangdbVersion string) Versions {
+	versions := Versions{}
+	versions.add(TenangDB, tenangdbVersion)
+
+	if cfg.Database.Container == "" && !cfg.Database.IsPostgreSQL() {
+		if mydumper := cfg.Database.Mydumper; mydumper != nil && mydumper.Enabled {
+			versions.add(Mydumper, Detect(mydumper.BinaryPath))
+			if mydumper.Myloader != nil {
+				versions.add(Myloader, Detect(mydumper.Myloader.BinaryPath))
+			}
+		}
+		// mysqldump also backs up when mydumper is disabled or for chunks
+		versions.add(Mysqldump, Detect(cfg.Database.MysqldumpPath))
+	}
+	if cfg.Upload.Enabled {
+		versions.add(Rclone, Detect(cfg.Upload.RclonePath))
+	}
+	return versions
+}
+
+// Restore detects the versions of the tools a restore of a backup made
+// with tool ("mydumper" or "mysqldump") runs
+func Restore(cfg *config.Config, tool, tenangdbVersion string) Versions {
+	versions := Versions{}
+	versions.add(TenangDB, tenangdbVersion)
+	if cfg.Database.Container != "" {
+		return versions
+	}
+
+	if tool == Mydumper {
+		if mydumper := cfg.Database.Mydumper; mydumper != nil && mydumper.Myloader != nil {
+			versions.add(Myloader, Detect(mydumper.Myloader.BinaryPath))
+		}
+	} else {
+		versions.add(Mysql, Detect(cfg.Database.MysqlPath))
+	}
+	return versions
+}
+
+// For returns the versions relevant to a backup made with tool, dropping
+// the dump tool that was not used
+func (v Versions) For(tool string) Versions {
+	used := Versions{}
+	for name, version := range v {
+		switch {
+		case tool == Mydumper && name == Mysqldump:
+		case tool != Mydumper && (name == Mydumper || name == Myloader):
+		default:
+			used[name] = version
+		}
+	}
+	return used
+}
+
+func (v Versions) add(tool, version string) {
+	if version != "" && version != "unknown" {
+		v[tool] = strings.TrimPrefix(version, "v")
+	}
+}
+
+// restoredBy pairs each restore tool with the backup tool whose version it
+// should match. mysql and mysqldump ship in the same client package, as do
+// myloader and mydumper.
+var restoredBy = map[string][]string{
+	TenangDB: {TenangDB},
+	Myloader: {Myloader, Mydumper},
+	Mysql:    {Mysqldump},
+}
+
+// Mismatch is a restore tool a major version away from the tool that made
+// the backup
+type Mismatch struct {
+	Tool          string // restore tool
+	Version       string
+	BackupTool    string
+	BackupVersion string
+}
+
+// Compare returns the restore tools whose major version differs from the
+// matching tool recorded for the backup
+func Compare(backup, restore Versions) []Mismatch {
+	var mismatches []Mismatch
+	for _, tool := range []string{TenangDB, Myloader, Mysql} {
+		version, ok := restore[tool]
+		if !ok {
+			continue
+		}
+		for _, backupTool := range restoredBy[tool] {
+			backupVersion, ok := backup[backupTool]
+			if !ok {
+				continue
+			}
+			if Major(version) != Major(backupVersion) {
+				mismatches = append(mismatches, Mismatch{Tool: tool, Version: version, BackupTool: backupTool, BackupVersion: backupVersion})
+			}
+			break
+		}
+	}
+	return mismatches
+}
+
+// Major returns the major version: the first component, or the first two
+// for 0.x releases, whose minor versions break compatibility like mydumper's
+func Major(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) > 1 {
+		if n, err := strconv.Atoi(parts[0]); err == nil && n == 0 {
+			return parts[0] + "." + parts[1]
+		}
+	}
+	return parts[0]
+}
@@ -0,0 +1,40 @@
+package toolversion
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := map[string]string{
+		"mysqldump  Ver 8.0.36 for Linux on x86_64 (MySQL Community Server - GPL)": "8.0.36",
+		"mysqldump  Ver 10.13 Distrib 5.7.44, for Linux (x86_64)":                  "5.7.44",
+		"mysqldump  Ver 10.19 Distrib 10.11.6-MariaDB, for debian-linux-gnu":       "10.11.6",
+		"mydumper v0.19.3-1, built against MySQL 8.0.39":                           "0.19.3",
+		"mydumper 0.10.0, built against MySQL 5.7.21":                              "0.10.0",
+		"rclone v1.65.0\n- os/version: ubuntu 22.04":                               "1.65.0",
+		"command not found": "",
+	}
+	for output, want := range tests {
+		if got := Parse(output); got != want {
+			t.Errorf("Parse(%q) = %q, want %q", output, got, want)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	backup := Versions{TenangDB: "1.4.0", Mydumper: "0.10.0", Mysqldump: "5.7.44"}
+
+	got := Compare(backup, Versions{TenangDB: "1.9.2", Myloader: "0.19.3", Mysql: "8.0.36"})
+	want := []Mismatch{
+		{Tool: Myloader, Version: "0.19.3", BackupTool: Mydumper, BackupVersion: "0.10.0"},
+		{Tool: Mysql, Version: "8.0.36", BackupTool: Mysqldump, BackupVersion: "5.7.44"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Compare() = %+v, want %+v", got, want)
+	}
+
+	if got := Compare(backup, Versions{TenangDB: "1.0.0", Myloader: "0.10.3"}); len(got) != 0 {
+		t.Errorf("Compare() within a major = %+v, want none", got)
+	}
+}
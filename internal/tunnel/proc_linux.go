@@ -0,0 +1,11 @@
+package tunnel
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// bindToParent stops ssh if tenangdb exits without closing the tunnel
+func bindToParent(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGTERM}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package tunnel
+
+import "os/exec"
+
+// bindToParent is only supported on Linux; elsewhere the tunnel relies on
+// Close being called
+func bindToParent(cmd *exec.Cmd) {}
@@ -0,0 +1,140 @@
+package tunnel
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+const (
+	startTimeout = 15 * time.Second
+	pollInterval = 100 * time.Millisecond
+)
+
+// Tunnel forwards a local port to a database behind an SSH bastion using the
+// system ssh client
+type Tunnel struct {
+	LocalPort int
+	cmd       *exec.Cmd
+	exited    chan struct{}
+	stderr    bytes.Buffer
+	logger    *logger.Logger
+}
+
+// Open starts an ssh process forwarding a free local port to
+// remoteHost:remotePort as seen from the bastion, and waits until the port
+// accepts connections
+func Open(ctx context.Context, cfg *config.SSHConfig, remoteHost string, remotePort int, log *logger.Logger) (*Tunnel, error) {
+	localPort, err := freePort()
+	if err != nil {
+		return nil, fmt.Errorf("failed to allocate local tunnel port: %w", err)
+	}
+
+	t := &Tunnel{
+		LocalPort: localPort,
+		exited:    make(chan struct{}),
+		logger:    log,
+	}
+
+	t.cmd = exec.Command(cfg.BinaryPath, sshArgs(cfg, localPort, remoteHost, remotePort)...)
+	t.cmd.Stderr = &t.stderr
+	bindToParent(t.cmd)
+	log.WithField("command", strings.Join(t.cmd.Args, " ")).Debug("Running external command")
+
+	if err := t.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start ssh: %w", err)
+	}
+	go func() {
+		t.cmd.Wait()
+		close(t.exited)
+	}()
+
+	if err := t.waitReady(ctx); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	log.WithFields(map[string]interface{}{
+		"bastion":    cfg.Host,
+		"local_port": localPort,
+		"remote":     net.JoinHostPort(remoteHost, strconv.Itoa(remotePort)),
+	}).Info("🔐 SSH tunnel established")
+
+	return t, nil
+}
+
+// Close stops the ssh process
+func (t *Tunnel) Close() {
+	select {
+	case <-t.exited:
+		return
+	default:
+	}
+
+	if t.cmd.Process != nil {
+		t.cmd.Process.Kill()
+	}
+	<-t.exited
+	t.logger.Debug("SSH tunnel closed")
+}
+
+// waitReady polls the local port until ssh forwards it
+func (t *Tunnel) waitReady(ctx context.Context) error {
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(t.LocalPort))
+	deadline := time.Now().Add(startTimeout)
+
+	for time.Now().Before(deadline) {
+		select {
+		case <-t.exited:
+			return fmt.Errorf("ssh tunnel exited: %s", strings.TrimSpace(t.stderr.String()))
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		conn, err := net.DialTimeout("tcp", address, pollInterval)
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		time.Sleep(pollInterval)
+	}
+
+	return fmt.Errorf("ssh tunnel not ready after %s", startTimeout)
+}
+
+func sshArgs(cfg *config.SSHConfig, localPort int, remoteHost string, remotePort int) []string {
+	args := []string{
+		"-N",
+		"-o", "BatchMode=yes",
+		"-o", "ExitOnForwardFailure=yes",
+		"-o", "ServerAliveInterval=30",
+		"-L", fmt.Sprintf("127.0.0.1:%d:%s:%d", localPort, remoteHost, remotePort),
+		"-p", strconv.Itoa(cfg.Port),
+	}
+	if cfg.KeyFile != "" {
+		args = append(args, "-i", cfg.KeyFile, "-o", "IdentitiesOnly=yes")
+	}
+	if cfg.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+cfg.KnownHostsFile)
+	}
+	return append(args, cfg.User+"@"+cfg.Host)
+}
+
+// freePort asks the kernel for an unused local port
+func freePort() (int, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port, nil
+}
@@ -0,0 +1,35 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// benchDir holds the test files of MeasureUpload below a destination
+const benchDir = ".tenangdb-bench"
+
+// MeasureUpload uploads file below destination with the configured tuning,
+// removes it again and returns how long the upload took
+func (s *Service) MeasureUpload(ctx context.Context, file, destination string) (time.Duration, error) {
+	uploadCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
+	defer cancel()
+
+	remote := strings.TrimSuffix(destination, "/") + "/" + benchDir + "/" + filepath.Base(file)
+	args := append([]string{"copyto", file, remote}, s.tuningArgs()...)
+	args = append(args, s.configArgs()...)
+
+	start := time.Now()
+	if output, err := s.rcloneCommand(uploadCtx, args...).CombinedOutput(); err != nil {
+		return 0, fmt.Errorf("rclone command failed: %w (output: %s)", err, string(output))
+	}
+	elapsed := time.Since(start)
+
+	args = append([]string{"deletefile", remote}, s.configArgs()...)
+	if output, err := s.rcloneCommand(ctx, args...).CombinedOutput(); err != nil {
+		s.logger.WithField("output", string(output)).WithError(err).Warn("Failed to remove benchmark file from " + destination)
+	}
+	return elapsed, nil
+}
@@ -0,0 +1,460 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// IsRemotePath reports whether backupPath names an rclone remote such as
+// "s3:backups/app/2025-07/app-2025-07-05_10-30-15.sql.gz" rather than a
+// local file
+func IsRemotePath(backupPath string) bool {
+	if _, err := os.Stat(backupPath); err == nil {
+		return false
+	}
+	i := strings.Index(backupPath, ":")
+	return i > 0 && !strings.ContainsAny(backupPath[:i], `/\`)
+}
+
+// downloadChunkSize is the range of a file one stream fetches at a time.
+// An interrupted download resumes after its last complete chunk.
+var downloadChunkSize int64 = 64 << 20
+
+// PartSuffix marks a file that is still being downloaded
+const PartSuffix = ".part"
+
+// Download copies a remote backup and its manifest into localDir and
+// returns the local path. Files are fetched in ranged chunks, streams at a
+// time, into a .part file that an interrupted download resumes from when
+// localDir is reused; files uploaded in parts are put back together in it.
+// Parts and files with a SHA-256 in their manifest are verified before the
+// .part file takes its final name.
+func (s *Service) Download(ctx context.Context, remote, localDir string, streams int) (string, error) {
+	remote = strings.TrimSuffix(remote, "/")
+
+	// Drop the "remote:" prefix when naming the local copy
+	localPath := filepath.Join(localDir, path.Base(remote[strings.Index(remote, ":")+1:]))
+	part := localPath + PartSuffix
+
+	// Backups from before manifests existed have none
+	manifestArgs := append([]string{"copy", remote + manifest.Suffix, localDir}, s.configArgs()...)
+	if output, err := s.rcloneCommand(ctx, manifestArgs...).CombinedOutput(); err != nil {
+		s.logger.WithField("output", strings.TrimSpace(string(output))).Debug("No manifest downloaded")
+	}
+
+	var pieces []downloadPiece
+	var size int64
+	if m, err := manifest.Read(manifest.PathFor(localPath)); err == nil && len(m.Parts) > 0 {
+		if pieces, size, err = s.partPieces(ctx, remote, part, m.Parts); err != nil {
+			return "", err
+		}
+	} else {
+		stat, err := s.remoteStat(ctx, remote)
+		if err != nil {
+			return "", err
+		}
+		if stat.IsDir {
+			return localPath, s.downloadDir(ctx, remote, localPath, streams)
+		}
+		pieces = []downloadPiece{{remote: remote, stat: stat, statePath: part + partialStateSuffix}}
+		size = stat.Size
+	}
+
+	if _, err := os.Stat(localPath); err == nil {
+		if err := verifyChecksum(localPath, localPath); err == nil {
+			s.logger.WithField("path", localPath).Info("Backup already downloaded")
+			return localPath, nil
+		}
+		os.Remove(localPath)
+	}
+
+	for _, piece := range pieces {
+		if err := s.downloadFile(ctx, piece, part, streams); err != nil {
+			return "", err
+		}
+		if piece.sha256 == "" {
+			continue
+		}
+		sum, err := sectionChecksum(part, piece.base, piece.stat.Size)
+		if err != nil {
+			return "", fmt.Errorf("failed to checksum download: %w", err)
+		}
+		if sum != piece.sha256 {
+			// Only this part is fetched again on the next attempt
+			os.Remove(piece.statePath)
+			return "", fmt.Errorf("checksum mismatch for %s: got %s, manifest has %s", path.Base(piece.remote), sum, piece.sha256)
+		}
+	}
+
+	// A larger file downloaded into the .part file before leaves a tail
+	if err := os.Truncate(part, size); err != nil {
+		return "", fmt.Errorf("failed to finish download: %w", err)
+	}
+	if err := verifyChecksum(part, localPath); err != nil {
+		// Resuming would keep the bad bytes
+		removePartial(part, pieces)
+		return "", err
+	}
+	if err := os.Rename(part, localPath); err != nil {
+		return "", fmt.Errorf("failed to finish download: %w", err)
+	}
+	removePartial(part, pieces)
+	return localPath, nil
+}
+
+// downloadDir copies a remote directory, such as a mydumper backup. rclone
+// copy skips files that are already complete, so a rerun into the same
+// directory resumes too.
+func (s *Service) downloadDir(ctx context.Context, remote, localPath string, streams int) error {
+	args := []string{"copy", remote, localPath, "--checksum"}
+	if streams > 0 {
+		args = append(args, "--multi-thread-streams", strconv.Itoa(streams), "--multi-thread-cutoff", "64M")
+	}
+	args = append(args, s.tuningArgs()...)
+	args = append(args, s.configArgs()...)
+
+	s.logger.WithField("remote", remote).WithField("streams", streams).Info("☁️  Downloading backup")
+	if output, err := s.rcloneCommand(ctx, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("rclone download failed: %w (output: %s)", err, string(output))
+	}
+	return nil
+}
+
+// partialStateSuffix names the file recording which chunks of a .part file
+// are complete
+const partialStateSuffix = ".json"
+
+// downloadPiece is a remote file written at base in a .part file: the
+// whole backup, or one part of a backup uploaded in parts
+type downloadPiece struct {
+	remote    string
+	stat      remoteFile
+	base      int64
+	sha256    string // of a part, from the manifest
+	statePath string
+}
+
+// partPieces looks up the parts of a backup uploaded in parts and returns
+// where each goes in the .part file, and the size of the backup
+func (s *Service) partPieces(ctx context.Context, remote, part string, parts []manifest.Part) ([]downloadPiece, int64, error) {
+	pieces := make([]downloadPiece, 0, len(parts))
+	var base int64
+	for i, p := range parts {
+		remotePart := manifest.PartName(remote, i)
+		stat, err := s.remoteStat(ctx, remotePart)
+		if err != nil {
+			return nil, 0, err
+		}
+		if stat.Size != p.Size {
+			return nil, 0, fmt.Errorf("part %s is %d bytes, the manifest has %d", p.Name, stat.Size, p.Size)
+		}
+		pieces = append(pieces, downloadPiece{remote: remotePart, stat: stat, base: base, sha256: p.SHA256, statePath: manifest.PartName(part, i) + partialStateSuffix})
+		base += p.Size
+	}
+	return pieces, base, nil
+}
+
+// partialDownload is the progress of a piece of a .part file. It only
+// resumes a download of the same remote file, unchanged since.
+type partialDownload struct {
+	Remote    string `json:"remote"`
+	Size      int64  `json:"size"`
+	ModTime   string `json:"mod_time"`
+	ChunkSize int64  `json:"chunk_size"`
+	Done      []int  `json:"done"`
+}
+
+// downloadFile fetches a piece into part, skipping the chunks an earlier
+// attempt completed
+func (s *Service) downloadFile(ctx context.Context, piece downloadPiece, part string, streams int) error {
+	remote, stat := piece.remote, piece.stat
+	state := &partialDownload{Remote: remote, Size: stat.Size, ModTime: stat.ModTime, ChunkSize: downloadChunkSize}
+	if previous, err := readPartial(piece.statePath); err == nil && previous.Remote == remote && previous.Size == stat.Size &&
+		previous.ModTime == stat.ModTime && previous.ChunkSize == downloadChunkSize {
+		state = previous
+	}
+
+	done := make(map[int]bool, len(state.Done))
+	for _, chunk := range state.Done {
+		done[chunk] = true
+	}
+	chunks := int((stat.Size + downloadChunkSize - 1) / downloadChunkSize)
+	var pending []int
+	for chunk := 0; chunk < chunks; chunk++ {
+		if !done[chunk] {
+			pending = append(pending, chunk)
+		}
+	}
+
+	log := s.logger.WithField("remote", remote).WithField("streams", streams)
+	switch {
+	case len(pending) == 0 && chunks > 0:
+		log.Debug("Already downloaded")
+	case len(state.Done) > 0:
+		log.WithField("completed", fmt.Sprintf("%d/%d chunks", chunks-len(pending), chunks)).Info("☁️  Resuming backup download")
+	default:
+		log.Info("☁️  Downloading backup")
+	}
+
+	file, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create download: %w", err)
+	}
+	defer file.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	work := make(chan int)
+	errs := make(chan error, max(streams, 1))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < max(streams, 1); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for chunk := range work {
+				if err := s.downloadChunk(ctx, remote, file, piece.base, chunk, stat.Size); err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+				mu.Lock()
+				state.Done = append(state.Done, chunk)
+				err := writePartial(piece.statePath, state)
+				mu.Unlock()
+				if err != nil {
+					errs <- err
+					cancel()
+					return
+				}
+			}
+		}()
+	}
+	for _, chunk := range pending {
+		select {
+		case work <- chunk:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	close(work)
+	wg.Wait()
+	close(errs)
+
+	if err := <-errs; err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// downloadChunk writes one chunk of remote to file, at base plus the
+// chunk's offset in remote
+func (s *Service) downloadChunk(ctx context.Context, remote string, file *os.File, base int64, chunk int, size int64) error {
+	offset := int64(chunk) * downloadChunkSize
+	length := min(downloadChunkSize, size-offset)
+	args := []string{"cat", remote, "--offset", strconv.FormatInt(offset, 10), "--count", strconv.FormatInt(length, 10)}
+	args = append(args, s.configArgs()...)
+
+	var stderr bytes.Buffer
+	out := &countingWriter{w: io.NewOffsetWriter(file, base+offset)}
+	cmd := s.rcloneCommand(ctx, args...)
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("rclone download failed: %w (output: %s)", err, strings.TrimSpace(stderr.String()))
+	}
+	if out.n != length {
+		return fmt.Errorf("rclone cat returned %d of %d bytes of %s at offset %d", out.n, length, remote, offset)
+	}
+	return nil
+}
+
+// countingWriter counts the bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func readPartial(path string) (*partialDownload, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var state partialDownload
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// writePartial records progress, replacing the previous record in one step
+func writePartial(path string, state *partialDownload) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to record download progress: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// removePartial removes a .part file and the progress of its pieces
+func removePartial(part string, pieces []downloadPiece) {
+	os.Remove(part)
+	for _, piece := range pieces {
+		os.Remove(piece.statePath)
+	}
+}
+
+// PartialMaxAge is how long an interrupted download is kept for resuming
+// after it was last written to
+const PartialMaxAge = 7 * 24 * time.Hour
+
+// ExpirePartial removes the .part files in dir, and the progress records of
+// their pieces, that nothing has written to for maxAge, and returns the
+// removed .part files. Records left without their .part file expire too.
+func ExpirePartial(dir string, maxAge time.Duration, now time.Time) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// A .part file and its records, such as backup.sql.gz.part.json or
+	// backup.sql.gz.part.001.json, last written to at newest
+	type partial struct {
+		files  []string
+		newest time.Time
+	}
+	partials := make(map[string]*partial)
+	for _, entry := range entries {
+		name := entry.Name()
+		i := strings.LastIndex(name, PartSuffix)
+		if entry.IsDir() || i < 0 {
+			continue
+		}
+		if rest := name[i+len(PartSuffix):]; rest != "" && !strings.Contains(rest, partialStateSuffix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		part := filepath.Join(dir, name[:i+len(PartSuffix)])
+		p := partials[part]
+		if p == nil {
+			p = &partial{}
+			partials[part] = p
+		}
+		p.files = append(p.files, filepath.Join(dir, name))
+		if info.ModTime().After(p.newest) {
+			p.newest = info.ModTime()
+		}
+	}
+
+	var removed []string
+	for part, p := range partials {
+		if now.Sub(p.newest) < maxAge {
+			continue
+		}
+		for _, file := range p.files {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return removed, fmt.Errorf("failed to remove abandoned download: %w", err)
+			}
+		}
+		removed = append(removed, part)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// sectionChecksum returns the hex SHA-256 of size bytes of a file at offset
+func sectionChecksum(path string, offset, size int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, offset, size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verifyChecksum compares a downloaded file with the SHA-256 recorded in
+// the manifest of localPath, before anything decompresses or loads it
+func verifyChecksum(path, localPath string) error {
+	m, err := manifest.Read(manifest.PathFor(localPath))
+	if err != nil || m.SHA256 == "" {
+		return nil
+	}
+
+	sum, err := manifest.FileChecksum(path)
+	if err != nil {
+		return fmt.Errorf("failed to checksum download: %w", err)
+	}
+	if sum != m.SHA256 {
+		return fmt.Errorf("checksum mismatch for %s: got %s, manifest has %s", filepath.Base(localPath), sum, m.SHA256)
+	}
+	return nil
+}
+
+// remoteFile is what rclone lsjson --stat reports about a remote path
+type remoteFile struct {
+	IsDir   bool   `json:"IsDir"`
+	Size    int64  `json:"Size"`
+	ModTime string `json:"ModTime"`
+}
+
+// remoteStat looks up a remote path, such as a backup file or a mydumper
+// directory
+func (s *Service) remoteStat(ctx context.Context, remote string) (remoteFile, error) {
+	var stat remoteFile
+	args := append([]string{"lsjson", "--stat", remote}, s.configArgs()...)
+	output, err := s.rcloneCommand(ctx, args...).Output()
+	if err != nil {
+		return stat, fmt.Errorf("backup not found at %s: %w", remote, err)
+	}
+
+	if err := json.Unmarshal(output, &stat); err != nil {
+		return stat, fmt.Errorf("failed to parse rclone output: %w", err)
+	}
+	return stat, nil
+}
+
+// configArgs points rclone at the configured rclone.conf
+func (s *Service) configArgs() []string {
+	if s.config.RcloneConfigPath == "" {
+		return nil
+	}
+	return []string{"--config", s.config.RcloneConfigPath}
+}
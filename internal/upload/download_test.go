@@ -0,0 +1,303 @@
+package upload
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// fakeRclone stands in for rclone: remote:path is $FAKE_REMOTE/path, every
+// file was modified at 2025-07-05T10:30:15Z, and every cat is logged to
+// $FAKE_REMOTE_LOG
+const fakeRclone = `#!/bin/sh
+cmd="$1"
+shift
+[ "$1" = "--stat" ] && shift
+file="$FAKE_REMOTE/${1#*:}"
+case "$cmd" in
+copy)
+	[ -f "$file" ] || exit 1
+	cp "$file" "$2" ;;
+lsjson)
+	[ -f "$file" ] || exit 3
+	echo "{\"IsDir\":false,\"Size\":$(wc -c < "$file"),\"ModTime\":\"2025-07-05T10:30:15Z\"}" ;;
+cat)
+	echo "${file##*/} $3" >> "$FAKE_REMOTE_LOG"
+	tail -c +$(($3 + 1)) "$file" | head -c "$5" ;;
+esac
+`
+
+// fakeRemote returns a service whose rclone is fakeRclone, the directory
+// behind its remote and a function returning the cats run so far
+func fakeRemote(t *testing.T) (*Service, string, func() []string) {
+	t.Helper()
+	dir := t.TempDir()
+	cli := filepath.Join(dir, "rclone")
+	if err := os.WriteFile(cli, []byte(fakeRclone), 0755); err != nil {
+		t.Fatal(err)
+	}
+	remoteDir := filepath.Join(dir, "remote")
+	if err := os.MkdirAll(remoteDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	catLog := filepath.Join(dir, "cat.log")
+	t.Setenv("FAKE_REMOTE", remoteDir)
+	t.Setenv("FAKE_REMOTE_LOG", catLog)
+
+	// Small chunks, so a few bytes make several of them
+	previous := downloadChunkSize
+	downloadChunkSize = 4
+	t.Cleanup(func() { downloadChunkSize = previous })
+
+	service := NewService(&config.UploadConfig{RclonePath: cli}, logger.NewLogger("error"))
+	cats := func() []string {
+		data, _ := os.ReadFile(catLog)
+		os.Remove(catLog)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if lines[0] == "" {
+			return nil
+		}
+		// Streams run in any order
+		sort.Strings(lines)
+		return lines
+	}
+	return service, remoteDir, cats
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDownload(t *testing.T) {
+	content := []byte("0123456789")
+	const artifact = "app-2025-07-05_10-30-15.sql.gz"
+	chunks := func(offsets ...int) []string {
+		var cats []string
+		for _, offset := range offsets {
+			cats = append(cats, artifact+" "+strconv.Itoa(offset))
+		}
+		return cats
+	}
+	state := func(size int64, modTime string, done ...int) *partialDownload {
+		return &partialDownload{Remote: "remote:" + artifact, Size: size, ModTime: modTime, ChunkSize: 4, Done: done}
+	}
+
+	tests := []struct {
+		name     string
+		checksum bool // the manifest records the SHA-256 of content
+		part     []byte
+		state    *partialDownload
+		wantCats []string
+		wantErr  string
+	}{
+		{
+			name:     "fresh download",
+			wantCats: chunks(0, 4, 8),
+		},
+		{
+			name:     "resumes after completed chunks",
+			part:     []byte("0123"),
+			state:    state(10, "2025-07-05T10:30:15Z", 0),
+			wantCats: chunks(4, 8),
+		},
+		{
+			name:     "remote size changed",
+			part:     []byte("012345678"),
+			state:    state(9, "2025-07-05T10:30:15Z", 0, 1, 2),
+			wantCats: chunks(0, 4, 8),
+		},
+		{
+			name:     "remote modification time changed",
+			part:     []byte("xxxx"),
+			state:    state(10, "2025-07-04T10:30:15Z", 0),
+			wantCats: chunks(0, 4, 8),
+		},
+		{
+			name:     "truncates the tail of a larger earlier download",
+			part:     []byte("0123456789abcdef"),
+			wantCats: chunks(0, 4, 8),
+		},
+		{
+			name:     "checksum mismatch discards resumed chunks",
+			checksum: true,
+			part:     []byte("xxxx"),
+			state:    state(10, "2025-07-05T10:30:15Z", 0),
+			wantCats: chunks(4, 8),
+			wantErr:  "checksum mismatch",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, remoteDir, cats := fakeRemote(t)
+			writeFile(t, filepath.Join(remoteDir, artifact), content)
+			if tt.checksum {
+				sum, err := manifest.FileChecksum(filepath.Join(remoteDir, artifact))
+				if err != nil {
+					t.Fatal(err)
+				}
+				m := &manifest.Manifest{Database: "app", Artifact: artifact, SHA256: sum}
+				if _, err := m.Write(filepath.Join(remoteDir, artifact)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			localDir := t.TempDir()
+			part := filepath.Join(localDir, artifact) + PartSuffix
+			if tt.part != nil {
+				writeFile(t, part, tt.part)
+			}
+			if tt.state != nil {
+				if err := writePartial(part+partialStateSuffix, tt.state); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			localPath, err := service.Download(context.Background(), "remote:"+artifact, localDir, 2)
+			if got := cats(); !slices.Equal(got, tt.wantCats) {
+				t.Errorf("cats = %q, want %q", got, tt.wantCats)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				// Nothing of the bad download is resumed
+				for _, path := range []string{part, part + partialStateSuffix} {
+					if _, err := os.Stat(path); !os.IsNotExist(err) {
+						t.Errorf("%s kept after a checksum mismatch", filepath.Base(path))
+					}
+				}
+				localPath, err = service.Download(context.Background(), "remote:"+artifact, localDir, 2)
+				if got, want := len(cats()), 3; got != want {
+					t.Errorf("retry ran %d cats, want %d", got, want)
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got, _ := os.ReadFile(localPath); string(got) != string(content) {
+				t.Errorf("downloaded %q, want %q", got, content)
+			}
+			for _, path := range []string{part, part + partialStateSuffix} {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("%s kept after the download finished", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadParts(t *testing.T) {
+	service, remoteDir, cats := fakeRemote(t)
+	const artifact = "app-2025-07-05_10-30-15.sql.gz"
+	contents := []string{"0123456", "789"}
+
+	m := &manifest.Manifest{Database: "app", Artifact: artifact}
+	for i, content := range contents {
+		path := manifest.PartName(filepath.Join(remoteDir, artifact), i)
+		writeFile(t, path, []byte(content))
+		sum, err := manifest.FileChecksum(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.Parts = append(m.Parts, manifest.Part{Name: filepath.Base(path), Size: int64(len(content)), SHA256: sum})
+	}
+	if _, err := m.Write(filepath.Join(remoteDir, artifact)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both parts were fetched before, but the second one's bytes were
+	// corrupted
+	localDir := t.TempDir()
+	part := filepath.Join(localDir, artifact) + PartSuffix
+	writeFile(t, part, []byte("0123456x89"))
+	done := [][]int{{0, 1}, {0}}
+	for i, content := range contents {
+		state := &partialDownload{Remote: "remote:" + manifest.PartName(artifact, i), Size: int64(len(content)), ModTime: "2025-07-05T10:30:15Z", ChunkSize: 4, Done: done[i]}
+		if err := writePartial(manifest.PartName(part, i)+partialStateSuffix, state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := service.Download(context.Background(), "remote:"+artifact, localDir, 2)
+	if err == nil || !strings.Contains(err.Error(), manifest.PartName(artifact, 1)+": got") {
+		t.Fatalf("err = %v, want a checksum mismatch of the second part", err)
+	}
+	if got := cats(); len(got) != 0 {
+		t.Errorf("cats = %q, want none", got)
+	}
+
+	// Only the bad part is fetched again
+	localPath, err := service.Download(context.Background(), "remote:"+artifact, localDir, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := cats(), []string{manifest.PartName(artifact, 1) + " 0"}; !slices.Equal(got, want) {
+		t.Errorf("cats = %q, want %q", got, want)
+	}
+	if got, _ := os.ReadFile(localPath); string(got) != strings.Join(contents, "") {
+		t.Errorf("downloaded %q, want %q", got, strings.Join(contents, ""))
+	}
+
+	// A part that does not match the manifest is not downloaded at all
+	writeFile(t, manifest.PartName(filepath.Join(remoteDir, artifact), 1), []byte("78"))
+	if _, err := service.Download(context.Background(), "remote:"+artifact, t.TempDir(), 2); err == nil || !strings.Contains(err.Error(), "the manifest has 3") {
+		t.Errorf("err = %v, want a part size mismatch", err)
+	}
+}
+
+func TestExpirePartial(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	old := now.Add(-PartialMaxAge - time.Hour)
+
+	files := map[string]time.Time{
+		"abandoned.sql.gz.part":      old,
+		"abandoned.sql.gz.part.json": old,
+		"split.sql.gz.part":          old,
+		"split.sql.gz.part.001.json": old,
+		"split.sql.gz.part.002.json": now, // still being written
+		"orphan.sql.gz.part.json":    old,
+		"recent.sql.gz.part":         now,
+		"recent.sql.gz.part.json":    old,
+		"done.sql.gz":                old,
+		"done.sql.gz.manifest.json":  old,
+	}
+	for name, modTime := range files {
+		path := filepath.Join(dir, name)
+		writeFile(t, path, nil)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := ExpirePartial(dir, PartialMaxAge, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(dir, "abandoned.sql.gz.part"), filepath.Join(dir, "orphan.sql.gz.part")}
+	if !slices.Equal(removed, want) {
+		t.Errorf("removed = %q, want %q", removed, want)
+	}
+	for name := range files {
+		_, err := os.Stat(filepath.Join(dir, name))
+		gone := strings.HasPrefix(name, "abandoned.") || strings.HasPrefix(name, "orphan.")
+		if gone != os.IsNotExist(err) {
+			t.Errorf("%s removed = %v, want %v", name, os.IsNotExist(err), gone)
+		}
+	}
+}
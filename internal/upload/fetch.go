@@ -0,0 +1,148 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/pkg/database"
+)
+
+// backupIDLayout is the timestamp at the end of a backup ID
+const backupIDLayout = "2006-01-02_15-04-05"
+
+// RemoteBackupDir returns where a mydumper directory uploaded as a tree is
+// stored below destination: {destination}/{database}/{YYYY-MM}/{id}
+func RemoteBackupDir(destination, dbName, id string) (string, error) {
+	pathName := database.PathName(dbName)
+	stamp := strings.TrimPrefix(id, pathName+"-")
+	created, err := time.Parse(backupIDLayout, stamp)
+	if stamp == id || err != nil {
+		return "", fmt.Errorf("backup ID %s is not a backup of %s", id, dbName)
+	}
+	localPath := filepath.Join(pathName, created.Format("2006-01"), id)
+	return remotePath(destination, localPath, true), nil
+}
+
+// FetchTable downloads the files one table needs from a mydumper directory
+// in the cloud into localDir: the backup metadata, the database and table
+// schema, and the table's data files
+func (s *Service) FetchTable(ctx context.Context, remoteDir, dbName, table, localDir string) error {
+	prefix := escapeGlob(dbName)
+	args := []string{
+		"copy", remoteDir, localDir,
+		"--include", "/metadata",
+		"--include", "/" + prefix + "-schema-create.sql*",
+		"--include", "/" + prefix + "." + escapeGlob(table) + "-schema*",
+		"--include", "/" + prefix + "." + escapeGlob(table) + ".*",
+	}
+	args = append(args, s.tuningArgs()...)
+	args = append(args, s.configArgs()...)
+
+	fetchCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
+	defer cancel()
+	if output, err := s.rcloneCommand(fetchCtx, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("rclone command failed: %w (output: %s)", err, string(output))
+	}
+
+	schemas, _ := filepath.Glob(filepath.Join(escapeGlob(localDir), prefix+"."+escapeGlob(table)+"-schema.sql*"))
+	if len(schemas) == 0 {
+		os.RemoveAll(localDir)
+		return fmt.Errorf("table %s not found in %s", table, remoteDir)
+	}
+	return nil
+}
+
+// RemoteArtifactPath returns where a file artifact uploaded to destination
+// is stored: {destination}/{database}/{YYYY-MM}/{name}
+func RemoteArtifactPath(destination, localPath string) string {
+	return strings.TrimSuffix(remotePath(destination, localPath, false), "/") + "/" + filepath.Base(localPath)
+}
+
+// RemoteRange reads byte ranges of a file in the cloud with rclone cat, so
+// parts of an archive can be read without downloading all of it
+type RemoteRange struct {
+	ctx     context.Context
+	service *Service
+	remote  string
+	parts   []manifest.Part
+}
+
+// RangeReader returns a RemoteRange of remote, or of the parts it was
+// uploaded in
+func (s *Service) RangeReader(ctx context.Context, remote string, parts []manifest.Part) *RemoteRange {
+	return &RemoteRange{ctx: ctx, service: s, remote: remote, parts: parts}
+}
+
+// ReadRange reads length bytes at offset, counted from the end of the file
+// when negative
+func (r *RemoteRange) ReadRange(offset, length int64) ([]byte, error) {
+	if len(r.parts) == 0 {
+		return r.readRange(r.remote, offset, length)
+	}
+
+	var size int64
+	for _, part := range r.parts {
+		size += part.Size
+	}
+	if offset < 0 {
+		offset += size
+	}
+	if offset < 0 || offset+length > size {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	data := make([]byte, 0, length)
+	var base int64
+	for i, part := range r.parts {
+		from, to := max(offset, base), min(offset+length, base+part.Size)
+		if from < to {
+			chunk, err := r.readRange(manifest.PartName(r.remote, i), from-base, to-from)
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, chunk...)
+		}
+		base += part.Size
+	}
+	return data, nil
+}
+
+// readRange reads length bytes of one remote file at offset
+func (r *RemoteRange) readRange(remote string, offset, length int64) ([]byte, error) {
+	args := []string{"cat", remote, "--offset", strconv.FormatInt(offset, 10), "--count", strconv.FormatInt(length, 10)}
+	args = append(args, r.service.configArgs()...)
+
+	ctx, cancel := context.WithTimeout(r.ctx, time.Duration(r.service.config.Timeout)*time.Second)
+	defer cancel()
+	var stderr bytes.Buffer
+	cmd := r.service.rcloneCommand(ctx, args...)
+	cmd.Stderr = &stderr
+	data, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("rclone cat failed: %w (output: %s)", err, strings.TrimSpace(stderr.String()))
+	}
+	if int64(len(data)) != length {
+		return nil, fmt.Errorf("rclone cat returned %d of %d bytes of %s", len(data), length, remote)
+	}
+	return data, nil
+}
+
+// escapeGlob quotes the characters rclone filters treat as patterns
+func escapeGlob(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(`\*?[]{}`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,127 @@
+package upload
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/compression"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/encryption"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// policyFor returns the policy of uploads to destination. Destinations
+// other than the configured two get the backup as it is.
+func (s *Service) policyFor(destination string) config.DestinationPolicy {
+	switch destination {
+	case s.config.Destination:
+		return s.config.DestinationPolicy
+	case s.config.FallbackDestination:
+		return s.config.FallbackPolicy
+	}
+	return config.DestinationPolicy{}
+}
+
+// uploadCopy uploads the copy of a backup file the policy of destination
+// asks for and records it in the backup's manifest, so the manifest
+// uploaded next describes the copy. It returns false, uploading nothing,
+// when the backup already is what the policy asks for.
+func (s *Service) uploadCopy(ctx context.Context, filePath, destination string) (bool, error) {
+	policy := s.policyFor(destination)
+	if !policy.Changes() {
+		return false, nil
+	}
+
+	dir, err := os.MkdirTemp(filepath.Dir(filePath), ".tenangdb-copy-")
+	if err != nil {
+		return false, fmt.Errorf("failed to create copy directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	copyPath, c, err := makeCopy(ctx, filePath, dir, policy)
+	if err != nil || c == nil {
+		return false, err
+	}
+	c.Destination = destination
+
+	s.logger.WithField("backup_file", filepath.Base(filePath)).WithField("copy", c.Artifact).
+		WithField("destination", destination).Info("Uploading copy made for destination policy")
+	// Copies go in one piece, split_size_gb only splits the backup itself
+	if err := s.uploadFile(ctx, copyPath, remotePath(destination, filePath, false)); err != nil {
+		return true, err
+	}
+
+	if m, err := manifest.Load(filePath); err == nil {
+		m.SetCopy(*c)
+		if _, err := m.Write(filePath); err != nil {
+			return true, fmt.Errorf("failed to update manifest: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// makeCopy writes the copy of a backup archive policy asks for into dir. It
+// returns a nil copy when the archive already is what policy asks for.
+func makeCopy(ctx context.Context, filePath, dir string, policy config.DestinationPolicy) (string, *manifest.Copy, error) {
+	format := compression.ArchiveFormat(filePath)
+	if format == "" || encryption.IsEncrypted(filePath) {
+		return "", nil, fmt.Errorf("%s is not an unencrypted tar.gz or tar.zst archive, destination policies cannot change it", filepath.Base(filePath))
+	}
+
+	path := filePath
+	c := &manifest.Copy{Compression: format}
+	if policy.Compression != "" && policy.Compression != format {
+		name := strings.TrimSuffix(filepath.Base(filePath), format) + policy.Compression
+		path = filepath.Join(dir, name)
+		if err := compression.Recompress(filePath, path, policy.Compression); err != nil {
+			return "", nil, err
+		}
+		c.Compression = policy.Compression
+	}
+
+	if policy.Encryption.Enabled {
+		key, err := encryption.NewKey(ctx, &policy.Encryption)
+		if err != nil {
+			return "", nil, err
+		}
+		encryptedPath := filepath.Join(dir, filepath.Base(path)+encryption.Suffix)
+		if err := key.EncryptFile(path, encryptedPath); err != nil {
+			return "", nil, err
+		}
+		path = encryptedPath
+		c.Encryption = &manifest.Encryption{Provider: policy.Encryption.Provider, KeyID: policy.Encryption.KeyID, Algorithm: encryption.Algorithm, Mode: config.EncryptionModeStream}
+	}
+
+	if path == filePath {
+		return "", nil, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, err
+	}
+	sum, err := manifest.FileChecksum(path)
+	if err != nil {
+		return "", nil, err
+	}
+	c.Artifact, c.SizeBytes, c.SHA256 = filepath.Base(path), info.Size(), sum
+	return path, c, nil
+}
+
+// uploadCopyManifest uploads the manifest of the copy destination holds in
+// place of the backup manifestPath describes
+func (s *Service) uploadCopyManifest(ctx context.Context, manifestPath string, m *manifest.Manifest, c *manifest.Copy) error {
+	dir, err := os.MkdirTemp(filepath.Dir(manifestPath), ".tenangdb-copy-")
+	if err != nil {
+		return fmt.Errorf("failed to create copy directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	copyManifest, err := m.ForCopy(c).Write(filepath.Join(dir, c.Artifact))
+	if err != nil {
+		return err
+	}
+	return s.uploadFile(ctx, copyManifest, remotePath(c.Destination, manifestPath, false))
+}
@@ -0,0 +1,142 @@
+package upload
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/compression"
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/encryption"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// fakeCopyRclone stands in for rclone copy: remote:path is
+// $FAKE_REMOTE/remote/path
+const fakeCopyRclone = `#!/bin/sh
+[ "$1" = "copy" ] || exit 1
+dest="$FAKE_REMOTE/${3%%:*}/${3#*:}"
+mkdir -p "$dest"
+cp "$2" "$dest/"
+`
+
+// fakeVault stands in for the vault CLI: the wrapped key is the key name
+// and the base64 plaintext
+const fakeVault = `#!/bin/sh
+path="$3"
+in=$(cat)
+case "$path" in
+*/encrypt/*) echo "vault:${path##*/}:$in" ;;
+*/decrypt/*) echo "${in#vault:${path##*/}:}" ;;
+esac
+`
+
+func TestUploadToDestinationPolicies(t *testing.T) {
+	dir := t.TempDir()
+	for name, script := range map[string]string{"rclone": fakeCopyRclone, "vault": fakeVault} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	remoteDir := filepath.Join(dir, "remote")
+	t.Setenv("FAKE_REMOTE", remoteDir)
+
+	// A tar.gz backup as the compressor leaves it, with its manifest
+	backupDir := filepath.Join(dir, "backups", "app", "2025-07")
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	const name = "app-2025-07-05_10-30-15"
+	artifact := filepath.Join(backupDir, name+".tar.gz")
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	dump := []byte("INSERT INTO `orders` VALUES (1,'customer-1');\n")
+	if err := tw.WriteHeader(&tar.Header{Name: name + "/app.orders.sql", Mode: 0644, Size: int64(len(dump))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(dump)
+	tw.Close()
+	gz.Close()
+	writeFile(t, artifact, archive.Bytes())
+	m := &manifest.Manifest{Database: "app", Artifact: filepath.Base(artifact), Compression: "tar.gz"}
+	if _, err := m.Write(artifact); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain copy on the NAS, an encrypted zstd one in the cloud
+	cfg := &config.UploadConfig{
+		Enabled:             true,
+		RclonePath:          filepath.Join(dir, "rclone"),
+		Destination:         "nas:backups",
+		FallbackDestination: "cloud:backups",
+		Timeout:             60,
+		RetryCount:          1,
+		FallbackPolicy: config.DestinationPolicy{
+			Compression: "tar.zst",
+			Encryption:  config.EncryptionConfig{Enabled: true, Provider: config.EncryptionProviderVaultTransit, KeyID: "cloud", CLIPath: filepath.Join(dir, "vault"), VaultMount: "transit"},
+		},
+	}
+	service := NewService(cfg, logger.NewLogger("error"))
+	ctx := context.Background()
+	for _, destination := range []string{"nas:backups", "cloud:backups"} {
+		if err := service.UploadTo(ctx, artifact, destination); err != nil {
+			t.Fatal(err)
+		}
+		if err := service.UploadTo(ctx, manifest.PathFor(artifact), destination); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The NAS got the backup itself
+	nasDir := filepath.Join(remoteDir, "nas", "backups", "app", "2025-07")
+	if got, _ := os.ReadFile(filepath.Join(nasDir, name+".tar.gz")); !bytes.Equal(got, archive.Bytes()) {
+		t.Errorf("NAS copy differs from the backup")
+	}
+	if nasManifest, err := manifest.Load(filepath.Join(nasDir, name+".tar.gz")); err != nil || nasManifest.Compression != "tar.gz" || nasManifest.Encryption != nil {
+		t.Errorf("NAS manifest = %+v (err %v), want the backup's", nasManifest, err)
+	}
+
+	// The cloud got an encrypted tar.zst, described by its own manifest
+	cloudDir := filepath.Join(remoteDir, "cloud", "backups", "app", "2025-07")
+	cloudCopy := filepath.Join(cloudDir, name+".tar.zst"+encryption.Suffix)
+	if _, err := os.Stat(filepath.Join(cloudDir, name+".tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("cloud holds the plain backup")
+	}
+	if !encryption.IsEncrypted(cloudCopy) {
+		t.Fatalf("cloud copy %s is not encrypted", filepath.Base(cloudCopy))
+	}
+	cloudManifest, err := manifest.Load(cloudCopy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, _ := manifest.FileChecksum(cloudCopy)
+	if cloudManifest.Artifact != filepath.Base(cloudCopy) || cloudManifest.Compression != "tar.zst" || cloudManifest.Encryption == nil ||
+		cloudManifest.Encryption.KeyID != "cloud" || cloudManifest.SHA256 != sum || cloudManifest.Destination != "cloud:backups" {
+		t.Errorf("cloud manifest = %+v", cloudManifest)
+	}
+	decrypted := filepath.Join(dir, "decrypted.tar.zst")
+	if err := encryption.DecryptFile(ctx, &cfg.FallbackPolicy.Encryption, cloudCopy, decrypted); err != nil {
+		t.Fatal(err)
+	}
+	if format := compression.DetectFormat(decrypted); format != compression.FormatZstd {
+		t.Errorf("decrypted cloud copy is %q, want zstd", format)
+	}
+
+	// The local manifest records which destination holds which copy
+	local, err := manifest.Load(artifact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(local.Copies) != 1 || local.CopyAt("cloud:backups") == nil || local.CopyAt("cloud:backups").Artifact != filepath.Base(cloudCopy) {
+		t.Errorf("local copies = %+v, want the cloud copy only", local.Copies)
+	}
+	if leftovers, _ := filepath.Glob(filepath.Join(backupDir, ".tenangdb-copy-*")); len(leftovers) != 0 {
+		t.Errorf("copy directories left behind: %q", leftovers)
+	}
+}
@@ -6,16 +6,22 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/abdullahainun/tenangdb/internal/config"
 	"github.com/abdullahainun/tenangdb/internal/logger"
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+	"github.com/abdullahainun/tenangdb/internal/runid"
 )
 
 type Service struct {
 	config *config.UploadConfig
 	logger *logger.Logger
+	labels map[string]string
 }
 
 func NewService(config *config.UploadConfig, logger *logger.Logger) *Service {
@@ -25,6 +31,10 @@ func NewService(config *config.UploadConfig, logger *logger.Logger) *Service {
 	}
 }
 
+// artifactTimestamp is the -{YYYY-MM-DD_HH-MM-SS} that follows the
+// database name in backup file names
+var artifactTimestamp = regexp.MustCompile(`-\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}`)
+
 // extractBackupInfo extracts database name and date from backup file path
 // Expected path format: {baseDir}/{database}/{YYYY-MM}/{filename}
 func extractBackupInfo(filePath string) (database, date string) {
@@ -46,19 +56,50 @@ func extractBackupInfo(filePath string) (database, date string) {
 		}
 	}
 	
-	// Fallback: extract database from filename if pattern not found
+	// Fallback: extract database from filename if pattern not found. The
+	// name itself may hold dashes, so cut at the timestamp.
 	filename := filepath.Base(filePath)
-	if dashIndex := strings.Index(filename, "-"); dashIndex > 0 {
-		database = filename[:dashIndex]
+	if m := artifactTimestamp.FindStringIndex(filename); m != nil && m[0] > 0 {
+		database = filename[:m[0]]
 	}
 	
 	return
 }
 
+// Upload copies a backup file or directory to the primary destination
 func (s *Service) Upload(ctx context.Context, filePath string) error {
+	return s.UploadTo(ctx, filePath, s.config.Destination)
+}
+
+// UploadWithFallback uploads to the primary destination and, if that fails
+// after all retries, to upload.fallback_destination. It returns the
+// destination that holds the copy.
+func (s *Service) UploadWithFallback(ctx context.Context, filePath string) (string, error) {
+	err := s.UploadTo(ctx, filePath, s.config.Destination)
+	if err == nil {
+		return s.config.Destination, nil
+	}
+	if s.config.FallbackDestination == "" {
+		return "", err
+	}
+
+	s.logger.WithError(err).WithField("fallback", s.config.FallbackDestination).
+		Warn("⚠️ Upload to primary destination failed, using fallback")
+	if fallbackErr := s.UploadTo(ctx, filePath, s.config.FallbackDestination); fallbackErr != nil {
+		return "", fmt.Errorf("%v; fallback: %w", err, fallbackErr)
+	}
+	return s.config.FallbackDestination, nil
+}
+
+// UploadTo copies a backup file or directory below destination; an empty
+// destination means the primary one
+func (s *Service) UploadTo(ctx context.Context, filePath, destination string) error {
 	if !s.config.Enabled {
 		return nil
 	}
+	if destination == "" {
+		destination = s.config.Destination
+	}
 
 	// Check if this is a directory or file
 	info, err := os.Stat(filePath)
@@ -67,13 +108,87 @@ func (s *Service) Upload(ctx context.Context, filePath string) error {
 	}
 
 	if info.IsDir() {
-		return s.uploadDirectory(ctx, filePath)
-	} else {
-		return s.uploadFile(ctx, filePath)
+		return s.uploadDirectory(ctx, filePath, destination)
+	}
+
+	// A destination holding a copy made for its policy gets the manifest
+	// of the copy
+	if manifest.IsManifest(filePath) {
+		if m, err := manifest.Read(filePath); err == nil {
+			if c := m.CopyAt(destination); c != nil {
+				return s.uploadCopyManifest(ctx, filePath, m, c)
+			}
+		}
+	} else if uploaded, err := s.uploadCopy(ctx, filePath, destination); uploaded || err != nil {
+		return err
+	}
+
+	// The manifest lists the parts, restores can't find them without it
+	m, err := manifest.Load(filePath)
+	if split := s.splitSize(); split > 0 && info.Size() > split {
+		if err == nil {
+			return s.uploadParts(ctx, filePath, destination, m)
+		}
+		s.logger.WithField("backup_file", filepath.Base(filePath)).Warn("⚠️ No manifest to list parts in, uploading in one piece")
+	}
+	if err == nil && len(m.Parts) > 0 {
+		m.Parts = nil
+		if _, err := m.Write(filePath); err != nil {
+			return fmt.Errorf("failed to update manifest: %w", err)
+		}
+	}
+	return s.uploadFile(ctx, filePath, remotePath(destination, filePath, false))
+}
+
+// CopyRemote copies a backup uploaded below from, with the parts it was
+// split into, to the same place below to. rclone copies between the
+// remotes, server side where both are on the same backend, so the local
+// copy of the backup is not needed.
+func (s *Service) CopyRemote(ctx context.Context, artifactPath, from, to string) error {
+	name := "/" + escapeGlob(filepath.Base(artifactPath))
+	args := s.copyArgs(ctx, remotePath(from, artifactPath, false), remotePath(to, artifactPath, false))
+	args = append(args, "--include", name, "--include", name+"/**", "--include", name+".[0-9][0-9][0-9]")
+	log := s.logger.WithField("backup_file", filepath.Base(artifactPath))
+
+	var lastErr error
+	for attempt := 1; attempt <= s.config.RetryCount; attempt++ {
+		if attempt > 1 {
+			log.WithField("attempt", attempt).Info("Retrying copy")
+			time.Sleep(time.Second * 10)
+		}
+
+		copyCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
+		output, err := s.rcloneCommand(copyCtx, args...).CombinedOutput()
+		cancel()
+		if err == nil {
+			return nil
+		}
+		lastErr = fmt.Errorf("rclone command failed: %w (output: %s)", err, string(output))
+		log.WithError(lastErr).WithField("attempt", attempt).Warn("Copy attempt failed")
+	}
+
+	return fmt.Errorf("copy failed after %d attempts: %w", s.config.RetryCount, lastErr)
+}
+
+// UploadBinlogs copies the binary log archive of server to
+// {destination}/.binlogs/{server} at the primary destination. rclone skips
+// the logs already there, so logs a failed upload missed go with the next.
+func (s *Service) UploadBinlogs(ctx context.Context, archiveDir, server string) error {
+	if !s.config.Enabled {
+		return nil
 	}
+	return s.uploadFile(ctx, archiveDir, BinlogRemoteDir(s.config.Destination, server))
 }
 
-func (s *Service) uploadFile(ctx context.Context, filePath string) error {
+// BinlogRemoteDir is where the binary logs of server are archived below
+// destination
+func BinlogRemoteDir(destination, server string) string {
+	return strings.TrimSuffix(destination, "/") + "/.binlogs/" + server
+}
+
+// uploadFile copies filePath, or the contents of a directory, into the
+// remote directory with retries
+func (s *Service) uploadFile(ctx context.Context, filePath, remote string) error {
 	fileName := filepath.Base(filePath)
 	log := s.logger.WithField("backup_file", fileName)
 
@@ -87,7 +202,7 @@ func (s *Service) uploadFile(ctx context.Context, filePath string) error {
 			time.Sleep(time.Second * 10)
 		}
 
-		if err := s.uploadSingleFile(ctx, filePath); err == nil {
+		if err := s.uploadSingleFile(ctx, filePath, remote); err == nil {
 			log.Info("☁️  Upload completed successfully")
 			return nil
 		} else {
@@ -99,7 +214,7 @@ func (s *Service) uploadFile(ctx context.Context, filePath string) error {
 	return fmt.Errorf("upload failed after %d attempts: %w", s.config.RetryCount, lastErr)
 }
 
-func (s *Service) uploadDirectory(ctx context.Context, dirPath string) error {
+func (s *Service) uploadDirectory(ctx context.Context, dirPath, destination string) error {
 	dirName := filepath.Base(dirPath)
 	log := s.logger.WithField("backup_directory", dirName)
 
@@ -113,7 +228,7 @@ func (s *Service) uploadDirectory(ctx context.Context, dirPath string) error {
 			time.Sleep(time.Second * 10)
 		}
 
-		if err := s.uploadDirectoryStructure(ctx, dirPath); err == nil {
+		if err := s.uploadDirectoryStructure(ctx, dirPath, destination); err == nil {
 			log.Info("☁️  Upload completed successfully")
 			return nil
 		} else {
@@ -125,39 +240,29 @@ func (s *Service) uploadDirectory(ctx context.Context, dirPath string) error {
 	return fmt.Errorf("upload failed after %d attempts: %w", s.config.RetryCount, lastErr)
 }
 
-func (s *Service) uploadSingleFile(ctx context.Context, filePath string) error {
+func (s *Service) uploadSingleFile(ctx context.Context, filePath, remote string) error {
 	// Create context with timeout
 	uploadCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
 	defer cancel()
 
-	// Extract database and date from backup path
-	database, date := extractBackupInfo(filePath)
-	
-	// Construct organized destination path
-	destination := s.config.Destination
-	if database != "" {
-		destination = strings.TrimSuffix(destination, "/") + "/" + database
-		if date != "" {
-			destination = destination + "/" + date
-		}
-	}
+	cmd := s.rcloneCommand(uploadCtx, s.copyArgs(ctx, filePath, remote)...)
 
-	// Build rclone command
-	args := []string{
-		"copy",
-		filePath,
-		destination,
-		"--progress",
-		"--stats", "10s",
-		"--checksum",
+	// Execute command
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rclone command failed: %w (output: %s)", err, string(output))
 	}
 
-	// Add config path if specified
-	if s.config.RcloneConfigPath != "" {
-		args = append(args, "--config", s.config.RcloneConfigPath)
-	}
+	return nil
+}
 
-	cmd := exec.CommandContext(uploadCtx, s.config.RclonePath, args...)
+func (s *Service) uploadDirectoryStructure(ctx context.Context, dirPath, destination string) error {
+	// Create context with timeout
+	uploadCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
+	defer cancel()
+
+	// Copy the entire directory structure
+	cmd := s.rcloneCommand(uploadCtx, s.copyArgs(ctx, dirPath, remotePath(destination, dirPath, true))...)
 
 	// Execute command
 	output, err := cmd.CombinedOutput()
@@ -168,53 +273,127 @@ func (s *Service) uploadSingleFile(ctx context.Context, filePath string) error {
 	return nil
 }
 
-func (s *Service) uploadDirectoryStructure(ctx context.Context, dirPath string) error {
-	// Create context with timeout
-	uploadCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
-	defer cancel()
+// RemotePath returns the organized destination a backup is uploaded to:
+// {destination}/{database}/{YYYY-MM}, plus the directory name for
+// directory backups
+func (s *Service) RemotePath(localPath string, isDir bool) string {
+	return remotePath(s.config.Destination, localPath, isDir)
+}
+
+// remotePath organizes localPath below the given destination
+func remotePath(destination, localPath string, isDir bool) string {
+	// Extract database and date from backup path
+	database, date := extractBackupInfo(localPath)
 
-	// Extract database and date from directory path
-	database, date := extractBackupInfo(dirPath)
-	
-	// Get directory name to preserve in cloud
-	dirName := filepath.Base(dirPath)
-	
-	// Construct organized destination path including directory name
-	destination := s.config.Destination
 	if database != "" {
 		destination = strings.TrimSuffix(destination, "/") + "/" + database
 		if date != "" {
-			destination = destination + "/" + date + "/" + dirName
+			destination = destination + "/" + date
+			if isDir {
+				// Preserve the directory name in the cloud
+				destination = destination + "/" + filepath.Base(localPath)
+			}
 		}
 	}
+	return destination
+}
+
+// CopyCommand returns the rclone command line that uploads localPath
+func (s *Service) CopyCommand(ctx context.Context, localPath string, isDir bool) []string {
+	args := s.copyArgs(ctx, localPath, s.RemotePath(localPath, isDir))
+	return append([]string{s.config.RclonePath}, args...)
+}
 
-	// Build rclone command to copy entire directory structure
+// copyArgs builds the rclone copy arguments
+func (s *Service) copyArgs(ctx context.Context, source, destination string) []string {
 	args := []string{
 		"copy",
-		dirPath,
+		source,
 		destination,
 		"--progress",
 		"--stats", "10s",
 		"--checksum",
 	}
+	args = append(args, s.metadataArgs(ctx)...)
+	args = append(args, s.tuningArgs()...)
 
 	// Add config path if specified
 	if s.config.RcloneConfigPath != "" {
 		args = append(args, "--config", s.config.RcloneConfigPath)
 	}
+	return args
+}
 
-	cmd := exec.CommandContext(uploadCtx, s.config.RclonePath, args...)
+// rcloneCommand prepares an rclone invocation that goes through the
+// configured proxy
+func (s *Service) rcloneCommand(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, s.config.RclonePath, args...)
+	if proxyEnv := s.config.Proxy.Env(); proxyEnv != nil {
+		cmd.Env = append(os.Environ(), proxyEnv...)
+	}
+	s.logger.WithField("command", strings.Join(cmd.Args, " ")).Debug("Running external command")
+	return cmd
+}
 
-	// Execute command
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("rclone command failed: %w (output: %s)", err, string(output))
+// tuningArgs passes upload.tuning to rclone. Backend flags only affect
+// remotes of their type, so the S3 and Drive options can be set together.
+func (s *Service) tuningArgs() []string {
+	t := s.config.Tuning
+	var args []string
+	if t.Transfers > 0 {
+		args = append(args, "--transfers", strconv.Itoa(t.Transfers))
+	}
+	if t.Checkers > 0 {
+		args = append(args, "--checkers", strconv.Itoa(t.Checkers))
+	}
+	if t.S3ChunkSize != "" {
+		args = append(args, "--s3-chunk-size", t.S3ChunkSize)
+	}
+	if t.S3UploadConcurrency > 0 {
+		args = append(args, "--s3-upload-concurrency", strconv.Itoa(t.S3UploadConcurrency))
 	}
+	if t.DriveChunkSize != "" {
+		args = append(args, "--drive-chunk-size", t.DriveChunkSize)
+	}
+	return args
+}
 
-	return nil
+// SetLabels adds backup labels to the object metadata of uploads
+func (s *Service) SetLabels(labels map[string]string) {
+	s.labels = labels
 }
 
-func (s *Service) CleanupRemote(ctx context.Context, retentionDays int) error {
+// metadataArgs tags uploaded objects with the run ID and labels when
+// upload.metadata is enabled; this needs rclone 1.59+ and a backend that
+// stores metadata
+func (s *Service) metadataArgs(ctx context.Context) []string {
+	if !s.config.Metadata {
+		return nil
+	}
+
+	var args []string
+	if runID := runid.FromContext(ctx); runID != "" {
+		args = append(args, "--metadata-set", "tenangdb-run-id="+runID)
+	}
+
+	keys := make([]string, 0, len(s.labels))
+	for key := range s.labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--metadata-set", "tenangdb-label-"+key+"="+s.labels[key])
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+	return append([]string{"--metadata"}, args...)
+}
+
+// CleanupRemote deletes cloud backups older than retentionDays, except the
+// local backup paths in keep, such as pinned backups
+func (s *Service) CleanupRemote(ctx context.Context, retentionDays int, keep []string) error {
 	if !s.config.Enabled {
 		return nil
 	}
@@ -232,13 +411,14 @@ func (s *Service) CleanupRemote(ctx context.Context, retentionDays int) error {
 		"--min-age", fmt.Sprintf("%dd", retentionDays),
 		"--dry-run", // Remove this flag in production
 	}
+	args = append(args, s.excludeArgs(keep)...)
 
 	// Add config path if specified
 	if s.config.RcloneConfigPath != "" {
 		args = append(args, "--config", s.config.RcloneConfigPath)
 	}
 
-	cmd := exec.CommandContext(cleanupCtx, s.config.RclonePath, args...)
+	cmd := s.rcloneCommand(cleanupCtx, args...)
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
@@ -248,3 +428,38 @@ func (s *Service) CleanupRemote(ctx context.Context, retentionDays int) error {
 	s.logger.WithField("output", string(output)).Info("Remote cleanup completed")
 	return nil
 }
+
+// excludeArgs builds rclone filters that leave the uploaded copies of
+// localPaths alone, including the parts of split uploads
+func (s *Service) excludeArgs(localPaths []string) []string {
+	root := strings.TrimSuffix(s.config.Destination, "/") + "/"
+
+	var args []string
+	for _, localPath := range localPaths {
+		info, err := os.Stat(localPath)
+		if err != nil {
+			continue
+		}
+
+		remote := s.RemotePath(localPath, info.IsDir())
+		if !strings.HasPrefix(remote, root) {
+			continue
+		}
+		pattern := "/" + strings.TrimPrefix(remote, root)
+		if info.IsDir() {
+			pattern += "/**"
+		} else {
+			// The destination may hold a copy made for its policy
+			name := filepath.Base(localPath)
+			if m, err := manifest.Load(localPath); err == nil {
+				if c := m.CopyAt(s.config.Destination); c != nil {
+					name = c.Artifact
+				}
+			}
+			pattern += "/" + name
+			args = append(args, "--exclude", pattern+".[0-9][0-9][0-9]")
+		}
+		args = append(args, "--exclude", pattern)
+	}
+	return args
+}
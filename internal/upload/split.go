@@ -0,0 +1,92 @@
+package upload
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/manifest"
+)
+
+// splitSize returns the part size of upload.split_size_gb, 0 when files
+// are uploaded whole
+func (s *Service) splitSize() int64 {
+	return int64(s.config.SplitSizeGB) << 30
+}
+
+// PartSizes returns the sizes of the parts a file of size bytes is uploaded
+// in, nil if it is uploaded whole
+func (s *Service) PartSizes(size int64) []int64 {
+	partSize := s.splitSize()
+	if partSize <= 0 || size <= partSize {
+		return nil
+	}
+	var sizes []int64
+	for offset := int64(0); offset < size; offset += partSize {
+		sizes = append(sizes, min(partSize, size-offset))
+	}
+	return sizes
+}
+
+// uploadParts uploads a file larger than upload.split_size_gb as
+// {artifact}.001, {artifact}.002 and so on, retrying each part on its own,
+// and lists the parts with their checksums in the artifact's manifest so
+// restores can put it back together
+func (s *Service) uploadParts(ctx context.Context, filePath, destination string, m *manifest.Manifest) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	fileName := filepath.Base(filePath)
+	partSize := s.splitSize()
+	count := int((info.Size() + partSize - 1) / partSize)
+	log := s.logger.WithField("backup_file", fileName).WithField("parts", count)
+	log.Info("☁️  Uploading " + fileName + " to cloud in parts")
+
+	var parts []manifest.Part
+	for i := 0; i < count; i++ {
+		offset := int64(i) * partSize
+		part := manifest.Part{Name: manifest.PartName(fileName, i), Size: min(partSize, info.Size()-offset)}
+
+		var lastErr error
+		for attempt := 1; attempt <= s.config.RetryCount; attempt++ {
+			if attempt > 1 {
+				log.WithField("part", part.Name).WithField("attempt", attempt).Info("Retrying part upload")
+				time.Sleep(time.Second * 10)
+			}
+
+			hash := sha256.New()
+			lastErr = s.rcat(ctx, manifest.PartName(filePath, i), destination, part.Size, func(w io.Writer) error {
+				_, err := io.Copy(io.MultiWriter(w, hash), io.NewSectionReader(file, offset, part.Size))
+				return err
+			})
+			if lastErr == nil {
+				part.SHA256 = hex.EncodeToString(hash.Sum(nil))
+				break
+			}
+			log.WithError(lastErr).WithField("part", part.Name).WithField("attempt", attempt).Warn("Part upload attempt failed")
+		}
+		if lastErr != nil {
+			return fmt.Errorf("upload of %s failed after %d attempts: %w", part.Name, s.config.RetryCount, lastErr)
+		}
+		parts = append(parts, part)
+	}
+
+	m.Parts = parts
+	if _, err := m.Write(filePath); err != nil {
+		return fmt.Errorf("failed to record parts in manifest: %w", err)
+	}
+	log.Info("☁️  Upload completed successfully")
+	return nil
+}
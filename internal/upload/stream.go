@@ -0,0 +1,118 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamWithFallback uploads the output of write as the remote copy of
+// artifactPath, which need not exist locally, trying the fallback
+// destination like UploadWithFallback. It returns the destination that
+// holds the copy.
+func (s *Service) StreamWithFallback(ctx context.Context, artifactPath string, write func(io.Writer) error) (string, error) {
+	err := s.streamTo(ctx, artifactPath, s.config.Destination, write)
+	if err == nil {
+		return s.config.Destination, nil
+	}
+	if s.config.FallbackDestination == "" {
+		return "", err
+	}
+
+	s.logger.WithError(err).WithField("fallback", s.config.FallbackDestination).
+		Warn("⚠️ Upload to primary destination failed, using fallback")
+	if fallbackErr := s.streamTo(ctx, artifactPath, s.config.FallbackDestination, write); fallbackErr != nil {
+		return "", fmt.Errorf("%v; fallback: %w", err, fallbackErr)
+	}
+	return s.config.FallbackDestination, nil
+}
+
+// streamTo runs write into rclone rcat, retrying the whole stream on
+// failure since a partial stream cannot be resumed
+func (s *Service) streamTo(ctx context.Context, artifactPath, destination string, write func(io.Writer) error) error {
+	fileName := filepath.Base(artifactPath)
+	log := s.logger.WithField("backup_file", fileName)
+
+	log.Info("☁️  Streaming " + fileName + " to cloud")
+
+	var lastErr error
+	for attempt := 1; attempt <= s.config.RetryCount; attempt++ {
+		if attempt > 1 {
+			log.WithField("attempt", attempt).Info("Retrying upload")
+			time.Sleep(time.Second * 10)
+		}
+
+		if err := s.rcat(ctx, artifactPath, destination, -1, write); err == nil {
+			log.Info("☁️  Upload completed successfully")
+			return nil
+		} else {
+			lastErr = err
+			log.WithError(err).WithField("attempt", attempt).Warn("Upload attempt failed")
+		}
+	}
+
+	return fmt.Errorf("upload failed after %d attempts: %w", s.config.RetryCount, lastErr)
+}
+
+// rcat pipes the output of write into rclone rcat. The pipe has no buffer
+// beyond the OS one, so a slow upload slows down the writer. A known size,
+// or -1, lets rclone pick multipart chunks large enough for it.
+func (s *Service) rcat(ctx context.Context, artifactPath, destination string, size int64, write func(io.Writer) error) error {
+	uploadCtx, cancel := context.WithTimeout(ctx, time.Duration(s.config.Timeout)*time.Second)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := s.rcloneCommand(uploadCtx, s.rcatArgs(ctx, artifactPath, destination, size)...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start rclone: %w", err)
+	}
+
+	writeErr := write(stdin)
+	if writeErr != nil {
+		// Abort rather than let rclone store a truncated object
+		cancel()
+	}
+	stdin.Close()
+
+	waitErr := cmd.Wait()
+	if writeErr != nil {
+		if waitErr != nil && output.Len() > 0 {
+			return fmt.Errorf("failed to write archive: %w (rclone output: %s)", writeErr, output.String())
+		}
+		return fmt.Errorf("failed to write archive: %w", writeErr)
+	}
+	if waitErr != nil {
+		return fmt.Errorf("rclone command failed: %w (output: %s)", waitErr, output.String())
+	}
+	return nil
+}
+
+// StreamCommand returns the rclone command line that streams artifactPath,
+// or a part of it, of size bytes, -1 if unknown, to the primary destination
+func (s *Service) StreamCommand(ctx context.Context, artifactPath string, size int64) []string {
+	args := s.rcatArgs(ctx, artifactPath, s.config.Destination, size)
+	return append([]string{s.config.RclonePath}, args...)
+}
+
+// rcatArgs builds the rclone rcat arguments
+func (s *Service) rcatArgs(ctx context.Context, artifactPath, destination string, size int64) []string {
+	remote := strings.TrimSuffix(remotePath(destination, artifactPath, false), "/") + "/" + filepath.Base(artifactPath)
+	args := []string{"rcat", remote, "--stats", "10s"}
+	if size >= 0 {
+		args = append(args, "--size", strconv.FormatInt(size, 10))
+	}
+	args = append(args, s.metadataArgs(ctx)...)
+	args = append(args, s.tuningArgs()...)
+	return append(args, s.configArgs()...)
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// tableActivity is the information_schema view of one table, with times
+// in Unix seconds and zero when unknown
+type tableActivity struct {
+	name    string
+	created int64
+	updated int64
+}
+
+// BinlogCoordinates returns the current binary log file and position of the
+// server, or an empty file when binary logging is off
+func (c *Client) BinlogCoordinates(ctx context.Context) (string, uint64, error) {
+	// MySQL 8.2 renamed SHOW MASTER STATUS and 8.4 removed the old name
+	rows, err := c.db.QueryContext(ctx, "SHOW BINARY LOG STATUS")
+	if err != nil {
+		rows, err = c.db.QueryContext(ctx, "SHOW MASTER STATUS")
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read binlog coordinates: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", 0, err
+	}
+	if !rows.Next() {
+		return "", 0, rows.Err()
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", 0, err
+	}
+	if len(values) < 2 {
+		return "", 0, fmt.Errorf("unexpected binlog status with %d columns", len(values))
+	}
+	position, err := strconv.ParseUint(string(values[1]), 10, 64)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid binlog position %q", values[1])
+	}
+	return string(values[0]), position, nil
+}
+
+// WrittenSince tells whether dbName may have changed since a backup that
+// started at since and held tables. It returns why it may have, or "" when
+// no table was created, altered, written or dropped and no routine, event
+// or trigger changed. Tables in ignore, such as the backup heartbeat, don't
+// count. Views carry no timestamps, so view changes go unnoticed.
+func (c *Client) WrittenSince(ctx context.Context, dbName string, since time.Time, tables []string, ignore ...string) (string, error) {
+	var uptime int64
+	var name string
+	if err := c.db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Uptime'").Scan(&name, &uptime); err != nil {
+		return "", fmt.Errorf("failed to read server uptime: %w", err)
+	}
+	serverStart := time.Now().Add(-time.Duration(uptime) * time.Second)
+
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME, COALESCE(UNIX_TIMESTAMP(CREATE_TIME), 0), COALESCE(UNIX_TIMESTAMP(UPDATE_TIME), 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?`, dbName)
+	if err != nil {
+		return "", fmt.Errorf("failed to read table activity: %w", err)
+	}
+	defer rows.Close()
+
+	var activity []tableActivity
+	for rows.Next() {
+		var t tableActivity
+		if err := rows.Scan(&t.name, &t.created, &t.updated); err != nil {
+			return "", err
+		}
+		if !slices.Contains(ignore, t.name) {
+			activity = append(activity, t)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if reason := tableWrites(activity, tables, since, serverStart); reason != "" {
+		return reason, nil
+	}
+
+	// Stored objects record when they were last changed
+	for _, query := range []string{
+		"SELECT COUNT(*) FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? AND LAST_ALTERED >= FROM_UNIXTIME(?)",
+		"SELECT COUNT(*) FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ? AND LAST_ALTERED >= FROM_UNIXTIME(?)",
+		"SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ? AND CREATED >= FROM_UNIXTIME(?)",
+	} {
+		var count int
+		if err := c.db.QueryRowContext(ctx, query, dbName, since.Unix()).Scan(&count); err != nil {
+			return "", fmt.Errorf("failed to read stored object changes: %w", err)
+		}
+		if count > 0 {
+			return "routines, events or triggers changed", nil
+		}
+	}
+	return "", nil
+}
+
+// tableWrites compares the tables of a database with a backup that started
+// at since and held previous. InnoDB keeps UPDATE_TIME in memory only, so a
+// table without one may have been written before a restart after since.
+func tableWrites(activity []tableActivity, previous []string, since, serverStart time.Time) string {
+	current := make(map[string]bool, len(activity))
+	for _, t := range activity {
+		current[t.name] = true
+		switch {
+		case t.created >= since.Unix():
+			return fmt.Sprintf("table %s was created or altered", t.name)
+		case t.updated >= since.Unix():
+			return fmt.Sprintf("table %s was written", t.name)
+		case t.updated == 0 && !serverStart.Before(since):
+			return fmt.Sprintf("the server restarted since the backup, writes to %s before that are unknown", t.name)
+		}
+	}
+	for _, name := range previous {
+		if !current[name] {
+			return fmt.Sprintf("table %s was dropped", name)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTableWrites(t *testing.T) {
+	since := time.Date(2025, 6, 1, 2, 0, 0, 0, time.UTC)
+	before := since.Add(-time.Hour).Unix()
+	bootedEarlier := since.Add(-24 * time.Hour)
+	previous := []string{"orders", "users"}
+
+	tests := []struct {
+		name        string
+		activity    []tableActivity
+		serverStart time.Time
+		want        string
+	}{
+		{
+			name:        "untouched",
+			activity:    []tableActivity{{"orders", before, before}, {"users", before, 0}},
+			serverStart: bootedEarlier,
+		},
+		{
+			name:        "written",
+			activity:    []tableActivity{{"orders", before, since.Unix() + 60}, {"users", before, before}},
+			serverStart: bootedEarlier,
+			want:        "orders was written",
+		},
+		{
+			name:        "altered",
+			activity:    []tableActivity{{"orders", since.Unix(), 0}, {"users", before, before}},
+			serverStart: bootedEarlier,
+			want:        "orders was created",
+		},
+		{
+			name:        "restart hides writes",
+			activity:    []tableActivity{{"orders", before, before}, {"users", before, 0}},
+			serverStart: since.Add(time.Hour),
+			want:        "restarted",
+		},
+		{
+			name:        "dropped",
+			activity:    []tableActivity{{"orders", before, before}},
+			serverStart: bootedEarlier,
+			want:        "users was dropped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tableWrites(tt.activity, previous, since, tt.serverStart)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("tableWrites() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
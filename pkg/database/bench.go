@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// LargestTable returns the base table of dbName with the most data
+func (c *Client) LargestTable(ctx context.Context, dbName string) (string, error) {
+	var table string
+	err := c.db.QueryRowContext(ctx, `SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY COALESCE(DATA_LENGTH, 0) DESC, TABLE_NAME LIMIT 1`, dbName).Scan(&table)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("database %s has no tables", dbName)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to find largest table: %w", err)
+	}
+	return table, nil
+}
+
+// DumpSample dumps the data of up to rows rows of a table into file with
+// the backup's mysqldump options and returns how many rows it holds
+func (c *Client) DumpSample(ctx context.Context, dbName, table string, rows int64, file string) (int64, error) {
+	var count int64
+	query := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s.%s LIMIT %d) sample", quoteIdentifier(dbName), quoteIdentifier(table), rows)
+	if err := c.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sample rows: %w", err)
+	}
+
+	where := fmt.Sprintf("--where=1=1 LIMIT %d", rows)
+	if err := c.runMysqldump(ctx, file, "--no-create-info", "--skip-triggers", where, dbName, table); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BinaryLog is one binary log file of the server
+type BinaryLog struct {
+	Name string
+	Size int64
+}
+
+// BinaryLogs lists the binary logs the server still holds, oldest first.
+// The last one is the log being written.
+func (c *Client) BinaryLogs(ctx context.Context) ([]BinaryLog, error) {
+	rows, err := c.db.QueryContext(ctx, "SHOW BINARY LOGS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list binary logs: %w", err)
+	}
+	defer rows.Close()
+
+	// MySQL 8.0 added an Encrypted column
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) < 2 {
+		return nil, fmt.Errorf("unexpected binary log list with %d columns", len(columns))
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var logs []BinaryLog
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		size, err := strconv.ParseInt(string(values[1]), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid size %q of binary log %s", values[1], values[0])
+		}
+		logs = append(logs, BinaryLog{Name: string(values[0]), Size: size})
+	}
+	return logs, rows.Err()
+}
+
+// FlushBinaryLogs closes the binary log being written and starts a new one,
+// so everything written so far can be archived
+func (c *Client) FlushBinaryLogs(ctx context.Context) error {
+	if _, err := c.db.ExecContext(ctx, "FLUSH BINARY LOGS"); err != nil {
+		return fmt.Errorf("failed to flush binary logs: %w", err)
+	}
+	return nil
+}
+
+// BinlogServer names the server whose binary logs are archived, from its
+// hostname and server_id. It stays the same through SSH tunnels and
+// changed connection settings, and differs between a source and its
+// replicas.
+func (c *Client) BinlogServer(ctx context.Context) (string, error) {
+	var hostname string
+	var serverID uint64
+	if err := c.db.QueryRowContext(ctx, "SELECT @@hostname, @@server_id").Scan(&hostname, &serverID); err != nil {
+		return "", fmt.Errorf("failed to identify server: %w", err)
+	}
+	return binlogServerName(hostname, serverID), nil
+}
+
+// binlogServerName makes hostname and serverID safe to use as a directory
+// name, locally and at the upload destination
+func binlogServerName(hostname string, serverID uint64) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_':
+			return r
+		}
+		return '-'
+	}, hostname)
+	name = strings.Trim(name, ".-")
+	if name == "" {
+		name = "server"
+	}
+	return fmt.Sprintf("%s-%d", name, serverID)
+}
+
+// FetchBinaryLogs copies the named binary logs from the server into dir
+// with mysqlbinlog --read-from-remote-server. Each file is fetched whole
+// and only moved into dir once its size matches the server's, so an interrupted
+// fetch leaves nothing that looks archived. Reading binary logs remotely
+// needs the REPLICATION SLAVE privilege.
+func (c *Client) FetchBinaryLogs(ctx context.Context, mysqlbinlogPath, dir string, logs []BinaryLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	if c.config.Container != "" {
+		return fmt.Errorf("binary logs can't be archived from database.container, mysqlbinlog would write them inside the container")
+	}
+
+	partial := filepath.Join(dir, ".partial")
+	if err := os.RemoveAll(partial); err != nil {
+		return fmt.Errorf("failed to clear partial binary logs: %w", err)
+	}
+	if err := os.MkdirAll(partial, 0755); err != nil {
+		return fmt.Errorf("failed to create binary log directory: %w", err)
+	}
+	defer os.RemoveAll(partial)
+
+	args := []string{
+		"--read-from-remote-server",
+		"--raw",
+		"--result-file=" + partial + string(filepath.Separator),
+		fmt.Sprintf("--user=%s", c.config.Username),
+	}
+	args = append(args, c.connectionArgs()...)
+	if c.config.Password != "" {
+		args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
+	}
+	for _, log := range logs {
+		args = append(args, log.Name)
+	}
+
+	cmd := exec.CommandContext(ctx, mysqlbinlogPath, args...)
+	c.logCommand(cmd)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("mysqlbinlog failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	for _, log := range logs {
+		fetched := filepath.Join(partial, log.Name)
+		info, err := os.Stat(fetched)
+		if err != nil {
+			return fmt.Errorf("mysqlbinlog did not write %s: %w", log.Name, err)
+		}
+		if info.Size() != log.Size {
+			return fmt.Errorf("binary log %s has %d bytes, the server reported %d", log.Name, info.Size(), log.Size)
+		}
+		if err := os.Rename(fetched, filepath.Join(dir, log.Name)); err != nil {
+			return fmt.Errorf("failed to archive %s: %w", log.Name, err)
+		}
+	}
+	return nil
+}
+
+// BinlogSequence splits a binary log file name such as binlog.000042 into
+// its base name and sequence number. ok is false for names that don't end
+// in a number.
+func BinlogSequence(name string) (base string, seq uint64, ok bool) {
+	dot := strings.LastIndexByte(name, '.')
+	if dot <= 0 || dot == len(name)-1 {
+		return "", 0, false
+	}
+	seq, err := strconv.ParseUint(name[dot+1:], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return name[:dot], seq, true
+}
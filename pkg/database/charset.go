@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// schemaCreatePattern reads the defaults from mydumper's *-schema-create.sql
+var schemaCreatePattern = regexp.MustCompile(`(?i)DEFAULT CHARACTER SET (\w+)(?: COLLATE (\w+))?`)
+
+// Charset is a character set and collation pair
+type Charset struct {
+	Name      string
+	Collation string
+}
+
+func (c Charset) String() string {
+	if c.Collation == "" {
+		return c.Name
+	}
+	return c.Name + "/" + c.Collation
+}
+
+// DatabaseCharset returns the default charset of dbName; exists is false if
+// the database does not exist
+func (c *Client) DatabaseCharset(ctx context.Context, dbName string) (charset Charset, exists bool, err error) {
+	err = c.db.QueryRowContext(ctx,
+		"SELECT DEFAULT_CHARACTER_SET_NAME, DEFAULT_COLLATION_NAME FROM information_schema.SCHEMATA WHERE SCHEMA_NAME = ?",
+		dbName).Scan(&charset.Name, &charset.Collation)
+	if err == sql.ErrNoRows {
+		return Charset{}, false, nil
+	}
+	if err != nil {
+		return Charset{}, false, fmt.Errorf("failed to read charset of %s: %w", dbName, err)
+	}
+	return charset, true, nil
+}
+
+// ServerCharset returns the charset new databases get by default
+func (c *Client) ServerCharset(ctx context.Context) (Charset, error) {
+	var charset Charset
+	if err := c.db.QueryRowContext(ctx, "SELECT @@character_set_server, @@collation_server").Scan(&charset.Name, &charset.Collation); err != nil {
+		return Charset{}, fmt.Errorf("failed to read server charset: %w", err)
+	}
+	return charset, nil
+}
+
+// HasCollation reports whether the server knows a collation, e.g. 5.7 lacks
+// the utf8mb4_0900 collations of MySQL 8
+func (c *Client) HasCollation(ctx context.Context, collation string) (bool, error) {
+	var count int
+	if err := c.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.COLLATIONS WHERE COLLATION_NAME = ?", collation).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to look up collation %s: %w", collation, err)
+	}
+	return count > 0, nil
+}
+
+// CreateDatabase creates dbName with the given charset; an empty collation
+// uses the charset's default
+func (c *Client) CreateDatabase(ctx context.Context, dbName string, charset Charset) error {
+	stmt := "CREATE DATABASE " + quoteIdentifier(dbName)
+	if charset.Name != "" {
+		stmt += " CHARACTER SET " + charset.Name
+	}
+	if charset.Collation != "" {
+		stmt += " COLLATE " + charset.Collation
+	}
+	if _, err := c.db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create database %s: %w", dbName, err)
+	}
+	return nil
+}
+
+// SchemaCreateCharset reads the database charset from the schema-create
+// file of an uncompressed mydumper backup, for backups without a manifest
+func SchemaCreateCharset(backupPath string) (Charset, bool) {
+	matches, _ := filepath.Glob(filepath.Join(backupPath, "*-schema-create.sql"))
+	if len(matches) == 0 {
+		return Charset{}, false
+	}
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return Charset{}, false
+	}
+	m := schemaCreatePattern.FindStringSubmatch(string(data))
+	if m == nil {
+		return Charset{}, false
+	}
+	return Charset{Name: m[1], Collation: m[2]}, true
+}
@@ -0,0 +1,24 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSchemaCreateCharset(t *testing.T) {
+	dir := t.TempDir()
+	if _, ok := SchemaCreateCharset(dir); ok {
+		t.Fatal("expected no charset without a schema-create file")
+	}
+
+	schema := "CREATE DATABASE IF NOT EXISTS `app` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */ /*!80016 DEFAULT ENCRYPTION='N' */;\n"
+	if err := os.WriteFile(filepath.Join(dir, "app-schema-create.sql"), []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	charset, ok := SchemaCreateCharset(dir)
+	if !ok || charset.Name != "utf8mb4" || charset.Collation != "utf8mb4_0900_ai_ci" {
+		t.Errorf("got %v, %v", charset, ok)
+	}
+}
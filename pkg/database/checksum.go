@@ -0,0 +1,81 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+)
+
+// ChecksumTables runs CHECKSUM TABLE on a random sample of up to sampleSize
+// base tables of dbName, or on all of them when sampleSize is 0. Tables the
+// server returns no checksum for are left out.
+func (c *Client) ChecksumTables(ctx context.Context, dbName string, sampleSize int) (map[string]uint64, error) {
+	tables, err := c.baseTables(ctx, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	if sampleSize > 0 && len(tables) > sampleSize {
+		rand.Shuffle(len(tables), func(i, j int) { tables[i], tables[j] = tables[j], tables[i] })
+		tables = tables[:sampleSize]
+		sort.Strings(tables)
+	}
+	return c.checksumTables(ctx, dbName, tables)
+}
+
+// CompareChecksums checksums the tables of want in dbName and returns a
+// description of every table whose checksum differs or is missing. dbName
+// may differ from the database the checksums were taken in, for example a
+// scratch database a backup was restored into.
+func (c *Client) CompareChecksums(ctx context.Context, dbName string, want map[string]uint64) ([]string, error) {
+	tables := make([]string, 0, len(want))
+	for table := range want {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	got, err := c.checksumTables(ctx, dbName, tables)
+	if err != nil {
+		return nil, err
+	}
+	return DiffChecksums(want, got), nil
+}
+
+// DiffChecksums describes the tables of want whose checksum in got differs
+// or is missing, in table order
+func DiffChecksums(want, got map[string]uint64) []string {
+	tables := make([]string, 0, len(want))
+	for table := range want {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	var diffs []string
+	for _, table := range tables {
+		sum, ok := got[table]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: missing", table))
+		case sum != want[table]:
+			diffs = append(diffs, fmt.Sprintf("%s: checksum %d, expected %d", table, sum, want[table]))
+		}
+	}
+	return diffs
+}
+
+func (c *Client) checksumTables(ctx context.Context, dbName string, tables []string) (map[string]uint64, error) {
+	checksums := make(map[string]uint64, len(tables))
+	for _, table := range tables {
+		var name string
+		var sum sql.NullInt64
+		err := c.db.QueryRowContext(ctx, "CHECKSUM TABLE "+quoteIdentifier(dbName)+"."+quoteIdentifier(table)).Scan(&name, &sum)
+		if err != nil {
+			return nil, fmt.Errorf("failed to checksum table %s: %w", table, err)
+		}
+		if sum.Valid {
+			checksums[table] = uint64(sum.Int64)
+		}
+	}
+	return checksums, nil
+}
@@ -0,0 +1,21 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffChecksums(t *testing.T) {
+	want := map[string]uint64{"orders": 42, "users": 7, "audit": 1}
+	got := map[string]uint64{"orders": 42, "users": 8}
+
+	diffs := DiffChecksums(want, got)
+	expected := []string{"audit: missing", "users: checksum 8, expected 7"}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("DiffChecksums = %v, want %v", diffs, expected)
+	}
+
+	if diffs := DiffChecksums(want, want); len(diffs) != 0 {
+		t.Errorf("identical checksums reported as %v", diffs)
+	}
+}
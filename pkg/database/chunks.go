@@ -0,0 +1,350 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// ChunkIndexFile lists the files of a chunked mysqldump backup in load order
+const ChunkIndexFile = "tenangdb-chunks.json"
+
+type chunkIndex struct {
+	Files []string `json:"files"`
+}
+
+// largeTable is a table that large_table_rules dumps in chunks
+type largeTable struct {
+	name      string
+	rows      int64 // rows per chunk
+	chunkBy   string
+	estimated int64 // row estimate from information_schema
+}
+
+// SetLargeTableRules enables chunked dumps of the tables the rules match
+func (c *Client) SetLargeTableRules(rules []config.LargeTableRule) {
+	c.largeTableRules = rules
+}
+
+// largeTables returns the tables of dbName matched by large_table_rules
+func (c *Client) largeTables(ctx context.Context, dbName string) ([]largeTable, error) {
+	if len(c.largeTableRules) == 0 {
+		return nil, nil
+	}
+
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME, COALESCE(DATA_LENGTH, 0), COALESCE(TABLE_ROWS, 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []largeTable
+	for rows.Next() {
+		var name string
+		var dataLength, estimated int64
+		if err := rows.Scan(&name, &dataLength, &estimated); err != nil {
+			return nil, err
+		}
+		if rule, ok := matchLargeTableRule(c.largeTableRules, name, dataLength); ok {
+			tables = append(tables, largeTable{name: name, rows: rule.Rows, chunkBy: rule.ChunkBy, estimated: estimated})
+		}
+	}
+	return tables, rows.Err()
+}
+
+// matchLargeTableRule returns the first rule that applies to a table
+func matchLargeTableRule(rules []config.LargeTableRule, table string, dataLength int64) (config.LargeTableRule, bool) {
+	for _, rule := range rules {
+		if rule.Table != "" {
+			if ok, _ := filepath.Match(rule.Table, table); !ok {
+				continue
+			}
+		}
+		if dataLength < rule.MinSizeMB*1024*1024 {
+			continue
+		}
+		return rule, true
+	}
+	return config.LargeTableRule{}, false
+}
+
+// mydumperChunkArgs passes per-table rows to mydumper through a generated
+// defaults file. Legacy mydumper only has a global --rows, so the smallest
+// chunk size is used for all tables. The returned cleanup removes the file.
+func (c *Client) mydumperChunkArgs(ctx context.Context, dbName string, modern bool) ([]string, func(), error) {
+	tables, err := c.largeTables(ctx, dbName)
+	if err != nil || len(tables) == 0 {
+		return nil, func() {}, err
+	}
+
+	if !modern {
+		minRows := tables[0].rows
+		for _, t := range tables[1:] {
+			if t.rows < minRows {
+				minRows = t.rows
+			}
+		}
+		return []string{fmt.Sprintf("--rows=%d", minRows)}, func() {}, nil
+	}
+
+	file, err := os.CreateTemp("", "tenangdb-mydumper-*.cnf")
+	if err != nil {
+		return nil, func() {}, fmt.Errorf("failed to create mydumper table config: %w", err)
+	}
+	cleanup := func() { os.Remove(file.Name()) }
+
+	for _, t := range tables {
+		fmt.Fprintf(file, "[%s.%s]\nrows = %d\n\n", quoteIdentifier(dbName), quoteIdentifier(t.name), t.rows)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return nil, func() {}, fmt.Errorf("failed to write mydumper table config: %w", err)
+	}
+	return []string{"--defaults-extra-file=" + file.Name()}, cleanup, nil
+}
+
+// createChunkedMysqldumpBackup dumps dbName into a directory: the database
+// without its large tables, the large tables' schema, their data in
+// primary key ranges, and finally all triggers so they don't fire while
+// chunks load. The dumps are separate transactions, so large tables are
+// only consistent with the rest of the backup if writes are paused, which
+// is also why backup.non_transactional can only exclude tables here.
+func (c *Client) createChunkedMysqldumpBackup(ctx context.Context, dbName, backupDir, timestamp string, tables []largeTable, engineTables []EngineTable, views []InvalidView, skip []string) (string, error) {
+	dbBackupDir := filepath.Join(backupDir, fmt.Sprintf("%s-%s", PathName(dbName), timestamp))
+	if err := os.MkdirAll(dbBackupDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	var index chunkIndex
+	dump := func(file string, args ...string) error {
+		if err := c.runMysqldump(ctx, filepath.Join(dbBackupDir, file), args...); err != nil {
+			return err
+		}
+		index.Files = append(index.Files, file)
+		return nil
+	}
+
+	names := make([]string, len(tables))
+	mainArgs := []string{"--skip-triggers"}
+	for i, t := range tables {
+		names[i] = t.name
+		mainArgs = append(mainArgs, fmt.Sprintf("--ignore-table=%s.%s", dbName, t.name))
+	}
+	for _, name := range append(c.excludedEngineTables(engineTables), invalidViewNames(views)...) {
+		mainArgs = append(mainArgs, fmt.Sprintf("--ignore-table=%s.%s", dbName, name))
+	}
+	mainArgs = append(mainArgs, dumpTargets(dbName)...)
+	fileName := PathName(dbName)
+
+	err := func() error {
+		if err := dump(fileName+".sql", mainArgs...); err != nil {
+			return err
+		}
+		if err := dump(fileName+"-large-schema.sql", append([]string{"--no-data", "--skip-triggers"}, dumpTargets(dbName, names...)...)...); err != nil {
+			return err
+		}
+
+		for _, t := range tables {
+			ranges, err := c.chunkRanges(ctx, dbName, t)
+			if err != nil {
+				return fmt.Errorf("failed to plan chunks of %s: %w", t.name, err)
+			}
+			for i, where := range ranges {
+				file := fmt.Sprintf("%s.%05d.sql", PathName(t.name), i+1)
+				if err := dump(file, append([]string{"--no-create-info", "--skip-triggers", "--where=" + where}, dumpTargets(dbName, t.name)...)...); err != nil {
+					return err
+				}
+			}
+			c.logger.WithField("table", t.name).WithField("chunks", len(ranges)).Debug("Dumped large table in chunks")
+		}
+
+		if skipsPrivilege(skip, PrivilegeTrigger) {
+			return nil
+		}
+		return dump(fileName+"-triggers.sql", append([]string{"--no-data", "--no-create-info", "--triggers"}, dumpTargets(dbName)...)...)
+	}()
+	if err == nil {
+		err = writeChunkIndex(dbBackupDir, &index)
+	}
+	if err == nil {
+		err = writeInvalidViews(dbBackupDir, views)
+	}
+	if err != nil {
+		os.RemoveAll(dbBackupDir)
+		return "", err
+	}
+
+	return dbBackupDir, nil
+}
+
+// chunkRanges splits a table into WHERE clauses of about t.rows rows each,
+// assuming keys are spread evenly between the smallest and largest value
+func (c *Client) chunkRanges(ctx context.Context, dbName string, t largeTable) ([]string, error) {
+	column := t.chunkBy
+	if column == "" {
+		var err error
+		if column, err = c.primaryKeyColumn(ctx, dbName, t.name); err != nil {
+			return nil, err
+		}
+	}
+	quoted := quoteIdentifier(column)
+
+	var minKey, maxKey sql.NullInt64
+	query := fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s.%s", quoted, quoted, quoteIdentifier(dbName), quoteIdentifier(t.name))
+	if err := c.db.QueryRowContext(ctx, query).Scan(&minKey, &maxKey); err != nil {
+		return nil, fmt.Errorf("failed to read key range of %s: %w", column, err)
+	}
+	if !minKey.Valid {
+		return []string{"1=1"}, nil
+	}
+
+	ranges := splitKeyRange(quoted, minKey.Int64, maxKey.Int64, t.rows, t.estimated)
+	// A chunk_by column may be nullable; such rows fall outside every range
+	if t.chunkBy != "" && len(ranges) > 1 {
+		ranges = append(ranges, quoted+" IS NULL")
+	}
+	return ranges, nil
+}
+
+// splitKeyRange covers [minKey, maxKey] with ranges of about rows rows. The
+// first and last range are open so rows outside the sampled bounds are not
+// lost.
+func splitKeyRange(column string, minKey, maxKey, rows, estimated int64) []string {
+	width := rows
+	if estimated > rows {
+		// Sparse keys: widen ranges so each holds about rows rows
+		span := maxKey - minKey + 1
+		width = span / (estimated / rows)
+	}
+	if width < 1 {
+		width = 1
+	}
+
+	lower := minKey + width
+	if lower > maxKey {
+		return []string{"1=1"}
+	}
+
+	ranges := []string{fmt.Sprintf("%s < %d", column, lower)}
+	for {
+		upper := lower + width
+		if upper > maxKey {
+			return append(ranges, fmt.Sprintf("%s >= %d", column, lower))
+		}
+		ranges = append(ranges, fmt.Sprintf("%s >= %d AND %s < %d", column, lower, column, upper))
+		lower = upper
+	}
+}
+
+// primaryKeyColumn returns the single integer primary key column of a table
+func (c *Client) primaryKeyColumn(ctx context.Context, dbName, table string) (string, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT k.COLUMN_NAME, c.DATA_TYPE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.COLUMNS c
+		  ON c.TABLE_SCHEMA = k.TABLE_SCHEMA AND c.TABLE_NAME = k.TABLE_NAME AND c.COLUMN_NAME = k.COLUMN_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ? AND k.CONSTRAINT_NAME = 'PRIMARY'`, dbName, table)
+	if err != nil {
+		return "", fmt.Errorf("failed to read primary key: %w", err)
+	}
+	defer rows.Close()
+
+	var columns, types []string
+	for rows.Next() {
+		var column, dataType string
+		if err := rows.Scan(&column, &dataType); err != nil {
+			return "", err
+		}
+		columns = append(columns, column)
+		types = append(types, dataType)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	if len(columns) != 1 || !strings.HasSuffix(types[0], "int") {
+		return "", fmt.Errorf("table %s has no single integer primary key, set chunk_by", table)
+	}
+	return columns[0], nil
+}
+
+// runMysqldump runs mysqldump with the shared options plus args into file
+func (c *Client) runMysqldump(ctx context.Context, file string, args ...string) error {
+	cmd := c.toolCommand(ctx, c.config.MysqldumpPath, dumpTools, append(c.mysqldumpOptions(), args...)...)
+	c.logCommand(cmd)
+
+	out, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer out.Close()
+
+	var stderr bytes.Buffer
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("mysqldump failed for %s: %w\nOutput: %s", filepath.Base(file), err, stderr.String())
+	}
+	return out.Close()
+}
+
+func writeChunkIndex(dir string, index *chunkIndex) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ChunkIndexFile), data, 0644)
+}
+
+// IsChunkedDump reports whether path is a chunked mysqldump backup
+func IsChunkedDump(path string) bool {
+	_, err := os.Stat(filepath.Join(path, ChunkIndexFile))
+	return err == nil
+}
+
+// openDump opens a mysqldump backup for loading; chunked backups are read
+// as one stream in index order
+func openDump(path string) (io.Reader, func(), error) {
+	if !IsChunkedDump(path) {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open backup file: %w", err)
+		}
+		return file, func() { file.Close() }, nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, ChunkIndexFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read chunk index: %w", err)
+	}
+	var index chunkIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse chunk index: %w", err)
+	}
+
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	readers := make([]io.Reader, 0, len(index.Files))
+	for _, name := range index.Files {
+		f, err := os.Open(filepath.Join(path, filepath.Base(name)))
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to open backup chunk: %w", err)
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+	}
+	return io.MultiReader(readers...), closeAll, nil
+}
@@ -0,0 +1,45 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestSplitKeyRange(t *testing.T) {
+	tests := []struct {
+		name                  string
+		min, max, rows, total int64
+		want                  []string
+	}{
+		{"single chunk", 1, 50, 100, 50, []string{"1=1"}},
+		{"dense keys", 1, 300, 100, 300, []string{"`id` < 101", "`id` >= 101 AND `id` < 201", "`id` >= 201"}},
+		{"sparse keys", 1, 1000, 100, 200, []string{"`id` < 501", "`id` >= 501"}},
+		{"stale estimate", 1, 250, 100, 0, []string{"`id` < 101", "`id` >= 101 AND `id` < 201", "`id` >= 201"}},
+	}
+
+	for _, tt := range tests {
+		got := splitKeyRange("`id`", tt.min, tt.max, tt.rows, tt.total)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatchLargeTableRule(t *testing.T) {
+	rules := []config.LargeTableRule{
+		{Table: "events_*", MinSizeMB: 10, Rows: 1000},
+		{Rows: 5000, MinSizeMB: 100},
+	}
+
+	if _, ok := matchLargeTableRule(rules, "events_2024", 5*1024*1024); ok {
+		t.Error("small events table matched")
+	}
+	if rule, ok := matchLargeTableRule(rules, "events_2024", 20*1024*1024); !ok || rule.Rows != 1000 {
+		t.Errorf("events table got %+v, %v", rule, ok)
+	}
+	if rule, ok := matchLargeTableRule(rules, "orders", 200*1024*1024); !ok || rule.Rows != 5000 {
+		t.Errorf("orders table got %+v, %v", rule, ok)
+	}
+}
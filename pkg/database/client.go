@@ -5,6 +5,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -15,31 +16,33 @@ import (
 	"github.com/abdullahainun/tenangdb/internal/config"
 	"github.com/abdullahainun/tenangdb/internal/logger"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 type Client struct {
 	config *config.DatabaseConfig
 	db     *sql.DB
+	logger *logger.Logger
+
+	largeTableRules  []config.LargeTableRule
+	nonTransactional string // config.NonTransactional* mode
+	restoreTracking  *RestoreTracking
 }
 
 func NewClient(config *config.DatabaseConfig) (*Client, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/",
-		config.Username,
-		config.Password,
-		config.Host,
-		config.Port,
-	)
-
-	db, err := sql.Open("mysql", dsn)
+	db, err := sql.Open("mysql", buildDSN(config))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
 
-	// Set connection timeouts
-	db.SetConnMaxLifetime(time.Duration(config.Timeout) * time.Second)
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
+	// Set connection pool limits
+	lifetime := config.ConnMaxLifetime
+	if lifetime == 0 {
+		lifetime = time.Duration(config.Timeout) * time.Second
+	}
+	db.SetConnMaxLifetime(lifetime)
+	db.SetMaxOpenConns(orDefault(config.MaxOpenConns, 10))
+	db.SetMaxIdleConns(orDefault(config.MaxIdleConns, 5))
 
 	// Test connection
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.Timeout)*time.Second)
@@ -55,13 +58,76 @@ func NewClient(config *config.DatabaseConfig) (*Client, error) {
 	}, nil
 }
 
-func (c *Client) CreateBackup(ctx context.Context, dbName, backupDir string) (string, error) {
+// buildDSN renders the driver connection string, escaping credentials
+func buildDSN(cfg *config.DatabaseConfig) string {
+	dsn := mysql.NewConfig()
+	dsn.User = cfg.Username
+	dsn.Passwd = cfg.Password
+	dsn.Net = "tcp"
+	dsn.Addr = fmt.Sprintf("%s:%d", cfg.Host, cfg.Port)
+	if cfg.Socket != "" {
+		dsn.Net = "unix"
+		dsn.Addr = cfg.Socket
+	}
+
+	dsn.Timeout = cfg.DialTimeout
+	if dsn.Timeout == 0 {
+		dsn.Timeout = time.Duration(cfg.Timeout) * time.Second
+	}
+	dsn.ReadTimeout = cfg.ReadTimeout
+	dsn.WriteTimeout = cfg.WriteTimeout
+
+	if cfg.Charset != "" {
+		dsn.Params = map[string]string{"charset": cfg.Charset}
+	}
+	if cfg.Collation != "" {
+		dsn.Collation = cfg.Collation
+	}
+
+	return dsn.FormatDSN()
+}
+
+func orDefault(value, fallback int) int {
+	if value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// charsetArgs passes the configured connection charset to mysqldump and mysql
+func (c *Client) charsetArgs() []string {
+	if c.config.Charset == "" {
+		return nil
+	}
+	return []string{fmt.Sprintf("--default-character-set=%s", c.config.Charset)}
+}
+
+// connectionArgs returns the client tool options that reach the server,
+// through the unix socket when one is configured
+func (c *Client) connectionArgs() []string {
+	if c.config.Socket != "" {
+		return []string{fmt.Sprintf("--socket=%s", c.config.Socket)}
+	}
+	return []string{
+		fmt.Sprintf("--host=%s", c.config.Host),
+		fmt.Sprintf("--port=%d", c.config.Port),
+	}
+}
+
+// SetLogger enables debug output of external commands and per-table details
+func (c *Client) SetLogger(log *logger.Logger) {
+	c.logger = log
+}
+
+// CreateBackup dumps dbName into backupDir. Objects needing a privilege in
+// skip, such as PrivilegeTrigger, are left out of the dump.
+func (c *Client) CreateBackup(ctx context.Context, dbName, backupDir string, skip ...string) (string, error) {
 	now := time.Now()
 	timestamp := now.Format("2006-01-02_15-04-05")
 
 	// Create organized directory structure: database-backup/dbname/YYYY-MM/
 	yearMonth := now.Format("2006-01")
-	organizedBackupDir := filepath.Join(backupDir, dbName, yearMonth)
+	organizedBackupDir := filepath.Join(backupDir, PathName(dbName), yearMonth)
 
 	// Ensure the organized directory exists
 	if err := os.MkdirAll(organizedBackupDir, 0755); err != nil {
@@ -70,20 +136,79 @@ func (c *Client) CreateBackup(ctx context.Context, dbName, backupDir string) (st
 
 	// Check if mydumper is enabled in config
 	if c.config.Mydumper != nil && c.config.Mydumper.Enabled {
-		return c.createMydumperBackup(ctx, dbName, organizedBackupDir, timestamp)
+		return c.createMydumperBackup(ctx, dbName, organizedBackupDir, timestamp, skip)
 	}
 
 	// Fallback to mysqldump
-	return c.createMysqldumpBackup(ctx, dbName, organizedBackupDir, timestamp)
+	return c.createMysqldumpBackup(ctx, dbName, organizedBackupDir, timestamp, skip)
 }
 
-func (c *Client) createMydumperBackup(ctx context.Context, dbName, backupDir, timestamp string) (string, error) {
+func (c *Client) createMydumperBackup(ctx context.Context, dbName, backupDir, timestamp string, skip []string) (string, error) {
 	// Create database-specific directory
-	dbBackupDir := filepath.Join(backupDir, fmt.Sprintf("%s-%s", dbName, timestamp))
+	dbBackupDir := filepath.Join(backupDir, fmt.Sprintf("%s-%s", PathName(dbName), timestamp))
 	if err := os.MkdirAll(dbBackupDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
+	engineTables, err := c.nonTransactionalTables(ctx, dbName)
+	if err != nil {
+		os.RemoveAll(dbBackupDir)
+		return "", err
+	}
+	views, err := c.invalidViews(ctx, dbName)
+	if err != nil {
+		os.RemoveAll(dbBackupDir)
+		return "", err
+	}
+	args := c.mydumperEngineArgs(c.mydumperArgs(dbBackupDir, dbName), engineTables)
+	args = append(args, mydumperExcludeArgs(dbName, append(c.excludedEngineTables(engineTables), invalidViewNames(views)...))...)
+	args = mydumperSkipArgs(args, skip)
+
+	// Split large tables into chunks of the configured number of rows
+	chunkArgs, cleanup, err := c.mydumperChunkArgs(ctx, dbName, c.isMydumperVersionCompatible())
+	if err != nil {
+		os.RemoveAll(dbBackupDir)
+		return "", err
+	}
+	defer cleanup()
+	if len(chunkArgs) > 0 && !c.isMydumperVersionCompatible() && c.logger != nil {
+		c.logger.WithField("database", dbName).Warn("⚠️ Legacy mydumper has no per-table rows, using the smallest large_table_rules rows for all tables")
+	}
+	args = append(args, chunkArgs...)
+
+	cmd := exec.CommandContext(ctx, c.config.Mydumper.BinaryPath, args...)
+	c.logCommand(cmd)
+
+	// Capture both stdout and stderr for better error reporting
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+
+	if err := cmd.Run(); err != nil {
+		// Remove failed backup directory
+		os.RemoveAll(dbBackupDir)
+		return "", fmt.Errorf("mydumper failed: %w, stdout: %s, stderr: %s", err, stdout.String(), stderr.String())
+	}
+
+	// Verify backup directory was created and has content
+	if err := c.verifyMydumperBackup(dbBackupDir); err != nil {
+		os.RemoveAll(dbBackupDir)
+		return "", fmt.Errorf("mydumper backup verification failed: %w", err)
+	}
+
+	if err := writeInvalidViews(dbBackupDir, views); err != nil {
+		os.RemoveAll(dbBackupDir)
+		return "", err
+	}
+
+	c.logTableFiles(dbName, dbBackupDir)
+
+	return dbBackupDir, nil
+}
+
+// mydumperArgs builds the mydumper command line for a database
+func (c *Client) mydumperArgs(dbBackupDir, dbName string) []string {
 	// Build mydumper command with version-compatible arguments
 	args := c.buildMydumperArgs(dbBackupDir, dbName)
 
@@ -91,8 +216,7 @@ func (c *Client) createMydumperBackup(ctx context.Context, dbName, backupDir, ti
 	if c.config.Mydumper.DefaultsFile != "" {
 		args = append(args, fmt.Sprintf("--defaults-file=%s", c.config.Mydumper.DefaultsFile))
 	} else {
-		args = append(args, fmt.Sprintf("--host=%s", c.config.Host))
-		args = append(args, fmt.Sprintf("--port=%d", c.config.Port))
+		args = append(args, c.connectionArgs()...)
 		args = append(args, fmt.Sprintf("--user=%s", c.config.Username))
 		if c.config.Password != "" {
 			args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
@@ -125,55 +249,41 @@ func (c *Client) createMydumperBackup(ctx context.Context, dbName, backupDir, ti
 		args = append(args, "--no-data")
 	}
 
-	cmd := exec.CommandContext(ctx, c.config.Mydumper.BinaryPath, args...)
-
-	// Capture both stdout and stderr for better error reporting
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-
+	return args
+}
 
-	if err := cmd.Run(); err != nil {
-		// Remove failed backup directory
-		os.RemoveAll(dbBackupDir)
-		return "", fmt.Errorf("mydumper failed: %w, stdout: %s, stderr: %s", err, stdout.String(), stderr.String())
+func (c *Client) createMysqldumpBackup(ctx context.Context, dbName, backupDir, timestamp string, skip []string) (string, error) {
+	engineTables, err := c.nonTransactionalTables(ctx, dbName)
+	if err != nil {
+		return "", err
 	}
-
-	// Verify backup directory was created and has content
-	if err := c.verifyMydumperBackup(dbBackupDir); err != nil {
-		os.RemoveAll(dbBackupDir)
-		return "", fmt.Errorf("mydumper backup verification failed: %w", err)
+	views, err := c.invalidViews(ctx, dbName)
+	if err != nil {
+		return "", err
 	}
 
-	return dbBackupDir, nil
-}
+	// Large tables are dumped in primary key ranges instead of one file
+	largeTables, err := c.largeTables(ctx, dbName)
+	if err != nil {
+		return "", err
+	}
+	if len(largeTables) > 0 {
+		return c.createChunkedMysqldumpBackup(ctx, dbName, backupDir, timestamp, largeTables, engineTables, views, skip)
+	}
 
-func (c *Client) createMysqldumpBackup(ctx context.Context, dbName, backupDir, timestamp string) (string, error) {
-	fileName := fmt.Sprintf("%s-%s.sql", dbName, timestamp)
+	fileName := fmt.Sprintf("%s-%s.sql", PathName(dbName), timestamp)
 	backupPath := filepath.Join(backupDir, fileName)
 
-	// Build mysqldump command with maximum compatibility
-	args := []string{
-		"--single-transaction",
-		"--skip-lock-tables",
-		"--complete-insert",
-		"--extended-insert",
-		"--hex-blob",
-		"--add-drop-table",
-		"--disable-keys",
-		fmt.Sprintf("--host=%s", c.config.Host),
-		fmt.Sprintf("--port=%d", c.config.Port),
-		fmt.Sprintf("--user=%s", c.config.Username),
+	args := c.mysqldumpEngineArgs(c.mysqldumpOptions(), dbName, engineTables)
+	for _, v := range views {
+		args = append(args, fmt.Sprintf("--ignore-table=%s.%s", dbName, v.Name))
 	}
-
-	if c.config.Password != "" {
-		args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
+	if skipsPrivilege(skip, PrivilegeTrigger) {
+		args = append(args, "--skip-triggers")
 	}
-
-	// Add database name
-	args = append(args, dbName)
-
-	cmd := exec.CommandContext(ctx, c.config.MysqldumpPath, args...)
+	args = append(args, dumpTargets(dbName)...)
+	cmd := c.toolCommand(ctx, c.config.MysqldumpPath, dumpTools, args...)
+	c.logCommand(cmd)
 
 	// Create output file
 	outFile, err := os.Create(backupPath)
@@ -218,10 +328,72 @@ func (c *Client) createMysqldumpBackup(ctx context.Context, dbName, backupDir, t
 		os.Remove(backupPath)
 		return "", fmt.Errorf("backup verification failed: %w", err)
 	}
+	if err := writeInvalidViews(backupPath, views); err != nil {
+		os.Remove(backupPath)
+		return "", err
+	}
 
 	return backupPath, nil
 }
 
+// mysqldumpArgs builds the mysqldump command line for a database
+func (c *Client) mysqldumpArgs(dbName string) []string {
+	return append(c.mysqldumpOptions(), dumpTargets(dbName)...)
+}
+
+// mysqldumpOptions returns the mysqldump options shared by every dump
+func (c *Client) mysqldumpOptions() []string {
+	// Build mysqldump command with maximum compatibility
+	args := []string{
+		"--single-transaction",
+		"--skip-lock-tables",
+		"--complete-insert",
+		"--extended-insert",
+		"--hex-blob",
+		"--add-drop-table",
+		"--disable-keys",
+		fmt.Sprintf("--user=%s", c.config.Username),
+	}
+	args = append(args, c.toolConnectionArgs()...)
+	args = append(args, c.charsetArgs()...)
+
+	if c.config.Password != "" {
+		args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
+	}
+
+	return args
+}
+
+// BackupPlan describes the backup CreateBackup would make of a database
+type BackupPlan struct {
+	Artifact string   // file or directory that would be written
+	Tool     string   // mydumper or mysqldump
+	Command  []string // command line with secrets redacted
+}
+
+// PlanBackup returns what CreateBackup would do at now, without connecting
+// to the server
+func PlanBackup(cfg *config.DatabaseConfig, dbName, backupDir string, now time.Time) BackupPlan {
+	c := &Client{config: cfg}
+	timestamp := now.Format("2006-01-02_15-04-05")
+	organizedBackupDir := filepath.Join(backupDir, PathName(dbName), now.Format("2006-01"))
+
+	if cfg.Mydumper != nil && cfg.Mydumper.Enabled {
+		dbBackupDir := filepath.Join(organizedBackupDir, fmt.Sprintf("%s-%s", PathName(dbName), timestamp))
+		return BackupPlan{
+			Artifact: dbBackupDir,
+			Tool:     "mydumper",
+			Command:  redactArgs(append([]string{cfg.Mydumper.BinaryPath}, c.mydumperArgs(dbBackupDir, dbName)...)),
+		}
+	}
+
+	return BackupPlan{
+		Artifact: filepath.Join(organizedBackupDir, fmt.Sprintf("%s-%s.sql", PathName(dbName), timestamp)),
+		Tool:     "mysqldump",
+		Command:  redactArgs(c.toolArgs(cfg.MysqldumpPath, dumpTools, c.mysqldumpArgs(dbName)...)),
+	}
+}
+
 func (c *Client) verifyBackupFile(backupPath string) error {
 	info, err := os.Stat(backupPath)
 	if err != nil {
@@ -259,6 +431,13 @@ func (c *Client) verifyMydumperBackup(backupDir string) error {
 	if _, err := os.Stat(metadataFile); err != nil {
 		return fmt.Errorf("metadata file not found: %w", err)
 	}
+	metadata, err := ParseMydumperMetadata(metadataFile)
+	if err != nil {
+		return err
+	}
+	if metadata.Finished.IsZero() {
+		return fmt.Errorf("mydumper metadata does not record a finished dump")
+	}
 
 	// Check if backup directory has content
 	files, err := os.ReadDir(backupDir)
@@ -291,9 +470,25 @@ func (c *Client) CreateDirectory(path string) error {
 	return os.MkdirAll(path, 0755)
 }
 
-func (c *Client) RestoreBackup(ctx context.Context, backupPath, dbName string) error {
+func (c *Client) RestoreBackup(ctx context.Context, backupPath, dbName string, restoreCfg *config.RestoreConfig) error {
 	// Create a temporary logger for compression operations
 	log := logger.NewLogger("info")
+
+	if restoreCfg == nil {
+		restoreCfg = &config.RestoreConfig{}
+	}
+
+	// Only disable binary logging when the user is allowed to, otherwise the
+	// restore would fail on the first statement
+	skipBinlog := false
+	if restoreCfg.SkipBinlog {
+		if err := c.checkBinlogPrivilege(ctx); err != nil {
+			log.WithError(err).Warn("⚠️ Cannot disable binary logging for restore session, restore will be replicated")
+		} else {
+			skipBinlog = true
+			log.Info("Binary logging disabled for restore session")
+		}
+	}
 	
 	// Auto-decompress if needed
 	finalBackupPath := backupPath
@@ -309,6 +504,8 @@ func (c *Client) RestoreBackup(ctx context.Context, backupPath, dbName string) e
 			Level:   6,
 		}
 		compressor := compression.NewCompressor(compressionConfig, log)
+		compressor.SetMaxExtractSize(int64(restoreCfg.MaxExtractSizeGB) << 30)
+		compressor.SetTempDirectory(restoreCfg.TempDirectory, restoreCfg.ExpansionFactor)
 		
 		// Decompress backup
 		decompressedPath, err := compressor.DecompressBackup(backupPath)
@@ -338,30 +535,88 @@ func (c *Client) RestoreBackup(ctx context.Context, backupPath, dbName string) e
 		c.config.Mydumper.Myloader != nil && c.config.Mydumper.Myloader.Enabled {
 
 		// Check if backup path is a directory (mydumper backup)
-		if info, err := os.Stat(finalBackupPath); err == nil && info.IsDir() {
-			return c.restoreWithMyloader(ctx, finalBackupPath, dbName)
+		if info, err := os.Stat(finalBackupPath); err == nil && info.IsDir() && !IsChunkedDump(finalBackupPath) {
+			// myloader keeps binary logging off unless --enable-binlog is given
+			if err := c.restoreWithMyloader(ctx, finalBackupPath, dbName, restoreCfg); err != nil {
+				return err
+			}
+			if err := c.restoreServerObjects(ctx, finalBackupPath, dbName, log); err != nil {
+				return err
+			}
+			return c.restoreInvalidViews(ctx, finalBackupPath, dbName, log)
 		}
 	}
 
 	// Fallback to mysql restore for .sql files
-	return c.restoreWithMysql(ctx, finalBackupPath, dbName)
+	if err := c.restoreWithMysql(ctx, finalBackupPath, dbName, restoreCfg, skipBinlog); err != nil {
+		return err
+	}
+	if err := c.restoreServerObjects(ctx, finalBackupPath, dbName, log); err != nil {
+		return err
+	}
+	return c.restoreInvalidViews(ctx, finalBackupPath, dbName, log)
 }
 
-func (c *Client) restoreWithMyloader(ctx context.Context, backupDir, dbName string) error {
+// checkBinlogPrivilege verifies that the current user may change sql_log_bin
+// for its own session (requires SUPER or SYSTEM_VARIABLES_ADMIN)
+func (c *Client) checkBinlogPrivilege(ctx context.Context) error {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SET SESSION sql_log_bin = 0"); err != nil {
+		return err
+	}
+
+	// Restore the default before the connection goes back to the pool
+	if _, err := conn.ExecContext(ctx, "SET SESSION sql_log_bin = 1"); err != nil {
+		return fmt.Errorf("failed to reset sql_log_bin: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Client) restoreWithMyloader(ctx context.Context, backupDir, dbName string, restoreCfg *config.RestoreConfig) error {
+	// myloader reads files itself, so it gets a rewritten view of the backup
+	if definerRewriter(restoreCfg.Definer) != nil {
+		view, err := definerView(backupDir, restoreCfg.Definer)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(view)
+		backupDir = view
+	}
+
+	if c.restoreTracking != nil {
+		return c.restoreTablesWithMyloader(ctx, backupDir, dbName, restoreCfg)
+	}
+	return c.runMyloader(ctx, backupDir, dbName, restoreCfg)
+}
+
+// runMyloader loads a mydumper directory into dbName, replacing the tables
+// it contains
+func (c *Client) runMyloader(ctx context.Context, backupDir, dbName string, restoreCfg *config.RestoreConfig) error {
 	// Build myloader command
 	args := []string{
 		"--overwrite-tables",
-		"--database", dbName,
-		"--directory", backupDir,
+		"--database=" + dbName,
+		"--directory=" + backupDir,
 		fmt.Sprintf("--threads=%d", c.config.Mydumper.Myloader.Threads),
 	}
 
+	// myloader already loads with foreign key checks off and creates
+	// triggers after the data, so only skipping them needs a flag
+	if restoreCfg.Triggers == config.TriggersSkip {
+		args = append(args, "--skip-triggers")
+	}
+
 	// Use defaults-file if specified, otherwise use individual connection parameters
 	if c.config.Mydumper.Myloader.DefaultsFile != "" {
 		args = append(args, fmt.Sprintf("--defaults-file=%s", c.config.Mydumper.Myloader.DefaultsFile))
 	} else {
-		args = append(args, fmt.Sprintf("--host=%s", c.config.Host))
-		args = append(args, fmt.Sprintf("--port=%d", c.config.Port))
+		args = append(args, c.connectionArgs()...)
 		args = append(args, fmt.Sprintf("--user=%s", c.config.Username))
 		if c.config.Password != "" {
 			args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
@@ -369,6 +624,7 @@ func (c *Client) restoreWithMyloader(ctx context.Context, backupDir, dbName stri
 	}
 
 	cmd := exec.CommandContext(ctx, c.config.Mydumper.Myloader.BinaryPath, args...)
+	c.logCommand(cmd)
 
 	// Capture stderr but don't display it unless there's an error
 	var stderr bytes.Buffer
@@ -382,29 +638,85 @@ func (c *Client) restoreWithMyloader(ctx context.Context, backupDir, dbName stri
 	return nil
 }
 
-func (c *Client) restoreWithMysql(ctx context.Context, backupPath, dbName string) error {
-	// Build mysql command
-	args := []string{
-		fmt.Sprintf("--host=%s", c.config.Host),
-		fmt.Sprintf("--port=%d", c.config.Port),
-		fmt.Sprintf("--user=%s", c.config.Username),
-		dbName,
+func (c *Client) restoreWithMysql(ctx context.Context, backupPath, dbName string, restoreCfg *config.RestoreConfig, skipBinlog bool) error {
+	// Session variables applied before the dump is loaded
+	var sessionVars []string
+	if skipBinlog {
+		sessionVars = append(sessionVars, "sql_log_bin=0")
+	}
+	if restoreCfg.DisableForeignKeyChecks {
+		sessionVars = append(sessionVars, "foreign_key_checks=0")
+	}
+	if restoreCfg.DisableUniqueChecks {
+		sessionVars = append(sessionVars, "unique_checks=0")
 	}
 
-	if c.config.Password != "" {
-		args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
+	// Open backup file, or the files of a chunked backup in load order
+	backupFile, closeBackup, err := openDump(backupPath)
+	if err != nil {
+		return err
 	}
+	defer closeBackup()
 
-	cmd := exec.CommandContext(ctx, c.config.MysqlPath, args...)
+	// Strip or rewrite DEFINER clauses for servers missing those accounts
+	input := backupFile
+	if definerRewriter(restoreCfg.Definer) != nil {
+		rewritten := definerReader(backupFile, restoreCfg.Definer)
+		defer rewritten.Close()
+		input = rewritten
+	}
 
-	// Open backup file
-	backupFile, err := os.Open(backupPath)
-	if err != nil {
-		return fmt.Errorf("failed to open backup file: %w", err)
+	if restoreCfg.Triggers != config.TriggersSkip && restoreCfg.Triggers != config.TriggersDefer {
+		return c.runMysql(ctx, dbName, sessionVars, input)
+	}
+
+	// Divert trigger definitions out of the dump while it is being loaded
+	var triggers bytes.Buffer
+	pipeReader, pipeWriter := io.Pipe()
+	defer pipeReader.Close()
+
+	filterDone := make(chan error, 1)
+	go func() {
+		err := splitTriggers(input, pipeWriter, &triggers)
+		pipeWriter.CloseWithError(err)
+		filterDone <- err
+	}()
+
+	if err := c.runMysql(ctx, dbName, sessionVars, pipeReader); err != nil {
+		return err
+	}
+	if err := <-filterDone; err != nil {
+		return fmt.Errorf("failed to filter triggers from backup: %w", err)
+	}
+
+	if restoreCfg.Triggers == config.TriggersDefer && triggers.Len() > 0 {
+		if err := c.runMysql(ctx, dbName, sessionVars, &triggers); err != nil {
+			return fmt.Errorf("failed to create deferred triggers: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// runMysql feeds input to the mysql client connected to dbName
+func (c *Client) runMysql(ctx context.Context, dbName string, sessionVars []string, input io.Reader) error {
+	// Build mysql command
+	args := append(c.toolConnectionArgs(), fmt.Sprintf("--user=%s", c.config.Username))
+	args = append(args, c.charsetArgs()...)
+
+	if len(sessionVars) > 0 {
+		args = append(args, "--init-command=SET SESSION "+strings.Join(sessionVars, ", "))
+	}
+
+	args = append(args, "--database="+dbName)
+
+	if c.config.Password != "" {
+		args = append(args, fmt.Sprintf("--password=%s", c.config.Password))
 	}
-	defer backupFile.Close()
 
-	cmd.Stdin = backupFile
+	cmd := c.toolCommand(ctx, c.config.MysqlPath, clientTools, args...)
+	c.logCommand(cmd)
+	cmd.Stdin = input
 
 	// Capture stderr but don't display it unless there's an error
 	var stderr bytes.Buffer
@@ -517,11 +829,8 @@ func isCommonWarning(line string) bool {
 	return false
 }
 
-// isCompressedBackup checks if backup is compressed
+// isCompressedBackup checks if a backup is compressed from its magic bytes,
+// so renamed and extension-less archives restore too
 func (c *Client) isCompressedBackup(backupPath string) bool {
-	ext := strings.ToLower(filepath.Ext(backupPath))
-	return ext == ".gz" || ext == ".zst" || ext == ".xz" || 
-		   strings.HasSuffix(strings.ToLower(backupPath), ".tar.gz") ||
-		   strings.HasSuffix(strings.ToLower(backupPath), ".tar.zst") ||
-		   strings.HasSuffix(strings.ToLower(backupPath), ".tar.xz")
+	return compression.DetectFormat(backupPath) != ""
 }
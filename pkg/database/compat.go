@@ -0,0 +1,135 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+var (
+	// MySQL 8 collations 5.7 does not know, e.g. utf8mb4_0900_ai_ci or
+	// utf8mb4_de_pb_0900_as_cs; utf8mb4_0900_bin is handled separately
+	collation0900Pattern = regexp.MustCompile(`\butf8mb4_(?:[a-z]{2,3}_(?:[a-z]{2,5}_)?)?0900_[a-z_]+\b`)
+	utf8mb3Pattern       = regexp.MustCompile(`\butf8mb3(_[a-z0-9_]+)?\b`)
+	encryptionPattern    = regexp.MustCompile(`(?i)(?:/\*!80016\s+)?(?:DEFAULT\s+)?ENCRYPTION\s*=\s*'[NY]'(?:\s*\*/)?`)
+)
+
+// rewriteFor57 removes MySQL 8 syntax from one line of DDL
+func rewriteFor57(line string) string {
+	line = strings.ReplaceAll(line, "utf8mb4_0900_bin", "utf8mb4_bin")
+	line = collation0900Pattern.ReplaceAllString(line, "utf8mb4_unicode_ci")
+	line = utf8mb3Pattern.ReplaceAllString(line, "utf8$1")
+	line = encryptionPattern.ReplaceAllString(line, "")
+	return line
+}
+
+// ApplyTargetCompat rewrites a finished backup so it restores on an older
+// server. Only DDL is touched: INSERT lines of mysqldump files and mydumper
+// data files are left alone so table contents are never altered. It
+// returns the number of changed lines.
+func ApplyTargetCompat(backupPath, target string) (int, error) {
+	if target != config.Compat57 {
+		return 0, fmt.Errorf("unsupported target compat %q", target)
+	}
+
+	return rewriteBackup(backupPath, rewriteFor57)
+}
+
+// rewriteBackup applies rewrite to the DDL lines of a backup in place: the
+// whole mysqldump file, or the *-schema*.sql files of a mydumper directory
+func rewriteBackup(backupPath string, rewrite func(string) string) (int, error) {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return 0, err
+	}
+	if !info.IsDir() {
+		return rewriteFile(backupPath, rewrite)
+	}
+
+	// Chunked mysqldump backups keep DDL in several plain .sql files
+	pattern := "*-schema*.sql"
+	if IsChunkedDump(backupPath) {
+		pattern = "*.sql"
+	}
+	files, err := filepath.Glob(filepath.Join(backupPath, pattern))
+	if err != nil {
+		return 0, err
+	}
+	if compressed, _ := filepath.Glob(filepath.Join(backupPath, "*-schema*.sql.*")); len(compressed) > 0 {
+		return 0, fmt.Errorf("rewriting needs uncompressed schema files, disable mydumper compress_method")
+	}
+
+	changed := 0
+	for _, file := range files {
+		n, err := rewriteFile(file, rewrite)
+		if err != nil {
+			return changed, err
+		}
+		changed += n
+	}
+	return changed, nil
+}
+
+// rewriteFile applies rewrite to the DDL lines of a file in place
+func rewriteFile(path string, rewrite func(string) string) (int, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer in.Close()
+
+	tempPath := path + ".rewrite"
+	out, err := os.Create(tempPath)
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tempPath)
+
+	changed, err := rewriteStream(in, out, rewrite)
+	if err != nil {
+		out.Close()
+		return 0, err
+	}
+	if err := out.Close(); err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+	return changed, os.Rename(tempPath, path)
+}
+
+// rewriteStream copies r to w, applying rewrite to every line that is not
+// an INSERT so table contents are never altered
+func rewriteStream(r io.Reader, w io.Writer, rewrite func(string) string) (int, error) {
+	changed := 0
+	reader := bufio.NewReader(r)
+	writer := bufio.NewWriter(w)
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			if !strings.HasPrefix(line, "INSERT INTO ") {
+				if rewritten := rewrite(line); rewritten != line {
+					line = rewritten
+					changed++
+				}
+			}
+			if _, err := writer.WriteString(line); err != nil {
+				return changed, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return changed, readErr
+		}
+	}
+	return changed, writer.Flush()
+}
@@ -0,0 +1,48 @@
+package database
+
+import "testing"
+
+func TestRewriteFor57(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;",
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;",
+		},
+		{
+			"  `name` varchar(64) COLLATE utf8mb4_0900_as_cs NOT NULL,",
+			"  `name` varchar(64) COLLATE utf8mb4_unicode_ci NOT NULL,",
+		},
+		{
+			"  `code` varchar(8) COLLATE utf8mb4_0900_bin,",
+			"  `code` varchar(8) COLLATE utf8mb4_bin,",
+		},
+		{
+			"  `de` text COLLATE utf8mb4_de_pb_0900_ai_ci,",
+			"  `de` text COLLATE utf8mb4_unicode_ci,",
+		},
+		{
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8mb3 COLLATE=utf8mb3_general_ci;",
+			") ENGINE=InnoDB DEFAULT CHARSET=utf8 COLLATE=utf8_general_ci;",
+		},
+		{
+			"CREATE DATABASE `app` /*!40100 DEFAULT CHARACTER SET utf8mb4 */ /*!80016 DEFAULT ENCRYPTION='N' */;",
+			"CREATE DATABASE `app` /*!40100 DEFAULT CHARACTER SET utf8mb4 */ ;",
+		},
+		{
+			") ENGINE=InnoDB DEFAULT CHARSET=latin1 ENCRYPTION='N';",
+			") ENGINE=InnoDB DEFAULT CHARSET=latin1 ;",
+		},
+		{
+			"  `id` int NOT NULL,",
+			"  `id` int NOT NULL,",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := rewriteFor57(tt.in); got != tt.want {
+			t.Errorf("rewriteFor57(%q)\n got %q\nwant %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package database
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/abdullahainun/tenangdb/internal/docker"
+)
+
+// Client tools looked up inside database.container, MariaDB names first
+// since recent MariaDB images no longer ship the mysql ones
+var (
+	dumpTools   = []string{"mariadb-dump", "mysqldump"}
+	clientTools = []string{"mariadb", "mysql"}
+)
+
+// toolCommand runs mysqldump or mysql: the binary at path, or the one inside
+// database.container through docker exec
+func (c *Client) toolCommand(ctx context.Context, path string, tools []string, args ...string) *exec.Cmd {
+	argv := c.toolArgs(path, tools, args...)
+	return exec.CommandContext(ctx, argv[0], argv[1:]...)
+}
+
+func (c *Client) toolArgs(path string, tools []string, args ...string) []string {
+	if c.config.Container == "" {
+		return append([]string{path}, args...)
+	}
+	return docker.ExecArgs(c.config, tools, args...)
+}
+
+// toolConnectionArgs returns the connection options of mysqldump and mysql.
+// Inside a container they use the server's local socket.
+func (c *Client) toolConnectionArgs() []string {
+	if c.config.Container != "" {
+		return nil
+	}
+	return c.connectionArgs()
+}
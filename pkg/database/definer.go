@@ -0,0 +1,136 @@
+package database
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// definerClause matches DEFINER=`user`@`host` in routines, views, triggers
+// and events, with the whitespace that follows it
+var definerClause = regexp.MustCompile(`(?i)\bDEFINER\s*=\s*(?:CURRENT_USER(?:\(\))?|` + definerPattern + `)\s*`)
+
+// definerRewriter returns the line rewrite for a DEFINER handling mode, or
+// nil if clauses are kept as they are
+func definerRewriter(mode string) func(string) string {
+	switch mode {
+	case "", config.DefinerKeep:
+		return nil
+	case config.DefinerStrip:
+		return func(line string) string {
+			if !strings.Contains(strings.ToUpper(line), "DEFINER") {
+				return line
+			}
+			return definerClause.ReplaceAllString(line, "")
+		}
+	default:
+		replacement := "DEFINER=" + quoteDefiner(mode) + " "
+		return func(line string) string {
+			if !strings.Contains(strings.ToUpper(line), "DEFINER") {
+				return line
+			}
+			return definerClause.ReplaceAllLiteralString(line, replacement)
+		}
+	}
+}
+
+// quoteDefiner renders an account given as app@% in DEFINER syntax
+func quoteDefiner(account string) string {
+	if account == "CURRENT_USER" || strings.HasPrefix(account, "`") || strings.HasPrefix(account, "'") {
+		return account
+	}
+	at := strings.LastIndex(account, "@")
+	return quoteIdentifier(account[:at]) + "@" + quoteIdentifier(account[at+1:])
+}
+
+// ApplyDefiner strips or rewrites the DEFINER clauses of a finished backup
+// and returns the number of changed lines
+func ApplyDefiner(backupPath, mode string) (int, error) {
+	rewrite := definerRewriter(mode)
+	if rewrite == nil {
+		return 0, nil
+	}
+	return rewriteBackup(backupPath, rewrite)
+}
+
+// definerReader rewrites DEFINER clauses of a dump while it is being read
+func definerReader(r io.Reader, mode string) io.ReadCloser {
+	rewrite := definerRewriter(mode)
+	pipeReader, pipeWriter := io.Pipe()
+	go func() {
+		_, err := rewriteStream(r, pipeWriter, rewrite)
+		pipeWriter.CloseWithError(err)
+	}()
+	return pipeReader
+}
+
+// definerView prepares a mydumper directory for myloader with its DEFINER
+// clauses rewritten, without changing the backup: schema files are
+// rewritten copies and data files are symlinks. The caller removes the
+// returned directory.
+func definerView(backupDir, mode string) (string, error) {
+	view, err := os.MkdirTemp(filepath.Dir(backupDir), ".definer-"+filepath.Base(backupDir)+"-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create restore directory: %w", err)
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		os.RemoveAll(view)
+		return "", err
+	}
+
+	rewrite := definerRewriter(mode)
+	for _, entry := range entries {
+		if strings.Contains(entry.Name(), "-schema") && strings.Contains(entry.Name(), ".sql.") {
+			os.RemoveAll(view)
+			return "", fmt.Errorf("rewriting definers needs uncompressed schema files, %s is compressed", entry.Name())
+		}
+
+		source, err := filepath.Abs(filepath.Join(backupDir, entry.Name()))
+		if err != nil {
+			os.RemoveAll(view)
+			return "", err
+		}
+		target := filepath.Join(view, entry.Name())
+
+		if isSchemaFile(entry.Name()) {
+			err = copyRewritten(source, target, rewrite)
+		} else {
+			err = os.Symlink(source, target)
+		}
+		if err != nil {
+			os.RemoveAll(view)
+			return "", fmt.Errorf("failed to prepare %s: %w", entry.Name(), err)
+		}
+	}
+	return view, nil
+}
+
+// isSchemaFile reports whether a mydumper file holds DDL
+func isSchemaFile(name string) bool {
+	return strings.Contains(name, "-schema") && strings.HasSuffix(name, ".sql")
+}
+
+func copyRewritten(source, target string, rewrite func(string) string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := rewriteStream(in, out, rewrite); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
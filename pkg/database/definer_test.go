@@ -0,0 +1,38 @@
+package database
+
+import "testing"
+
+func TestDefinerRewriter(t *testing.T) {
+	lines := []string{
+		"CREATE DEFINER=`root`@`localhost` PROCEDURE `refresh`()\n",
+		"/*!50013 DEFINER=`admin`@`%` SQL SECURITY DEFINER */\n",
+		"/*!50003 CREATE*/ /*!50017 DEFINER='app'@'10.0.0.%'*/ /*!50003 TRIGGER `t` BEFORE INSERT ON `orders` FOR EACH ROW SET NEW.x = 1 */;;\n",
+		"CREATE DEFINER=CURRENT_USER EVENT `purge` ON SCHEDULE EVERY 1 DAY DO DELETE FROM logs\n",
+	}
+
+	strip := []string{
+		"CREATE PROCEDURE `refresh`()\n",
+		"/*!50013 SQL SECURITY DEFINER */\n",
+		"/*!50003 CREATE*/ /*!50017 */ /*!50003 TRIGGER `t` BEFORE INSERT ON `orders` FOR EACH ROW SET NEW.x = 1 */;;\n",
+		"CREATE EVENT `purge` ON SCHEDULE EVERY 1 DAY DO DELETE FROM logs\n",
+	}
+	rewrite := []string{
+		"CREATE DEFINER=`app`@`%` PROCEDURE `refresh`()\n",
+		"/*!50013 DEFINER=`app`@`%` SQL SECURITY DEFINER */\n",
+		"/*!50003 CREATE*/ /*!50017 DEFINER=`app`@`%` */ /*!50003 TRIGGER `t` BEFORE INSERT ON `orders` FOR EACH ROW SET NEW.x = 1 */;;\n",
+		"CREATE DEFINER=`app`@`%` EVENT `purge` ON SCHEDULE EVERY 1 DAY DO DELETE FROM logs\n",
+	}
+
+	if definerRewriter("keep") != nil || definerRewriter("") != nil {
+		t.Error("keep should not rewrite")
+	}
+
+	for mode, want := range map[string][]string{"strip": strip, "app@%": rewrite} {
+		fn := definerRewriter(mode)
+		for i, line := range lines {
+			if got := fn(line); got != want[i] {
+				t.Errorf("%s: got %q, want %q", mode, got, want[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// VerifyRestore checks a restored database: it must hold tables, and
+// CHECK TABLE must find each of them intact. It returns the number of
+// tables checked.
+func (c *Client) VerifyRestore(ctx context.Context, dbName string) (int, error) {
+	tables, err := c.baseTables(ctx, dbName)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list restored tables: %w", err)
+	}
+	if len(tables) == 0 {
+		return 0, fmt.Errorf("restored database %s has no tables", dbName)
+	}
+
+	for _, table := range tables {
+		if err := c.checkTable(ctx, dbName, table); err != nil {
+			return 0, err
+		}
+	}
+	return len(tables), nil
+}
+
+// baseTables returns the names of the base tables of a database, without
+// views, in name order
+func (c *Client) baseTables(ctx context.Context, dbName string) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME`, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// checkTable runs CHECK TABLE, which reports one row per message with the
+// final status last
+func (c *Client) checkTable(ctx context.Context, dbName, table string) error {
+	rows, err := c.db.QueryContext(ctx, "CHECK TABLE "+quoteIdentifier(dbName)+"."+quoteIdentifier(table))
+	if err != nil {
+		return fmt.Errorf("failed to check table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var status string
+	for rows.Next() {
+		var name, op, msgType sql.NullString
+		var msgText string
+		if err := rows.Scan(&name, &op, &msgType, &msgText); err != nil {
+			return fmt.Errorf("failed to check table %s: %w", table, err)
+		}
+		if strings.EqualFold(msgType.String, "status") || strings.EqualFold(msgType.String, "error") {
+			status = msgText
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check table %s: %w", table, err)
+	}
+
+	if !strings.EqualFold(status, "OK") && !strings.EqualFold(status, "Table is already up to date") {
+		return fmt.Errorf("table %s failed CHECK TABLE: %s", table, status)
+	}
+	return nil
+}
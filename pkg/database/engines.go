@@ -0,0 +1,155 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// nonTransactionalEngines are the engines whose tables --single-transaction
+// does not give a consistent snapshot of
+var nonTransactionalEngines = []string{"MEMORY", "FEDERATED", "CSV", "MyISAM"}
+
+// EngineTable is a table on an engine without transactions
+type EngineTable struct {
+	Name   string
+	Engine string
+}
+
+// SetNonTransactional sets how dumps treat tables on engines without
+// transactions: config.NonTransactionalWarn, Lock or Exclude
+func (c *Client) SetNonTransactional(mode string) {
+	c.nonTransactional = mode
+}
+
+// NonTransactionalTables returns the base tables of dbName on MEMORY,
+// FEDERATED, CSV and MyISAM engines, in name order
+func (c *Client) NonTransactionalTables(ctx context.Context, dbName string) ([]EngineTable, error) {
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(nonTransactionalEngines)), ",")
+	args := []interface{}{dbName}
+	for _, engine := range nonTransactionalEngines {
+		args = append(args, engine)
+	}
+
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME, ENGINE FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' AND ENGINE IN (`+placeholders+`) ORDER BY TABLE_NAME`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list table engines: %w", err)
+	}
+	defer rows.Close()
+
+	var tables []EngineTable
+	for rows.Next() {
+		var t EngineTable
+		if err := rows.Scan(&t.Name, &t.Engine); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// nonTransactionalTables finds the tables of dbName that --single-transaction
+// does not protect and logs what the dump does about them. It returns the
+// tables, or nil when there are none.
+func (c *Client) nonTransactionalTables(ctx context.Context, dbName string) ([]EngineTable, error) {
+	tables, err := c.NonTransactionalTables(ctx, dbName)
+	if err != nil || len(tables) == 0 {
+		return nil, err
+	}
+
+	if c.logger != nil {
+		names := make([]string, len(tables))
+		for i, t := range tables {
+			names[i] = t.Name + " (" + t.Engine + ")"
+		}
+		log := c.logger.WithField("database", dbName).WithField("tables", names)
+		switch c.nonTransactional {
+		case config.NonTransactionalLock:
+			log.Info("🔒 Locking tables for the dump, some engines are not covered by --single-transaction")
+		case config.NonTransactionalExclude:
+			log.Warn("⚠️ Leaving tables on non-transactional engines out of the backup")
+		default:
+			log.Warn("⚠️ Tables on non-transactional engines are not protected by --single-transaction and may be inconsistent, set backup.non_transactional to lock or exclude them")
+		}
+	}
+	return tables, nil
+}
+
+// mysqldumpEngineArgs adapts mysqldump options to the tables on engines
+// without transactions: locking replaces the transaction, excluded tables
+// are ignored
+func (c *Client) mysqldumpEngineArgs(options []string, dbName string, tables []EngineTable) []string {
+	switch c.nonTransactional {
+	case config.NonTransactionalLock:
+		if len(tables) == 0 {
+			return options
+		}
+		locked := make([]string, 0, len(options))
+		for _, option := range options {
+			if option != "--single-transaction" && option != "--skip-lock-tables" {
+				locked = append(locked, option)
+			}
+		}
+		return append(locked, "--lock-tables")
+	case config.NonTransactionalExclude:
+		for _, t := range tables {
+			options = append(options, fmt.Sprintf("--ignore-table=%s.%s", dbName, t.Name))
+		}
+	}
+	return options
+}
+
+// mydumperEngineArgs adapts mydumper arguments to the tables on engines
+// without transactions when they are locked: the options that assume every
+// table is transactional are dropped, so mydumper keeps its lock until those
+// tables are dumped. Excluded tables are left to mydumperExcludeArgs.
+func (c *Client) mydumperEngineArgs(args []string, tables []EngineTable) []string {
+	if len(tables) == 0 || c.nonTransactional != config.NonTransactionalLock {
+		return args
+	}
+
+	locked := make([]string, 0, len(args))
+	for _, arg := range args {
+		switch arg {
+		case "--trx-tables", "--no-locks", "--trx-consistency-only":
+			continue
+		}
+		locked = append(locked, arg)
+	}
+	return locked
+}
+
+// excludedEngineTables returns the names of the tables on engines without
+// transactions that backup.non_transactional leaves out
+func (c *Client) excludedEngineTables(tables []EngineTable) []string {
+	if c.nonTransactional != config.NonTransactionalExclude {
+		return nil
+	}
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// mydumperExcludeArgs filters tables out of a mydumper dump with --regex,
+// mydumper's only filter that works across its versions
+func mydumperExcludeArgs(dbName string, tables []string) []string {
+	if len(tables) == 0 {
+		return nil
+	}
+	return []string{"--regex=" + excludeTablesRegex(dbName, tables)}
+}
+
+// excludeTablesRegex matches every db.table name except the given tables
+func excludeTablesRegex(dbName string, tables []string) string {
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = regexp.QuoteMeta(t)
+	}
+	return fmt.Sprintf(`^(?!%s\.(%s)$)`, regexp.QuoteMeta(dbName), strings.Join(names, "|"))
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestMysqldumpEngineArgs(t *testing.T) {
+	options := []string{"--single-transaction", "--skip-lock-tables", "--hex-blob"}
+	tables := []EngineTable{{Name: "sessions", Engine: "MEMORY"}, {Name: "legacy", Engine: "MyISAM"}}
+
+	tests := []struct {
+		mode   string
+		tables []EngineTable
+		want   []string
+	}{
+		{config.NonTransactionalWarn, tables, options},
+		{config.NonTransactionalLock, tables, []string{"--hex-blob", "--lock-tables"}},
+		{config.NonTransactionalLock, nil, options},
+		{config.NonTransactionalExclude, tables, append(append([]string{}, options...), "--ignore-table=app.sessions", "--ignore-table=app.legacy")},
+	}
+	for _, tt := range tests {
+		c := &Client{nonTransactional: tt.mode}
+		got := c.mysqldumpEngineArgs(append([]string{}, options...), "app", tt.tables)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s with %d tables: %v, want %v", tt.mode, len(tt.tables), got, tt.want)
+		}
+	}
+}
+
+func TestExcludeTablesRegex(t *testing.T) {
+	// mydumper matches with PCRE; Go's regexp has no lookahead, so only the
+	// pattern and its quoting are checked
+	pattern := excludeTablesRegex("app.v2", []string{"sessions", "log+archive"})
+	want := `^(?!app\.v2\.(sessions|log\+archive)$)`
+	if pattern != want {
+		t.Errorf("excludeTablesRegex = %s, want %s", pattern, want)
+	}
+}
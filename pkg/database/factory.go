@@ -2,6 +2,9 @@ package database
 
 import (
 	"fmt"
+	"strconv"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
 )
 
 // DefaultProviderFactory implements ProviderFactory
@@ -27,18 +30,52 @@ func (f *DefaultProviderFactory) CreateProvider(config *ProviderConfig) (Provide
 	case MySQL:
 		return NewMySQLProvider(config)
 	case PostgreSQL:
-		// Will be implemented in v1.2.0
-		return nil, fmt.Errorf("PostgreSQL support coming in v1.2.0")
+		return NewPostgreSQLProvider(config)
 	default:
 		return nil, fmt.Errorf("unsupported database type: %s", config.Type)
 	}
 }
 
+// NewConfiguredProvider creates the provider of database.type from the
+// database section of the config file
+func NewConfiguredProvider(cfg *config.DatabaseConfig) (Provider, error) {
+	providerConfig := &ProviderConfig{
+		Type:         DatabaseType(cfg.Type),
+		Host:         cfg.Host,
+		Port:         cfg.Port,
+		Username:     cfg.Username,
+		Password:     cfg.Password,
+		SSLMode:      cfg.SSLMode,
+		Timeout:      strconv.Itoa(cfg.Timeout) + "s",
+		Charset:      cfg.Charset,
+		Collation:    cfg.Collation,
+		MaxOpenConns: cfg.MaxOpenConns,
+		MaxIdleConns: cfg.MaxIdleConns,
+	}
+	if cfg.DialTimeout > 0 {
+		providerConfig.DialTimeout = cfg.DialTimeout.String()
+	}
+	if cfg.IsPostgreSQL() {
+		providerConfig.PostgreSQL = &PostgreSQLConfig{
+			Format:            cfg.PostgreSQL.Format,
+			UsePgDumpParallel: cfg.PostgreSQL.Format == PgFormatDirectory,
+			Jobs:              cfg.PostgreSQL.Jobs,
+			PgDumpPath:        cfg.PostgreSQL.PgDumpPath,
+			PgRestorePath:     cfg.PostgreSQL.PgRestorePath,
+			PsqlPath:          cfg.PostgreSQL.PsqlPath,
+		}
+	} else {
+		providerConfig.DumpToolPath = cfg.MysqldumpPath
+		providerConfig.ClientToolPath = cfg.MysqlPath
+	}
+	return NewProviderFactory().CreateProvider(providerConfig)
+}
+
 // GetSupportedTypes returns the list of supported database types
 func (f *DefaultProviderFactory) GetSupportedTypes() []DatabaseType {
 	return []DatabaseType{
 		MySQL,
-		// PostgreSQL will be added in v1.2.0
+		PostgreSQL,
 	}
 }
 
@@ -50,7 +87,7 @@ func (f *DefaultProviderFactory) detectDatabaseType(config *ProviderConfig) Data
 		return MySQL
 	}
 	
-	// Check for explicit PostgreSQL configuration (future)
+	// Check for explicit PostgreSQL configuration
 	if config.PostgreSQL != nil {
 		return PostgreSQL
 	}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/compression"
+)
+
+// WriteHeartbeat records runID in the heartbeat table of dbName, creating
+// the table on first use, and returns when it was written. A dump started
+// afterwards must hold the row.
+func (c *Client) WriteHeartbeat(ctx context.Context, dbName, table, runID string) (time.Time, error) {
+	name := quoteIdentifier(dbName) + "." + quoteIdentifier(table)
+	create := "CREATE TABLE IF NOT EXISTS " + name + ` (
+		id TINYINT UNSIGNED NOT NULL PRIMARY KEY,
+		run_id VARCHAR(64) NOT NULL,
+		written_at DATETIME(6) NOT NULL
+	) ENGINE=InnoDB COMMENT='tenangdb backup heartbeat'`
+	if _, err := c.db.ExecContext(ctx, create); err != nil {
+		return time.Time{}, fmt.Errorf("failed to create heartbeat table: %w", err)
+	}
+
+	writtenAt := time.Now().UTC()
+	if _, err := c.db.ExecContext(ctx, "REPLACE INTO "+name+" (id, run_id, written_at) VALUES (1, ?, ?)", runID, writtenAt); err != nil {
+		return time.Time{}, fmt.Errorf("failed to write heartbeat: %w", err)
+	}
+	return writtenAt, nil
+}
+
+// DumpHasHeartbeat reports whether an uncompressed backup of dbName holds
+// the heartbeat row of runID: in the table's data files of a mydumper
+// directory, anywhere in a mysqldump file or chunked directory
+func DumpHasHeartbeat(backupPath, dbName, table, runID string) (bool, error) {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return false, err
+	}
+	if !info.IsDir() || IsChunkedDump(backupPath) {
+		r, closeDump, err := openDump(backupPath)
+		if err != nil {
+			return false, err
+		}
+		defer closeDump()
+		return containsBytes(r, []byte(runID))
+	}
+
+	entries, err := os.ReadDir(backupPath)
+	if err != nil {
+		return false, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	groups, _ := groupMydumperFiles(names)
+	for _, name := range groups[table] {
+		if strings.Contains(name, "-schema") {
+			continue
+		}
+		found, err := fileHasBytes(filepath.Join(backupPath, name), []byte(runID))
+		if err != nil || found {
+			return found, err
+		}
+	}
+	return false, nil
+}
+
+// fileHasBytes reports whether a possibly compressed file holds needle
+func fileHasBytes(path string, needle []byte) (bool, error) {
+	r, closeFile, err := compression.OpenDecompressed(path)
+	if err != nil {
+		return false, err
+	}
+	defer closeFile()
+	return containsBytes(r, needle)
+}
+
+// containsBytes reports whether r holds needle, reading it in pieces that
+// overlap by the needle's length so no match is split between them
+func containsBytes(r io.Reader, needle []byte) (bool, error) {
+	if len(needle) == 0 {
+		return true, nil
+	}
+	buf := make([]byte, 0, 64*1024+len(needle))
+	for {
+		n, err := r.Read(buf[len(buf):cap(buf)])
+		buf = buf[:len(buf)+n]
+		if bytes.Contains(buf, needle) {
+			return true, nil
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		if keep := len(needle) - 1; len(buf) > keep {
+			buf = append(buf[:0], buf[len(buf)-keep:]...)
+		}
+	}
+}
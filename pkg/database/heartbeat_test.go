@@ -0,0 +1,67 @@
+package database
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestContainsBytesAcrossReads(t *testing.T) {
+	content := strings.Repeat("x", 100000) + "run-20250601-abc" + strings.Repeat("y", 100)
+	found, err := containsBytes(iotest.HalfReader(strings.NewReader(content)), []byte("run-20250601-abc"))
+	if err != nil || !found {
+		t.Errorf("containsBytes() = %v, %v, want true", found, err)
+	}
+	found, err = containsBytes(strings.NewReader(content), []byte("run-20250601-xyz"))
+	if err != nil || found {
+		t.Errorf("containsBytes() = %v, %v, want false", found, err)
+	}
+}
+
+func TestDumpHasHeartbeat(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if !strings.HasSuffix(name, ".gz") {
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := gzip.NewWriter(file)
+		w.Write([]byte(content))
+		w.Close()
+		file.Close()
+	}
+
+	// The run ID in another table's data doesn't count
+	write("metadata", "")
+	write("app-schema-create.sql", "CREATE DATABASE app;\n")
+	write("app.notes-schema.sql", "CREATE TABLE notes (body TEXT);\n")
+	write("app.notes.00000.sql", "INSERT INTO notes VALUES (\"run-new\");\n")
+	write("app.tenangdb_heartbeat-schema.sql", "CREATE TABLE tenangdb_heartbeat (id TINYINT);\n")
+	write("app.tenangdb_heartbeat.00000.sql.gz", "INSERT INTO `tenangdb_heartbeat` VALUES(1,\"run-old\",\"2025-06-01 02:00:00\");\n")
+
+	for runID, want := range map[string]bool{"run-old": true, "run-new": false} {
+		found, err := DumpHasHeartbeat(dir, "app", "tenangdb_heartbeat", runID)
+		if err != nil || found != want {
+			t.Errorf("DumpHasHeartbeat(%s) = %v, %v, want %v", runID, found, err, want)
+		}
+	}
+
+	dumpFile := filepath.Join(t.TempDir(), "app.sql")
+	if err := os.WriteFile(dumpFile, []byte("INSERT INTO `tenangdb_heartbeat` VALUES (1,'run-old','2025-06-01 02:00:00');\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := DumpHasHeartbeat(dumpFile, "app", "tenangdb_heartbeat", "run-old"); err != nil || !found {
+		t.Errorf("DumpHasHeartbeat(mysqldump) = %v, %v, want true", found, err)
+	}
+}
@@ -0,0 +1,74 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ImpactSample is the load of the server at one moment of a backup run.
+// Connections of the backup user are left out of the waits, so they show
+// what other clients suffered.
+type ImpactSample struct {
+	At             time.Time
+	ThreadsRunning int
+	RowLockWaits   int64         // Innodb_row_lock_waits, a counter since server start
+	LockWaiters    int           // transactions waiting for a row lock
+	MaxLockWait    time.Duration // longest current row lock wait
+	BlockedQueries int           // statements waiting for a table, metadata or global read lock
+	MaxBlocked     time.Duration // longest of those waits
+}
+
+// SampleImpact reads the running threads and the current lock waits of the
+// server. Reading the waits of other users needs the PROCESS privilege.
+func (c *Client) SampleImpact(ctx context.Context) (ImpactSample, error) {
+	sample := ImpactSample{At: time.Now()}
+
+	rows, err := c.db.QueryContext(ctx, "SHOW GLOBAL STATUS WHERE Variable_name IN ('Threads_running', 'Innodb_row_lock_waits')")
+	if err != nil {
+		return sample, fmt.Errorf("failed to read server status: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var value int64
+		if err := rows.Scan(&name, &value); err != nil {
+			return sample, err
+		}
+		switch name {
+		case "Threads_running":
+			sample.ThreadsRunning = int(value)
+		case "Innodb_row_lock_waits":
+			sample.RowLockWaits = value
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return sample, err
+	}
+
+	// INNODB_TRX is what SHOW ENGINE INNODB STATUS reports as LOCK WAIT,
+	// in a form that can be queried on 5.7 and 8.0 alike
+	var maxLockWait int64
+	if err := c.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(MAX(TIMESTAMPDIFF(SECOND, t.trx_wait_started, NOW())), 0)
+		FROM information_schema.INNODB_TRX t
+		LEFT JOIN information_schema.PROCESSLIST p ON p.ID = t.trx_mysql_thread_id
+		WHERE t.trx_state = 'LOCK WAIT' AND COALESCE(p.USER, '') <> SUBSTRING_INDEX(CURRENT_USER(), '@', 1)`).
+		Scan(&sample.LockWaiters, &maxLockWait); err != nil {
+		return sample, fmt.Errorf("failed to read row lock waits: %w", err)
+	}
+	sample.MaxLockWait = time.Duration(maxLockWait) * time.Second
+
+	// FLUSH TABLES WITH READ LOCK and LOCK TABLES of a dump make writers
+	// wait in these states
+	var maxBlocked int64
+	if err := c.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(MAX(TIME), 0)
+		FROM information_schema.PROCESSLIST
+		WHERE (STATE LIKE 'Waiting for%lock' OR STATE = 'Waiting for table flush')
+		AND USER <> SUBSTRING_INDEX(CURRENT_USER(), '@', 1)`).
+		Scan(&sample.BlockedQueries, &maxBlocked); err != nil {
+		return sample, fmt.Errorf("failed to read blocked queries: %w", err)
+	}
+	sample.MaxBlocked = time.Duration(maxBlocked) * time.Second
+
+	return sample, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// ApplyMasking overwrites columns of dbName according to the rules that
+// apply to it and returns the number of changed rows. Rules whose table or
+// column does not exist in dbName have nothing to mask; they are skipped
+// and returned as table.column.
+func (c *Client) ApplyMasking(ctx context.Context, dbName string, rules []config.MaskingRule) (int64, []string, error) {
+	columns, err := c.columnsOf(ctx, dbName)
+	if err != nil {
+		return 0, nil, err
+	}
+	apply, skipped := maskableRules(rules, dbName, columns)
+
+	var changed int64
+	for _, rule := range apply {
+		// Value is a trusted SQL expression from the config file
+		stmt := fmt.Sprintf("UPDATE %s.%s SET %s = %s",
+			quoteIdentifier(dbName), quoteIdentifier(rule.Table), quoteIdentifier(rule.Column), rule.Value)
+		result, err := c.db.ExecContext(ctx, stmt)
+		if err != nil {
+			return changed, skipped, fmt.Errorf("failed to mask %s.%s: %w", rule.Table, rule.Column, err)
+		}
+		if n, err := result.RowsAffected(); err == nil {
+			changed += n
+		}
+	}
+	return changed, skipped, nil
+}
+
+// columnsOf returns the columns of every table of dbName as lower-case
+// table.column keys
+func (c *Client) columnsOf(ctx context.Context, dbName string) (map[string]bool, error) {
+	rows, err := c.db.QueryContext(ctx,
+		"SELECT TABLE_NAME, COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ?", dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s: %w", dbName, err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, err
+		}
+		columns[columnKey(table, column)] = true
+	}
+	return columns, rows.Err()
+}
+
+// maskableRules splits the rules for dbName into those whose column exists
+// and the table.column of those whose table or column is missing. Names
+// are matched case-insensitively: a rule that only differs in case from an
+// existing column is applied, and fails on servers where that matters
+// rather than leaving the column unmasked.
+func maskableRules(rules []config.MaskingRule, dbName string, columns map[string]bool) ([]config.MaskingRule, []string) {
+	var apply []config.MaskingRule
+	var skipped []string
+	for _, rule := range rules {
+		if rule.Database != "" && rule.Database != dbName {
+			continue
+		}
+		if !columns[columnKey(rule.Table, rule.Column)] {
+			skipped = append(skipped, rule.Table+"."+rule.Column)
+			continue
+		}
+		apply = append(apply, rule)
+	}
+	return apply, skipped
+}
+
+func columnKey(table, column string) string {
+	return strings.ToLower(table) + "." + strings.ToLower(column)
+}
+
+// DropDatabase removes dbName if it exists
+func (c *Client) DropDatabase(ctx context.Context, dbName string) error {
+	if _, err := c.db.ExecContext(ctx, "DROP DATABASE IF EXISTS "+quoteIdentifier(dbName)); err != nil {
+		return fmt.Errorf("failed to drop database %s: %w", dbName, err)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+func TestMaskableRules(t *testing.T) {
+	rules := []config.MaskingRule{
+		{Table: "users", Column: "email", Value: "'x'"},
+		{Table: "Users", Column: "Phone", Value: "NULL"},
+		{Table: "users", Column: "ssn", Value: "NULL"},         // dropped in a later schema
+		{Table: "legacy_users", Column: "email", Value: "'x'"}, // table gone
+		{Database: "billing", Table: "cards", Column: "pan", Value: "NULL"},
+	}
+	columns := map[string]bool{
+		columnKey("users", "email"): true,
+		columnKey("users", "phone"): true,
+	}
+
+	apply, skipped := maskableRules(rules, "app", columns)
+	if len(apply) != 2 || apply[0].Column != "email" || apply[1].Column != "Phone" {
+		t.Errorf("applied %+v, want users.email and Users.Phone", apply)
+	}
+	if want := []string{"users.ssn", "legacy_users.email"}; !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped %v, want %v", skipped, want)
+	}
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MydumperMetadata is what tenangdb reads from the metadata file mydumper
+// writes next to a dump
+type MydumperMetadata struct {
+	Started  time.Time
+	Finished time.Time // zero when the dump did not finish
+
+	// Binary log coordinates of the source at the start of the dump, empty
+	// when binary logging is off
+	BinlogFile     string
+	BinlogPosition uint64
+	GTIDSet        string
+}
+
+// metadataTimeLayout is how both metadata formats write timestamps
+const metadataTimeLayout = "2006-01-02 15:04:05"
+
+// ParseMydumperMetadata reads a mydumper metadata file. Two formats exist:
+// mydumper up to 0.10 writes "SHOW MASTER STATUS:" followed by indented
+// "Log:", "Pos:" and "GTID:" lines, while newer versions write an ini file
+// with a [source] (or [master]) section holding File, Position and
+// Executed_Gtid_Set, and prefix the start and finish lines with "#".
+func ParseMydumperMetadata(path string) (*MydumperMetadata, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var m MydumperMetadata
+	var section string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		if line == "" {
+			continue
+		}
+
+		if value, ok := strings.CutPrefix(line, "Started dump at:"); ok {
+			m.Started = parseMetadataTime(value)
+			continue
+		}
+		if value, ok := strings.CutPrefix(line, "Finished dump at:"); ok {
+			m.Finished = parseMetadataTime(value)
+			continue
+		}
+
+		// Section headers: "[source]" in the ini format, "SHOW MASTER
+		// STATUS:" in the legacy one
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(strings.Trim(line, "[]"))
+			continue
+		}
+		if strings.HasPrefix(line, "SHOW ") && strings.HasSuffix(line, ":") {
+			section = strings.ToLower(strings.TrimSuffix(line, ":"))
+			continue
+		}
+
+		switch section {
+		case "source", "master", "show master status", "show binary log status":
+		default:
+			continue
+		}
+
+		// GTID sets of several servers continue on the next lines
+		if strings.HasSuffix(m.GTIDSet, ",") {
+			m.GTIDSet += line
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			key, value, ok = strings.Cut(line, ":")
+		}
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "file", "log":
+			m.BinlogFile = value
+		case "position", "pos":
+			if m.BinlogPosition, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid binlog position %q in %s", value, path)
+			}
+		case "executed_gtid_set", "gtid":
+			m.GTIDSet = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	if m.Started.IsZero() && m.Finished.IsZero() && m.BinlogFile == "" {
+		return nil, fmt.Errorf("%s is not a mydumper metadata file", path)
+	}
+	return &m, nil
+}
+
+func parseMetadataTime(value string) time.Time {
+	t, err := time.ParseInLocation(metadataTimeLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
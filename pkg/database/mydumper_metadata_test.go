@@ -0,0 +1,70 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseMydumperMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		file     string
+		position uint64
+		gtid     string
+	}{
+		{
+			name: "legacy",
+			content: `Started dump at: 2024-06-01 02:00:00
+SHOW MASTER STATUS:
+	Log: mysql-bin.000123
+	Pos: 4567
+	GTID:3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,
+4a21fb58-71ca-11e1-9e33-c80aa9429562:1-9
+
+SHOW SLAVE STATUS:
+	Host: 10.0.0.1
+	Log: relay-bin.000001
+	Pos: 1
+
+Finished dump at: 2024-06-01 02:05:00
+`,
+			file:     "mysql-bin.000123",
+			position: 4567,
+			gtid:     "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,4a21fb58-71ca-11e1-9e33-c80aa9429562:1-9",
+		},
+		{
+			name: "ini",
+			content: "# Started dump at: 2024-06-01 02:00:00\n" +
+				"[config]\nquote_character = BACKTICK\n\n" +
+				"[myloader_session_variables]\nSQL_MODE='NO_AUTO_VALUE_ON_ZERO'\n\n" +
+				"[source]\n# Channel_Name = ''\nFile = binlog.000042\nPosition = 157\nExecuted_Gtid_Set = 3e11fa47-71ca-11e1-9e33-c80aa9429562:1-77\n\n" +
+				"[`app`.`orders`]\nreal_table_name=orders\nrows = 100\n" +
+				"# Finished dump at: 2024-06-01 02:05:00\n",
+			file:     "binlog.000042",
+			position: 157,
+			gtid:     "3e11fa47-71ca-11e1-9e33-c80aa9429562:1-77",
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), "metadata")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		m, err := ParseMydumperMetadata(path)
+		if err != nil {
+			t.Fatalf("%s: ParseMydumperMetadata failed: %v", tt.name, err)
+		}
+		if m.BinlogFile != tt.file || m.BinlogPosition != tt.position || m.GTIDSet != tt.gtid {
+			t.Errorf("%s: got %s:%d %q, want %s:%d %q", tt.name, m.BinlogFile, m.BinlogPosition, m.GTIDSet, tt.file, tt.position, tt.gtid)
+		}
+		wantFinished := time.Date(2024, time.June, 1, 2, 5, 0, 0, time.Local)
+		if !m.Finished.Equal(wantFinished) {
+			t.Errorf("%s: Finished = %s, want %s", tt.name, m.Finished, wantFinished)
+		}
+	}
+}
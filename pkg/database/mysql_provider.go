@@ -11,7 +11,7 @@ import (
 
 	"github.com/abdullahainun/tenangdb/internal/logger"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // MySQLProvider implements the Provider interface for MySQL databases
@@ -52,34 +52,49 @@ func NewMySQLProvider(config *ProviderConfig) (Provider, error) {
 
 // connect establishes a connection to the MySQL database
 func (p *MySQLProvider) connect() error {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/",
-		p.config.Username,
-		p.config.Password,
-		p.config.Host,
-		p.config.Port,
-	)
-
-	db, err := sql.Open("mysql", dsn)
-	if err != nil {
-		return fmt.Errorf("failed to open database connection: %w", err)
+	// Set connection timeouts
+	timeout := parseDuration(p.config.Timeout, 30*time.Second)
+
+	dsn := mysql.NewConfig()
+	dsn.User = p.config.Username
+	dsn.Passwd = p.config.Password
+	dsn.Net = "tcp"
+	dsn.Addr = fmt.Sprintf("%s:%d", p.config.Host, p.config.Port)
+	dsn.Timeout = parseDuration(p.config.DialTimeout, timeout)
+	dsn.ReadTimeout = parseDuration(p.config.ReadTimeout, 0)
+	dsn.WriteTimeout = parseDuration(p.config.WriteTimeout, 0)
+	if p.config.Charset != "" {
+		dsn.Params = map[string]string{"charset": p.config.Charset}
+	}
+	if p.config.Collation != "" {
+		dsn.Collation = p.config.Collation
 	}
 
-	// Set connection timeouts
-	timeout := 30 * time.Second
-	if p.config.Timeout != "" {
-		if parsedTimeout, err := time.ParseDuration(p.config.Timeout); err == nil {
-			timeout = parsedTimeout
-		}
+	db, err := sql.Open("mysql", dsn.FormatDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open database connection: %w", err)
 	}
 
-	db.SetConnMaxLifetime(timeout)
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(5)
+	db.SetConnMaxLifetime(parseDuration(p.config.ConnMaxLifetime, timeout))
+	db.SetMaxOpenConns(orDefault(p.config.MaxOpenConns, 10))
+	db.SetMaxIdleConns(orDefault(p.config.MaxIdleConns, 5))
 
 	p.db = db
 	return nil
 }
 
+// parseDuration parses a config duration, returning fallback when it is
+// empty or invalid
+func parseDuration(value string, fallback time.Duration) time.Duration {
+	if value == "" {
+		return fallback
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return d
+	}
+	return fallback
+}
+
 // TestConnection tests the database connection
 func (p *MySQLProvider) TestConnection(ctx context.Context) error {
 	if p.db == nil {
@@ -282,7 +297,7 @@ func (p *MySQLProvider) hasMyLoader() bool {
 func (p *MySQLProvider) createMySQLDumpBackup(ctx context.Context, dbName string, opts *BackupOptions) (string, error) {
 	// This will contain the existing mysqldump logic from client.go
 	// Placeholder implementation
-	fileName := fmt.Sprintf("backup-%s-%s.sql", dbName, opts.Timestamp)
+	fileName := fmt.Sprintf("backup-%s-%s.sql", PathName(dbName), opts.Timestamp)
 	backupPath := filepath.Join(opts.Directory, fileName)
 
 	args := []string{
@@ -295,12 +310,13 @@ func (p *MySQLProvider) createMySQLDumpBackup(ctx context.Context, dbName string
 		"--quick",
 		"--lock-tables=false",
 		"--add-drop-database",
-		"--databases", dbName,
 		fmt.Sprintf("--host=%s", p.config.Host),
 		fmt.Sprintf("--port=%d", p.config.Port),
 		fmt.Sprintf("--user=%s", p.config.Username),
 		fmt.Sprintf("--password=%s", p.config.Password),
+		"--databases",
 	}
+	args = append(args, dumpTargets(dbName)...)
 
 	cmd := exec.CommandContext(ctx, "mysqldump", args...)
 	
@@ -323,7 +339,7 @@ func (p *MySQLProvider) createMySQLDumpBackup(ctx context.Context, dbName string
 func (p *MySQLProvider) createMyDumperBackup(ctx context.Context, dbName string, opts *BackupOptions) (string, error) {
 	// This will contain the existing mydumper logic from client.go
 	// Placeholder implementation
-	backupDir := filepath.Join(opts.Directory, fmt.Sprintf("%s-%s", dbName, opts.Timestamp))
+	backupDir := filepath.Join(opts.Directory, fmt.Sprintf("%s-%s", PathName(dbName), opts.Timestamp))
 
 	args := []string{
 		fmt.Sprintf("--host=%s", p.config.Host),
@@ -334,8 +350,8 @@ func (p *MySQLProvider) createMyDumperBackup(ctx context.Context, dbName string,
 		"--routines",
 		"--events",
 		"--triggers",
-		"--databases", dbName,
-		"--outputdir", backupDir,
+		"--database=" + dbName,
+		"--outputdir=" + backupDir,
 	}
 
 	cmd := exec.CommandContext(ctx, "mydumper", args...)
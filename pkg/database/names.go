@@ -0,0 +1,50 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathName turns a database name into the name of its backup directory
+// and files. MySQL allows almost any character in quoted names, so path
+// separators, control characters, a leading dot and @ itself are written
+// as @XXXX like MySQL's own file name encoding. Ordinary names, including
+// ones with dashes, dots or spaces, stay as they are.
+func PathName(dbName string) string {
+	var b strings.Builder
+	for i, r := range dbName {
+		if r == '/' || r == '\\' || r == '@' || r < 0x20 || r == 0x7f || (i == 0 && r == '.') {
+			fmt.Fprintf(&b, "@%04x", r)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// DatabaseName reverses PathName, turning the name of a backup directory or
+// file back into the database name
+func DatabaseName(pathName string) string {
+	if !strings.Contains(pathName, "@") {
+		return pathName
+	}
+	var b strings.Builder
+	for i := 0; i < len(pathName); i++ {
+		if pathName[i] == '@' && i+5 <= len(pathName) {
+			if r, err := strconv.ParseUint(pathName[i+1:i+5], 16, 32); err == nil {
+				b.WriteRune(rune(r))
+				i += 4
+				continue
+			}
+		}
+		b.WriteByte(pathName[i])
+	}
+	return b.String()
+}
+
+// dumpTargets ends the options of mysqldump and names the database and
+// tables to dump, so names starting with a dash are not taken for options
+func dumpTargets(dbName string, tables ...string) []string {
+	return append([]string{"--", dbName}, tables...)
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// exoticNames are database names MySQL accepts in backticks
+var exoticNames = []string{
+	"my-app", "my.app", "select", "My App", "-app", "--all-databases",
+	"../etc", "a/b", `a\b`, ".hidden", "user@example", "a@002fb", "tab\tname", "日本語", "a`b",
+}
+
+func TestPathNameRoundTrip(t *testing.T) {
+	seen := make(map[string]string)
+	for _, name := range exoticNames {
+		path := PathName(name)
+		if filepath.Base(path) != path || path == "." || path == ".." || path[0] == '.' {
+			t.Errorf("PathName(%q) = %q, not a plain name", name, path)
+		}
+		if got := DatabaseName(path); got != name {
+			t.Errorf("DatabaseName(PathName(%q)) = %q", name, got)
+		}
+		if other, ok := seen[path]; ok {
+			t.Errorf("%q and %q share the path name %q", name, other, path)
+		}
+		seen[path] = name
+	}
+
+	// Ordinary names keep their layout
+	for _, name := range []string{"app", "my-app", "my.app", "select", "My App", "日本語"} {
+		if got := PathName(name); got != name {
+			t.Errorf("PathName(%q) = %q, want it unchanged", name, got)
+		}
+	}
+}
+
+func TestPlanBackupExoticNames(t *testing.T) {
+	now := time.Date(2025, 7, 5, 2, 0, 0, 0, time.UTC)
+	cfg := &config.DatabaseConfig{Host: "localhost", Port: 3306, Username: "backup", MysqldumpPath: "mysqldump"}
+
+	for _, name := range exoticNames {
+		plan := PlanBackup(cfg, name, "/backups", now)
+
+		// The artifact stays inside the database's directory
+		rel, err := filepath.Rel("/backups", plan.Artifact)
+		if err != nil || filepath.Dir(filepath.Dir(rel)) != PathName(name) {
+			t.Errorf("%q: artifact %s escapes its database directory", name, plan.Artifact)
+		}
+
+		// The name follows the end of options, so mysqldump can't take it
+		// for one
+		end := slices.Index(plan.Command, "--")
+		if end < 0 || end+1 >= len(plan.Command) || plan.Command[end+1] != name || end+2 != len(plan.Command) {
+			t.Errorf("%q: command %q doesn't end with -- and the name", name, plan.Command)
+		}
+	}
+
+	cfg.Mydumper = &config.MydumperConfig{Enabled: true, BinaryPath: "mydumper"}
+	plan := PlanBackup(cfg, "-app", "/backups", now)
+	if !slices.Contains(plan.Command, "--database=-app") {
+		t.Errorf("mydumper command %q lacks --database=-app", plan.Command)
+	}
+}
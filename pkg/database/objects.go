@@ -0,0 +1,157 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ObjectsDirSuffix names the directory next to a backup holding its object
+// files: {db}-{timestamp}.objects
+const ObjectsDirSuffix = ".objects"
+
+// schemaObject is a kind of stored object written to its own file
+type schemaObject struct {
+	kind     string // subdirectory and key of the returned counts
+	list     string // query listing the names in a schema
+	show     string // SHOW CREATE statement, %s is the quoted name
+	column   string // column of the SHOW CREATE output holding the statement
+	compound bool   // body may contain semicolons, needs DELIMITER
+}
+
+var schemaObjects = []schemaObject{
+	{
+		kind: "procedures", list: "SELECT ROUTINE_NAME FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? AND ROUTINE_TYPE = 'PROCEDURE' ORDER BY ROUTINE_NAME",
+		show: "SHOW CREATE PROCEDURE %s", column: "Create Procedure", compound: true,
+	},
+	{
+		kind: "functions", list: "SELECT ROUTINE_NAME FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? AND ROUTINE_TYPE = 'FUNCTION' ORDER BY ROUTINE_NAME",
+		show: "SHOW CREATE FUNCTION %s", column: "Create Function", compound: true,
+	},
+	{
+		kind: "views", list: "SELECT TABLE_NAME FROM information_schema.VIEWS WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME",
+		show: "SHOW CREATE VIEW %s", column: "Create View",
+	},
+	{
+		kind: "triggers", list: "SELECT TRIGGER_NAME FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ? ORDER BY TRIGGER_NAME",
+		show: "SHOW CREATE TRIGGER %s", column: "SQL Original Statement", compound: true,
+	},
+	{
+		kind: "events", list: "SELECT EVENT_NAME FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ? ORDER BY EVENT_NAME",
+		show: "SHOW CREATE EVENT %s", column: "Create Event", compound: true,
+	},
+}
+
+// DumpObjectFiles writes the stored procedures, functions, views, triggers
+// and events of dbName into dir, one {kind}/{name}.sql file per object, so
+// their definitions can be reviewed and diffed between backups. It returns
+// the number of objects of each kind.
+func (c *Client) DumpObjectFiles(ctx context.Context, dbName, dir string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, object := range schemaObjects {
+		names, err := c.objectNames(ctx, object.list, dbName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s: %w", object.kind, err)
+		}
+		if len(names) == 0 {
+			continue
+		}
+
+		kindDir := filepath.Join(dir, object.kind)
+		if err := os.MkdirAll(kindDir, 0755); err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			qualified := quoteIdentifier(dbName) + "." + quoteIdentifier(name)
+			stmt, err := c.showCreate(ctx, fmt.Sprintf(object.show, qualified), object.column)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s %s: %w", object.kind, name, err)
+			}
+			file := filepath.Join(kindDir, objectFileName(name))
+			if err := os.WriteFile(file, []byte(objectSQL(stmt, object.compound)), 0644); err != nil {
+				return nil, err
+			}
+			counts[object.kind]++
+		}
+	}
+	return counts, nil
+}
+
+func (c *Client) objectNames(ctx context.Context, query, dbName string) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, query, dbName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// showCreate runs a SHOW CREATE statement and returns the named column.
+// The column layout differs between object kinds and server versions.
+func (c *Client) showCreate(ctx context.Context, query, column string) (string, error) {
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("no definition returned")
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+	for i, name := range columns {
+		if name == column {
+			if !values[i].Valid {
+				// NULL when the account lacks the privileges to see the body
+				return "", fmt.Errorf("definition is not visible to this account")
+			}
+			return values[i].String, nil
+		}
+	}
+	return "", fmt.Errorf("no %s column in output", column)
+}
+
+// objectSQL renders a definition as a file that loads with the mysql
+// client. Bodies that may contain semicolons are wrapped in DELIMITER.
+func objectSQL(stmt string, compound bool) string {
+	stmt = strings.TrimSpace(stmt)
+	if !compound {
+		return stmt + ";\n"
+	}
+	return "DELIMITER ;;\n" + stmt + " ;;\nDELIMITER ;\n"
+}
+
+// objectFileName makes an object name safe to use as a file name
+func objectFileName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_", "\x00", "_").Replace(name)
+	if name == "." || name == ".." {
+		name = strings.ReplaceAll(name, ".", "_")
+	}
+	return name + ".sql"
+}
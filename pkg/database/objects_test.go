@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestObjectSQL(t *testing.T) {
+	view := objectSQL("CREATE VIEW `v` AS select 1 AS `1`\n", false)
+	if view != "CREATE VIEW `v` AS select 1 AS `1`;\n" {
+		t.Errorf("view = %q", view)
+	}
+
+	proc := objectSQL("CREATE PROCEDURE `p`() BEGIN SELECT 1; SELECT 2; END", true)
+	want := "DELIMITER ;;\nCREATE PROCEDURE `p`() BEGIN SELECT 1; SELECT 2; END ;;\nDELIMITER ;\n"
+	if proc != want {
+		t.Errorf("procedure = %q, want %q", proc, want)
+	}
+}
+
+func TestObjectFileName(t *testing.T) {
+	tests := map[string]string{
+		"close_period": "close_period.sql",
+		"a/b":          "a_b.sql",
+		"..":           "__.sql",
+		"..x":          "..x.sql",
+	}
+	for name, want := range tests {
+		if got := objectFileName(name); got != want {
+			t.Errorf("objectFileName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
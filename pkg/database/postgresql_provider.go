@@ -0,0 +1,565 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+	"github.com/abdullahainun/tenangdb/internal/logger"
+)
+
+// PostgreSQL dump formats of pg_dump --format
+const (
+	PgFormatPlain     = "plain"
+	PgFormatCustom    = "custom"
+	PgFormatDirectory = "directory"
+	PgFormatTar       = "tar"
+)
+
+// pgMaintenanceDB is the database connected to for server-wide queries
+const pgMaintenanceDB = "postgres"
+
+// pgToolDirs are where packages install versioned PostgreSQL client tools
+// outside PATH, e.g. the postgresql-client-16 package of Debian
+var pgToolDirs = []string{
+	"/usr/lib/postgresql/*/bin",
+	"/usr/pgsql-*/bin",
+	"/opt/homebrew/opt/postgresql@*/bin",
+	"/usr/local/opt/postgresql@*/bin",
+	"/Applications/Postgres.app/Contents/Versions/*/bin",
+}
+
+// PostgreSQLProvider implements the Provider interface for PostgreSQL
+// servers. It runs psql, pg_dump and pg_restore and needs no driver;
+// connection settings reach the tools through libpq environment variables,
+// so the password never shows up in the process list.
+type PostgreSQLProvider struct {
+	config *ProviderConfig
+	logger *logger.Logger
+	tools  map[string]string // tool name to discovered path, "" when missing
+}
+
+// NewPostgreSQLProvider creates a new PostgreSQL provider
+func NewPostgreSQLProvider(config *ProviderConfig) (Provider, error) {
+	if err := ValidateConfig(config); err != nil {
+		return nil, fmt.Errorf("invalid PostgreSQL config: %w", err)
+	}
+
+	// Set default PostgreSQL configuration if not provided
+	if config.PostgreSQL == nil {
+		config.PostgreSQL = &PostgreSQLConfig{Format: PgFormatCustom}
+	}
+	if config.PostgreSQL.Format == "" {
+		config.PostgreSQL.Format = PgFormatCustom
+	}
+	switch config.PostgreSQL.Format {
+	case PgFormatPlain, PgFormatCustom, PgFormatDirectory, PgFormatTar:
+	default:
+		return nil, fmt.Errorf("invalid PostgreSQL format %q, use plain, custom, directory or tar", config.PostgreSQL.Format)
+	}
+
+	return &PostgreSQLProvider{
+		config: config,
+		logger: logger.NewLogger("postgresql-provider"),
+		tools:  make(map[string]string),
+	}, nil
+}
+
+// TestConnection tests the database connection
+func (p *PostgreSQLProvider) TestConnection(ctx context.Context) error {
+	_, err := p.query(ctx, pgMaintenanceDB, "SELECT 1", nil)
+	return err
+}
+
+// Close releases nothing, every query runs its own psql
+func (p *PostgreSQLProvider) Close() error {
+	return nil
+}
+
+// ListDatabases returns the databases that accept connections
+func (p *PostgreSQLProvider) ListDatabases(ctx context.Context) ([]*DatabaseInfo, error) {
+	rows, err := p.query(ctx, pgMaintenanceDB, `SELECT datname,
+		CASE WHEN has_database_privilege(datname, 'CONNECT') THEN pg_database_size(datname) ELSE 0 END,
+		pg_encoding_to_char(encoding)
+		FROM pg_database WHERE datallowconn ORDER BY datname`, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+
+	systemDBs := p.GetSystemDatabases()
+	var databases []*DatabaseInfo
+	for _, row := range rows {
+		if len(row) != 3 {
+			continue
+		}
+		size, _ := strconv.ParseInt(row[1], 10, 64)
+		databases = append(databases, &DatabaseInfo{
+			Name:     row[0],
+			Size:     size,
+			IsSystem: slices.Contains(systemDBs, row[0]),
+			Charset:  row[2],
+		})
+	}
+	return databases, nil
+}
+
+// DatabaseExists checks if a database exists
+func (p *PostgreSQLProvider) DatabaseExists(ctx context.Context, dbName string) (bool, error) {
+	rows, err := p.query(ctx, pgMaintenanceDB, "SELECT 1 FROM pg_database WHERE datname = :'name'", map[string]string{"name": dbName})
+	if err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
+// CreateBackup dumps each database with pg_dump
+func (p *PostgreSQLProvider) CreateBackup(ctx context.Context, opts *BackupOptions) ([]*BackupResult, error) {
+	pgDump := p.tool("pg_dump", p.config.PostgreSQL.PgDumpPath)
+	if pgDump == "" {
+		return nil, fmt.Errorf("pg_dump not found, install the PostgreSQL client tools or set postgresql.pg_dump_path")
+	}
+	format, err := p.dumpFormat(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*BackupResult
+	for _, dbName := range opts.Databases {
+		result := &BackupResult{
+			Database:    dbName,
+			Format:      opts.Format,
+			Compression: format != PgFormatPlain && format != PgFormatTar && opts.Compression,
+		}
+
+		start := time.Now()
+		backupPath := filepath.Join(opts.Directory, pgBackupName(dbName, opts.Timestamp, format))
+		err := p.runTool(ctx, opts.Timeout, dbName, pgDump, p.dumpArgs(format, backupPath, opts))
+		result.Duration = time.Since(start)
+		result.BackupPath = backupPath
+
+		if err != nil {
+			os.RemoveAll(backupPath) // Clean up on failure
+			result.Error = fmt.Errorf("pg_dump failed: %w", err)
+		} else {
+			result.Success = true
+			result.Size = pathSize(backupPath)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// RestoreBackup restores a pg_dump backup into opts.TargetDB, with psql
+// for plain SQL dumps and pg_restore for the other formats. The target
+// database is created when missing.
+func (p *PostgreSQLProvider) RestoreBackup(ctx context.Context, opts *RestoreOptions) error {
+	if opts.TargetDB == "" {
+		return fmt.Errorf("target database is required")
+	}
+	format, err := detectPgDumpFormat(opts.BackupPath)
+	if err != nil {
+		return err
+	}
+
+	var tool string
+	var args []string
+	if format == PgFormatPlain {
+		if tool = p.tool("psql", p.config.PostgreSQL.PsqlPath); tool == "" {
+			return fmt.Errorf("psql not found, install the PostgreSQL client tools or set postgresql.psql_path")
+		}
+		args = []string{"--no-psqlrc", "--no-password", "--quiet", "--set", "ON_ERROR_STOP=1", "--single-transaction"}
+		args = append(args, opts.ExtraArgs...)
+		args = append(args, "--file="+opts.BackupPath)
+	} else {
+		if tool = p.tool("pg_restore", p.config.PostgreSQL.PgRestorePath); tool == "" {
+			return fmt.Errorf("pg_restore not found, install the PostgreSQL client tools or set postgresql.pg_restore_path")
+		}
+		args = p.restoreArgs(format, opts)
+	}
+
+	if err := p.prepareTarget(ctx, opts.TargetDB, opts.DropIfExists); err != nil {
+		return err
+	}
+	if err := p.runTool(ctx, opts.Timeout, opts.TargetDB, tool, args); err != nil {
+		return fmt.Errorf("%s failed: %w", filepath.Base(tool), err)
+	}
+	return nil
+}
+
+// GetAvailableTools returns available PostgreSQL tools
+func (p *PostgreSQLProvider) GetAvailableTools() []string {
+	var tools []string
+	for _, tool := range []struct{ name, configured string }{
+		{"pg_dump", p.config.PostgreSQL.PgDumpPath},
+		{"pg_restore", p.config.PostgreSQL.PgRestorePath},
+		{"psql", p.config.PostgreSQL.PsqlPath},
+		{"pg_dumpall", ""},
+	} {
+		if p.tool(tool.name, tool.configured) != "" {
+			tools = append(tools, tool.name)
+		}
+	}
+	return tools
+}
+
+// ValidateTools validates that required tools are available
+func (p *PostgreSQLProvider) ValidateTools() error {
+	var missing []string
+	if p.tool("pg_dump", p.config.PostgreSQL.PgDumpPath) == "" {
+		missing = append(missing, "pg_dump")
+	}
+	if p.tool("psql", p.config.PostgreSQL.PsqlPath) == "" {
+		missing = append(missing, "psql")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s not found", strings.Join(missing, " and "))
+	}
+	return nil
+}
+
+// GetProviderType returns the provider type
+func (p *PostgreSQLProvider) GetProviderType() DatabaseType {
+	return PostgreSQL
+}
+
+// GetDefaultPort returns the default PostgreSQL port
+func (p *PostgreSQLProvider) GetDefaultPort() int {
+	return 5432
+}
+
+// GetSystemDatabases returns PostgreSQL system databases
+func (p *PostgreSQLProvider) GetSystemDatabases() []string {
+	return []string{"postgres", "template0", "template1"}
+}
+
+// Helper methods
+
+// tool returns the path of a PostgreSQL client tool: the configured one,
+// the one in PATH, or the newest versioned install. It returns "" when
+// none is found.
+func (p *PostgreSQLProvider) tool(name, configured string) string {
+	if path, ok := p.tools[name]; ok {
+		return path
+	}
+
+	var path string
+	switch {
+	case configured != "":
+		path, _ = exec.LookPath(configured)
+	default:
+		if found, err := exec.LookPath(name); err == nil {
+			path = found
+		} else {
+			for _, dir := range pgVersionDirs(pgToolDirs) {
+				if info, err := os.Stat(filepath.Join(dir, name)); err == nil && !info.IsDir() {
+					path = filepath.Join(dir, name)
+					break
+				}
+			}
+		}
+	}
+	if path != "" {
+		p.logger.WithField("tool", name).WithField("path", path).Debug("Found PostgreSQL tool")
+	}
+	p.tools[name] = path
+	return path
+}
+
+// pgVersionDirs expands patterns to existing directories, highest
+// PostgreSQL version first
+func pgVersionDirs(patterns []string) []string {
+	var dirs []string
+	for _, pattern := range patterns {
+		matches, _ := filepath.Glob(pattern)
+		dirs = append(dirs, matches...)
+	}
+	sort.SliceStable(dirs, func(i, j int) bool {
+		return pgDirVersion(dirs[i]) > pgDirVersion(dirs[j])
+	})
+	return dirs
+}
+
+// pgDirVersion returns the major version in a versioned install path such
+// as /usr/lib/postgresql/16/bin or /usr/pgsql-15/bin, or 0
+func pgDirVersion(dir string) float64 {
+	parts := strings.Split(filepath.ToSlash(dir), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		part := parts[i]
+		if at := strings.LastIndexAny(part, "-@"); at >= 0 {
+			part = part[at+1:]
+		}
+		if version, err := strconv.ParseFloat(part, 64); err == nil {
+			return version
+		}
+	}
+	return 0
+}
+
+// dumpFormat returns the pg_dump format of a backup: directory for
+// parallel dumps, plain for SQL and the configured format otherwise
+func (p *PostgreSQLProvider) dumpFormat(opts *BackupOptions) (string, error) {
+	switch {
+	case opts.Format == Binary:
+		return "", fmt.Errorf("binary backups are not supported for PostgreSQL, use sql or custom")
+	case opts.UseParallel || p.config.PostgreSQL.UsePgDumpParallel:
+		return PgFormatDirectory, nil
+	case opts.Format == SQL:
+		return PgFormatPlain, nil
+	case opts.Format == Custom && p.config.PostgreSQL.Format == PgFormatPlain:
+		return PgFormatCustom, nil
+	}
+	return p.config.PostgreSQL.Format, nil
+}
+
+// dumpArgs builds the pg_dump arguments writing a backup to backupPath.
+// The database comes from PGDATABASE, so names starting with a dash or
+// holding an equals sign are not taken for options or a connection string.
+func (p *PostgreSQLProvider) dumpArgs(format, backupPath string, opts *BackupOptions) []string {
+	args := []string{
+		"--no-password",
+		"--format=" + format,
+		"--file=" + backupPath,
+	}
+	if format == PgFormatDirectory {
+		args = append(args, fmt.Sprintf("--jobs=%d", p.jobs()))
+	}
+	if !opts.Compression && (format == PgFormatCustom || format == PgFormatDirectory) {
+		args = append(args, "--compress=0")
+	}
+	switch {
+	case opts.IncludeSchema && !opts.IncludeData:
+		args = append(args, "--schema-only")
+	case opts.IncludeData && !opts.IncludeSchema:
+		args = append(args, "--data-only")
+	}
+	return append(args, opts.ExtraArgs...)
+}
+
+// restoreArgs builds the pg_restore arguments loading a custom, directory
+// or tar backup into the target database
+func (p *PostgreSQLProvider) restoreArgs(format string, opts *RestoreOptions) []string {
+	args := []string{
+		"--no-password",
+		"--exit-on-error",
+		"--dbname=" + pgConnString(opts.TargetDB),
+	}
+	// tar archives cannot be restored in parallel
+	if format != PgFormatTar {
+		args = append(args, fmt.Sprintf("--jobs=%d", p.jobs()))
+	}
+	args = append(args, opts.ExtraArgs...)
+	return append(args, opts.BackupPath)
+}
+
+// jobs returns the number of parallel pg_dump and pg_restore jobs
+func (p *PostgreSQLProvider) jobs() int {
+	return orDefault(p.config.PostgreSQL.Jobs, runtime.NumCPU())
+}
+
+// prepareTarget creates the target database of a restore, dropping it
+// first when drop is set
+func (p *PostgreSQLProvider) prepareTarget(ctx context.Context, dbName string, drop bool) error {
+	vars := map[string]string{"name": dbName}
+	if drop {
+		if _, err := p.query(ctx, pgMaintenanceDB, `DROP DATABASE IF EXISTS :"name"`, vars); err != nil {
+			return fmt.Errorf("failed to drop database %s: %w", dbName, err)
+		}
+	}
+	exists, err := p.DatabaseExists(ctx, dbName)
+	if err != nil {
+		return fmt.Errorf("failed to check database %s: %w", dbName, err)
+	}
+	if exists {
+		return nil
+	}
+	if _, err := p.query(ctx, pgMaintenanceDB, `CREATE DATABASE :"name"`, vars); err != nil {
+		return fmt.Errorf("failed to create database %s: %w", dbName, err)
+	}
+	return nil
+}
+
+// query runs one SQL statement through psql in dbName and returns its
+// rows. The statement is read from stdin, where psql substitutes vars as
+// :'name' literals and :"name" identifiers.
+func (p *PostgreSQLProvider) query(ctx context.Context, dbName, query string, vars map[string]string) ([][]string, error) {
+	psql := p.tool("psql", p.config.PostgreSQL.PsqlPath)
+	if psql == "" {
+		return nil, fmt.Errorf("psql not found, install the PostgreSQL client tools or set postgresql.psql_path")
+	}
+
+	args := []string{"--no-psqlrc", "--no-password", "--tuples-only", "--no-align", "--field-separator=\x1f", "--set", "ON_ERROR_STOP=1"}
+	for name, value := range vars {
+		args = append(args, "--set", name+"="+value)
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, psql, args...)
+	cmd.Env = p.env(dbName)
+	cmd.Stdin = strings.NewReader(query + ";\n")
+	cmd.Stdout = &stdout
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, commandError(err, &stderr)
+	}
+
+	var rows [][]string
+	for _, line := range strings.Split(strings.TrimRight(stdout.String(), "\n"), "\n") {
+		if line != "" {
+			rows = append(rows, strings.Split(line, "\x1f"))
+		}
+	}
+	return rows, nil
+}
+
+// runTool runs a PostgreSQL client tool against dbName, within timeout
+// when it is set
+func (p *PostgreSQLProvider) runTool(ctx context.Context, timeout time.Duration, dbName, tool string, args []string) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, tool, args...)
+	cmd.Env = p.env(dbName)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return commandError(err, &stderr)
+	}
+	return nil
+}
+
+// env returns the libpq environment connecting to dbName
+func (p *PostgreSQLProvider) env(dbName string) []string {
+	timeout := parseDuration(p.config.DialTimeout, parseDuration(p.config.Timeout, 30*time.Second))
+	env := append(os.Environ(),
+		"PGHOST="+p.config.Host,
+		"PGPORT="+strconv.Itoa(p.config.Port),
+		"PGUSER="+p.config.Username,
+		"PGDATABASE="+dbName,
+		"PGAPPNAME=tenangdb",
+		// libpq treats values below 2 seconds as 2
+		fmt.Sprintf("PGCONNECT_TIMEOUT=%d", max(int(timeout.Seconds()), 2)),
+	)
+	if p.config.Password != "" {
+		env = append(env, "PGPASSWORD="+p.config.Password)
+	}
+	if p.config.SSLMode != "" {
+		env = append(env, "PGSSLMODE="+p.config.SSLMode)
+	}
+	if p.config.Charset != "" {
+		env = append(env, "PGCLIENTENCODING="+p.config.Charset)
+	}
+	return env
+}
+
+// commandError adds what a tool wrote to stderr to its exit error
+func commandError(err error, stderr *bytes.Buffer) error {
+	if output := strings.TrimSpace(stderr.String()); output != "" {
+		return fmt.Errorf("%w: %s", err, output)
+	}
+	return err
+}
+
+// pgConnString returns a libpq connection string selecting dbName, for
+// tools that take the database as --dbname
+func pgConnString(dbName string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(dbName)
+	return "dbname='" + escaped + "'"
+}
+
+// PlanPostgreSQLBackup returns where a backup of dbName taken at now goes
+// and the pg_dump command that writes it, without running anything.
+// compress is false when tenangdb compresses the backup afterwards.
+func PlanPostgreSQLBackup(cfg *config.DatabaseConfig, dbName, backupDir string, now time.Time, compress bool) BackupPlan {
+	p := &PostgreSQLProvider{config: &ProviderConfig{PostgreSQL: &PostgreSQLConfig{Format: cfg.PostgreSQL.Format, Jobs: cfg.PostgreSQL.Jobs}}}
+	dir := filepath.Join(backupDir, PathName(dbName), now.Format("2006-01"))
+	artifact := filepath.Join(dir, pgBackupName(dbName, now.Format("2006-01-02_15-04-05"), cfg.PostgreSQL.Format))
+
+	pgDump := cfg.PostgreSQL.PgDumpPath
+	if pgDump == "" {
+		pgDump = "pg_dump"
+	}
+	return BackupPlan{
+		Artifact: artifact,
+		Tool:     "pg_dump",
+		Command:  append([]string{pgDump}, p.dumpArgs(cfg.PostgreSQL.Format, artifact, &BackupOptions{Compression: compress})...),
+	}
+}
+
+// pgBackupName returns the file or directory name of a backup in format,
+// {database}-{timestamp} like the backups of the MySQL client
+func pgBackupName(dbName, timestamp, format string) string {
+	name := fmt.Sprintf("%s-%s", PathName(dbName), timestamp)
+	switch format {
+	case PgFormatPlain:
+		return name + ".sql"
+	case PgFormatCustom:
+		return name + ".dump"
+	case PgFormatTar:
+		return name + ".tar"
+	}
+	return name
+}
+
+// detectPgDumpFormat tells the pg_dump format of a backup from its
+// content: a directory with a toc.dat, the PGDMP signature of custom
+// archives, a tar header, or plain SQL otherwise
+func detectPgDumpFormat(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	if info.IsDir() {
+		if _, err := os.Stat(filepath.Join(path, "toc.dat")); err != nil {
+			return "", fmt.Errorf("%s is not a pg_dump directory backup, it has no toc.dat", path)
+		}
+		return PgFormatDirectory, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+	header := make([]byte, 512)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("failed to read backup: %w", err)
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte("PGDMP")):
+		return PgFormatCustom, nil
+	case len(header) >= 262 && string(header[257:262]) == "ustar":
+		return PgFormatTar, nil
+	}
+	return PgFormatPlain, nil
+}
+
+// pathSize returns the size of a file, or of all files in a directory
+func pathSize(path string) int64 {
+	var size int64
+	filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
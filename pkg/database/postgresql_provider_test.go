@@ -0,0 +1,168 @@
+package database
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDetectPgDumpFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	tarHeader := make([]byte, 512)
+	copy(tarHeader[257:], "ustar")
+	files := map[string][]byte{
+		"plain.sql":   []byte("--\n-- PostgreSQL database dump\n--\n"),
+		"custom.dump": []byte("PGDMP\x01\x0f\x00"),
+		"backup.tar":  tarHeader,
+		"empty.sql":   nil,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	directory := filepath.Join(dir, "directory")
+	if err := os.Mkdir(directory, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(directory, "toc.dat"), []byte("PGDMP"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{
+		"plain.sql":   PgFormatPlain,
+		"custom.dump": PgFormatCustom,
+		"backup.tar":  PgFormatTar,
+		"empty.sql":   PgFormatPlain,
+		"directory":   PgFormatDirectory,
+	} {
+		got, err := detectPgDumpFormat(filepath.Join(dir, path))
+		if err != nil {
+			t.Errorf("%s: %v", path, err)
+		} else if got != want {
+			t.Errorf("%s: format %s, want %s", path, got, want)
+		}
+	}
+
+	// A directory without a table of contents is no pg_dump backup
+	if _, err := detectPgDumpFormat(dir); err == nil {
+		t.Error("expected an error for a directory without toc.dat")
+	}
+}
+
+func TestPgVersionDirs(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"lib/postgresql/9.6/bin", "lib/postgresql/16/bin", "lib/postgresql/13/bin", "pgsql-15/bin"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := pgVersionDirs([]string{filepath.Join(root, "lib/postgresql/*/bin"), filepath.Join(root, "pgsql-*/bin")})
+	var versions []float64
+	for _, dir := range dirs {
+		versions = append(versions, pgDirVersion(dir))
+	}
+	if want := []float64{16, 15, 13, 9.6}; !slices.Equal(versions, want) {
+		t.Errorf("versions %v, want %v", versions, want)
+	}
+	if v := pgDirVersion("/opt/homebrew/opt/postgresql@14/bin"); v != 14 {
+		t.Errorf("homebrew version %v, want 14", v)
+	}
+}
+
+func TestPostgreSQLDumpArgs(t *testing.T) {
+	provider, err := NewPostgreSQLProvider(&ProviderConfig{Type: PostgreSQL, Host: "localhost", Username: "backup"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := provider.(*PostgreSQLProvider)
+	if p.config.Port != 5432 {
+		t.Errorf("port %d, want 5432", p.config.Port)
+	}
+
+	tests := []struct {
+		name   string
+		opts   BackupOptions
+		format string
+		args   []string
+	}{
+		{"configured format", BackupOptions{Compression: true, IncludeData: true, IncludeSchema: true}, PgFormatCustom, nil},
+		{"sql", BackupOptions{Format: SQL, IncludeData: true, IncludeSchema: true}, PgFormatPlain, nil},
+		{"uncompressed schema", BackupOptions{Format: Custom, IncludeSchema: true}, PgFormatCustom, []string{"--compress=0", "--schema-only"}},
+		{"parallel", BackupOptions{UseParallel: true, Compression: true, IncludeData: true}, PgFormatDirectory, []string{"--data-only"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := p.dumpFormat(&tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if format != tt.format {
+				t.Errorf("format %s, want %s", format, tt.format)
+			}
+			args := p.dumpArgs(format, "/backups/app", &tt.opts)
+			for _, arg := range tt.args {
+				if !slices.Contains(args, arg) {
+					t.Errorf("args %v lack %s", args, arg)
+				}
+			}
+			if parallel := strings.Contains(strings.Join(args, " "), "--jobs="); parallel != (format == PgFormatDirectory) {
+				t.Errorf("args %v, --jobs only belongs to directory dumps", args)
+			}
+		})
+	}
+
+	if _, err := p.dumpFormat(&BackupOptions{Format: Binary}); err == nil {
+		t.Error("expected binary backups to be rejected")
+	}
+}
+
+func TestPostgreSQLCreateBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	// A pg_dump stand-in recording its environment and writing the backup
+	pgDump := filepath.Join(dir, "pg_dump")
+	script := "#!/bin/sh\nenv | grep '^PG' | sort > \"" + filepath.Join(dir, "env") + "\"\n" +
+		"for arg; do case $arg in --file=*) echo dump > \"${arg#--file=}\";; esac; done\n"
+	if err := os.WriteFile(pgDump, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := NewPostgreSQLProvider(&ProviderConfig{
+		Type:       PostgreSQL,
+		Host:       "db.internal",
+		Port:       5433,
+		Username:   "backup",
+		Password:   "s3cret",
+		SSLMode:    "require",
+		PostgreSQL: &PostgreSQLConfig{PgDumpPath: pgDump},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := provider.CreateBackup(context.Background(), &BackupOptions{Databases: []string{"-app"}, Directory: dir, Timestamp: "20250101-120000"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Success {
+		t.Fatalf("results %+v, want one successful backup", results)
+	}
+	if want := filepath.Join(dir, "-app-20250101-120000.dump"); results[0].BackupPath != want {
+		t.Errorf("backup path %s, want %s", results[0].BackupPath, want)
+	}
+
+	env, err := os.ReadFile(filepath.Join(dir, "env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"PGDATABASE=-app", "PGHOST=db.internal", "PGPASSWORD=s3cret", "PGPORT=5433", "PGSSLMODE=require", "PGUSER=backup"} {
+		if !strings.Contains(string(env), want+"\n") {
+			t.Errorf("pg_dump environment lacks %s:\n%s", want, env)
+		}
+	}
+}
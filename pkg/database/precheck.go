@@ -0,0 +1,151 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ServerVersion returns the VERSION() of the server, e.g. "8.0.36" or
+// "10.11.6-MariaDB"
+func (c *Client) ServerVersion(ctx context.Context) (string, error) {
+	var version string
+	if err := c.db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to read server version: %w", err)
+	}
+	return version, nil
+}
+
+// UsedCollations returns the collations of the tables and columns of dbName
+func (c *Client) UsedCollations(ctx context.Context, dbName string) ([]string, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_COLLATION FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_COLLATION IS NOT NULL
+		UNION SELECT COLLATION_NAME FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND COLLATION_NAME IS NOT NULL`, dbName, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collations: %w", err)
+	}
+	defer rows.Close()
+
+	var collations []string
+	for rows.Next() {
+		var collation string
+		if err := rows.Scan(&collation); err != nil {
+			return nil, err
+		}
+		collations = append(collations, collation)
+	}
+	sort.Strings(collations)
+	return collations, rows.Err()
+}
+
+// ServerRelease is a parsed server version
+type ServerRelease struct {
+	Major, Minor int
+	MariaDB      bool
+}
+
+var releasePattern = regexp.MustCompile(`^(\d+)\.(\d+)`)
+
+// ParseServerVersion parses a VERSION() string. ok is false for versions
+// it does not understand.
+func ParseServerVersion(version string) (release ServerRelease, ok bool) {
+	m := releasePattern.FindStringSubmatch(version)
+	if m == nil {
+		return release, false
+	}
+	release.Major, _ = strconv.Atoi(m[1])
+	release.Minor, _ = strconv.Atoi(m[2])
+	release.MariaDB = strings.Contains(strings.ToLower(version), "mariadb")
+	return release, true
+}
+
+func (r ServerRelease) String() string {
+	if r.MariaDB {
+		return fmt.Sprintf("MariaDB %d.%d", r.Major, r.Minor)
+	}
+	return fmt.Sprintf("MySQL %d.%d", r.Major, r.Minor)
+}
+
+// newerThan compares MySQL releases, or MariaDB releases with each other
+func (r ServerRelease) newerThan(other ServerRelease) bool {
+	if r.Major != other.Major {
+		return r.Major > other.Major
+	}
+	return r.Minor > other.Minor
+}
+
+// RestoreSource describes the server a backup was taken from, as recorded
+// in its manifest
+type RestoreSource struct {
+	ServerVersion string
+	TargetCompat  string   // oldest server the dump was rewritten for, e.g. "5.7"
+	Collations    []string // collations of its tables and columns
+}
+
+// CheckRestoreCompat checks that this server can load a backup of source
+// and returns an explanation of every reason it can't. Problems that only
+// show up far into a load, such as unknown collations, are caught here.
+func (c *Client) CheckRestoreCompat(ctx context.Context, source RestoreSource) ([]string, error) {
+	targetVersion, err := c.ServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := compatProblems(source, targetVersion)
+
+	// A dump rewritten for an older server no longer uses its own
+	// collations
+	if source.TargetCompat == "" {
+		var unknown []string
+		for _, collation := range source.Collations {
+			known, err := c.HasCollation(ctx, collation)
+			if err != nil {
+				return nil, err
+			}
+			if !known {
+				unknown = append(unknown, collation)
+			}
+		}
+		if len(unknown) > 0 {
+			problems = append(problems, fmt.Sprintf("the target server (%s) does not know the collations %s used by the backup's tables; "+
+				"take the backup with backup.target_compat or restore onto a newer server", targetVersion, strings.Join(unknown, ", ")))
+		}
+	}
+	return problems, nil
+}
+
+// compatProblems compares the source and target server versions
+func compatProblems(source RestoreSource, targetVersion string) []string {
+	from, ok := ParseServerVersion(source.ServerVersion)
+	if !ok {
+		return nil
+	}
+	to, ok := ParseServerVersion(targetVersion)
+	if !ok {
+		return nil
+	}
+
+	// target_compat covers targets at least as new as it
+	if source.TargetCompat != "" {
+		if compat, ok := ParseServerVersion(source.TargetCompat); ok && !to.MariaDB && !compat.newerThan(to) {
+			return nil
+		}
+	}
+
+	switch {
+	case !from.MariaDB && to.MariaDB && from.Major >= 8:
+		return []string{fmt.Sprintf("the backup comes from %s and the target is %s: MySQL 8 dumps use collations and syntax MariaDB "+
+			"does not support; take the backup with backup.target_compat: \"5.7\" or restore onto MySQL", from, to)}
+	case from.MariaDB != to.MariaDB:
+		return nil // other cross-flavor restores usually load, the collation check catches the rest
+	case from.newerThan(to):
+		return []string{fmt.Sprintf("the backup comes from %s and the target is the older %s: dumps of newer servers use "+
+			"collations, charsets and table options older ones reject; take the backup with backup.target_compat or restore onto %s or later",
+			from, to, from)}
+	}
+	return nil
+}
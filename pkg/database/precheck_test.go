@@ -0,0 +1,46 @@
+package database
+
+import "testing"
+
+func TestParseServerVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    ServerRelease
+		ok      bool
+	}{
+		{"8.0.36", ServerRelease{Major: 8, Minor: 0}, true},
+		{"5.7.44-log", ServerRelease{Major: 5, Minor: 7}, true},
+		{"10.11.6-MariaDB-1:10.11.6+maria~ubu2204", ServerRelease{Major: 10, Minor: 11, MariaDB: true}, true},
+		{"unknown", ServerRelease{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseServerVersion(tt.version)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ParseServerVersion(%q) = %+v, %v, want %+v, %v", tt.version, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompatProblems(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  RestoreSource
+		target  string
+		problem bool
+	}{
+		{"downgrade", RestoreSource{ServerVersion: "8.0.36"}, "5.7.44", true},
+		{"upgrade", RestoreSource{ServerVersion: "5.7.44"}, "8.0.36", false},
+		{"same release", RestoreSource{ServerVersion: "8.0.36"}, "8.0.20", false},
+		{"target_compat", RestoreSource{ServerVersion: "8.0.36", TargetCompat: "5.7"}, "5.7.44", false},
+		{"target_compat too new", RestoreSource{ServerVersion: "8.4.0", TargetCompat: "8.0"}, "5.7.44", true},
+		{"mysql 8 onto mariadb", RestoreSource{ServerVersion: "8.0.36"}, "10.11.6-MariaDB", true},
+		{"mariadb onto mysql", RestoreSource{ServerVersion: "10.6.16-MariaDB"}, "8.0.36", false},
+		{"unknown source", RestoreSource{}, "5.7.44", false},
+	}
+	for _, tt := range tests {
+		got := compatProblems(tt.source, tt.target)
+		if (len(got) > 0) != tt.problem {
+			t.Errorf("%s: compatProblems() = %v, want problem %v", tt.name, got, tt.problem)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Privileges a dump needs beyond reading tables. Without them mysqldump and
+// mydumper fail part way through, or leave the objects out silently.
+const (
+	PrivilegeTrigger = "TRIGGER" // SHOW CREATE TRIGGER
+	PrivilegeEvent   = "EVENT"   // SHOW EVENTS, mydumper only
+)
+
+// grantLine matches a GRANT statement of SHOW GRANTS: the privileges and
+// the object they are granted on
+var grantLine = regexp.MustCompile("^GRANT (.+?) ON (\\S+) TO ")
+
+// MissingDumpPrivileges returns the privileges the dump of dbName needs
+// that the backup user lacks, including privileges of its active roles
+func (c *Client) MissingDumpPrivileges(ctx context.Context, dbName string) ([]string, error) {
+	needed := []string{PrivilegeTrigger}
+	if c.config.Mydumper != nil && c.config.Mydumper.Enabled {
+		needed = append(needed, PrivilegeEvent)
+	}
+
+	grants, err := c.currentGrants(ctx)
+	if err != nil {
+		return nil, err
+	}
+	granted := grantedPrivileges(grants, dbName)
+
+	var missing []string
+	for _, privilege := range needed {
+		if !granted[privilege] && !granted["ALL PRIVILEGES"] && !granted["ALL"] {
+			missing = append(missing, privilege)
+		}
+	}
+	return missing, nil
+}
+
+// currentGrants returns the GRANT statements of the backup user, with the
+// privileges of its active MySQL 8 roles expanded
+func (c *Client) currentGrants(ctx context.Context) ([]string, error) {
+	query := "SHOW GRANTS"
+	var roles string
+	if err := c.db.QueryRowContext(ctx, "SELECT CURRENT_ROLE()").Scan(&roles); err == nil && roles != "" && roles != "NONE" {
+		query = "SHOW GRANTS FOR CURRENT_USER() USING " + roles
+	}
+
+	rows, err := c.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read grants: %w", err)
+	}
+	defer rows.Close()
+
+	var grants []string
+	for rows.Next() {
+		var grant string
+		if err := rows.Scan(&grant); err != nil {
+			return nil, err
+		}
+		grants = append(grants, grant)
+	}
+	return grants, rows.Err()
+}
+
+// grantedPrivileges returns the privileges that grants give on every table
+// of dbName: global ones and ones on a matching database pattern. Grants on
+// single tables don't cover the database.
+func grantedPrivileges(grants []string, dbName string) map[string]bool {
+	granted := make(map[string]bool)
+	for _, grant := range grants {
+		m := grantLine.FindStringSubmatch(grant)
+		if m == nil {
+			continue
+		}
+		object := m[2]
+		if object != "*.*" {
+			if !strings.HasSuffix(object, ".*") || !matchSchemaPattern(unquoteIdentifier(strings.TrimSuffix(object, ".*")), dbName) {
+				continue
+			}
+		}
+		for _, privilege := range strings.Split(m[1], ",") {
+			granted[strings.ToUpper(strings.TrimSpace(privilege))] = true
+		}
+	}
+	return granted
+}
+
+// unquoteIdentifier removes the backticks SHOW GRANTS quotes names with
+func unquoteIdentifier(name string) string {
+	if len(name) >= 2 && name[0] == '`' && name[len(name)-1] == '`' {
+		return strings.ReplaceAll(name[1:len(name)-1], "``", "`")
+	}
+	return name
+}
+
+// matchSchemaPattern matches a database name against the database of a
+// grant, where % and _ are wildcards unless escaped with a backslash
+func matchSchemaPattern(pattern, dbName string) bool {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; {
+		case ch == '\\' && i+1 < len(pattern):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(pattern[i])))
+		case ch == '%':
+			expr.WriteString(".*")
+		case ch == '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	expr.WriteString("$")
+	matched, err := regexp.MatchString(expr.String(), dbName)
+	return err == nil && matched
+}
+
+// skipsPrivilege reports whether a dump leaves out the objects that need
+// privilege
+func skipsPrivilege(skip []string, privilege string) bool {
+	for _, p := range skip {
+		if p == privilege {
+			return true
+		}
+	}
+	return false
+}
+
+// mydumperSkipArgs drops the options dumping the objects that need a
+// privilege in skip
+func mydumperSkipArgs(args, skip []string) []string {
+	kept := args[:0:0]
+	for _, arg := range args {
+		if (arg == "--triggers" && skipsPrivilege(skip, PrivilegeTrigger)) || (arg == "--events" && skipsPrivilege(skip, PrivilegeEvent)) {
+			continue
+		}
+		kept = append(kept, arg)
+	}
+	return kept
+}
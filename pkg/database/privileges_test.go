@@ -0,0 +1,43 @@
+package database
+
+import "testing"
+
+func TestGrantedPrivileges(t *testing.T) {
+	grants := []string{
+		"GRANT SELECT, RELOAD, PROCESS ON *.* TO `tenangdb`@`%`",
+		"GRANT SHOW VIEW, LOCK TABLES, TRIGGER ON `app\\_%`.* TO `tenangdb`@`%`",
+		"GRANT EVENT ON `shop`.`orders` TO `tenangdb`@`%`",
+		"GRANT `backup_role`@`%` TO `tenangdb`@`%`",
+	}
+
+	tests := []struct {
+		dbName  string
+		trigger bool
+		event   bool
+	}{
+		{"app_billing", true, false},
+		{"appxbilling", false, false}, // the underscore is escaped
+		{"shop", false, false},        // a table grant doesn't cover the database
+	}
+	for _, tt := range tests {
+		granted := grantedPrivileges(grants, tt.dbName)
+		if !granted["SELECT"] {
+			t.Errorf("%s: global SELECT not granted", tt.dbName)
+		}
+		if granted[PrivilegeTrigger] != tt.trigger || granted[PrivilegeEvent] != tt.event {
+			t.Errorf("%s: TRIGGER %v, EVENT %v, want %v, %v", tt.dbName,
+				granted[PrivilegeTrigger], granted[PrivilegeEvent], tt.trigger, tt.event)
+		}
+	}
+}
+
+func TestMydumperSkipArgs(t *testing.T) {
+	args := []string{"--routines", "--triggers", "--events", "--database=app"}
+	got := mydumperSkipArgs(args, []string{PrivilegeEvent})
+	if len(got) != 3 || got[1] != "--triggers" || got[2] != "--database=app" {
+		t.Errorf("mydumperSkipArgs() = %v", got)
+	}
+	if len(args) != 4 {
+		t.Error("mydumperSkipArgs changed its input")
+	}
+}
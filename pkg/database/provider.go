@@ -102,6 +102,16 @@ type ProviderConfig struct {
 	Password        string       `yaml:"password"`
 	SSLMode         string       `yaml:"ssl_mode,omitempty"`
 	Timeout         string       `yaml:"timeout,omitempty"`
+
+	// Connection tuning, durations as "10s"; empty values keep the defaults
+	DialTimeout     string `yaml:"dial_timeout,omitempty"`
+	ReadTimeout     string `yaml:"read_timeout,omitempty"`
+	WriteTimeout    string `yaml:"write_timeout,omitempty"`
+	MaxOpenConns    int    `yaml:"max_open_conns,omitempty"`
+	MaxIdleConns    int    `yaml:"max_idle_conns,omitempty"`
+	ConnMaxLifetime string `yaml:"conn_max_lifetime,omitempty"`
+	Charset         string `yaml:"charset,omitempty"`
+	Collation       string `yaml:"collation,omitempty"`
 	
 	// Tool paths (auto-discovered if empty)
 	DumpToolPath    string `yaml:"dump_tool_path,omitempty"`
@@ -126,11 +136,12 @@ type MySQLConfig struct {
 
 // PostgreSQLConfig contains PostgreSQL-specific configuration  
 type PostgreSQLConfig struct {
-	// Will be implemented in v1.2.0
-	UsePgDumpParallel bool   `yaml:"use_pg_dump_parallel"`
+	UsePgDumpParallel bool   `yaml:"use_pg_dump_parallel"` // directory format with --jobs
+	Jobs              int    `yaml:"jobs,omitempty"`       // parallel jobs, defaults to the CPU count
 	PgDumpPath        string `yaml:"pg_dump_path,omitempty"`
+	PgRestorePath     string `yaml:"pg_restore_path,omitempty"`
 	PsqlPath          string `yaml:"psql_path,omitempty"`
-	Format            string `yaml:"format"` // plain, custom, directory, tar
+	Format            string `yaml:"format"` // plain, custom (default), directory, tar
 }
 
 // ProviderFactory creates database providers based on configuration
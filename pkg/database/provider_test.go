@@ -213,12 +213,16 @@ func TestProviderFactory(t *testing.T) {
 	// Clean up
 	provider.Close()
 
-	// Test PostgreSQL provider creation (should fail for now)
+	// Test PostgreSQL provider creation
 	config.Type = PostgreSQL
-	_, err = factory.CreateProvider(config)
-	if err == nil {
-		t.Error("Expected PostgreSQL provider creation to fail in v1.1.6")
+	provider, err = factory.CreateProvider(config)
+	if err != nil {
+		t.Fatalf("Failed to create PostgreSQL provider: %v", err)
 	}
+	if provider.GetProviderType() != PostgreSQL {
+		t.Errorf("Expected provider type PostgreSQL, got %s", provider.GetProviderType())
+	}
+	provider.Close()
 
 	// Test invalid type
 	config.Type = "invalid"
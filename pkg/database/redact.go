@@ -0,0 +1,95 @@
+package database
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "****"
+
+// redactArgs hides password values in command line arguments
+func redactArgs(args []string) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--password="):
+			out[i] = "--password=" + redacted
+		case strings.HasPrefix(arg, "-p") && len(arg) > 2 && !strings.HasPrefix(arg, "--"):
+			out[i] = "-p" + redacted
+		case i > 0 && args[i-1] == "--password":
+			out[i] = redacted
+		default:
+			out[i] = arg
+		}
+	}
+	return out
+}
+
+// logCommand prints an external command at debug level with secrets redacted
+func (c *Client) logCommand(cmd *exec.Cmd) {
+	if c.logger == nil {
+		return
+	}
+	c.logger.WithField("command", strings.Join(redactArgs(cmd.Args), " ")).Debug("Running external command")
+}
+
+// logTableFiles prints the data files mydumper wrote for each table
+func (c *Client) logTableFiles(dbName, backupDir string) {
+	if c.logger == nil || !c.logger.IsLevelEnabled(logrus.DebugLevel) {
+		return
+	}
+
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return
+	}
+
+	type tableFiles struct {
+		files int
+		size  int64
+	}
+	tables := make(map[string]*tableFiles)
+
+	// Data files are named {database}.{table}[.{chunk}].sql[.gz|.zst]
+	prefix := dbName + "."
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.Contains(name, ".sql") {
+			continue
+		}
+		table := strings.SplitN(strings.TrimPrefix(name, prefix), ".", 2)[0]
+		if strings.Contains(table, "-schema") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if tables[table] == nil {
+			tables[table] = &tableFiles{}
+		}
+		tables[table].files++
+		tables[table].size += info.Size()
+	}
+
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	for _, table := range names {
+		c.logger.WithField("database", dbName).
+			WithField("table", table).
+			WithField("files", tables[table].files).
+			WithField("size_bytes", tables[table].size).
+			WithField("directory", filepath.Base(backupDir)).
+			Debug("Table data dumped")
+	}
+}
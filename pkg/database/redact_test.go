@@ -0,0 +1,15 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	args := []string{"mysql", "--host=db", "--password=s3cret", "-ps3cret", "--password", "s3cret", "--port=3306", "app"}
+	want := []string{"mysql", "--host=db", "--password=****", "-p****", "--password", "****", "--port=3306", "app"}
+
+	if got := redactArgs(args); !reflect.DeepEqual(got, want) {
+		t.Errorf("redactArgs() = %v, want %v", got, want)
+	}
+}
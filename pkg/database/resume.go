@@ -0,0 +1,292 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abdullahainun/tenangdb/internal/config"
+)
+
+// restoreObjectsStep names the last step of a table-by-table restore, which
+// loads views, routines and events once all tables are in place
+const restoreObjectsStep = "*views-and-routines*"
+
+// RestoreTracking makes myloader restores load one table at a time and
+// record each finished table in a state file, so that a failed restore can
+// continue where it stopped
+type RestoreTracking struct {
+	StatePath       string
+	Backup          string // backup path as given by the user, to match a resumed restore
+	Resume          bool   // skip the tables the state file lists as loaded
+	TruncatePartial bool   // empty the table a failed restore was loading before anything else runs
+}
+
+// RestoreState is the progress of a table-by-table restore
+type RestoreState struct {
+	Backup    string    `json:"backup"`
+	Database  string    `json:"database"`
+	Completed []string  `json:"completed"`
+	Partial   string    `json:"partial,omitempty"` // table being loaded when the restore stopped
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LoadRestoreState reads a restore state file. It returns nil without an
+// error when there is none.
+func LoadRestoreState(path string) (*RestoreState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read restore state: %w", err)
+	}
+	var state RestoreState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse restore state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// Save writes the state, replacing the previous one
+func (s *RestoreState) Save(path string) error {
+	s.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal restore state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write restore state: %w", err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to write restore state: %w", err)
+	}
+	return nil
+}
+
+func (s *RestoreState) completed(step string) bool {
+	for _, done := range s.Completed {
+		if done == step {
+			return true
+		}
+	}
+	return false
+}
+
+// SetRestoreTracking enables table-by-table myloader restores with
+// progress kept in a state file; nil loads each backup in one myloader run
+func (c *Client) SetRestoreTracking(tracking *RestoreTracking) {
+	c.restoreTracking = tracking
+}
+
+// restoreTablesWithMyloader loads a mydumper directory one table at a time,
+// each from a directory of links holding only that table's files, and then
+// the remaining objects. Finished tables are recorded in the state file, and
+// skipped when resuming.
+func (c *Client) restoreTablesWithMyloader(ctx context.Context, backupDir, dbName string, restoreCfg *config.RestoreConfig) error {
+	tracking := c.restoreTracking
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	groups, common := groupMydumperFiles(names)
+
+	state := &RestoreState{Backup: tracking.Backup, Database: dbName}
+	if tracking.Resume {
+		previous, err := LoadRestoreState(tracking.StatePath)
+		if err != nil {
+			return err
+		}
+		if previous == nil || previous.Backup != tracking.Backup || previous.Database != dbName {
+			return fmt.Errorf("no interrupted restore of %s into %s to resume", tracking.Backup, dbName)
+		}
+		state = previous
+		c.logger.WithField("completed", len(state.Completed)).WithField("partial", state.Partial).
+			Info("⏯️ Resuming restore, skipping tables already loaded")
+
+		if state.Partial != "" && tracking.TruncatePartial {
+			if _, err := c.db.ExecContext(ctx, "TRUNCATE TABLE "+quoteIdentifier(dbName)+"."+quoteIdentifier(state.Partial)); err != nil {
+				c.logger.WithError(err).WithField("table", state.Partial).Warn("⚠️ Failed to truncate partially loaded table")
+			} else {
+				c.logger.WithField("table", state.Partial).Info("🧹 Truncated partially loaded table")
+			}
+		}
+	}
+
+	steps := make([]string, 0, len(groups)+1)
+	for table := range groups {
+		steps = append(steps, table)
+	}
+	sort.Strings(steps)
+	steps = append(steps, restoreObjectsStep)
+	groups[restoreObjectsStep] = common.rest
+
+	for i, step := range steps {
+		if state.completed(step) {
+			continue
+		}
+
+		state.Partial = step
+		if step == restoreObjectsStep {
+			state.Partial = ""
+		}
+		if err := state.Save(tracking.StatePath); err != nil {
+			return err
+		}
+
+		files := append(append([]string{}, common.always...), groups[step]...)
+		if err := c.restoreMydumperFiles(ctx, backupDir, files, dbName, restoreCfg); err != nil {
+			return fmt.Errorf("restore stopped at %s (%d of %d steps loaded), run again with --resume to continue: %w",
+				stepName(step), len(state.Completed), len(steps), err)
+		}
+
+		state.Completed = append(state.Completed, step)
+		state.Partial = ""
+		if err := state.Save(tracking.StatePath); err != nil {
+			return err
+		}
+		c.logger.WithField("step", stepName(step)).WithField("progress", fmt.Sprintf("%d/%d", i+1, len(steps))).
+			Debug("Restored table")
+	}
+
+	if err := os.Remove(tracking.StatePath); err != nil && !os.IsNotExist(err) {
+		c.logger.WithError(err).Warn("Failed to remove restore state")
+	}
+	return nil
+}
+
+// restoreMydumperFiles runs myloader on a directory of links to some files
+// of a mydumper backup
+func (c *Client) restoreMydumperFiles(ctx context.Context, backupDir string, files []string, dbName string, restoreCfg *config.RestoreConfig) error {
+	subset, err := os.MkdirTemp(filepath.Dir(backupDir), ".resume-"+filepath.Base(backupDir)+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create restore directory: %w", err)
+	}
+	defer os.RemoveAll(subset)
+
+	for _, name := range files {
+		source, err := filepath.Abs(filepath.Join(backupDir, name))
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(source, filepath.Join(subset, name)); err != nil {
+			return fmt.Errorf("failed to prepare %s: %w", name, err)
+		}
+	}
+	return c.runMyloader(ctx, subset, dbName, restoreCfg)
+}
+
+func stepName(step string) string {
+	if step == restoreObjectsStep {
+		return "views, routines and events"
+	}
+	return "table " + step
+}
+
+// mydumperCommonFiles are the files of a mydumper directory that are not
+// tied to one table: always is needed by every myloader run, rest holds
+// views, routines, events and other objects
+type mydumperCommonFiles struct {
+	always []string
+	rest   []string
+}
+
+// groupMydumperFiles sorts the files of a mydumper directory by table. Table
+// files are named {db}.{table}-schema.sql, {db}.{table}-schema-triggers.sql
+// and {db}.{table}.sql or {db}.{table}.{n}.sql, each possibly compressed.
+func groupMydumperFiles(names []string) (map[string][]string, mydumperCommonFiles) {
+	var common mydumperCommonFiles
+
+	var db string
+	for _, name := range names {
+		if i := strings.Index(name, "-schema-create.sql"); i > 0 {
+			db = name[:i]
+		}
+	}
+
+	groups := make(map[string][]string)
+	if db != "" {
+		for _, name := range names {
+			rest := strings.TrimPrefix(name, db+".")
+			if rest == name {
+				continue
+			}
+			if i := strings.Index(rest, "-schema.sql"); i > 0 {
+				groups[rest[:i]] = nil
+			}
+		}
+	}
+
+	for _, name := range names {
+		switch {
+		case name == "metadata" || strings.HasPrefix(name, "metadata.") || (db != "" && strings.HasPrefix(name, db+"-schema-create.sql")):
+			common.always = append(common.always, name)
+			continue
+		case name == ServerObjectsFile || name == InvalidViewsFile:
+			continue // applied by tenangdb itself after myloader
+		}
+		if table, ok := tableOfFile(db, name, groups); ok {
+			groups[table] = append(groups[table], name)
+			continue
+		}
+		common.rest = append(common.rest, name)
+	}
+	return groups, common
+}
+
+// tableOfFile returns the table a mydumper file holds the schema, triggers
+// or data of
+func tableOfFile(db, name string, tables map[string][]string) (string, bool) {
+	if db == "" {
+		return "", false
+	}
+	rest := strings.TrimPrefix(name, db+".")
+	if rest == name {
+		return "", false
+	}
+
+	// Longest table name first, so table "a" does not claim files of "a.b"
+	var best string
+	for table := range tables {
+		if len(table) <= len(best) || !strings.HasPrefix(rest, table) {
+			continue
+		}
+		suffix := rest[len(table):]
+		if strings.HasPrefix(suffix, "-schema.sql") || strings.HasPrefix(suffix, "-schema-triggers.sql") ||
+			strings.HasPrefix(suffix, ".sql") || isDataChunkSuffix(suffix) {
+			best = table
+		}
+	}
+	return best, best != ""
+}
+
+// isDataChunkSuffix matches the ".{n}.sql" ending of data chunk files
+func isDataChunkSuffix(suffix string) bool {
+	if !strings.HasPrefix(suffix, ".") {
+		return false
+	}
+	parts := strings.SplitN(suffix[1:], ".", 2)
+	if len(parts) != 2 || parts[0] == "" || !strings.HasPrefix(parts[1], "sql") {
+		return false
+	}
+	for _, r := range parts[0] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
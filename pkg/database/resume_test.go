@@ -0,0 +1,69 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestGroupMydumperFiles(t *testing.T) {
+	names := []string{
+		"metadata",
+		"app-schema-create.sql.gz",
+		"app.orders-schema.sql.gz",
+		"app.orders-schema-triggers.sql.gz",
+		"app.orders.00000.sql.gz",
+		"app.orders.00001.sql.gz",
+		"app.orders_archive-schema.sql.gz",
+		"app.orders_archive.sql.gz",
+		"app.active_orders-schema.sql.gz",
+		"app.active_orders-schema-view.sql.gz",
+		"app-schema-post.sql.gz",
+		ServerObjectsFile,
+	}
+
+	groups, common := groupMydumperFiles(names)
+
+	want := map[string][]string{
+		"orders":         {"app.orders-schema.sql.gz", "app.orders-schema-triggers.sql.gz", "app.orders.00000.sql.gz", "app.orders.00001.sql.gz"},
+		"orders_archive": {"app.orders_archive-schema.sql.gz", "app.orders_archive.sql.gz"},
+		"active_orders":  {"app.active_orders-schema.sql.gz"},
+	}
+	for table := range groups {
+		sort.Strings(groups[table])
+	}
+	for table := range want {
+		sort.Strings(want[table])
+	}
+	if !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %v, want %v", groups, want)
+	}
+	if want := []string{"metadata", "app-schema-create.sql.gz"}; !reflect.DeepEqual(common.always, want) {
+		t.Errorf("always = %v, want %v", common.always, want)
+	}
+	if want := []string{"app.active_orders-schema-view.sql.gz", "app-schema-post.sql.gz"}; !reflect.DeepEqual(common.rest, want) {
+		t.Errorf("rest = %v, want %v", common.rest, want)
+	}
+}
+
+func TestRestoreStateRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "restore-app.json")
+
+	state, err := LoadRestoreState(path)
+	if err != nil || state != nil {
+		t.Fatalf("missing state = %v, %v; want nil, nil", state, err)
+	}
+
+	saved := &RestoreState{Backup: "/backups/app/2025-07/app-2025-07-05_10-30-15", Database: "app", Completed: []string{"orders"}, Partial: "users"}
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	state, err = LoadRestoreState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.completed("orders") || state.completed("users") || state.Partial != "users" {
+		t.Errorf("loaded state = %+v", state)
+	}
+}
@@ -0,0 +1,11 @@
+package database
+
+import "testing"
+
+func TestStableSchema(t *testing.T) {
+	dump := "CREATE TABLE `orders` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB AUTO_INCREMENT=4711 DEFAULT CHARSET=utf8mb4;\n"
+	want := "CREATE TABLE `orders` (\n  `id` int NOT NULL AUTO_INCREMENT\n) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n"
+	if got := string(stableSchema([]byte(dump))); got != want {
+		t.Errorf("stableSchema = %q, want %q", got, want)
+	}
+}
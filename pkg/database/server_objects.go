@@ -0,0 +1,359 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/logger"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// serverObjectPrefix marks the server object statements in a backup. They
+// are SQL comments, so the dump still loads with a plain mysql client, and
+// restore applies them itself, tolerating objects that already exist.
+const serverObjectPrefix = "-- tenangdb-server-object: "
+
+// ServerObjectsFile holds the server object statements of mydumper backups.
+// It has no .sql extension so myloader leaves it alone.
+const ServerObjectsFile = "tenangdb-server-objects"
+
+// Error numbers of objects that already exist on the restore target
+const (
+	errRoleExists          = 1396 // ER_CANNOT_USER
+	errResourceGroupExists = 3650 // ER_RESOURCE_GROUP_EXISTS
+)
+
+// ServerObjects are the MySQL 8 objects that mysqldump and mydumper miss by
+// default: roles, resource groups and column histograms
+type ServerObjects struct {
+	Statements  []string
+	Captured    map[string]int // object kind to count
+	NotCaptured []string       // objects the backup does not contain, and why
+}
+
+// DumpServerObjects collects roles, resource groups and histograms related
+// to dbName. Roles keep their global grants and grants on dbName only. tool
+// is the dump tool used, to report objects it leaves out.
+func (c *Client) DumpServerObjects(ctx context.Context, dbName, tool string) (*ServerObjects, error) {
+	objects := &ServerObjects{Captured: make(map[string]int)}
+
+	version, err := c.ServerVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// mysqldump runs without --routines and --events
+	if tool == "mysqldump" {
+		c.reportMissing(ctx, objects, "stored routines (mysqldump runs without --routines)",
+			"SELECT COUNT(*) FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ?", dbName)
+		c.reportMissing(ctx, objects, "events (mysqldump runs without --events)",
+			"SELECT COUNT(*) FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ?", dbName)
+	}
+
+	mysql8 := strings.HasPrefix(version, "8.") || strings.HasPrefix(version, "9.")
+	if !mysql8 || strings.Contains(strings.ToLower(version), "mariadb") {
+		objects.NotCaptured = append(objects.NotCaptured,
+			fmt.Sprintf("roles, resource groups and histograms: need MySQL 8.0 or later (server is %s)", version))
+		return objects, nil
+	}
+
+	if err := c.dumpRoles(ctx, dbName, objects); err != nil {
+		objects.NotCaptured = append(objects.NotCaptured, fmt.Sprintf("roles: %v", err))
+	}
+	if err := c.dumpResourceGroups(ctx, objects); err != nil {
+		objects.NotCaptured = append(objects.NotCaptured, fmt.Sprintf("resource groups: %v", err))
+	}
+	if err := c.dumpHistograms(ctx, dbName, objects); err != nil {
+		objects.NotCaptured = append(objects.NotCaptured, fmt.Sprintf("histograms: %v", err))
+	}
+
+	return objects, nil
+}
+
+// reportMissing notes objects counted by query that the backup leaves out
+func (c *Client) reportMissing(ctx context.Context, objects *ServerObjects, what, query string, args ...interface{}) {
+	var count int
+	if err := c.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil || count == 0 {
+		return
+	}
+	objects.NotCaptured = append(objects.NotCaptured, fmt.Sprintf("%d %s", count, what))
+}
+
+// dumpRoles recreates roles with their grants. Roles are locked accounts
+// with an expired, empty password, which is how CREATE ROLE makes them.
+func (c *Client) dumpRoles(ctx context.Context, dbName string, objects *ServerObjects) error {
+	rows, err := c.db.QueryContext(ctx, `SELECT User, Host FROM mysql.user
+		WHERE account_locked = 'Y' AND password_expired = 'Y' AND authentication_string = ''`)
+	if err != nil {
+		return err
+	}
+	type account struct{ user, host string }
+	var roles []account
+	isRole := make(map[account]bool)
+	for rows.Next() {
+		var a account
+		if err := rows.Scan(&a.user, &a.host); err != nil {
+			rows.Close()
+			return err
+		}
+		roles = append(roles, a)
+		isRole[a] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	ownGrants := fmt.Sprintf(" ON %s.", quoteIdentifier(dbName))
+	skippedGrants := 0
+	for _, role := range roles {
+		name := quoteAccount(role.user, role.host)
+		objects.Statements = append(objects.Statements, "CREATE ROLE IF NOT EXISTS "+name)
+
+		grants, err := c.db.QueryContext(ctx, "SHOW GRANTS FOR "+name)
+		if err != nil {
+			return err
+		}
+		for grants.Next() {
+			var grant string
+			if err := grants.Scan(&grant); err != nil {
+				grants.Close()
+				return err
+			}
+			// USAGE is implied and role memberships come from role_edges
+			if strings.HasPrefix(grant, "GRANT USAGE ON *.*") || !strings.Contains(grant, " ON ") {
+				continue
+			}
+			if strings.Contains(grant, " ON *.* ") || strings.Contains(grant, ownGrants) {
+				objects.Statements = append(objects.Statements, grant)
+			} else {
+				skippedGrants++
+			}
+		}
+		grants.Close()
+	}
+	objects.Captured["roles"] = len(roles)
+	if skippedGrants > 0 {
+		objects.NotCaptured = append(objects.NotCaptured, fmt.Sprintf("%d role grants on other databases", skippedGrants))
+	}
+
+	// Role hierarchies are kept; memberships of user accounts are not, as
+	// the accounts themselves are not backed up
+	edges, err := c.db.QueryContext(ctx, `SELECT FROM_USER, FROM_HOST, TO_USER, TO_HOST, WITH_ADMIN_OPTION FROM mysql.role_edges`)
+	if err != nil {
+		return err
+	}
+	defer edges.Close()
+	userGrants := 0
+	for edges.Next() {
+		var from, to account
+		var admin string
+		if err := edges.Scan(&from.user, &from.host, &to.user, &to.host, &admin); err != nil {
+			return err
+		}
+		if !isRole[to] {
+			userGrants++
+			continue
+		}
+		grant := fmt.Sprintf("GRANT %s TO %s", quoteAccount(from.user, from.host), quoteAccount(to.user, to.host))
+		if admin == "Y" {
+			grant += " WITH ADMIN OPTION"
+		}
+		objects.Statements = append(objects.Statements, grant)
+	}
+	if userGrants > 0 {
+		objects.NotCaptured = append(objects.NotCaptured, fmt.Sprintf("%d role grants to user accounts", userGrants))
+	}
+	return edges.Err()
+}
+
+// dumpResourceGroups recreates user defined resource groups
+func (c *Client) dumpResourceGroups(ctx context.Context, objects *ServerObjects) error {
+	rows, err := c.db.QueryContext(ctx, `SELECT RESOURCE_GROUP_NAME, RESOURCE_GROUP_TYPE, RESOURCE_GROUP_ENABLED, VCPU_IDS, THREAD_PRIORITY
+		FROM information_schema.RESOURCE_GROUPS WHERE RESOURCE_GROUP_NAME NOT IN ('USR_default', 'SYS_default')`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var name, groupType, vcpus string
+		var enabled, priority int
+		if err := rows.Scan(&name, &groupType, &enabled, &vcpus, &priority); err != nil {
+			return err
+		}
+
+		stmt := fmt.Sprintf("CREATE RESOURCE GROUP %s TYPE = %s", quoteIdentifier(name), groupType)
+		if vcpus != "" {
+			stmt += " VCPU = " + vcpus
+		}
+		stmt += fmt.Sprintf(" THREAD_PRIORITY = %d", priority)
+		if enabled == 0 {
+			stmt += " DISABLE"
+		}
+		objects.Statements = append(objects.Statements, stmt)
+		count++
+	}
+	objects.Captured["resource_groups"] = count
+	return rows.Err()
+}
+
+// dumpHistograms rebuilds column histograms of dbName. Table names are left
+// unqualified so they apply to whichever database is restored into.
+func (c *Client) dumpHistograms(ctx context.Context, dbName string, objects *ServerObjects) error {
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME, COLUMN_NAME, HISTOGRAM->>'$."number-of-buckets-specified"'
+		FROM information_schema.COLUMN_STATISTICS WHERE SCHEMA_NAME = ?`, dbName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var table, column string
+		var buckets sql.NullString
+		if err := rows.Scan(&table, &column, &buckets); err != nil {
+			return err
+		}
+		stmt := fmt.Sprintf("ANALYZE TABLE %s UPDATE HISTOGRAM ON %s", quoteIdentifier(table), quoteIdentifier(column))
+		if buckets.Valid && buckets.String != "" {
+			stmt += " WITH " + buckets.String + " BUCKETS"
+		}
+		objects.Statements = append(objects.Statements, stmt)
+		count++
+	}
+	objects.Captured["histograms"] = count
+	return rows.Err()
+}
+
+// WriteServerObjects stores the statements in a backup: appended as comments
+// to a mysqldump file, or in ServerObjectsFile inside a mydumper directory
+func WriteServerObjects(backupPath string, objects *ServerObjects) error {
+	if len(objects.Statements) == 0 {
+		return nil
+	}
+
+	target := backupPath
+	flags := os.O_WRONLY | os.O_APPEND
+	if info, err := os.Stat(backupPath); err == nil && info.IsDir() {
+		target = filepath.Join(backupPath, ServerObjectsFile)
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(target, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "\n-- MySQL 8 server objects, applied by tenangdb restore")
+	for _, stmt := range objects.Statements {
+		fmt.Fprintln(w, serverObjectPrefix+strings.ReplaceAll(stmt, "\n", " "))
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write server objects: %w", err)
+	}
+	return file.Close()
+}
+
+// readServerObjects returns the server object statements stored in a backup
+func readServerObjects(backupPath string) ([]string, error) {
+	target := backupPath
+	if info, err := os.Stat(backupPath); err == nil && info.IsDir() {
+		target = filepath.Join(backupPath, ServerObjectsFile)
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	// Dump lines can be megabytes long; only the start of each is needed
+	var statements []string
+	r := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, isPrefix, err := r.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(string(line), serverObjectPrefix) && !isPrefix {
+			statements = append(statements, strings.TrimPrefix(string(line), serverObjectPrefix))
+		}
+		for isPrefix {
+			if _, isPrefix, err = r.ReadLine(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return statements, nil
+}
+
+// restoreServerObjects applies the server object statements of a backup
+// after its data was loaded into dbName. Objects that already exist are
+// kept; other failures are logged without failing the restore.
+func (c *Client) restoreServerObjects(ctx context.Context, backupPath, dbName string, log *logger.Logger) error {
+	statements, err := readServerObjects(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read server objects: %w", err)
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(dbName)); err != nil {
+		return fmt.Errorf("failed to select database: %w", err)
+	}
+
+	applied, failed := 0, 0
+	for _, stmt := range statements {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			var mysqlErr *mysql.MySQLError
+			if errors.As(err, &mysqlErr) && (mysqlErr.Number == errRoleExists || mysqlErr.Number == errResourceGroupExists) {
+				continue
+			}
+			log.WithError(err).WithField("statement", stmt).Warn("⚠️ Failed to restore server object")
+			failed++
+			continue
+		}
+		applied++
+	}
+
+	log.WithField("applied", applied).WithField("failed", failed).Info("🧩 Restored server objects")
+	return nil
+}
+
+// quoteIdentifier quotes a MySQL identifier with backticks
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// quoteAccount renders 'user'@'host'
+func quoteAccount(user, host string) string {
+	quote := func(s string) string {
+		return "'" + strings.ReplaceAll(strings.ReplaceAll(s, `\`, `\\`), "'", `\'`) + "'"
+	}
+	return quote(user) + "@" + quote(host)
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerObjectsRoundTrip(t *testing.T) {
+	objects := &ServerObjects{Statements: []string{
+		"CREATE ROLE IF NOT EXISTS 'app_read'@'%'",
+		"GRANT SELECT ON `app`.* TO `app_read`@`%`",
+		"ANALYZE TABLE `orders` UPDATE HISTOGRAM ON `status` WITH 16 BUCKETS",
+	}}
+
+	t.Run("mysqldump file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app-2025-07-05_10-30-15.sql")
+		// Extended inserts make lines longer than the read buffer
+		dump := "CREATE TABLE orders (id int);\nINSERT INTO orders VALUES " + strings.Repeat("(1),", 100000) + "(1);\n"
+		if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := WriteServerObjects(path, objects); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readServerObjects(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, objects.Statements) {
+			t.Errorf("got %q, want %q", got, objects.Statements)
+		}
+
+		data, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(data), dump) {
+			t.Error("dump content was changed")
+		}
+	})
+
+	t.Run("mydumper directory", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := WriteServerObjects(dir, objects); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readServerObjects(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, objects.Statements) {
+			t.Errorf("got %q, want %q", got, objects.Statements)
+		}
+	})
+
+	t.Run("backup without objects", func(t *testing.T) {
+		got, err := readServerObjects(t.TempDir())
+		if err != nil || got != nil {
+			t.Errorf("got %q, %v; want nothing", got, err)
+		}
+	})
+}
@@ -50,7 +50,7 @@ func (w *SetupWizard) SetupDatabaseConfig() (*ProviderConfig, error) {
 func (w *SetupWizard) selectDatabaseType() DatabaseType {
 	fmt.Printf("🗄️  Select Database Type:\n")
 	fmt.Printf("  1. MySQL (default)\n")
-	fmt.Printf("  2. PostgreSQL (coming in v1.2.0)\n")
+	fmt.Printf("  2. PostgreSQL\n")
 	fmt.Printf("  3. Auto-detect from port\n")
 	fmt.Printf("\n")
 
@@ -64,8 +64,8 @@ func (w *SetupWizard) selectDatabaseType() DatabaseType {
 				fmt.Printf("✅ Selected: MySQL\n\n")
 				return MySQL
 			case "2":
-				fmt.Printf("⚠️  PostgreSQL support is coming in v1.2.0. Using MySQL for now.\n\n")
-				return MySQL
+				fmt.Printf("✅ Selected: PostgreSQL\n\n")
+				return PostgreSQL
 			case "3":
 				fmt.Printf("✅ Will auto-detect based on port (3306=MySQL, 5432=PostgreSQL)\n\n")
 				return "" // Will be set later based on port
@@ -111,9 +111,6 @@ func (w *SetupWizard) setupConnectionSettings(config *ProviderConfig) {
 		case 5432:
 			config.Type = PostgreSQL
 			fmt.Printf("🔍 Auto-detected: PostgreSQL (port 5432)\n")
-			fmt.Printf("⚠️  PostgreSQL support coming in v1.2.0. Using MySQL for now.\n")
-			config.Type = MySQL
-			port = 3306
 		default:
 			// Default to MySQL
 			config.Type = MySQL
@@ -188,22 +185,30 @@ func (w *SetupWizard) setupMySQLConfig(config *ProviderConfig) {
 	}
 }
 
-// setupPostgreSQLConfig configures PostgreSQL-specific settings (placeholder for v1.2.0)
+// setupPostgreSQLConfig configures PostgreSQL-specific settings
 func (w *SetupWizard) setupPostgreSQLConfig(config *ProviderConfig) {
 	fmt.Printf("\n🔧 PostgreSQL Configuration\n")
 	fmt.Printf("==========================\n")
-	fmt.Printf("⚠️  PostgreSQL support will be fully implemented in v1.2.0\n")
-	fmt.Printf("🔄 Converting to MySQL configuration for now...\n")
-	
-	// Convert to MySQL for now
-	config.Type = MySQL
-	config.Port = 3306
-	w.setupMySQLConfig(config)
+
+	config.PostgreSQL = &PostgreSQLConfig{Format: PgFormatCustom}
+
+	// Ask about parallel backup preference
+	fmt.Print("Use pg_dump --jobs for faster parallel backups? [y/N]: ")
+	if w.scanner.Scan() {
+		input := strings.ToLower(strings.TrimSpace(w.scanner.Text()))
+		if input == "y" || input == "yes" {
+			config.PostgreSQL.UsePgDumpParallel = true
+			config.PostgreSQL.Format = PgFormatDirectory
+			fmt.Printf("✅ Will write directory format backups with pg_dump --jobs\n")
+		} else {
+			fmt.Printf("✅ Will write custom format backups with pg_dump\n")
+		}
+	}
 }
 
 // GetSupportedTypesDisplay returns a display string of supported database types
 func GetSupportedTypesDisplay() string {
-	return "MySQL (PostgreSQL coming in v1.2.0)"
+	return "MySQL, PostgreSQL"
 }
 
 // ValidateAndSetDefaults validates the configuration and sets appropriate defaults
@@ -224,8 +229,9 @@ func ValidateAndSetDefaults(config *ProviderConfig) error {
 			}
 		}
 	case PostgreSQL:
-		// Will be implemented in v1.2.0
-		return fmt.Errorf("PostgreSQL support coming in v1.2.0")
+		if config.PostgreSQL == nil {
+			config.PostgreSQL = &PostgreSQLConfig{Format: PgFormatCustom}
+		}
 	}
 
 	return nil
@@ -0,0 +1,237 @@
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TableRowCounts returns the approximate row count of every base table of
+// dbName, as estimated by information_schema. InnoDB estimates can be off
+// by tens of percent, so they only tell an empty table from a full one.
+func (c *Client) TableRowCounts(ctx context.Context, dbName string) (map[string]int64, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME, COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table row counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int64)
+	for rows.Next() {
+		var table string
+		var count int64
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, err
+		}
+		counts[table] = count
+	}
+	return counts, rows.Err()
+}
+
+// DatabaseSizes returns the data and index size of each of databases as
+// estimated by information_schema. Databases without tables are left out.
+func (c *Client) DatabaseSizes(ctx context.Context, databases []string) (map[string]int64, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_SCHEMA, COALESCE(SUM(DATA_LENGTH + INDEX_LENGTH), 0) FROM information_schema.TABLES
+		GROUP BY TABLE_SCHEMA`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database sizes: %w", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool)
+	for _, dbName := range databases {
+		wanted[dbName] = true
+	}
+	sizes := make(map[string]int64)
+	for rows.Next() {
+		var dbName string
+		var size int64
+		if err := rows.Scan(&dbName, &size); err != nil {
+			return nil, err
+		}
+		if wanted[dbName] {
+			sizes[dbName] = size
+		}
+	}
+	return sizes, rows.Err()
+}
+
+// chunkDataFile matches the data files of a chunked mysqldump backup,
+// which hold no CREATE TABLE statements
+var chunkDataFile = regexp.MustCompile(`\.\d+\.sql$`)
+
+// DumpedTables returns the tables an uncompressed backup holds: the schema
+// files of a mydumper directory, or the CREATE TABLE statements of a
+// mysqldump file or chunked directory
+func DumpedTables(backupPath string) ([]string, error) {
+	info, err := os.Stat(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return dumpFileTables(backupPath)
+	}
+
+	entries, err := os.ReadDir(backupPath)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+
+	if IsChunkedDump(backupPath) {
+		seen := make(map[string]bool)
+		var tables []string
+		for _, name := range names {
+			if !strings.HasSuffix(name, ".sql") || chunkDataFile.MatchString(name) {
+				continue
+			}
+			found, err := dumpFileTables(filepath.Join(backupPath, name))
+			if err != nil {
+				return nil, err
+			}
+			for _, table := range found {
+				if !seen[table] {
+					seen[table] = true
+					tables = append(tables, table)
+				}
+			}
+		}
+		sort.Strings(tables)
+		return tables, nil
+	}
+
+	// mydumper also writes a placeholder table schema for every view
+	groups, _ := groupMydumperFiles(names)
+	var tables []string
+	for table, files := range groups {
+		view := false
+		for _, file := range files {
+			view = view || strings.Contains(file, "-schema-view.sql")
+		}
+		if !view {
+			tables = append(tables, table)
+		}
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// dumpFileTables scans a mysqldump file for CREATE TABLE statements. Lines
+// are read in pieces, since extended INSERTs can be far larger than memory
+// should hold.
+func dumpFileTables(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	prefix := []byte("CREATE TABLE `")
+	var tables []string
+	reader := bufio.NewReaderSize(file, 64*1024)
+	lineStart := true
+	for {
+		line, isPrefix, err := reader.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if lineStart && bytes.HasPrefix(line, prefix) {
+			if name, ok := backquoted(line[len("CREATE TABLE "):]); ok {
+				tables = append(tables, name)
+			}
+		}
+		lineStart = !isPrefix
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// backquoted returns the identifier at the start of b, undoubling escaped
+// backquotes
+func backquoted(b []byte) (string, bool) {
+	if len(b) == 0 || b[0] != '`' {
+		return "", false
+	}
+	var name strings.Builder
+	for i := 1; i < len(b); i++ {
+		if b[i] != '`' {
+			name.WriteByte(b[i])
+			continue
+		}
+		if i+1 < len(b) && b[i+1] == '`' {
+			name.WriteByte('`')
+			i++
+			continue
+		}
+		return name.String(), true
+	}
+	return "", false
+}
+
+// TableComparison is the difference between the tables of a backup and the
+// live database
+type TableComparison struct {
+	Missing []string   `json:"missing,omitempty"` // live tables the backup does not hold
+	Extra   []string   `json:"extra,omitempty"`   // backed up tables no longer live
+	Drifted []RowDrift `json:"drifted,omitempty"` // tables whose row count moved beyond the tolerance
+}
+
+// RowDrift is a table whose approximate row count differs between the
+// backup and the live database
+type RowDrift struct {
+	Table      string `json:"table"`
+	BackupRows int64  `json:"backup_rows"`
+	LiveRows   int64  `json:"live_rows"`
+}
+
+// driftSlack is the row count difference always tolerated, since estimates
+// of small tables jump around
+const driftSlack = 1000
+
+// CompareTables compares the approximate row counts of the tables in a
+// backup with those of the live database. A row count drifted when it
+// differs by more than tolerance (0.5 for 50%) of the larger of the two.
+func CompareTables(backup, live map[string]int64, tolerance float64) TableComparison {
+	var result TableComparison
+	for table, liveRows := range live {
+		backupRows, ok := backup[table]
+		if !ok {
+			result.Missing = append(result.Missing, table)
+			continue
+		}
+
+		diff, larger := liveRows-backupRows, liveRows
+		if diff < 0 {
+			diff, larger = -diff, backupRows
+		}
+		if diff > driftSlack && float64(diff) > tolerance*float64(larger) {
+			result.Drifted = append(result.Drifted, RowDrift{Table: table, BackupRows: backupRows, LiveRows: liveRows})
+		}
+	}
+	for table := range backup {
+		if _, ok := live[table]; !ok {
+			result.Extra = append(result.Extra, table)
+		}
+	}
+
+	sort.Strings(result.Missing)
+	sort.Strings(result.Extra)
+	sort.Slice(result.Drifted, func(i, j int) bool { return result.Drifted[i].Table < result.Drifted[j].Table })
+	return result
+}
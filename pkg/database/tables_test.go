@@ -0,0 +1,49 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareTables(t *testing.T) {
+	backup := map[string]int64{"orders": 50000, "users": 20000, "old_audit": 10, "tiny": 10}
+	live := map[string]int64{"orders": 52000, "users": 80000, "sessions": 0, "tiny": 900}
+
+	got := CompareTables(backup, live, 0.5)
+	want := TableComparison{
+		Missing: []string{"sessions"},
+		Extra:   []string{"old_audit"},
+		Drifted: []RowDrift{{Table: "users", BackupRows: 20000, LiveRows: 80000}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CompareTables() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDumpFileTables(t *testing.T) {
+	dump := strings.Join([]string{
+		"-- Table structure for table `orders`",
+		"CREATE TABLE `orders` (",
+		"  `id` int NOT NULL",
+		") ENGINE=InnoDB;",
+		"INSERT INTO `notes` VALUES ('CREATE TABLE `fake` (');",
+		"/*!50001 CREATE VIEW `recent` AS SELECT 1 */;",
+		"CREATE TABLE `odd``name` (",
+		");",
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := DumpedTables(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"odd`name", "orders"}; !reflect.DeepEqual(tables, want) {
+		t.Errorf("DumpedTables() = %v, want %v", tables, want)
+	}
+}
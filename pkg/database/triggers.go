@@ -0,0 +1,87 @@
+package database
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// definerPattern matches a MySQL account as written in DEFINER clauses,
+// e.g. `root`@`localhost`, 'app'@'%' or app@localhost
+const definerPattern = "(?:`[^`]*`|'[^']*'|[^\\s@]+)@(?:`[^`]*`|'[^']*'|[^\\s*]+)"
+
+var (
+	versionCommentOpen = regexp.MustCompile(`/\*!\d+`)
+	createTriggerRegex = regexp.MustCompile(`(?i)^\s*CREATE\s+(?:DEFINER\s*=\s*` + definerPattern + `\s+)?TRIGGER\s`)
+)
+
+// splitTriggers copies a mysqldump stream to data while diverting trigger
+// definitions to triggers. mysqldump wraps every trigger in a
+// "DELIMITER ;;" ... "DELIMITER ;" block, so blocks are buffered and routed
+// as a whole; routines and events use the same wrapping and stay in data.
+func splitTriggers(r io.Reader, data, triggers io.Writer) error {
+	reader := bufio.NewReader(r)
+	var block []string
+	inBlock := false
+
+	flush := func(w io.Writer) error {
+		for _, line := range block {
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+		block = block[:0]
+		return nil
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if line != "" {
+			trimmed := strings.TrimSpace(line)
+			switch {
+			case !inBlock && strings.HasPrefix(trimmed, "DELIMITER ") && trimmed != "DELIMITER ;":
+				inBlock = true
+				block = append(block, line)
+			case inBlock:
+				block = append(block, line)
+				if trimmed == "DELIMITER ;" {
+					target := data
+					if isTriggerBlock(block) {
+						target = triggers
+					}
+					if err := flush(target); err != nil {
+						return err
+					}
+					inBlock = false
+				}
+			default:
+				if _, err := io.WriteString(data, line); err != nil {
+					return err
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			// Unterminated block, keep it with the data rather than lose it
+			return flush(data)
+		}
+		if readErr != nil {
+			return readErr
+		}
+	}
+}
+
+// isTriggerBlock reports whether a delimiter block creates a trigger
+func isTriggerBlock(block []string) bool {
+	for _, line := range block {
+		if strings.HasPrefix(strings.TrimSpace(line), "DELIMITER") {
+			continue
+		}
+		// Drop MySQL version comment markers: /*!50003 CREATE*/ -> CREATE
+		stripped := versionCommentOpen.ReplaceAllString(line, " ")
+		stripped = strings.ReplaceAll(stripped, "*/", " ")
+		return createTriggerRegex.MatchString(stripped)
+	}
+	return false
+}
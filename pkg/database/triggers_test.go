@@ -0,0 +1,51 @@
+package database
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const sampleDump = "CREATE TABLE `orders` (`id` int);\n" +
+	"INSERT INTO `orders` VALUES (1);\n" +
+	"DELIMITER ;;\n" +
+	"/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`localhost`*/ /*!50003 TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW BEGIN\n" +
+	"  UPDATE stats SET n = n + 1;\n" +
+	"END */;;\n" +
+	"DELIMITER ;\n" +
+	"DELIMITER ;;\n" +
+	"CREATE DEFINER=`app user`@`%` PROCEDURE `refresh`() BEGIN SELECT 'TRIGGER'; END ;;\n" +
+	"DELIMITER ;\n" +
+	"INSERT INTO `orders` VALUES (2);\n"
+
+func TestSplitTriggers(t *testing.T) {
+	var data, triggers bytes.Buffer
+	if err := splitTriggers(strings.NewReader(sampleDump), &data, &triggers); err != nil {
+		t.Fatalf("splitTriggers() error = %v", err)
+	}
+
+	if !strings.Contains(triggers.String(), "TRIGGER `orders_ai`") {
+		t.Errorf("Expected trigger to be diverted, got triggers=%q", triggers.String())
+	}
+	if strings.Contains(data.String(), "orders_ai") {
+		t.Error("Expected trigger to be removed from data stream")
+	}
+	if !strings.Contains(data.String(), "PROCEDURE `refresh`") {
+		t.Error("Expected procedure to stay in data stream")
+	}
+	if !strings.Contains(data.String(), "VALUES (2)") {
+		t.Error("Expected statements after the trigger to stay in data stream")
+	}
+}
+
+func TestSplitTriggersUnterminatedBlock(t *testing.T) {
+	input := "DELIMITER ;;\nCREATE TRIGGER t BEFORE INSERT ON x FOR EACH ROW SET @a = 1;;\n"
+
+	var data, triggers bytes.Buffer
+	if err := splitTriggers(strings.NewReader(input), &data, &triggers); err != nil {
+		t.Fatalf("splitTriggers() error = %v", err)
+	}
+	if data.String() != input {
+		t.Errorf("Expected unterminated block to be kept in data, got %q", data.String())
+	}
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abdullahainun/tenangdb/internal/logger"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// invalidViewPrefix marks the definitions of views that were invalid at
+// backup time. Like server objects they are SQL comments, so the dump loads
+// with a plain mysql client, and restore tries to create them itself.
+const invalidViewPrefix = "-- tenangdb-invalid-view: "
+
+// InvalidViewsFile holds the invalid view definitions of mydumper backups.
+// It has no .sql extension so myloader leaves it alone.
+const InvalidViewsFile = "tenangdb-invalid-views"
+
+// Errors of views that can no longer be queried
+const (
+	errViewInvalid = 1356 // ER_VIEW_INVALID: references missing tables, columns or functions
+	errNoSuchUser  = 1449 // ER_NO_SUCH_USER: the definer account was dropped
+)
+
+// InvalidView is a view that cannot be queried, which makes mysqldump and
+// mydumper fail or write a dump that does not restore
+type InvalidView struct {
+	Name       string
+	Error      string
+	Definition string // CREATE VIEW statement, empty if it could not be read
+}
+
+// InvalidViews returns the views of dbName that fail when queried, in name
+// order
+func (c *Client) InvalidViews(ctx context.Context, dbName string) ([]InvalidView, error) {
+	rows, err := c.db.QueryContext(ctx, `SELECT TABLE_NAME FROM information_schema.VIEWS
+		WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	var views []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to list views: %w", err)
+		}
+		views = append(views, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list views: %w", err)
+	}
+	if len(views) == 0 {
+		return nil, nil
+	}
+
+	// Inside the database, SHOW CREATE VIEW leaves references to its own
+	// tables unqualified, so definitions restore under another name
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(dbName)); err != nil {
+		return nil, fmt.Errorf("failed to select database: %w", err)
+	}
+
+	var invalid []InvalidView
+	for _, name := range views {
+		rows, err := conn.QueryContext(ctx, "SELECT 1 FROM "+quoteIdentifier(name)+" LIMIT 0")
+		if err == nil {
+			rows.Close()
+			continue
+		}
+		var mysqlErr *mysql.MySQLError
+		if !errors.As(err, &mysqlErr) || (mysqlErr.Number != errViewInvalid && mysqlErr.Number != errNoSuchUser) {
+			return nil, fmt.Errorf("failed to check view %s: %w", name, err)
+		}
+
+		view := InvalidView{Name: name, Error: mysqlErr.Message}
+		var viewName, charset, collation string
+		if err := conn.QueryRowContext(ctx, "SHOW CREATE VIEW "+quoteIdentifier(name)).Scan(&viewName, &view.Definition, &charset, &collation); err != nil {
+			view.Definition = ""
+		}
+		invalid = append(invalid, view)
+	}
+	return invalid, nil
+}
+
+// invalidViews finds the invalid views of dbName and logs them. Dumps leave
+// them out and store their definitions with writeInvalidViews instead.
+func (c *Client) invalidViews(ctx context.Context, dbName string) ([]InvalidView, error) {
+	views, err := c.InvalidViews(ctx, dbName)
+	if err != nil || len(views) == 0 {
+		return nil, err
+	}
+
+	if c.logger != nil {
+		for _, v := range views {
+			c.logger.WithField("database", dbName).WithField("view", v.Name).WithField("error", v.Error).
+				Warn("⚠️ View is invalid and is left out of the dump, restore tries to recreate it")
+		}
+	}
+	return views, nil
+}
+
+// invalidViewNames returns the names of views
+func invalidViewNames(views []InvalidView) []string {
+	names := make([]string, len(views))
+	for i, v := range views {
+		names[i] = v.Name
+	}
+	return names
+}
+
+// writeInvalidViews stores the definitions of invalid views in a backup:
+// appended as comments to a mysqldump file, or in InvalidViewsFile inside a
+// directory
+func writeInvalidViews(backupPath string, views []InvalidView) error {
+	if len(views) == 0 {
+		return nil
+	}
+
+	target := backupPath
+	flags := os.O_WRONLY | os.O_APPEND
+	if info, err := os.Stat(backupPath); err == nil && info.IsDir() {
+		target = filepath.Join(backupPath, InvalidViewsFile)
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(target, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", target, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, "\n-- Views that were invalid at backup time, recreated by tenangdb restore when possible")
+	for _, v := range views {
+		fmt.Fprintf(w, "-- %s: %s\n", v.Name, strings.ReplaceAll(v.Error, "\n", " "))
+		if v.Definition != "" {
+			fmt.Fprintln(w, invalidViewPrefix+strings.ReplaceAll(v.Definition, "\n", " "))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to write invalid views: %w", err)
+	}
+	return file.Close()
+}
+
+// readInvalidViews returns the invalid view definitions stored in a backup
+func readInvalidViews(backupPath string) ([]string, error) {
+	target := backupPath
+	if info, err := os.Stat(backupPath); err == nil && info.IsDir() {
+		target = filepath.Join(backupPath, InvalidViewsFile)
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	// Dump lines can be megabytes long; only the start of each is needed
+	var statements []string
+	r := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, isPrefix, err := r.ReadLine()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(string(line), invalidViewPrefix) && !isPrefix {
+			statements = append(statements, strings.TrimPrefix(string(line), invalidViewPrefix))
+		}
+		for isPrefix {
+			if _, isPrefix, err = r.ReadLine(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return statements, nil
+}
+
+// restoreInvalidViews tries to create the views that were invalid at backup
+// time, after everything else was restored into dbName. Views may depend on
+// each other, so creation is retried until a pass makes no progress; the
+// views still failing then are logged without failing the restore.
+func (c *Client) restoreInvalidViews(ctx context.Context, backupPath, dbName string, log *logger.Logger) error {
+	statements, err := readInvalidViews(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to read invalid views: %w", err)
+	}
+	if len(statements) == 0 {
+		return nil
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "USE "+quoteIdentifier(dbName)); err != nil {
+		return fmt.Errorf("failed to select database: %w", err)
+	}
+
+	pending := statements
+	errs := make(map[string]error)
+	for len(pending) > 0 {
+		var failed []string
+		for _, stmt := range pending {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				errs[stmt] = err
+				failed = append(failed, stmt)
+				continue
+			}
+			delete(errs, stmt)
+		}
+		if len(failed) == len(pending) {
+			break
+		}
+		pending = failed
+	}
+
+	for _, stmt := range pending {
+		log.WithError(errs[stmt]).WithField("statement", stmt).Warn("⚠️ View that was invalid at backup time is still invalid and was not restored")
+	}
+	log.WithField("restored", len(statements)-len(pending)).WithField("still_invalid", len(pending)).
+		Info("🪟 Handled views that were invalid at backup time")
+	return nil
+}
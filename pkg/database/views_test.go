@@ -0,0 +1,66 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInvalidViewsRoundTrip(t *testing.T) {
+	views := []InvalidView{
+		{
+			Name:       "active_orders",
+			Error:      "View 'app.active_orders' references invalid table(s) or column(s) or function(s) or definer/invoker of view lack rights to use them",
+			Definition: "CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `active_orders` AS select `orders_old`.`id` AS `id`\nfrom `orders_old`",
+		},
+		{Name: "broken", Error: "The user specified as a definer ('gone'@'%') does not exist"},
+	}
+	want := []string{"CREATE ALGORITHM=UNDEFINED DEFINER=`app`@`%` SQL SECURITY DEFINER VIEW `active_orders` AS select `orders_old`.`id` AS `id` from `orders_old`"}
+
+	t.Run("mysqldump file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app-2025-07-05_10-30-15.sql")
+		dump := "CREATE TABLE orders (id int);\nINSERT INTO orders VALUES " + strings.Repeat("(1),", 100000) + "(1);\n"
+		if err := os.WriteFile(path, []byte(dump), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := writeInvalidViews(path, views); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readInvalidViews(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+
+		data, _ := os.ReadFile(path)
+		if !strings.HasPrefix(string(data), dump) {
+			t.Error("dump content was changed")
+		}
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := writeInvalidViews(dir, views); err != nil {
+			t.Fatal(err)
+		}
+		got, err := readInvalidViews(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %q, want %q", got, want)
+		}
+	})
+
+	t.Run("none", func(t *testing.T) {
+		got, err := readInvalidViews(t.TempDir())
+		if err != nil || got != nil {
+			t.Errorf("got %q, %v for a backup without invalid views", got, err)
+		}
+	})
+}
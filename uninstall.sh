@@ -271,7 +271,7 @@ backup_data() {
 remove_systemd_services() {
     print_status "🚀 Removing systemd services..."
     
-    local services=("tenangdb.service" "tenangdb.timer" "tenangdb-cleanup.service" "tenangdb-cleanup.timer" "tenangdb-exporter.service")
+    local services=("tenangdb.service" "tenangdb.timer" "tenangdb-cleanup.service" "tenangdb-cleanup.timer" "tenangdb-refresh-standby.service" "tenangdb-refresh-standby.timer" "tenangdb-exporter.service")
     
     for service in "${services[@]}"; do
         # Check both systemctl list-unit-files and actual files